@@ -3,10 +3,12 @@ package api
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
 	"net/http"
+	"time"
 
 	"github.com/gorilla/mux"
 	golog "github.com/ipfs/go-log"
@@ -152,6 +154,39 @@ func HealthCheckHandler(w http.ResponseWriter, r *http.Request) {
 	w.Write([]byte(`{ "meta": { "code": 200, "status": "ok", "version":"` + APIVersion + `" }, "data": [] }`))
 }
 
+// HealthHandler probes instance subsystems, responding with a health report.
+// Unhealthy instances respond with a 503 status code. Filesystem write probes
+// are opt-in with a "write=true" query param. Probe errors are logged, not
+// sent to the client
+func HealthHandler(inst *lib.Instance) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		params := &lib.HealthParams{WriteProbe: r.FormValue("write") == "true"}
+		report, err := inst.Health(r.Context(), params)
+		if err != nil {
+			log.Errorw("health check", "err", err)
+			apiutil.WriteErrResponse(w, http.StatusInternalServerError, fmt.Errorf("health check failed"))
+			return
+		}
+		for i, s := range report.Subsystems {
+			if s.Error != "" {
+				log.Errorw("health check", "subsystem", s.Name, "err", s.Error)
+				report.Subsystems[i].Error = "probe failed"
+			}
+		}
+		if !report.Healthy {
+			code := http.StatusServiceUnavailable
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(code)
+			json.NewEncoder(w).Encode(apiutil.Response{
+				Meta: &apiutil.Meta{Code: code, Error: "instance is unhealthy"},
+				Data: report,
+			})
+			return
+		}
+		apiutil.WriteResponse(w, report)
+	}
+}
+
 // refRouteParams carry a config for a ref based route
 type refRouteParams struct {
 	Endpoint qhttp.APIEndpoint
@@ -218,6 +253,8 @@ func NewServerRoutes(s Server) *mux.Router {
 
 	m := s.Instance.GiveAPIServer(s.Middleware, []string{})
 	m.Use(corsMiddleware(cfg.API.AllowedOrigins))
+	m.Use(timeoutMiddleware(time.Duration(cfg.API.RequestTimeoutMs)*time.Millisecond, streamingEndpoints...))
+	m.Use(gzipMiddleware(gzipMinSize))
 	m.Use(muxVarsToQueryParamMiddleware)
 	m.Use(refStringMiddleware)
 	m.Use(token.OAuthTokenMiddleware)
@@ -226,7 +263,7 @@ func NewServerRoutes(s Server) *mux.Router {
 
 	// misc endpoints
 	m.Handle(AEHome.String(), s.NoLogMiddleware(s.HomeHandler))
-	m.Handle(AEHealth.String(), s.NoLogMiddleware(HealthCheckHandler))
+	m.Handle(AEHealth.String(), s.NoLogMiddleware(HealthHandler(s.Instance)))
 	m.Handle(AEIPFS.String(), s.Middleware(s.HandleIPFSPath))
 	if cfg.API.Webui {
 		m.Handle(AEWebUI.String(), s.Middleware(WebuiHandler))
@@ -243,6 +280,9 @@ func NewServerRoutes(s Server) *mux.Router {
 	m.Handle(AEUnpack.String(), s.Middleware(UnpackHandler(AEUnpack.NoTrailingSlash())))
 	m.Handle(AESaveByUpload.String(), s.Middleware(SaveByUploadHandler(s.Instance, AESaveByUpload.NoTrailingSlash())))
 
+	// transform endpoints
+	m.Handle(qhttp.AETransformBuiltins.String(), s.Middleware(TransformBuiltinsHandler(s.Instance))).Methods(http.MethodGet)
+
 	// sync/protocol endpoints
 	if cfg.RemoteServer != nil && cfg.RemoteServer.Enabled {
 		log.Info("running in `remote` mode")
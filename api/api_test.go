@@ -3,6 +3,7 @@ package api
 import (
 	"bytes"
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
@@ -223,6 +224,31 @@ func TestHealthCheck(t *testing.T) {
 	runHandlerTestCases(t, "health check", HealthCheckHandler, healthCheckCases, true)
 }
 
+func TestHealthHandler(t *testing.T) {
+	ctx, done := context.WithCancel(context.Background())
+	defer done()
+
+	node, teardown := newTestNode(t)
+	defer teardown()
+	inst := newTestInstanceWithProfileFromNode(ctx, node)
+
+	w := httptest.NewRecorder()
+	HealthHandler(inst)(w, httptest.NewRequest(http.MethodGet, "/health?write=true", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+
+	res := struct {
+		Data *lib.HealthReport
+	}{}
+	if err := json.NewDecoder(w.Body).Decode(&res); err != nil {
+		t.Fatal(err)
+	}
+	if res.Data == nil || !res.Data.Healthy {
+		t.Errorf("expected a healthy report, got: %#v", res.Data)
+	}
+}
+
 type handlerMimeMultipartTestCase struct {
 	method    string
 	endpoint  string
@@ -209,6 +209,26 @@ func SaveByUploadHandler(inst *lib.Instance, routePrefix string) http.HandlerFun
 	}
 }
 
+// TransformBuiltinsHandler lists the starlark modules & builtins available to
+// transform scripts, for displaying help in editors
+// Example:
+// curl http://localhost:2503/transform/builtins
+func TransformBuiltinsHandler(inst *lib.Instance) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			util.NotFoundHandler(w, r)
+			return
+		}
+
+		res, err := inst.Transform().Builtins(r.Context(), &lib.BuiltinsParams{})
+		if err != nil {
+			util.RespondWithError(w, err)
+			return
+		}
+		util.WriteResponse(w, res)
+	}
+}
+
 func extensionToMimeType(ext string) string {
 	switch ext {
 	case ".csv":
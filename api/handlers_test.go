@@ -226,6 +226,70 @@ func datasetJSONResponse(t *testing.T, body string) *dataset.Dataset {
 	return res.Data
 }
 
+func TestTransformBuiltinsHandler(t *testing.T) {
+	run := NewAPITestRunner(t)
+	defer run.Delete()
+
+	gotStatusCode, gotBody := APICall("/transform/builtins", TransformBuiltinsHandler(run.Inst), nil)
+	assertStatusCode(t, "get transform builtins", gotStatusCode, 200)
+
+	res := struct {
+		Data []struct {
+			Name    string
+			Members []struct {
+				Name    string
+				Type    string
+				Members []struct {
+					Name string
+					Type string
+				}
+			}
+		}
+	}{}
+	if err := json.Unmarshal([]byte(gotBody), &res); err != nil {
+		t.Fatalf("error decoding response: %s", err)
+	}
+
+	found := false
+	for _, mod := range res.Data {
+		if mod.Name != "dataset.star" {
+			continue
+		}
+		for _, member := range mod.Members {
+			if member.Name != "dataset" {
+				continue
+			}
+			for _, fn := range member.Members {
+				if fn.Name == "new" && fn.Type == "builtin_function_or_method" {
+					found = true
+				}
+			}
+		}
+	}
+	if !found {
+		t.Errorf("expected dataset.star module to list the dataset.new builtin. got: %s", gotBody)
+	}
+
+	gotStatusCode, _ = APICallWithParams("POST", "/transform/builtins", nil, TransformBuiltinsHandler(run.Inst), nil)
+	assertStatusCode(t, "post transform builtins", gotStatusCode, 404)
+
+	// the server only routes GET requests to builtins
+	ts := run.MustTestServer(t)
+	defer ts.Close()
+	httpRes, err := http.Get(ts.URL + "/transform/builtins")
+	if err != nil {
+		t.Fatal(err)
+	}
+	httpRes.Body.Close()
+	assertStatusCode(t, "server get transform builtins", httpRes.StatusCode, http.StatusOK)
+	httpRes, err = http.Post(ts.URL+"/transform/builtins", "application/json", strings.NewReader("{}"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	httpRes.Body.Close()
+	assertStatusCode(t, "server post transform builtins", httpRes.StatusCode, http.StatusMethodNotAllowed)
+}
+
 func TestValidateCSVRequest(t *testing.T) {
 	var caseName string
 	var expectErr error
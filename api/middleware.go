@@ -1,13 +1,20 @@
 package api
 
 import (
+	"bufio"
+	"compress/gzip"
+	"encoding/json"
 	"fmt"
+	"net"
 	"net/http"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/gorilla/mux"
 	"github.com/qri-io/qri/api/util"
 	"github.com/qri-io/qri/dsref"
+	qhttp "github.com/qri-io/qri/lib/http"
 )
 
 // Middleware handles request logging
@@ -30,6 +37,200 @@ func (s Server) mwFunc(handler http.HandlerFunc, shouldLog bool) http.HandlerFun
 	}
 }
 
+// streamingEndpoints respond with bodies of unbounded size. Requests to these
+// endpoints, and any routes nested under them, run without a timeout
+var streamingEndpoints = []qhttp.APIEndpoint{
+	qhttp.AEGet,
+	AEIPFS,
+	qhttp.AERemoteDSync,
+	qhttp.AERemoteLogSync,
+}
+
+// timeoutMiddleware cancels the context of requests that run longer than
+// timeout, responding with 503 Service Unavailable. Timed requests have their
+// response buffered until the handler returns, so websocket upgrades & routes
+// under streaming endpoints are exempt. A timeout of zero or less disables the
+// middleware
+func timeoutMiddleware(timeout time.Duration, streaming ...qhttp.APIEndpoint) mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		if timeout <= 0 {
+			return next
+		}
+
+		body, _ := json.Marshal(util.Response{
+			Meta: &util.Meta{
+				Code:  http.StatusServiceUnavailable,
+				Error: fmt.Sprintf("request timed out after %s", timeout),
+			},
+		})
+		withTimeout := http.TimeoutHandler(next, timeout, string(body))
+
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Header.Get("Upgrade") != "" || isStreamingRoute(r, streaming) {
+				next.ServeHTTP(w, r)
+				return
+			}
+			withTimeout.ServeHTTP(w, r)
+		})
+	}
+}
+
+// isStreamingRoute reports whether the route matching r is one of the
+// streaming endpoints, or nested under one
+func isStreamingRoute(r *http.Request, streaming []qhttp.APIEndpoint) bool {
+	route := mux.CurrentRoute(r)
+	if route == nil {
+		return false
+	}
+	tmpl, err := route.GetPathTemplate()
+	if err != nil {
+		return false
+	}
+	for _, ep := range streaming {
+		if tmpl == ep.String() || strings.HasPrefix(tmpl, ep.NoTrailingSlash()+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// gzipMinSize is the smallest response body in bytes the api will compress
+const gzipMinSize = 1024
+
+// gzipMiddleware compresses response bodies of at least threshold bytes for
+// clients that accept gzip encoding. Smaller responses are sent as-is with an
+// accurate Content-Length
+func gzipMiddleware(threshold int) mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Method == http.MethodHead || r.Header.Get("Upgrade") != "" || !acceptsGzip(r) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			gw := &gzipResponseWriter{ResponseWriter: w, threshold: threshold, status: http.StatusOK}
+			defer gw.close()
+			next.ServeHTTP(gw, r)
+		})
+	}
+}
+
+func acceptsGzip(r *http.Request) bool {
+	for _, enc := range strings.Split(r.Header.Get("Accept-Encoding"), ",") {
+		if strings.TrimSpace(strings.SplitN(enc, ";", 2)[0]) == "gzip" {
+			return true
+		}
+	}
+	return false
+}
+
+// gzipResponseWriter buffers a response until it's known to reach the gzip
+// threshold, then streams the rest of the response through a gzip writer
+type gzipResponseWriter struct {
+	http.ResponseWriter
+	threshold int
+	status    int
+	buf       []byte
+	gz        *gzip.Writer
+	// set once headers have been sent without compression
+	passthrough bool
+}
+
+func (w *gzipResponseWriter) WriteHeader(status int) {
+	w.status = status
+}
+
+func (w *gzipResponseWriter) Write(p []byte) (int, error) {
+	if w.gz != nil {
+		return w.gz.Write(p)
+	}
+	if w.passthrough {
+		return w.ResponseWriter.Write(p)
+	}
+
+	w.buf = append(w.buf, p...)
+	if len(w.buf) < w.threshold {
+		return len(p), nil
+	}
+	return len(p), w.start()
+}
+
+// start sends headers & the buffered response, switching to compressing the
+// rest of the response unless the handler has already encoded it
+func (w *gzipResponseWriter) start() error {
+	h := w.Header()
+	if h.Get("Content-Encoding") != "" {
+		// handler has already encoded the response, don't double-encode
+		w.passthrough = true
+		w.ResponseWriter.WriteHeader(w.status)
+		_, err := w.ResponseWriter.Write(w.buf)
+		w.buf = nil
+		return err
+	}
+
+	if h.Get("Content-Type") == "" {
+		h.Set("Content-Type", http.DetectContentType(w.buf))
+	}
+	h.Del("Content-Length")
+	h.Set("Content-Encoding", "gzip")
+	h.Add("Vary", "Accept-Encoding")
+	w.ResponseWriter.WriteHeader(w.status)
+
+	w.gz = gzip.NewWriter(w.ResponseWriter)
+	_, err := w.gz.Write(w.buf)
+	w.buf = nil
+	return err
+}
+
+// Flush sends any buffered response to the client. Flushing before the gzip
+// threshold is reached means the response is streamed, and the response is
+// compressed regardless of size
+func (w *gzipResponseWriter) Flush() {
+	if w.gz == nil && !w.passthrough {
+		if err := w.start(); err != nil {
+			log.Debugf("gzip: starting flushed response: %s", err)
+			return
+		}
+	}
+	if w.gz != nil {
+		if err := w.gz.Flush(); err != nil {
+			log.Debugf("gzip: flushing response: %s", err)
+			return
+		}
+	}
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Hijack lets the handler take over the connection, if the underlying response
+// writer supports it
+func (w *gzipResponseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	hj, ok := w.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, fmt.Errorf("response writer does not support hijacking")
+	}
+	// the handler owns the connection now, there's nothing left to write
+	w.passthrough = true
+	return hj.Hijack()
+}
+
+// close flushes any buffered response, completing the gzip stream if the
+// response was compressed
+func (w *gzipResponseWriter) close() error {
+	if w.gz != nil {
+		return w.gz.Close()
+	}
+	if w.passthrough {
+		return nil
+	}
+
+	w.Header().Set("Content-Length", strconv.Itoa(len(w.buf)))
+	w.ResponseWriter.WriteHeader(w.status)
+	_, err := w.ResponseWriter.Write(w.buf)
+	return err
+}
+
 // corsMiddleware adds Cross-Origin Resource Sharing headers for any request
 // who's origin matches one of allowedOrigins
 func corsMiddleware(allowedOrigins []string) mux.MiddlewareFunc {
@@ -0,0 +1,193 @@
+package api
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/qri-io/qri/api/util"
+	qhttp "github.com/qri-io/qri/lib/http"
+)
+
+func TestTimeoutMiddleware(t *testing.T) {
+	cancelled := make(chan struct{}, 1)
+	slow := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+			cancelled <- struct{}{}
+		case <-time.After(time.Second):
+			w.Write([]byte("finished"))
+		}
+	})
+	fast := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("finished"))
+	})
+
+	mw := timeoutMiddleware(20 * time.Millisecond)
+
+	w := httptest.NewRecorder()
+	mw(slow).ServeHTTP(w, httptest.NewRequest("GET", "/slow", nil))
+	if w.Code != http.StatusServiceUnavailable {
+		t.Errorf("expected slow handler to respond with status %d, got %d", http.StatusServiceUnavailable, w.Code)
+	}
+	if !strings.Contains(w.Body.String(), "request timed out") {
+		t.Errorf("expected timeout error in response body, got: %s", w.Body.String())
+	}
+	select {
+	case <-cancelled:
+	case <-time.After(time.Second):
+		t.Error("expected request context to be cancelled on timeout")
+	}
+
+	w = httptest.NewRecorder()
+	mw(fast).ServeHTTP(w, httptest.NewRequest("GET", "/fast", nil))
+	if w.Code != http.StatusOK || w.Body.String() != "finished" {
+		t.Errorf("expected fast handler to complete. got status %d, body: %q", w.Code, w.Body.String())
+	}
+
+	// streaming routes can flush partial responses & outlive the timeout
+	streaming := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("part"))
+		w.(http.Flusher).Flush()
+		time.Sleep(40 * time.Millisecond)
+		w.Write([]byte("finished"))
+	})
+	m := mux.NewRouter()
+	m.Use(timeoutMiddleware(20*time.Millisecond, qhttp.AEGet))
+	m.Handle("/ds/get/{username}/{name}/body.csv", streaming)
+	m.Handle("/ds/other", slow)
+
+	w = httptest.NewRecorder()
+	m.ServeHTTP(w, httptest.NewRequest("GET", "/ds/get/me/ds/body.csv", nil))
+	if w.Code != http.StatusOK || w.Body.String() != "partfinished" {
+		t.Errorf("expected streaming route to complete. got status %d, body: %q", w.Code, w.Body.String())
+	}
+	if !w.Flushed {
+		t.Error("expected streaming route to be flushed")
+	}
+	w = httptest.NewRecorder()
+	m.ServeHTTP(w, httptest.NewRequest("GET", "/ds/other", nil))
+	if w.Code != http.StatusServiceUnavailable {
+		t.Errorf("expected non-streaming route to respond with status %d, got %d", http.StatusServiceUnavailable, w.Code)
+	}
+
+	// a zero timeout disables the middleware
+	w = httptest.NewRecorder()
+	timeoutMiddleware(0)(fast).ServeHTTP(w, httptest.NewRequest("GET", "/fast", nil))
+	if w.Code != http.StatusOK {
+		t.Errorf("expected disabled middleware to pass requests through, got status %d", w.Code)
+	}
+}
+
+func TestGzipMiddleware(t *testing.T) {
+	items := []map[string]string{}
+	for i := 0; i < 200; i++ {
+		items = append(items, map[string]string{"component": "body", "type": "modified"})
+	}
+	large := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		util.WriteResponse(w, items)
+	})
+	small := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		util.WriteResponse(w, "ok")
+	})
+
+	mw := gzipMiddleware(gzipMinSize)
+
+	expect := httptest.NewRecorder()
+	large.ServeHTTP(expect, httptest.NewRequest("GET", "/status", nil))
+
+	req := httptest.NewRequest("GET", "/status", nil)
+	req.Header.Set("Accept-Encoding", "gzip, deflate")
+	w := httptest.NewRecorder()
+	mw(large).ServeHTTP(w, req)
+
+	if got := w.Header().Get("Content-Encoding"); got != "gzip" {
+		t.Fatalf("expected large response to be gzip encoded, got Content-Encoding %q", got)
+	}
+	if got := w.Header().Get("Content-Length"); got != "" {
+		t.Errorf("expected compressed response to omit Content-Length, got %q", got)
+	}
+	if w.Body.Len() >= expect.Body.Len() {
+		t.Errorf("expected compressed body (%d bytes) to be smaller than uncompressed (%d bytes)", w.Body.Len(), expect.Body.Len())
+	}
+	gz, err := gzip.NewReader(w.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := ioutil.ReadAll(gz)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(expect.Body.Bytes(), got) {
+		t.Errorf("decompressed body mismatch.\nwant: %s\ngot:  %s", expect.Body.String(), string(got))
+	}
+
+	// responses under the threshold aren't compressed
+	w = httptest.NewRecorder()
+	mw(small).ServeHTTP(w, req)
+	if got := w.Header().Get("Content-Encoding"); got != "" {
+		t.Errorf("expected small response to be uncompressed, got Content-Encoding %q", got)
+	}
+	if got, expect := w.Header().Get("Content-Length"), fmt.Sprintf("%d", w.Body.Len()); got != expect {
+		t.Errorf("expected Content-Length %s, got %q", expect, got)
+	}
+
+	// clients that don't accept gzip get uncompressed responses
+	w = httptest.NewRecorder()
+	mw(large).ServeHTTP(w, httptest.NewRequest("GET", "/status", nil))
+	if got := w.Header().Get("Content-Encoding"); got != "" {
+		t.Errorf("expected response to be uncompressed, got Content-Encoding %q", got)
+	}
+	if !bytes.Equal(expect.Body.Bytes(), w.Body.Bytes()) {
+		t.Error("expected uncompressed response to match handler output")
+	}
+
+	// flushing streams the compressed response as it's written
+	var flushed []byte
+	streaming := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("part,"))
+		w.(http.Flusher).Flush()
+		flushed = append(flushed, w.(*gzipResponseWriter).ResponseWriter.(*httptest.ResponseRecorder).Body.Bytes()...)
+		w.Write([]byte("finished"))
+	})
+	w = httptest.NewRecorder()
+	mw(streaming).ServeHTTP(w, req)
+	if !w.Flushed {
+		t.Error("expected flush to reach the underlying response writer")
+	}
+	if got := w.Header().Get("Content-Encoding"); got != "gzip" {
+		t.Errorf("expected flushed response to be gzip encoded, got Content-Encoding %q", got)
+	}
+	if gz, err = gzip.NewReader(bytes.NewReader(flushed)); err != nil {
+		t.Fatal(err)
+	}
+	// the stream is incomplete at flush time, read what's available
+	part := make([]byte, len("part,"))
+	if _, err := io.ReadFull(gz, part); err != nil || string(part) != "part," {
+		t.Errorf("expected flushed data to decompress to %q, got: %q (%v)", "part,", part, err)
+	}
+	if gz, err = gzip.NewReader(w.Body); err != nil {
+		t.Fatal(err)
+	}
+	if got, err = ioutil.ReadAll(gz); err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != "part,finished" {
+		t.Errorf("expected streamed body %q, got: %q", "part,finished", got)
+	}
+
+	hijacking := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, _, err := w.(http.Hijacker).Hijack(); err == nil {
+			t.Error("expected hijacking a writer that doesn't support it to error")
+		}
+	})
+	mw(hijacking).ServeHTTP(httptest.NewRecorder(), req)
+}
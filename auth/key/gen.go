@@ -1,7 +1,11 @@
 package key
 
 import (
+	"bytes"
 	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"sync/atomic"
 
 	"github.com/libp2p/go-libp2p-core/crypto"
 	crypto_pb "github.com/libp2p/go-libp2p-core/crypto/pb"
@@ -60,3 +64,44 @@ func (g cryptoGenerator) GeneratePrivateKeyAndPeerID() (privKey, peerID string)
 	}
 	return
 }
+
+// seededCryptoGenerator derives a reproducible sequence of Ed25519 keys from a
+// seed
+type seededCryptoGenerator struct {
+	seed  []byte
+	count uint64
+}
+
+var _ CryptoGenerator = (*seededCryptoGenerator)(nil)
+
+// NewSeededCryptoGenerator returns a source of p2p cryptographic info that
+// derives keys from the given seed. Generators created with the same seed
+// produce the same sequence of keys, which is useful for reproducible setups
+// of ephemeral nodes. Seeded keys are only as secret as the seed they come
+// from, and should never be used for long-lived identities
+func NewSeededCryptoGenerator(seed []byte) CryptoGenerator {
+	return &seededCryptoGenerator{seed: append([]byte(nil), seed...)}
+}
+
+// GeneratePrivateKeyAndPeerID returns the next private key and peerID
+// derived from the seed
+func (g *seededCryptoGenerator) GeneratePrivateKeyAndPeerID() (privKey, peerID string) {
+	// each key is derived from a hash of the seed and the number of keys
+	// generated so far. claiming a count atomically keeps concurrent callers
+	// from deriving the same key
+	counter := make([]byte, 8)
+	binary.BigEndian.PutUint64(counter, atomic.AddUint64(&g.count, 1)-1)
+	keySeed := sha256.Sum256(append(append([]byte(nil), g.seed...), counter...))
+
+	if priv, pub, err := crypto.GenerateEd25519Key(bytes.NewReader(keySeed[:])); err == nil {
+		privKey, err = EncodePrivKeyB64(priv)
+		if err != nil {
+			panic(err)
+		}
+		// Obtain profile.ID from public key
+		if pid, err := IDFromPubKey(pub); err == nil {
+			peerID = pid
+		}
+	}
+	return
+}
@@ -0,0 +1,79 @@
+package key_test
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/qri-io/qri/auth/key"
+)
+
+func TestSeededCryptoGenerator(t *testing.T) {
+	a := key.NewSeededCryptoGenerator([]byte("reproducible"))
+	b := key.NewSeededCryptoGenerator([]byte("reproducible"))
+
+	aPriv, aID := a.GeneratePrivateKeyAndPeerID()
+	bPriv, bID := b.GeneratePrivateKeyAndPeerID()
+	if aID == "" || aPriv == "" {
+		t.Fatal("expected seeded generator to produce a key and peerID")
+	}
+	if aID != bID {
+		t.Errorf("expected the same seed to yield the same peerID. got %q and %q", aID, bID)
+	}
+	if aPriv != bPriv {
+		t.Error("expected the same seed to yield the same private key")
+	}
+
+	// the private key must match the peerID
+	pk, err := key.DecodeB64PrivKey(aPriv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	id, err := key.IDFromPrivKey(pk)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id != aID {
+		t.Errorf("private key does not match peerID. want: %q, got: %q", aID, id)
+	}
+
+	// subsequent keys differ, but are still reproducible
+	aNextPriv, aNextID := a.GeneratePrivateKeyAndPeerID()
+	if aNextID == aID || aNextPriv == aPriv {
+		t.Error("expected successive keys from a generator to differ")
+	}
+	if _, bNextID := b.GeneratePrivateKeyAndPeerID(); aNextID != bNextID {
+		t.Errorf("expected second keys from the same seed to match. got %q and %q", aNextID, bNextID)
+	}
+
+	// a different seed yields a different key
+	_, otherID := key.NewSeededCryptoGenerator([]byte("different")).GeneratePrivateKeyAndPeerID()
+	if otherID == aID {
+		t.Error("expected different seeds to yield different peerIDs")
+	}
+}
+
+func TestSeededCryptoGeneratorConcurrent(t *testing.T) {
+	g := key.NewSeededCryptoGenerator([]byte("concurrent"))
+
+	const n = 20
+	ids := make(chan string, n)
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, id := g.GeneratePrivateKeyAndPeerID()
+			ids <- id
+		}()
+	}
+	wg.Wait()
+	close(ids)
+
+	seen := map[string]bool{}
+	for id := range ids {
+		if seen[id] {
+			t.Errorf("concurrent callers got the same peerID %q", id)
+		}
+		seen[id] = true
+	}
+}
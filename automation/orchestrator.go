@@ -5,6 +5,7 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"sync"
 	"time"
 
 	golog "github.com/ipfs/go-log"
@@ -34,6 +35,17 @@ type OrchestratorOptions struct {
 	WorkflowStore workflow.Store
 	Listeners     []trigger.Listener
 	RunStore      run.Store
+	// ActivationLog records each time a trigger causes a workflow to run.
+	// optional
+	ActivationLog trigger.ActivationLog
+	// MaxConcurrentRuns caps the number of runs & applies executing at once by
+	// setting the number of run queue workers. Applies that wait for their
+	// result go through the run queue when a cap is set. Zero runs queued runs
+	// one at a time & doesn't limit applies that wait
+	MaxConcurrentRuns int
+	// MaxQueuedRuns caps the number of runs waiting in the run queue, runs
+	// beyond this cap are rejected with ErrRunQueueFull. Zero means no limit
+	MaxQueuedRuns int
 }
 
 // WorkflowRunner is for running workflows using some execution engine
@@ -49,6 +61,17 @@ type WorkflowRunParams struct {
 	Secrets      map[string]string
 	OutputWidth  int
 	OutputHeight int
+	// Deterministic runs the transform with a fixed clock & a random source
+	// seeded with Seed
+	Deterministic bool
+	Seed          int64
+	// LoadRetryAttempts & LoadRetryBackoff configure retrying transient
+	// dataset load failures. fewer than two attempts doesn't retry
+	LoadRetryAttempts int
+	LoadRetryBackoff  time.Duration
+	// VerifyBodyChecksum confirms bodies the transform reports as unchanged
+	// by comparing checksums with the previous version
+	VerifyBodyChecksum bool
 }
 
 // Orchestrator manages automation in qri
@@ -57,11 +80,24 @@ type Orchestrator struct {
 	workflows workflow.Store
 	listeners map[string]trigger.Listener
 	runs      run.Store
+	activity  trigger.ActivationLog
 	runner    WorkflowRunner
 	bus       event.Bus
 	cancel    context.CancelFunc
 	doneCh    chan struct{}
 	running   bool
+
+	maxConcurrent int
+	maxQueued     int
+
+	activeLk   sync.Mutex
+	activeRuns map[string]*activeRun
+}
+
+// activeRun tracks an executing run so it can be cancelled
+type activeRun struct {
+	cancel    context.CancelFunc
+	cancelled bool
 }
 
 // NewOrchestrator constructs an orchestrator
@@ -74,6 +110,13 @@ func NewOrchestrator(ctx context.Context, bus event.Bus, runner WorkflowRunner,
 	if runner == nil {
 		return nil, fmt.Errorf("WorkflowRunner required")
 	}
+	if opts.MaxConcurrentRuns < 0 || opts.MaxQueuedRuns < 0 {
+		return nil, fmt.Errorf("MaxConcurrentRuns and MaxQueuedRuns must be zero or greater")
+	}
+	workers := 1
+	if opts.MaxConcurrentRuns > 0 {
+		workers = opts.MaxConcurrentRuns
+	}
 
 	ctx, cancel := context.WithCancel(ctx)
 	ok := false
@@ -92,7 +135,13 @@ func NewOrchestrator(ctx context.Context, bus event.Bus, runner WorkflowRunner,
 		runner:    runner,
 		workflows: opts.WorkflowStore,
 		runs:      opts.RunStore,
-		runQueue:  NewRunQueue(ctx, bus, 50*time.Millisecond, 1),
+		activity:  opts.ActivationLog,
+		runQueue:  NewRunQueue(ctx, bus, 50*time.Millisecond, workers),
+
+		maxConcurrent: opts.MaxConcurrentRuns,
+		maxQueued:     opts.MaxQueuedRuns,
+
+		activeRuns: map[string]*activeRun{},
 	}
 
 	for _, l := range opts.Listeners {
@@ -148,9 +197,14 @@ func DefaultOrchestratorOptions(bus event.Bus, repoPath string) (OrchestratorOpt
 	if err != nil {
 		return OrchestratorOptions{}, err
 	}
+	al, err := trigger.NewFileActivationLog(repoPath)
+	if err != nil {
+		return OrchestratorOptions{}, err
+	}
 	return OrchestratorOptions{
 		WorkflowStore: wfs,
 		RunStore:      rs,
+		ActivationLog: al,
 		Listeners: []trigger.Listener{
 			trigger.NewCronListener(bus),
 		},
@@ -163,6 +217,7 @@ func DefaultMemOrchestratorOptions(ctx context.Context, bus event.Bus) Orchestra
 	return OrchestratorOptions{
 		WorkflowStore: workflow.NewMemStore(),
 		RunStore:      run.NewMemStore(),
+		ActivationLog: trigger.NewMemActivationLog(),
 		Listeners: []trigger.Listener{
 			trigger.NewRuntimeListener(ctx, bus),
 		},
@@ -293,15 +348,44 @@ func (o *Orchestrator) handleTrigger(ctx context.Context, e event.Event) error {
 			}
 			runID := run.NewID()
 			runFunc := o.runWorkflowFactory(wf, runID)
-			if err := o.runQueue.Push(ctx, wf.OwnerID.Encode(), runID, "run", runFunc); err != nil {
+			if err := o.enqueue(ctx, wf.OwnerID.Encode(), runID, "run", runFunc); err != nil {
 
 				log.Debugw("handleTrigger: error queuing workflow", "err", err)
+				return
 			}
+			o.recordActivation(ctx, wtp, runID)
 		}()
 	}
 	return nil
 }
 
+// recordActivation writes a trigger activation to the orchestrator's
+// ActivationLog, if one is configured
+func (o *Orchestrator) recordActivation(ctx context.Context, wtp event.WorkflowTriggerEvent, runID string) {
+	if o.activity == nil {
+		return
+	}
+	a := &trigger.Activation{
+		Timestamp:  *NowFunc(),
+		OwnerID:    wtp.OwnerID,
+		WorkflowID: wtp.WorkflowID,
+		TriggerID:  wtp.TriggerID,
+		RunID:      runID,
+	}
+	if err := o.activity.Append(ctx, a); err != nil {
+		log.Debugw("recordActivation: error appending activation", "err", err)
+	}
+}
+
+// TriggerActivations lists the recorded trigger activations for a workflow in
+// reverse chronological order
+func (o *Orchestrator) TriggerActivations(ctx context.Context, wid workflow.ID, lp params.List) ([]*trigger.Activation, error) {
+	if o.activity == nil {
+		return []*trigger.Activation{}, nil
+	}
+	return o.activity.List(ctx, wid.String(), lp)
+}
+
 func (o *Orchestrator) runWorkflowFactory(wf *workflow.Workflow, runID string) runQueueFunc {
 	return func(ctx context.Context) error {
 		return o.runWorkflow(ctx, wf, runID)
@@ -319,13 +403,45 @@ func (o *Orchestrator) RunWorkflow(ctx context.Context, wid workflow.ID, runID s
 	}
 
 	runFunc := o.runWorkflowFactory(wf, runID)
-	return runID, o.runQueue.Push(ctx, wf.OwnerID.Encode(), runID, "run", runFunc)
+	return runID, o.enqueue(ctx, wf.OwnerID.Encode(), runID, "run", runFunc)
+}
+
+// enqueue pushes a run onto the run queue, returning ErrRunQueueFull if the
+// maximum number of runs are already waiting
+func (o *Orchestrator) enqueue(ctx context.Context, ownerID, runID, mode string, f runQueueFunc) error {
+	return o.runQueue.PushIfRoom(ctx, ownerID, runID, mode, o.maxQueued, f)
+}
+
+// enqueueAndWait pushes a run onto the run queue & blocks until it finishes.
+// f is called with ctx instead of the run queue's context, cancelling ctx
+// before the run starts removes it from the queue
+func (o *Orchestrator) enqueueAndWait(ctx context.Context, ownerID, runID, mode string, f runQueueFunc) error {
+	errCh := make(chan error, 1)
+	err := o.enqueue(ctx, ownerID, runID, mode, func(context.Context) error {
+		err := f(ctx)
+		errCh <- err
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	select {
+	case err := <-errCh:
+		return err
+	case <-ctx.Done():
+		o.runQueue.Cancel(runID)
+		return ctx.Err()
+	}
 }
 
 func (o *Orchestrator) runWorkflow(ctx context.Context, wf *workflow.Workflow, runID string) error {
 	wid := wf.ID
 	log.Debugw("runWorkflow, workflow", "id", wid)
 
+	runCtx, ar := o.trackRun(ctx, runID)
+	defer o.untrackRun(runID)
+
 	go func(wf *workflow.Workflow) {
 		if err := o.bus.PublishID(ctx, event.ETAutomationWorkflowStarted, wf.ID.String(), event.WorkflowStartedEvent{
 			InitID:     wf.InitID,
@@ -339,7 +455,7 @@ func (o *Orchestrator) runWorkflow(ctx context.Context, wf *workflow.Workflow, r
 
 	if o.runs != nil {
 		r := &run.State{ID: runID, WorkflowID: wid}
-		if _, err := o.runs.Create(ctx, r); err != nil {
+		if _, err := o.runs.Create(runCtx, r); err != nil {
 			return err
 		}
 
@@ -353,15 +469,22 @@ func (o *Orchestrator) runWorkflow(ctx context.Context, wf *workflow.Workflow, r
 	streams := ioes.NewDiscardIOStreams()
 
 	// TODO(dustmop): Retrieve params from enqueued run, pass them into RunAndCommit
-	err := o.runner.RunAndCommit(ctx, runID, wf, streams, WorkflowRunParams{})
+	err := o.runner.RunAndCommit(runCtx, runID, wf, streams, WorkflowRunParams{})
+	// stop tracking the run before reporting it stopped, cancelling a run that
+	// has finished must fail with ErrUnknownRun
+	o.untrackRun(runID)
+	runStatus := run.RSFailed
+	if err == nil {
+		runStatus = run.RSSucceeded
+	}
+	if errors.Is(err, dsfs.ErrNoChanges) {
+		runStatus = run.RSUnchanged
+	}
+	if o.wasCancelled(ar) {
+		runStatus = run.RSCancelled
+		o.markRunCancelled(runID)
+	}
 	go func(wf *workflow.Workflow) {
-		runStatus := run.RSFailed
-		if err == nil {
-			runStatus = run.RSSucceeded
-		}
-		if errors.Is(err, dsfs.ErrNoChanges) {
-			runStatus = run.RSUnchanged
-		}
 		if err := o.bus.PublishID(ctx, event.ETAutomationWorkflowStopped, wf.ID.String(), event.WorkflowStoppedEvent{
 			InitID:     wf.InitID,
 			OwnerID:    wf.OwnerID,
@@ -381,19 +504,24 @@ func (o *Orchestrator) runWorkflow(ctx context.Context, wf *workflow.Workflow, r
 // ApplyWorkflow runs the given workflow, but does not record the output
 func (o *Orchestrator) ApplyWorkflow(ctx context.Context, wait bool, scriptOutput io.Writer, wf *workflow.Workflow, ds *dataset.Dataset, params WorkflowRunParams) (string, error) {
 	runID := run.NewID()
-	if wait {
-		return runID, o.applyWorkflow(ctx, scriptOutput, wf, ds, runID, params)
-	}
-
 	// enqueue the workflow, with a function to run it once the queue is ready
 	runFunc := func(ctx context.Context) error {
 		return o.applyWorkflow(ctx, scriptOutput, wf, ds, runID, params)
 	}
-	return runID, o.runQueue.Push(ctx, wf.OwnerID.Encode(), runID, "apply", runFunc)
+	if wait {
+		if o.maxConcurrent == 0 {
+			return runID, runFunc(ctx)
+		}
+		return runID, o.enqueueAndWait(ctx, wf.OwnerID.Encode(), runID, "apply", runFunc)
+	}
+	return runID, o.enqueue(ctx, wf.OwnerID.Encode(), runID, "apply", runFunc)
 }
 
 func (o *Orchestrator) applyWorkflow(ctx context.Context, scriptOutput io.Writer, wf *workflow.Workflow, ds *dataset.Dataset, runID string, params WorkflowRunParams) error {
 	log.Debugw("ApplyWorkflow", "workflow id", wf.ID, "run id", runID)
+	ctx, _ = o.trackRun(ctx, runID)
+	defer o.untrackRun(runID)
+
 	if scriptOutput != nil {
 		o.bus.SubscribeID(func(ctx context.Context, e event.Event) error {
 			log.Debugw("apply transform event", "type", e.Type, "payload", e.Payload)
@@ -415,10 +543,73 @@ func (o *Orchestrator) applyWorkflow(ctx context.Context, scriptOutput io.Writer
 	return o.runner.RunEphemeral(ctx, runID, wf, ds, true, params)
 }
 
-// CancelRun cancels the run of the given runID
-func (o *Orchestrator) CancelRun(ctx context.Context, runID string) {
+// CancelRun cancels the run of the given runID. Runs that are executing have
+// their context cancelled & are marked with a cancelled status, runs that are
+// queued are removed from the queue. It returns ErrUnknownRun if the run is not
+// in progress
+func (o *Orchestrator) CancelRun(ctx context.Context, runID string) error {
 	log.Debugw("orchestrator.CancelRun", "runID", runID)
-	o.runQueue.Cancel(runID)
+	o.activeLk.Lock()
+	ar, ok := o.activeRuns[runID]
+	if ok {
+		ar.cancelled = true
+	}
+	o.activeLk.Unlock()
+	if ok {
+		ar.cancel()
+		return nil
+	}
+	if err := o.runQueue.Cancel(runID); err != nil {
+		return fmt.Errorf("cancelling run %q: %w", runID, err)
+	}
+	return nil
+}
+
+// trackRun registers a cancellable context for an executing run
+func (o *Orchestrator) trackRun(ctx context.Context, runID string) (context.Context, *activeRun) {
+	ctx, cancel := context.WithCancel(ctx)
+	ar := &activeRun{cancel: cancel}
+	o.activeLk.Lock()
+	o.activeRuns[runID] = ar
+	o.activeLk.Unlock()
+	return ctx, ar
+}
+
+// untrackRun removes a run from the set of executing runs. The run queue
+// keeps a run cancellable until its function returns, drop it there too so a
+// finished run can't be cancelled
+func (o *Orchestrator) untrackRun(runID string) {
+	o.activeLk.Lock()
+	if ar, ok := o.activeRuns[runID]; ok {
+		ar.cancel()
+		delete(o.activeRuns, runID)
+	}
+	o.activeLk.Unlock()
+	o.runQueue.Done(runID)
+}
+
+func (o *Orchestrator) wasCancelled(ar *activeRun) bool {
+	o.activeLk.Lock()
+	defer o.activeLk.Unlock()
+	return ar.cancelled
+}
+
+// markRunCancelled sets the status of a stored run state to cancelled
+func (o *Orchestrator) markRunCancelled(runID string) {
+	if o.runs == nil {
+		return
+	}
+	// use a fresh context, the run context has been cancelled
+	ctx := context.Background()
+	r, err := o.runs.Get(ctx, runID)
+	if err != nil {
+		log.Debugw("markRunCancelled: getting run", "runID", runID, "err", err)
+		return
+	}
+	r.Status = run.RSCancelled
+	if _, err := o.runs.Put(ctx, r); err != nil {
+		log.Debugw("markRunCancelled: updating run", "runID", runID, "err", err)
+	}
 }
 
 // SaveWorkflow creates a new workflow if the workflow id is empty, or updates
@@ -2,6 +2,8 @@ package automation
 
 import (
 	"context"
+	"errors"
+	"sync"
 	"testing"
 	"time"
 
@@ -12,6 +14,7 @@ import (
 	"github.com/qri-io/qri/automation/run"
 	"github.com/qri-io/qri/automation/trigger"
 	"github.com/qri-io/qri/automation/workflow"
+	"github.com/qri-io/qri/base/params"
 	"github.com/qri-io/qri/event"
 )
 
@@ -254,6 +257,270 @@ func TestIntegration(t *testing.T) {
 	<-done
 }
 
+func TestTriggerActivationLog(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	bus := event.NewBus(ctx)
+
+	runStore := run.NewMemStore()
+	activations := trigger.NewMemActivationLog()
+	runtimeListener := trigger.NewRuntimeListener(ctx, bus)
+	opts := OrchestratorOptions{
+		WorkflowStore: workflow.NewMemStore(),
+		RunStore:      runStore,
+		ActivationLog: activations,
+		Listeners:     []trigger.Listener{runtimeListener},
+	}
+	o, err := NewOrchestrator(ctx, bus, newTestWorkflowRunner(runStore, nil), opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer o.Stop()
+
+	wf, err := o.SaveWorkflow(ctx, &workflow.Workflow{
+		InitID:  "dataset_id",
+		OwnerID: "profile_id",
+		Active:  true,
+		Triggers: []map[string]interface{}{
+			{"type": trigger.RuntimeType, "active": true},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := o.Start(ctx); err != nil {
+		t.Fatal(err)
+	}
+	// give time for Start to start each listener
+	<-time.After(100 * time.Millisecond)
+
+	stopped := make(chan string)
+	bus.SubscribeTypes(func(ctx context.Context, e event.Event) error {
+		stopped <- "workflow finished"
+		return nil
+	}, event.ETAutomationWorkflowStopped)
+
+	triggerID := wf.Triggers[0]["id"].(string)
+	done := errOnTimeout(t, stopped, "timed out before `ETAutomationWorkflowStopped` event fired")
+	runtimeListener.TriggerCh <- event.WorkflowTriggerEvent{
+		OwnerID:    wf.OwnerID,
+		WorkflowID: wf.WorkflowID(),
+		TriggerID:  triggerID,
+	}
+	<-done
+
+	got, err := o.TriggerActivations(ctx, wf.ID, params.ListAll)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 1 {
+		t.Fatalf("expected 1 activation, got %d", len(got))
+	}
+	runs, err := runStore.List(ctx, wf.ID, params.ListAll)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(runs) != 1 {
+		t.Fatalf("expected 1 run, got %d", len(runs))
+	}
+	expect := &trigger.Activation{
+		OwnerID:    wf.OwnerID,
+		WorkflowID: wf.WorkflowID(),
+		TriggerID:  triggerID,
+		RunID:      runs[0].ID,
+	}
+	if diff := cmp.Diff(expect, got[0], cmpopts.IgnoreFields(trigger.Activation{}, "Timestamp")); diff != "" {
+		t.Errorf("activation mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestMaxConcurrentRuns(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	bus := event.NewBus(ctx)
+
+	opts := DefaultMemOrchestratorOptions(ctx, bus)
+	opts.MaxConcurrentRuns = 2
+	runner := &concurrencyTrackingRunner{delay: 50 * time.Millisecond}
+	o, err := NewOrchestrator(ctx, bus, runner, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer o.Stop()
+
+	wf := &workflow.Workflow{OwnerID: "profile_id"}
+	errs := make(chan error, 5)
+	for i := 0; i < 5; i++ {
+		go func() {
+			_, err := o.ApplyWorkflow(ctx, true, nil, wf, nil, WorkflowRunParams{})
+			errs <- err
+		}()
+	}
+	for i := 0; i < 5; i++ {
+		if err := <-errs; err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if runner.max > 2 {
+		t.Errorf("expected at most 2 concurrent runs, got %d", runner.max)
+	}
+	if runner.total != 5 {
+		t.Errorf("expected 5 total runs, got %d", runner.total)
+	}
+}
+
+func TestMaxQueuedRuns(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	bus := event.NewBus(ctx)
+
+	opts := DefaultMemOrchestratorOptions(ctx, bus)
+	opts.MaxConcurrentRuns = 1
+	opts.MaxQueuedRuns = 1
+	runner := &concurrencyTrackingRunner{delay: 500 * time.Millisecond}
+	o, err := NewOrchestrator(ctx, bus, runner, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer o.Stop()
+
+	wf := &workflow.Workflow{OwnerID: "profile_id"}
+	errs := make(chan error, 2)
+	for i := 0; i < 2; i++ {
+		go func() {
+			_, err := o.ApplyWorkflow(ctx, true, nil, wf, nil, WorkflowRunParams{})
+			errs <- err
+		}()
+		// give the run queue time to start the first apply before the second
+		// is queued
+		<-time.After(100 * time.Millisecond)
+	}
+
+	if _, err := o.ApplyWorkflow(ctx, true, nil, wf, nil, WorkflowRunParams{}); !errors.Is(err, ErrRunQueueFull) {
+		t.Errorf("expected error %q, got: %v", ErrRunQueueFull, err)
+	}
+	if _, err := o.ApplyWorkflow(ctx, false, nil, wf, nil, WorkflowRunParams{}); !errors.Is(err, ErrRunQueueFull) {
+		t.Errorf("expected queued apply error %q, got: %v", ErrRunQueueFull, err)
+	}
+	for i := 0; i < 2; i++ {
+		if err := <-errs; err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestCancelRun(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	bus := event.NewBus(ctx)
+
+	opts := DefaultMemOrchestratorOptions(ctx, bus)
+	started := make(chan string)
+	o, err := NewOrchestrator(ctx, bus, &blockingRunner{started: started}, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer o.Stop()
+
+	wf, err := o.SaveWorkflow(ctx, &workflow.Workflow{InitID: "dataset_id", OwnerID: "profile_id"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stopped := make(chan string)
+	bus.SubscribeTypes(func(ctx context.Context, e event.Event) error {
+		if p, ok := e.Payload.(event.WorkflowStoppedEvent); ok {
+			stopped <- p.Status
+		}
+		return nil
+	}, event.ETAutomationWorkflowStopped)
+
+	done := errOnTimeout(t, started, "timed out before run started")
+	runID, err := o.RunWorkflow(ctx, wf.ID, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	<-done
+
+	if err := o.CancelRun(ctx, runID); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case status := <-stopped:
+		if status != string(run.RSCancelled) {
+			t.Errorf("expected stopped status %q, got %q", run.RSCancelled, status)
+		}
+	case <-time.After(200 * time.Millisecond):
+		t.Fatal("timed out before run stopped")
+	}
+
+	state, err := o.RunInfo(ctx, runID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if state.Status != run.RSCancelled {
+		t.Errorf("expected run status %q, got %q", run.RSCancelled, state.Status)
+	}
+
+	if err := o.CancelRun(ctx, runID); !errors.Is(err, ErrUnknownRun) {
+		t.Errorf("expected cancelling a finished run to error with %q, got: %v", ErrUnknownRun, err)
+	}
+}
+
+// blockingRunner runs until its context is cancelled
+type blockingRunner struct {
+	started chan string
+}
+
+func (r *blockingRunner) RunEphemeral(ctx context.Context, runID string, wf *workflow.Workflow, ds *dataset.Dataset, wait bool, params WorkflowRunParams) error {
+	r.started <- runID
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+func (r *blockingRunner) RunAndCommit(ctx context.Context, runID string, wf *workflow.Workflow, streams ioes.IOStreams, params WorkflowRunParams) error {
+	r.started <- runID
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+// concurrencyTrackingRunner records the maximum number of runs executing at
+// once
+type concurrencyTrackingRunner struct {
+	delay time.Duration
+	lk    sync.Mutex
+	cur   int
+	max   int
+	total int
+}
+
+func (r *concurrencyTrackingRunner) track() {
+	r.lk.Lock()
+	r.cur++
+	r.total++
+	if r.cur > r.max {
+		r.max = r.cur
+	}
+	r.lk.Unlock()
+
+	<-time.After(r.delay)
+
+	r.lk.Lock()
+	r.cur--
+	r.lk.Unlock()
+}
+
+func (r *concurrencyTrackingRunner) RunEphemeral(ctx context.Context, runID string, wf *workflow.Workflow, ds *dataset.Dataset, wait bool, params WorkflowRunParams) error {
+	r.track()
+	return nil
+}
+
+func (r *concurrencyTrackingRunner) RunAndCommit(ctx context.Context, runID string, wf *workflow.Workflow, streams ioes.IOStreams, params WorkflowRunParams) error {
+	r.track()
+	return nil
+}
+
 func errOnTimeout(t *testing.T, c chan string, errMsg string) <-chan struct{} {
 	done := make(chan struct{})
 	go func() {
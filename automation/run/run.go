@@ -61,6 +61,8 @@ const (
 	RSUnchanged = Status("unchanged")
 	// RSSkipped indicates a script/step was not executed
 	RSSkipped = Status("skipped")
+	// RSCancelled indicates a script was stopped by request before it completed
+	RSCancelled = Status("cancelled")
 )
 
 // State is a passable, cachable data structure that describes the execution of
@@ -127,7 +129,7 @@ func (rs *State) AddTransformEvent(e event.Event) error {
 		return nil
 	case event.ETTransformStop:
 		rs.StopTime = toTimePointer(e.Timestamp)
-		if tl, ok := e.Payload.(event.TransformLifecycle); ok {
+		if tl, ok := e.Payload.(event.TransformLifecycle); ok && rs.Status != RSCancelled {
 			rs.Status = Status(tl.Status)
 		}
 		if rs.StartTime != nil && rs.StopTime != nil {
@@ -168,9 +170,11 @@ func (rs *State) AddTransformEvent(e event.Event) error {
 		return nil
 	case event.ETTransformPrint,
 		event.ETTransformError,
+		event.ETTransformLog,
 		event.ETTransformDatasetPreview:
 		return rs.appendStepOutputLog(e)
 	case event.ETTransformCanceled:
+		rs.Status = RSCancelled
 		return nil
 	}
 	return fmt.Errorf("unexpected event type: %q", e.Type)
@@ -193,6 +197,62 @@ func (rs *State) appendStepOutputLog(e event.Event) error {
 	return nil
 }
 
+// LogEntry is a structured log line a transform script emitted during a run
+type LogEntry struct {
+	Step      string    `json:"step"`
+	Timestamp time.Time `json:"timestamp"`
+	event.TransformLog
+}
+
+// LogQuery filters the structured logs of a run
+type LogQuery struct {
+	// Lvl is the minimum level of entries to return. the empty level matches
+	// all entries
+	Lvl event.TransformMsgLvl
+	// Since & Until bound entry timestamps, inclusive. zero values don't bound
+	// the range
+	Since time.Time
+	Until time.Time
+}
+
+// logLvlRank orders log levels from least to most severe
+var logLvlRank = map[event.TransformMsgLvl]int{
+	event.TransformMsgLvlNone:  0,
+	event.TransformMsgLvlDebug: 1,
+	event.TransformMsgLvlInfo:  2,
+	event.TransformMsgLvlWarn:  3,
+	event.TransformMsgLvlError: 4,
+}
+
+// Logs returns the structured log entries recorded in the run that match q,
+// in the order they were emitted
+func (rs *State) Logs(q LogQuery) []LogEntry {
+	entries := []LogEntry{}
+	for _, step := range rs.Steps {
+		for _, e := range step.Output {
+			if e.Type != event.ETTransformLog {
+				continue
+			}
+			tl, ok := e.Payload.(event.TransformLog)
+			if !ok {
+				continue
+			}
+			ts := time.Unix(0, e.Timestamp)
+			if logLvlRank[tl.Lvl] < logLvlRank[q.Lvl] ||
+				(!q.Since.IsZero() && ts.Before(q.Since)) ||
+				(!q.Until.IsZero() && ts.After(q.Until)) {
+				continue
+			}
+			entries = append(entries, LogEntry{
+				Step:         step.Name,
+				Timestamp:    ts,
+				TransformLog: tl,
+			})
+		}
+	}
+	return entries
+}
+
 // StepState describes the execution of a transform step
 type StepState struct {
 	Name      string        `json:"name"`
@@ -284,6 +344,12 @@ func (ss *StepState) UnmarshalJSON(data []byte) error {
 				return err
 			}
 			e.Payload = p
+		case event.ETTransformLog:
+			p := event.TransformLog{}
+			if err := json.Unmarshal(re.Payload, &p); err != nil {
+				return err
+			}
+			e.Payload = p
 		case event.ETTransformDatasetPreview:
 			e.Payload = &dataset.Dataset{}
 			if err := json.Unmarshal(re.Payload, e.Payload); err != nil {
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/google/go-cmp/cmp"
 	"github.com/qri-io/qri/event"
@@ -170,3 +171,44 @@ func TestRunStateMarshaling(t *testing.T) {
 		})
 	}
 }
+
+func TestStateLogs(t *testing.T) {
+	runID := NewID()
+	rs := NewState(runID)
+	events := []event.Event{
+		{Type: event.ETTransformStart, Timestamp: 1000, SessionID: runID, Payload: event.TransformLifecycle{}},
+		{Type: event.ETTransformStepStart, Timestamp: 2000, SessionID: runID, Payload: event.TransformStepLifecycle{Name: "download"}},
+		{Type: event.ETTransformPrint, Timestamp: 3000, SessionID: runID, Payload: event.TransformMessage{Msg: "not a log line"}},
+		{Type: event.ETTransformLog, Timestamp: 4000, SessionID: runID, Payload: event.TransformLog{Lvl: event.TransformMsgLvlDebug, Msg: "fetching"}},
+		{Type: event.ETTransformStepStop, Timestamp: 5000, SessionID: runID, Payload: event.TransformStepLifecycle{Name: "download", Status: "succeeded"}},
+		{Type: event.ETTransformStepStart, Timestamp: 6000, SessionID: runID, Payload: event.TransformStepLifecycle{Name: "transform"}},
+		{Type: event.ETTransformLog, Timestamp: 7000, SessionID: runID, Payload: event.TransformLog{Lvl: event.TransformMsgLvlWarn, Msg: "missing rows", Fields: map[string]interface{}{"count": int64(2)}}},
+	}
+	for _, e := range events {
+		if err := rs.AddTransformEvent(e); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	debug := LogEntry{Step: "download", Timestamp: time.Unix(0, 4000), TransformLog: event.TransformLog{Lvl: event.TransformMsgLvlDebug, Msg: "fetching"}}
+	warn := LogEntry{Step: "transform", Timestamp: time.Unix(0, 7000), TransformLog: event.TransformLog{Lvl: event.TransformMsgLvlWarn, Msg: "missing rows", Fields: map[string]interface{}{"count": int64(2)}}}
+
+	cases := []struct {
+		description string
+		q           LogQuery
+		expect      []LogEntry
+	}{
+		{"all entries", LogQuery{}, []LogEntry{debug, warn}},
+		{"minimum level", LogQuery{Lvl: event.TransformMsgLvlInfo}, []LogEntry{warn}},
+		{"since", LogQuery{Since: time.Unix(0, 5000)}, []LogEntry{warn}},
+		{"until", LogQuery{Until: time.Unix(0, 4000)}, []LogEntry{debug}},
+		{"no matches", LogQuery{Lvl: event.TransformMsgLvlError}, []LogEntry{}},
+	}
+	for _, c := range cases {
+		t.Run(c.description, func(t *testing.T) {
+			if diff := cmp.Diff(c.expect, rs.Logs(c.q)); diff != "" {
+				t.Errorf("result mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
@@ -13,6 +13,11 @@ import (
 var (
 	// ErrEmptyQueue indicates that the queue is empty
 	ErrEmptyQueue = fmt.Errorf("empty queue")
+	// ErrUnknownRun indicates the given runID is not queued or running
+	ErrUnknownRun = fmt.Errorf("unknown run, it may have already finished")
+	// ErrRunQueueFull indicates a run was rejected because the maximum number of
+	// runs are already waiting to execute
+	ErrRunQueueFull = fmt.Errorf("run queue is full")
 )
 
 type runQueueFunc func(context.Context) error
@@ -20,8 +25,11 @@ type runQueueFunc func(context.Context) error
 // RunQueue queues runs and apply transforms & allows you to cancel runs and apply transforms
 type RunQueue interface {
 	Push(ctx context.Context, ownerID string, runID string, mode string, f runQueueFunc) error
+	PushIfRoom(ctx context.Context, ownerID string, runID string, mode string, max int, f runQueueFunc) error
 	Pop(ctx context.Context) (*runQueueInfo, error)
 	Cancel(runID string) error
+	Done(runID string)
+	Len() int
 	Shutdown() error
 }
 
@@ -38,7 +46,6 @@ type runQueue struct {
 	pub        event.Publisher
 	cancels    map[string]context.CancelFunc
 	clk        sync.Mutex
-	cancelCh   chan string
 	closeQueue context.CancelFunc
 }
 
@@ -56,7 +63,6 @@ func NewRunQueue(ctx context.Context, pub event.Publisher, interval time.Duratio
 		pub:        pub,
 		cancels:    map[string]context.CancelFunc{},
 		clk:        sync.Mutex{},
-		cancelCh:   make(chan string),
 		closeQueue: cancel,
 	}
 	if workers == 0 {
@@ -65,27 +71,9 @@ func NewRunQueue(ctx context.Context, pub event.Publisher, interval time.Duratio
 	for i := 0; i < workers; i++ {
 		go r.pollQueue(ctx, interval)
 	}
-	go r.listenForCancelations(ctx)
 	return r
 }
 
-func (r *runQueue) listenForCancelations(ctx context.Context) {
-	log.Debug("listening for run cancelations")
-	for {
-		select {
-		case cancelRunID := <-r.cancelCh:
-			r.clk.Lock()
-			if _, ok := r.cancels[cancelRunID]; !ok {
-				continue
-			}
-			r.cancels[cancelRunID]()
-			r.clk.Unlock()
-		case <-ctx.Done():
-			return
-		}
-	}
-}
-
 func (r *runQueue) addRunCancel(runID string, cancelFunc context.CancelFunc) {
 	r.clk.Lock()
 	defer r.clk.Unlock()
@@ -132,6 +120,24 @@ func (r *runQueue) pollQueue(ctx context.Context, interval time.Duration) {
 func (r *runQueue) Push(ctx context.Context, ownerID string, runID string, mode string, f runQueueFunc) error {
 	r.qlk.Lock()
 	defer r.qlk.Unlock()
+	r.push(ctx, ownerID, runID, mode, f)
+	return nil
+}
+
+// PushIfRoom adds a run to the queue if fewer than max runs are waiting,
+// returning ErrRunQueueFull otherwise. A max of zero means no limit
+func (r *runQueue) PushIfRoom(ctx context.Context, ownerID string, runID string, mode string, max int, f runQueueFunc) error {
+	r.qlk.Lock()
+	defer r.qlk.Unlock()
+	if max > 0 && len(r.queue) >= max {
+		return ErrRunQueueFull
+	}
+	r.push(ctx, ownerID, runID, mode, f)
+	return nil
+}
+
+// push appends a run to the queue, callers must hold the queue lock
+func (r *runQueue) push(ctx context.Context, ownerID string, runID string, mode string, f runQueueFunc) {
 	scopedCtx := profile.AddIDToContext(ctx, ownerID)
 	go func() {
 		switch mode {
@@ -152,7 +158,6 @@ func (r *runQueue) Push(ctx context.Context, ownerID string, runID string, mode
 		f:       f,
 	}
 	r.queue = append(r.queue, info)
-	return nil
 }
 
 func (r *runQueue) Pop(ctx context.Context) (*runQueueInfo, error) {
@@ -178,9 +183,39 @@ func (r *runQueue) Pop(ctx context.Context) (*runQueueInfo, error) {
 	return info, nil
 }
 
+// Len returns the number of runs waiting in the queue
+func (r *runQueue) Len() int {
+	r.qlk.Lock()
+	defer r.qlk.Unlock()
+	return len(r.queue)
+}
+
+// Cancel stops a running run, or removes a run that has yet to start from the
+// queue. It returns ErrUnknownRun if the runID is neither running nor queued
 func (r *runQueue) Cancel(runID string) error {
-	r.cancelCh <- runID
-	return nil
+	r.clk.Lock()
+	cancel, ok := r.cancels[runID]
+	r.clk.Unlock()
+	if ok {
+		cancel()
+		return nil
+	}
+
+	r.qlk.Lock()
+	defer r.qlk.Unlock()
+	for i, info := range r.queue {
+		if info.runID == runID {
+			r.queue = append(r.queue[:i], r.queue[i+1:]...)
+			return nil
+		}
+	}
+	return ErrUnknownRun
+}
+
+// Done marks a running run as finished. Runs can report they've finished
+// before their function returns, after which Cancel returns ErrUnknownRun
+func (r *runQueue) Done(runID string) {
+	r.removeRunCancel(runID)
 }
 
 func (r *runQueue) Shutdown() error {
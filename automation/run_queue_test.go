@@ -2,6 +2,9 @@ package automation
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"sync"
 	"testing"
 	"time"
 
@@ -87,3 +90,60 @@ func TestRunQueueCancel(t *testing.T) {
 		t.Errorf(gotMsg)
 	}
 }
+
+func TestRunQueueCancelUnknown(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	rq := NewRunQueue(ctx, event.NilBus, time.Hour, 1)
+
+	if err := rq.Cancel("unknown"); !errors.Is(err, ErrUnknownRun) {
+		t.Errorf("expected error %q, got: %v", ErrUnknownRun, err)
+	}
+
+	if err := rq.Push(ctx, "owner", "queued", "apply", func(ctx context.Context) error { return nil }); err != nil {
+		t.Fatal(err)
+	}
+	if err := rq.Cancel("queued"); err != nil {
+		t.Fatal(err)
+	}
+	if rq.Len() != 0 {
+		t.Errorf("expected cancelled run to be removed from the queue, queue length: %d", rq.Len())
+	}
+}
+
+func TestRunQueuePushIfRoomConcurrent(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	// poll infrequently enough that nothing is popped while pushing
+	rq := NewRunQueue(ctx, event.NilBus, time.Hour, 1)
+	f := func(ctx context.Context) error { return nil }
+
+	const max = 3
+	var (
+		wg   sync.WaitGroup
+		lk   sync.Mutex
+		full int
+	)
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			err := rq.PushIfRoom(ctx, "owner", fmt.Sprintf("run_%d", i), "apply", max, f)
+			if errors.Is(err, ErrRunQueueFull) {
+				lk.Lock()
+				full++
+				lk.Unlock()
+			} else if err != nil {
+				t.Error(err)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	if rq.Len() != max {
+		t.Errorf("expected %d queued runs, got: %d", max, rq.Len())
+	}
+	if full != 20-max {
+		t.Errorf("expected %d runs to be rejected, got: %d", 20-max, full)
+	}
+}
@@ -0,0 +1,160 @@
+package trigger
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/qri-io/qri/base/params"
+	"github.com/qri-io/qri/profile"
+)
+
+// Activation records a single firing of a trigger
+type Activation struct {
+	Timestamp  time.Time  `json:"timestamp"`
+	OwnerID    profile.ID `json:"ownerID"`
+	WorkflowID string     `json:"workflowID"`
+	TriggerID  string     `json:"triggerID"`
+	RunID      string     `json:"runID"`
+}
+
+// ActivationLog is an append-only record of trigger activations
+type ActivationLog interface {
+	// Append adds an activation to the end of the log
+	Append(ctx context.Context, a *Activation) error
+	// List returns the activations for the given workflow ID in reverse
+	// chronological order. An empty workflow ID lists activations across all
+	// workflows
+	List(ctx context.Context, workflowID string, lp params.List) ([]*Activation, error)
+}
+
+// MemActivationLog is an in-memory implementation of an ActivationLog
+type MemActivationLog struct {
+	mu          sync.Mutex
+	activations []*Activation
+}
+
+// compile-time assertion that MemActivationLog is an ActivationLog
+var _ ActivationLog = (*MemActivationLog)(nil)
+
+// NewMemActivationLog constructs an empty in-memory ActivationLog
+func NewMemActivationLog() *MemActivationLog {
+	return &MemActivationLog{}
+}
+
+// Append adds an activation to the end of the log
+func (l *MemActivationLog) Append(ctx context.Context, a *Activation) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.activations = append(l.activations, a)
+	return nil
+}
+
+// List returns the activations for the given workflow ID in reverse
+// chronological order
+func (l *MemActivationLog) List(ctx context.Context, workflowID string, lp params.List) ([]*Activation, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if lp.Limit == 0 {
+		return []*Activation{}, nil
+	}
+
+	res := []*Activation{}
+	for i := len(l.activations) - 1; i >= 0; i-- {
+		a := l.activations[i]
+		if workflowID != "" && a.WorkflowID != workflowID {
+			continue
+		}
+		res = append(res, a)
+	}
+
+	if lp.Offset >= len(res) {
+		return []*Activation{}, nil
+	}
+	res = res[lp.Offset:]
+	if lp.Limit > 0 && lp.Limit < len(res) {
+		res = res[:lp.Limit]
+	}
+	return res, nil
+}
+
+// fileActivationLog persists activations as newline-delimited JSON, appending
+// a line to the file for each activation
+type fileActivationLog struct {
+	path string
+	mem  *MemActivationLog
+}
+
+// compile-time assertion that fileActivationLog is an ActivationLog
+var _ ActivationLog = (*fileActivationLog)(nil)
+
+// NewFileActivationLog creates an ActivationLog that persists to a file in the
+// given repo path
+func NewFileActivationLog(repoPath string) (ActivationLog, error) {
+	l := &fileActivationLog{
+		path: filepath.Join(repoPath, "trigger_activations.jsonl"),
+		mem:  NewMemActivationLog(),
+	}
+	return l, l.loadFromFile()
+}
+
+func (l *fileActivationLog) loadFromFile() error {
+	f, err := os.Open(l.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	defer f.Close()
+
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		if len(sc.Bytes()) == 0 {
+			continue
+		}
+		a := &Activation{}
+		if err := json.Unmarshal(sc.Bytes(), a); err != nil {
+			log.Debugw("fileActivationLog deserializing activation", "error", err)
+			return err
+		}
+		l.mem.activations = append(l.mem.activations, a)
+	}
+	return sc.Err()
+}
+
+// Append writes the activation to the end of the log file
+func (l *fileActivationLog) Append(ctx context.Context, a *Activation) error {
+	data, err := json.Marshal(a)
+	if err != nil {
+		return err
+	}
+
+	l.mem.mu.Lock()
+	defer l.mem.mu.Unlock()
+
+	f, err := os.OpenFile(l.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(append(data, '\n')); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	l.mem.activations = append(l.mem.activations, a)
+	return nil
+}
+
+// List returns the activations for the given workflow ID in reverse
+// chronological order
+func (l *fileActivationLog) List(ctx context.Context, workflowID string, lp params.List) ([]*Activation, error) {
+	return l.mem.List(ctx, workflowID, lp)
+}
@@ -0,0 +1,62 @@
+package trigger_test
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/qri-io/qri/automation/trigger"
+	"github.com/qri-io/qri/base/params"
+	"github.com/qri-io/qri/profile"
+)
+
+func TestFileActivationLog(t *testing.T) {
+	ctx := context.Background()
+	tmpdir, err := ioutil.TempDir("", "trigger_activations")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpdir)
+
+	al, err := trigger.NewFileActivationLog(tmpdir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	owner := profile.IDB58MustDecode("QmTwtwLMKHHKCrugNxyAaZ31nhBqRUQVysT2xK911n4m6F")
+	now := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+	entries := []*trigger.Activation{
+		{Timestamp: now, OwnerID: owner, WorkflowID: "wf_a", TriggerID: "trig_a", RunID: "run_1"},
+		{Timestamp: now.Add(time.Minute), OwnerID: owner, WorkflowID: "wf_b", TriggerID: "trig_b", RunID: "run_2"},
+		{Timestamp: now.Add(time.Hour), OwnerID: owner, WorkflowID: "wf_a", TriggerID: "trig_a", RunID: "run_3"},
+	}
+	for _, a := range entries {
+		if err := al.Append(ctx, a); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// reload from disk
+	al, err = trigger.NewFileActivationLog(tmpdir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := al.List(ctx, "wf_a", params.ListAll)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expect := []*trigger.Activation{entries[2], entries[0]}
+	if diff := cmp.Diff(expect, got); diff != "" {
+		t.Errorf("activations mismatch (-want +got):\n%s", diff)
+	}
+
+	got, err = al.List(ctx, "", params.List{Limit: 1, Offset: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff([]*trigger.Activation{entries[1]}, got); diff != "" {
+		t.Errorf("paginated activations mismatch (-want +got):\n%s", diff)
+	}
+}
@@ -4,6 +4,8 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"sync"
+	"time"
 
 	"github.com/qri-io/qri/event"
 )
@@ -125,12 +127,28 @@ func (rt *RuntimeTrigger) UnmarshalJSON(d []byte) error {
 	return nil
 }
 
+// RuntimeListenerOptions configures the behaviour of a RuntimeListener
+type RuntimeListenerOptions struct {
+	// DebounceWindow coalesces trigger events for the same workflow that arrive
+	// within the window into a single trigger. The first trigger in the window
+	// fires, subsequent triggers are dropped. A zero value disables debouncing
+	DebounceWindow time.Duration
+	// SkipIfRunning drops trigger events for a workflow that has a run in
+	// progress
+	SkipIfRunning bool
+}
+
 // RuntimeListener listens for RuntimeTriggers to fire
 type RuntimeListener struct {
 	bus       event.Bus
 	TriggerCh chan event.WorkflowTriggerEvent
 	listening bool
 	triggers  *Set
+	opts      RuntimeListenerOptions
+
+	lk        sync.Mutex
+	lastFired map[string]time.Time
+	running   map[string]int
 }
 
 var _ Listener = (*RuntimeListener)(nil)
@@ -138,11 +156,24 @@ var _ Listener = (*RuntimeListener)(nil)
 // NewRuntimeListener creates a RuntimeListener, and begin receiving on the
 // trigger channel. Any triggers received before the RuntimeListener has been
 // started using `runtimeListener.Start(ctx)` will be ignored
-func NewRuntimeListener(ctx context.Context, bus event.Bus) *RuntimeListener {
+func NewRuntimeListener(ctx context.Context, bus event.Bus, opts ...func(o *RuntimeListenerOptions)) *RuntimeListener {
+	o := RuntimeListenerOptions{}
+	for _, opt := range opts {
+		opt(&o)
+	}
 	rl := &RuntimeListener{
 		bus:       bus,
 		TriggerCh: make(chan event.WorkflowTriggerEvent),
 		triggers:  NewSet(RuntimeType, NewRuntimeTrigger),
+		opts:      o,
+		lastFired: map[string]time.Time{},
+		running:   map[string]int{},
+	}
+	if o.SkipIfRunning {
+		bus.SubscribeTypes(rl.handleRunEvent,
+			event.ETAutomationWorkflowStarted,
+			event.ETAutomationWorkflowStopped,
+		)
 	}
 	// start ensures that if a RuntimeTrigger attempts to trigger a workflow,
 	// but the RuntimeListener has not been told to start listening for
@@ -181,6 +212,10 @@ func (l *RuntimeListener) start(ctx context.Context) error {
 					log.Debugf("RuntimeListener error: %s", err)
 					continue
 				}
+				if l.suppress(wtp) {
+					log.Debugw("RuntimeListener: trigger coalesced", "workflowID", wtp.WorkflowID, "triggerID", wtp.TriggerID)
+					continue
+				}
 
 				err := l.bus.Publish(ctx, event.ETAutomationWorkflowTrigger, wtp)
 				if err != nil {
@@ -213,6 +248,50 @@ func (l *RuntimeListener) shouldTrigger(ctx context.Context, wtp event.WorkflowT
 	return ErrNotFound
 }
 
+// suppress reports whether a trigger event should be dropped, either because
+// the workflow fired within the debounce window or because a run of the
+// workflow is in progress. When the event is not suppressed it is recorded as
+// the most recent firing for the workflow
+func (l *RuntimeListener) suppress(wtp event.WorkflowTriggerEvent) bool {
+	l.lk.Lock()
+	defer l.lk.Unlock()
+
+	if l.opts.SkipIfRunning && l.running[wtp.WorkflowID] > 0 {
+		return true
+	}
+	now := NowFunc()
+	if l.opts.DebounceWindow > 0 {
+		if last, ok := l.lastFired[wtp.WorkflowID]; ok && now.Sub(last) < l.opts.DebounceWindow {
+			return true
+		}
+	}
+	l.lastFired[wtp.WorkflowID] = now
+	return false
+}
+
+// handleRunEvent keeps track of the workflows that currently have a run in
+// progress
+func (l *RuntimeListener) handleRunEvent(ctx context.Context, e event.Event) error {
+	l.lk.Lock()
+	defer l.lk.Unlock()
+
+	switch e.Type {
+	case event.ETAutomationWorkflowStarted:
+		if p, ok := e.Payload.(event.WorkflowStartedEvent); ok {
+			l.running[p.WorkflowID]++
+		}
+	case event.ETAutomationWorkflowStopped:
+		if p, ok := e.Payload.(event.WorkflowStoppedEvent); ok {
+			if l.running[p.WorkflowID] <= 1 {
+				delete(l.running, p.WorkflowID)
+			} else {
+				l.running[p.WorkflowID]--
+			}
+		}
+	}
+	return nil
+}
+
 // Start tells the RuntimeListener to begin actively listening for RuntimeTriggers
 func (l *RuntimeListener) Start(ctx context.Context) error {
 	l.listening = true
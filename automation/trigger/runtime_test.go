@@ -3,6 +3,7 @@ package trigger_test
 import (
 	"context"
 	"testing"
+	"time"
 
 	"github.com/qri-io/qri/automation/spec"
 	"github.com/qri-io/qri/automation/trigger"
@@ -157,3 +158,112 @@ func TestRuntimeListenerListen(t *testing.T) {
 		t.Fatal("Listen did not remove wfA1 when wfA1 had no triggers")
 	}
 }
+
+func TestRuntimeListenerDebounce(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	bus := event.NewBus(ctx)
+
+	triggered := make(chan event.WorkflowTriggerEvent, 3)
+	bus.SubscribeTypes(func(ctx context.Context, e event.Event) error {
+		if wte, ok := e.Payload.(event.WorkflowTriggerEvent); ok {
+			triggered <- wte
+		}
+		return nil
+	}, event.ETAutomationWorkflowTrigger)
+
+	rl := trigger.NewRuntimeListener(ctx, bus, func(o *trigger.RuntimeListenerOptions) {
+		o.DebounceWindow = time.Minute
+	})
+	rt := trigger.NewEmptyRuntimeTrigger()
+	rt.SetActive(true)
+	wf := &workflow.Workflow{
+		ID:       workflow.ID("debounce workflow"),
+		OwnerID:  profile.ID("debounce owner"),
+		Active:   true,
+		Triggers: []map[string]interface{}{rt.ToMap()},
+	}
+	if err := rl.Listen(wf); err != nil {
+		t.Fatal(err)
+	}
+	if err := rl.Start(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	wte := event.WorkflowTriggerEvent{
+		OwnerID:    wf.OwnerID,
+		WorkflowID: wf.ID.String(),
+		TriggerID:  rt.ID(),
+	}
+	for i := 0; i < 3; i++ {
+		rl.TriggerCh <- wte
+	}
+
+	select {
+	case <-triggered:
+	case <-time.After(time.Millisecond * 500):
+		t.Fatal("expected listener to emit a trigger event")
+	}
+	select {
+	case <-triggered:
+		t.Fatal("expected rapid triggers to be coalesced into a single trigger event")
+	case <-time.After(time.Millisecond * 100):
+	}
+}
+
+func TestRuntimeListenerSkipIfRunning(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	bus := event.NewBus(ctx)
+
+	triggered := make(chan event.WorkflowTriggerEvent, 3)
+	bus.SubscribeTypes(func(ctx context.Context, e event.Event) error {
+		if wte, ok := e.Payload.(event.WorkflowTriggerEvent); ok {
+			triggered <- wte
+		}
+		return nil
+	}, event.ETAutomationWorkflowTrigger)
+
+	rl := trigger.NewRuntimeListener(ctx, bus, func(o *trigger.RuntimeListenerOptions) {
+		o.SkipIfRunning = true
+	})
+	rt := trigger.NewEmptyRuntimeTrigger()
+	rt.SetActive(true)
+	wf := &workflow.Workflow{
+		ID:       workflow.ID("running workflow"),
+		OwnerID:  profile.ID("running owner"),
+		Active:   true,
+		Triggers: []map[string]interface{}{rt.ToMap()},
+	}
+	if err := rl.Listen(wf); err != nil {
+		t.Fatal(err)
+	}
+	if err := rl.Start(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	wte := event.WorkflowTriggerEvent{
+		OwnerID:    wf.OwnerID,
+		WorkflowID: wf.ID.String(),
+		TriggerID:  rt.ID(),
+	}
+	if err := bus.Publish(ctx, event.ETAutomationWorkflowStarted, event.WorkflowStartedEvent{WorkflowID: wf.ID.String()}); err != nil {
+		t.Fatal(err)
+	}
+	rl.TriggerCh <- wte
+	select {
+	case <-triggered:
+		t.Fatal("expected trigger to be skipped while a run is in progress")
+	case <-time.After(time.Millisecond * 100):
+	}
+
+	if err := bus.Publish(ctx, event.ETAutomationWorkflowStopped, event.WorkflowStoppedEvent{WorkflowID: wf.ID.String()}); err != nil {
+		t.Fatal(err)
+	}
+	rl.TriggerCh <- wte
+	select {
+	case <-triggered:
+	case <-time.After(time.Millisecond * 500):
+		t.Fatal("expected listener to emit a trigger event once the run has stopped")
+	}
+}
@@ -0,0 +1,50 @@
+package archive
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+
+	cid "github.com/ipfs/go-cid"
+	car "github.com/ipld/go-car"
+)
+
+// VerifyCAR checks data is a CAR file with a single root, where every block
+// hashes to its CID and the root block is present, returning the root CID.
+// Checking doesn't store any blocks, so a CAR file can be verified before it's
+// imported
+func VerifyCAR(data []byte) (cid.Cid, error) {
+	rdr, err := car.NewCarReader(bytes.NewReader(data))
+	if err != nil {
+		return cid.Cid{}, fmt.Errorf("%w: not a zip, tar or CAR file", ErrMalformedPackage)
+	}
+	if len(rdr.Header.Roots) != 1 {
+		return cid.Cid{}, fmt.Errorf("%w: CAR file must have exactly one root, got %d", ErrMalformedPackage, len(rdr.Header.Roots))
+	}
+	root := rdr.Header.Roots[0]
+
+	hasRoot := false
+	for {
+		blk, err := rdr.Next()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return cid.Cid{}, fmt.Errorf("%w: reading CAR block: %s", ErrMalformedPackage, err)
+		}
+		id := blk.Cid()
+		sum, err := id.Prefix().Sum(blk.RawData())
+		if err != nil {
+			return cid.Cid{}, fmt.Errorf("%w: hashing CAR block %s: %s", ErrMalformedPackage, id, err)
+		}
+		if !sum.Equals(id) {
+			return cid.Cid{}, fmt.Errorf("%w: CAR block data doesn't match CID %s", ErrMalformedPackage, id)
+		}
+		if id.Equals(root) {
+			hasRoot = true
+		}
+	}
+	if !hasRoot {
+		return cid.Cid{}, fmt.Errorf("%w: CAR file is missing root block %s", ErrMalformedPackage, root)
+	}
+	return root, nil
+}
@@ -0,0 +1,62 @@
+package archive
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	cid "github.com/ipfs/go-cid"
+	merkledag "github.com/ipfs/go-merkledag"
+	car "github.com/ipld/go-car"
+	carutil "github.com/ipld/go-car/util"
+)
+
+func TestVerifyCAR(t *testing.T) {
+	root := merkledag.NewRawNode([]byte("root"))
+	leaf := merkledag.NewRawNode([]byte("leaf"))
+
+	writeCAR := func(roots []cid.Cid, blocks ...[][]byte) []byte {
+		buf := &bytes.Buffer{}
+		if err := car.WriteHeader(&car.CarHeader{Roots: roots, Version: 1}, buf); err != nil {
+			t.Fatal(err)
+		}
+		for _, blk := range blocks {
+			if err := carutil.LdWrite(buf, blk...); err != nil {
+				t.Fatal(err)
+			}
+		}
+		return buf.Bytes()
+	}
+
+	data := writeCAR([]cid.Cid{root.Cid()},
+		[][]byte{root.Cid().Bytes(), root.RawData()},
+		[][]byte{leaf.Cid().Bytes(), leaf.RawData()},
+	)
+	got, err := VerifyCAR(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !got.Equals(root.Cid()) {
+		t.Errorf("expected root %s, got: %s", root.Cid(), got)
+	}
+
+	bad := map[string][]byte{
+		"not a CAR file": []byte("not a CAR file"),
+		"corrupted block": writeCAR([]cid.Cid{root.Cid()},
+			[][]byte{root.Cid().Bytes(), root.RawData()},
+			[][]byte{leaf.Cid().Bytes(), []byte("not the leaf")},
+		),
+		"missing root": writeCAR([]cid.Cid{root.Cid()},
+			[][]byte{leaf.Cid().Bytes(), leaf.RawData()},
+		),
+		"multiple roots": writeCAR([]cid.Cid{root.Cid(), leaf.Cid()},
+			[][]byte{root.Cid().Bytes(), root.RawData()},
+			[][]byte{leaf.Cid().Bytes(), leaf.RawData()},
+		),
+	}
+	for name, data := range bad {
+		if _, err := VerifyCAR(data); !errors.Is(err, ErrMalformedPackage) {
+			t.Errorf("%s: expected error %q, got: %v", name, ErrMalformedPackage, err)
+		}
+	}
+}
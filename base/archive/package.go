@@ -0,0 +1,351 @@
+package archive
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strings"
+
+	"github.com/qri-io/dataset"
+	"github.com/qri-io/qfs"
+	"github.com/qri-io/qri/base/component"
+	"github.com/qri-io/qri/base/dsfs"
+)
+
+const (
+	// PackageFormatZip writes a dataset package as a zip archive
+	PackageFormatZip = "zip"
+	// PackageFormatTar writes a dataset package as an uncompressed tar archive
+	PackageFormatTar = "tar"
+)
+
+var (
+	// ErrMalformedPackage indicates an archive isn't a valid dataset package
+	ErrMalformedPackage = errors.New("malformed dataset package")
+	// ErrPackageTooLarge indicates the files of an archive unpack to more than
+	// MaxPackageSize bytes
+	ErrPackageTooLarge = errors.New("dataset package is too large")
+
+	// MaxPackageSize is the largest combined size in bytes of the files
+	// ReadPackage will unpack from an archive. Compressed archives can unpack
+	// to many times their own size
+	MaxPackageSize int64 = 1 << 30
+)
+
+// packageEntry is a single stored file of a dataset package
+type packageEntry struct {
+	component string
+	name      string
+	path      string
+	// size of the file in bytes, -1 if unknown
+	size int64
+}
+
+// WritePackage writes the stored files of a dataset version to w as a zip or
+// tar archive. Files are named according to the dsfs.PackageFile layout, with
+// the body named for its format, eg: "body.csv". components limits the
+// archive to files for the named components, eg: "meta", "body". An empty
+// list writes every component. dataset.json is always included
+func WritePackage(ctx context.Context, fs qfs.Filesystem, ds *dataset.Dataset, format string, components []string, w io.Writer) error {
+	if ds == nil || ds.Path == "" {
+		return fmt.Errorf("dataset has no stored path")
+	}
+	selected, err := selectComponents(components)
+	if err != nil {
+		return err
+	}
+
+	var add func(ent packageEntry, f qfs.File) error
+	var done func() error
+	switch format {
+	case PackageFormatZip:
+		zw := zip.NewWriter(w)
+		add = func(ent packageEntry, f qfs.File) error {
+			fw, err := zw.Create(ent.name)
+			if err != nil {
+				return err
+			}
+			_, err = io.Copy(fw, f)
+			return err
+		}
+		done = zw.Close
+	case PackageFormatTar:
+		tw := tar.NewWriter(w)
+		ts := dsfs.Timestamp()
+		if ds.Commit != nil && !ds.Commit.Timestamp.IsZero() {
+			ts = ds.Commit.Timestamp
+		}
+		add = func(ent packageEntry, f qfs.File) error {
+			// tar headers lead with the file size. files of unknown size are
+			// buffered to measure them
+			var r io.Reader = f
+			size := ent.size
+			if size < 0 {
+				if sf, ok := f.(qfs.SizeFile); ok {
+					size = sf.Size()
+				}
+			}
+			if size < 0 {
+				data, err := ioutil.ReadAll(f)
+				if err != nil {
+					return err
+				}
+				r = bytes.NewReader(data)
+				size = int64(len(data))
+			}
+			hdr := &tar.Header{Name: ent.name, Mode: 0644, Size: size, ModTime: ts}
+			if err := tw.WriteHeader(hdr); err != nil {
+				return err
+			}
+			_, err := io.Copy(tw, r)
+			return err
+		}
+		done = tw.Close
+	default:
+		return fmt.Errorf("unsupported package format %q, must be one of %q or %q", format, PackageFormatZip, PackageFormatTar)
+	}
+
+	bodyName := ""
+	if ds.Structure != nil {
+		bodyName = ds.Structure.BodyFilename()
+	}
+	for _, ent := range packageEntries(fs, ds, bodyName) {
+		if selected != nil && ent.component != "dataset" && !selected[ent.component] {
+			continue
+		}
+		f, err := fs.Get(ctx, ent.path)
+		if err != nil {
+			return fmt.Errorf("getting %s: %w", ent.name, err)
+		}
+		err = add(ent, f)
+		f.Close()
+		if err != nil {
+			return fmt.Errorf("writing %s: %w", ent.name, err)
+		}
+	}
+	return done()
+}
+
+// selectComponents validates a list of component names, returning them as a
+// set. An empty list returns a nil set, selecting everything
+func selectComponents(components []string) (map[string]bool, error) {
+	if len(components) == 0 {
+		return nil, nil
+	}
+	valid := map[string]bool{"stats": true}
+	for _, name := range component.AllSubcomponentNames() {
+		valid[name] = true
+	}
+	selected := map[string]bool{}
+	for _, name := range components {
+		if !valid[name] {
+			return nil, fmt.Errorf("unknown component %q", name)
+		}
+		selected[name] = true
+	}
+	return selected, nil
+}
+
+// packageEntries lists the stored files of a dataset in package order.
+// bodyName is the filename of the body, which depends on the body format
+func packageEntries(fs qfs.Filesystem, ds *dataset.Dataset, bodyName string) []packageEntry {
+	ents := []packageEntry{
+		{"dataset", dsfs.PackageFileDataset.String(), dsfs.PackageFilepath(fs, ds.Path, dsfs.PackageFileDataset), -1},
+	}
+	add := func(component string, pf dsfs.PackageFile, path string) {
+		if path != "" {
+			ents = append(ents, packageEntry{component, pf.String(), path, -1})
+		}
+	}
+
+	if ds.Structure != nil {
+		add("structure", dsfs.PackageFileStructure, ds.Structure.Path)
+	}
+	if ds.Commit != nil {
+		add("commit", dsfs.PackageFileCommit, ds.Commit.Path)
+	}
+	if ds.Transform != nil {
+		add("transform", dsfs.PackageFileTransform, ds.Transform.Path)
+		if ds.Transform.ScriptPath != "" {
+			ents = append(ents, packageEntry{"transform", "transform_script", ds.Transform.ScriptPath, -1})
+		}
+	}
+	if ds.Meta != nil {
+		add("meta", dsfs.PackageFileMeta, ds.Meta.Path)
+	}
+	if ds.Viz != nil {
+		add("viz", dsfs.PackageFileViz, ds.Viz.Path)
+		add("viz", dsfs.PackageFileVizScript, ds.Viz.ScriptPath)
+		add("viz", dsfs.PackageFileRenderedViz, ds.Viz.RenderedPath)
+	}
+	if ds.Readme != nil {
+		add("readme", dsfs.PackageFileReadme, ds.Readme.Path)
+		add("readme", dsfs.PackageFileReadmeScript, ds.Readme.ScriptPath)
+		add("readme", dsfs.PackageFileRenderedReadme, ds.Readme.RenderedPath)
+	}
+	if ds.Stats != nil {
+		add("stats", dsfs.PackageFileStats, ds.Stats.Path)
+	}
+	if ds.BodyPath != "" && bodyName != "" {
+		// structure length records the body size in bytes
+		size := int64(-1)
+		if ds.Structure.Length > 0 {
+			size = int64(ds.Structure.Length)
+		}
+		ents = append(ents, packageEntry{"body", bodyName, ds.BodyPath, size})
+	}
+	return ents
+}
+
+// IsPackageArchive reports whether data looks like a zip or tar archive
+func IsPackageArchive(data []byte) bool {
+	return isZip(data) || isTar(data)
+}
+
+func isZip(data []byte) bool {
+	return bytes.HasPrefix(data, []byte("PK\x03\x04"))
+}
+
+func isTar(data []byte) bool {
+	return len(data) > 262 && string(data[257:262]) == "ustar"
+}
+
+// ReadPackage reads the files of a zip or tar archive written by
+// WritePackage, rejecting archives that don't hold a flat list of package
+// files including dataset.json, or that unpack to more than MaxPackageSize
+// bytes
+func ReadPackage(data []byte) (map[string][]byte, error) {
+	files := map[string][]byte{}
+	remaining := MaxPackageSize
+	add := func(name string, size int64, r io.Reader) error {
+		if strings.ContainsAny(name, "/\\") {
+			return fmt.Errorf("%w: unexpected directory entry %q", ErrMalformedPackage, name)
+		}
+		if _, exists := files[name]; exists {
+			return fmt.Errorf("%w: duplicate entry %q", ErrMalformedPackage, name)
+		}
+		// check the size the archive reports before reading, and limit reads
+		// in case the reported size is wrong
+		if size > remaining {
+			return fmt.Errorf("%w: exceeds %d bytes", ErrPackageTooLarge, MaxPackageSize)
+		}
+		data, err := ioutil.ReadAll(io.LimitReader(r, remaining+1))
+		if err != nil {
+			return fmt.Errorf("%w: reading %q: %s", ErrMalformedPackage, name, err)
+		}
+		if int64(len(data)) > remaining {
+			return fmt.Errorf("%w: exceeds %d bytes", ErrPackageTooLarge, MaxPackageSize)
+		}
+		remaining -= int64(len(data))
+		files[name] = data
+		return nil
+	}
+
+	switch {
+	case isZip(data):
+		zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s", ErrMalformedPackage, err)
+		}
+		for _, f := range zr.File {
+			rc, err := f.Open()
+			if err != nil {
+				return nil, fmt.Errorf("%w: opening %q: %s", ErrMalformedPackage, f.Name, err)
+			}
+			err = add(f.Name, int64(f.UncompressedSize64), rc)
+			rc.Close()
+			if err != nil {
+				return nil, err
+			}
+		}
+	case isTar(data):
+		tr := tar.NewReader(bytes.NewReader(data))
+		for {
+			hdr, err := tr.Next()
+			if err == io.EOF {
+				break
+			} else if err != nil {
+				return nil, fmt.Errorf("%w: %s", ErrMalformedPackage, err)
+			}
+			if err := add(hdr.Name, hdr.Size, tr); err != nil {
+				return nil, err
+			}
+		}
+	default:
+		return nil, fmt.Errorf("%w: not a zip or tar archive", ErrMalformedPackage)
+	}
+
+	if _, ok := files[dsfs.PackageFileDataset.String()]; !ok {
+		return nil, fmt.Errorf("%w: missing required file %s", ErrMalformedPackage, dsfs.PackageFileDataset)
+	}
+	return files, nil
+}
+
+// StorePackage writes the files of a package read by ReadPackage to dst,
+// returning the path of the stored dataset. Every component dataset.json
+// references must be in the package, and match the path it's referenced by.
+// Files are checked before any are stored, so a package that fails to verify
+// leaves dst unchanged. Packages are content-addressed, so they can only be
+// stored in the same type of filesystem they were exported from
+func StorePackage(ctx context.Context, dst qfs.Filesystem, files map[string][]byte) (string, error) {
+	store, ok := dst.(qfs.MerkleDagStore)
+	if !ok {
+		return "", fmt.Errorf("destination must be a MerkleDagStore")
+	}
+
+	refs, err := dataset.UnmarshalDataset(files[dsfs.PackageFileDataset.String()])
+	if err != nil {
+		return "", fmt.Errorf("%w: %s: %s", ErrMalformedPackage, dsfs.PackageFileDataset, err)
+	}
+	bodyName := ""
+	for name := range files {
+		if strings.HasPrefix(name, "body.") {
+			bodyName = name
+		}
+	}
+
+	expect := map[string]string{dsfs.PackageFileDataset.String(): ""}
+	for _, ent := range packageEntries(dst, refs, bodyName)[1:] {
+		expect[ent.name] = dsfs.GetHashBase(ent.path)
+		if _, ok := files[ent.name]; !ok {
+			return "", fmt.Errorf("%w: missing %s, referenced by %s", ErrMalformedPackage, ent.name, dsfs.PackageFileDataset)
+		}
+	}
+
+	for name, data := range files {
+		hash, ok := expect[name]
+		if !ok {
+			return "", fmt.Errorf("%w: unexpected file %q", ErrMalformedPackage, name)
+		}
+		if hash == "" {
+			continue
+		}
+		path, err := dsfs.ComputeFilePath(ctx, store, data)
+		if err != nil {
+			return "", err
+		}
+		if dsfs.GetHashBase(path) != hash {
+			return "", fmt.Errorf("%w: %s doesn't match its reference in %s", ErrMalformedPackage, name, dsfs.PackageFileDataset)
+		}
+	}
+
+	added := qfs.NewLinks()
+	for name, data := range files {
+		res, err := store.PutFile(dsfs.NewMemfileBytes(name, data))
+		if err != nil {
+			return "", err
+		}
+		added.Add(res.ToLink(name, true))
+	}
+
+	res, err := store.PutNode(added)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("/%s/%s", dst.Type(), res.Cid.String()), nil
+}
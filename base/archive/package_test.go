@@ -0,0 +1,84 @@
+package archive
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/qri-io/qfs"
+	"github.com/qri-io/qri/base/dsfs"
+)
+
+func TestReadPackageSizeLimit(t *testing.T) {
+	ctx := context.Background()
+	fs, names, err := testFS()
+	if err != nil {
+		t.Fatal(err)
+	}
+	ds, err := dsfs.LoadDataset(ctx, fs, names["movies"])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, format := range []string{PackageFormatZip, PackageFormatTar} {
+		buf := &bytes.Buffer{}
+		if err := WritePackage(ctx, fs, ds, format, nil, buf); err != nil {
+			t.Fatal(err)
+		}
+		files, err := ReadPackage(buf.Bytes())
+		if err != nil {
+			t.Fatalf("%s: %s", format, err)
+		}
+		size := int64(0)
+		for _, data := range files {
+			size += int64(len(data))
+		}
+
+		prev := MaxPackageSize
+		MaxPackageSize = size - 1
+		_, err = ReadPackage(buf.Bytes())
+		MaxPackageSize = prev
+		if !errors.Is(err, ErrPackageTooLarge) {
+			t.Errorf("%s: expected a package unpacking past the limit to fail with %q, got: %v", format, ErrPackageTooLarge, err)
+		}
+	}
+}
+
+func TestStorePackageVerifiesBeforeStoring(t *testing.T) {
+	ctx := context.Background()
+	fs, names, err := testFS()
+	if err != nil {
+		t.Fatal(err)
+	}
+	ds, err := dsfs.LoadDataset(ctx, fs, names["movies"])
+	if err != nil {
+		t.Fatal(err)
+	}
+	buf := &bytes.Buffer{}
+	if err := WritePackage(ctx, fs, ds, PackageFormatZip, nil, buf); err != nil {
+		t.Fatal(err)
+	}
+	files, err := ReadPackage(buf.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dst := qfs.NewMemFS()
+	path, err := StorePackage(ctx, dst, files)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if path != names["movies"] {
+		t.Errorf("expected stored path to match exported path %q, got: %q", names["movies"], path)
+	}
+
+	files["body.csv"] = []byte("movie\nnot the body that was exported")
+	dst = qfs.NewMemFS()
+	if _, err := StorePackage(ctx, dst, files); !errors.Is(err, ErrMalformedPackage) {
+		t.Errorf("expected a tampered package to fail with %q, got: %v", ErrMalformedPackage, err)
+	}
+	if len(dst.Files) != 0 {
+		t.Errorf("expected a package that fails to verify not to store any files, got %d", len(dst.Files))
+	}
+}
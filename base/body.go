@@ -4,10 +4,13 @@ import (
 	"bytes"
 	"encoding/json"
 	"fmt"
+	"io"
 	"io/ioutil"
+	"strconv"
 
 	"github.com/qri-io/dataset"
 	"github.com/qri-io/dataset/dsio"
+	"github.com/qri-io/dataset/tabular"
 	"github.com/qri-io/qfs"
 )
 
@@ -45,6 +48,183 @@ func ReadBodyBytes(ds *dataset.Dataset, format dataset.DataFormat, fcfg dataset.
 	return data, nil
 }
 
+// BodyStream returns a reader of a dataset's body in the desired format,
+// starting at entry offset & reading at most limit entries. A limit of zero or
+// less reads to the end of the body. Whole bodies already stored in the
+// requested format are read directly, others are converted entry-by-entry as
+// the stream is read, so the body is never held in memory. Closing the reader
+// stops any conversion in progress
+func BodyStream(ds *dataset.Dataset, format dataset.DataFormat, fcfg dataset.FormatConfig, limit, offset int, columns []string) (io.ReadCloser, error) {
+	if ds == nil {
+		return nil, fmt.Errorf("can't load body from a nil dataset")
+	}
+	file := ds.BodyFile()
+	if file == nil {
+		return nil, fmt.Errorf("no body file to read")
+	}
+	if ds.Structure == nil {
+		return nil, fmt.Errorf("dataset has no structure")
+	}
+	if format == dataset.UnknownDataFormat {
+		format = ds.Structure.DataFormat()
+	}
+	paged := limit > 0 || offset > 0
+	if format == ds.Structure.DataFormat() && fcfg == nil && !paged && len(columns) == 0 {
+		return file, nil
+	}
+
+	in := ds.Structure
+	var indexes []int
+	if len(columns) > 0 {
+		var err error
+		if in, indexes, err = ProjectStructure(ds.Structure, columns); err != nil {
+			file.Close()
+			return nil, err
+		}
+	}
+
+	st := &dataset.Structure{}
+	assign := &dataset.Structure{
+		Format: format.String(),
+		Schema: in.Schema,
+	}
+	if fcfg != nil {
+		assign.FormatConfig = fcfg.Map()
+	}
+	st.Assign(ds.Structure, assign)
+
+	rr, err := dsio.NewEntryReader(ds.Structure, file)
+	if err != nil {
+		file.Close()
+		return nil, fmt.Errorf("creating entry reader: %w", err)
+	}
+	if paged {
+		if limit <= 0 {
+			limit = -1
+		}
+		rr = &dsio.PagedReader{Reader: rr, Limit: limit, Offset: offset}
+	}
+	if indexes != nil {
+		rr = &projectedReader{reader: rr, st: in, indexes: indexes}
+	}
+
+	pr, pw := io.Pipe()
+	w, err := dsio.NewEntryWriter(st, pw)
+	if err != nil {
+		file.Close()
+		return nil, fmt.Errorf("creating entry writer: %w", err)
+	}
+
+	go func() {
+		defer file.Close()
+		err := dsio.Copy(rr, w)
+		if closeErr := w.Close(); err == nil {
+			err = closeErr
+		}
+		pw.CloseWithError(err)
+	}()
+	return pr, nil
+}
+
+// ProjectStructure narrows a tabular structure to a list of columns, each
+// named by title or by zero-based index. It returns a copy of the structure
+// with a schema describing only those columns, in the order given, and the
+// index of each column in the original rows. Bodies must have array rows
+func ProjectStructure(st *dataset.Structure, columns []string) (*dataset.Structure, []int, error) {
+	if st == nil || st.Schema == nil {
+		return nil, nil, fmt.Errorf("column projection requires a schema")
+	}
+	cols, _, err := tabular.ColumnsFromJSONSchema(st.Schema)
+	if err != nil {
+		return nil, nil, fmt.Errorf("column projection requires a tabular body: %w", err)
+	}
+	items, _ := st.Schema["items"].(map[string]interface{})
+	colSchemas, _ := items["items"].([]interface{})
+
+	indexes := make([]int, 0, len(columns))
+	projected := make([]interface{}, 0, len(columns))
+	for _, name := range columns {
+		idx := -1
+		for i, col := range cols {
+			if col.Title == name {
+				idx = i
+				break
+			}
+		}
+		if idx == -1 {
+			if i, err := strconv.Atoi(name); err == nil && i >= 0 && i < len(cols) {
+				idx = i
+			}
+		}
+		if idx == -1 {
+			return nil, nil, fmt.Errorf("unknown column %q", name)
+		}
+		indexes = append(indexes, idx)
+		projected = append(projected, colSchemas[idx])
+	}
+
+	sch := map[string]interface{}{}
+	for k, v := range st.Schema {
+		sch[k] = v
+	}
+	itemSch := map[string]interface{}{}
+	for k, v := range items {
+		itemSch[k] = v
+	}
+	itemSch["items"] = projected
+	sch["items"] = itemSch
+
+	res := &dataset.Structure{}
+	res.Assign(st)
+	res.Schema = sch
+	// the projected structure doesn't describe stored bytes
+	res.Path = ""
+	res.Checksum = ""
+	res.Length = 0
+	return res, indexes, nil
+}
+
+// projectedReader narrows the array rows of a wrapped reader to a set of
+// column indexes. Rows are projected one at a time as they're read
+type projectedReader struct {
+	reader  dsio.EntryReader
+	st      *dataset.Structure
+	indexes []int
+}
+
+var _ dsio.EntryReader = (*projectedReader)(nil)
+
+// Structure returns the projected structure
+func (r *projectedReader) Structure() *dataset.Structure {
+	return r.st
+}
+
+// ReadEntry reads an entry from the wrapped reader, keeping only projected
+// columns
+func (r *projectedReader) ReadEntry() (dsio.Entry, error) {
+	ent, err := r.reader.ReadEntry()
+	if err != nil {
+		return ent, err
+	}
+	row, ok := ent.Value.([]interface{})
+	if !ok {
+		return ent, fmt.Errorf("entry %d: column projection requires array rows, got %T", ent.Index, ent.Value)
+	}
+	vals := make([]interface{}, len(r.indexes))
+	for i, idx := range r.indexes {
+		if idx < len(row) {
+			vals[i] = row[idx]
+		}
+	}
+	ent.Value = vals
+	return ent, nil
+}
+
+// Close closes the wrapped reader
+func (r *projectedReader) Close() error {
+	return r.reader.Close()
+}
+
 // GetBody takes returns the Body as a go-native structure,
 // using limit, offset, and all parameters to determine what part of the Body to return
 func GetBody(ds *dataset.Dataset, limit, offset int, all bool) (interface{}, error) {
@@ -243,3 +243,50 @@ func newTestJSONObjectReader(entryCount int) dsio.EntryReader {
 	}
 	return er
 }
+
+func TestProjectStructure(t *testing.T) {
+	st := &dataset.Structure{
+		Format:   "csv",
+		Length:   100,
+		Checksum: "/mem/QmChecksum",
+		Schema: map[string]interface{}{
+			"type": "array",
+			"items": map[string]interface{}{
+				"type": "array",
+				"items": []interface{}{
+					map[string]interface{}{"title": "city", "type": "string"},
+					map[string]interface{}{"title": "pop", "type": "integer"},
+					map[string]interface{}{"title": "avg_age", "type": "number"},
+				},
+			},
+		},
+	}
+
+	got, indexes, err := ProjectStructure(st, []string{"avg_age", "0"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff([]int{2, 0}, indexes); diff != "" {
+		t.Errorf("indexes mismatch (-want +got):\n%s", diff)
+	}
+	cols, _, err := tabular.ColumnsFromJSONSchema(got.Schema)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff([]string{"avg_age", "city"}, cols.Titles()); diff != "" {
+		t.Errorf("projected columns mismatch (-want +got):\n%s", diff)
+	}
+	if got.Length != 0 || got.Checksum != "" {
+		t.Errorf("expected projected structure to drop stored body details")
+	}
+	if len(st.Schema["items"].(map[string]interface{})["items"].([]interface{})) != 3 {
+		t.Errorf("expected original schema to be unmodified")
+	}
+
+	if _, _, err := ProjectStructure(st, []string{"missing"}); err == nil {
+		t.Error("expected unknown column to error")
+	}
+	if _, _, err := ProjectStructure(st, []string{"3"}); err == nil {
+		t.Error("expected out of range column index to error")
+	}
+}
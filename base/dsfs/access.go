@@ -0,0 +1,92 @@
+package dsfs
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/qri-io/dataset"
+)
+
+// AccessControlMetaKey is the meta key that holds a dataset's access control
+const AccessControlMetaKey = "qri.access"
+
+// AccessControl restricts which peers can read a dataset. It's stored in the
+// dataset meta component, so it's persisted & hashed with the dataset.
+//
+// Because it lives in meta, access control is public: anyone who can read a
+// version can see who may read it, and anyone who can save a version of the
+// dataset can change it. Remotes only enforce it on requests that prove the
+// caller's identity with a signature. Access control restricts what a remote
+// will serve, it doesn't encrypt anything
+type AccessControl struct {
+	// Owner is the base58-encoded profile ID of the dataset owner. The owner
+	// can always read the dataset
+	Owner string `json:"owner"`
+	// Readers lists base58-encoded profile IDs allowed to read the dataset
+	Readers []string `json:"readers,omitempty"`
+}
+
+// CanRead reports whether the profile with the given base58-encoded ID is
+// allowed to read the dataset
+func (ac *AccessControl) CanRead(profileID string) bool {
+	if ac == nil {
+		return true
+	}
+	if profileID == "" {
+		return false
+	}
+	if profileID == ac.Owner {
+		return true
+	}
+	for _, id := range ac.Readers {
+		if id == profileID {
+			return true
+		}
+	}
+	return false
+}
+
+// SetAccessControl stores access control in the meta component of a dataset,
+// creating the component if necessary
+func SetAccessControl(ds *dataset.Dataset, ac *AccessControl) error {
+	if ac == nil || ac.Owner == "" {
+		return fmt.Errorf("access control owner is required")
+	}
+
+	// store the access control as a generic map so stored & loaded datasets
+	// hold the same type
+	data, err := json.Marshal(ac)
+	if err != nil {
+		return err
+	}
+	val := map[string]interface{}{}
+	if err := json.Unmarshal(data, &val); err != nil {
+		return err
+	}
+	if ds.Meta == nil {
+		ds.Meta = &dataset.Meta{}
+	}
+	return ds.Meta.SetArbitrary(AccessControlMetaKey, val)
+}
+
+// DatasetAccessControl reads access control from the meta component of a
+// dataset. datasets without access control return nil, meaning anyone can
+// read them
+func DatasetAccessControl(ds *dataset.Dataset) (*AccessControl, error) {
+	if ds == nil || ds.Meta == nil {
+		return nil, nil
+	}
+	val, ok := ds.Meta.Meta()[AccessControlMetaKey]
+	if !ok || val == nil {
+		return nil, nil
+	}
+	data, err := json.Marshal(val)
+	if err != nil {
+		return nil, err
+	}
+	ac := &AccessControl{}
+	if err := json.Unmarshal(data, ac); err != nil {
+		return nil, fmt.Errorf("invalid access control: %w", err)
+	}
+	return ac, nil
+}
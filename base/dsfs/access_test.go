@@ -0,0 +1,63 @@
+package dsfs
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/qri-io/dataset"
+	"github.com/qri-io/qfs"
+	testkeys "github.com/qri-io/qri/auth/key/test"
+	"github.com/qri-io/qri/event"
+)
+
+func TestAccessControlPersists(t *testing.T) {
+	ctx := context.Background()
+	fs := qfs.NewMemFS()
+	privKey := testkeys.GetKeyData(10).PrivKey
+
+	ds := &dataset.Dataset{
+		Commit:    &dataset.Commit{},
+		Structure: &dataset.Structure{Format: "json", Schema: dataset.BaseSchemaArray},
+	}
+	ds.SetBodyFile(qfs.NewMemfileBytes("/body.json", []byte(`[]`)))
+
+	expect := &AccessControl{
+		Owner:   testkeys.GetKeyData(10).EncodedPeerID,
+		Readers: []string{testkeys.GetKeyData(1).EncodedPeerID},
+	}
+	if err := SetAccessControl(ds, expect); err != nil {
+		t.Fatal(err)
+	}
+
+	path, err := CreateDataset(ctx, fs, fs, event.NilBus, ds, nil, privKey, SaveSwitches{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := LoadDataset(ctx, fs, path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ac, err := DatasetAccessControl(got)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(expect, ac); diff != "" {
+		t.Errorf("access control mismatch (-want +got):\n%s", diff)
+	}
+
+	if !ac.CanRead(testkeys.GetKeyData(10).EncodedPeerID) {
+		t.Errorf("expected owner to be able to read")
+	}
+	if !ac.CanRead(testkeys.GetKeyData(1).EncodedPeerID) {
+		t.Errorf("expected listed reader to be able to read")
+	}
+	if ac.CanRead(testkeys.GetKeyData(2).EncodedPeerID) {
+		t.Errorf("expected unlisted peer to be denied")
+	}
+
+	if err := SetAccessControl(&dataset.Dataset{}, &AccessControl{}); err == nil {
+		t.Errorf("expected access control without an owner to error")
+	}
+}
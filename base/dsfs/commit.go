@@ -61,8 +61,8 @@ func commitFileAddFunc(ctx context.Context, privKey crypto.PrivKey, publisher ev
 			return fmt.Errorf("saving failed: %w", err)
 		}
 
-		if err := EnsureCommitTitleAndMessage(ctx, src, ds, prev, sw.bodyAct, sw.FileHint, sw.ForceIfNoChanges); err != nil {
-			log.Debugf("EnsureCommitTitleAndMessage: %s", err)
+		if err := ensureCommitDescription(ctx, src, ds, prev, sw); err != nil {
+			log.Debugf("ensureCommitDescription: %s", err)
 			return fmt.Errorf("saving failed: %w", err)
 		}
 
@@ -77,7 +77,7 @@ func commitFileAddFunc(ctx context.Context, privKey crypto.PrivKey, publisher ev
 		ds.Commit.Signature = base64.StdEncoding.EncodeToString(signedBytes)
 		log.Debugw("writing commit", "title", ds.Commit.Title, "message", ds.Commit.Message)
 
-		f, err := JSONFile(PackageFileCommit.String(), ds.Commit)
+		f, err := saveJSONFile(PackageFileCommit.String(), ds.Commit, sw)
 		if err != nil {
 			return err
 		}
@@ -156,8 +156,10 @@ func confirmByteChangesExist(ds, prev *dataset.Dataset, force bool, dst qfs.Merk
 
 // EnsureCommitTitleAndMessage creates the commit and title, message, skipping
 // if both title and message are set. If no values are provided a commit
-// description is generated by examining changes between the two versions
-func EnsureCommitTitleAndMessage(ctx context.Context, fs qfs.Filesystem, ds, prev *dataset.Dataset, bodyAct BodyAction, fileHint string, forceIfNoChanges bool) error {
+// description is generated by examining changes between the two versions.
+// Previous bodies up to diffThreshold bytes are diffed to describe body
+// changes, a threshold of 0 uses BodySizeSmallEnoughToDiff
+func EnsureCommitTitleAndMessage(ctx context.Context, fs qfs.Filesystem, ds, prev *dataset.Dataset, bodyAct BodyAction, fileHint string, forceIfNoChanges bool, diffThreshold int) error {
 	if ds.Commit == nil {
 		ds.Commit = &dataset.Commit{}
 	}
@@ -168,7 +170,7 @@ func EnsureCommitTitleAndMessage(ctx context.Context, fs qfs.Filesystem, ds, pre
 
 	// fast path when commit and title are set
 	log.Debugw("EnsureCommitTitleAndMessage", "bodyAct", bodyAct)
-	shortTitle, longMessage, err := generateCommitDescriptions(ctx, fs, ds, prev, bodyAct, forceIfNoChanges)
+	shortTitle, longMessage, err := generateCommitDescriptions(ctx, fs, ds, prev, bodyAct, forceIfNoChanges, diffThreshold)
 	if err != nil {
 		log.Debugf("generateCommitDescriptions err: %s", err)
 		return err
@@ -191,10 +193,61 @@ func EnsureCommitTitleAndMessage(ctx context.Context, fs qfs.Filesystem, ds, pre
 	return nil
 }
 
+// CommitDescription is the title & message of a commit
+type CommitDescription struct {
+	Title   string
+	Message string
+}
+
+// CommitMessageFunc generates the commit description for a new dataset
+// version. prev is empty when creating a new dataset. generated is the
+// description qri derived from changes between the two versions. Returning
+// an empty title or message falls back to the generated value
+type CommitMessageFunc func(ctx context.Context, ds, prev *dataset.Dataset, bodyAct BodyAction, generated CommitDescription) (CommitDescription, error)
+
+// ensureCommitDescription sets the commit title & message, passing generated
+// values through sw.CommitMessage when one is provided. Titles and messages
+// set by the caller are never replaced
+func ensureCommitDescription(ctx context.Context, fs qfs.Filesystem, ds, prev *dataset.Dataset, sw *SaveSwitches) error {
+	return DescribeCommit(ctx, fs, ds, prev, sw.bodyAct, sw.FileHint, sw.ForceIfNoChanges, sw.BodyDiffThreshold, sw.CommitMessage)
+}
+
+// DescribeCommit sets the commit title & message with
+// EnsureCommitTitleAndMessage, passing generated values through describe when
+// it's non-nil. Titles and messages already set on ds are never replaced
+func DescribeCommit(ctx context.Context, fs qfs.Filesystem, ds, prev *dataset.Dataset, bodyAct BodyAction, fileHint string, forceIfNoChanges bool, diffThreshold int, describe CommitMessageFunc) error {
+	if ds.Commit == nil {
+		ds.Commit = &dataset.Commit{}
+	}
+	title, message := ds.Commit.Title, ds.Commit.Message
+	if err := EnsureCommitTitleAndMessage(ctx, fs, ds, prev, bodyAct, fileHint, forceIfNoChanges, diffThreshold); err != nil {
+		return err
+	}
+	if describe == nil || (title != "" && message != "") {
+		return nil
+	}
+
+	if prev == nil {
+		prev = &dataset.Dataset{}
+	}
+	generated := CommitDescription{Title: ds.Commit.Title, Message: ds.Commit.Message}
+	desc, err := describe(ctx, ds, prev, bodyAct, generated)
+	if err != nil {
+		return fmt.Errorf("generating commit message: %w", err)
+	}
+	if title == "" && desc.Title != "" {
+		ds.Commit.Title = desc.Title
+	}
+	if message == "" && desc.Message != "" {
+		ds.Commit.Message = desc.Message
+	}
+	return nil
+}
+
 const defaultCreatedDescription = "created dataset"
 
 // returns a commit message based on the diff of the two datasets
-func generateCommitDescriptions(ctx context.Context, fs qfs.Filesystem, ds, prev *dataset.Dataset, bodyAct BodyAction, forceIfNoChanges bool) (short, long string, err error) {
+func generateCommitDescriptions(ctx context.Context, fs qfs.Filesystem, ds, prev *dataset.Dataset, bodyAct BodyAction, forceIfNoChanges bool, diffThreshold int) (short, long string, err error) {
 	if prev == nil || prev.IsEmpty() {
 		return defaultCreatedDescription, defaultCreatedDescription, nil
 	}
@@ -202,7 +255,7 @@ func generateCommitDescriptions(ctx context.Context, fs qfs.Filesystem, ds, prev
 	// Inline body if it is a reasonable size, to get message about how the body has changed.
 	if bodyAct != BodySame {
 		// If previous version had bodyfile, read it and assign it
-		if prev.Structure != nil && prev.Structure.Length < BodySizeSmallEnoughToDiff {
+		if prev.Structure != nil && prev.Structure.Length < bodyDiffThreshold(diffThreshold) {
 			if prev.BodyFile() != nil {
 				log.Debugf("inlining body file to calculate a diff")
 				if prevReader, err := dsio.NewEntryReader(prev.Structure, prev.BodyFile()); err == nil {
@@ -30,7 +30,7 @@ type computeFieldsFile struct {
 	acc *dsstats.Accumulator
 
 	// buffer of entries for diffing small datasets. will be set to nil if
-	// body reads more than BodySizeSmallEnoughToDiff bytes
+	// body reads more than the save's body diff threshold
 	diffMessageBuf *dsio.EntryBuffer
 
 	bodySize   int64 // copy provided body file .Size() method
@@ -212,8 +212,9 @@ func (cff *computeFieldsFile) handleRows(ctx context.Context) {
 		// message, we know that a compute-fields-file has made it all the way through
 		// setup
 		go func() {
-			completion := 0.1
-			if cff.bodySize >= 0 {
+			// completion stays at 0 when the body size is unknown
+			var completion float64
+			if cff.bodySize > 0 {
 				completion = float64(cff.teeReader.BytesRead()) / float64(cff.bodySize)
 			}
 			evtErr := cff.publisher.Publish(ctx, event.ETDatasetSaveProgress, event.DsSaveEvent{
@@ -244,7 +245,7 @@ func (cff *computeFieldsFile) handleRows(ctx context.Context) {
 			}
 
 			if i%batchSize == 0 && i != 0 {
-				numValErrs, flushErr := cff.flushBatch(ctx, batchBuf, st, jsch)
+				numValErrs, flushErr := cff.flushBatch(ctx, batchBuf, st, jsch, entries)
 				if flushErr != nil {
 					log.Debugf("error flushing batch while reading; %s", flushErr)
 					return flushErr
@@ -284,7 +285,7 @@ func (cff *computeFieldsFile) handleRows(ctx context.Context) {
 		}
 
 		log.Debugf("read all %d entries", entries)
-		numValErrs, err := cff.flushBatch(ctx, batchBuf, st, jsch)
+		numValErrs, err := cff.flushBatch(ctx, batchBuf, st, jsch, entries)
 		if err != nil {
 			log.Debugf("flushing final batch: %s", err)
 			cff.done <- err
@@ -324,12 +325,14 @@ func (cff *computeFieldsFile) handleRows(ctx context.Context) {
 	return
 }
 
-func (cff *computeFieldsFile) flushBatch(ctx context.Context, buf *dsio.EntryBuffer, st *dataset.Structure, jsch *jsonschema.Schema) (int, error) {
+// flushBatch validates a batch of entries, publishing a progress event with
+// the number of entries read so far
+func (cff *computeFieldsFile) flushBatch(ctx context.Context, buf *dsio.EntryBuffer, st *dataset.Structure, jsch *jsonschema.Schema, entries int) (int, error) {
 	log.Debugf("flushing batch %d", cff.batches)
 	cff.batches++
 
-	if cff.diffMessageBuf != nil && cff.teeReader.BytesRead() > BodySizeSmallEnoughToDiff {
-		log.Debugf("removing diffMessage data buffer. bytesRead exceeds %d bytes", BodySizeSmallEnoughToDiff)
+	if limit := bodyDiffThreshold(cff.sw.BodyDiffThreshold); cff.diffMessageBuf != nil && cff.teeReader.BytesRead() > limit {
+		log.Debugf("removing diffMessage data buffer. bytesRead exceeds %d bytes", limit)
 		cff.diffMessageBuf.Close()
 		cff.diffMessageBuf = nil
 		cff.sw.bodyAct = BodyTooBig
@@ -357,14 +360,22 @@ func (cff *computeFieldsFile) flushBatch(ctx context.Context, buf *dsio.EntryBuf
 		return 0, fmt.Errorf("%w. found at least %d errors", ErrStrictMode, len(*validationState.Errs))
 	}
 
-	if cff.publisher != nil && cff.bodySize > 0 {
+	if cff.publisher != nil {
+		bytesRead := int64(cff.teeReader.BytesRead())
+		// completion stays at 0 when the body size is unknown, bytes & entries
+		// processed still report progress
+		var completion float64
+		if cff.bodySize > 0 {
+			completion = float64(bytesRead) / float64(cff.bodySize)
+		}
 		go func() {
-			completion := float64(cff.teeReader.BytesRead()) / float64(cff.bodySize)
 			evtErr := cff.publisher.Publish(ctx, event.ETDatasetSaveProgress, event.DsSaveEvent{
-				Username:   cff.ds.Peername,
-				Name:       cff.ds.Name,
-				Message:    "processing body file",
-				Completion: completion,
+				Username:         cff.ds.Peername,
+				Name:             cff.ds.Name,
+				Message:          "processing body file",
+				Completion:       completion,
+				BytesProcessed:   bytesRead,
+				EntriesProcessed: entries,
 			})
 			if evtErr != nil {
 				log.Debugw("ignored error while publishing save progress", "evtErr", evtErr)
@@ -1,8 +1,11 @@
 package dsfs
 
 import (
+	"bytes"
 	"context"
+	"io/ioutil"
 	"testing"
+	"time"
 
 	"github.com/qri-io/dataset"
 	"github.com/qri-io/qfs"
@@ -31,3 +34,78 @@ func TestComputeFieldsFile(t *testing.T) {
 		t.Errorf("unexpected filename. want: %q got %q", expect, cff.FileName())
 	}
 }
+
+func TestComputeFieldsProgress(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	bus := event.NewBus(ctx)
+	progress := make(chan event.DsSaveEvent, 10)
+	bus.SubscribeTypes(func(ctx context.Context, e event.Event) error {
+		if evt, ok := e.Payload.(event.DsSaveEvent); ok && evt.EntriesProcessed > 0 {
+			progress <- evt
+		}
+		return nil
+	}, event.ETDatasetSaveProgress)
+
+	body := []byte(`[[1,"a"],[2,"b"],[3,"c"],[4,"d"]]`)
+	ds := &dataset.Dataset{
+		Commit:    &dataset.Commit{},
+		Structure: &dataset.Structure{Format: "json", Schema: dataset.BaseSchemaArray},
+	}
+	ds.SetBodyFile(qfs.NewMemfileBytes("/body.json", body))
+	cff, err := newComputeFieldsFile(ctx, bus, nil, ds, nil, &SaveSwitches{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ioutil.ReadAll(cff); err != nil {
+		t.Fatal(err)
+	}
+	if err := <-cff.(doneProcessingFile).DoneProcessing(); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case evt := <-progress:
+		if evt.EntriesProcessed != 4 {
+			t.Errorf("expected 4 entries processed, got %d", evt.EntriesProcessed)
+		}
+		if evt.BytesProcessed != int64(len(body)) {
+			t.Errorf("expected %d bytes processed, got %d", len(body), evt.BytesProcessed)
+		}
+		if evt.Completion != 1 {
+			t.Errorf("expected completion to be 1, got %f", evt.Completion)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("timed out waiting for a body progress event")
+	}
+
+	// bodies of unknown size report no completion
+	ds = &dataset.Dataset{
+		Commit:    &dataset.Commit{},
+		Structure: &dataset.Structure{Format: "json", Schema: dataset.BaseSchemaArray},
+	}
+	ds.SetBodyFile(qfs.NewMemfileReader("/body.json", bytes.NewReader(body)))
+	cff, err = newComputeFieldsFile(ctx, bus, nil, ds, nil, &SaveSwitches{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ioutil.ReadAll(cff); err != nil {
+		t.Fatal(err)
+	}
+	if err := <-cff.(doneProcessingFile).DoneProcessing(); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case evt := <-progress:
+		if evt.EntriesProcessed != 4 {
+			t.Errorf("expected 4 entries processed, got %d", evt.EntriesProcessed)
+		}
+		if evt.Completion != 0 {
+			t.Errorf("expected completion of a body with unknown size to be 0, got %f", evt.Completion)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("timed out waiting for a body progress event")
+	}
+}
@@ -24,6 +24,24 @@ func JSONFile(name string, m json.Marshaler) (fs.File, error) {
 	return NewMemfileBytes(name, data), nil
 }
 
+// saveJSONFile creates a package file from a json.Marshaler, indenting the
+// JSON if the save switches ask for it
+func saveJSONFile(name string, m json.Marshaler, sw *SaveSwitches) (fs.File, error) {
+	data, err := m.MarshalJSON()
+	if err != nil {
+		log.Debug(err.Error())
+		return nil, err
+	}
+	if sw != nil && sw.PrettyJSON {
+		buf := &bytes.Buffer{}
+		if err := json.Indent(buf, data, "", "  "); err != nil {
+			return nil, err
+		}
+		data = buf.Bytes()
+	}
+	return NewMemfileBytes(name, data), nil
+}
+
 func fileBytes(file qfs.File, err error) ([]byte, error) {
 	if err != nil {
 		log.Debug(err.Error())
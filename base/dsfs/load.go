@@ -2,6 +2,7 @@ package dsfs
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 
 	"github.com/qri-io/dataset"
@@ -49,11 +50,16 @@ func LoadDatasetRefs(ctx context.Context, fs qfs.Filesystem, path string) (*data
 		return nil, fmt.Errorf("reading %s file: %w", PackageFileDataset.String(), err)
 	}
 
-	ds, err := dataset.UnmarshalDataset(data)
-	if err != nil {
+	f := &datasetFile{}
+	if err := json.Unmarshal(data, f); err != nil {
 		log.Debug(err.Error())
 		return nil, fmt.Errorf("unmarshaling %s file: %w", PackageFileDataset.String(), err)
 	}
+	ds := f.Dataset()
+
+	for _, pf := range f.legacyComponents() {
+		log.Debugw("legacy component ignored", "path", path, "component", pf.String())
+	}
 
 	// assign path to retain reference to the path this dataset was read from
 	ds.Path = path
@@ -61,6 +67,42 @@ func LoadDatasetRefs(ctx context.Context, fs qfs.Filesystem, path string) (*data
 	return ds, nil
 }
 
+// plainDataset has the fields of a dataset without its JSON decoding
+// methods, so it can be embedded in datasetFile
+type plainDataset dataset.Dataset
+
+// datasetFile decodes dataset.json in a single pass, keeping fields written
+// by early versions of qri alongside the dataset. Current datasets have no
+// equivalent for these components, so they're ignored when read
+type datasetFile struct {
+	plainDataset
+	Abstract          json.RawMessage `json:"abstract,omitempty"`
+	Resources         json.RawMessage `json:"resources,omitempty"`
+	AbstractTransform json.RawMessage `json:"abstractTransform,omitempty"`
+}
+
+// Dataset returns the decoded dataset
+func (f *datasetFile) Dataset() *dataset.Dataset {
+	ds := dataset.Dataset(f.plainDataset)
+	return &ds
+}
+
+// legacyComponents lists the legacy package files the dataset.json file
+// references
+func (f *datasetFile) legacyComponents() []PackageFile {
+	var found []PackageFile
+	if f.Abstract != nil {
+		found = append(found, PackageFileAbstract)
+	}
+	if f.Resources != nil {
+		found = append(found, PackageFileResources)
+	}
+	if f.AbstractTransform != nil {
+		found = append(found, PackageFileAbstractTransform)
+	}
+	return found
+}
+
 // DerefDataset attempts to fully dereference a dataset
 func DerefDataset(ctx context.Context, store qfs.Filesystem, ds *dataset.Dataset) error {
 	if err := DerefMeta(ctx, store, ds); err != nil {
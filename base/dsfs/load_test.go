@@ -3,12 +3,14 @@ package dsfs
 import (
 	"bytes"
 	"context"
+	"encoding/json"
 	"fmt"
 	"io"
 	"io/ioutil"
 	"strings"
 	"testing"
 
+	"github.com/google/go-cmp/cmp"
 	"github.com/qri-io/dataset"
 	"github.com/qri-io/qfs"
 	testkeys "github.com/qri-io/qri/auth/key/test"
@@ -183,3 +185,65 @@ func (r *EqualReader) Read(p []byte) (int, error) {
 
 	return readA, nil
 }
+
+func TestLoadDatasetLegacyComponents(t *testing.T) {
+	ctx := context.Background()
+	fs := qfs.NewMemFS()
+
+	links := qfs.NewLinks()
+	for _, name := range []string{PackageFileDataset.String(), PackageFileResources.String()} {
+		data, err := ioutil.ReadFile("testdata/legacy_resources/" + name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		res, err := fs.PutFile(NewMemfileBytes(name, data))
+		if err != nil {
+			t.Fatal(err)
+		}
+		links.Add(res.ToLink(name, true))
+	}
+	root, err := fs.PutNode(links)
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := fsPathFromCID(fs, root.Cid)
+
+	ds, err := LoadDataset(ctx, fs, path)
+	if err != nil {
+		t.Fatalf("expected dataset with legacy components to load, got: %s", err)
+	}
+	if ds.Meta == nil || ds.Meta.Title != "legacy precipitation" {
+		t.Errorf("expected current components to load alongside legacy ones, got meta: %v", ds.Meta)
+	}
+
+	data, err := ioutil.ReadFile("testdata/legacy_resources/dataset.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	f := &datasetFile{}
+	if err := json.Unmarshal(data, f); err != nil {
+		t.Fatal(err)
+	}
+	got := f.legacyComponents()
+	expect := []PackageFile{PackageFileAbstract, PackageFileResources}
+	if diff := cmp.Diff(expect, got); diff != "" {
+		t.Errorf("legacy components mismatch (-want +got):\n%s", diff)
+	}
+
+	// decoding a dataset file matches decoding a dataset
+	expectDs, err := dataset.UnmarshalDataset(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectData, err := json.Marshal(expectDs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	gotData, err := json.Marshal(f.Dataset())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(string(expectData), string(gotData)); diff != "" {
+		t.Errorf("decoded dataset mismatch (-want +got):\n%s", diff)
+	}
+}
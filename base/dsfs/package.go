@@ -34,11 +34,11 @@ const (
 	// in it's own file
 	PackageFileStructure
 	// PackageFileAbstract is the abstract verion of
-	// structure
+	// structure. Legacy, ignored when read
 	PackageFileAbstract
 	// PackageFileResources lists the resource datasets
-	// that went into creating a dataset
-	// TODO - I think this can be removed now that Transform exists
+	// that went into creating a dataset. Legacy, replaced by transform
+	// resources & ignored when read
 	PackageFileResources
 	// PackageFileCommit isolates the user-entered
 	// documentation of the changes to this dataset's history
@@ -47,7 +47,8 @@ const (
 	// generated this dataset
 	PackageFileTransform
 	// PackageFileAbstractTransform is the abstract version of
-	// the operation performed to create this dataset
+	// the operation performed to create this dataset. Legacy, ignored when
+	// read
 	PackageFileAbstractTransform
 	// PackageFileMeta encapsulates human-readable metadata
 	PackageFileMeta
@@ -66,26 +67,30 @@ const (
 	PackageFileRenderedReadme
 	// PackageFileStats isolates the statistical metadata component
 	PackageFileStats
+	// PackageFileTransformSignature is the dataset author's signature of the
+	// transform script
+	PackageFileTransformSignature
 )
 
 // filenames maps PackageFile to their filename counterparts
 var filenames = map[PackageFile]string{
-	PackageFileUnknown:           "",
-	PackageFileDataset:           "dataset.json",
-	PackageFileStructure:         "structure.json",
-	PackageFileAbstract:          "abstract.json",
-	PackageFileAbstractTransform: "abstract_transform.json",
-	PackageFileResources:         "resources",
-	PackageFileCommit:            "commit.json",
-	PackageFileTransform:         "transform.json",
-	PackageFileMeta:              "meta.json",
-	PackageFileViz:               "viz.json",
-	PackageFileVizScript:         "viz_script",
-	PackageFileRenderedViz:       "index.html",
-	PackageFileReadme:            "readme.json",
-	PackageFileReadmeScript:      "readme.md",
-	PackageFileRenderedReadme:    "readme.html",
-	PackageFileStats:             "stats.json",
+	PackageFileUnknown:            "",
+	PackageFileDataset:            "dataset.json",
+	PackageFileStructure:          "structure.json",
+	PackageFileAbstract:           "abstract.json",
+	PackageFileAbstractTransform:  "abstract_transform.json",
+	PackageFileResources:          "resources",
+	PackageFileCommit:             "commit.json",
+	PackageFileTransform:          "transform.json",
+	PackageFileMeta:               "meta.json",
+	PackageFileViz:                "viz.json",
+	PackageFileVizScript:          "viz_script",
+	PackageFileRenderedViz:        "index.html",
+	PackageFileReadme:             "readme.json",
+	PackageFileReadmeScript:       "readme.md",
+	PackageFileRenderedReadme:     "readme.html",
+	PackageFileStats:              "stats.json",
+	PackageFileTransformSignature: "transform_signature",
 }
 
 // String implements the io.Stringer interface for PackageFile
@@ -0,0 +1,239 @@
+package dsfs
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"time"
+
+	"github.com/qri-io/dataset"
+	"github.com/qri-io/dataset/dsio"
+	"github.com/qri-io/qfs"
+)
+
+// FormatConfigPartitionsKey is the structure format config key that lists the
+// parts of a partitioned body
+const FormatConfigPartitionsKey = "partitions"
+
+// BodyPartition is one part of a partitioned body. Partitioned bodies are
+// written as a single body file, with the partition layout recorded in the
+// structure's format config as a list of parts in body order:
+//
+//	"formatConfig": {
+//	  "partitions": [
+//	    { "name": "2021-08-01.csv", "entries": 2 },
+//	    { "name": "2021-08-02.csv", "entries": 3 }
+//	  ]
+//	}
+//
+// Every part is encoded in the structure's format & shares the structure's
+// schema. A part holds the next "entries" entries of the body
+type BodyPartition struct {
+	Name    string `json:"name"`
+	Entries int    `json:"entries"`
+}
+
+// BodyPartitions reads the partition layout from a structure, returning nil
+// if the body isn't partitioned
+func BodyPartitions(st *dataset.Structure) ([]BodyPartition, error) {
+	if st == nil || st.FormatConfig == nil || st.FormatConfig[FormatConfigPartitionsKey] == nil {
+		return nil, nil
+	}
+	list, ok := st.FormatConfig[FormatConfigPartitionsKey].([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("invalid %s value: %v", FormatConfigPartitionsKey, st.FormatConfig[FormatConfigPartitionsKey])
+	}
+	parts := make([]BodyPartition, 0, len(list))
+	for i, v := range list {
+		m, ok := v.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("invalid partition %d: %v", i, v)
+		}
+		p := BodyPartition{}
+		if p.Name, ok = m["name"].(string); !ok || p.Name == "" {
+			return nil, fmt.Errorf("partition %d: name is required", i)
+		}
+		switch n := m["entries"].(type) {
+		case int:
+			p.Entries = n
+		case int64:
+			p.Entries = int(n)
+		case float64:
+			p.Entries = int(n)
+		default:
+			return nil, fmt.Errorf("partition %q: invalid entries value: %v", p.Name, m["entries"])
+		}
+		if p.Entries < 0 {
+			return nil, fmt.Errorf("partition %q: entries cannot be negative", p.Name)
+		}
+		parts = append(parts, p)
+	}
+	return parts, nil
+}
+
+// SetBodyPartitions records a partition layout in a structure's format config.
+// setting an empty layout removes it
+func SetBodyPartitions(st *dataset.Structure, parts []BodyPartition) {
+	if len(parts) == 0 {
+		delete(st.FormatConfig, FormatConfigPartitionsKey)
+		if len(st.FormatConfig) == 0 {
+			st.FormatConfig = nil
+		}
+		return
+	}
+	list := make([]interface{}, len(parts))
+	for i, p := range parts {
+		list[i] = map[string]interface{}{"name": p.Name, "entries": p.Entries}
+	}
+	if st.FormatConfig == nil {
+		st.FormatConfig = map[string]interface{}{}
+	}
+	st.FormatConfig[FormatConfigPartitionsKey] = list
+}
+
+// PartitionedBodyFile combines the files of a partitioned body directory into
+// a single body file. Parts are read in filename order, one at a time as the
+// body is read. Once the body has been read to the end, Partitions returns the
+// layout of the parts that were read
+type PartitionedBodyFile struct {
+	qfs.File
+	partitions []BodyPartition
+}
+
+// NewPartitionedBodyFile creates a body file from a directory of body parts
+func NewPartitionedBodyFile(st *dataset.Structure, dir qfs.File) *PartitionedBodyFile {
+	pr, pw := io.Pipe()
+	f := &PartitionedBodyFile{
+		File: qfs.NewMemfileReader(st.BodyFilename(), pr),
+	}
+	go func() {
+		pw.CloseWithError(f.combine(st, dir, pw))
+	}()
+	return f
+}
+
+// Partitions returns the layout of the parts read so far
+func (f *PartitionedBodyFile) Partitions() []BodyPartition {
+	return f.partitions
+}
+
+func (f *PartitionedBodyFile) combine(st *dataset.Structure, dir qfs.File, w io.Writer) error {
+	// collect part handles up front so parts can be read in name order.
+	// file contents aren't read until the part's turn comes
+	var files []qfs.File
+	for {
+		part, err := dir.NextFile()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return err
+		}
+		if part.IsDirectory() {
+			return fmt.Errorf("body partition %q is a directory, partitions cannot be nested", part.FileName())
+		}
+		files = append(files, part)
+	}
+	sort.Slice(files, func(i, j int) bool { return files[i].FileName() < files[j].FileName() })
+
+	ew, err := dsio.NewEntryWriter(st, w)
+	if err != nil {
+		return err
+	}
+	index := 0
+	for _, part := range files {
+		r, err := dsio.NewEntryReader(st, part)
+		if err != nil {
+			return fmt.Errorf("body partition %q: %w", part.FileName(), err)
+		}
+		p := BodyPartition{Name: part.FileName()}
+		for {
+			ent, err := r.ReadEntry()
+			if err != nil {
+				if err.Error() == "EOF" {
+					break
+				}
+				return fmt.Errorf("body partition %q: %w", p.Name, err)
+			}
+			ent.Index = index
+			if err := ew.WriteEntry(ent); err != nil {
+				return err
+			}
+			index++
+			p.Entries++
+		}
+		f.partitions = append(f.partitions, p)
+	}
+	return ew.Close()
+}
+
+// OpenPartitionedBody splits a body file into the parts listed in the
+// structure's partition layout, returning a directory with one file per part.
+// Parts are decoded lazily from the body as they're read, so each part must be
+// read to the end before the next part is requested
+func OpenPartitionedBody(st *dataset.Structure, body qfs.File) (qfs.File, error) {
+	parts, err := BodyPartitions(st)
+	if err != nil {
+		return nil, err
+	}
+	if parts == nil {
+		return nil, fmt.Errorf("body is not partitioned")
+	}
+	r, err := dsio.NewEntryReader(st, body)
+	if err != nil {
+		return nil, err
+	}
+	return &partitionedBodyDir{name: "body", st: st, r: r, parts: parts}, nil
+}
+
+// partitionedBodyDir is a directory of body parts read from a single body
+type partitionedBodyDir struct {
+	name  string
+	st    *dataset.Structure
+	r     dsio.EntryReader
+	parts []BodyPartition
+}
+
+var _ qfs.File = (*partitionedBodyDir)(nil)
+
+func (d *partitionedBodyDir) Read([]byte) (int, error) {
+	return 0, fmt.Errorf("cannot read from a directory")
+}
+func (d *partitionedBodyDir) Close() error       { return d.r.Close() }
+func (d *partitionedBodyDir) FileName() string   { return d.name }
+func (d *partitionedBodyDir) FullPath() string   { return d.name }
+func (d *partitionedBodyDir) IsDirectory() bool  { return true }
+func (d *partitionedBodyDir) ModTime() time.Time { return time.Time{} }
+func (d *partitionedBodyDir) MediaType() string  { return "application/x-directory" }
+
+// NextFile returns the next part of the body
+func (d *partitionedBodyDir) NextFile() (qfs.File, error) {
+	if len(d.parts) == 0 {
+		return nil, io.EOF
+	}
+	p := d.parts[0]
+	d.parts = d.parts[1:]
+
+	pr, pw := io.Pipe()
+	go func() {
+		pw.CloseWithError(d.writePart(p, pw))
+	}()
+	return qfs.NewMemfileReader(p.Name, pr), nil
+}
+
+func (d *partitionedBodyDir) writePart(p BodyPartition, w io.Writer) error {
+	ew, err := dsio.NewEntryWriter(d.st, w)
+	if err != nil {
+		return err
+	}
+	for i := 0; i < p.Entries; i++ {
+		ent, err := d.r.ReadEntry()
+		if err != nil {
+			return fmt.Errorf("body partition %q: %w", p.Name, err)
+		}
+		ent.Index = i
+		if err := ew.WriteEntry(ent); err != nil {
+			return err
+		}
+	}
+	return ew.Close()
+}
@@ -0,0 +1,106 @@
+package dsfs
+
+import (
+	"context"
+	"io"
+	"io/ioutil"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/qri-io/dataset"
+	"github.com/qri-io/qfs"
+	testkeys "github.com/qri-io/qri/auth/key/test"
+	"github.com/qri-io/qri/event"
+)
+
+func TestPartitionedBodyRoundTrip(t *testing.T) {
+	ctx := context.Background()
+	fs := qfs.NewMemFS()
+	privKey := testkeys.GetKeyData(10).PrivKey
+
+	ds := &dataset.Dataset{
+		Commit: &dataset.Commit{Title: "initial commit", Timestamp: time.Date(2021, 8, 3, 0, 0, 0, 0, time.UTC)},
+		Structure: &dataset.Structure{
+			Format:       "csv",
+			FormatConfig: map[string]interface{}{"headerRow": true},
+			Schema: map[string]interface{}{
+				"type": "array",
+				"items": map[string]interface{}{
+					"type": "array",
+					"items": []interface{}{
+						map[string]interface{}{"title": "city", "type": "string"},
+						map[string]interface{}{"title": "temp", "type": "integer"},
+					},
+				},
+			},
+		},
+	}
+	// parts are combined in filename order, regardless of directory order
+	ds.SetBodyFile(qfs.NewMemdir("body",
+		qfs.NewMemfileBytes("2021-08-02.csv", []byte("city,temp\ntoronto,22\nchicago,27\n")),
+		qfs.NewMemfileBytes("2021-08-01.csv", []byte("city,temp\nnew york,30\n")),
+	))
+
+	path, err := CreateDataset(ctx, fs, fs, event.NilBus, ds, nil, privKey, SaveSwitches{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := LoadDataset(ctx, fs, path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Structure.Entries != 3 {
+		t.Errorf("expected 3 entries, got: %d", got.Structure.Entries)
+	}
+	layout, err := BodyPartitions(got.Structure)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectLayout := []BodyPartition{
+		{Name: "2021-08-01.csv", Entries: 1},
+		{Name: "2021-08-02.csv", Entries: 2},
+	}
+	if diff := cmp.Diff(expectLayout, layout); diff != "" {
+		t.Errorf("partition layout mismatch (-want +got):\n%s", diff)
+	}
+
+	body, err := fs.Get(ctx, got.BodyPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := ioutil.ReadAll(body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff("city,temp\nnew york,30\ntoronto,22\nchicago,27\n", string(data)); diff != "" {
+		t.Errorf("stored body mismatch (-want +got):\n%s", diff)
+	}
+
+	dir, err := OpenPartitionedBody(got.Structure, qfs.NewMemfileBytes("body.csv", data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	parts := map[string]string{}
+	for {
+		f, err := dir.NextFile()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			t.Fatal(err)
+		}
+		data, err := ioutil.ReadAll(f)
+		if err != nil {
+			t.Fatal(err)
+		}
+		parts[f.FileName()] = string(data)
+	}
+	expectParts := map[string]string{
+		"2021-08-01.csv": "city,temp\nnew york,30\n",
+		"2021-08-02.csv": "city,temp\ntoronto,22\nchicago,27\n",
+	}
+	if diff := cmp.Diff(expectParts, parts); diff != "" {
+		t.Errorf("partitions mismatch (-want +got):\n%s", diff)
+	}
+}
@@ -0,0 +1,57 @@
+package dsfs
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/qri-io/qfs"
+	"github.com/qri-io/qri/dsref"
+)
+
+// PinDataset pins the blocks of the dataset stored at path, if fs supports
+// pinning. depth controls pinning the datasets the dataset's transform read
+// from: 0 pins only the dataset at path, 1 adds the datasets its transform
+// read, and so on. A negative depth pins every upstream dataset. Upstream
+// datasets that aren't stored in fs are skipped
+func PinDataset(ctx context.Context, fs qfs.Filesystem, path string, depth int) error {
+	pinner, ok := fs.(qfs.PinningFS)
+	if !ok {
+		return nil
+	}
+	return pinDataset(ctx, fs, pinner, path, depth, map[string]bool{})
+}
+
+func pinDataset(ctx context.Context, fs qfs.Filesystem, pinner qfs.PinningFS, path string, depth int, visited map[string]bool) error {
+	visited[path] = true
+	if err := pinner.Pin(ctx, path, true); err != nil {
+		return fmt.Errorf("pinning %s: %w", path, err)
+	}
+	if depth == 0 {
+		return nil
+	}
+
+	ds, err := LoadDatasetRefs(ctx, fs, path)
+	if err != nil {
+		return err
+	}
+	if ds.Transform == nil {
+		return nil
+	}
+	for _, r := range ds.Transform.Resources {
+		if r == nil {
+			continue
+		}
+		ref, err := dsref.Parse(r.Path)
+		if err != nil || ref.Path == "" || visited[ref.Path] {
+			continue
+		}
+		if has, err := fs.Has(ctx, ref.Path); err != nil || !has {
+			log.Debugw("skip pinning upstream dataset", "path", ref.Path, "err", err)
+			continue
+		}
+		if err := pinDataset(ctx, fs, pinner, ref.Path, depth-1, visited); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,83 @@
+package dsfs
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/qri-io/dataset"
+	"github.com/qri-io/qfs"
+	testkeys "github.com/qri-io/qri/auth/key/test"
+	"github.com/qri-io/qri/event"
+)
+
+// pinRecordingFS is a MemFS that records pinned paths
+type pinRecordingFS struct {
+	*qfs.MemFS
+	pinned map[string]bool
+}
+
+var _ qfs.PinningFS = (*pinRecordingFS)(nil)
+
+func (fs *pinRecordingFS) Pin(ctx context.Context, key string, recursive bool) error {
+	fs.pinned[key] = true
+	return nil
+}
+
+func (fs *pinRecordingFS) Unpin(ctx context.Context, key string, recursive bool) error {
+	delete(fs.pinned, key)
+	return nil
+}
+
+func TestCreateDatasetPinDepth(t *testing.T) {
+	ctx := context.Background()
+	fs := &pinRecordingFS{MemFS: qfs.NewMemFS(), pinned: map[string]bool{}}
+	privKey := testkeys.GetKeyData(10).PrivKey
+
+	input := &dataset.Dataset{
+		Commit:    &dataset.Commit{},
+		Structure: &dataset.Structure{Format: "json", Schema: dataset.BaseSchemaArray},
+	}
+	input.SetBodyFile(qfs.NewMemfileBytes("/body.json", []byte(`["input"]`)))
+	inputPath, err := CreateDataset(ctx, fs, fs, event.NilBus, input, nil, privKey, SaveSwitches{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(fs.pinned) != 0 {
+		t.Errorf("expected saving without Pin to pin nothing, got: %v", fs.pinned)
+	}
+
+	newDerived := func(title string) *dataset.Dataset {
+		ds := &dataset.Dataset{
+			Commit:    &dataset.Commit{Title: title},
+			Structure: &dataset.Structure{Format: "json", Schema: dataset.BaseSchemaArray},
+			Transform: &dataset.Transform{
+				Syntax: "starlark",
+				Resources: map[string]*dataset.TransformResource{
+					inputPath: {Path: fmt.Sprintf("peer/input@%s", inputPath)},
+				},
+			},
+		}
+		ds.SetBodyFile(qfs.NewMemfileBytes("/body.json", []byte(fmt.Sprintf(`[%q]`, title))))
+		return ds
+	}
+
+	shallowPath, err := CreateDataset(ctx, fs, fs, event.NilBus, newDerived("shallow"), nil, privKey, SaveSwitches{Pin: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !fs.pinned[shallowPath] {
+		t.Errorf("expected saved dataset to be pinned")
+	}
+	if fs.pinned[inputPath] {
+		t.Errorf("expected shallow pin not to pin the input dataset")
+	}
+
+	deepPath, err := CreateDataset(ctx, fs, fs, event.NilBus, newDerived("deep"), nil, privKey, SaveSwitches{Pin: true, PinDepth: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !fs.pinned[deepPath] || !fs.pinned[inputPath] {
+		t.Errorf("expected a pin depth of 1 to pin the dataset & its input, got: %v", fs.pinned)
+	}
+}
@@ -0,0 +1,103 @@
+package dsfs
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"io/ioutil"
+
+	crypto "github.com/libp2p/go-libp2p-core/crypto"
+	"github.com/qri-io/dataset"
+	"github.com/qri-io/dataset/stepfile"
+	"github.com/qri-io/qfs"
+)
+
+var (
+	// ErrTransformNotSigned indicates a transform has no script signature
+	ErrTransformNotSigned = errors.New("transform script is not signed")
+	// ErrInvalidTransformSignature indicates a transform script doesn't match
+	// the signature stored with it
+	ErrInvalidTransformSignature = errors.New("transform script signature is invalid")
+)
+
+// signTransform signs the script of a transform, returning the signature
+// file to store alongside the transform. script is the contents of the
+// transform script file, nil for transforms defined by steps. transforms with
+// no script return a nil file
+func signTransform(pk crypto.PrivKey, tf *dataset.Transform, script []byte) (fs.File, error) {
+	data, err := transformSigningBytes(tf, script)
+	if err != nil || data == nil {
+		return nil, err
+	}
+	sig, err := pk.Sign(data)
+	if err != nil {
+		return nil, fmt.Errorf("signing transform script: %w", err)
+	}
+	return NewMemfileBytes(PackageFileTransformSignature.String(), sig), nil
+}
+
+// transformSigningBytes returns the script bytes a transform signature
+// covers: the steps in stepfile format if the transform has steps, otherwise
+// the script file. a nil result means the transform has no script to sign
+func transformSigningBytes(tf *dataset.Transform, script []byte) ([]byte, error) {
+	if len(tf.Steps) == 0 {
+		if len(script) == 0 {
+			return nil, nil
+		}
+		return script, nil
+	}
+	buf := &bytes.Buffer{}
+	if err := stepfile.Write(tf.Steps, buf); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// VerifyTransformSignature confirms the transform script of the dataset
+// stored at dsPath matches the signature saved with it, using the public key
+// of the dataset author. Transforms with a script that were saved without a
+// signature return ErrTransformNotSigned
+func VerifyTransformSignature(ctx context.Context, fs qfs.Filesystem, dsPath string, tf *dataset.Transform, pub crypto.PubKey) error {
+	if tf == nil {
+		return fmt.Errorf("no transform to verify")
+	}
+
+	var script []byte
+	if len(tf.Steps) == 0 && tf.ScriptPath != "" {
+		f, err := fs.Get(ctx, tf.ScriptPath)
+		if err != nil {
+			return fmt.Errorf("reading transform script: %w", err)
+		}
+		defer f.Close()
+		if script, err = ioutil.ReadAll(f); err != nil {
+			return fmt.Errorf("reading transform script: %w", err)
+		}
+	}
+	data, err := transformSigningBytes(tf, script)
+	if err != nil {
+		return err
+	}
+	if data == nil {
+		// no script, nothing to verify
+		return nil
+	}
+
+	f, err := fs.Get(ctx, PackageFilepath(fs, dsPath, PackageFileTransformSignature))
+	if err != nil {
+		log.Debugw("reading transform signature", "path", dsPath, "err", err)
+		return ErrTransformNotSigned
+	}
+	defer f.Close()
+	sig, err := ioutil.ReadAll(f)
+	if err != nil {
+		return fmt.Errorf("reading transform signature: %w", err)
+	}
+
+	valid, err := pub.Verify(data, sig)
+	if err != nil || !valid {
+		return ErrInvalidTransformSignature
+	}
+	return nil
+}
@@ -0,0 +1,74 @@
+package dsfs
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/qri-io/dataset"
+	"github.com/qri-io/qfs"
+	testkeys "github.com/qri-io/qri/auth/key/test"
+	"github.com/qri-io/qri/event"
+)
+
+func TestVerifyTransformSignature(t *testing.T) {
+	ctx := context.Background()
+	fs := qfs.NewMemFS()
+	privKey := testkeys.GetKeyData(10).PrivKey
+	pubKey := privKey.GetPublic()
+
+	ds := &dataset.Dataset{
+		Commit:    &dataset.Commit{},
+		Structure: &dataset.Structure{Format: "json", Schema: dataset.BaseSchemaArray},
+		Transform: &dataset.Transform{Syntax: "starlark"},
+	}
+	ds.SetBodyFile(qfs.NewMemfileBytes("/body.json", []byte(`[]`)))
+	ds.Transform.SetScriptFile(qfs.NewMemfileBytes("transform.star", []byte("print('hello')\n")))
+
+	path, err := CreateDataset(ctx, fs, fs, event.NilBus, ds, nil, privKey, SaveSwitches{SignTransform: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := LoadDataset(ctx, fs, path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := VerifyTransformSignature(ctx, fs, path, got.Transform, pubKey); err != nil {
+		t.Errorf("expected saved transform to verify, got: %s", err)
+	}
+
+	otherKey := testkeys.GetKeyData(1).PrivKey.GetPublic()
+	if err := VerifyTransformSignature(ctx, fs, path, got.Transform, otherKey); !errors.Is(err, ErrInvalidTransformSignature) {
+		t.Errorf("expected verifying with another key to fail with %q, got: %v", ErrInvalidTransformSignature, err)
+	}
+
+	tampered, err := fs.Put(ctx, qfs.NewMemfileBytes("transform.star", []byte("print('goodbye')\n")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	got.Transform.ScriptPath = tampered
+	if err := VerifyTransformSignature(ctx, fs, path, got.Transform, pubKey); !errors.Is(err, ErrInvalidTransformSignature) {
+		t.Errorf("expected tampered script to fail with %q, got: %v", ErrInvalidTransformSignature, err)
+	}
+
+	// transforms aren't signed without the SignTransform switch
+	ds = &dataset.Dataset{
+		Commit:    &dataset.Commit{Title: "unsigned"},
+		Structure: &dataset.Structure{Format: "json", Schema: dataset.BaseSchemaArray},
+		Transform: &dataset.Transform{Syntax: "starlark"},
+	}
+	ds.SetBodyFile(qfs.NewMemfileBytes("/body.json", []byte(`[]`)))
+	ds.Transform.SetScriptFile(qfs.NewMemfileBytes("transform.star", []byte("print('hello')\n")))
+	unsignedPath, err := CreateDataset(ctx, fs, fs, event.NilBus, ds, nil, privKey, SaveSwitches{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	unsigned, err := LoadDataset(ctx, fs, unsignedPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := VerifyTransformSignature(ctx, fs, unsignedPath, unsigned.Transform, pubKey); !errors.Is(err, ErrTransformNotSigned) {
+		t.Errorf("expected unsigned transform to fail with %q, got: %v", ErrTransformNotSigned, err)
+	}
+}
@@ -0,0 +1,204 @@
+package dsfs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"strings"
+
+	"github.com/ipfs/go-cid"
+	"github.com/qri-io/dataset"
+	"github.com/qri-io/qfs"
+)
+
+// BlockRef identifies a block of a stored dataset by the component it belongs
+// to. Component uses the same short labels as dag.Info, eg: "bd" for body
+type BlockRef struct {
+	Component string `json:"component"`
+	Path      string `json:"path"`
+	CID       string `json:"cid"`
+}
+
+// Verification reports the integrity of a stored dataset's blocks
+type Verification struct {
+	Path    string     `json:"path"`
+	Checked int        `json:"checked"`
+	Missing []BlockRef `json:"missing,omitempty"`
+	Corrupt []BlockRef `json:"corrupt,omitempty"`
+}
+
+// Intact is true when every checked block is present and matches its hash
+func (v *Verification) Intact() bool {
+	return len(v.Missing) == 0 && len(v.Corrupt) == 0
+}
+
+// VerifyDataset walks the blocks of a dataset stored at path, confirming each
+// block is present and its data hashes to its CID. Blocks that can't be
+// found are reported as missing, blocks that don't match their CID as
+// corrupt. VerifyDataset only returns an error if the dataset can't be
+// walked at all
+func VerifyDataset(ctx context.Context, fs qfs.Filesystem, path string) (*Verification, error) {
+	v := &Verification{Path: path}
+	root := BlockRef{Component: "ds", Path: path}
+	store, id, err := verifiableBlock(fs, path)
+	if err != nil {
+		return nil, err
+	}
+	root.CID = id.String()
+
+	// the root is present if its dataset file can be read. some stores can't
+	// read a root block with missing children, so blocks the root links to
+	// are checked by component below
+	v.Checked++
+	ds, err := LoadDatasetRefs(ctx, fs, path)
+	if err != nil {
+		if errors.Is(err, qfs.ErrNotFound) {
+			v.Missing = append(v.Missing, root)
+			return v, nil
+		}
+		return nil, err
+	}
+	if present, intact := checkBlock(store, id); present && !intact {
+		v.Corrupt = append(v.Corrupt, root)
+	}
+
+	for _, ref := range componentBlockRefs(ds) {
+		store, id, err := verifiableBlock(fs, ref.Path)
+		if err != nil {
+			return nil, fmt.Errorf("%s component: %w", ref.Component, err)
+		}
+		ref.CID = id.String()
+		verifyBlockTree(ctx, store, v, ref, id)
+	}
+	return v, nil
+}
+
+// VerifyComponents checks the component files of a dataset stored at path
+// hash to the paths dataset.json records for them, returning the components
+// that don't. Components that can't be found are returned as well. Only the
+// root block of each component is read & the body is skipped, making this a
+// quick way to spot a single damaged component like meta.json. Use
+// VerifyDataset to check every block
+func VerifyComponents(ctx context.Context, fs qfs.Filesystem, path string) ([]BlockRef, error) {
+	ds, err := LoadDatasetRefs(ctx, fs, path)
+	if err != nil {
+		return nil, err
+	}
+
+	mismatched := []BlockRef{}
+	for _, ref := range componentBlockRefs(ds) {
+		if ref.Component == "bd" {
+			continue
+		}
+		store, id, err := verifiableBlock(fs, ref.Path)
+		if err != nil {
+			return nil, fmt.Errorf("%s component: %w", ref.Component, err)
+		}
+		ref.CID = id.String()
+		if present, intact := checkBlock(store, id); !present || !intact {
+			mismatched = append(mismatched, ref)
+		}
+	}
+	return mismatched, nil
+}
+
+// componentBlockRefs lists the stored paths a dataset references
+func componentBlockRefs(ds *dataset.Dataset) []BlockRef {
+	refs := []BlockRef{}
+	add := func(component, path string) {
+		if path != "" && !strings.HasPrefix(path, "/inline/") {
+			refs = append(refs, BlockRef{Component: component, Path: path})
+		}
+	}
+
+	if ds.Commit != nil {
+		add("cm", ds.Commit.Path)
+	}
+	if ds.Meta != nil {
+		add("md", ds.Meta.Path)
+	}
+	if ds.Structure != nil {
+		add("st", ds.Structure.Path)
+	}
+	if ds.Stats != nil {
+		add("sa", ds.Stats.Path)
+	}
+	if ds.Transform != nil {
+		add("tf", ds.Transform.Path)
+		add("tf", ds.Transform.ScriptPath)
+	}
+	if ds.Readme != nil {
+		add("rm", ds.Readme.Path)
+		add("rm", ds.Readme.ScriptPath)
+	}
+	if ds.Viz != nil {
+		add("vz", ds.Viz.Path)
+		add("vz", ds.Viz.ScriptPath)
+		add("rd", ds.Viz.RenderedPath)
+	}
+	add("bd", ds.BodyPath)
+	return refs
+}
+
+// verifiableBlock resolves a path to the merkle dag store that holds it & the
+// CID of the block it names
+func verifiableBlock(fs qfs.Filesystem, path string) (qfs.MerkleDagStore, cid.Cid, error) {
+	id, err := cid.Parse(GetHashBase(path))
+	if err != nil {
+		return nil, cid.Cid{}, fmt.Errorf("path %q is not content-addressed: %w", path, err)
+	}
+
+	fsType := strings.SplitN(strings.TrimPrefix(path, "/"), "/", 2)[0]
+	if mux, ok := fs.(interface {
+		Filesystem(fsType string) qfs.Filesystem
+	}); ok {
+		fs = mux.Filesystem(fsType)
+	}
+	store, ok := fs.(qfs.MerkleDagStore)
+	if !ok || fs.Type() != fsType {
+		return nil, cid.Cid{}, fmt.Errorf("no merkle dag store for path %q", path)
+	}
+	return store, id, nil
+}
+
+// checkBlock reports whether a block can be read from a store, and if it
+// hashes to its CID. Directory blocks in some stores can't be read as raw
+// bytes, so only their presence can be confirmed
+func checkBlock(store qfs.MerkleDagStore, id cid.Cid) (present, intact bool) {
+	r, err := store.GetBlock(id)
+	if err != nil {
+		log.Debugw("verify: getting block", "cid", id, "err", err)
+		return false, false
+	}
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return true, true
+	}
+	sum, err := id.Prefix().Sum(data)
+	return true, err == nil && sum.Equals(id)
+}
+
+// verifyBlockTree checks a block & every block it links to
+func verifyBlockTree(ctx context.Context, store qfs.MerkleDagStore, v *Verification, ref BlockRef, id cid.Cid) {
+	if ctx.Err() != nil {
+		return
+	}
+	v.Checked++
+	present, intact := checkBlock(store, id)
+	if !present {
+		v.Missing = append(v.Missing, ref)
+		return
+	}
+	if !intact {
+		v.Corrupt = append(v.Corrupt, ref)
+	}
+	node, err := store.GetNode(id)
+	if err != nil {
+		return
+	}
+	for _, lnk := range node.Links().SortedSlice() {
+		child := BlockRef{Component: ref.Component, Path: ref.Path, CID: lnk.Cid.String()}
+		verifyBlockTree(ctx, store, v, child, lnk.Cid)
+	}
+}
@@ -0,0 +1,55 @@
+package dsfs
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/qri-io/dataset"
+	"github.com/qri-io/qfs"
+	testkeys "github.com/qri-io/qri/auth/key/test"
+	"github.com/qri-io/qri/event"
+)
+
+func TestVerifyComponents(t *testing.T) {
+	ctx := context.Background()
+	fs := qfs.NewMemFS()
+	privKey := testkeys.GetKeyData(10).PrivKey
+
+	ds := &dataset.Dataset{
+		Commit:    &dataset.Commit{},
+		Meta:      &dataset.Meta{Title: "verify components"},
+		Structure: &dataset.Structure{Format: "json", Schema: dataset.BaseSchemaArray},
+	}
+	ds.SetBodyFile(qfs.NewMemfileBytes("/body.json", []byte(`["a","b"]`)))
+	path, err := CreateDataset(ctx, fs, fs, event.NilBus, ds, nil, privKey, SaveSwitches{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := VerifyComponents(ctx, fs, path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 0 {
+		t.Fatalf("expected freshly saved dataset to have no mismatched components, got: %v", got)
+	}
+
+	refs, err := LoadDatasetRefs(ctx, fs, path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mdKey := GetHashBase(refs.Meta.Path)
+	if err := fs.PutFileAtKey(ctx, mdKey, qfs.NewMemfileBytes("meta.json", []byte(`{"title":"tampered"}`))); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err = VerifyComponents(ctx, fs, path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expect := []BlockRef{{Component: "md", Path: refs.Meta.Path, CID: mdKey}}
+	if diff := cmp.Diff(expect, got); diff != "" {
+		t.Errorf("mismatched components (-want +got):\n%s", diff)
+	}
+}
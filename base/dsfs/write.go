@@ -5,10 +5,14 @@ import (
 	"errors"
 	"fmt"
 	"io/fs"
+	"io/ioutil"
 	"strings"
 	"time"
 
 	cid "github.com/ipfs/go-cid"
+	files "github.com/ipfs/go-ipfs-files"
+	coreiface "github.com/ipfs/interface-go-ipfs-core"
+	caopts "github.com/ipfs/interface-go-ipfs-core/options"
 	crypto "github.com/libp2p/go-libp2p-core/crypto"
 	"github.com/qri-io/dataset"
 	"github.com/qri-io/dataset/dsviz"
@@ -46,6 +50,10 @@ type SaveSwitches struct {
 	Replace bool
 	// Pin is whether the dataset should be pinned
 	Pin bool
+	// PinDepth is how many levels of datasets read by the dataset's transform
+	// are pinned along with it. The default of 0 pins only the saved dataset,
+	// a negative depth pins every upstream dataset
+	PinDepth int
 	// ConvertFormatToPrev is whether the body should be converted to match the previous format
 	ConvertFormatToPrev bool
 	// ForceIfNoChanges is whether the save should be forced even if no changes are detected
@@ -60,6 +68,20 @@ type SaveSwitches struct {
 	Drop string
 	// parsed drop string into list of components
 	dropRevs []*dsref.Rev
+	// CommitMessage optionally customizes generated commit titles & messages
+	CommitMessage CommitMessageFunc
+	// PrettyJSON indents the JSON metadata files written for the dataset.
+	// Compact & indented files hash differently, so the same dataset saved in
+	// each mode has a different path
+	PrettyJSON bool
+	// BodyDiffThreshold is the largest body size in bytes that is diffed
+	// against the previous version to describe body changes in the commit
+	// message. 0 uses BodySizeSmallEnoughToDiff
+	BodyDiffThreshold int
+	// SignTransform signs the transform script with the author's private key,
+	// storing the signature as a package file. The signature file changes the
+	// path of the saved dataset
+	SignTransform bool
 
 	// action to take when calculating commit messages
 	// bodyAction is set by computeFieldsFile to feed data to the commit component
@@ -67,6 +89,15 @@ type SaveSwitches struct {
 	bodyAct BodyAction
 }
 
+// bodyDiffThreshold returns the body size limit for diffing, substituting
+// BodySizeSmallEnoughToDiff for a zero threshold
+func bodyDiffThreshold(threshold int) int {
+	if threshold > 0 {
+		return threshold
+	}
+	return BodySizeSmallEnoughToDiff
+}
+
 // CreateDataset writes a dataset to a provided store.
 // Store is where we're going to store the data
 // Dataset to be saved
@@ -139,6 +170,20 @@ func CreateDataset(
 		return "", err
 	}
 
+	if sw.Pin {
+		if err := PinDataset(ctx, destination, path, sw.PinDepth); err != nil {
+			if evtErr := pub.Publish(ctx, event.ETDatasetSaveCompleted, event.DsSaveEvent{
+				Username:   peername,
+				Name:       name,
+				Error:      err,
+				Completion: 1.0,
+			}); evtErr != nil {
+				log.Debugw("ignored error while publishing save completed", "evtErr", evtErr)
+			}
+			return path, err
+		}
+	}
+
 	// TODO (b5) - many codepaths that call this function use the `ds` arg after saving
 	// we need to dereference here so fields are set, but this is overkill if
 	// the caller doesn't use the ds arg afterward
@@ -208,7 +253,7 @@ func WriteDataset(
 	writeFuncs := []writeComponentFunc{
 		bodyFileFunc(ctx, pk, publisher),      // no deps
 		metadataFile,                          // no deps
-		transformFile,                         // no deps
+		transformFileFunc(pk),                 // no deps
 		structureFile,                         // requires bdoy if it exists
 		statsFile,                             // requires body, structure if they exist
 		readmeFile,                            // no deps
@@ -256,6 +301,16 @@ func bodyFileFunc(ctx context.Context, pk crypto.PrivKey, publisher event.Publis
 		}
 
 		sw.bodyAct = BodyDefault
+		// partitioned bodies are written as a single body file, recording the
+		// partition layout in the structure
+		var partitioned *PartitionedBodyFile
+		if ds.BodyFile().IsDirectory() {
+			if ds.Structure == nil {
+				return fmt.Errorf("partitioned bodies require a structure")
+			}
+			partitioned = NewPartitionedBodyFile(ds.Structure, ds.BodyFile())
+			ds.SetBodyFile(partitioned)
+		}
 		bodyFilename := bodyFilename(ds)
 		cff, err := newComputeFieldsFile(ctx, publisher, pk, ds, prev, sw)
 		if err != nil {
@@ -273,6 +328,9 @@ func bodyFileFunc(ctx context.Context, pk crypto.PrivKey, publisher event.Publis
 		if err := <-cff.(doneProcessingFile).DoneProcessing(); err != nil {
 			return err
 		}
+		if partitioned != nil {
+			SetBodyPartitions(ds.Structure, partitioned.Partitions())
+		}
 
 		log.Debugw("setting calculated stats")
 		ds.Stats, err = cff.(statsComponentFile).StatsComponent()
@@ -302,7 +360,7 @@ func structureFile(src qfs.Filesystem, dst qfs.MerkleDagStore, prev, ds *dataset
 		}
 	}
 
-	f, err := JSONFile(PackageFileStructure.String(), ds.Structure)
+	f, err := saveJSONFile(PackageFileStructure.String(), ds.Structure, sw)
 	if err != nil {
 		return err
 	}
@@ -319,44 +377,66 @@ func metadataFile(src qfs.Filesystem, dst qfs.MerkleDagStore, prev, ds *dataset.
 		return errNoComponent
 	}
 	ds.Meta.DropTransientValues()
-	f, err := JSONFile(PackageFileMeta.String(), ds.Meta)
+	f, err := saveJSONFile(PackageFileMeta.String(), ds.Meta, sw)
 	if err != nil {
 		return err
 	}
 	return writePackageFile(dst, f, added)
 }
 
-func transformFile(src qfs.Filesystem, dst qfs.MerkleDagStore, prev, ds *dataset.Dataset, added qfs.Links, sw *SaveSwitches) error {
-	if ds.Transform == nil {
-		return errNoComponent
-	}
+// transformFileFunc writes the transform component & script file, signing
+// the script with the author's private key when the SignTransform switch is
+// set
+func transformFileFunc(pk crypto.PrivKey) writeComponentFunc {
+	return func(src qfs.Filesystem, dst qfs.MerkleDagStore, prev, ds *dataset.Dataset, added qfs.Links, sw *SaveSwitches) error {
+		if ds.Transform == nil {
+			return errNoComponent
+		}
 
-	ds.Transform.DropTransientValues()
-	// TODO (b5): this is validation logic, should happen before WriteDataset is
-	// ever called.
-	// all resources must be references
-	for key, r := range ds.Transform.Resources {
-		if r.Path == "" {
-			return fmt.Errorf("transform resource %s requires a path to save", key)
+		ds.Transform.DropTransientValues()
+		// TODO (b5): this is validation logic, should happen before WriteDataset is
+		// ever called.
+		// all resources must be references
+		for key, r := range ds.Transform.Resources {
+			if r.Path == "" {
+				return fmt.Errorf("transform resource %s requires a path to save", key)
+			}
+		}
+
+		var script []byte
+		if tfsf := ds.Transform.ScriptFile(); tfsf != nil {
+			var err error
+			if script, err = ioutil.ReadAll(tfsf); err != nil {
+				return fmt.Errorf("reading transform script: %w", err)
+			}
+			if err := writePackageFile(dst, NewMemfileBytes(transformScriptFilename, script), added); err != nil {
+				return err
+			}
+			link := added.Get(transformScriptFilename)
+			ds.Transform.ScriptPath = fsPathFromCID(dst, link.Cid)
 		}
-	}
 
-	if tfsf := ds.Transform.ScriptFile(); tfsf != nil {
-		if err := writePackageFile(dst, NewMemfileReader(transformScriptFilename, tfsf), added); err != nil {
+		if pk != nil && sw.SignTransform {
+			sigFile, err := signTransform(pk, ds.Transform, script)
+			if err != nil {
+				return err
+			}
+			if sigFile != nil {
+				if err := writePackageFile(dst, sigFile, added); err != nil {
+					return err
+				}
+			}
+		}
+
+		// // transform component is inlined into dataset
+		// return errNoComponent
+		f, err := saveJSONFile(PackageFileTransform.String(), ds.Transform, sw)
+		if err != nil {
 			return err
 		}
-		link := added.Get(transformScriptFilename)
-		ds.Transform.ScriptPath = fsPathFromCID(dst, link.Cid)
-	}
 
-	// // transform component is inlined into dataset
-	// return errNoComponent
-	f, err := JSONFile(PackageFileTransform.String(), ds.Transform)
-	if err != nil {
-		return err
+		return writePackageFile(dst, f, added)
 	}
-
-	return writePackageFile(dst, f, added)
 }
 
 func statsFile(src qfs.Filesystem, dst qfs.MerkleDagStore, prev, ds *dataset.Dataset, added qfs.Links, sw *SaveSwitches) error {
@@ -374,7 +454,7 @@ func statsFile(src qfs.Filesystem, dst qfs.MerkleDagStore, prev, ds *dataset.Dat
 		}
 		return errNoComponent
 	}
-	f, err := JSONFile(PackageFileStats.String(), ds.Stats)
+	f, err := saveJSONFile(PackageFileStats.String(), ds.Stats, sw)
 	if err != nil {
 		return err
 	}
@@ -476,7 +556,7 @@ func writeDatasetFile(src qfs.Filesystem, dst qfs.MerkleDagStore, prev, ds *data
 	updateScriptPaths(dst, ds, added)
 	setComponentRefs(dst, ds, bodyFilename(ds), added)
 
-	f, err := JSONFile(PackageFileDataset.String(), ds)
+	f, err := saveJSONFile(PackageFileDataset.String(), ds, sw)
 	if err != nil {
 		return err
 	}
@@ -497,6 +577,36 @@ func updateScriptPaths(s qfs.MerkleDagStore, ds *dataset.Dataset, added qfs.Link
 	}
 }
 
+// BodyChecksum returns the value saving body data to a store would assign to
+// a structure's Checksum field. Checksums are computed without adding body
+// data to the store
+func BodyChecksum(ctx context.Context, s qfs.MerkleDagStore, body []byte) (string, error) {
+	return ComputeFilePath(ctx, s, body)
+}
+
+// ComputeFilePath returns the path adding data to a store as a file would
+// produce, without adding data to the store
+func ComputeFilePath(ctx context.Context, s qfs.MerkleDagStore, data []byte) (string, error) {
+	switch store := s.(type) {
+	case *qfs.MemFS:
+		// memfs addresses are a hash of file content, a scratch store computes
+		// the same address
+		res, err := qfs.NewMemFS().PutFile(NewMemfileBytes("file", data))
+		if err != nil {
+			return "", err
+		}
+		return fsPathFromCID(s, res.Cid), nil
+	case interface{ CoreAPI() coreiface.CoreAPI }:
+		// match the options IPFS stores add files with
+		path, err := store.CoreAPI().Unixfs().Add(ctx, files.NewBytesFile(data), caopts.Unixfs.CidVersion(0), caopts.Unixfs.HashOnly(true))
+		if err != nil {
+			return "", err
+		}
+		return fsPathFromCID(s, path.Root()), nil
+	}
+	return "", fmt.Errorf("computing file paths isn't supported by %T stores", s)
+}
+
 func fsPathFromCID(s qfs.MerkleDagStore, id cid.Cid) string {
 	fs := s.(qfs.Filesystem)
 	return fmt.Sprintf("/%s/%s", fs.Type(), id.String())
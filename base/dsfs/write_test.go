@@ -11,6 +11,7 @@ import (
 	"time"
 
 	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
 	"github.com/qri-io/dataset"
 	"github.com/qri-io/dataset/dsio"
 	"github.com/qri-io/dataset/dstest"
@@ -235,6 +236,85 @@ func TestDatasetSaveCustomTimestamp(t *testing.T) {
 	}
 }
 
+func TestDatasetSaveCommitMessageFunc(t *testing.T) {
+	ctx := context.Background()
+	fs := qfs.NewMemFS()
+	privKey := testkeys.GetKeyData(10).PrivKey
+
+	newDs := func(commit *dataset.Commit) *dataset.Dataset {
+		ds := &dataset.Dataset{
+			Commit:    commit,
+			Structure: &dataset.Structure{Format: "json", Schema: dataset.BaseSchemaArray},
+		}
+		ds.SetBodyFile(qfs.NewMemfileBytes("/body.json", []byte(`[]`)))
+		return ds
+	}
+
+	var gotGenerated CommitDescription
+	sw := SaveSwitches{
+		CommitMessage: func(ctx context.Context, ds, prev *dataset.Dataset, bodyAct BodyAction, generated CommitDescription) (CommitDescription, error) {
+			gotGenerated = generated
+			return CommitDescription{
+				Title:   "[TICKET-1] " + generated.Title,
+				Message: "custom message",
+			}, nil
+		},
+	}
+
+	path, err := CreateDataset(ctx, fs, fs, event.NilBus, newDs(&dataset.Commit{}), nil, privKey, sw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := LoadDataset(ctx, fs, path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if gotGenerated.Title != defaultCreatedDescription {
+		t.Errorf("expected hook to receive generated title %q, got %q", defaultCreatedDescription, gotGenerated.Title)
+	}
+	if expect := "[TICKET-1] created dataset"; got.Commit.Title != expect {
+		t.Errorf("commit title mismatch. want: %q, got: %q", expect, got.Commit.Title)
+	}
+	if expect := "custom message"; got.Commit.Message != expect {
+		t.Errorf("commit message mismatch. want: %q, got: %q", expect, got.Commit.Message)
+	}
+
+	// user-provided titles take precedence over the hook
+	path, err = CreateDataset(ctx, fs, fs, event.NilBus, newDs(&dataset.Commit{Title: "my title"}), nil, privKey, sw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, err = LoadDataset(ctx, fs, path); err != nil {
+		t.Fatal(err)
+	}
+	if expect := "my title"; got.Commit.Title != expect {
+		t.Errorf("commit title mismatch. want: %q, got: %q", expect, got.Commit.Title)
+	}
+	if expect := "custom message"; got.Commit.Message != expect {
+		t.Errorf("commit message mismatch. want: %q, got: %q", expect, got.Commit.Message)
+	}
+
+	// without a hook the generated description is used
+	path, err = CreateDataset(ctx, fs, fs, event.NilBus, newDs(&dataset.Commit{}), nil, privKey, SaveSwitches{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, err = LoadDataset(ctx, fs, path); err != nil {
+		t.Fatal(err)
+	}
+	if got.Commit.Title != defaultCreatedDescription {
+		t.Errorf("commit title mismatch. want: %q, got: %q", defaultCreatedDescription, got.Commit.Title)
+	}
+
+	// hook errors fail the save
+	sw.CommitMessage = func(ctx context.Context, ds, prev *dataset.Dataset, bodyAct BodyAction, generated CommitDescription) (CommitDescription, error) {
+		return CommitDescription{}, fmt.Errorf("oh noes")
+	}
+	if _, err = CreateDataset(ctx, fs, fs, event.NilBus, newDs(&dataset.Commit{}), nil, privKey, sw); err == nil {
+		t.Error("expected hook error to fail save")
+	}
+}
+
 func TestDatasetSaveEvents(t *testing.T) {
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
@@ -464,7 +544,7 @@ func TestGenerateCommitMessage(t *testing.T) {
 
 	for _, c := range badCases {
 		t.Run(fmt.Sprintf("%s", c.description), func(t *testing.T) {
-			_, _, err := generateCommitDescriptions(ctx, fs, c.ds, c.prev, BodySame, c.force)
+			_, _, err := generateCommitDescriptions(ctx, fs, c.ds, c.prev, BodySame, c.force, 0)
 			if err == nil {
 				t.Errorf("error expected, did not get one")
 			} else if c.errMsg != err.Error() {
@@ -747,7 +827,7 @@ sixteen,seventeen,18`),
 			if compareBody(c.prev.Body, c.ds.Body) {
 				bodyAct = BodySame
 			}
-			shortTitle, longMessage, err := generateCommitDescriptions(ctx, fs, c.ds, c.prev, bodyAct, c.force)
+			shortTitle, longMessage, err := generateCommitDescriptions(ctx, fs, c.ds, c.prev, bodyAct, c.force, 0)
 			if err != nil {
 				t.Errorf("error: %s", err.Error())
 				return
@@ -1012,3 +1092,166 @@ func BenchmarkValidateJSON(b *testing.B) {
 		})
 	}
 }
+
+func TestCreateDatasetPrettyJSON(t *testing.T) {
+	ctx := context.Background()
+	privKey := testkeys.GetKeyData(10).PrivKey
+	ts := time.Date(2100, 1, 2, 3, 4, 5, 6, time.UTC)
+
+	save := func(pretty bool) (qfs.Filesystem, string) {
+		fs := qfs.NewMemFS()
+		ds := &dataset.Dataset{
+			Commit:    &dataset.Commit{Title: "initial commit", Timestamp: ts},
+			Meta:      &dataset.Meta{Title: "pretty json"},
+			Structure: &dataset.Structure{Format: "json", Schema: dataset.BaseSchemaArray},
+		}
+		ds.SetBodyFile(qfs.NewMemfileBytes("/body.json", []byte(`[1,2,3]`)))
+		path, err := CreateDataset(ctx, fs, fs, event.NilBus, ds, nil, privKey, SaveSwitches{PrettyJSON: pretty})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return fs, path
+	}
+	datasetFileBytes := func(fs qfs.Filesystem, path string) []byte {
+		data, err := fileBytes(fs.Get(ctx, PackageFilepath(fs, path, PackageFileDataset)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return data
+	}
+
+	compactFS, compactPath := save(false)
+	prettyFS, prettyPath := save(true)
+
+	if compactPath == prettyPath {
+		t.Errorf("expected compact & pretty saves to have different paths")
+	}
+	compact := datasetFileBytes(compactFS, compactPath)
+	pretty := datasetFileBytes(prettyFS, prettyPath)
+	if bytes.Equal(compact, pretty) {
+		t.Errorf("expected compact & pretty dataset files to differ")
+	}
+	if !bytes.Contains(pretty, []byte("\n  ")) {
+		t.Errorf("expected pretty dataset file to be indented. got:\n%s", pretty)
+	}
+	if bytes.Contains(compact, []byte("\n")) {
+		t.Errorf("expected compact dataset file to have no newlines. got:\n%s", compact)
+	}
+
+	if _, again := save(true); again != prettyPath {
+		t.Errorf("expected repeated pretty saves to have the same path. want: %q got: %q", prettyPath, again)
+	}
+
+	compactDs, err := LoadDataset(ctx, compactFS, compactPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	prettyDs, err := LoadDataset(ctx, prettyFS, prettyPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// paths & signatures are derived from the stored bytes, which differ
+	ignorePaths := cmp.FilterPath(func(p cmp.Path) bool {
+		switch p.Last().String() {
+		case ".Path", ".Signature":
+			return true
+		}
+		return false
+	}, cmp.Ignore())
+	if diff := cmp.Diff(compactDs, prettyDs, ignorePaths, cmpopts.IgnoreUnexported(dataset.Dataset{}, dataset.Meta{}, dataset.Structure{}, dataset.Commit{})); diff != "" {
+		t.Errorf("expected compact & pretty datasets to load identically (-compact +pretty):\n%s", diff)
+	}
+}
+
+func TestBodyChecksum(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	ipfs, destroy, err := makeTestIPFSRepo(ctx, "")
+	if err != nil {
+		t.Fatalf("error creating IPFS test repo: %s", err)
+	}
+	defer destroy()
+
+	body := []byte(`[["a",1],["b",2]]`)
+	stores := []qfs.Filesystem{qfs.NewMemFS(), ipfs}
+	for _, fs := range stores {
+		t.Run(fs.Type(), func(t *testing.T) {
+			store := fs.(qfs.MerkleDagStore)
+			checksum, err := BodyChecksum(ctx, store, body)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			// computing a checksum doesn't store the body
+			getCtx, cancel := context.WithTimeout(ctx, time.Second)
+			defer cancel()
+			if _, err := fs.Get(getCtx, checksum); err == nil {
+				t.Error("expected computing a checksum not to add the body to the store")
+			}
+
+			res, err := store.PutFile(NewMemfileBytes("body.json", body))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if stored := fsPathFromCID(store, res.Cid); checksum != stored {
+				t.Errorf("checksum mismatch. computed: %q, stored: %q", checksum, stored)
+			}
+		})
+	}
+}
+
+// Test that the save's body diff threshold overrides the default
+func TestCreateDatasetBodyDiffThreshold(t *testing.T) {
+	ctx := context.Background()
+
+	prevBodySizeLimit := BodySizeSmallEnoughToDiff
+	defer func() { BodySizeSmallEnoughToDiff = prevBodySizeLimit }()
+	BodySizeSmallEnoughToDiff = 10
+
+	privKey := testkeys.GetKeyData(10).PrivKey
+	newDs := func(body string) *dataset.Dataset {
+		ds := &dataset.Dataset{
+			Commit:    &dataset.Commit{},
+			Structure: &dataset.Structure{Format: "json", Schema: dataset.BaseSchemaArray},
+		}
+		ds.SetBodyFile(qfs.NewMemfileBytes("body.json", []byte(body)))
+		return ds
+	}
+
+	save := func(sw SaveSwitches) string {
+		fs := qfs.NewMemFS()
+		prevPath, err := CreateDataset(ctx, fs, fs, event.NilBus, newDs(`[1,2,3,4,5,6,7,8,9]`), nil, privKey, SaveSwitches{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		prev, err := LoadDataset(ctx, fs, prevPath)
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, err := LoadBody(ctx, fs, prev)
+		if err != nil {
+			t.Fatal(err)
+		}
+		prev.SetBodyFile(body)
+		next := newDs(`[1,2,3,4,5,6,7,8,9,10]`)
+		next.PreviousPath = prevPath
+		path, err := CreateDataset(ctx, fs, fs, event.NilBus, next, prev, privKey, sw)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := LoadDataset(ctx, fs, path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return got.Commit.Message
+	}
+
+	if got := save(SaveSwitches{}); got != "body changed" {
+		t.Errorf("expected a body above the default threshold to get a summary message, got: %q", got)
+	}
+	// a raised threshold diffs the same body
+	if got := save(SaveSwitches{BodyDiffThreshold: 1000}); got != "body:\n\tadded row 9" {
+		t.Errorf("expected a body below a raised threshold to get a detailed message, got: %q", got)
+	}
+}
@@ -81,17 +81,36 @@ func RenameDatasetRef(ctx context.Context, r repo.Repo, author *profile.Profile,
 	if err != nil && err != logbook.ErrNoLogbook {
 		return nil, err
 	}
+	wroteLog := err == nil
+
+	// rollback undoes the logbook rename if updating the refstore fails, so
+	// logbook, dscache & refstore stay in agreement. Logbooks are append-only,
+	// so the undo is a second rename back to the original name
+	rollback := func(cause error) error {
+		if wroteLog {
+			if err := r.Logbook().WriteDatasetRename(ctx, author, ref.InitID, ref.Name); err != nil {
+				log.Errorw("rolling back dataset rename", "initID", ref.InitID, "err", err)
+			}
+		}
+		return cause
+	}
 
 	// use the versionInfo returned from the delete to preserve fields like
 	// FSIPath when replaced
 	vi, err := repo.DeleteVersionInfoShim(ctx, r, ref)
 	if err != nil {
-		return nil, err
+		return nil, rollback(err)
 	}
+	prev := *vi
 	vi.InitID = ref.InitID
 	vi.Name = newName
-	err = repo.PutVersionInfoShim(ctx, r, vi)
-	return vi, err
+	if err = repo.PutVersionInfoShim(ctx, r, vi); err != nil {
+		if putErr := repo.PutVersionInfoShim(ctx, r, &prev); putErr != nil {
+			log.Errorw("restoring dataset reference after failed rename", "ref", ref.Human(), "err", putErr)
+		}
+		return nil, rollback(err)
+	}
+	return vi, nil
 }
 
 // ModifyRepoUsername performs all tasks necessary to switch a username
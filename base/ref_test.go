@@ -2,6 +2,7 @@ package base
 
 import (
 	"context"
+	"fmt"
 	"testing"
 	"time"
 
@@ -241,3 +242,59 @@ func TestReplaceRefIfMoreRecent(t *testing.T) {
 		}
 	}
 }
+
+// failPutRefRepo is a repo that can't store references under a given name
+type failPutRefRepo struct {
+	repo.Repo
+	name string
+}
+
+func (r failPutRefRepo) PutRef(ref reporef.DatasetRef) error {
+	if ref.Name == r.name {
+		return fmt.Errorf("refstore unavailable")
+	}
+	return r.Repo.PutRef(ref)
+}
+
+func TestRenameDatasetRefRollback(t *testing.T) {
+	ctx := context.Background()
+	r := newTestRepo(t)
+	ref := addCitiesDataset(t, r)
+	author := r.Profiles().Owner(ctx)
+	initID, err := r.Logbook().WriteDatasetInit(ctx, author, ref.Name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ref.InitID = initID
+
+	failing := failPutRefRepo{Repo: r, name: "renamed_cities"}
+	if _, err := RenameDatasetRef(ctx, failing, author, ref, "renamed_cities"); err == nil {
+		t.Fatal("expected rename with a failing refstore to error")
+	}
+
+	old := dsref.Ref{Username: ref.Username, Name: ref.Name}
+	if _, err := r.ResolveRef(ctx, &old); err != nil {
+		t.Errorf("expected original name to resolve after rollback, got: %s", err)
+	}
+	if _, err := repo.GetVersionInfoShim(r, dsref.Ref{Username: ref.Username, Name: ref.Name}); err != nil {
+		t.Errorf("expected original reference to be restored, got: %s", err)
+	}
+	if name, err := r.Logbook().DatasetRef(ctx, old); err != nil {
+		t.Errorf("expected logbook to resolve original name, got: %s", err)
+	} else if name.Name() != ref.Name {
+		t.Errorf("logbook name mismatch. expected: %q, got: %q", ref.Name, name.Name())
+	}
+
+	renamed := dsref.Ref{Username: ref.Username, Name: "renamed_cities"}
+	if _, err := r.ResolveRef(ctx, &renamed); err == nil {
+		t.Errorf("expected new name not to resolve after rollback")
+	}
+
+	vi, err := RenameDatasetRef(ctx, r, author, ref, "renamed_cities")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if vi.Name != "renamed_cities" {
+		t.Errorf("expected renamed version info, got name %q", vi.Name)
+	}
+}
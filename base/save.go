@@ -11,10 +11,12 @@ import (
 	"github.com/qri-io/dataset/detect"
 	"github.com/qri-io/dataset/validate"
 	"github.com/qri-io/qfs"
+	"github.com/qri-io/qfs/qipfs"
 	"github.com/qri-io/qri/automation/run"
 	"github.com/qri-io/qri/base/dsfs"
 	"github.com/qri-io/qri/dsref"
 	qerr "github.com/qri-io/qri/errors"
+	"github.com/qri-io/qri/event"
 	"github.com/qri-io/qri/logbook"
 	"github.com/qri-io/qri/profile"
 	"github.com/qri-io/qri/repo"
@@ -40,6 +42,115 @@ func SaveDataset(
 		return nil, fmt.Errorf("SaveDataset requires an initID")
 	}
 
+	changes, prev, err := prepareSaveChanges(ctx, r, author, prevPath, changes, sw)
+	if err != nil {
+		return nil, err
+	}
+
+	// Write the dataset to storage and get back the new path
+	ds, err = CreateDataset(ctx, r, writeDest, author, changes, prev, sw)
+	if err != nil {
+		return nil, err
+	}
+	ds.ID = initID
+
+	// Write the save to logbook
+	if err = r.Logbook().WriteVersionSave(ctx, author, ds, runState); err != nil {
+		return nil, err
+	}
+	ds.ID = initID
+
+	vi := dsref.ConvertDatasetToVersionInfo(ds)
+	if runState != nil {
+		vi.RunID = runState.ID
+		vi.RunDuration = runState.Duration
+		vi.RunStatus = string(runState.Status)
+	}
+	if err = r.Bus().Publish(ctx, event.ETDatasetSaved, vi); err != nil {
+		log.Debugw("publishing dataset saved event", "initID", initID, "err", err)
+	}
+	return ds, nil
+}
+
+// ComputeDatasetCID runs the save pipeline for a new version of a dataset
+// without storing anything, returning the CID the version would be saved at.
+// The version is written to a throwaway in-memory store that hashes the same
+// way as writeDest. Saving the same changes with the same switches & commit
+// timestamp produces a version with the returned CID. Changes that don't alter
+// the dataset return the CID of prevPath.
+//
+// Commit timestamps are part of the hashed content. When changes don't set
+// Commit.Timestamp the computed version is stamped with the current time, and
+// won't match a later save. Without a timestamp only the unchanged case, which
+// returns the CID of prevPath, is reliable
+func ComputeDatasetCID(
+	ctx context.Context,
+	r repo.Repo,
+	writeDest qfs.Filesystem,
+	author *profile.Profile,
+	prevPath string,
+	changes *dataset.Dataset,
+	sw SaveSwitches,
+) (string, error) {
+	ds, prev, err := prepareSaveChanges(ctx, r, author, prevPath, changes, sw)
+	if err != nil {
+		return "", err
+	}
+	if ds.Name == "" {
+		return "", fmt.Errorf("cannot create dataset without a name")
+	}
+	if err := Drop(ds, sw.Drop); err != nil {
+		return "", err
+	}
+	if err := validate.Dataset(ds); err != nil {
+		return "", fmt.Errorf("invalid dataset: %w", err)
+	}
+
+	scratch, err := scratchFilesystem(ctx, writeDest)
+	if err != nil {
+		return "", err
+	}
+
+	// write with dsfs.WriteDataset instead of dsfs.CreateDataset, which loads
+	// the result back from the destination. unchanged components are linked
+	// from the previous version, and won't be present in the scratch store
+	src := r.Filesystem()
+	if err := dsfs.DerefDataset(ctx, src, ds); err != nil {
+		return "", err
+	}
+	if !prev.IsEmpty() {
+		if err := dsfs.DerefDataset(ctx, src, prev); err != nil {
+			return "", err
+		}
+	}
+	path, err := dsfs.WriteDataset(ctx, src, scratch, prev, ds, event.NilBus, author.PrivKey, sw)
+	if errors.Is(err, dsfs.ErrNoChanges) {
+		// an unchanged dataset keeps the CID of the previous version
+		return filepath.Base(prevPath), nil
+	}
+	if err != nil {
+		return "", err
+	}
+	return filepath.Base(path), nil
+}
+
+// scratchFilesystem creates an empty in-memory filesystem that hashes files
+// the same way as fs
+func scratchFilesystem(ctx context.Context, fs qfs.Filesystem) (qfs.Filesystem, error) {
+	switch fs.Type() {
+	case qfs.MemFilestoreType:
+		return qfs.NewMemFS(), nil
+	case qipfs.FilestoreType:
+		return newIPFSScratchStore(ctx), nil
+	}
+	return nil, fmt.Errorf("computing CIDs for %q filesystems is not supported", fs.Type())
+}
+
+// prepareSaveChanges loads the version at prevPath & combines it with changes
+// to build the dataset version a save will write. It returns the new version
+// and the previous version, which is empty if prevPath is empty
+func prepareSaveChanges(ctx context.Context, r repo.Repo, author *profile.Profile, prevPath string, changes *dataset.Dataset, sw SaveSwitches) (*dataset.Dataset, *dataset.Dataset, error) {
+	var err error
 	prev := &dataset.Dataset{}
 	mutable := &dataset.Dataset{}
 	fs := r.Filesystem()
@@ -47,20 +158,20 @@ func SaveDataset(
 		// Load the dataset's most recent version, which will become the previous version after
 		// this save operation completes.
 		if prev, err = dsfs.LoadDataset(ctx, fs, prevPath); err != nil {
-			return
+			return nil, nil, err
 		}
 		if prev.BodyPath != "" {
 			var body qfs.File
 			body, err = dsfs.LoadBody(ctx, fs, prev)
 			if err != nil {
-				return nil, err
+				return nil, nil, err
 			}
 			prev.SetBodyFile(body)
 		}
 		// Load a mutable copy of the dataset because most of the save path assuming we are doing
 		// a patch update to the current head, and not a full replacement.
 		if mutable, err = dsfs.LoadDataset(ctx, fs, prevPath); err != nil {
-			return nil, err
+			return nil, nil, err
 		}
 
 		// remove the commit. commit must be created from scratch with each new version
@@ -72,17 +183,15 @@ func SaveDataset(
 		log.Debugf("body formats differ. prev=%q new=%q", prev.Structure.Format, changes.Structure.Format)
 		if sw.ConvertFormatToPrev {
 			log.Debugf("changing structure format prev=%q new=%q", prev.Structure.Format, changes.Structure.Format)
-			var f qfs.File
-			f, err = ConvertBodyFormat(changes.BodyFile(), changes.Structure, prev.Structure)
+			f, err := ConvertBodyFormat(changes.BodyFile(), changes.Structure, prev.Structure)
 			if err != nil {
-				return nil, err
+				return nil, nil, err
 			}
 			// Set the new format on the change structure.
 			changes.Structure.Format = prev.Structure.Format
 			changes.SetBodyFile(f)
 		} else {
-			err = fmt.Errorf("Refusing to change structure from %s to %s", prev.Structure.Format, changes.Structure.Format)
-			return nil, err
+			return nil, nil, fmt.Errorf("Refusing to change structure from %s to %s", prev.Structure.Format, changes.Structure.Format)
 		}
 	}
 
@@ -94,25 +203,12 @@ func SaveDataset(
 
 	// infer missing values
 	if err = InferValues(author, changes); err != nil {
-		return
+		return nil, nil, err
 	}
 
 	// let's make history, if it exists
 	changes.PreviousPath = prevPath
-
-	// Write the dataset to storage and get back the new path
-	ds, err = CreateDataset(ctx, r, writeDest, author, changes, prev, sw)
-	if err != nil {
-		return nil, err
-	}
-	ds.ID = initID
-
-	// Write the save to logbook
-	if err = r.Logbook().WriteVersionSave(ctx, author, ds, runState); err != nil {
-		return nil, err
-	}
-	ds.ID = initID
-	return ds, nil
+	return changes, prev, nil
 }
 
 // CreateDataset uses dsfs to add a dataset to a repo's store, updating the refstore
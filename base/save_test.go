@@ -4,6 +4,9 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
 	"testing"
 	"time"
 
@@ -11,6 +14,7 @@ import (
 	"github.com/qri-io/dataset"
 	"github.com/qri-io/qfs"
 	"github.com/qri-io/qfs/muxfs"
+	"github.com/qri-io/qfs/qipfs"
 	"github.com/qri-io/qri/dsref"
 	"github.com/qri-io/qri/event"
 	"github.com/qri-io/qri/logbook"
@@ -325,3 +329,120 @@ func datasetSchemaToJSON(ds *dataset.Dataset) string {
 	}
 	return string(js)
 }
+
+func TestSaveDatasetPublishesSaved(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	fs, err := muxfs.New(ctx, []qfs.Config{{Type: "mem"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	bus := event.NewBus(ctx)
+	r, err := repo.NewMemRepoWithProfile(ctx, testPeerProfile, fs, bus)
+	if err != nil {
+		t.Fatal(err)
+	}
+	run := &TestRunner{Context: ctx, Repo: r}
+
+	saved := []dsref.VersionInfo{}
+	bus.SubscribeTypes(func(_ context.Context, e event.Event) error {
+		if vi, ok := e.Payload.(dsref.VersionInfo); ok {
+			saved = append(saved, vi)
+		}
+		return nil
+	}, event.ETDatasetSaved)
+
+	ds := run.BuildDataset("saved_event", "json")
+	ds.Meta = &dataset.Meta{Title: "saved event"}
+	ds.SetBodyFile(qfs.NewMemfileBytes("body.json", []byte(`["a","b","c"]`)))
+	ref, err := run.SaveDataset(ds)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ds = run.BuildDataset("saved_event", "json")
+	ds.SetBodyFile(qfs.NewMemfileBytes("body.json", []byte(`["a","b","c","d"]`)))
+	if ref, err = run.SaveDataset(ds); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(saved) != 2 {
+		t.Fatalf("expected one saved event per save, got %d", len(saved))
+	}
+	got := saved[1]
+	if got.InitID != ref.InitID || got.Path != ref.Path || got.Name != "saved_event" {
+		t.Errorf("saved event doesn't describe the saved version. want ref %#v, got: %#v", ref, got)
+	}
+	if got.BodyRows != 4 || got.BodySize == 0 || got.BodyFormat != "json" {
+		t.Errorf("expected saved event to include body stats, got rows=%d size=%d format=%q", got.BodyRows, got.BodySize, got.BodyFormat)
+	}
+	if got.MetaTitle != "saved event" {
+		t.Errorf("expected meta title %q, got %q", "saved event", got.MetaTitle)
+	}
+}
+
+func TestComputeDatasetCIDIPFS(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	tmp, err := ioutil.TempDir("", "compute_cid_ipfs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmp)
+	ipfsPath := filepath.Join(tmp, ".ipfs")
+	if err := qipfs.InitRepo(ipfsPath, ""); err != nil {
+		t.Fatal(err)
+	}
+	fs, err := muxfs.New(ctx, []qfs.Config{
+		{Type: "ipfs", Config: map[string]interface{}{"path": ipfsPath}},
+		{Type: "mem"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, err := repo.NewMemRepoWithProfile(ctx, testPeerProfile, fs, event.NilBus)
+	if err != nil {
+		t.Fatal(err)
+	}
+	run := &TestRunner{Context: ctx, Repo: r}
+	writeDest := r.Filesystem().DefaultWriteFS()
+	author := r.Profiles().Owner(ctx)
+
+	// bodies span multiple chunks to check files are split the same way
+	newDs := func(entries int) *dataset.Dataset {
+		ds := run.BuildDataset("compute_cid", "json")
+		ds.Commit = &dataset.Commit{Title: "compute", Timestamp: time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)}
+		body := make([]int, entries)
+		for i := range body {
+			body[i] = i
+		}
+		data, err := json.Marshal(body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ds.SetBodyFile(qfs.NewMemfileBytes("body.json", data))
+		return ds
+	}
+
+	prevPath := ""
+	for i, entries := range []int{60000, 60001} {
+		cid, err := ComputeDatasetCID(ctx, r, writeDest, author, prevPath, newDs(entries), SaveSwitches{})
+		if err != nil {
+			t.Fatalf("version %d: %s", i, err)
+		}
+		if has, _ := writeDest.Has(ctx, "/ipfs/"+cid); has {
+			t.Errorf("version %d: expected computing a CID not to write the version", i)
+		}
+
+		ref, err := run.SaveDataset(newDs(entries))
+		if err != nil {
+			t.Fatalf("version %d: %s", i, err)
+		}
+		if got := filepath.Base(ref.Path); got != cid {
+			t.Errorf("version %d: saved path %q doesn't match computed CID %q", i, ref.Path, cid)
+		}
+		prevPath = ref.Path
+	}
+}
@@ -0,0 +1,210 @@
+package base
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"io/fs"
+	"strings"
+
+	blockservice "github.com/ipfs/go-blockservice"
+	cid "github.com/ipfs/go-cid"
+	datastore "github.com/ipfs/go-datastore"
+	dssync "github.com/ipfs/go-datastore/sync"
+	blockstore "github.com/ipfs/go-ipfs-blockstore"
+	chunker "github.com/ipfs/go-ipfs-chunker"
+	offline "github.com/ipfs/go-ipfs-exchange-offline"
+	ipld "github.com/ipfs/go-ipld-format"
+	merkledag "github.com/ipfs/go-merkledag"
+	unixfs "github.com/ipfs/go-unixfs"
+	importer "github.com/ipfs/go-unixfs/importer"
+	uio "github.com/ipfs/go-unixfs/io"
+	"github.com/qri-io/qfs"
+	"github.com/qri-io/qfs/qipfs"
+)
+
+// ipfsScratchStore is an in-memory merkle-DAG store that hashes files & nodes
+// the same way as an IPFS filestore. It's backed by an offline DAG service
+// over a map datastore, which avoids the cost of building an IPFS node
+type ipfsScratchStore struct {
+	ctx context.Context
+	dag ipld.DAGService
+}
+
+var (
+	_ qfs.Filesystem     = (*ipfsScratchStore)(nil)
+	_ qfs.MerkleDagStore = (*ipfsScratchStore)(nil)
+	_ qfs.CAFS           = (*ipfsScratchStore)(nil)
+)
+
+func newIPFSScratchStore(ctx context.Context) *ipfsScratchStore {
+	bs := blockstore.NewBlockstore(dssync.MutexWrap(datastore.NewMapDatastore()))
+	return &ipfsScratchStore{
+		ctx: ctx,
+		dag: merkledag.NewDAGService(blockservice.New(bs, offline.Exchange(bs))),
+	}
+}
+
+// Type reports the IPFS filestore type, paths written to a scratch store match
+// the paths an IPFS filestore would write
+func (s *ipfsScratchStore) Type() string { return qipfs.FilestoreType }
+
+// IsContentAddressedFilesystem marks the store as content-addressed
+func (s *ipfsScratchStore) IsContentAddressedFilesystem() {}
+
+// GetNode fetches a linked data node
+func (s *ipfsScratchStore) GetNode(id cid.Cid, path ...string) (qfs.DagNode, error) {
+	if len(path) > 0 {
+		return nil, fmt.Errorf("unsupported: path values on scratch store GetNode")
+	}
+	nd, err := s.dag.Get(s.ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	size, err := nd.Size()
+	if err != nil {
+		return nil, err
+	}
+	return scratchDagNode{node: nd, size: int64(size)}, nil
+}
+
+// PutNode adds a directory node of links
+func (s *ipfsScratchStore) PutNode(links qfs.Links) (qfs.PutResult, error) {
+	node := unixfs.EmptyDirNode()
+	node.SetCidBuilder(cid.V0Builder{})
+	for name, lnk := range links.Map() {
+		node.AddRawLink(name, lnk.IPLD())
+	}
+	if err := s.dag.Add(s.ctx, node); err != nil {
+		return qfs.PutResult{}, err
+	}
+	size, err := node.Size()
+	if err != nil {
+		return qfs.PutResult{}, err
+	}
+	return qfs.PutResult{Cid: node.Cid(), Size: int64(size)}, nil
+}
+
+// GetBlock reads the raw bytes of a block
+func (s *ipfsScratchStore) GetBlock(id cid.Cid) (io.Reader, error) {
+	nd, err := s.dag.Get(s.ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return bytes.NewReader(nd.RawData()), nil
+}
+
+// PutBlock adds a raw block
+func (s *ipfsScratchStore) PutBlock(d []byte) (cid.Cid, error) {
+	nd := merkledag.NewRawNode(d)
+	if err := s.dag.Add(s.ctx, nd); err != nil {
+		return cid.Cid{}, err
+	}
+	return nd.Cid(), nil
+}
+
+// PutFile adds a file using the default unixfs chunker & balanced layout with
+// CIDv0, matching the IPFS filestore's PutFile
+func (s *ipfsScratchStore) PutFile(f fs.File) (qfs.PutResult, error) {
+	return s.addFile(f)
+}
+
+func (s *ipfsScratchStore) addFile(r io.Reader) (qfs.PutResult, error) {
+	nd, err := importer.BuildDagFromReader(s.dag, chunker.DefaultSplitter(r))
+	if err != nil {
+		return qfs.PutResult{}, err
+	}
+	dr, err := uio.NewDagReader(s.ctx, nd, s.dag)
+	if err != nil {
+		return qfs.PutResult{}, err
+	}
+	return qfs.PutResult{Cid: nd.Cid(), Size: int64(dr.Size())}, nil
+}
+
+// GetFile reads a file added with PutFile
+func (s *ipfsScratchStore) GetFile(root cid.Cid, path ...string) (io.ReadCloser, error) {
+	if len(path) > 0 {
+		return nil, fmt.Errorf("unsupported: path values on scratch store GetFile")
+	}
+	nd, err := s.dag.Get(s.ctx, root)
+	if err != nil {
+		return nil, err
+	}
+	return uio.NewDagReader(s.ctx, nd, s.dag)
+}
+
+// Has returns whether the store contains a path
+func (s *ipfsScratchStore) Has(ctx context.Context, path string) (bool, error) {
+	id, err := s.pathCid(path)
+	if err != nil {
+		return false, err
+	}
+	if _, err := s.dag.Get(ctx, id); err != nil {
+		if err == ipld.ErrNotFound {
+			return false, nil
+		}
+		return false, err
+	}
+	return true, nil
+}
+
+// Get reads a file at a path
+func (s *ipfsScratchStore) Get(ctx context.Context, path string) (qfs.File, error) {
+	id, err := s.pathCid(path)
+	if err != nil {
+		return nil, err
+	}
+	r, err := s.GetFile(id)
+	if err != nil {
+		return nil, err
+	}
+	return qfs.NewMemfileReader(path, r), nil
+}
+
+// Put adds a file, returning its path
+func (s *ipfsScratchStore) Put(ctx context.Context, file qfs.File) (string, error) {
+	res, err := s.addFile(file)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("/%s/%s", s.Type(), res.Cid), nil
+}
+
+// Delete removes the root node at a path
+func (s *ipfsScratchStore) Delete(ctx context.Context, path string) error {
+	id, err := s.pathCid(path)
+	if err != nil {
+		return err
+	}
+	return s.dag.Remove(ctx, id)
+}
+
+func (s *ipfsScratchStore) pathCid(path string) (cid.Cid, error) {
+	prefix := "/" + s.Type() + "/"
+	if !strings.HasPrefix(path, prefix) {
+		return cid.Cid{}, qfs.ErrNotFound
+	}
+	return cid.Parse(strings.TrimPrefix(path, prefix))
+}
+
+type scratchDagNode struct {
+	node ipld.Node
+	size int64
+}
+
+var _ qfs.DagNode = (*scratchDagNode)(nil)
+
+func (n scratchDagNode) Size() int64  { return n.size }
+func (n scratchDagNode) Cid() cid.Cid { return n.node.Cid() }
+func (n scratchDagNode) Links() qfs.Links {
+	links := qfs.NewLinks()
+	for _, link := range n.node.Links() {
+		links.Add(qfs.Link{
+			Name: link.Name,
+			Cid:  link.Cid,
+			Size: int64(link.Size),
+		})
+	}
+	return links
+}
@@ -2,7 +2,9 @@ package cmd
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
+	"fmt"
 	"path/filepath"
 	"strings"
 
@@ -39,6 +41,7 @@ type AnalyzeTransformOptions struct {
 	ioes.IOStreams
 	Instance *lib.Instance
 	FilePath string
+	JSON     bool
 }
 
 // Complete adds any missing configuration that can only be added just before calling Run
@@ -46,6 +49,9 @@ func (o *AnalyzeTransformOptions) Complete(f Factory, args []string) (err error)
 	if o.Instance, err = f.Instance(); err != nil {
 		return err
 	}
+	if len(args) > 0 {
+		o.FilePath = args[0]
+	}
 	o.FilePath, err = filepath.Abs(o.FilePath)
 	return err
 }
@@ -68,12 +74,31 @@ func (o *AnalyzeTransformOptions) Run() (err error) {
 		return err
 	}
 
+	if o.JSON {
+		data, err := json.MarshalIndent(res, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Fprintln(o.Out, string(data))
+		return nil
+	}
+
 	for _, msg := range res.Diagnostics {
-		if msg.Category == "unused" {
+		switch msg.Category {
+		case "unused":
 			printWarning(o.Out, "Function unused: %s", msg.Message)
-		} else {
+		case "recursion":
+			printWarning(o.Out, "Function is recursive: %s", msg.Message)
+		case "leak":
+			printWarning(o.Out, "Sensitive data leak: %s", msg.Message)
+		case "parse":
+			printWarning(o.Out, "Parse error at %s: %s", msg.Pos, msg.Message)
+		default:
 			printWarning(o.Out, "Unknown warning: %s", msg.Message)
 		}
 	}
+	if res.CallGraph != "" {
+		printInfo(o.Out, "Call graph:\n%s", strings.TrimSuffix(res.CallGraph, "\n"))
+	}
 	return nil
 }
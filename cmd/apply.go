@@ -45,6 +45,9 @@ the --apply flag on the save command to commit results from transforms.`,
 	cmd.MarkFlagRequired("file")
 	cmd.Flags().StringSliceVar(&o.Secrets, "secrets", nil, "transform secrets as comma separated key,value,key,value,... sequence")
 	cmd.Flags().BoolVar(&o.Quiet, "quiet", false, "whether to suppress output from the application")
+	cmd.Flags().BoolVar(&o.Deterministic, "deterministic", false, "run with a fixed clock and seeded random numbers for reproducible output")
+	cmd.Flags().Int64Var(&o.Seed, "seed", 0, "random seed to use with --deterministic")
+	cmd.Flags().BoolVar(&o.VerifyBody, "verify-body", false, "confirm bodies the transform leaves unchanged by comparing checksums with the previous version")
 
 	return cmd
 }
@@ -59,6 +62,10 @@ type ApplyOptions struct {
 	FilePath string
 	Quiet    bool
 	Secrets  []string
+
+	Deterministic bool
+	Seed          int64
+	VerifyBody    bool
 }
 
 // Complete adds any missing configuration that can only be added just before calling Run
@@ -102,10 +109,13 @@ func (o *ApplyOptions) Run() (err error) {
 	}
 
 	params := lib.ApplyParams{
-		Ref:          o.Refs.Ref(),
-		Transform:    &tf,
-		ScriptOutput: o.Out,
-		Wait:         true,
+		Ref:                o.Refs.Ref(),
+		Transform:          &tf,
+		ScriptOutput:       o.Out,
+		Wait:               true,
+		Deterministic:      o.Deterministic,
+		Seed:               o.Seed,
+		VerifyBodyChecksum: o.VerifyBody,
 	}
 
 	terminalWidth, terminalHeight := sizeOfTerminal()
@@ -373,7 +373,7 @@ func TestSaveDatasetWithComponentError(t *testing.T) {
 	}
 }
 
-// Test that saving with two components of the same kind is an error
+// Test that saving with two files that define a body is an error
 func TestSaveConflictingComponents(t *testing.T) {
 	if err := confirmQriNotRunning(); err != nil {
 		t.Skip(err.Error())
@@ -384,12 +384,12 @@ func TestSaveConflictingComponents(t *testing.T) {
 
 	// Save two versions, but second has a conflict error
 	run.MustExec(t, "qri save --file=testdata/movies/ds_ten.yaml me/test_ds")
-	err := run.ExecCommand("qri save --file=testdata/movies/meta_override.yaml --file=testdata/movies/meta_override.yaml me/test_ds")
+	err := run.ExecCommand("qri save --file=testdata/movies/body_ten.csv --file=testdata/movies/body_twenty.csv me/test_ds")
 	if err == nil {
-		t.Errorf("expected error, did not get one")
+		t.Fatalf("expected error, did not get one")
 	}
-	expect := `conflict, multiple components of kind "md"`
-	if err.Error() != expect {
+	expect := `conflict, multiple files define a dataset body`
+	if !strings.HasPrefix(err.Error(), expect) {
 		t.Errorf("expected error: \"%s\", got: \"%s\"", expect, err.Error())
 	}
 }
@@ -482,7 +482,7 @@ func TestSaveTransformModifiedButSameBody(t *testing.T) {
     created dataset from tf_123.star
 
 `, map[string]string{
-		"commit1": "/ipfs/QmW8WzjmaUTHpjAXJr8NYXaMMU3RCAkGJ5WCMWDGGo4n8p",
+		"commit1": "/ipfs/QmQavEwooxXXvz1H9KkxTeUetSVSeWz2P4aHV71F2DfLtb",
 		"commit2": "/ipfs/QmYSEZWTzZEAArSN5fUVXAezExhTXa4hxyyzSYsafpXvJR",
 	})
 	if diff := cmp.Diff(expect, output); diff != "" {
@@ -84,7 +84,19 @@ func NewDAGCommand(f Factory, ioStreams ioes.IOStreams) *cobra.Command {
 	info.Flags().BoolVar(&o.Pretty, "pretty", false, "print output without indentation, only applies to json format")
 	info.Flags().BoolVar(&o.Hex, "hex", false, "hex-encode output")
 
-	cmd.AddCommand(manifest, info)
+	verify := &cobra.Command{
+		Use:   "verify DATASET [DATASET...]",
+		Short: "check dataset blocks are present and intact",
+		Args:  cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := o.Complete(f, args, false); err != nil {
+				return err
+			}
+			return o.Verify()
+		},
+	}
+
+	cmd.AddCommand(manifest, info, verify)
 	return cmd
 }
 
@@ -276,6 +288,39 @@ func (o *DAGOptions) Info() (err error) {
 	return err
 }
 
+// Verify executes the dag verify command
+func (o *DAGOptions) Verify() error {
+	ctx := context.TODO()
+	intact := true
+	for _, ref := range o.Refs {
+		res, err := o.inst.Dataset().Verify(ctx, &lib.VerifyParams{Ref: ref})
+		if err != nil {
+			return err
+		}
+
+		out := fmt.Sprintf("%s: checked %d blocks\n", ref, res.Checked)
+		for _, b := range res.Missing {
+			out += fmt.Sprintf("\tmissing %s block %s\n", abbrFieldToFull(b.Component), b.CID)
+		}
+		for _, b := range res.Corrupt {
+			out += fmt.Sprintf("\tcorrupt %s block %s\n", abbrFieldToFull(b.Component), b.CID)
+		}
+		if res.Intact() {
+			out += "\tok\n"
+		} else {
+			intact = false
+		}
+		if _, err := o.Out.Write([]byte(out)); err != nil {
+			return err
+		}
+	}
+
+	if !intact {
+		return fmt.Errorf("dataset blocks are missing or corrupt")
+	}
+	return nil
+}
+
 func fullFieldToAbbr(field string) string {
 	switch field {
 	case "commit":
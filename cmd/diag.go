@@ -0,0 +1,122 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/qri-io/ioes"
+	"github.com/qri-io/qri/errors"
+	"github.com/qri-io/qri/lib"
+	"github.com/spf13/cobra"
+)
+
+// NewDiagCommand creates a new `qri diag` command for inspecting the internal
+// state of a qri repo
+func NewDiagCommand(f Factory, ioStreams ioes.IOStreams) *cobra.Command {
+	o := &DiagOptions{IOStreams: ioStreams}
+	cmd := &cobra.Command{
+		Use:    "diag",
+		Hidden: true,
+		Short:  "diagnose the state of a qri repo",
+	}
+
+	dscache := &cobra.Command{
+		Use:   "dscache",
+		Short: "print the contents of the dscache",
+		Long: `Print the users & dataset references stored in the dscache, along with the
+stats cached for each reference. If the repo has no dscache, diag builds one
+from the repo's logbook without storing it.
+
+Use --validate to check the dscache for internal consistency, and --rebuild to
+replace the dscache with one built from the repo's logbook, recovering from
+a dscache that's drifted out of sync with the logbook.`,
+		Example: `  # Show dscache contents:
+  $ qri diag dscache
+
+  # Show dscache contents as JSON:
+  $ qri diag dscache --json
+
+  # Rebuild the dscache from the logbook:
+  $ qri diag dscache --rebuild`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := o.Complete(f, args); err != nil {
+				return err
+			}
+			if err := o.Validate(); err != nil {
+				return err
+			}
+			return o.Dscache()
+		},
+	}
+
+	dscache.Flags().BoolVar(&o.JSON, "json", false, "print dscache contents as JSON")
+	dscache.Flags().BoolVar(&o.Rebuild, "rebuild", false, "rebuild the dscache from the logbook")
+	dscache.Flags().BoolVar(&o.ValidateCache, "validate", false, "check the dscache for internal consistency")
+
+	cmd.AddCommand(dscache)
+	return cmd
+}
+
+// DiagOptions encapsulates state for the diag command
+type DiagOptions struct {
+	ioes.IOStreams
+
+	JSON          bool
+	Rebuild       bool
+	ValidateCache bool
+
+	inst *lib.Instance
+}
+
+// Complete adds any missing configuration that can only be added just before calling Run
+func (o *DiagOptions) Complete(f Factory, args []string) (err error) {
+	o.inst, err = f.Instance()
+	return err
+}
+
+// Validate checks that any user inputs are valid
+func (o *DiagOptions) Validate() error {
+	if o.Rebuild && o.ValidateCache {
+		return errors.New(lib.ErrBadArgs, "cannot use --rebuild and --validate together")
+	}
+	return nil
+}
+
+// Dscache prints the contents of the dscache, or rebuilds or validates it
+func (o *DiagOptions) Dscache() error {
+	ctx := context.TODO()
+
+	if o.Rebuild {
+		n, err := o.inst.Collection().RebuildDscache(ctx, &lib.EmptyParams{})
+		if err != nil {
+			return err
+		}
+		printSuccess(o.Out, "rebuilt dscache with %d datasets", n)
+		return nil
+	}
+	if o.ValidateCache {
+		if err := o.inst.Collection().ValidateDscache(ctx, &lib.EmptyParams{}); err != nil {
+			return err
+		}
+		printSuccess(o.Out, "dscache is valid")
+		return nil
+	}
+
+	cache, err := o.inst.Collection().Dscache(ctx, &lib.EmptyParams{})
+	if err != nil {
+		return err
+	}
+
+	if o.JSON {
+		data, err := json.MarshalIndent(cache, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Fprintln(o.Out, string(data))
+		return nil
+	}
+	fmt.Fprint(o.Out, cache.VerboseString(false))
+	return nil
+}
@@ -0,0 +1,65 @@
+package cmd
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestDiagDscache(t *testing.T) {
+	run := NewTestRunner(t, "test_peer_diag_dscache", "qri_test_diag_dscache")
+	defer run.Delete()
+
+	run.MustExec(t, "qri save --body testdata/movies/body_ten.csv me/diag_movies")
+
+	output := run.MustExec(t, "qri diag dscache")
+	if !strings.Contains(output, "prettyName    = diag_movies") {
+		t.Errorf("expected dscache output to contain saved dataset, got:\n%s", output)
+	}
+
+	output = run.MustExec(t, "qri diag dscache --json")
+	if !strings.Contains(output, `"name": "diag_movies"`) {
+		t.Errorf("expected dscache JSON output to contain saved dataset, got:\n%s", output)
+	}
+}
+
+func TestDiagDscacheRebuild(t *testing.T) {
+	run := NewTestRunner(t, "test_peer_diag_rebuild", "qri_test_diag_rebuild")
+	defer run.Delete()
+
+	run.MustExec(t, "qri save --body testdata/movies/body_ten.csv me/movies")
+	run.MustExec(t, "qri diag dscache --rebuild")
+
+	// keep a copy of the dscache as it was before the second version
+	cachePath := filepath.Join(run.RepoPath, "dscache.qfb")
+	stale, err := ioutil.ReadFile(cachePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	run.MustExec(t, "qri save --body testdata/movies/body_twenty.csv me/movies")
+	expect := run.MustExec(t, "qri get body me/movies")
+
+	// corrupt the dscache by reverting it to the stale copy. resolution now
+	// points at the first version
+	if err := ioutil.WriteFile(cachePath, stale, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if got := run.MustExec(t, "qri get body me/movies"); got == expect {
+		t.Fatal("expected stale dscache to resolve the first version")
+	}
+
+	output := run.MustExec(t, "qri diag dscache --rebuild")
+	if !strings.Contains(output, "rebuilt dscache with 1 datasets") {
+		t.Errorf("expected rebuild to report dataset count, got:\n%s", output)
+	}
+	run.MustExec(t, "qri diag dscache --validate")
+
+	got := run.MustExec(t, "qri get body me/movies")
+	if diff := cmp.Diff(expect, got); diff != "" {
+		t.Errorf("body mismatch after rebuild (-want +got):\n%s", diff)
+	}
+}
@@ -24,7 +24,10 @@ dataset version(s). By default pull fetches the latest version of a dataset.
   $ qri pull b5/world_bank_population
 
   # pull a specific version from a remote by hash
-  $ qri pull ramfox b5/world_bank_population@/ipfs/QmFoo...`,
+  $ qri pull ramfox b5/world_bank_population@/ipfs/QmFoo...
+
+  # show where a reference resolves without downloading anything
+  $ qri pull --resolve-only b5/world_bank_population`,
 		Annotations: map[string]string{
 			"group": "network",
 		},
@@ -40,6 +43,7 @@ dataset version(s). By default pull fetches the latest version of a dataset.
 	cmd.Flags().StringVar(&o.Source, "source", "", "location to pull from")
 	cmd.MarkFlagFilename("link")
 	cmd.Flags().BoolVar(&o.LogsOnly, "logs-only", false, "only fetch logs, skipping HEAD data")
+	cmd.Flags().BoolVar(&o.ResolveOnly, "resolve-only", false, "only resolve the reference, reporting where it resolved")
 
 	return cmd
 }
@@ -47,9 +51,10 @@ dataset version(s). By default pull fetches the latest version of a dataset.
 // PullOptions encapsulates state for the add command
 type PullOptions struct {
 	ioes.IOStreams
-	LinkDir  string
-	Source   string
-	LogsOnly bool
+	LinkDir     string
+	Source      string
+	LogsOnly    bool
+	ResolveOnly bool
 
 	inst *lib.Instance
 }
@@ -73,6 +78,18 @@ func (o *PullOptions) Run(args []string) error {
 	ctx := context.TODO()
 
 	for _, arg := range args {
+		if o.ResolveOnly {
+			r, err := o.inst.WithSource(o.Source).Dataset().ResolvePull(ctx, &lib.ResolvePullParams{Ref: arg})
+			if err != nil {
+				return err
+			}
+			fmt.Fprintf(o.Out, "\n%s\nresolved from %s", r.Ref.String(), r.Source)
+			if r.Location != "" {
+				fmt.Fprintf(o.Out, " (%s)", r.Location)
+			}
+			continue
+		}
+
 		p := &lib.PullParams{
 			Ref:      arg,
 			LogsOnly: o.LogsOnly,
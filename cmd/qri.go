@@ -56,6 +56,7 @@ https://github.com/qri-io/qri/issues`,
 		NewConfigCommand(opt, ioStreams),
 		NewConnectCommand(opt, ioStreams),
 		NewDAGCommand(opt, ioStreams),
+		NewDiagCommand(opt, ioStreams),
 		NewDiffCommand(opt, ioStreams),
 		NewGetCommand(opt, ioStreams),
 		NewListCommand(opt, ioStreams),
@@ -72,6 +73,7 @@ https://github.com/qri-io/qri/issues`,
 		NewSaveCommand(opt, ioStreams),
 		NewSearchCommand(opt, ioStreams),
 		NewSetupCommand(opt, ioStreams),
+		NewTransformCommand(opt, ioStreams),
 		NewValidateCommand(opt, ioStreams),
 		NewVersionCommand(opt, ioStreams),
 		NewWhatChangedCommand(opt, ioStreams),
@@ -0,0 +1,44 @@
+package cmd
+
+import (
+	"github.com/qri-io/ioes"
+	"github.com/spf13/cobra"
+)
+
+// NewTransformCommand creates a new `qri transform` cobra command for working
+// with transform scripts
+func NewTransformCommand(f Factory, ioStreams ioes.IOStreams) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "transform",
+		Short: "work with transform scripts",
+		Annotations: map[string]string{
+			"group": "dataset",
+		},
+	}
+
+	o := &AnalyzeTransformOptions{IOStreams: ioStreams}
+	analyze := &cobra.Command{
+		Use:   "analyze FILE",
+		Short: "statically analyze a transform script",
+		Long: `'qri transform analyze' checks a transform script without running it.
+Analysis reports functions that are never called, recursive functions, leaks
+of sensitive data, and errors parsing the script, followed by a graph of the
+functions the script calls.`,
+		Example: `  # Analyze a transform script:
+  $ qri transform analyze transform.star
+
+  # Output the analysis as json:
+  $ qri transform analyze transform.star --json`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := o.Complete(f, args); err != nil {
+				return err
+			}
+			return o.Run()
+		},
+	}
+	analyze.Flags().BoolVar(&o.JSON, "json", false, "output analysis as json")
+
+	cmd.AddCommand(analyze)
+	return cmd
+}
@@ -0,0 +1,47 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/qri-io/qri/lib"
+)
+
+func TestTransformAnalyze(t *testing.T) {
+	run := NewTestRunner(t, "test_peer_transform_analyze", "qri_test_transform_analyze")
+	defer run.Delete()
+
+	output := run.MustExec(t, "qri transform analyze testdata/tf_unused_func.star")
+	expect := `Function unused: unused
+Call graph:
+helper
+unused
+`
+	if diff := cmp.Diff(expect, output); diff != "" {
+		t.Errorf("output mismatch (-want +got):\n%s", diff)
+	}
+
+	output = run.MustExec(t, "qri transform analyze testdata/tf_unused_func.star --json")
+	res := lib.AnalyzeTransformResult{}
+	if err := json.Unmarshal([]byte(output), &res); err != nil {
+		t.Fatal(err)
+	}
+	if len(res.Diagnostics) != 1 {
+		t.Fatalf("expected 1 diagnostic, got %d", len(res.Diagnostics))
+	}
+	if diff := cmp.Diff("unused", res.Diagnostics[0].Message); diff != "" {
+		t.Errorf("diagnostic mismatch (-want +got):\n%s", diff)
+	}
+
+	tmpDir := run.MakeTmpDir(t, "transform_analyze")
+	badScript := filepath.Join(tmpDir, "bad_syntax.star")
+	run.MustWriteFile(t, badScript, "def helper()\n  pass\n")
+	output = run.MustExec(t, fmt.Sprintf("qri transform analyze %s", badScript))
+	if !strings.Contains(output, "Parse error at") {
+		t.Errorf("expected parse error in output, got: %s", output)
+	}
+}
@@ -26,6 +26,9 @@ type API struct {
 	ServeRemoteTraffic bool `json:"serveremotetraffic"`
 	// should the api provide the /webui endpoint? default is true
 	Webui bool `json:"webui"`
+	// RequestTimeoutMs is the number of milliseconds a request may run before
+	// the api cancels it. zero disables request timeouts
+	RequestTimeoutMs int `json:"requesttimeoutms,omitempty"`
 }
 
 // SetArbitrary is an interface implementation of base/fill/struct in order to
@@ -56,6 +59,11 @@ func (a API) Validate() error {
         "description": "when true the /webui endpoint will serve a frontend app",
         "type": "boolean"
       },
+      "requesttimeoutms": {
+        "description": "milliseconds a request may run before being cancelled. 0 disables timeouts",
+        "type": "integer",
+        "minimum": 0
+      },
       "serveremotetraffic": {
         "description": "whether to allow requests from addresses other than localhost",
         "type": "boolean"
@@ -91,6 +99,7 @@ func (a *API) Copy() *API {
 		Address:            a.Address,
 		ServeRemoteTraffic: a.ServeRemoteTraffic,
 		Webui:              a.Webui,
+		RequestTimeoutMs:   a.RequestTimeoutMs,
 	}
 	if a.AllowedOrigins != nil {
 		res.AllowedOrigins = make([]string, len(a.AllowedOrigins))
@@ -10,6 +10,29 @@ import (
 type Automation struct {
 	Enabled         bool
 	RunStoreMaxSize string
+	// MaxConcurrentRuns caps the number of workflow runs & applies that can
+	// execute at the same time. 0 runs queued workflows one at a time and
+	// doesn't limit applies that wait for their result
+	MaxConcurrentRuns int
+	// MaxQueuedRuns caps the number of runs waiting to execute. Runs beyond
+	// this cap are rejected. 0 means no limit
+	MaxQueuedRuns int
+	// AllowedHTTPDomains lists the domains transform scripts may make http
+	// requests to. Subdomains of listed domains are also allowed. When empty
+	// requests to any domain are allowed
+	AllowedHTTPDomains []string
+	// BodyDiffThreshold is the largest body size in bytes saves & transforms
+	// will diff to write a detailed commit message. 0 uses the default of
+	// dsfs.BodySizeSmallEnoughToDiff
+	BodyDiffThreshold int
+	// VerifyBodyChecksum makes saves & applies confirm bodies a transform
+	// reports as unchanged by comparing checksums with the previous version,
+	// instead of trusting the transform's change set
+	VerifyBodyChecksum bool
+	// DisableTransformPulling stops transforms from pulling datasets they load
+	// that aren't stored locally. Loading a dataset that isn't local fails
+	// instead
+	DisableTransformPulling bool
 }
 
 // DefaultAutomation constructs an automation configuration with standard values
@@ -36,14 +59,34 @@ func (a *Automation) Validate() error {
 	} else if a.RunStoreMaxSize != "unlimited" {
 		return fmt.Errorf("invalid RunStoreMaxSize value: %s", a.RunStoreMaxSize)
 	}
+	if a.MaxConcurrentRuns < 0 {
+		return fmt.Errorf("invalid MaxConcurrentRuns: must be zero or greater")
+	}
+	if a.MaxQueuedRuns < 0 {
+		return fmt.Errorf("invalid MaxQueuedRuns: must be zero or greater")
+	}
+	if a.BodyDiffThreshold < 0 {
+		return fmt.Errorf("invalid BodyDiffThreshold: must be zero or greater")
+	}
 
 	return nil
 }
 
 // Copy creates a shallow copy of Automation
 func (a *Automation) Copy() *Automation {
-	return &Automation{
-		Enabled:         a.Enabled,
-		RunStoreMaxSize: a.RunStoreMaxSize,
+	res := &Automation{
+		Enabled:           a.Enabled,
+		RunStoreMaxSize:   a.RunStoreMaxSize,
+		MaxConcurrentRuns: a.MaxConcurrentRuns,
+		MaxQueuedRuns:     a.MaxQueuedRuns,
+		BodyDiffThreshold: a.BodyDiffThreshold,
+
+		VerifyBodyChecksum:      a.VerifyBodyChecksum,
+		DisableTransformPulling: a.DisableTransformPulling,
+	}
+	if a.AllowedHTTPDomains != nil {
+		res.AllowedHTTPDomains = make([]string, len(a.AllowedHTTPDomains))
+		copy(res.AllowedHTTPDomains, a.AllowedHTTPDomains)
 	}
+	return res
 }
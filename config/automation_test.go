@@ -9,6 +9,22 @@ func TestAutomationValidate(t *testing.T) {
 	if err != nil {
 		t.Errorf("error validating default api: %s", err)
 	}
+
+	a := DefaultAutomation()
+	a.MaxConcurrentRuns = -1
+	if err := a.Validate(); err == nil {
+		t.Errorf("expected negative MaxConcurrentRuns to error")
+	}
+	a = DefaultAutomation()
+	a.MaxQueuedRuns = -1
+	if err := a.Validate(); err == nil {
+		t.Errorf("expected negative MaxQueuedRuns to error")
+	}
+	a = DefaultAutomation()
+	a.BodyDiffThreshold = -1
+	if err := a.Validate(); err == nil {
+		t.Errorf("expected negative BodyDiffThreshold to error")
+	}
 }
 
 func TestAutomationCopy(t *testing.T) {
@@ -17,6 +33,12 @@ func TestAutomationCopy(t *testing.T) {
 
 	a.Enabled = !a.Enabled
 	a.RunStoreMaxSize = "foo"
+	a.MaxConcurrentRuns = 3
+	a.MaxQueuedRuns = 5
+	a.BodyDiffThreshold = 100
+	a.VerifyBodyChecksum = true
+	a.DisableTransformPulling = true
+	a.AllowedHTTPDomains = []string{"example.com"}
 
 	if a.Enabled == b.Enabled {
 		t.Errorf("Enabled fields should not match")
@@ -24,4 +46,28 @@ func TestAutomationCopy(t *testing.T) {
 	if a.RunStoreMaxSize == b.RunStoreMaxSize {
 		t.Errorf("RunStoreMaxSize fields should not match")
 	}
+	if a.MaxConcurrentRuns == b.MaxConcurrentRuns {
+		t.Errorf("MaxConcurrentRuns fields should not match")
+	}
+	if a.MaxQueuedRuns == b.MaxQueuedRuns {
+		t.Errorf("MaxQueuedRuns fields should not match")
+	}
+	if a.BodyDiffThreshold == b.BodyDiffThreshold {
+		t.Errorf("BodyDiffThreshold fields should not match")
+	}
+	if a.VerifyBodyChecksum == b.VerifyBodyChecksum {
+		t.Errorf("VerifyBodyChecksum fields should not match")
+	}
+	if a.DisableTransformPulling == b.DisableTransformPulling {
+		t.Errorf("DisableTransformPulling fields should not match")
+	}
+	if len(b.AllowedHTTPDomains) != 0 {
+		t.Errorf("AllowedHTTPDomains fields should not match")
+	}
+
+	c := a.Copy()
+	a.AllowedHTTPDomains[0] = "changed.com"
+	if c.AllowedHTTPDomains[0] != "example.com" {
+		t.Errorf("AllowedHTTPDomains should be deep copied")
+	}
 }
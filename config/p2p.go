@@ -36,6 +36,12 @@ type P2P struct {
 
 	// Enable AutoNAT service. unless you're hosting a server, leave this as false
 	AutoNAT bool `json:"autoNAT"`
+
+	// QriPeerTagValue is the connection manager tag value given to peers that
+	// speak the qri protocol. Higher values make the connection manager less
+	// likely to prune qri peers under connection pressure. Zero uses the
+	// default value
+	QriPeerTagValue int `json:"qripeertagvalue,omitempty"`
 }
 
 // SetArbitrary is an interface implementation of base/fill/struct in order to safely
@@ -128,6 +134,11 @@ func (cfg P2P) Validate() error {
         "items": {
           "type": "string"
         }
+      },
+      "qripeertagvalue": {
+        "description": "Connection manager tag value given to qri peers. Zero uses the default",
+        "type": "integer",
+        "minimum": 0
       }
     }
   }`)
@@ -141,6 +152,8 @@ func (cfg *P2P) Copy() *P2P {
 		PeerID:  cfg.PeerID,
 		PrivKey: cfg.PrivKey,
 		Port:    cfg.Port,
+
+		QriPeerTagValue: cfg.QriPeerTagValue,
 	}
 
 	if cfg.QriBootstrapAddrs != nil {
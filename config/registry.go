@@ -7,6 +7,14 @@ import (
 // Registry encapsulates configuration options for centralized qri registries
 type Registry struct {
 	Location string `json:"location"`
+	// Fallbacks lists additional registry locations to try in order when a
+	// request to the primary location fails
+	Fallbacks []string `json:"fallbacks,omitempty"`
+	// ProfileID is the base58-encoded profile ID the registry signs responses
+	// with. When set, clients require previews from the registry to carry
+	// this profile's signature. Without it, unsigned previews & previews
+	// signed by other profiles are accepted with a warning
+	ProfileID string `json:"profileid,omitempty"`
 }
 
 // SetArbitrary is an interface implementation of base/fill/struct in order to safely
@@ -36,6 +44,17 @@ func (cfg Registry) Validate() error {
       "location": {
         "description": "the",
         "type": "string"
+      },
+      "fallbacks": {
+        "description": "registry locations to try in order when the primary location fails",
+        "type": "array",
+        "items": {
+          "type": "string"
+        }
+      },
+      "profileid": {
+        "description": "profile ID the registry signs responses with",
+        "type": "string"
       }
     }
   }`)
@@ -45,7 +64,12 @@ func (cfg Registry) Validate() error {
 // Copy makes a deep copy of the Registry struct
 func (cfg *Registry) Copy() *Registry {
 	res := &Registry{
-		Location: cfg.Location,
+		Location:  cfg.Location,
+		ProfileID: cfg.ProfileID,
+	}
+	if cfg.Fallbacks != nil {
+		res.Fallbacks = make([]string, len(cfg.Fallbacks))
+		copy(res.Fallbacks, cfg.Fallbacks)
 	}
 	return res
 }
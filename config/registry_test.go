@@ -17,6 +17,7 @@ func TestRegistryCopy(t *testing.T) {
 		registry *Registry
 	}{
 		{DefaultRegistry()},
+		{&Registry{Location: "https://registry.qri.cloud", Fallbacks: []string{"https://backup.qri.cloud"}, ProfileID: "QmRegistryProfileID"}},
 	}
 	for i, c := range cases {
 		cpy := c.registry.Copy()
@@ -29,5 +30,11 @@ func TestRegistryCopy(t *testing.T) {
 			t.Errorf("Registry Copy test case %v, editing one registry struct should not affect the other: \ncopy: %v, \noriginal: %v", i, cpy, c.registry)
 			continue
 		}
+		if len(cpy.Fallbacks) > 0 {
+			cpy.Fallbacks[0] = "different/fallback"
+			if c.registry.Fallbacks[0] == cpy.Fallbacks[0] {
+				t.Errorf("Registry Copy test case %v, editing copied fallbacks should not affect the original", i)
+			}
+		}
 	}
 }
@@ -8,6 +8,26 @@ import (
 type Repo struct {
 	Type string `json:"type"`
 	Path string `json:"path,omitempty"`
+	// DscacheCreateNew enables building a dscache when one doesn't exist yet.
+	// Without it, a dscache is only created when explicitly requested
+	DscacheCreateNew bool `json:"dscacheCreateNew,omitempty"`
+	// PrettyJSON indents the JSON metadata files of saved datasets. Compact
+	// JSON is smaller, indented JSON is easier to read. The two modes produce
+	// different hashes for the same dataset
+	PrettyJSON bool `json:"prettyJSON,omitempty"`
+	// DscacheSaveDelayMs is the number of milliseconds dscache changes wait
+	// before being written to disk, collapsing rapid changes into one write.
+	// 0 writes every change immediately
+	DscacheSaveDelayMs int `json:"dscacheSaveDelayMs,omitempty"`
+	// SignTransforms signs the transform scripts of saved datasets with the
+	// author's key. The signature is stored as an extra package file, which
+	// changes the hash of the dataset
+	SignTransforms bool `json:"signTransforms,omitempty"`
+	// VerifyTransformSignatures refuses to load datasets with transform
+	// scripts that can't be verified: unsigned scripts, or scripts by authors
+	// without a known key. Scripts with invalid signatures fail to load
+	// regardless
+	VerifyTransformSignatures bool `json:"verifyTransformSignatures,omitempty"`
 }
 
 // SetArbitrary is an interface implementation of base/fill/struct in order to safely
@@ -40,6 +60,27 @@ func (cfg Repo) Validate() error {
           "fs",
           "mem"
         ]
+      },
+      "dscacheCreateNew": {
+        "description": "Create a dscache automatically if one doesn't exist",
+        "type": "boolean"
+      },
+      "prettyJSON": {
+        "description": "Indent JSON metadata files of saved datasets",
+        "type": "boolean"
+      },
+      "dscacheSaveDelayMs": {
+        "description": "milliseconds dscache changes wait before being written to disk. 0 writes changes immediately",
+        "type": "integer",
+        "minimum": 0
+      },
+      "signTransforms": {
+        "description": "Sign transform scripts of saved datasets with the author's key",
+        "type": "boolean"
+      },
+      "verifyTransformSignatures": {
+        "description": "Refuse to load datasets with transform scripts that can't be verified",
+        "type": "boolean"
       }
     }
   }`)
@@ -49,7 +90,12 @@ func (cfg Repo) Validate() error {
 // Copy returns a deep copy of the Repo struct
 func (cfg *Repo) Copy() *Repo {
 	res := &Repo{
-		Type: cfg.Type,
+		Type:                      cfg.Type,
+		DscacheCreateNew:          cfg.DscacheCreateNew,
+		PrettyJSON:                cfg.PrettyJSON,
+		DscacheSaveDelayMs:        cfg.DscacheSaveDelayMs,
+		SignTransforms:            cfg.SignTransforms,
+		VerifyTransformSignatures: cfg.VerifyTransformSignatures,
 	}
 
 	return res
@@ -21,6 +21,7 @@ func TestRepoCopy(t *testing.T) {
 		repo *Repo
 	}{
 		{r},
+		{&Repo{Type: "fs", DscacheCreateNew: true, PrettyJSON: true, DscacheSaveDelayMs: 500, SignTransforms: true, VerifyTransformSignatures: true}},
 	}
 	for i, c := range cases {
 		cpy := c.repo.Copy()
@@ -106,11 +106,13 @@ func buildDscacheFlatbuffer(userPairList []userProfilePair, entryInfoList []*ent
 		dscachefb.RefEntryInfoAddTopIndex(builder, int32(ce.TopIndex))
 		dscachefb.RefEntryInfoAddCursorIndex(builder, int32(ce.CursorIndex))
 		dscachefb.RefEntryInfoAddPrettyName(builder, prettyName)
+		dscachefb.RefEntryInfoAddForeign(builder, ce.Foreign)
 		dscachefb.RefEntryInfoAddMetaTitle(builder, metaTitle)
 		dscachefb.RefEntryInfoAddThemeList(builder, themeList)
 		dscachefb.RefEntryInfoAddBodySize(builder, int64(ce.BodySize))
 		dscachefb.RefEntryInfoAddBodyRows(builder, int32(ce.BodyRows))
 		dscachefb.RefEntryInfoAddCommitTime(builder, ce.CommitTime.Unix())
+		dscachefb.RefEntryInfoAddCommitCount(builder, int32(ce.CommitCount))
 		dscachefb.RefEntryInfoAddNumErrors(builder, int32(ce.NumErrors))
 		dscachefb.RefEntryInfoAddHeadRef(builder, headRef)
 		ref := dscachefb.RefEntryInfoEnd(builder)
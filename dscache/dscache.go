@@ -2,9 +2,12 @@ package dscache
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"io/ioutil"
+	"sort"
 	"strings"
+	"sync"
 	"time"
 
 	flatbuffers "github.com/google/flatbuffers/go"
@@ -14,6 +17,8 @@ import (
 	"github.com/qri-io/qri/dscache/dscachefb"
 	"github.com/qri-io/qri/dsref"
 	"github.com/qri-io/qri/event"
+	"github.com/qri-io/qri/logbook"
+	"github.com/qri-io/qri/logbook/oplog"
 	"github.com/qri-io/qri/profile"
 	reporef "github.com/qri-io/qri/repo/ref"
 )
@@ -26,8 +31,34 @@ var (
 	ErrNoDscache = fmt.Errorf("dscache: does not exist")
 	// ErrInvalidProfileID is returned when an invalid profileID is given to dscache
 	ErrInvalidProfileID = fmt.Errorf("invalid profileID")
+	// ErrAmbiguousPathPrefix is returned when a path prefix matches more than
+	// one dataset in the dscache
+	ErrAmbiguousPathPrefix = fmt.Errorf("dscache: ambiguous path prefix")
+	// ErrInvalidDscache is returned by Validate when dscache entries are
+	// inconsistent
+	ErrInvalidDscache = fmt.Errorf("dscache: invalid")
 )
 
+// UnknownProfilesError is returned by ListRefs in strict mode when dataset
+// entries reference profileIDs that have no associated username. It matches
+// ErrInvalidDscache with errors.Is, a rebuild should repair the cache
+type UnknownProfilesError struct {
+	// ProfileIDs lists each distinct profileID without a username
+	ProfileIDs []string
+	// InitIDs lists the initIDs of every entry with an unknown profileID
+	InitIDs []string
+}
+
+// Error implements the error interface
+func (e *UnknownProfilesError) Error() string {
+	return fmt.Sprintf("dscache: %d dataset(s) reference profileIDs with no associated username: %s", len(e.InitIDs), strings.Join(e.ProfileIDs, ", "))
+}
+
+// Is reports UnknownProfilesError as a kind of ErrInvalidDscache
+func (e *UnknownProfilesError) Is(target error) bool {
+	return target == ErrInvalidDscache
+}
+
 // Dscache represents an in-memory serialized dscache flatbuffer
 type Dscache struct {
 	Filename            string
@@ -36,8 +67,28 @@ type Dscache struct {
 	CreateNewEnabled    bool
 	ProfileIDToUsername map[string]string
 	DefaultUsername     string
+	// StrictUsernames makes ListRefs return an *UnknownProfilesError when
+	// entries have profileIDs without a username association. By default
+	// these entries are logged & listed with an empty peername
+	StrictUsernames bool
+	// SaveDelay sets the flush policy for writing the dscache to disk. When
+	// zero every change is written immediately. Otherwise writes are deferred
+	// until SaveDelay has passed since the first unsaved change, collapsing
+	// rapid successive changes into a single write. Call Flush to write
+	// pending changes before the delay elapses
+	SaveDelay time.Duration
+
+	saveLk    sync.Mutex
+	saveTimer *time.Timer
+	unsaved   []byte
+
+	// usersLk guards ProfileIDToUsername
+	usersLk sync.Mutex
 }
 
+// writeFile writes dscache bytes to disk, replaced in tests to count writes
+var writeFile = ioutil.WriteFile
+
 // NewDscache will construct a dscache from the given filename, or will construct an empty dscache
 // that will save to the given filename. Using an empty filename will disable loading and saving
 func NewDscache(ctx context.Context, fsys qfs.Filesystem, bus event.Bus, username, filename string) *Dscache {
@@ -60,7 +111,9 @@ func NewDscache(ctx context.Context, fsys qfs.Filesystem, bus event.Bus, usernam
 		event.ETLogbookWriteCommit,
 		event.ETDatasetDeleteAll,
 		event.ETDatasetRename,
-		event.ETDatasetCreateLink)
+		event.ETDatasetCreateLink,
+		event.ETDatasetRemoveLink,
+		event.ETDatasetPulled)
 
 	return &cache
 }
@@ -80,6 +133,7 @@ func (d *Dscache) Assign(other *Dscache) error {
 	}
 	d.Root = other.Root
 	d.Buffer = other.Buffer
+	d.resetUsernames()
 	return d.save()
 }
 
@@ -134,50 +188,222 @@ func (d *Dscache) VerboseString(showEmpty bool) string {
 	return out.String()
 }
 
-// ListRefs returns references to each dataset in the cache
+// dscacheJSON is the JSON encoding of a dscache
+type dscacheJSON struct {
+	Users []userAssocJSON `json:"users"`
+	Refs  []refEntryJSON  `json:"refs"`
+}
+
+type userAssocJSON struct {
+	Username  string `json:"username"`
+	ProfileID string `json:"profileID"`
+}
+
+type refEntryJSON struct {
+	dsref.VersionInfo
+	TopIndex    int `json:"topIndex"`
+	CursorIndex int `json:"cursorIndex"`
+}
+
+// MarshalJSON encodes the dscache users & refs as JSON, for debugging. Ref
+// usernames are resolved from the dscache's user associations
+func (d *Dscache) MarshalJSON() ([]byte, error) {
+	if d.IsEmpty() {
+		return nil, ErrNoDscache
+	}
+	usernames := d.usernames()
+	out := dscacheJSON{
+		Users: make([]userAssocJSON, 0, d.Root.UsersLength()),
+		Refs:  make([]refEntryJSON, 0, d.Root.RefsLength()),
+	}
+	for i := 0; i < d.Root.UsersLength(); i++ {
+		userAssoc := dscachefb.UserAssoc{}
+		d.Root.Users(&userAssoc, i)
+		out.Users = append(out.Users, userAssocJSON{
+			Username:  string(userAssoc.Username()),
+			ProfileID: string(userAssoc.ProfileID()),
+		})
+	}
+	for i := 0; i < d.Root.RefsLength(); i++ {
+		r := dscachefb.RefEntryInfo{}
+		d.Root.Refs(&r, i)
+		vi := convertEntryToVersionInfo(&r)
+		vi.Username = usernames[vi.ProfileID]
+		out.Refs = append(out.Refs, refEntryJSON{
+			VersionInfo: vi,
+			TopIndex:    int(r.TopIndex()),
+			CursorIndex: int(r.CursorIndex()),
+		})
+	}
+	return json.Marshal(out)
+}
+
+// ListRefs returns references to each dataset in the cache, sorted by
+// username, then dataset name. Storage order within the flatbuffer changes
+// whenever the cache is rebuilt, sorting keeps the list stable for callers
+// that paginate over it
 func (d *Dscache) ListRefs() ([]reporef.DatasetRef, error) {
 	if d.IsEmpty() {
 		return nil, ErrNoDscache
 	}
-	d.ensureProToUserMap()
+	usernames := d.usernames()
+	if d.StrictUsernames {
+		if err := d.checkProfileUsernames(usernames); err != nil {
+			return nil, err
+		}
+	}
 	refs := make([]reporef.DatasetRef, 0, d.Root.RefsLength())
+	for _, i := range sortedRefIndexes(d.Root, usernames) {
+		refs = append(refs, datasetRefAt(d.Root, usernames, i))
+	}
+	return refs, nil
+}
+
+// ListUserDatasets returns the head version of each dataset in the cache
+// belonging to username, sorted by dataset name
+func (d *Dscache) ListUserDatasets(username string) ([]dsref.VersionInfo, error) {
+	if d.IsEmpty() {
+		return nil, ErrNoDscache
+	}
+	infos := []dsref.VersionInfo{}
+	// entries with an unknown profileID have no username, don't match them
+	if username == "" {
+		return infos, nil
+	}
+	usernames := d.usernames()
 	for i := 0; i < d.Root.RefsLength(); i++ {
-		refCache := dscachefb.RefEntryInfo{}
-		d.Root.Refs(&refCache, i)
+		r := dscachefb.RefEntryInfo{}
+		d.Root.Refs(&r, i)
+		if usernames[string(r.ProfileID())] != username {
+			continue
+		}
+		info := convertEntryToVersionInfo(&r)
+		info.Username = username
+		infos = append(infos, info)
+	}
+	sort.Slice(infos, func(i, j int) bool { return infos[i].Name < infos[j].Name })
+	return infos, nil
+}
 
-		proIDStr := string(refCache.ProfileID())
-		profileID, err := profile.IDB58Decode(proIDStr)
-		if err != nil {
-			log.Errorf("could not parse profileID %q", proIDStr)
+// checkProfileUsernames returns an *UnknownProfilesError if any entry has a
+// profileID with no associated username
+func (d *Dscache) checkProfileUsernames(usernames map[string]string) error {
+	var (
+		unknown = map[string]bool{}
+		err     = &UnknownProfilesError{}
+	)
+	for i := 0; i < d.Root.RefsLength(); i++ {
+		r := dscachefb.RefEntryInfo{}
+		d.Root.Refs(&r, i)
+		proID := string(r.ProfileID())
+		if _, ok := usernames[proID]; ok {
+			continue
 		}
-		username, ok := d.ProfileIDToUsername[proIDStr]
-		if !ok {
-			log.Errorf("no username associated with profileID %q", proIDStr)
-		}
-
-		refs = append(refs, reporef.DatasetRef{
-			Peername:  username,
-			ProfileID: profileID,
-			Name:      string(refCache.PrettyName()),
-			Path:      string(refCache.HeadRef()),
-			FSIPath:   string(refCache.FsiPath()),
-			Dataset: &dataset.Dataset{
-				Meta: &dataset.Meta{
-					Title: string(refCache.MetaTitle()),
-				},
-				Structure: &dataset.Structure{
-					ErrCount: int(refCache.NumErrors()),
-					Entries:  int(refCache.BodyRows()),
-					Length:   int(refCache.BodySize()),
-				},
-				Commit:      &dataset.Commit{},
-				NumVersions: int(refCache.TopIndex()),
-			},
-		})
+		err.InitIDs = append(err.InitIDs, string(r.InitID()))
+		if !unknown[proID] {
+			unknown[proID] = true
+			err.ProfileIDs = append(err.ProfileIDs, proID)
+		}
+	}
+	if len(err.InitIDs) == 0 {
+		return nil
+	}
+	return err
+}
+
+// StreamRefs is a streaming variant of ListRefs, sending references to each
+// dataset in the cache on the returned channel one at a time, in the same
+// order as ListRefs. The channel is closed after the last reference is sent,
+// or when the passed-in context is cancelled
+func (d *Dscache) StreamRefs(ctx context.Context) (<-chan reporef.DatasetRef, error) {
+	if d.IsEmpty() {
+		return nil, ErrNoDscache
 	}
+	// hold onto the current root & usernames, mutations replace both entirely
+	root := d.Root
+	usernames := d.usernames()
+	refs := make(chan reporef.DatasetRef)
+	go func() {
+		defer close(refs)
+		for _, i := range sortedRefIndexes(root, usernames) {
+			select {
+			case refs <- datasetRefAt(root, usernames, i):
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
 	return refs, nil
 }
 
+// sortedRefIndexes returns the indexes of ref entries in root, ordered by
+// username, then dataset name, then initID
+func sortedRefIndexes(root *dscachefb.Dscache, usernames map[string]string) []int {
+	type sortKey struct {
+		username, name, initID string
+	}
+	keys := make([]sortKey, root.RefsLength())
+	idxs := make([]int, root.RefsLength())
+	for i := range idxs {
+		r := dscachefb.RefEntryInfo{}
+		root.Refs(&r, i)
+		keys[i] = sortKey{
+			username: usernames[string(r.ProfileID())],
+			name:     string(r.PrettyName()),
+			initID:   string(r.InitID()),
+		}
+		idxs[i] = i
+	}
+	sort.Slice(idxs, func(a, b int) bool {
+		ka, kb := keys[idxs[a]], keys[idxs[b]]
+		if ka.username != kb.username {
+			return ka.username < kb.username
+		}
+		if ka.name != kb.name {
+			return ka.name < kb.name
+		}
+		return ka.initID < kb.initID
+	})
+	return idxs
+}
+
+// datasetRefAt converts the ref entry at index i of root into a DatasetRef
+func datasetRefAt(root *dscachefb.Dscache, usernames map[string]string, i int) reporef.DatasetRef {
+	refCache := dscachefb.RefEntryInfo{}
+	root.Refs(&refCache, i)
+
+	proIDStr := string(refCache.ProfileID())
+	profileID, err := profile.IDB58Decode(proIDStr)
+	if err != nil {
+		log.Errorf("could not parse profileID %q", proIDStr)
+	}
+	username, ok := usernames[proIDStr]
+	if !ok {
+		log.Errorf("no username associated with profileID %q", proIDStr)
+	}
+
+	return reporef.DatasetRef{
+		Peername:  username,
+		ProfileID: profileID,
+		Name:      string(refCache.PrettyName()),
+		Path:      string(refCache.HeadRef()),
+		FSIPath:   string(refCache.FsiPath()),
+		Foreign:   refCache.Foreign(),
+		Dataset: &dataset.Dataset{
+			Meta: &dataset.Meta{
+				Title: string(refCache.MetaTitle()),
+			},
+			Structure: &dataset.Structure{
+				ErrCount: int(refCache.NumErrors()),
+				Entries:  int(refCache.BodyRows()),
+				Length:   int(refCache.BodySize()),
+			},
+			Commit:      &dataset.Commit{},
+			NumVersions: int(refCache.TopIndex()),
+		},
+	}
+}
+
 // ResolveRef completes a reference using available data, filling in either
 // missing initID or human fields
 // implements dsref.Resolver interface
@@ -191,6 +417,23 @@ func (d *Dscache) ResolveRef(ctx context.Context, ref *dsref.Ref) (string, error
 		return d.completeRef(ctx, ref)
 	}
 
+	// a reference with only a path can be resolved by an abbreviated path
+	if ref.Username == "" && ref.Name == "" && ref.Path != "" {
+		vi, err := d.LookupByPathPrefix(ref.Path)
+		if err != nil {
+			return "", err
+		}
+		ref.InitID = vi.InitID
+		ref.ProfileID = vi.ProfileID
+		ref.Username = vi.Username
+		ref.Name = vi.Name
+		ref.Path = vi.Path
+		return "", nil
+	}
+
+	if ref.Username == "me" && d.DefaultUsername != "" {
+		ref.Username = d.DefaultUsername
+	}
 	vi, err := d.LookupByName(*ref)
 	if err != nil {
 		return "", dsref.ErrRefNotFound
@@ -205,6 +448,33 @@ func (d *Dscache) ResolveRef(ctx context.Context, ref *dsref.Ref) (string, error
 	return "", nil
 }
 
+// ResolveRefFull resolves a reference like ResolveRef, returning the complete
+// VersionInfo held in the cache, including body & commit stats. Cached stats
+// describe the head version, and are omitted if ref resolves to an earlier
+// version
+func (d *Dscache) ResolveRefFull(ctx context.Context, ref *dsref.Ref) (*dsref.VersionInfo, error) {
+	if _, err := d.ResolveRef(ctx, ref); err != nil {
+		return nil, err
+	}
+
+	for i := 0; i < d.Root.RefsLength(); i++ {
+		r := dscachefb.RefEntryInfo{}
+		d.Root.Refs(&r, i)
+		if string(r.InitID()) != ref.InitID {
+			continue
+		}
+		if string(r.HeadRef()) != ref.Path {
+			vi := ref.VersionInfo()
+			return &vi, nil
+		}
+		info := convertEntryToVersionInfo(&r)
+		info.Username = d.usernameForProfileID(info.ProfileID)
+		return &info, nil
+	}
+
+	return nil, dsref.ErrRefNotFound
+}
+
 func (d *Dscache) completeRef(ctx context.Context, ref *dsref.Ref) (string, error) {
 
 	r := dscachefb.RefEntryInfo{}
@@ -216,17 +486,7 @@ func (d *Dscache) completeRef(ctx context.Context, ref *dsref.Ref) (string, erro
 			ref.Name = string(r.PrettyName())
 
 			// Convert profileID into a username
-			for i := 0; i < d.Root.UsersLength(); i++ {
-				userAssoc := dscachefb.UserAssoc{}
-				d.Root.Users(&userAssoc, i)
-				username := userAssoc.Username()
-				profileID := userAssoc.ProfileID()
-				if string(profileID) == ref.ProfileID {
-					ref.Username = string(username)
-					break
-				}
-			}
-
+			ref.Username = d.usernameForProfileID(ref.ProfileID)
 			return "", nil
 		}
 	}
@@ -234,8 +494,64 @@ func (d *Dscache) completeRef(ctx context.Context, ref *dsref.Ref) (string, erro
 	return "", dsref.ErrRefNotFound
 }
 
-// LookupByName looks up a dataset by dsref and returns the latest VersionInfo if found
+// usernameForProfileID returns the username associated with a profileID, or
+// an empty string if no association exists
+func (d *Dscache) usernameForProfileID(profileID string) string {
+	for i := 0; i < d.Root.UsersLength(); i++ {
+		userAssoc := dscachefb.UserAssoc{}
+		d.Root.Users(&userAssoc, i)
+		if string(userAssoc.ProfileID()) == profileID {
+			return string(userAssoc.Username())
+		}
+	}
+	return ""
+}
+
+// LookupByPathPrefix finds the dataset whose head path starts with the given
+// prefix, git-style. The prefix may include a filesystem prefix ("/ipfs/Qm...")
+// or omit it ("Qm..."). Returns dsref.ErrRefNotFound if no dataset matches and
+// ErrAmbiguousPathPrefix if more than one does
+func (d *Dscache) LookupByPathPrefix(prefix string) (*dsref.VersionInfo, error) {
+	if d.IsEmpty() {
+		return nil, dsref.ErrRefNotFound
+	}
+	if prefix == "" {
+		return nil, fmt.Errorf("%w: empty path prefix", dsref.ErrRefNotFound)
+	}
+
+	var found *dsref.VersionInfo
+	for i := 0; i < d.Root.RefsLength(); i++ {
+		r := dscachefb.RefEntryInfo{}
+		d.Root.Refs(&r, i)
+		headRef := string(r.HeadRef())
+		if headRef == "" {
+			continue
+		}
+		// compare against the path both with & without the filesystem prefix
+		hash := headRef[strings.LastIndex(headRef, "/")+1:]
+		if !strings.HasPrefix(headRef, prefix) && !strings.HasPrefix(hash, prefix) {
+			continue
+		}
+		if found != nil {
+			return nil, fmt.Errorf("%w: %q matches both %s and %s", ErrAmbiguousPathPrefix, prefix, found.Path, headRef)
+		}
+		info := convertEntryToVersionInfo(&r)
+		info.Username = d.usernameForProfileID(info.ProfileID)
+		found = &info
+	}
+
+	if found == nil {
+		return nil, dsref.ErrRefNotFound
+	}
+	return found, nil
+}
+
+// LookupByName looks up a dataset by dsref and returns the latest VersionInfo if found.
+// The "me" username is an alias for the DefaultUsername
 func (d *Dscache) LookupByName(ref dsref.Ref) (*dsref.VersionInfo, error) {
+	if ref.Username == "me" && d.DefaultUsername != "" {
+		ref.Username = d.DefaultUsername
+	}
 	// Convert the username into a profileID
 	for i := 0; i < d.Root.UsersLength(); i++ {
 		userAssoc := dscachefb.UserAssoc{}
@@ -298,7 +614,41 @@ func (d *Dscache) handler(_ context.Context, e event.Event) error {
 			log.Error(err)
 		}
 	case event.ETDatasetRename:
-		// TODO(dustmop): Handle renames
+		rename, ok := e.Payload.(event.DsRename)
+		if !ok {
+			log.Error("dscache got an event with a payload that isn't a event.DsRename type: %v", e.Payload)
+			return nil
+		}
+		if err := d.updateRenameDataset(rename.InitID, rename.NewName); err != nil && err != ErrNoDscache {
+			log.Error(err)
+		}
+	case event.ETDatasetCreateLink:
+		link, ok := e.Payload.(event.DsLink)
+		if !ok {
+			log.Error("dscache got an event with a payload that isn't a event.DsLink type: %v", e.Payload)
+			return nil
+		}
+		if err := d.updateFSIPath(link.InitID, link.FSIPath); err != nil && err != ErrNoDscache {
+			log.Error(err)
+		}
+	case event.ETDatasetRemoveLink:
+		initID, ok := e.Payload.(string)
+		if !ok {
+			log.Error("dscache got an event with a payload that isn't a string type: %v", e.Payload)
+			return nil
+		}
+		if err := d.updateFSIPath(initID, ""); err != nil && err != ErrNoDscache {
+			log.Error(err)
+		}
+	case event.ETDatasetPulled:
+		act, ok := e.Payload.(dsref.VersionInfo)
+		if !ok {
+			log.Error("dscache got an event with a payload that isn't a dsref.VersionInfo type: %v", e.Payload)
+			return nil
+		}
+		if err := d.updatePulledDataset(act); err != nil && err != ErrNoDscache {
+			log.Error(err)
+		}
 	}
 
 	return nil
@@ -327,28 +677,92 @@ func (d *Dscache) updateInitDataset(act dsref.VersionInfo) error {
 		d.Assign(cache)
 		return nil
 	}
+	return d.addEntry(act.Username, dsref.VersionInfo{
+		InitID:    act.InitID,
+		ProfileID: act.ProfileID,
+		Name:      act.Name,
+	})
+}
+
+// addEntry rebuilds the dscache with an added entry, associating the entry's
+// profileID with username if the cache doesn't know it yet
+func (d *Dscache) addEntry(username string, vi dsref.VersionInfo) error {
 	builder := NewBuilder()
 	// copy users
+	knownUser := false
 	for i := 0; i < d.Root.UsersLength(); i++ {
 		up := dscachefb.UserAssoc{}
 		d.Root.Users(&up, i)
 		builder.AddUser(string(up.Username()), string(up.ProfileID()))
+		knownUser = knownUser || string(up.ProfileID()) == vi.ProfileID
+	}
+	if !knownUser && username != "" {
+		builder.AddUser(username, vi.ProfileID)
 	}
 	// copy ds versions
-	for i := 0; i < d.Root.UsersLength(); i++ {
+	for i := 0; i < d.Root.RefsLength(); i++ {
 		r := dscachefb.RefEntryInfo{}
 		d.Root.Refs(&r, i)
 		builder.AddDsVersionInfoWithIndexes(convertEntryToVersionInfo(&r), int(r.TopIndex()), int(r.CursorIndex()))
 	}
 	// Add new ds version info
-	builder.AddDsVersionInfo(dsref.VersionInfo{
-		InitID:    act.InitID,
-		ProfileID: act.ProfileID,
-		Name:      act.Name,
-	})
+	builder.AddDsVersionInfo(vi)
 	cache := builder.Build()
-	d.Assign(cache)
-	return nil
+	return d.Assign(cache)
+}
+
+// updatePulledDataset records a dataset pulled from a remote, marking it as
+// foreign if another peer authored it. Datasets the cache doesn't have an
+// entry for are added
+func (d *Dscache) updatePulledDataset(act dsref.VersionInfo) error {
+	if act.InitID == "" {
+		return fmt.Errorf("pulled dataset has no initID")
+	}
+	if d.IsEmpty() {
+		if !d.CreateNewEnabled {
+			return nil
+		}
+		if !d.validateProfileID(act.ProfileID) {
+			return ErrInvalidProfileID
+		}
+		builder := NewBuilder()
+		builder.AddUser(act.Username, act.ProfileID)
+		builder.AddDsVersionInfoWithIndexes(act, act.CommitCount, act.CommitCount)
+		return d.Assign(builder.Build())
+	}
+
+	found := false
+	for i := 0; i < d.Root.RefsLength(); i++ {
+		r := dscachefb.RefEntryInfo{}
+		d.Root.Refs(&r, i)
+		if string(r.InitID()) == act.InitID {
+			found = true
+			break
+		}
+	}
+	if !found {
+		return d.addEntry(act.Username, act)
+	}
+
+	builder := flatbuffers.NewBuilder(0)
+	users := d.copyUserAssociationList(builder, nil)
+	refs := d.copyReferenceListWithReplacement(
+		builder,
+		func(r *dscachefb.RefEntryInfo) bool {
+			return string(r.InitID()) == act.InitID
+		},
+		func(refStartMutationFunc func(*flatbuffers.Builder, func(*refScalars))) {
+			hashRef := builder.CreateString(act.Path)
+			refStartMutationFunc(builder, func(sc *refScalars) {
+				sc.Foreign = act.Foreign
+			})
+			dscachefb.RefEntryInfoAddHeadRef(builder, hashRef)
+		},
+	)
+	root, serialized := d.finishBuilding(builder, users, refs)
+	d.Root = root
+	d.Buffer = serialized
+	return d.save()
 }
 
 // Copy the entire dscache, except for the matching entry, rebuild that one to modify it
@@ -359,7 +773,7 @@ func (d *Dscache) updateChangeCursor(act dsref.VersionInfo) error {
 	// Flatbuffers for go do not allow mutation (for complex types like strings). So we construct
 	// a new flatbuffer entirely, copying the old one while replacing the entry we care to change.
 	builder := flatbuffers.NewBuilder(0)
-	users := d.copyUserAssociationList(builder)
+	users := d.copyUserAssociationList(builder, nil)
 	refs := d.copyReferenceListWithReplacement(
 		builder,
 		// Function to match the entry we're looking to replace
@@ -367,20 +781,22 @@ func (d *Dscache) updateChangeCursor(act dsref.VersionInfo) error {
 			return string(r.InitID()) == act.InitID
 		},
 		// Function to replace the matching entry
-		func(refStartMutationFunc func(builder *flatbuffers.Builder)) {
+		func(refStartMutationFunc func(*flatbuffers.Builder, func(*refScalars))) {
 			var metaTitle flatbuffers.UOffsetT
 			metaTitle = builder.CreateString(act.MetaTitle)
 			hashRef := builder.CreateString(string(act.Path))
 			// Start building a ref object, by mutating an existing ref object.
-			refStartMutationFunc(builder)
+			refStartMutationFunc(builder, func(sc *refScalars) {
+				sc.TopIndex = int32(act.CommitCount)
+				sc.CursorIndex = int32(act.CommitCount)
+				sc.CommitCount = int32(act.CommitCount)
+				sc.CommitTime = act.CommitTime.Unix()
+				sc.BodySize = int64(act.BodySize)
+				sc.BodyRows = int32(act.BodyRows)
+				sc.NumErrors = int32(act.NumErrors)
+			})
 			// Add only the fields we want to change.
-			dscachefb.RefEntryInfoAddTopIndex(builder, int32(act.CommitCount))
-			dscachefb.RefEntryInfoAddCursorIndex(builder, int32(act.CommitCount))
 			dscachefb.RefEntryInfoAddMetaTitle(builder, metaTitle)
-			dscachefb.RefEntryInfoAddCommitTime(builder, act.CommitTime.Unix())
-			dscachefb.RefEntryInfoAddBodySize(builder, int64(act.BodySize))
-			dscachefb.RefEntryInfoAddBodyRows(builder, int32(act.BodyRows))
-			dscachefb.RefEntryInfoAddNumErrors(builder, int32(act.NumErrors))
 			dscachefb.RefEntryInfoAddHeadRef(builder, hashRef)
 			// Don't call RefEntryInfoEnd, that is handled by copyReferenceListWithReplacement
 		},
@@ -391,6 +807,55 @@ func (d *Dscache) updateChangeCursor(act dsref.VersionInfo) error {
 	return d.save()
 }
 
+// Copy the entire dscache, replacing the FSIPath of the matching entry. An
+// empty fsiPath unlinks the dataset
+func (d *Dscache) updateFSIPath(initID, fsiPath string) error {
+	if d.IsEmpty() {
+		return ErrNoDscache
+	}
+	builder := flatbuffers.NewBuilder(0)
+	users := d.copyUserAssociationList(builder, nil)
+	refs := d.copyReferenceListWithReplacement(
+		builder,
+		func(r *dscachefb.RefEntryInfo) bool {
+			return string(r.InitID()) == initID
+		},
+		func(refStartMutationFunc func(*flatbuffers.Builder, func(*refScalars))) {
+			path := builder.CreateString(fsiPath)
+			refStartMutationFunc(builder, nil)
+			dscachefb.RefEntryInfoAddFsiPath(builder, path)
+		},
+	)
+	root, serialized := d.finishBuilding(builder, users, refs)
+	d.Root = root
+	d.Buffer = serialized
+	return d.save()
+}
+
+// Copy the entire dscache, replacing the pretty name of the matching entry
+func (d *Dscache) updateRenameDataset(initID, newName string) error {
+	if d.IsEmpty() {
+		return ErrNoDscache
+	}
+	builder := flatbuffers.NewBuilder(0)
+	users := d.copyUserAssociationList(builder, nil)
+	refs := d.copyReferenceListWithReplacement(
+		builder,
+		func(r *dscachefb.RefEntryInfo) bool {
+			return string(r.InitID()) == initID
+		},
+		func(refStartMutationFunc func(*flatbuffers.Builder, func(*refScalars))) {
+			prettyName := builder.CreateString(newName)
+			refStartMutationFunc(builder, nil)
+			dscachefb.RefEntryInfoAddPrettyName(builder, prettyName)
+		},
+	)
+	root, serialized := d.finishBuilding(builder, users, refs)
+	d.Root = root
+	d.Buffer = serialized
+	return d.save()
+}
+
 // Copy the entire dscache, except leave out the matching entry.
 func (d *Dscache) updateDeleteDataset(initID string) error {
 	if d.IsEmpty() {
@@ -399,7 +864,7 @@ func (d *Dscache) updateDeleteDataset(initID string) error {
 	// Flatbuffers for go do not allow mutation (for complex types like strings). So we construct
 	// a new flatbuffer entirely, copying the old one while omitting the entry we want to remove.
 	builder := flatbuffers.NewBuilder(0)
-	users := d.copyUserAssociationList(builder)
+	users := d.copyUserAssociationList(builder, nil)
 	refs := d.copyReferenceListWithReplacement(
 		builder,
 		func(r *dscachefb.RefEntryInfo) bool {
@@ -414,6 +879,168 @@ func (d *Dscache) updateDeleteDataset(initID string) error {
 	return d.save()
 }
 
+// Compact rebuilds the dscache, dropping user associations that are no longer
+// referenced by any dataset. The default user's association is always kept.
+// Rebuilding the flatbuffer from the live set also discards any space left
+// over from earlier mutations
+func (d *Dscache) Compact() error {
+	if d.IsEmpty() {
+		return ErrNoDscache
+	}
+
+	live := map[string]bool{}
+	for i := 0; i < d.Root.RefsLength(); i++ {
+		r := dscachefb.RefEntryInfo{}
+		d.Root.Refs(&r, i)
+		live[string(r.ProfileID())] = true
+	}
+
+	builder := flatbuffers.NewBuilder(0)
+	users := d.copyUserAssociationList(builder, func(ua *dscachefb.UserAssoc) bool {
+		return live[string(ua.ProfileID())] || (d.DefaultUsername != "" && string(ua.Username()) == d.DefaultUsername)
+	})
+	refs := d.copyReferenceListWithReplacement(
+		builder,
+		// match nothing, copying every entry as-is
+		func(r *dscachefb.RefEntryInfo) bool { return false },
+		nil,
+	)
+	root, serialized := d.finishBuilding(builder, users, refs)
+	d.Root = root
+	d.Buffer = serialized
+	d.resetUsernames()
+	return d.save()
+}
+
+// Validate checks the dscache for internal consistency: every entry must have
+// a unique initID, a name, a profileID with a matching user association, and
+// a cursor index that doesn't exceed its top index. An empty dscache is valid
+func (d *Dscache) Validate() error {
+	if d.IsEmpty() {
+		return nil
+	}
+
+	users := map[string]bool{}
+	for i := 0; i < d.Root.UsersLength(); i++ {
+		ua := dscachefb.UserAssoc{}
+		d.Root.Users(&ua, i)
+		users[string(ua.ProfileID())] = true
+	}
+
+	initIDs := map[string]bool{}
+	for i := 0; i < d.Root.RefsLength(); i++ {
+		r := dscachefb.RefEntryInfo{}
+		d.Root.Refs(&r, i)
+		initID := string(r.InitID())
+		switch {
+		case initID == "":
+			return fmt.Errorf("%w: entry %d has no initID", ErrInvalidDscache, i)
+		case initIDs[initID]:
+			return fmt.Errorf("%w: duplicate entries for initID %q", ErrInvalidDscache, initID)
+		case len(r.PrettyName()) == 0:
+			return fmt.Errorf("%w: entry %q has no name", ErrInvalidDscache, initID)
+		case !users[string(r.ProfileID())]:
+			return fmt.Errorf("%w: entry %q has unknown profileID %q", ErrInvalidDscache, initID, string(r.ProfileID()))
+		case r.CursorIndex() > r.TopIndex():
+			return fmt.Errorf("%w: entry %q cursor index %d exceeds top index %d", ErrInvalidDscache, initID, r.CursorIndex(), r.TopIndex())
+		}
+		initIDs[initID] = true
+	}
+	return nil
+}
+
+// Repair reconciles the TopIndex, CursorIndex, and CommitCount of each entry
+// against the number of commits recorded in the logbook, rewriting any entry
+// where they disagree. Entries that have no history in the logbook are left
+// as-is. Returns the number of entries that were repaired
+func (d *Dscache) Repair(ctx context.Context, book *logbook.Book) (int, error) {
+	if d.IsEmpty() {
+		return 0, ErrNoDscache
+	}
+	if book == nil {
+		return 0, logbook.ErrNoLogbook
+	}
+
+	userLogs, err := book.ListAllLogs(ctx)
+	if err != nil {
+		return 0, err
+	}
+	commitCounts := map[string]int{}
+	for _, userLog := range userLogs {
+		for _, dsLog := range userLog.Logs {
+			if len(dsLog.Logs) != 1 {
+				continue
+			}
+			commitCounts[dsLog.ID()] = countCommits(dsLog.Logs[0])
+		}
+	}
+
+	needsRepair := func(r *dscachefb.RefEntryInfo) bool {
+		count, ok := commitCounts[string(r.InitID())]
+		if !ok {
+			return false
+		}
+		return int(r.TopIndex()) != count || int(r.CursorIndex()) != count || int(r.CommitCount()) != count
+	}
+
+	repaired := 0
+	for i := 0; i < d.Root.RefsLength(); i++ {
+		r := dscachefb.RefEntryInfo{}
+		d.Root.Refs(&r, i)
+		if needsRepair(&r) {
+			log.Debugw("repairing dscache entry", "initID", string(r.InitID()), "topIndex", r.TopIndex(), "cursorIndex", r.CursorIndex(), "commitCount", r.CommitCount(), "logbookCount", commitCounts[string(r.InitID())])
+			repaired++
+		}
+	}
+	if repaired == 0 {
+		return 0, nil
+	}
+
+	builder := flatbuffers.NewBuilder(0)
+	users := d.copyUserAssociationList(builder, nil)
+	var replacing *dscachefb.RefEntryInfo
+	refs := d.copyReferenceListWithReplacement(
+		builder,
+		func(r *dscachefb.RefEntryInfo) bool {
+			replacing = r
+			return needsRepair(r)
+		},
+		func(refStartMutationFunc func(*flatbuffers.Builder, func(*refScalars))) {
+			count := int32(commitCounts[string(replacing.InitID())])
+			refStartMutationFunc(builder, func(sc *refScalars) {
+				sc.TopIndex = count
+				sc.CursorIndex = count
+				sc.CommitCount = count
+			})
+		},
+	)
+	root, serialized := d.finishBuilding(builder, users, refs)
+	d.Root = root
+	d.Buffer = serialized
+	return repaired, d.save()
+}
+
+// countCommits tallies the number of commits in a branch log, the same way
+// logbook does when reporting CommitCount
+func countCommits(branchLog *oplog.Log) int {
+	count := 0
+	for _, op := range branchLog.Ops {
+		if op.Model != logbook.CommitModel {
+			continue
+		}
+		switch op.Type {
+		case oplog.OpTypeInit:
+			count++
+		case oplog.OpTypeRemove:
+			count -= int(op.Size)
+		}
+	}
+	if count < 0 {
+		return 0
+	}
+	return count
+}
+
 func convertEntryToVersionInfo(r *dscachefb.RefEntryInfo) dsref.VersionInfo {
 	return dsref.VersionInfo{
 		InitID:      string(r.InitID()),
@@ -433,25 +1060,73 @@ func convertEntryToVersionInfo(r *dscachefb.RefEntryInfo) dsref.VersionInfo {
 	}
 }
 
-func (d *Dscache) ensureProToUserMap() {
+// usernames returns the dscache's profileID to username associations,
+// building them from the root if needed. The returned map is never modified,
+// changes to the dscache replace it, so callers can keep reading it while the
+// dscache changes
+func (d *Dscache) usernames() map[string]string {
+	d.usersLk.Lock()
+	defer d.usersLk.Unlock()
 	if d.ProfileIDToUsername != nil {
-		return
+		return d.ProfileIDToUsername
 	}
-	d.ProfileIDToUsername = make(map[string]string)
+	usernames := make(map[string]string, d.Root.UsersLength())
 	for i := 0; i < d.Root.UsersLength(); i++ {
 		userAssoc := dscachefb.UserAssoc{}
 		d.Root.Users(&userAssoc, i)
-		username := userAssoc.Username()
-		profileID := userAssoc.ProfileID()
-		d.ProfileIDToUsername[string(profileID)] = string(username)
+		usernames[string(userAssoc.ProfileID())] = string(userAssoc.Username())
 	}
+	d.ProfileIDToUsername = usernames
+	return usernames
+}
+
+// resetUsernames drops the profileID to username associations, they're
+// rebuilt from the root on next use
+func (d *Dscache) resetUsernames() {
+	d.usersLk.Lock()
+	defer d.usersLk.Unlock()
+	d.ProfileIDToUsername = nil
 }
 
-// save writes the serialized bytes to the given filename
+// save writes the serialized bytes to the given filename, or schedules a
+// write if SaveDelay is set
 func (d *Dscache) save() error {
 	if d.Filename == "" {
 		log.Infof("dscache: no filename set, will not save")
 		return nil
 	}
-	return ioutil.WriteFile(d.Filename, d.Buffer, 0644)
+	if d.SaveDelay <= 0 {
+		return writeFile(d.Filename, d.Buffer, 0644)
+	}
+
+	d.saveLk.Lock()
+	defer d.saveLk.Unlock()
+	d.unsaved = d.Buffer
+	if d.saveTimer == nil {
+		d.saveTimer = time.AfterFunc(d.SaveDelay, func() {
+			if err := d.Flush(); err != nil {
+				log.Errorf("dscache: writing pending changes: %s", err)
+			}
+		})
+	}
+	return nil
+}
+
+// Flush writes any changes that are waiting on SaveDelay to disk
+func (d *Dscache) Flush() error {
+	if d == nil {
+		return nil
+	}
+	d.saveLk.Lock()
+	defer d.saveLk.Unlock()
+	if d.saveTimer != nil {
+		d.saveTimer.Stop()
+		d.saveTimer = nil
+	}
+	if d.unsaved == nil {
+		return nil
+	}
+	buf := d.unsaved
+	d.unsaved = nil
+	return writeFile(d.Filename, buf, 0644)
 }
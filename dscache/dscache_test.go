@@ -1,22 +1,30 @@
 package dscache
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
 	"io/ioutil"
 	"os"
 	"path/filepath"
 	"strings"
 	"testing"
+	"time"
 
+	"github.com/google/go-cmp/cmp"
 	"github.com/qri-io/qfs"
 	"github.com/qri-io/qfs/localfs"
 	testkeys "github.com/qri-io/qri/auth/key/test"
+	"github.com/qri-io/qri/dscache/dscachefb"
 	"github.com/qri-io/qri/dsref"
 	dsrefspec "github.com/qri-io/qri/dsref/spec"
 	"github.com/qri-io/qri/event"
 	"github.com/qri-io/qri/logbook"
 	"github.com/qri-io/qri/logbook/oplog"
 	"github.com/qri-io/qri/profile"
+	reporef "github.com/qri-io/qri/repo/ref"
 )
 
 // TODO(dlong): Test NewDscache, IsEmpty, Assign, ListRefs, Update
@@ -115,6 +123,745 @@ func TestResolveRef(t *testing.T) {
 	})
 }
 
+func TestResolveMeAlias(t *testing.T) {
+	ctx := context.Background()
+	profileID := profile.IDFromPeerID(testkeys.GetKeyData(0).PeerID).Encode()
+
+	builder := NewBuilder()
+	builder.AddUser("test_user", profileID)
+	builder.AddDsVersionInfo(dsref.VersionInfo{InitID: "abcd1", ProfileID: profileID, Name: "my_ds", Path: "/ipfs/QmMyDs"})
+	dsc := builder.Build()
+
+	// without a default username "me" has no meaning
+	ref := dsref.Ref{Username: "me", Name: "my_ds"}
+	if _, err := dsc.ResolveRef(ctx, &ref); !errors.Is(err, dsref.ErrRefNotFound) {
+		t.Errorf("expected resolving 'me' without a default username to fail, got: %v", err)
+	}
+
+	dsc.DefaultUsername = "test_user"
+	ref = dsref.Ref{Username: "me", Name: "my_ds"}
+	if _, err := dsc.ResolveRef(ctx, &ref); err != nil {
+		t.Fatal(err)
+	}
+	expect := dsref.Ref{Username: "test_user", Name: "my_ds", InitID: "abcd1", ProfileID: profileID, Path: "/ipfs/QmMyDs"}
+	if diff := cmp.Diff(expect, ref); diff != "" {
+		t.Errorf("resolved ref mismatch (-want +got):\n%s", diff)
+	}
+
+	vi, err := dsc.LookupByName(dsref.Ref{Username: "me", Name: "my_ds"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if vi.InitID != "abcd1" {
+		t.Errorf("expected lookup by 'me' to find initID abcd1, got %q", vi.InitID)
+	}
+}
+
+func TestResolveRefFull(t *testing.T) {
+	ctx := context.Background()
+	profileID := profile.IDFromPeerID(testkeys.GetKeyData(0).PeerID).Encode()
+	commitTime := time.Date(2001, time.January, 1, 1, 0, 0, 0, time.UTC)
+
+	builder := NewBuilder()
+	builder.AddUser("test_user", profileID)
+	builder.AddDsVersionInfo(dsref.VersionInfo{
+		InitID:      "abcd1",
+		ProfileID:   profileID,
+		Name:        "stats",
+		Path:        "/ipfs/QmStatsHead",
+		BodySize:    1024,
+		BodyRows:    32,
+		CommitTime:  commitTime,
+		CommitCount: 3,
+	})
+	dsc := builder.Build()
+
+	ref := dsref.Ref{Username: "test_user", Name: "stats"}
+	got, err := dsc.ResolveRefFull(ctx, &ref)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expect := &dsref.VersionInfo{
+		InitID:      "abcd1",
+		ProfileID:   profileID,
+		Username:    "test_user",
+		Name:        "stats",
+		Path:        "/ipfs/QmStatsHead",
+		BodySize:    1024,
+		BodyRows:    32,
+		CommitTime:  commitTime.In(time.Local),
+		CommitCount: 3,
+	}
+	if diff := cmp.Diff(expect, got); diff != "" {
+		t.Errorf("resolved info mismatch (-want +got):\n%s", diff)
+	}
+	if ref.InitID != "abcd1" {
+		t.Errorf("expected ref to be resolved, got initID %q", ref.InitID)
+	}
+
+	// stats describe the head, so resolving a previous version omits them
+	ref = dsref.Ref{Username: "test_user", Name: "stats", Path: "/ipfs/QmStatsPrev"}
+	if got, err = dsc.ResolveRefFull(ctx, &ref); err != nil {
+		t.Fatal(err)
+	}
+	if got.BodySize != 0 || got.Path != "/ipfs/QmStatsPrev" {
+		t.Errorf("expected previous version to resolve without stats, got: %#v", got)
+	}
+
+	ref = dsref.Ref{Username: "test_user", Name: "unknown"}
+	if _, err := dsc.ResolveRefFull(ctx, &ref); !errors.Is(err, dsref.ErrRefNotFound) {
+		t.Errorf("expected ErrRefNotFound, got: %v", err)
+	}
+}
+
+func TestLookupByPathPrefix(t *testing.T) {
+	ctx := context.Background()
+	keyData := testkeys.GetKeyData(0)
+	profileID := profile.IDFromPeerID(keyData.PeerID).Encode()
+
+	builder := NewBuilder()
+	builder.AddUser("test_user", profileID)
+	builder.AddDsVersionInfo(dsref.VersionInfo{
+		InitID:    "abcd1",
+		ProfileID: profileID,
+		Name:      "first",
+		Path:      "/ipfs/QmAbcdEfgh1234",
+	})
+	builder.AddDsVersionInfo(dsref.VersionInfo{
+		InitID:    "efgh2",
+		ProfileID: profileID,
+		Name:      "second",
+		Path:      "/ipfs/QmAbzzYxwv5678",
+	})
+	dsc := builder.Build()
+
+	vi, err := dsc.LookupByPathPrefix("QmAbcd")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if vi.InitID != "abcd1" {
+		t.Errorf("expected prefix to match initID %q, got %q", "abcd1", vi.InitID)
+	}
+
+	if _, err := dsc.LookupByPathPrefix("QmAb"); !errors.Is(err, ErrAmbiguousPathPrefix) {
+		t.Errorf("expected ambiguous prefix error, got: %v", err)
+	}
+	if _, err := dsc.LookupByPathPrefix("QmNope"); !errors.Is(err, dsref.ErrRefNotFound) {
+		t.Errorf("expected ErrRefNotFound, got: %v", err)
+	}
+
+	ref := dsref.Ref{Path: "/ipfs/QmAbzz"}
+	if _, err := dsc.ResolveRef(ctx, &ref); err != nil {
+		t.Fatal(err)
+	}
+	expect := dsref.Ref{
+		InitID:    "efgh2",
+		Username:  "test_user",
+		ProfileID: profileID,
+		Name:      "second",
+		Path:      "/ipfs/QmAbzzYxwv5678",
+	}
+	if diff := cmp.Diff(expect, ref); diff != "" {
+		t.Errorf("resolved ref mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestCompact(t *testing.T) {
+	keepID := profile.IDFromPeerID(testkeys.GetKeyData(0).PeerID).Encode()
+	dropID := profile.IDFromPeerID(testkeys.GetKeyData(1).PeerID).Encode()
+
+	builder := NewBuilder()
+	builder.AddUser("keep_user", keepID)
+	builder.AddUser("drop_user", dropID)
+	builder.AddDsVersionInfo(dsref.VersionInfo{InitID: "abcd1", ProfileID: keepID, Name: "kept", Path: "/ipfs/QmKept", BodyFormat: "csv"})
+	builder.AddDsVersionInfo(dsref.VersionInfo{InitID: "efgh2", ProfileID: dropID, Name: "dropped_a"})
+	builder.AddDsVersionInfo(dsref.VersionInfo{InitID: "ijkl3", ProfileID: dropID, Name: "dropped_b"})
+	dsc := builder.Build()
+
+	for _, initID := range []string{"efgh2", "ijkl3"} {
+		if err := dsc.updateDeleteDataset(initID); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if dsc.Root.UsersLength() != 2 {
+		t.Fatalf("expected deleting refs to leave 2 users in place, got %d", dsc.Root.UsersLength())
+	}
+	before := len(dsc.Buffer)
+
+	if err := dsc.Compact(); err != nil {
+		t.Fatal(err)
+	}
+
+	if dsc.Root.UsersLength() != 1 {
+		t.Fatalf("expected 1 user after compaction, got %d", dsc.Root.UsersLength())
+	}
+	if name := dsc.usernameForProfileID(dropID); name != "" {
+		t.Errorf("expected user association for %q to be removed, still found %q", dropID, name)
+	}
+	if name := dsc.usernameForProfileID(keepID); name != "keep_user" {
+		t.Errorf("expected user association for %q to remain, got %q", keepID, name)
+	}
+	if len(dsc.Buffer) >= before {
+		t.Errorf("expected compacted buffer to shrink. before: %d after: %d", before, len(dsc.Buffer))
+	}
+
+	vi, err := dsc.LookupByName(dsref.Ref{Username: "keep_user", Name: "kept"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if vi.Path != "/ipfs/QmKept" {
+		t.Errorf("expected remaining ref to be preserved, got path %q", vi.Path)
+	}
+}
+
+func TestUpdateFSIPath(t *testing.T) {
+	ctx := context.Background()
+	peerID := profile.IDFromPeerID(testkeys.GetKeyData(0).PeerID).Encode()
+
+	builder := NewBuilder()
+	builder.AddUser("test_user", peerID)
+	builder.AddDsVersionInfo(dsref.VersionInfo{InitID: "abcd1", ProfileID: peerID, Name: "linked", Path: "/ipfs/QmLinked"})
+	builder.AddDsVersionInfo(dsref.VersionInfo{InitID: "efgh2", ProfileID: peerID, Name: "other"})
+	dsc := builder.Build()
+
+	fsiPaths := func() map[string]string {
+		refs, err := dsc.ListRefs()
+		if err != nil {
+			t.Fatal(err)
+		}
+		paths := map[string]string{}
+		for _, ref := range refs {
+			paths[ref.Name] = ref.FSIPath
+		}
+		return paths
+	}
+
+	dsc.handler(ctx, event.Event{
+		Type:    event.ETDatasetCreateLink,
+		Payload: event.DsLink{InitID: "abcd1", FSIPath: "/path/to/linked"},
+	})
+	expect := map[string]string{"linked": "/path/to/linked", "other": ""}
+	if diff := cmp.Diff(expect, fsiPaths()); diff != "" {
+		t.Errorf("fsi path mismatch after link (-want +got):\n%s", diff)
+	}
+
+	vi, err := dsc.LookupByName(dsref.Ref{Username: "test_user", Name: "linked"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if vi.Path != "/ipfs/QmLinked" {
+		t.Errorf("expected linking to preserve head path, got %q", vi.Path)
+	}
+
+	dsc.handler(ctx, event.Event{Type: event.ETDatasetRemoveLink, Payload: "abcd1"})
+	expect = map[string]string{"linked": "", "other": ""}
+	if diff := cmp.Diff(expect, fsiPaths()); diff != "" {
+		t.Errorf("fsi path mismatch after unlink (-want +got):\n%s", diff)
+	}
+}
+
+func TestUpdatePulledDatasetForeign(t *testing.T) {
+	profileID := profile.IDFromPeerID(testkeys.GetKeyData(0).PeerID).Encode()
+
+	builder := NewBuilder()
+	builder.AddUser("test_user", profileID)
+	builder.AddDsVersionInfo(dsref.VersionInfo{InitID: "abcd1", ProfileID: profileID, Name: "pulled", Path: "/ipfs/QmFirst"})
+	dsc := builder.Build()
+
+	foreign := func() bool {
+		r := dscachefb.RefEntryInfo{}
+		dsc.Root.Refs(&r, 0)
+		return r.Foreign()
+	}
+
+	vi := dsref.VersionInfo{InitID: "abcd1", ProfileID: profileID, Username: "test_user", Name: "pulled", Path: "/ipfs/QmSecond", Foreign: true}
+	if err := dsc.updatePulledDataset(vi); err != nil {
+		t.Fatal(err)
+	}
+	if !foreign() {
+		t.Errorf("expected pulled entry to be marked foreign")
+	}
+
+	vi.Path = "/ipfs/QmThird"
+	vi.Foreign = false
+	if err := dsc.updatePulledDataset(vi); err != nil {
+		t.Fatal(err)
+	}
+	if foreign() {
+		t.Errorf("expected entry no longer to be marked foreign")
+	}
+	got, err := dsc.LookupByName(dsref.Ref{Username: "test_user", Name: "pulled"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Path != "/ipfs/QmThird" {
+		t.Errorf("expected path to be updated, got: %q", got.Path)
+	}
+}
+
+func TestUpdateRenameDataset(t *testing.T) {
+	ctx := context.Background()
+	peerID := profile.IDFromPeerID(testkeys.GetKeyData(0).PeerID).Encode()
+
+	builder := NewBuilder()
+	builder.AddUser("test_user", peerID)
+	builder.AddDsVersionInfo(dsref.VersionInfo{InitID: "abcd1", ProfileID: peerID, Name: "first_name", Path: "/ipfs/QmFirst"})
+	builder.AddDsVersionInfo(dsref.VersionInfo{InitID: "efgh2", ProfileID: peerID, Name: "other"})
+	dsc := builder.Build()
+
+	dsc.handler(ctx, event.Event{
+		Type:    event.ETDatasetRename,
+		Payload: event.DsRename{InitID: "abcd1", OldName: "first_name", NewName: "second_name"},
+	})
+
+	vi, err := dsc.LookupByName(dsref.Ref{Username: "test_user", Name: "second_name"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if vi.InitID != "abcd1" || vi.Path != "/ipfs/QmFirst" {
+		t.Errorf("expected rename to preserve initID and path, got %q %q", vi.InitID, vi.Path)
+	}
+	if _, err := dsc.LookupByName(dsref.Ref{Username: "test_user", Name: "first_name"}); err == nil {
+		t.Errorf("expected lookup by old name to fail")
+	}
+	if _, err := dsc.LookupByName(dsref.Ref{Username: "test_user", Name: "other"}); err != nil {
+		t.Errorf("expected unrelated dataset to be unaffected, got: %s", err)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	peerID := profile.IDFromPeerID(testkeys.GetKeyData(0).PeerID).Encode()
+	otherID := profile.IDFromPeerID(testkeys.GetKeyData(1).PeerID).Encode()
+
+	if err := (&Dscache{}).Validate(); err != nil {
+		t.Errorf("expected empty dscache to be valid, got: %s", err)
+	}
+
+	builder := NewBuilder()
+	builder.AddUser("test_user", peerID)
+	builder.AddDsVersionInfoWithIndexes(dsref.VersionInfo{InitID: "abcd1", ProfileID: peerID, Name: "one"}, 2, 2)
+	if err := builder.Build().Validate(); err != nil {
+		t.Errorf("expected valid dscache, got: %s", err)
+	}
+
+	bad := []struct {
+		description string
+		vi          dsref.VersionInfo
+		top, cursor int
+	}{
+		{"missing initID", dsref.VersionInfo{ProfileID: peerID, Name: "two"}, 0, 0},
+		{"duplicate initID", dsref.VersionInfo{InitID: "abcd1", ProfileID: peerID, Name: "two"}, 0, 0},
+		{"unknown profileID", dsref.VersionInfo{InitID: "efgh2", ProfileID: otherID, Name: "two"}, 0, 0},
+		{"cursor past top", dsref.VersionInfo{InitID: "efgh2", ProfileID: peerID, Name: "two"}, 1, 3},
+	}
+	for _, c := range bad {
+		builder := NewBuilder()
+		builder.AddUser("test_user", peerID)
+		builder.AddDsVersionInfo(dsref.VersionInfo{InitID: "abcd1", ProfileID: peerID, Name: "one"})
+		builder.AddDsVersionInfoWithIndexes(c.vi, c.top, c.cursor)
+		if err := builder.Build().Validate(); !errors.Is(err, ErrInvalidDscache) {
+			t.Errorf("case %q: expected ErrInvalidDscache, got: %v", c.description, err)
+		}
+	}
+}
+
+func TestRepair(t *testing.T) {
+	ctx := context.Background()
+	keyData := testkeys.GetKeyData(0)
+	book := makeFakeLogbook(ctx, t, "test_user", keyData.PrivKey)
+	profileID := profile.IDFromPeerID(keyData.PeerID).Encode()
+
+	initID, err := book.RefToInitID(dsref.Ref{Username: "test_user", Name: "second_name"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// an entry left inconsistent by a partial update
+	builder := NewBuilder()
+	builder.AddUser("test_user", profileID)
+	builder.AddDsVersionInfoWithIndexes(dsref.VersionInfo{
+		InitID:      initID,
+		ProfileID:   profileID,
+		Name:        "second_name",
+		Path:        "/ipfs/QmHashOfVersion6",
+		CommitCount: 1,
+	}, 5, 2)
+	dsc := builder.Build()
+
+	repaired, err := dsc.Repair(ctx, book)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if repaired != 1 {
+		t.Errorf("expected 1 repaired entry, got %d", repaired)
+	}
+
+	refs, err := dsc.ListRefs()
+	if err != nil {
+		t.Fatal(err)
+	}
+	vi, err := dsc.LookupByName(dsref.Ref{Username: "test_user", Name: "second_name"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := dscachefb.RefEntryInfo{}
+	dsc.Root.Refs(&r, 0)
+
+	expect := 3
+	if refs[0].Dataset.NumVersions != expect || vi.CommitCount != expect || int(r.CursorIndex()) != expect {
+		t.Errorf("expected consistent counts of %d. NumVersions: %d CommitCount: %d CursorIndex: %d", expect, refs[0].Dataset.NumVersions, vi.CommitCount, r.CursorIndex())
+	}
+
+	// repairing a consistent cache is a no-op
+	if repaired, err = dsc.Repair(ctx, book); err != nil {
+		t.Fatal(err)
+	}
+	if repaired != 0 {
+		t.Errorf("expected no entries to need repair, got %d", repaired)
+	}
+}
+
+func TestRepairToZeroCommits(t *testing.T) {
+	ctx := context.Background()
+	keyData := testkeys.GetKeyData(0)
+	pro, err := profile.NewSparsePKProfile("test_user", keyData.PrivKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rootPath, err := ioutil.TempDir("", "dscache_repair_zero")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(rootPath)
+
+	// a dataset with no commits in logbook
+	lb := logbook.NewLogbookTempBuilder(t, pro, qfs.NewMemFS(), rootPath)
+	initID := lb.DatasetInit(ctx, t, "no_commits")
+	book := lb.Logbook()
+
+	// a cache entry that still counts versions
+	builder := NewBuilder()
+	builder.AddUser("test_user", pro.ID.Encode())
+	builder.AddDsVersionInfoWithIndexes(dsref.VersionInfo{
+		InitID:      initID,
+		ProfileID:   pro.ID.Encode(),
+		Name:        "no_commits",
+		CommitCount: 2,
+	}, 2, 2)
+	dsc := builder.Build()
+
+	repaired, err := dsc.Repair(ctx, book)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if repaired != 1 {
+		t.Errorf("expected 1 repaired entry, got %d", repaired)
+	}
+
+	r := dscachefb.RefEntryInfo{}
+	dsc.Root.Refs(&r, 0)
+	if r.TopIndex() != 0 || r.CursorIndex() != 0 || r.CommitCount() != 0 {
+		t.Errorf("expected counts to be repaired to 0. TopIndex: %d CursorIndex: %d CommitCount: %d", r.TopIndex(), r.CursorIndex(), r.CommitCount())
+	}
+	if repaired, err = dsc.Repair(ctx, book); err != nil {
+		t.Fatal(err)
+	}
+	if repaired != 0 {
+		t.Errorf("expected no entries to need repair, got %d", repaired)
+	}
+}
+
+func TestStreamRefs(t *testing.T) {
+	ctx := context.Background()
+	profileID := profile.IDFromPeerID(testkeys.GetKeyData(0).PeerID).Encode()
+
+	builder := NewBuilder()
+	builder.AddUser("test_user", profileID)
+	for _, name := range []string{"c_dataset", "a_dataset", "b_dataset"} {
+		builder.AddDsVersionInfo(dsref.VersionInfo{InitID: name, ProfileID: profileID, Name: name})
+	}
+	dsc := builder.Build()
+
+	expect, err := dsc.ListRefs()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	refs, err := dsc.StreamRefs(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := []reporef.DatasetRef{}
+	for ref := range refs {
+		got = append(got, ref)
+	}
+	if diff := cmp.Diff(expect, got); diff != "" {
+		t.Errorf("streamed refs mismatch (-want +got):\n%s", diff)
+	}
+
+	var empty *Dscache
+	if _, err := empty.StreamRefs(ctx); err != ErrNoDscache {
+		t.Errorf("expected '%s': got '%v'", ErrNoDscache, err)
+	}
+}
+
+func TestStreamRefsWhileChanging(t *testing.T) {
+	ctx := context.Background()
+	profileID := profile.IDFromPeerID(testkeys.GetKeyData(0).PeerID).Encode()
+
+	builder := NewBuilder()
+	builder.AddUser("test_user", profileID)
+	for _, name := range []string{"c_dataset", "a_dataset", "b_dataset"} {
+		builder.AddDsVersionInfo(dsref.VersionInfo{InitID: name, ProfileID: profileID, Name: name})
+	}
+	dsc := builder.Build()
+
+	refs, err := dsc.StreamRefs(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// changes reset username associations while refs are being streamed
+	for i := 0; i < 10; i++ {
+		if err := dsc.Compact(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	got := []string{}
+	for ref := range refs {
+		got = append(got, ref.AliasString())
+	}
+	expect := []string{"test_user/a_dataset", "test_user/b_dataset", "test_user/c_dataset"}
+	if diff := cmp.Diff(expect, got); diff != "" {
+		t.Errorf("streamed refs mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestMarshalJSONWhileResettingUsernames(t *testing.T) {
+	profileID := profile.IDFromPeerID(testkeys.GetKeyData(0).PeerID).Encode()
+
+	builder := NewBuilder()
+	builder.AddUser("test_user", profileID)
+	builder.AddDsVersionInfo(dsref.VersionInfo{InitID: "abcd1", ProfileID: profileID, Name: "a_dataset"})
+	dsc := builder.Build()
+
+	// username associations are reset by changes to the cache while it's being
+	// encoded. run with -race to check associations are read under lock
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 100; i++ {
+			dsc.resetUsernames()
+		}
+	}()
+	for i := 0; i < 100; i++ {
+		data, err := dsc.MarshalJSON()
+		if err != nil {
+			t.Fatal(err)
+		}
+		got := dscacheJSON{}
+		if err := json.Unmarshal(data, &got); err != nil {
+			t.Fatal(err)
+		}
+		if len(got.Refs) != 1 || got.Refs[0].Username != "test_user" {
+			t.Fatalf("expected encoded ref to include username, got: %s", data)
+		}
+	}
+	<-done
+}
+
+func TestListRefsOrder(t *testing.T) {
+	aliceID := profile.IDFromPeerID(testkeys.GetKeyData(0).PeerID).Encode()
+	bobID := profile.IDFromPeerID(testkeys.GetKeyData(1).PeerID).Encode()
+
+	builder := NewBuilder()
+	builder.AddUser("bob", bobID)
+	builder.AddUser("alice", aliceID)
+	builder.AddDsVersionInfo(dsref.VersionInfo{InitID: "id1", ProfileID: bobID, Name: "b_dataset"})
+	builder.AddDsVersionInfo(dsref.VersionInfo{InitID: "id2", ProfileID: aliceID, Name: "z_dataset"})
+	builder.AddDsVersionInfo(dsref.VersionInfo{InitID: "id3", ProfileID: bobID, Name: "a_dataset"})
+	builder.AddDsVersionInfo(dsref.VersionInfo{InitID: "id4", ProfileID: aliceID, Name: "m_dataset"})
+	dsc := builder.Build()
+
+	refNames := func() []string {
+		refs, err := dsc.ListRefs()
+		if err != nil {
+			t.Fatal(err)
+		}
+		names := make([]string, 0, len(refs))
+		for _, ref := range refs {
+			names = append(names, ref.AliasString())
+		}
+		return names
+	}
+
+	expect := []string{"alice/m_dataset", "alice/z_dataset", "bob/a_dataset", "bob/b_dataset"}
+	if diff := cmp.Diff(expect, refNames()); diff != "" {
+		t.Errorf("ref order mismatch (-want +got):\n%s", diff)
+	}
+
+	// deleting & re-adding an entry rebuilds the cache, changing storage order
+	if err := dsc.updateDeleteDataset("id3"); err != nil {
+		t.Fatal(err)
+	}
+	if err := dsc.addEntry("bob", dsref.VersionInfo{InitID: "id3", ProfileID: bobID, Name: "a_dataset"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := dsc.Compact(); err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(expect, refNames()); diff != "" {
+		t.Errorf("ref order changed after rebuild (-want +got):\n%s", diff)
+	}
+}
+
+func TestListUserDatasets(t *testing.T) {
+	aliceID := profile.IDFromPeerID(testkeys.GetKeyData(0).PeerID).Encode()
+	bobID := profile.IDFromPeerID(testkeys.GetKeyData(1).PeerID).Encode()
+	unknownID := profile.IDFromPeerID(testkeys.GetKeyData(2).PeerID).Encode()
+
+	builder := NewBuilder()
+	builder.AddUser("bob", bobID)
+	builder.AddUser("alice", aliceID)
+	builder.AddDsVersionInfo(dsref.VersionInfo{InitID: "id1", ProfileID: bobID, Name: "b_dataset"})
+	builder.AddDsVersionInfo(dsref.VersionInfo{InitID: "id2", ProfileID: aliceID, Name: "z_dataset"})
+	builder.AddDsVersionInfo(dsref.VersionInfo{InitID: "id3", ProfileID: bobID, Name: "a_dataset"})
+	builder.AddDsVersionInfo(dsref.VersionInfo{InitID: "id4", ProfileID: unknownID, Name: "orphan"})
+	dsc := builder.Build()
+
+	infos, err := dsc.ListUserDatasets("bob")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expect := []dsref.VersionInfo{
+		{InitID: "id3", ProfileID: bobID, Username: "bob", Name: "a_dataset"},
+		{InitID: "id1", ProfileID: bobID, Username: "bob", Name: "b_dataset"},
+	}
+	if diff := cmp.Diff(expect, infos); diff != "" {
+		t.Errorf("result mismatch (-want +got):\n%s", diff)
+	}
+
+	for _, username := range []string{"carol", ""} {
+		infos, err := dsc.ListUserDatasets(username)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(infos) != 0 {
+			t.Errorf("expected no datasets for username %q, got: %v", username, infos)
+		}
+	}
+
+	if _, err := (*Dscache)(nil).ListUserDatasets("bob"); !errors.Is(err, ErrNoDscache) {
+		t.Errorf("expected nil dscache to return ErrNoDscache, got: %v", err)
+	}
+}
+
+func TestListRefsUnknownProfile(t *testing.T) {
+	knownID := profile.IDFromPeerID(testkeys.GetKeyData(0).PeerID).Encode()
+	unknownID := profile.IDFromPeerID(testkeys.GetKeyData(1).PeerID).Encode()
+
+	builder := NewBuilder()
+	builder.AddUser("test_user", knownID)
+	builder.AddDsVersionInfo(dsref.VersionInfo{InitID: "abcd1", ProfileID: knownID, Name: "known"})
+	builder.AddDsVersionInfo(dsref.VersionInfo{InitID: "efgh2", ProfileID: unknownID, Name: "orphan"})
+	dsc := builder.Build()
+
+	// lenient mode lists the entry with an empty peername
+	refs, err := dsc.ListRefs()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(refs) != 2 {
+		t.Fatalf("expected 2 refs, got %d", len(refs))
+	}
+	if refs[0].Peername != "" || refs[0].Name != "orphan" {
+		t.Errorf("expected orphaned ref to be listed with an empty peername, got %q", refs[0].AliasString())
+	}
+
+	dsc.StrictUsernames = true
+	_, err = dsc.ListRefs()
+	if !errors.Is(err, ErrInvalidDscache) {
+		t.Fatalf("expected error to match ErrInvalidDscache, got: %v", err)
+	}
+	var upErr *UnknownProfilesError
+	if !errors.As(err, &upErr) {
+		t.Fatalf("expected an *UnknownProfilesError, got %T", err)
+	}
+	expect := &UnknownProfilesError{ProfileIDs: []string{unknownID}, InitIDs: []string{"efgh2"}}
+	if diff := cmp.Diff(expect, upErr); diff != "" {
+		t.Errorf("error mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestSaveDelay(t *testing.T) {
+	profileID := profile.IDFromPeerID(testkeys.GetKeyData(0).PeerID).Encode()
+
+	tmpDir, err := ioutil.TempDir("", "dscache_save_delay")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	writes := 0
+	prevWriteFile := writeFile
+	writeFile = func(filename string, data []byte, perm os.FileMode) error {
+		writes++
+		return prevWriteFile(filename, data, perm)
+	}
+	defer func() { writeFile = prevWriteFile }()
+
+	builder := NewBuilder()
+	builder.AddUser("test_user", profileID)
+	builder.AddDsVersionInfo(dsref.VersionInfo{InitID: "abcd1", ProfileID: profileID, Name: "renamed_0"})
+	dsc := builder.Build()
+	dsc.Filename = filepath.Join(tmpDir, "dscache.qfb")
+	dsc.SaveDelay = time.Hour
+
+	const updates = 10
+	for i := 1; i <= updates; i++ {
+		if err := dsc.updateRenameDataset("abcd1", fmt.Sprintf("renamed_%d", i)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if writes != 0 {
+		t.Errorf("expected no writes before flushing, got %d", writes)
+	}
+
+	if err := dsc.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	if writes >= updates {
+		t.Errorf("expected fewer than %d writes for %d updates, got %d", updates, updates, writes)
+	}
+	data, err := ioutil.ReadFile(dsc.Filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(data, dsc.Buffer) {
+		t.Errorf("expected flushed file to match the latest dscache")
+	}
+
+	// flushing without pending changes doesn't write
+	if err := dsc.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	if writes != 1 {
+		t.Errorf("expected 1 write, got %d", writes)
+	}
+
+	// pending changes are written once the delay passes
+	dsc.SaveDelay = time.Millisecond * 10
+	if err := dsc.updateRenameDataset("abcd1", "renamed_again"); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(time.Millisecond * 100)
+	dsc.saveLk.Lock()
+	got := writes
+	dsc.saveLk.Unlock()
+	if got != 2 {
+		t.Errorf("expected delayed save to write, got %d writes", got)
+	}
+}
+
 func TestCacheRefConsistency(t *testing.T) {
 	ctx := context.Background()
 
@@ -5,11 +5,16 @@ import (
 	dscachefb "github.com/qri-io/qri/dscache/dscachefb"
 )
 
-func (d *Dscache) copyUserAssociationList(builder *flatbuffers.Builder) flatbuffers.UOffsetT {
+// For each user association in the dscache, copy it to the builder. If keepFunc is non-nil,
+// only associations it returns true for are copied.
+func (d *Dscache) copyUserAssociationList(builder *flatbuffers.Builder, keepFunc func(*dscachefb.UserAssoc) bool) flatbuffers.UOffsetT {
 	userList := make([]flatbuffers.UOffsetT, 0, d.Root.UsersLength())
 	for i := 0; i < d.Root.UsersLength(); i++ {
 		up := dscachefb.UserAssoc{}
 		d.Root.Users(&up, i)
+		if keepFunc != nil && !keepFunc(&up) {
+			continue
+		}
 		d.copyUserAssoc(builder, &up)
 		user := dscachefb.UserAssocEnd(builder)
 		userList = append(userList, user)
@@ -27,7 +32,7 @@ func (d *Dscache) copyUserAssociationList(builder *flatbuffers.Builder) flatbuff
 func (d *Dscache) copyReferenceListWithReplacement(
 	builder *flatbuffers.Builder,
 	findMatchFunc func(*dscachefb.RefEntryInfo) bool,
-	replaceRefFunc func(func(*flatbuffers.Builder))) flatbuffers.UOffsetT {
+	replaceRefFunc func(func(*flatbuffers.Builder, func(*refScalars)))) flatbuffers.UOffsetT {
 
 	// Construct refs, with all pertinent information for each dataset ref
 	refList := make([]flatbuffers.UOffsetT, 0, d.Root.RefsLength())
@@ -40,8 +45,10 @@ func (d *Dscache) copyReferenceListWithReplacement(
 			// The replace func may want to create some slots (such as strings) before the
 			// builder starts on construction. This means we can't call copyReference now, instead,
 			// pass it as a func to the callback, let it start construction when it is ready.
-			startRefBuildFunc := func(_ *flatbuffers.Builder) {
-				d.copyReference(builder, &r)
+			// Scalar fields can't be changed by adding their slots again once copied,
+			// the replace func passes an edit func to change them instead.
+			startRefBuildFunc := func(_ *flatbuffers.Builder, edit func(*refScalars)) {
+				d.copyReferenceWithScalars(builder, &r, edit)
 			}
 			if replaceRefFunc != nil {
 				replaceRefFunc(startRefBuildFunc)
@@ -80,24 +87,77 @@ func (d *Dscache) copyUserAssoc(builder *flatbuffers.Builder, ua *dscachefb.User
 	dscachefb.UserAssocAddProfileID(builder, profileID)
 }
 
+// refScalars holds the scalar fields of a ref entry. Flatbuffers doesn't write
+// scalars that equal their default value, so adding a slot a second time can't
+// reset a copied scalar to its default
+type refScalars struct {
+	TopIndex    int32
+	CursorIndex int32
+	Published   bool
+	Foreign     bool
+	BodySize    int64
+	BodyRows    int32
+	CommitTime  int64
+	CommitCount int32
+	NumErrors   int32
+	RunDuration int64
+}
+
 func (d *Dscache) copyReference(builder *flatbuffers.Builder, r *dscachefb.RefEntryInfo) {
+	d.copyReferenceWithScalars(builder, r, nil)
+}
+
+// copyReferenceWithScalars starts building a copy of a reference, calling edit
+// to change the copied scalar fields before they're written. edit may be nil
+func (d *Dscache) copyReferenceWithScalars(builder *flatbuffers.Builder, r *dscachefb.RefEntryInfo, edit func(*refScalars)) {
+	sc := refScalars{
+		TopIndex:    r.TopIndex(),
+		CursorIndex: r.CursorIndex(),
+		Published:   r.Published(),
+		Foreign:     r.Foreign(),
+		BodySize:    r.BodySize(),
+		BodyRows:    r.BodyRows(),
+		CommitTime:  r.CommitTime(),
+		CommitCount: r.CommitCount(),
+		NumErrors:   r.NumErrors(),
+		RunDuration: r.RunDuration(),
+	}
+	if edit != nil {
+		edit(&sc)
+	}
 	initID := builder.CreateString(string(r.InitID()))
 	profileID := builder.CreateString(string(r.ProfileID()))
 	prettyName := builder.CreateString(string(r.PrettyName()))
 	metaTitle := builder.CreateString(string(r.MetaTitle()))
 	themeList := builder.CreateString(string(r.ThemeList()))
 	hashRef := builder.CreateString(string(r.HeadRef()))
+	bodyFormat := builder.CreateString(string(r.BodyFormat()))
+	fsiPath := builder.CreateString(string(r.FsiPath()))
+	commitTitle := builder.CreateString(string(r.CommitTitle()))
+	commitMessage := builder.CreateString(string(r.CommitMessage()))
+	runID := builder.CreateString(string(r.RunID()))
+	runStatus := builder.CreateString(string(r.RunStatus()))
 	dscachefb.RefEntryInfoStart(builder)
 	dscachefb.RefEntryInfoAddInitID(builder, initID)
 	dscachefb.RefEntryInfoAddProfileID(builder, profileID)
-	dscachefb.RefEntryInfoAddTopIndex(builder, int32(r.TopIndex()))
-	dscachefb.RefEntryInfoAddCursorIndex(builder, int32(r.CursorIndex()))
+	dscachefb.RefEntryInfoAddTopIndex(builder, sc.TopIndex)
+	dscachefb.RefEntryInfoAddCursorIndex(builder, sc.CursorIndex)
 	dscachefb.RefEntryInfoAddPrettyName(builder, prettyName)
+	dscachefb.RefEntryInfoAddPublished(builder, sc.Published)
+	dscachefb.RefEntryInfoAddForeign(builder, sc.Foreign)
 	dscachefb.RefEntryInfoAddMetaTitle(builder, metaTitle)
 	dscachefb.RefEntryInfoAddThemeList(builder, themeList)
-	dscachefb.RefEntryInfoAddBodySize(builder, int64(r.BodySize()))
-	dscachefb.RefEntryInfoAddBodyRows(builder, int32(r.BodyRows()))
-	dscachefb.RefEntryInfoAddCommitTime(builder, r.CommitTime())
-	dscachefb.RefEntryInfoAddNumErrors(builder, int32(r.NumErrors()))
+	dscachefb.RefEntryInfoAddBodySize(builder, sc.BodySize)
+	dscachefb.RefEntryInfoAddBodyRows(builder, sc.BodyRows)
+	dscachefb.RefEntryInfoAddBodyFormat(builder, bodyFormat)
+	dscachefb.RefEntryInfoAddCommitTime(builder, sc.CommitTime)
+	dscachefb.RefEntryInfoAddCommitCount(builder, sc.CommitCount)
+	dscachefb.RefEntryInfoAddNumErrors(builder, sc.NumErrors)
 	dscachefb.RefEntryInfoAddHeadRef(builder, hashRef)
+	dscachefb.RefEntryInfoAddFsiPath(builder, fsiPath)
+	dscachefb.RefEntryInfoAddCommitTitle(builder, commitTitle)
+	dscachefb.RefEntryInfoAddCommitMessage(builder, commitMessage)
+	dscachefb.RefEntryInfoAddRunID(builder, runID)
+	dscachefb.RefEntryInfoAddRunStatus(builder, runStatus)
+	dscachefb.RefEntryInfoAddRunDuration(builder, sc.RunDuration)
 }
@@ -3,14 +3,18 @@ package dsref
 import (
 	"context"
 	"fmt"
+	"sync"
 )
 
 // MemResolver holds maps that can do a cheap version of dataset resolution,
-// for tests
+// for tests. MemResolver methods are safe for concurrent use. Reading or
+// writing RefMap & IDMap directly while other goroutines call methods is not
 type MemResolver struct {
 	Username string
 	RefMap   map[string]string
 	IDMap    map[string]VersionInfo
+
+	lk sync.RWMutex
 }
 
 // assert at compile time that MemResolver is a Resolver
@@ -29,12 +33,16 @@ func NewMemResolver(username string) *MemResolver {
 func (m *MemResolver) Put(info VersionInfo) {
 	refStr := fmt.Sprintf("%s/%s", info.Username, info.Name)
 	initID := info.InitID
+	m.lk.Lock()
+	defer m.lk.Unlock()
 	m.RefMap[refStr] = initID
 	m.IDMap[initID] = info
 }
 
 // GetInfo returns a VersionInfo by initID, or nil if not found
 func (m *MemResolver) GetInfo(initID string) *VersionInfo {
+	m.lk.RLock()
+	defer m.lk.RUnlock()
 	if info, ok := m.IDMap[initID]; ok {
 		return &info
 	}
@@ -48,6 +56,9 @@ func (m *MemResolver) ResolveRef(ctx context.Context, ref *Ref) (string, error)
 		return "", ErrRefNotFound
 	}
 
+	m.lk.RLock()
+	defer m.lk.RUnlock()
+
 	if ref.InitID != "" {
 		return m.completeRef(ctx, ref)
 	}
@@ -2,6 +2,8 @@ package dsref_test
 
 import (
 	"context"
+	"fmt"
+	"sync"
 	"testing"
 
 	"github.com/qri-io/qri/dsref"
@@ -29,3 +31,38 @@ func TestMemResolver(t *testing.T) {
 		return nil
 	})
 }
+
+func TestMemResolverConcurrentAccess(t *testing.T) {
+	ctx := context.Background()
+	m := dsref.NewMemResolver("test_peer_mem_resolver")
+
+	wg := sync.WaitGroup{}
+	for i := 0; i < 20; i++ {
+		wg.Add(2)
+		name := fmt.Sprintf("dataset_%d", i)
+		go func(i int) {
+			defer wg.Done()
+			m.Put(dsref.VersionInfo{
+				InitID:   fmt.Sprintf("init_id_%d", i),
+				Username: "test_peer_mem_resolver",
+				Name:     name,
+				Path:     "/mem/QmFoo",
+			})
+		}(i)
+		go func() {
+			defer wg.Done()
+			ref := &dsref.Ref{Username: "test_peer_mem_resolver", Name: name}
+			if _, err := m.ResolveRef(ctx, ref); err != nil && err != dsref.ErrRefNotFound {
+				t.Errorf("unexpected error resolving %q: %s", name, err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	for i := 0; i < 20; i++ {
+		ref := &dsref.Ref{Username: "test_peer_mem_resolver", Name: fmt.Sprintf("dataset_%d", i)}
+		if _, err := m.ResolveRef(ctx, ref); err != nil {
+			t.Errorf("resolving %q after concurrent puts: %s", ref.Name, err)
+		}
+	}
+}
@@ -11,8 +11,12 @@ const (
 	// payload is a dsref.VersionInfo
 	ETDatasetRename = Type("dataset:Rename")
 	// ETDatasetCreateLink occurs when a dataset gets linked to a working directory
-	// payload is a dsref.VersionInfo
+	// payload is a DsLink
 	ETDatasetCreateLink = Type("dataset:CreateLink")
+	// ETDatasetRemoveLink occurs when a dataset is unlinked from a working
+	// directory
+	// payload is an `InitID` string
+	ETDatasetRemoveLink = Type("dataset:RemoveLink")
 	// ETDatasetDownload indicates that a dataset has been downloaded
 	// payload is an `InitID` string
 	ETDatasetDownload = Type("dataset:Download")
@@ -28,6 +32,10 @@ const (
 	// ETDatasetSaveCompleted occurs when a dataset save finishes
 	// payload will be a DsSaveEvent
 	ETDatasetSaveCompleted = Type("dataset:SaveCompleted")
+	// ETDatasetSaved occurs once a new dataset version is stored & recorded in
+	// the logbook, after the entire save pipeline has finished
+	// payload is a dsref.VersionInfo describing the saved version
+	ETDatasetSaved = Type("dataset:Saved")
 )
 
 // DsRename encapsulates fields from a dataset rename
@@ -37,6 +45,12 @@ type DsRename struct {
 	NewName string `json:"newName"`
 }
 
+// DsLink associates a dataset with a working directory
+type DsLink struct {
+	InitID  string `json:"initID"`
+	FSIPath string `json:"fsiPath"`
+}
+
 // DsSaveEvent represents a change in version creation progress
 type DsSaveEvent struct {
 	Username string `json:"username"`
@@ -46,8 +60,12 @@ type DsSaveEvent struct {
 	Message string `json:"message"`
 	// saving error. only populated on failed ETSaveDatasetCompleted event
 	Error error `json:"error,omitempty"`
-	// completion pct from 0-1
+	// completion pct from 0-1. 0 while processing a body of unknown size
 	Completion float64 `json:"complete"`
+	// number of body bytes & entries read so far. only populated on
+	// ETDatasetSaveProgress events sent while processing the body
+	BytesProcessed   int64 `json:"bytesProcessed,omitempty"`
+	EntriesProcessed int   `json:"entriesProcessed,omitempty"`
 	// only populated on successful ETDatasetSaveCompleted
 	Path string `json:"path,omitempty"`
 }
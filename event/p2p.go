@@ -30,4 +30,8 @@ var (
 	// from a Qri peer
 	// payload will be a p2p.Message
 	ETP2PMessageReceived = Type("p2p:MessageReceived")
+	// ETP2PProfileUpdated fires when a profile received from a peer changes a
+	// profile in the local profile store
+	// payload is a *profile.Profile, the merged profile
+	ETP2PProfileUpdated = Type("p2p:ProfileUpdated")
 )
@@ -24,6 +24,9 @@ const (
 	// ETTransformError is for when a tranform program execution error occurs.
 	// Payload will be a Message
 	ETTransformError = Type("tf:Error")
+	// ETTransformLog is a structured log line emitted by a transform script.
+	// Payload will be a TransformLog
+	ETTransformLog = Type("tf:Log")
 	// ETTransformDatasetPreview is an abbreviated dataset document in a transform
 	// Payload will be a *dataset.Dataset Preview
 	ETTransformDatasetPreview = Type("tf:DatasetPreview")
@@ -78,3 +81,10 @@ type TransformMessage struct {
 	Msg  string          `json:"msg"`
 	Mode string          `json:"mode,omitempty"`
 }
+
+// TransformLog is the payload for structured log events
+type TransformLog struct {
+	Lvl    TransformMsgLvl        `json:"lvl"`
+	Msg    string                 `json:"msg"`
+	Fields map[string]interface{} `json:"fields,omitempty"`
+}
@@ -2,11 +2,13 @@ package lib
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
 
 	"github.com/qri-io/dataset"
+	"github.com/qri-io/dataset/dsio"
 	"github.com/qri-io/dataset/preview"
 	"github.com/qri-io/ioes"
 	"github.com/qri-io/qri/automation"
@@ -18,6 +20,7 @@ import (
 	qhttp "github.com/qri-io/qri/lib/http"
 	"github.com/qri-io/qri/transform"
 	"github.com/qri-io/qri/transform/staticlark"
+	"go.starlark.net/syntax"
 )
 
 // AutomationMethods groups together methods for automations
@@ -58,6 +61,21 @@ type ApplyParams struct {
 	// size of the output area that the results will display on
 	OutputWidth  int `json:"outputWidth"`
 	OutputHeight int `json:"outputHeight"`
+	// PreviewRows is the number of body rows to include in the result when
+	// Wait is true. zero uses the default preview size, values above the
+	// default are capped at the default. a negative value omits the body
+	PreviewRows int `json:"previewRows"`
+	// Deterministic runs the transform with a fixed clock and a random source
+	// seeded with Seed, so repeated applies produce identical output
+	Deterministic bool  `json:"deterministic"`
+	Seed          int64 `json:"seed"`
+	// LoadRetry retries load_dataset calls that fail for transient reasons.
+	// nil doesn't retry
+	LoadRetry *LoadRetryPolicy `json:"loadRetry,omitempty"`
+	// VerifyBodyChecksum confirms bodies the transform reports as unchanged
+	// by comparing checksums with the previous version. Enabled for every
+	// apply when the automation config sets VerifyBodyChecksum
+	VerifyBodyChecksum bool `json:"verifyBodyChecksum"`
 }
 
 // Validate returns an error if ApplyParams fields are in an invalid state
@@ -74,7 +92,8 @@ type ApplyResult struct {
 	RunID string `json:"runID"`
 }
 
-// Apply runs a transform script
+// Apply runs a transform script. Apply only previews transform output, it
+// doesn't create a dataset version or write to logbook
 func (m AutomationMethods) Apply(ctx context.Context, p *ApplyParams) (*ApplyResult, error) {
 	got, _, err := m.d.Dispatch(ctx, dispatchMethodName(m, "apply"), p)
 	if res, ok := got.(*ApplyResult); ok {
@@ -164,7 +183,8 @@ func (p *CancelParams) Validate() error {
 	return nil
 }
 
-// Cancel cancels the run for the given runID
+// Cancel cancels the run for the given runID. Cancelling a run that is unknown
+// or has already finished returns an error
 func (m AutomationMethods) Cancel(ctx context.Context, p *CancelParams) error {
 	_, _, err := m.d.Dispatch(ctx, dispatchMethodName(m, "cancel"), p)
 	return dispatchReturnError(nil, err)
@@ -220,6 +240,9 @@ func (p *AnalyzeTransformParams) Validate() error {
 // AnalyzeTransformResult ...
 type AnalyzeTransformResult struct {
 	Diagnostics []staticlark.Diagnostic
+	// CallGraph is a text representation of the functions in the script and
+	// the functions they call
+	CallGraph string
 }
 
 // AnalyzeTransform ...
@@ -274,9 +297,16 @@ func (automationImpl) Apply(scope scope, p *ApplyParams) (*ApplyResult, error) {
 	}
 
 	params := automation.WorkflowRunParams{
-		Secrets:      p.Secrets,
-		OutputWidth:  p.OutputWidth,
-		OutputHeight: p.OutputHeight,
+		Secrets:            p.Secrets,
+		OutputWidth:        p.OutputWidth,
+		OutputHeight:       p.OutputHeight,
+		Deterministic:      p.Deterministic,
+		Seed:               p.Seed,
+		VerifyBodyChecksum: p.VerifyBodyChecksum,
+	}
+	if p.LoadRetry != nil {
+		params.LoadRetryAttempts = p.LoadRetry.Attempts
+		params.LoadRetryBackoff = p.LoadRetry.Backoff
 	}
 
 	runID, err := scope.AutomationOrchestrator().ApplyWorkflow(ctx, p.Wait, p.ScriptOutput, wf, ds, params)
@@ -286,7 +316,7 @@ func (automationImpl) Apply(scope scope, p *ApplyParams) (*ApplyResult, error) {
 
 	res := &ApplyResult{}
 	if p.Wait {
-		ds, err := preview.Create(scope.Context(), ds)
+		ds, err := createApplyPreview(scope.Context(), ds, p.PreviewRows)
 		if err != nil {
 			return nil, err
 		}
@@ -296,6 +326,51 @@ func (automationImpl) Apply(scope scope, p *ApplyParams) (*ApplyResult, error) {
 	return res, nil
 }
 
+// createApplyPreview builds a preview of an applied dataset with at most rows
+// body entries. Zero rows uses the default preview size, fewer than zero
+// leaves the body out. Body entries past rows are never read. The given
+// dataset isn't modified
+func createApplyPreview(ctx context.Context, ds *dataset.Dataset, rows int) (*dataset.Dataset, error) {
+	if ds == nil {
+		return nil, fmt.Errorf("nil dataset")
+	}
+	body := ds.BodyFile()
+	if body != nil && ds.Structure == nil {
+		return nil, fmt.Errorf("cannot preview a dataset body without a structure")
+	}
+
+	p := &dataset.Dataset{}
+	p.Assign(ds)
+	if rows == 0 || rows >= preview.MaxNumDatasetRowsInPreview {
+		return preview.Create(ctx, p)
+	}
+
+	// build the preview without the body file, reading only the entries needed
+	p.SetBodyFile(nil)
+	p, err := preview.Create(ctx, p)
+	if err != nil {
+		return nil, err
+	}
+	if rows < 0 {
+		p.Body = nil
+		return p, nil
+	}
+	if body == nil {
+		return p, nil
+	}
+
+	st := &dataset.Structure{
+		Format: "json",
+		Schema: ds.Structure.Schema,
+	}
+	data, err := dsio.ConvertFile(body, ds.Structure, st, rows, 0, false)
+	if err != nil {
+		return nil, err
+	}
+	p.Body = json.RawMessage(data)
+	return p, nil
+}
+
 // Deploy adds or updates a Dataset, creates or updates an associated Workflow, and, if deployParams.Apply is true, immediately runs the Workflow
 func (automationImpl) Deploy(scope scope, p *DeployParams) error {
 	log.Debugw("deploy", "dataset name", p.Dataset.Name, "peername", p.Dataset.Peername, "workflow id", p.Workflow.ID)
@@ -465,8 +540,7 @@ func (automationImpl) RunInfo(scope scope, p *RunInfoParams) (*run.State, error)
 
 // Cancel cancels a run
 func (automationImpl) Cancel(scope scope, p *CancelParams) error {
-	scope.AutomationOrchestrator().CancelRun(scope.Context(), p.RunID)
-	return nil
+	return scope.AutomationOrchestrator().CancelRun(scope.Context(), p.RunID)
 }
 
 // Workflow fetches a workflow by the workflow or dataset id
@@ -549,7 +623,15 @@ func (inst *Instance) apply(ctx context.Context, wait bool, runID string, wf *wo
 		OutputHeight: params.OutputHeight,
 	}
 
-	transformer := transform.NewTransformer(ctx, scope.Filesystem(), scope.Loader(), scope.Bus(), sizeInfo)
+	loader := withLoadRetries(scope.TransformLoader(), params.LoadRetryAttempts, params.LoadRetryBackoff)
+	transformer := transform.NewTransformer(ctx, scope.Filesystem(), loader, scope.Bus(), sizeInfo)
+	transformer.AllowHTTPDomains(scope.AllowedHTTPDomains())
+	transformer.SetBodyDiffThreshold(scope.BodyDiffThreshold())
+	transformer.SetCommitMessageFunc(scope.CommitMessageFunc())
+	transformer.SetVerifyBodyChecksum(params.VerifyBodyChecksum || scope.VerifyBodyChecksum())
+	if params.Deterministic {
+		transformer.Deterministic(params.Seed)
+	}
 	return transformer.Apply(scope.Context(), ds, runID, wait, params.Secrets)
 }
 
@@ -560,12 +642,29 @@ func (automationImpl) AnalyzeTransform(scope scope, p *AnalyzeTransformParams) (
 
 	// Perform static analysis and show the results
 	diagnostics, err := staticlark.AnalyzeFile(p.ScriptFileName)
+	if err != nil {
+		// report syntax errors in the script as diagnostics
+		var synErr syntax.Error
+		if errors.As(err, &synErr) {
+			return &AnalyzeTransformResult{
+				Diagnostics: []staticlark.Diagnostic{{
+					Pos:      synErr.Pos,
+					Category: "parse",
+					Message:  synErr.Msg,
+				}},
+			}, nil
+		}
+		return nil, err
+	}
+
+	callGraph, err := staticlark.CallGraph(p.ScriptFileName)
 	if err != nil {
 		return nil, err
 	}
 
 	return &AnalyzeTransformResult{
 		Diagnostics: diagnostics,
+		CallGraph:   callGraph,
 	}, nil
 }
 
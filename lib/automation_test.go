@@ -1,19 +1,24 @@
 package lib
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"strings"
 	"testing"
 	"time"
 
 	"github.com/google/go-cmp/cmp"
 	"github.com/qri-io/dataset"
+	"github.com/qri-io/qfs"
+	"github.com/qri-io/qri/automation"
 	"github.com/qri-io/qri/automation/run"
 	"github.com/qri-io/qri/automation/workflow"
 	"github.com/qri-io/qri/event"
+	"github.com/qri-io/qri/logbook/oplog"
 )
 
 func TestApplyTransform(t *testing.T) {
@@ -84,6 +89,158 @@ dataset.commit(ds)
 	}
 }
 
+func TestApplyDoesNotWriteLogbook(t *testing.T) {
+	tr := newTestRunner(t)
+	defer tr.Delete()
+
+	if _, err := tr.SaveWithParams(&SaveParams{
+		Ref:      "me/cities_ds",
+		BodyPath: "testdata/cities_2/body.csv",
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	opCount := func() int {
+		logs, err := tr.Instance.logbook.ListAllLogs(tr.Ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var count func(l *oplog.Log) int
+		count = func(l *oplog.Log) int {
+			n := len(l.Ops)
+			for _, child := range l.Logs {
+				n += count(child)
+			}
+			return n
+		}
+		total := 0
+		for _, l := range logs {
+			total += count(l)
+		}
+		return total
+	}
+
+	before := opCount()
+	if _, err := tr.ApplyWithParams(tr.Ctx, &ApplyParams{
+		Ref: "me/cities_ds",
+		Transform: &dataset.Transform{
+			ScriptPath: "testdata/cities_2/add_city.star",
+		},
+		Wait: true,
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if after := opCount(); after != before {
+		t.Errorf("expected apply not to write to logbook. ops before: %d, after: %d", before, after)
+	}
+}
+
+func TestApplyPreviewRows(t *testing.T) {
+	tr := newTestRunner(t)
+	defer tr.Delete()
+
+	script := `
+load("dataframe.star", "dataframe")
+ds = dataset.latest()
+
+body = """a,b,c
+1,2,3
+4,5,6
+7,8,9
+"""
+ds.body = dataframe.parse_csv(body)
+dataset.commit(ds)
+`
+	cases := []struct {
+		rows   int
+		expect string
+	}{
+		{0, `[[1,2,3],[4,5,6],[7,8,9]]`},
+		{2, `[[1,2,3],[4,5,6]]`},
+		{1, `[[1,2,3]]`},
+		{-1, `null`},
+	}
+
+	for _, c := range cases {
+		res, err := tr.ApplyWithParams(tr.Ctx, &ApplyParams{
+			Wait:        true,
+			PreviewRows: c.rows,
+			Transform:   &dataset.Transform{Text: script},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, err := json.Marshal(res.Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if diff := cmp.Diff(c.expect, string(data)); diff != "" {
+			t.Errorf("PreviewRows %d body mismatch (-want +got):\n%s", c.rows, diff)
+		}
+	}
+}
+
+func TestCreateApplyPreviewRows(t *testing.T) {
+	ctx := context.Background()
+
+	// make the body larger than the JSON reader's read buffer
+	entries := make([]string, 200000)
+	for i := range entries {
+		entries[i] = fmt.Sprintf("%d", i)
+	}
+	body := []byte("[" + strings.Join(entries, ",") + "]")
+	newDataset := func() (*dataset.Dataset, *countingReader) {
+		ds := &dataset.Dataset{
+			Name:      "preview",
+			Structure: &dataset.Structure{Format: "json", Schema: dataset.BaseSchemaArray},
+		}
+		r := &countingReader{r: bytes.NewReader(body)}
+		ds.SetBodyFile(qfs.NewMemfileReader("body.json", r))
+		return ds, r
+	}
+
+	ds, r := newDataset()
+	got, err := createApplyPreview(ctx, ds, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(`[0,1]`, string(got.Body.(json.RawMessage))); diff != "" {
+		t.Errorf("body mismatch (-want +got):\n%s", diff)
+	}
+	if r.n >= len(body)/2 {
+		t.Errorf("expected preview to stop reading the body after 2 entries, read %d of %d bytes", r.n, len(body))
+	}
+	if ds.BodyFile() == nil {
+		t.Errorf("expected building a preview to leave the given dataset's body file in place")
+	}
+
+	ds, _ = newDataset()
+	if got, err = createApplyPreview(ctx, ds, -1); err != nil {
+		t.Fatal(err)
+	}
+	if got.Body != nil {
+		t.Errorf("expected negative rows to leave the body out, got: %v", got.Body)
+	}
+
+	ds, _ = newDataset()
+	ds.Structure = nil
+	if _, err := createApplyPreview(ctx, ds, 2); err == nil {
+		t.Errorf("expected previewing a body without a structure to error")
+	}
+}
+
+// countingReader counts the bytes read from r
+type countingReader struct {
+	r io.Reader
+	n int
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += n
+	return n, err
+}
+
 func TestApplyTransformValidationFailure(t *testing.T) {
 	tr := newTestRunner(t)
 	defer tr.Delete()
@@ -254,6 +411,97 @@ dataset.commit(ds)
 	}
 }
 
+func TestCancelRun(t *testing.T) {
+	tr := newTestRunner(t)
+	defer tr.Delete()
+
+	ds := &dataset.Dataset{
+		Name:     "long_running",
+		Peername: tr.MustOwner(t).Peername,
+		Transform: &dataset.Transform{
+			Steps: []*dataset.TransformStep{
+				{
+					Name:     "transform",
+					Syntax:   "starlark",
+					Category: "transform",
+					Script: `
+def spin():
+  for i in range(1000000000):
+    pass
+
+spin()
+`,
+				},
+			},
+		},
+	}
+	wf := &workflow.Workflow{
+		OwnerID: tr.MustOwner(t).ID,
+		Active:  true,
+	}
+
+	deployEnded := make(chan string)
+	bus := tr.Instance.Bus()
+	bus.SubscribeTypes(func(ctx context.Context, e event.Event) error {
+		if payload, ok := e.Payload.(event.DeployEvent); ok {
+			wf.ID = workflow.ID(payload.WorkflowID)
+			deployEnded <- payload.Error
+		}
+		return nil
+	}, event.ETAutomationDeployEnd)
+
+	done := errOnTimeout(t, deployEnded)
+	if err := tr.Instance.WithSource("local").Automation().Deploy(tr.Ctx, &DeployParams{Dataset: ds, Workflow: wf}); err != nil {
+		t.Fatalf("deploy unexpected error: %s", err)
+	}
+	if errMsg := <-done; errMsg != "" {
+		t.Fatal(errMsg)
+	}
+
+	transformStarted := make(chan string)
+	runStopped := make(chan string)
+	bus.SubscribeTypes(func(ctx context.Context, e event.Event) error {
+		switch e.Type {
+		case event.ETTransformStart:
+			transformStarted <- e.SessionID
+		case event.ETAutomationWorkflowStopped:
+			if p, ok := e.Payload.(event.WorkflowStoppedEvent); ok {
+				runStopped <- p.Status
+			}
+		}
+		return nil
+	}, event.ETTransformStart, event.ETAutomationWorkflowStopped)
+
+	started := errOnTimeout(t, transformStarted)
+	runID, err := tr.Instance.WithSource("local").Automation().Run(tr.Ctx, &RunParams{WorkflowID: wf.WorkflowID()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if gotID := <-started; gotID != runID {
+		t.Fatalf("expected transform for run %q to start, got: %q", runID, gotID)
+	}
+
+	stopped := errOnTimeout(t, runStopped)
+	if err := tr.Instance.WithSource("local").Automation().Cancel(tr.Ctx, &CancelParams{RunID: runID}); err != nil {
+		t.Fatal(err)
+	}
+	if status := <-stopped; status != string(run.RSCancelled) {
+		t.Errorf("expected run stopped event with status %q, got: %q", run.RSCancelled, status)
+	}
+
+	state, err := tr.Instance.WithSource("local").Automation().RunInfo(tr.Ctx, &RunInfoParams{ID: runID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if state.Status != run.RSCancelled {
+		t.Errorf("expected run status %q, got: %q", run.RSCancelled, state.Status)
+	}
+
+	if err := tr.Instance.WithSource("local").Automation().Cancel(tr.Ctx, &CancelParams{RunID: runID}); !errors.Is(err, automation.ErrUnknownRun) {
+		t.Errorf("expected cancelling a finished run to error with %q, got: %v", automation.ErrUnknownRun, err)
+	}
+}
+
 func TestRunParamsValidate(t *testing.T) {
 	p := &RunParams{}
 	if err := p.Validate(); err == nil {
@@ -8,6 +8,7 @@ import (
 
 	"github.com/qri-io/qri/base"
 	"github.com/qri-io/qri/base/params"
+	"github.com/qri-io/qri/dscache"
 	"github.com/qri-io/qri/dscache/build"
 	"github.com/qri-io/qri/dsref"
 	qhttp "github.com/qri-io/qri/lib/http"
@@ -33,9 +34,12 @@ func (m CollectionMethods) Name() string {
 // Attributes defines attributes for each method
 func (m CollectionMethods) Attributes() map[string]AttributeSet {
 	return map[string]AttributeSet{
-		"list":        {Endpoint: qhttp.AEList, HTTPVerb: "POST"},
-		"listrawrefs": {Endpoint: qhttp.DenyHTTP},
-		"get":         {Endpoint: qhttp.AECollectionGet, HTTPVerb: "POST"},
+		"list":            {Endpoint: qhttp.AEList, HTTPVerb: "POST"},
+		"listrawrefs":     {Endpoint: qhttp.DenyHTTP},
+		"dscache":         {Endpoint: qhttp.DenyHTTP},
+		"rebuilddscache":  {Endpoint: qhttp.DenyHTTP},
+		"validatedscache": {Endpoint: qhttp.DenyHTTP},
+		"get":             {Endpoint: qhttp.AECollectionGet, HTTPVerb: "POST"},
 	}
 }
 
@@ -81,6 +85,32 @@ func (m CollectionMethods) ListRawRefs(ctx context.Context, p *EmptyParams) (str
 	return "", dispatchReturnError(got, err)
 }
 
+// Dscache returns the dscache for inspection. If the instance has no dscache,
+// one is built from the repo without being stored
+func (m CollectionMethods) Dscache(ctx context.Context, p *EmptyParams) (*dscache.Dscache, error) {
+	got, _, err := m.d.Dispatch(ctx, dispatchMethodName(m, "dscache"), p)
+	if res, ok := got.(*dscache.Dscache); ok {
+		return res, err
+	}
+	return nil, dispatchReturnError(got, err)
+}
+
+// RebuildDscache replaces the contents of the dscache with one built from the
+// repo's logbook, returning the number of datasets in the rebuilt dscache
+func (m CollectionMethods) RebuildDscache(ctx context.Context, p *EmptyParams) (int, error) {
+	got, _, err := m.d.Dispatch(ctx, dispatchMethodName(m, "rebuilddscache"), p)
+	if res, ok := got.(int); ok {
+		return res, err
+	}
+	return 0, dispatchReturnError(got, err)
+}
+
+// ValidateDscache checks the dscache for internal consistency
+func (m CollectionMethods) ValidateDscache(ctx context.Context, p *EmptyParams) error {
+	_, _, err := m.d.Dispatch(ctx, dispatchMethodName(m, "validatedscache"), p)
+	return dispatchReturnError(nil, err)
+}
+
 // CollectionGetParams defines parameters for looking up the head of a dataset from the collection
 type CollectionGetParams struct {
 	Ref    string `json:"ref"`
@@ -266,6 +296,36 @@ func (collectionImpl) ListRawRefs(scope scope, p *EmptyParams) (string, error) {
 	return base.RawDatasetRefs(scope.Context(), scope.ActiveProfile().ID, scope.CollectionSet())
 }
 
+// Dscache returns the dscache for inspection
+func (collectionImpl) Dscache(scope scope, p *EmptyParams) (*dscache.Dscache, error) {
+	if c := scope.Dscache(); c != nil && !c.IsEmpty() {
+		return c, nil
+	}
+	return build.DscacheFromRepo(scope.Context(), scope.Repo())
+}
+
+// RebuildDscache replaces the contents of the dscache with one built from the
+// repo's logbook
+func (collectionImpl) RebuildDscache(scope scope, p *EmptyParams) (int, error) {
+	c := scope.Dscache()
+	if c == nil {
+		return 0, dscache.ErrNoDscache
+	}
+	built, err := build.DscacheFromRepo(scope.Context(), scope.Repo())
+	if err != nil {
+		return 0, err
+	}
+	if err := c.Assign(built); err != nil {
+		return 0, err
+	}
+	return c.Root.RefsLength(), nil
+}
+
+// ValidateDscache checks the dscache for internal consistency
+func (collectionImpl) ValidateDscache(scope scope, p *EmptyParams) error {
+	return scope.Dscache().Validate()
+}
+
 // Get gets the head of a dataset as a VersionInfo from the collection
 func (collectionImpl) Get(scope scope, p *CollectionGetParams) (*dsref.VersionInfo, error) {
 	s := scope.CollectionSet()
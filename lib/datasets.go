@@ -8,12 +8,15 @@ import (
 	"fmt"
 	"io"
 	"io/ioutil"
+	"os"
 	"path/filepath"
 	"regexp"
+	"sort"
 	"strings"
 	"time"
 
 	"github.com/qri-io/dag"
+	"github.com/qri-io/dag/dsync"
 	"github.com/qri-io/dataset"
 	"github.com/qri-io/dataset/detect"
 	"github.com/qri-io/dataset/preview"
@@ -32,9 +35,12 @@ import (
 	"github.com/qri-io/qri/event"
 	qhttp "github.com/qri-io/qri/lib/http"
 	"github.com/qri-io/qri/logbook"
+	"github.com/qri-io/qri/p2p"
+	"github.com/qri-io/qri/profile"
 	"github.com/qri-io/qri/remote"
 	"github.com/qri-io/qri/repo"
 	"github.com/qri-io/qri/transform"
+	stards "github.com/qri-io/qri/transform/startf/ds"
 )
 
 // DatasetMethods work with datasets, creating new versions (save), reading
@@ -55,10 +61,15 @@ func (m DatasetMethods) Attributes() map[string]AttributeSet {
 		"get":             {Endpoint: qhttp.AEGet, HTTPVerb: "POST"},
 		"getcsv":          {Endpoint: qhttp.DenyHTTP}, // getcsv is not part of the json api, but is handled in a separate `GetBodyCSVHandler` function
 		"getzip":          {Endpoint: qhttp.DenyHTTP}, // getzip is not part of the json api, but is handled is a separate `GetHandler` function
+		"bodystream":      {Endpoint: qhttp.DenyHTTP}, // bodystream returns a reader, which can't be sent over the wire
+		"export":          {Endpoint: qhttp.DenyHTTP}, // export streams to a writer, which can't be sent over the wire
+		"import":          {Endpoint: qhttp.DenyHTTP}, // import reads from a local path or reader
 		"activity":        {Endpoint: qhttp.AEActivity, HTTPVerb: "POST"},
 		"rename":          {Endpoint: qhttp.AERename, HTTPVerb: "POST", DefaultSource: "local"},
 		"save":            {Endpoint: qhttp.AESave, HTTPVerb: "POST"},
+		"computecid":      {Endpoint: qhttp.AEComputeCID, HTTPVerb: "POST", DefaultSource: "local"},
 		"pull":            {Endpoint: qhttp.AEPull, HTTPVerb: "POST", DefaultSource: "network"},
+		"resolvepull":     {Endpoint: qhttp.AEResolvePull, HTTPVerb: "POST", DefaultSource: "network"},
 		"push":            {Endpoint: qhttp.AEPush, HTTPVerb: "POST", DefaultSource: "local"},
 		"render":          {Endpoint: qhttp.AERender, HTTPVerb: "POST"},
 		"remove":          {Endpoint: qhttp.AERemove, HTTPVerb: "POST", DefaultSource: "local"},
@@ -66,7 +77,11 @@ func (m DatasetMethods) Attributes() map[string]AttributeSet {
 		"manifest":        {Endpoint: qhttp.AEManifest, HTTPVerb: "POST", DefaultSource: "local"},
 		"manifestmissing": {Endpoint: qhttp.AEManifestMissing, HTTPVerb: "POST", DefaultSource: "local"},
 		"daginfo":         {Endpoint: qhttp.AEDAGInfo, HTTPVerb: "POST", DefaultSource: "local"},
+		"verify":          {Endpoint: qhttp.AEVerify, HTTPVerb: "POST", DefaultSource: "local"},
+		"resolveinfo":     {Endpoint: qhttp.AEResolveInfo, HTTPVerb: "POST", DefaultSource: "local"},
 		"whatchanged":     {Endpoint: qhttp.AEWhatChanged, HTTPVerb: "POST", DefaultSource: "local"},
+		"stats":           {Endpoint: qhttp.AEStats, HTTPVerb: "POST"},
+		"provenance":      {Endpoint: qhttp.AEProvenance, HTTPVerb: "POST"},
 	}
 }
 
@@ -176,6 +191,112 @@ func (m DatasetMethods) GetZip(ctx context.Context, p *GetParams) (*GetZipResult
 	return nil, dispatchReturnError(got, err)
 }
 
+// BodyParams defines parameters for reading a dataset body
+type BodyParams struct {
+	// dataset reference to read the body of; e.g. "b5/world_bank_population"
+	Ref string `json:"ref"`
+	// format to read the body as, one of "csv", "json", "ndjson", "cbor" or
+	// "xlsx". defaults to the format the body is stored in
+	Format string `json:"format"`
+	// number of entries to skip before reading
+	Offset int `json:"offset"`
+	// maximum number of entries to read. zero reads all entries after Offset
+	Limit int `json:"limit"`
+	// columns to read, by title or zero-based index; e.g. ["city", "2"].
+	// empty reads all columns
+	Columns []string `json:"columns"`
+}
+
+// Validate returns an error if BodyParams fields are in an invalid state
+func (p *BodyParams) Validate() error {
+	if p.Ref == "" {
+		return dsref.ErrEmptyRef
+	}
+	if _, err := dataset.ParseDataFormatString(p.Format); err != nil {
+		return err
+	}
+	if p.Offset < 0 || p.Limit < 0 {
+		return fmt.Errorf("invalid limit / offset settings")
+	}
+	return nil
+}
+
+// BodyStream returns a reader of a dataset body in the requested format.
+// Bodies are converted as the reader is consumed instead of being read into
+// memory, making BodyStream suitable for large bodies. Offset & Limit page
+// through body entries without holding skipped entries in memory, Columns
+// narrows each entry to a subset of columns. Callers must close the returned
+// reader
+func (m DatasetMethods) BodyStream(ctx context.Context, p *BodyParams) (io.ReadCloser, error) {
+	got, _, err := m.d.Dispatch(ctx, dispatchMethodName(m, "bodystream"), p)
+	if res, ok := got.(io.ReadCloser); ok {
+		return res, err
+	}
+	return nil, dispatchReturnError(got, err)
+}
+
+// ExportParams defines parameters for the Export method
+type ExportParams struct {
+	Ref string `json:"ref"`
+	// Format is the archive format, one of "zip" or "tar". defaults to zip
+	Format string `json:"format"`
+	// Components limits the archive to the named components, eg: "meta",
+	// "body". empty exports all components
+	Components []string `json:"components"`
+	// Output receives the archive as it's written. if nil the archive is
+	// returned in ExportResults.Bytes
+	Output io.Writer `json:"-"`
+}
+
+// ExportResults is returned by Export
+type ExportResults struct {
+	// Bytes holds the archive when no Output writer is given
+	Bytes         []byte
+	GeneratedName string
+}
+
+// Export writes the stored files of a dataset version to a single zip or tar
+// archive
+func (m DatasetMethods) Export(ctx context.Context, p *ExportParams) (*ExportResults, error) {
+	got, _, err := m.d.Dispatch(ctx, dispatchMethodName(m, "export"), p)
+	if res, ok := got.(*ExportResults); ok {
+		return res, err
+	}
+	return nil, dispatchReturnError(got, err)
+}
+
+// ImportParams defines parameters for the Import method
+type ImportParams struct {
+	// Path is a local zip, tar or CAR file to import
+	Path string `json:"path"`
+	// Reader reads the file to import, takes precedence over Path
+	Reader io.Reader `json:"-"`
+	// Ref is the name to import the dataset as, eg: "me/imported". the name
+	// must not already be in use
+	Ref string `json:"ref"`
+}
+
+// Validate returns an error if ImportParams fields are in an invalid state
+func (p *ImportParams) Validate() error {
+	if p.Path == "" && p.Reader == nil {
+		return fmt.Errorf("import: path or reader required")
+	}
+	if p.Ref == "" {
+		return fmt.Errorf("import: dataset reference required")
+	}
+	return nil
+}
+
+// Import reads a dataset package written by Export, or an IPLD CAR file
+// rooted at a dataset, into local storage as a new dataset
+func (m DatasetMethods) Import(ctx context.Context, p *ImportParams) (*dataset.Dataset, error) {
+	got, _, err := m.d.Dispatch(ctx, dispatchMethodName(m, "import"), p)
+	if res, ok := got.(*dataset.Dataset); ok {
+		return res, err
+	}
+	return nil, dispatchReturnError(got, err)
+}
+
 func scriptFileSelection(ds *dataset.Dataset, selector string) (qfs.File, bool) {
 	parts := strings.Split(selector, ".")
 	if len(parts) != 2 {
@@ -303,6 +424,19 @@ func (m DatasetMethods) Save(ctx context.Context, p *SaveParams) (*dataset.Datas
 	return nil, dispatchReturnError(got, err)
 }
 
+// ComputeCID runs the save pipeline for changes to a dataset without storing
+// anything, returning the CID the resulting version would have. Saving the
+// same changes produces a version with this CID, and changes that would not
+// alter a dataset produce the CID of its current HEAD. The dataset is
+// identified by ds.Peername & ds.Name
+func (m DatasetMethods) ComputeCID(ctx context.Context, ds *dataset.Dataset) (string, error) {
+	got, _, err := m.d.Dispatch(ctx, dispatchMethodName(m, "computecid"), ds)
+	if res, ok := got.(string); ok {
+		return res, err
+	}
+	return "", dispatchReturnError(got, err)
+}
+
 // RenameParams defines parameters for Dataset renaming
 type RenameParams struct {
 	Current string `json:"current"`
@@ -369,6 +503,34 @@ func (m DatasetMethods) Pull(ctx context.Context, p *PullParams) (*dataset.Datas
 	return nil, dispatchReturnError(got, err)
 }
 
+// ResolvePullParams encapsulates parameters to the resolvepull method
+type ResolvePullParams struct {
+	Ref string `json:"ref"`
+}
+
+// PullResolution describes where a reference passed to pull resolves
+type PullResolution struct {
+	// Ref is the resolved reference, including the HEAD path
+	Ref dsref.Ref `json:"ref"`
+	// Source is the subsystem that resolved the reference, one of
+	// ResolvedFromLocal, ResolvedFromDscache, ResolvedFromRegistry, or
+	// ResolvedFromPeer
+	Source string `json:"source"`
+	// Location is the address the reference resolved from, empty for local
+	// sources
+	Location string `json:"location,omitempty"`
+}
+
+// ResolvePull resolves a reference the way pull would without fetching any
+// data, reporting where the reference resolved
+func (m DatasetMethods) ResolvePull(ctx context.Context, p *ResolvePullParams) (*PullResolution, error) {
+	got, _, err := m.d.Dispatch(ctx, dispatchMethodName(m, "resolvepull"), p)
+	if res, ok := got.(*PullResolution); ok {
+		return res, err
+	}
+	return nil, dispatchReturnError(got, err)
+}
+
 // PushParams encapsulates parmeters for dataset publication
 type PushParams struct {
 	Ref    string `json:"ref" schema:"ref"`
@@ -455,6 +617,37 @@ func (m DatasetMethods) DAGInfo(ctx context.Context, p *DAGInfoParams) (*dag.Inf
 	return nil, dispatchReturnError(got, err)
 }
 
+// VerifyParams defines parameters for the Verify method
+type VerifyParams struct {
+	Ref string `json:"ref"`
+}
+
+// Verify checks the integrity of a local dataset version, confirming every
+// block it references is present and hashes correctly
+func (m DatasetMethods) Verify(ctx context.Context, p *VerifyParams) (*dsfs.Verification, error) {
+	got, _, err := m.d.Dispatch(ctx, dispatchMethodName(m, "verify"), p)
+	if res, ok := got.(*dsfs.Verification); ok {
+		return res, err
+	}
+	return nil, dispatchReturnError(got, err)
+}
+
+// ResolveInfoParams defines parameters for the ResolveInfo method
+type ResolveInfoParams struct {
+	Ref string `json:"ref"`
+}
+
+// ResolveInfo resolves a reference to a dataset version, returning the
+// VersionInfo the repo has on record for it, including body stats, commit
+// details & published status. ResolveInfo doesn't load the dataset itself
+func (m DatasetMethods) ResolveInfo(ctx context.Context, p *ResolveInfoParams) (*dsref.VersionInfo, error) {
+	got, _, err := m.d.Dispatch(ctx, dispatchMethodName(m, "resolveinfo"), p)
+	if res, ok := got.(*dsref.VersionInfo); ok {
+		return res, err
+	}
+	return nil, dispatchReturnError(got, err)
+}
+
 // RenderParams defines parameters for the Render method
 type RenderParams struct {
 	// Ref is a string reference to the dataset to render
@@ -516,6 +709,65 @@ func (m DatasetMethods) WhatChanged(ctx context.Context, p *WhatChangedParams) (
 	return nil, dispatchReturnError(got, err)
 }
 
+// StatsParams are parameters for the stats command
+type StatsParams struct {
+	// dataset reference to get stats for; e.g. "b5/world_bank_population"
+	Ref string `json:"ref"`
+}
+
+// Validate returns an error if StatsParams fields are in an invalid state
+func (p *StatsParams) Validate() error {
+	if p.Ref == "" {
+		return dsref.ErrEmptyRef
+	}
+	return nil
+}
+
+// Stats gets the stats component of a dataset version, calculating stats from
+// the body if the version doesn't store them
+func (m DatasetMethods) Stats(ctx context.Context, p *StatsParams) (*dataset.Stats, error) {
+	got, _, err := m.d.Dispatch(ctx, dispatchMethodName(m, "stats"), p)
+	if res, ok := got.(*dataset.Stats); ok {
+		return res, err
+	}
+	return nil, dispatchReturnError(got, err)
+}
+
+// ProvenanceParams are parameters for the provenance command
+type ProvenanceParams struct {
+	// dataset reference to get provenance for; e.g. "b5/world_bank_population"
+	Ref string `json:"ref"`
+	// Recursive follows upstream datasets that are stored locally, building
+	// the full lineage graph
+	Recursive bool `json:"recursive"`
+}
+
+// Validate returns an error if ProvenanceParams fields are in an invalid state
+func (p *ProvenanceParams) Validate() error {
+	if p.Ref == "" {
+		return dsref.ErrEmptyRef
+	}
+	return nil
+}
+
+// ProvenanceNode is a dataset version in a lineage graph, and the upstream
+// dataset versions its transform read from
+type ProvenanceNode struct {
+	Ref      dsref.Ref   `json:"ref"`
+	Upstream []dsref.Ref `json:"upstream,omitempty"`
+}
+
+// Provenance lists the upstream datasets referenced by the transform of a
+// dataset version. The requested version is always the first node. Recursive
+// requests add a node for each upstream version stored locally
+func (m DatasetMethods) Provenance(ctx context.Context, p *ProvenanceParams) ([]*ProvenanceNode, error) {
+	got, _, err := m.d.Dispatch(ctx, dispatchMethodName(m, "provenance"), p)
+	if res, ok := got.([]*ProvenanceNode); ok {
+		return res, err
+	}
+	return nil, dispatchReturnError(got, err)
+}
+
 // datasetImpl holds the method implementations for DatasetMethods
 type datasetImpl struct{}
 
@@ -584,6 +836,10 @@ func openAndLoadDataset(scope scope, p *GetParams) (*dsref.Ref, *dataset.Dataset
 
 	ref := dsref.ConvertDatasetToVersionInfo(ds).SimpleRef()
 
+	if err = verifyTransformSignature(scope, ds); err != nil {
+		return nil, nil, err
+	}
+
 	if err = base.OpenDataset(scope.Context(), scope.Filesystem(), ds); err != nil {
 		log.Debugf("base.OpenDataset failed, error: %s", err)
 		return nil, nil, err
@@ -591,6 +847,39 @@ func openAndLoadDataset(scope scope, p *GetParams) (*dsref.Ref, *dataset.Dataset
 	return &ref, ds, nil
 }
 
+// verifyTransformSignature checks a dataset's transform script against the
+// signature saved with it, using the public key of the commit author. A
+// script with an invalid signature fails verification. Unsigned scripts &
+// scripts by authors this instance doesn't know are unverified, which only
+// fails when the repo config requires verified transforms
+func verifyTransformSignature(scope scope, ds *dataset.Dataset) error {
+	if ds.Transform == nil {
+		return nil
+	}
+	authorID := ds.ProfileID
+	if ds.Commit != nil && ds.Commit.Author != nil && ds.Commit.Author.ID != "" {
+		authorID = ds.Commit.Author.ID
+	}
+	var author *profile.Profile
+	if id, err := profile.IDB58Decode(authorID); err == nil {
+		author, _ = scope.Profiles().GetProfile(scope.Context(), id)
+	}
+	if author == nil || author.PubKey == nil {
+		if scope.VerifyTransformSignatures() {
+			return fmt.Errorf("cannot verify transform signature: unknown author %q", authorID)
+		}
+		log.Debugw("transform signature unverified, unknown author", "path", ds.Path, "authorID", authorID)
+		return nil
+	}
+
+	err := dsfs.VerifyTransformSignature(scope.Context(), scope.Filesystem(), ds.Path, ds.Transform, author.PubKey)
+	if errors.Is(err, dsfs.ErrTransformNotSigned) && !scope.VerifyTransformSignatures() {
+		log.Debugw("transform signature unverified, script is not signed", "path", ds.Path)
+		return nil
+	}
+	return err
+}
+
 func inlineAllScriptFiles(ctx context.Context, ds *dataset.Dataset, resolver qfs.PathResolver) error {
 	if ds.Readme != nil {
 		if err := ds.Readme.InlineScriptFile(ctx, resolver); err != nil {
@@ -667,6 +956,143 @@ func (datasetImpl) GetZip(scope scope, p *GetParams) (*GetZipResults, error) {
 	return &GetZipResults{Bytes: outBuf.Bytes(), GeneratedName: filename}, nil
 }
 
+// BodyStream returns a reader of a dataset body
+func (datasetImpl) BodyStream(scope scope, p *BodyParams) (io.ReadCloser, error) {
+	_, ds, err := openAndLoadDataset(scope, &GetParams{Ref: p.Ref})
+	if err != nil {
+		return nil, err
+	}
+	if ds.Structure == nil {
+		return nil, fmt.Errorf("dataset has no body")
+	}
+
+	format, err := dataset.ParseDataFormatString(p.Format)
+	if err != nil {
+		return nil, err
+	}
+	return base.BodyStream(ds, format, nil, p.Limit, p.Offset, p.Columns)
+}
+
+// Export writes the stored files of a dataset version to an archive
+func (datasetImpl) Export(scope scope, p *ExportParams) (*ExportResults, error) {
+	if p.Format == "" {
+		p.Format = archive.PackageFormatZip
+	}
+	ds, err := scope.Loader().LoadDataset(scope.Context(), p.Ref)
+	if err != nil {
+		return nil, err
+	}
+
+	res := &ExportResults{}
+	w := p.Output
+	var buf *bytes.Buffer
+	if w == nil {
+		buf = &bytes.Buffer{}
+		w = buf
+	}
+	if err := archive.WritePackage(scope.Context(), scope.Filesystem(), ds, p.Format, p.Components, w); err != nil {
+		return nil, err
+	}
+	if buf != nil {
+		res.Bytes = buf.Bytes()
+	}
+	if res.GeneratedName, err = archive.GenerateFilename(ds, p.Format); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// Import reads a dataset package or CAR file into local storage
+func (datasetImpl) Import(scope scope, p *ImportParams) (*dataset.Dataset, error) {
+	ctx := scope.Context()
+	r := p.Reader
+	if r == nil {
+		f, err := os.Open(p.Path)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		r = f
+	}
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var path string
+	if archive.IsPackageArchive(data) {
+		files, err := archive.ReadPackage(data)
+		if err != nil {
+			return nil, err
+		}
+		if path, err = archive.StorePackage(ctx, scope.Filesystem().DefaultWriteFS(), files); err != nil {
+			return nil, err
+		}
+	} else if path, err = importCAR(ctx, scope.Node(), data); err != nil {
+		return nil, err
+	}
+
+	ds, err := dsfs.LoadDataset(ctx, scope.Filesystem(), path)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", archive.ErrMalformedPackage, err)
+	}
+
+	resolver, err := scope.LocalResolver()
+	if err != nil {
+		return nil, err
+	}
+	author := scope.ActiveProfile()
+	ref, _, err := base.PrepareSaveRef(ctx, author, scope.Logbook(), resolver, p.Ref, "", true)
+	if err != nil {
+		return nil, err
+	}
+	success := false
+	defer func() {
+		// remove the log created for the new name if the import fails
+		if !success {
+			if err := scope.Logbook().RemoveLog(ctx, ref); err != nil {
+				log.Errorf("couldn't cleanup unused reference: %q", err)
+			}
+		}
+	}()
+
+	ds.ID = ref.InitID
+	ds.Name = ref.Name
+	ds.Peername = author.Peername
+	ds.ProfileID = author.ID.Encode()
+	ds.Path = path
+
+	vi := dsref.ConvertDatasetToVersionInfo(ds)
+	if err := repo.PutVersionInfoShim(ctx, scope.Repo(), &vi); err != nil {
+		return nil, err
+	}
+	if err := scope.Logbook().WriteVersionSave(ctx, author, ds, nil); err != nil {
+		return nil, err
+	}
+	success = true
+	return ds, nil
+}
+
+// importCAR adds the blocks of a CAR file to the IPFS block store, returning
+// the path of the CAR's root. Blocks are verified before any are added
+func importCAR(ctx context.Context, node *p2p.QriNode, data []byte) (string, error) {
+	root, err := archive.VerifyCAR(data)
+	if err != nil {
+		return "", err
+	}
+	if node == nil {
+		return "", fmt.Errorf("importing CAR files requires an IPFS filesystem")
+	}
+	capi, err := node.IPFSCoreAPI()
+	if err != nil {
+		return "", fmt.Errorf("importing CAR files requires an IPFS filesystem: %w", err)
+	}
+	if _, err := dsync.AddAllFromCARReader(ctx, capi.Block(), bytes.NewReader(data), nil); err != nil {
+		return "", fmt.Errorf("%w: %s", archive.ErrMalformedPackage, err)
+	}
+	return fmt.Sprintf("/ipfs/%s", root), nil
+}
+
 // maximum size of the body that is allowed to be returned by get. A variable
 // is used instead of a constant so that tests can override it.
 // TODO(dustmop): Move this to configuration so that users can override it or
@@ -835,17 +1261,21 @@ func (datasetImpl) Save(scope scope, p *SaveParams) (*dataset.Dataset, error) {
 		return nil, err
 	}
 
+	removeUnusedLog := func(ref dsref.Ref) {
+		log.Debugf("removing unused log for new dataset %s", ref)
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second*2)
+		if err := scope.Logbook().RemoveLog(ctx, ref); err != nil {
+			log.Errorf("couldn't cleanup unused reference: %q", err)
+		}
+		go scope.sendEvent(event.ETDatasetDeleteAll, ref.InitID, ref.InitID)
+		cancel()
+	}
+
 	success := false
 	defer func() {
 		// if creating a new dataset fails, we need to remove the dataset
 		if isNew && !success {
-			log.Debugf("removing unused log for new dataset %s", ref)
-			ctx, cancel := context.WithTimeout(context.Background(), time.Second*2)
-			if err := scope.Logbook().RemoveLog(ctx, ref); err != nil {
-				log.Errorf("couldn't cleanup unused reference: %q", err)
-			}
-			go scope.sendEvent(event.ETDatasetDeleteAll, ref.InitID, ref.InitID)
-			cancel()
+			removeUnusedLog(ref)
 		}
 	}()
 
@@ -916,7 +1346,11 @@ func (datasetImpl) Save(scope scope, p *SaveParams) (*dataset.Dataset, error) {
 
 		// apply the transform
 		shouldWait := true
-		transformer := transform.NewTransformer(scope.AppContext(), scope.Filesystem(), scope.Loader(), scope.Bus(), sizeInfo)
+		transformer := transform.NewTransformer(scope.AppContext(), scope.Filesystem(), scope.TransformLoader(), scope.Bus(), sizeInfo)
+		transformer.AllowHTTPDomains(scope.AllowedHTTPDomains())
+		transformer.SetBodyDiffThreshold(scope.BodyDiffThreshold())
+		transformer.SetCommitMessageFunc(scope.CommitMessageFunc())
+		transformer.SetVerifyBodyChecksum(scope.VerifyBodyChecksum())
 		if err := transformer.Commit(scope.Context(), ref.InitID, ds, runID, shouldWait, secrets); err != nil {
 			log.Errorw("transform run error", "err", err.Error())
 			runState.Message = err.Error()
@@ -939,6 +1373,23 @@ func (datasetImpl) Save(scope scope, p *SaveParams) (*dataset.Dataset, error) {
 			}
 		}
 
+		// the script committed to a dataset other than the one it's bound to,
+		// save the result there instead
+		if out := transformer.OutputRef(); out != "" {
+			outRef, outIsNew, err := base.PrepareSaveRef(scope.Context(), author, scope.Logbook(), resolver, out, "", false)
+			if err != nil {
+				return nil, fmt.Errorf("transform output %q: %w", out, err)
+			}
+			if outRef.InitID != ref.InitID {
+				if isNew {
+					removeUnusedLog(ref)
+				}
+				ref, isNew = outRef, outIsNew
+				ds.Name = ref.Name
+				ds.Peername = ref.Username
+			}
+		}
+
 		ds.Commit.RunID = runID
 	}
 
@@ -956,6 +1407,10 @@ func (datasetImpl) Save(scope scope, p *SaveParams) (*dataset.Dataset, error) {
 		ShouldRender:        p.ShouldRender,
 		NewName:             p.NewName,
 		Drop:                p.Drop,
+		CommitMessage:       scope.CommitMessageFunc(),
+		PrettyJSON:          scope.PrettyJSON(),
+		BodyDiffThreshold:   scope.BodyDiffThreshold(),
+		SignTransform:       scope.SignTransforms(),
 	}
 	savedDs, err := base.SaveDataset(scope.Context(), scope.Repo(), writeDest, author, ref.InitID, ref.Path, ds, runState, switches)
 	if err != nil {
@@ -980,6 +1435,82 @@ func (datasetImpl) Save(scope scope, p *SaveParams) (*dataset.Dataset, error) {
 	return res, nil
 }
 
+// ResolveInfo returns the VersionInfo for a dataset reference
+func (datasetImpl) ResolveInfo(scope scope, p *ResolveInfoParams) (*dsref.VersionInfo, error) {
+	ctx := scope.Context()
+	ref, _, err := scope.ParseAndResolveRef(ctx, p.Ref)
+	if err != nil {
+		return nil, err
+	}
+
+	// the collection & dscache hold stats for the head version
+	if s := scope.CollectionSet(); s != nil {
+		if pid, err := profile.IDB58Decode(ref.ProfileID); err == nil {
+			if vi, err := s.Get(ctx, pid, ref.InitID); err == nil && vi.Path == ref.Path {
+				return vi, nil
+			}
+		}
+	}
+	if c := scope.Dscache(); !c.IsEmpty() {
+		if vi, err := c.ResolveRefFull(ctx, &ref); err == nil && vi.Path == ref.Path && !vi.CommitTime.IsZero() {
+			return vi, nil
+		}
+	}
+
+	// fall back to logbook, which records commit details for every version
+	vi := ref.VersionInfo()
+	history, err := scope.Logbook().VersionHistory(ctx, ref)
+	if err != nil {
+		log.Debugw("ResolveInfo reading history", "ref", ref, "err", err)
+		return &vi, nil
+	}
+	for _, item := range history {
+		if item.Path == ref.Path {
+			item.InitID = ref.InitID
+			item.ProfileID = ref.ProfileID
+			return &item, nil
+		}
+	}
+	return &vi, nil
+}
+
+// ComputeCID returns the CID of a dataset version without saving it
+func (datasetImpl) ComputeCID(scope scope, ds *dataset.Dataset) (string, error) {
+	if ds == nil || ds.Name == "" {
+		return "", fmt.Errorf("dataset name is required to compute a CID")
+	}
+	author := scope.ActiveProfile()
+	// resolve "me" without modifying the caller's dataset
+	peername := ds.Peername
+	if peername == "" || peername == "me" {
+		peername = author.Peername
+	}
+
+	resolver, err := scope.LocalResolver()
+	if err != nil {
+		return "", err
+	}
+	// resolve without base.PrepareSaveRef, which writes to logbook when
+	// initializing new datasets
+	ref := dsref.Ref{Username: peername, Name: ds.Name}
+	if _, err := resolver.ResolveRef(scope.Context(), &ref); err != nil && !errors.Is(err, dsref.ErrRefNotFound) {
+		return "", err
+	}
+
+	if err := base.OpenDataset(scope.Context(), scope.Filesystem(), ds); err != nil {
+		return "", err
+	}
+
+	switches := base.SaveSwitches{
+		ConvertFormatToPrev: true,
+		CommitMessage:       scope.CommitMessageFunc(),
+		PrettyJSON:          scope.PrettyJSON(),
+		BodyDiffThreshold:   scope.BodyDiffThreshold(),
+		SignTransform:       scope.SignTransforms(),
+	}
+	return base.ComputeDatasetCID(scope.Context(), scope.Repo(), scope.Filesystem().DefaultWriteFS(), author, ref.Path, ds, switches)
+}
+
 // Rename changes a user's given name for a dataset
 func (datasetImpl) Rename(scope scope, p *RenameParams) (*dsref.VersionInfo, error) {
 	if p.Current == "" {
@@ -1121,6 +1652,25 @@ func (datasetImpl) Pull(scope scope, p *PullParams) (*dataset.Dataset, error) {
 	return res, nil
 }
 
+// ResolvePull resolves a reference the way pull would without fetching any
+// data, reporting where the reference resolved
+func (datasetImpl) ResolvePull(scope scope, p *ResolvePullParams) (*PullResolution, error) {
+	ref, err := dsref.Parse(p.Ref)
+	if err != nil {
+		return nil, fmt.Errorf("%q is not a valid dataset reference: %w", p.Ref, err)
+	}
+	source, location, err := scope.ResolveSource(scope.Context(), &ref)
+	if err != nil {
+		log.Debugf("resolving reference: %s", err)
+		return nil, err
+	}
+	return &PullResolution{
+		Ref:      ref,
+		Source:   source,
+		Location: location,
+	}, nil
+}
+
 // Push posts a dataset version to a remote
 func (datasetImpl) Push(scope scope, p *PushParams) (*dsref.Ref, error) {
 	if scope.SourceName() != "local" {
@@ -1320,6 +1870,22 @@ func (datasetImpl) DAGInfo(scope scope, p *DAGInfoParams) (*dag.Info, error) {
 	return res, nil
 }
 
+// Verify checks the integrity of a local dataset version
+func (datasetImpl) Verify(scope scope, p *VerifyParams) (*dsfs.Verification, error) {
+	if scope.SourceName() != "local" {
+		return nil, fmt.Errorf("can only verify datasets in local storage")
+	}
+
+	ref, _, err := scope.ParseAndResolveRef(scope.Context(), p.Ref)
+	if err != nil {
+		return nil, err
+	}
+	if ref.Path == "" {
+		return nil, qrierr.New(dsref.ErrNoHistory, fmt.Sprintf("can't verify dataset %q, it has no saved versions", ref.Human()))
+	}
+	return dsfs.VerifyDataset(scope.Context(), scope.Filesystem(), ref.Path)
+}
+
 // Render renders a viz or readme component as html
 func (datasetImpl) Render(scope scope, p *RenderParams) (res []byte, err error) {
 	ds := p.Dataset
@@ -1358,6 +1924,86 @@ func (datasetImpl) Render(scope scope, p *RenderParams) (res []byte, err error)
 	return res, nil
 }
 
+// Stats gets the stats component of a dataset version
+func (datasetImpl) Stats(scope scope, p *StatsParams) (*dataset.Stats, error) {
+	_, ds, err := openAndLoadDataset(scope, &GetParams{Ref: p.Ref})
+	if err != nil {
+		return nil, err
+	}
+	return scope.Stats().Stats(scope.Context(), ds)
+}
+
+// Provenance lists the upstream datasets of a dataset version
+func (datasetImpl) Provenance(scope scope, p *ProvenanceParams) ([]*ProvenanceNode, error) {
+	ref, ds, err := openAndLoadDataset(scope, &GetParams{Ref: p.Ref})
+	if err != nil {
+		return nil, err
+	}
+	ref.Path = ds.Path
+
+	root := &ProvenanceNode{Ref: *ref, Upstream: upstreamRefs(ds)}
+	nodes := []*ProvenanceNode{root}
+	if !p.Recursive {
+		return nodes, nil
+	}
+
+	visited := map[string]bool{ref.Path: true}
+	queue := append([]dsref.Ref{}, root.Upstream...)
+	for len(queue) > 0 {
+		up := queue[0]
+		queue = queue[1:]
+		if up.Path == "" || visited[up.Path] {
+			continue
+		}
+		visited[up.Path] = true
+
+		upDs, err := dsfs.LoadDataset(scope.Context(), scope.Filesystem(), up.Path)
+		if err != nil {
+			log.Debugw("provenance: upstream dataset isn't stored locally", "ref", up, "err", err)
+			continue
+		}
+		node := &ProvenanceNode{Ref: up, Upstream: upstreamRefs(upDs)}
+		nodes = append(nodes, node)
+		queue = append(queue, node.Upstream...)
+	}
+	return nodes, nil
+}
+
+// upstreamRefs returns the datasets a transform read with load_dataset, which
+// are recorded as transform resources when the transform is applied. The
+// pinned latest version & any other reference to the dataset itself aren't
+// upstream datasets and are skipped. Results are sorted by path
+func upstreamRefs(ds *dataset.Dataset) []dsref.Ref {
+	if ds.Transform == nil {
+		return nil
+	}
+	refs := make([]dsref.Ref, 0, len(ds.Transform.Resources))
+	for key, r := range ds.Transform.Resources {
+		if r == nil || key == stards.LatestResourceKey {
+			continue
+		}
+		ref, err := dsref.Parse(r.Path)
+		if err != nil {
+			log.Debugw("provenance: parsing transform resource", "key", key, "path", r.Path, "err", err)
+			continue
+		}
+		if isSelfRef(ds, ref) {
+			continue
+		}
+		refs = append(refs, ref)
+	}
+	sort.Slice(refs, func(i, j int) bool { return refs[i].Path < refs[j].Path })
+	return refs
+}
+
+// isSelfRef returns true if ref refers to the dataset ds is a version of
+func isSelfRef(ds *dataset.Dataset, ref dsref.Ref) bool {
+	if ref.InitID != "" && ref.InitID == ds.ID {
+		return true
+	}
+	return ref.Username != "" && ref.Username == ds.Peername && ref.Name == ds.Name
+}
+
 // WhatChanged gets what components changed for the given version
 func (datasetImpl) WhatChanged(scope scope, p *WhatChangedParams) ([]base.StatusItem, error) {
 	ref, err := dsref.Parse(p.Ref)
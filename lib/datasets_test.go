@@ -1,30 +1,42 @@
 package lib
 
 import (
+	"archive/tar"
 	"archive/zip"
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
 	"os"
 	"path"
+	"path/filepath"
 	"strconv"
 	"strings"
 	"sync"
 	"testing"
+	"time"
 
 	"github.com/ghodss/yaml"
 	"github.com/google/go-cmp/cmp"
 	cmpopts "github.com/google/go-cmp/cmp/cmpopts"
+	cid "github.com/ipfs/go-cid"
+	ipfspath "github.com/ipfs/interface-go-ipfs-core/path"
+	"github.com/qri-io/dag"
+	"github.com/qri-io/dag/dsync"
 	"github.com/qri-io/dataset"
 	"github.com/qri-io/dataset/dsio"
 	"github.com/qri-io/dataset/dstest"
 	"github.com/qri-io/dataset/preview"
+	"github.com/qri-io/ioes"
 	"github.com/qri-io/qfs"
+	testkeys "github.com/qri-io/qri/auth/key/test"
 	"github.com/qri-io/qri/base"
+	"github.com/qri-io/qri/base/archive"
 	"github.com/qri-io/qri/base/dsfs"
 	"github.com/qri-io/qri/base/params"
 	testcfg "github.com/qri-io/qri/config/test"
@@ -34,6 +46,7 @@ import (
 	p2ptest "github.com/qri-io/qri/p2p/test"
 	reporef "github.com/qri-io/qri/repo/ref"
 	testrepo "github.com/qri-io/qri/repo/test"
+	stards "github.com/qri-io/qri/transform/startf/ds"
 )
 
 func TestDatasetRequestsSave(t *testing.T) {
@@ -197,6 +210,66 @@ func TestDatasetRequestsSaveZip(t *testing.T) {
 	}
 }
 
+func TestDatasetRequestsSaveComponentFiles(t *testing.T) {
+	ctx, done := context.WithCancel(context.Background())
+	defer done()
+
+	node := newTestQriNode(t)
+	inst := NewInstanceFromConfigAndNode(ctx, testcfg.DefaultConfigForTesting(), node)
+
+	dir, err := ioutil.TempDir("", "save_component_files")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	files := map[string]string{
+		"body.csv":       "city,pop\ntoronto,40000000\nnew york,8500000\n",
+		"meta.json":      `{"title":"cities from component files"}`,
+		"transform.star": "def transform(ds, ctx):\n  pass\n",
+		"body_two.json":  `[["chicago",300000]]`,
+	}
+	paths := map[string]string{}
+	for name, data := range files {
+		paths[name] = filepath.Join(dir, name)
+		if err := ioutil.WriteFile(paths[name], []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	res, err := inst.Dataset().Save(ctx, &SaveParams{
+		Ref:       "me/component_files",
+		FilePaths: []string{paths["body.csv"], paths["meta.json"], paths["transform.star"]},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if res.Meta == nil || res.Meta.Title != "cities from component files" {
+		t.Errorf("expected meta component to be saved, got: %#v", res.Meta)
+	}
+	if res.Transform == nil || res.Transform.ScriptPath == "" {
+		t.Errorf("expected transform component to be saved, got: %#v", res.Transform)
+	}
+	if res.Structure == nil || res.Structure.Format != "csv" {
+		t.Errorf("expected csv structure to be detected, got: %#v", res.Structure)
+	}
+	if res.BodyPath == "" {
+		t.Errorf("expected body to be saved")
+	}
+	if res.Structure != nil && res.Structure.Entries != 2 {
+		t.Errorf("expected 2 body entries, got: %d", res.Structure.Entries)
+	}
+
+	_, err = inst.Dataset().Save(ctx, &SaveParams{
+		Ref:       "me/component_files",
+		FilePaths: []string{paths["body.csv"], paths["meta.json"], paths["body_two.json"]},
+	})
+	if !errors.Is(err, ErrConflictingBody) {
+		t.Errorf("expected conflicting body files to return ErrConflictingBody, got: %v", err)
+	}
+}
+
 func TestDatasetRequestsSaveApply(t *testing.T) {
 	run := newTestRunner(t)
 	defer run.Delete()
@@ -759,6 +832,13 @@ func TestDatasetRequestsRename(t *testing.T) {
 	if expect.Name != after.Name() {
 		t.Errorf("rename log mismatch. expected: %s, got: %s", expect.Name, after.Name())
 	}
+
+	if _, _, err := inst.ParseAndResolveRef(ctx, "peer/new_movies", "local"); err != nil {
+		t.Errorf("expected new name to resolve, got: %s", err)
+	}
+	if _, _, err := inst.ParseAndResolveRef(ctx, "peer/movies", "local"); !errors.Is(err, dsref.ErrRefNotFound) {
+		t.Errorf("expected old name to fail resolution with ErrRefNotFound, got: %v", err)
+	}
 }
 
 func TestDatasetRequestsRemove(t *testing.T) {
@@ -1166,3 +1246,856 @@ func mustBeArray(i interface{}, err error) []interface{} {
 	}
 	return i.([]interface{})
 }
+
+func TestDatasetStats(t *testing.T) {
+	run := newTestRunner(t)
+	defer run.Delete()
+
+	run.MustSaveFromBody(t, "cities_stats", "testdata/cities_2/body.csv")
+
+	sa, err := run.Instance.Dataset().Stats(run.Ctx, &StatsParams{Ref: "me/cities_stats"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// round trip through JSON to compare stats regardless of how they're typed
+	data, err := json.Marshal(sa.Stats)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cols := []map[string]interface{}{}
+	if err := json.Unmarshal(data, &cols); err != nil {
+		t.Fatal(err)
+	}
+	if len(cols) != 4 {
+		t.Fatalf("expected stats for 4 columns, got %d", len(cols))
+	}
+	pop := cols[1]
+	if pop["min"] != float64(35000) {
+		t.Errorf("expected pop min to be 35000, got %v", pop["min"])
+	}
+	if pop["max"] != float64(50000000) {
+		t.Errorf("expected pop max to be 50000000, got %v", pop["max"])
+	}
+
+	if _, err := run.Instance.Dataset().Stats(run.Ctx, &StatsParams{}); err == nil {
+		t.Error("expected stats with an empty ref to error")
+	}
+}
+
+func TestDatasetRequestsVerify(t *testing.T) {
+	tr := newTestRunner(t)
+	defer tr.Delete()
+
+	ds := tr.MustSaveFromBody(t, "verify_me", "testdata/cities_2/body.csv")
+	ref := fmt.Sprintf("%s/verify_me@%s", ds.Peername, ds.Path)
+
+	res, err := tr.Instance.Dataset().Verify(tr.Ctx, &VerifyParams{Ref: ref})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !res.Intact() {
+		t.Fatalf("expected freshly saved dataset to be intact, got: %#v", res)
+	}
+	if res.Checked < 2 {
+		t.Errorf("expected multiple blocks to be checked, got: %d", res.Checked)
+	}
+
+	memfs, ok := tr.Instance.Repo().Filesystem().Filesystem(qfs.MemFilestoreType).(*qfs.MemFS)
+	if !ok {
+		t.Fatal("expected test runner to use an in-memory filesystem")
+	}
+
+	// overwrite the body block with different data
+	bodyKey := dsfs.GetHashBase(ds.BodyPath)
+	if err := memfs.PutFileAtKey(tr.Ctx, bodyKey, qfs.NewMemfileBytes("body.csv", []byte("corrupt,data\n"))); err != nil {
+		t.Fatal(err)
+	}
+	res, err = tr.Instance.Dataset().Verify(tr.Ctx, &VerifyParams{Ref: ref})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Intact() {
+		t.Fatal("expected verify to detect a corrupt body block")
+	}
+	expect := []dsfs.BlockRef{{Component: "bd", Path: ds.BodyPath, CID: bodyKey}}
+	if diff := cmp.Diff(expect, res.Corrupt); diff != "" {
+		t.Errorf("corrupt blocks mismatch (-want +got):\n%s", diff)
+	}
+
+	// remove the structure block entirely
+	stKey := dsfs.GetHashBase(ds.Structure.Path)
+	if err := memfs.Delete(tr.Ctx, stKey); err != nil {
+		t.Fatal(err)
+	}
+	res, err = tr.Instance.Dataset().Verify(tr.Ctx, &VerifyParams{Ref: ref})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expect = []dsfs.BlockRef{{Component: "st", Path: ds.Structure.Path, CID: stKey}}
+	if diff := cmp.Diff(expect, res.Missing); diff != "" {
+		t.Errorf("missing blocks mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestDatasetRequestsResolveInfo(t *testing.T) {
+	tr := newTestRunner(t)
+	defer tr.Delete()
+
+	ds := tr.MustSaveFromBody(t, "resolve_info", "testdata/cities_2/body.csv")
+	ref := fmt.Sprintf("%s/resolve_info", ds.Peername)
+
+	vi, err := tr.Instance.Dataset().ResolveInfo(tr.Ctx, &ResolveInfoParams{Ref: ref})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if vi.Path != ds.Path {
+		t.Errorf("path mismatch. want: %q got: %q", ds.Path, vi.Path)
+	}
+	if vi.InitID == "" {
+		t.Error("expected info to include an initID")
+	}
+	if vi.BodySize != ds.Structure.Length {
+		t.Errorf("body size mismatch. want: %d got: %d", ds.Structure.Length, vi.BodySize)
+	}
+	if vi.BodyRows != ds.Structure.Entries {
+		t.Errorf("body rows mismatch. want: %d got: %d", ds.Structure.Entries, vi.BodyRows)
+	}
+	if vi.CommitTitle != ds.Commit.Title {
+		t.Errorf("commit title mismatch. want: %q got: %q", ds.Commit.Title, vi.CommitTitle)
+	}
+
+	// earlier versions fall back to commit details recorded in logbook
+	if _, err := tr.SaveWithParams(&SaveParams{Ref: ref, BodyPath: "testdata/cities_2/body.csv", Force: true}); err != nil {
+		t.Fatal(err)
+	}
+	vi, err = tr.Instance.Dataset().ResolveInfo(tr.Ctx, &ResolveInfoParams{Ref: fmt.Sprintf("%s@%s", ref, ds.Path)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if vi.Path != ds.Path || vi.CommitTitle != ds.Commit.Title {
+		t.Errorf("expected earlier version info, got path: %q title: %q", vi.Path, vi.CommitTitle)
+	}
+
+	if _, err := tr.Instance.Dataset().ResolveInfo(tr.Ctx, &ResolveInfoParams{Ref: "me/not_a_dataset"}); err == nil {
+		t.Error("expected resolving an unknown dataset to error")
+	}
+}
+
+func TestDatasetRequestsComputeCID(t *testing.T) {
+	tr := newTestRunner(t)
+	defer tr.Delete()
+
+	ts := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+	newDs := func(body string) *dataset.Dataset {
+		return &dataset.Dataset{
+			Peername:  "me",
+			Name:      "compute_cid",
+			Commit:    &dataset.Commit{Title: "initial commit", Timestamp: ts},
+			Structure: &dataset.Structure{Format: "json", Schema: dataset.BaseSchemaArray},
+			BodyBytes: []byte(body),
+		}
+	}
+
+	changes := newDs(`[1,2,3]`)
+	cid, err := tr.Instance.Dataset().ComputeCID(tr.Ctx, changes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cid == "" {
+		t.Fatal("expected a CID")
+	}
+	if changes.Peername != "me" {
+		t.Errorf("expected computing a CID not to modify the caller's peername, got %q", changes.Peername)
+	}
+
+	// computing must not create the dataset
+	if _, err := tr.Instance.WithSource("local").Dataset().Get(tr.Ctx, &GetParams{Ref: "me/compute_cid"}); err == nil {
+		t.Error("expected computing a CID not to save the dataset")
+	}
+
+	saved, err := tr.Instance.Dataset().Save(tr.Ctx, &SaveParams{Ref: "me/compute_cid", Dataset: newDs(`[1,2,3]`)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := filepath.Base(saved.Path); got != cid {
+		t.Errorf("saved path %q doesn't match precomputed CID %q", saved.Path, cid)
+	}
+
+	// an unchanged dataset computes the CID of HEAD
+	cid, err = tr.Instance.Dataset().ComputeCID(tr.Ctx, newDs(`[1,2,3]`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := filepath.Base(saved.Path); got != cid {
+		t.Errorf("expected unchanged dataset to compute HEAD CID %q, got %q", got, cid)
+	}
+
+	next := newDs(`[1,2,3,4]`)
+	next.Commit.Title = "add a number"
+	cid, err = tr.Instance.Dataset().ComputeCID(tr.Ctx, next)
+	if err != nil {
+		t.Fatal(err)
+	}
+	next = newDs(`[1,2,3,4]`)
+	next.Commit.Title = "add a number"
+	saved, err = tr.Instance.Dataset().Save(tr.Ctx, &SaveParams{Ref: "me/compute_cid", Dataset: next})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := filepath.Base(saved.Path); got != cid {
+		t.Errorf("saved path %q doesn't match precomputed CID %q", saved.Path, cid)
+	}
+}
+
+func TestDatasetRequestsSaveApplyOutputRef(t *testing.T) {
+	tr := newTestRunner(t)
+	defer tr.Delete()
+
+	input := tr.MustSaveFromBody(t, "fan_in", "testdata/cities_2/body.csv")
+
+	script := tr.MustWriteTmpFile(t, "fan_out.star", `ds = dataset.latest()
+---
+ds.body = ds.body.append([["tokyo", 9200000, 48.5, False]])
+dataset.commit(ds, ref="me/fan_out")
+`)
+	res, err := tr.Instance.Dataset().Save(tr.Ctx, &SaveParams{
+		Ref:       "me/fan_in",
+		FilePaths: []string{script},
+		Apply:     true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Name != "fan_out" {
+		t.Errorf("expected save to write to output dataset %q, got %q", "fan_out", res.Name)
+	}
+
+	out := tr.MustGet(t, "me/fan_out")
+	if out.Structure.Entries != 6 {
+		t.Errorf("expected output dataset to have 6 entries, got %d", out.Structure.Entries)
+	}
+	in := tr.MustGet(t, "me/fan_in")
+	if in.Path != input.Path {
+		t.Errorf("expected input dataset to be unchanged. path was %q, now %q", input.Path, in.Path)
+	}
+
+	// writing to another user's dataset is an error
+	script = tr.MustWriteTmpFile(t, "not_mine.star", `ds = dataset.latest()
+dataset.commit(ds, ref="someone_else/fan_out")
+`)
+	_, err = tr.Instance.Dataset().Save(tr.Ctx, &SaveParams{
+		Ref:       "me/fan_in",
+		FilePaths: []string{script},
+		Apply:     true,
+	})
+	if err == nil || !strings.Contains(err.Error(), "cannot save using a different username") {
+		t.Errorf("expected committing to another user's dataset to error, got: %v", err)
+	}
+}
+
+func TestDatasetRequestsSaveApplyCommitMessageFunc(t *testing.T) {
+	tr := newTestRunner(t)
+	defer tr.Delete()
+
+	tr.MustSaveFromBody(t, "ticketed", "testdata/cities_2/body.csv")
+	tr.Instance.commitMessage = func(ctx context.Context, ds, prev *dataset.Dataset, bodyAct dsfs.BodyAction, generated dsfs.CommitDescription) (dsfs.CommitDescription, error) {
+		return dsfs.CommitDescription{Title: "[TICKET-1] " + generated.Title}, nil
+	}
+
+	script := tr.MustWriteTmpFile(t, "ticketed.star", `ds = dataset.latest()
+ds.body = ds.body.append([["tokyo", 9200000, 48.5, False]])
+dataset.commit(ds)
+`)
+	if _, err := tr.Instance.Dataset().Save(tr.Ctx, &SaveParams{
+		Ref:       "me/ticketed",
+		FilePaths: []string{script},
+		Apply:     true,
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	got := tr.MustGet(t, "me/ticketed")
+	if !strings.HasPrefix(got.Commit.Title, "[TICKET-1] ") {
+		t.Errorf("expected transform commit title to be generated by the hook, got: %q", got.Commit.Title)
+	}
+}
+
+func TestDatasetRequestsSaveApplyVerifyBodyChecksum(t *testing.T) {
+	script := `ds = dataset.latest()
+ds.set_meta("title", "new title")
+dataset.commit(ds)
+`
+	cases := []struct {
+		verify bool
+		title  string
+	}{
+		{false, "updated meta and transform"},
+		{true, "updated meta, transform, and body"},
+	}
+
+	for _, c := range cases {
+		t.Run(fmt.Sprintf("verify_%t", c.verify), func(t *testing.T) {
+			tr := newTestRunner(t)
+			defer tr.Delete()
+			tr.Instance.cfg.Automation.VerifyBodyChecksum = c.verify
+
+			tr.MustSaveFromBody(t, "verified", "testdata/cities_2/body.csv")
+			// the script only sets meta, the new body comes from the user
+			if _, err := tr.Instance.Dataset().Save(tr.Ctx, &SaveParams{
+				Ref:       "me/verified",
+				BodyPath:  "testdata/cities_2/body_more.csv",
+				FilePaths: []string{tr.MustWriteTmpFile(t, "meta_only.star", script)},
+				Apply:     true,
+			}); err != nil {
+				t.Fatal(err)
+			}
+
+			got := tr.MustGet(t, "me/verified")
+			if got.Commit.Title != c.title {
+				t.Errorf("commit title mismatch. want: %q, got: %q", c.title, got.Commit.Title)
+			}
+		})
+	}
+}
+
+func TestDatasetRequestsBodyStream(t *testing.T) {
+	tr := newTestRunner(t)
+	defer tr.Delete()
+
+	ds := tr.MustSaveFromBody(t, "stream_me", "testdata/cities_2/body.csv")
+	f, err := tr.Instance.Repo().Filesystem().Get(tr.Ctx, ds.BodyPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stored, err := ioutil.ReadAll(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ref := fmt.Sprintf("%s/%s", ds.Peername, ds.Name)
+	r, err := tr.Instance.Dataset().BodyStream(tr.Ctx, &BodyParams{Ref: ref, Format: "csv"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := ioutil.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.Close()
+	if diff := cmp.Diff(string(stored), string(got)); diff != "" {
+		t.Errorf("streamed csv body mismatch (-want +got):\n%s", diff)
+	}
+
+	// converting formats streams entries
+	r, err = tr.Instance.Dataset().BodyStream(tr.Ctx, &BodyParams{Ref: ref, Format: "json"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	rows := [][]interface{}{}
+	if err := json.NewDecoder(r).Decode(&rows); err != nil {
+		t.Fatal(err)
+	}
+	r.Close()
+	if len(rows) != 5 {
+		t.Fatalf("expected 5 rows, got %d", len(rows))
+	}
+	if diff := cmp.Diff([]interface{}{"toronto", float64(50000000), 55.5, false}, rows[0]); diff != "" {
+		t.Errorf("first json row mismatch (-want +got):\n%s", diff)
+	}
+
+	if _, err := tr.Instance.Dataset().BodyStream(tr.Ctx, &BodyParams{Ref: ref, Format: "yaml"}); err == nil {
+		t.Error("expected unsupported format to error")
+	}
+}
+
+func TestDatasetRequestsBodyStreamPaging(t *testing.T) {
+	tr := newTestRunner(t)
+	defer tr.Delete()
+
+	body := "id,name\n"
+	for i := 0; i < 30; i++ {
+		body += fmt.Sprintf("%d,row_%d\n", i, i)
+	}
+	ds := tr.MustSaveFromBody(t, "paged", tr.MustWriteTmpFile(t, "paged.csv", body))
+	ref := fmt.Sprintf("%s/%s", ds.Peername, ds.Name)
+
+	r, err := tr.Instance.Dataset().BodyStream(tr.Ctx, &BodyParams{Ref: ref, Format: "json", Offset: 10, Limit: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	got := [][]interface{}{}
+	if err := json.NewDecoder(r).Decode(&got); err != nil {
+		t.Fatal(err)
+	}
+	expect := [][]interface{}{}
+	for i := 10; i < 20; i++ {
+		expect = append(expect, []interface{}{float64(i), fmt.Sprintf("row_%d", i)})
+	}
+	if diff := cmp.Diff(expect, got); diff != "" {
+		t.Errorf("rows 10-20 mismatch (-want +got):\n%s", diff)
+	}
+
+	// an offset without a limit reads to the end
+	r, err = tr.Instance.Dataset().BodyStream(tr.Ctx, &BodyParams{Ref: ref, Format: "json", Offset: 25})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	got = [][]interface{}{}
+	if err := json.NewDecoder(r).Decode(&got); err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 5 {
+		t.Errorf("expected 5 rows after offset 25, got %d", len(got))
+	}
+
+	if _, err := tr.Instance.Dataset().BodyStream(tr.Ctx, &BodyParams{Ref: ref, Offset: -1}); err == nil {
+		t.Error("expected negative offset to error")
+	}
+}
+
+func TestDatasetRequestsBodyStreamColumns(t *testing.T) {
+	tr := newTestRunner(t)
+	defer tr.Delete()
+
+	body := `city,country,pop,avg_age,in_usa
+toronto,canada,50000000,55.5,false
+new york,usa,8500000,44.4,true
+chicago,usa,300000,44.4,true
+`
+	ds := tr.MustSaveFromBody(t, "wide_cities", tr.MustWriteTmpFile(t, "wide_cities.csv", body))
+	ref := fmt.Sprintf("%s/%s", ds.Peername, ds.Name)
+
+	r, err := tr.Instance.Dataset().BodyStream(tr.Ctx, &BodyParams{Ref: ref, Columns: []string{"pop", "city"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := ioutil.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.Close()
+	expect := `pop,city
+50000000,toronto
+8500000,new york
+300000,chicago
+`
+	if diff := cmp.Diff(expect, string(got)); diff != "" {
+		t.Errorf("projected csv body mismatch (-want +got):\n%s", diff)
+	}
+
+	r, err = tr.Instance.Dataset().BodyStream(tr.Ctx, &BodyParams{Ref: ref, Format: "json", Columns: []string{"0", "in_usa"}, Limit: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err = ioutil.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.Close()
+	if diff := cmp.Diff(`[["toronto",false]]`, string(got)); diff != "" {
+		t.Errorf("projected json body mismatch (-want +got):\n%s", diff)
+	}
+
+	if _, err := tr.Instance.Dataset().BodyStream(tr.Ctx, &BodyParams{Ref: ref, Columns: []string{"nope"}}); err == nil {
+		t.Error("expected unknown column to error")
+	}
+}
+
+func TestDatasetRequestsExport(t *testing.T) {
+	tr := newTestRunner(t)
+	defer tr.Delete()
+
+	pro := tr.MustOwner(t)
+	ref, err := tr.SaveWithParams(&SaveParams{
+		Ref:      fmt.Sprintf("%s/export_me", pro.Peername),
+		BodyPath: "testdata/cities_2/body.csv",
+		Dataset: &dataset.Dataset{
+			Meta: &dataset.Meta{Title: "export me"},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	res, err := tr.Instance.Dataset().Export(tr.Ctx, &ExportParams{Ref: ref.String()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasSuffix(res.GeneratedName, ".zip") {
+		t.Errorf("expected generated name to have a .zip extension, got: %q", res.GeneratedName)
+	}
+	zr, err := zip.NewReader(bytes.NewReader(res.Bytes), int64(len(res.Bytes)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := []string{}
+	for _, f := range zr.File {
+		got = append(got, f.Name)
+	}
+	expect := []string{"dataset.json", "structure.json", "commit.json", "meta.json", "stats.json", "body.csv"}
+	if diff := cmp.Diff(expect, got); diff != "" {
+		t.Errorf("zip entries mismatch (-want +got):\n%s", diff)
+	}
+
+	// select components, streaming a tar archive to a writer
+	buf := &bytes.Buffer{}
+	res, err = tr.Instance.Dataset().Export(tr.Ctx, &ExportParams{
+		Ref:        ref.String(),
+		Format:     "tar",
+		Components: []string{"meta", "body"},
+		Output:     buf,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Bytes != nil {
+		t.Errorf("expected no bytes in results when streaming to a writer")
+	}
+	tarReader := tar.NewReader(buf)
+	got = []string{}
+	var body []byte
+	for {
+		hdr, err := tarReader.Next()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, hdr.Name)
+		if hdr.Name == "body.csv" {
+			if body, err = ioutil.ReadAll(tarReader); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	expect = []string{"dataset.json", "meta.json", "body.csv"}
+	if diff := cmp.Diff(expect, got); diff != "" {
+		t.Errorf("tar entries mismatch (-want +got):\n%s", diff)
+	}
+	expectBody, err := ioutil.ReadFile("testdata/cities_2/body.csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(string(expectBody), string(body)); diff != "" {
+		t.Errorf("tar body mismatch (-want +got):\n%s", diff)
+	}
+
+	if _, err := tr.Instance.Dataset().Export(tr.Ctx, &ExportParams{Ref: ref.String(), Components: []string{"nope"}}); err == nil {
+		t.Error("expected exporting an unknown component to fail")
+	}
+}
+
+func TestDatasetRequestsImport(t *testing.T) {
+	tr := newTestRunner(t)
+	defer tr.Delete()
+
+	pro := tr.MustOwner(t)
+	ref, err := tr.SaveWithParams(&SaveParams{
+		Ref:      fmt.Sprintf("%s/round_trip", pro.Peername),
+		BodyPath: "testdata/cities_2/body.csv",
+		Dataset: &dataset.Dataset{
+			Meta: &dataset.Meta{Title: "round trip"},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	exported, err := tr.Instance.Dataset().Export(tr.Ctx, &ExportParams{Ref: ref.String()})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fresh := newTestRunner(t)
+	defer fresh.Delete()
+
+	imported, err := fresh.Instance.Dataset().Import(fresh.Ctx, &ImportParams{
+		Reader: bytes.NewReader(exported.Bytes),
+		Ref:    "me/imported",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if imported.Path != ref.Path {
+		t.Errorf("expected imported path to match exported path %q, got: %q", ref.Path, imported.Path)
+	}
+
+	got := fresh.MustGet(t, fmt.Sprintf("%s/imported", pro.Peername))
+	if got.Path != ref.Path {
+		t.Errorf("expected imported name to resolve to %q, got: %q", ref.Path, got.Path)
+	}
+	if got.Meta == nil || got.Meta.Title != "round trip" {
+		t.Errorf("expected imported meta, got: %#v", got.Meta)
+	}
+
+	expectBody, err := tr.Instance.Dataset().GetCSV(tr.Ctx, &GetParams{Ref: ref.String(), All: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	gotBody, err := fresh.Instance.Dataset().GetCSV(fresh.Ctx, &GetParams{Ref: "me/imported", All: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(string(expectBody), string(gotBody)); diff != "" {
+		t.Errorf("body mismatch (-want +got):\n%s", diff)
+	}
+
+	// importing to a name that's in use fails
+	if _, err := fresh.Instance.Dataset().Import(fresh.Ctx, &ImportParams{Reader: bytes.NewReader(exported.Bytes), Ref: "me/imported"}); err == nil {
+		t.Error("expected importing to an existing name to fail")
+	}
+
+	// archives without dataset.json are rejected
+	buf := &bytes.Buffer{}
+	zw := zip.NewWriter(buf)
+	fw, _ := zw.Create("meta.json")
+	fw.Write([]byte(`{"title":"no dataset"}`))
+	zw.Close()
+	malformed := [][]byte{buf.Bytes(), []byte("not an archive")}
+	for i, data := range malformed {
+		_, err := fresh.Instance.Dataset().Import(fresh.Ctx, &ImportParams{Reader: bytes.NewReader(data), Ref: fmt.Sprintf("me/malformed_%d", i)})
+		if !errors.Is(err, archive.ErrMalformedPackage) {
+			t.Errorf("case %d: expected error %q, got: %v", i, archive.ErrMalformedPackage, err)
+		}
+	}
+}
+
+func TestDatasetRequestsImportCAR(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	crypto := testrepo.NewTestCrypto()
+
+	newIPFSInstance := func(name string) *Instance {
+		r, err := testrepo.NewTempRepo(name, fmt.Sprintf("import_car_%s", name), crypto)
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(r.Delete)
+		inst, err := NewInstance(ctx, r.QriPath, OptIOStreams(ioes.NewDiscardIOStreams()))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return inst
+	}
+
+	src := newIPFSInstance("car_src")
+	saved, err := src.Dataset().Save(ctx, &SaveParams{
+		Ref:      "me/car_export",
+		BodyPath: "testdata/cities_2/body.csv",
+		Dataset:  &dataset.Dataset{Meta: &dataset.Meta{Title: "car export"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	capi, err := src.node.IPFSCoreAPI()
+	if err != nil {
+		t.Fatal(err)
+	}
+	root, err := cid.Parse(strings.TrimPrefix(saved.Path, "/ipfs/"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	mfst, err := dag.NewManifest(ctx, capi.Dag(), root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, err := dsync.NewManifestCARReader(ctx, capi.Dag(), mfst, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dst := newIPFSInstance("car_dst")
+	dstAPI, err := dst.node.IPFSCoreAPI()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// CAR files with corrupted blocks are rejected before anything is stored
+	corrupted := append([]byte{}, data...)
+	corrupted[len(corrupted)-1]++
+	if _, err := dst.Dataset().Import(ctx, &ImportParams{Reader: bytes.NewReader(corrupted), Ref: "me/corrupted"}); !errors.Is(err, archive.ErrMalformedPackage) {
+		t.Errorf("expected importing a corrupted CAR file to fail with %q, got: %v", archive.ErrMalformedPackage, err)
+	}
+	for _, id := range mfst.Nodes {
+		if _, err := dstAPI.Block().Stat(ctx, ipfspath.New("/ipfs/"+id)); err == nil {
+			t.Errorf("expected a rejected CAR file not to store block %s", id)
+			break
+		}
+	}
+
+	imported, err := dst.Dataset().Import(ctx, &ImportParams{Reader: bytes.NewReader(data), Ref: "me/car_import"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if imported.Path != saved.Path {
+		t.Errorf("expected imported path to match exported path %q, got: %q", saved.Path, imported.Path)
+	}
+	got, err := dst.Dataset().Get(ctx, &GetParams{Ref: "me/car_import"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ds, ok := got.Value.(*dataset.Dataset); !ok || ds.Meta == nil || ds.Meta.Title != "car export" {
+		t.Errorf("expected imported dataset to have meta title %q, got: %#v", "car export", got.Value)
+	}
+}
+
+func TestDatasetRequestsGetVerifiesTransformSignature(t *testing.T) {
+	tr := newTestRunner(t)
+	defer tr.Delete()
+	tr.Instance.cfg.Repo.SignTransforms = true
+
+	tr.MustSaveFromBody(t, "signed", "testdata/cities_2/body.csv")
+	script := tr.MustWriteTmpFile(t, "signed.star", `ds = dataset.latest()
+ds.body = ds.body.append([["tokyo", 9200000, 48.5, False]])
+dataset.commit(ds)
+`)
+	if _, err := tr.Instance.Dataset().Save(tr.Ctx, &SaveParams{
+		Ref:       "me/signed",
+		FilePaths: []string{script},
+		Apply:     true,
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	signed := tr.MustGet(t, "me/signed")
+	fs := tr.Instance.Repo().Filesystem()
+	if _, err := fs.Get(tr.Ctx, dsfs.PackageFilepath(fs, signed.Path, dsfs.PackageFileTransformSignature)); err != nil {
+		t.Fatalf("expected saved transform to be signed: %s", err)
+	}
+
+	// write a version with a changed script, signed by someone other than
+	// the dataset author
+	tampered, err := dsfs.LoadDataset(tr.Ctx, fs, signed.Path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := base.OpenDataset(tr.Ctx, fs, tampered); err != nil {
+		t.Fatal(err)
+	}
+	tampered.Transform.Steps[0].Script = "print('tampered')"
+	tampered.Transform.ScriptPath = ""
+	tampered.Commit.Title = "tampered"
+	forger := testkeys.GetKeyData(9).PrivKey
+	path, err := dsfs.WriteDataset(tr.Ctx, fs, fs.DefaultWriteFS(), nil, tampered, event.NilBus, forger, dsfs.SaveSwitches{SignTransform: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = tr.Instance.Dataset().Get(tr.Ctx, &GetParams{Ref: "me/signed@" + path})
+	if !errors.Is(err, dsfs.ErrInvalidTransformSignature) {
+		t.Errorf("expected getting a tampered transform to fail with %q, got: %v", dsfs.ErrInvalidTransformSignature, err)
+	}
+
+	// write a version that references a stored script without a signature
+	unsigned, err := dsfs.LoadDataset(tr.Ctx, fs, signed.Path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := base.OpenDataset(tr.Ctx, fs, unsigned); err != nil {
+		t.Fatal(err)
+	}
+	unsignedScript, err := fs.DefaultWriteFS().Put(tr.Ctx, qfs.NewMemfileBytes("transform.star", []byte("print('unsigned')\n")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	unsigned.Transform.Steps = nil
+	unsigned.Transform.ScriptPath = unsignedScript
+	unsigned.Transform.SetScriptFile(nil)
+	unsigned.Commit.Title = "unsigned"
+	path, err = dsfs.WriteDataset(tr.Ctx, fs, fs.DefaultWriteFS(), nil, unsigned, event.NilBus, forger, dsfs.SaveSwitches{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// unsigned transforms are unverified, but load by default
+	if _, err = tr.Instance.Dataset().Get(tr.Ctx, &GetParams{Ref: "me/signed@" + path}); err != nil {
+		t.Errorf("expected getting an unsigned transform to succeed, got: %s", err)
+	}
+
+	tr.Instance.cfg.Repo.VerifyTransformSignatures = true
+	_, err = tr.Instance.Dataset().Get(tr.Ctx, &GetParams{Ref: "me/signed@" + path})
+	if !errors.Is(err, dsfs.ErrTransformNotSigned) {
+		t.Errorf("expected getting an unsigned transform with verification required to fail with %q, got: %v", dsfs.ErrTransformNotSigned, err)
+	}
+	if _, err = tr.Instance.Dataset().Get(tr.Ctx, &GetParams{Ref: "me/signed@" + signed.Path}); err != nil {
+		t.Errorf("expected getting a signed transform with verification required to succeed, got: %s", err)
+	}
+}
+
+func TestDatasetRequestsProvenance(t *testing.T) {
+	tr := newTestRunner(t)
+	defer tr.Delete()
+
+	wbp := InitWorldBankDataset(tr.Ctx, t, tr.Instance)
+
+	script := tr.MustWriteTmpFile(t, "wbp_plus_one.star", fmt.Sprintf(`wbp = load_dataset(%q)
+ds = dataset.latest()
+ds.body = wbp.body + [["g","h","i",False,3]]
+dataset.commit(ds)
+`, wbp.Alias()))
+	if _, err := tr.Instance.Dataset().Save(tr.Ctx, &SaveParams{
+		Ref:       "me/wbp_plus_one",
+		FilePaths: []string{script},
+		Apply:     true,
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	nodes, err := tr.Instance.Dataset().Provenance(tr.Ctx, &ProvenanceParams{Ref: "me/wbp_plus_one"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(nodes) != 1 {
+		t.Fatalf("expected a single node without recursion, got %d", len(nodes))
+	}
+	if nodes[0].Ref.Name != "wbp_plus_one" {
+		t.Errorf("expected first node to be the requested dataset, got %s", nodes[0].Ref)
+	}
+	if len(nodes[0].Upstream) != 1 {
+		t.Fatalf("expected one upstream dataset, got: %v", nodes[0].Upstream)
+	}
+	up := nodes[0].Upstream[0]
+	if up.Alias() != wbp.Alias() || up.Path != wbp.Path {
+		t.Errorf("upstream mismatch. want %s@%s, got %s@%s", wbp.Alias(), wbp.Path, up.Alias(), up.Path)
+	}
+
+	nodes, err = tr.Instance.Dataset().Provenance(tr.Ctx, &ProvenanceParams{Ref: "me/wbp_plus_one", Recursive: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(nodes) != 2 {
+		t.Fatalf("expected recursive provenance to include world bank population, got %d nodes", len(nodes))
+	}
+	if nodes[1].Ref.Path != wbp.Path || len(nodes[1].Upstream) != 0 {
+		t.Errorf("expected world bank population to have no upstream datasets, got: %#v", nodes[1])
+	}
+
+	if _, err := tr.Instance.Dataset().Provenance(tr.Ctx, &ProvenanceParams{}); err == nil {
+		t.Error("expected empty ref to error")
+	}
+}
+
+func TestUpstreamRefsSkipsSelf(t *testing.T) {
+	ds := &dataset.Dataset{
+		ID:       "jsbxlfxpbvtszbj3qwtkvajo3dkwfwgeuqgz35bnfrtvcpx6idfq",
+		Peername: "peer",
+		Name:     "self",
+		Transform: &dataset.Transform{
+			Resources: map[string]*dataset.TransformResource{
+				stards.LatestResourceKey: {Path: "peer/self@/mem/QmTwJGHiY1n5xsjGCGuGzTvWQ8jTNmn3rVbAu5KoJFdbEW"},
+				"peer/self":              {Path: "peer/self@/mem/QmPg2dSGNRfc2S1aGR2d6ZbWZQYK1kD6ywhFyFwH8DUPnt"},
+				"by_init_id":             {Path: "other/renamed@jsbxlfxpbvtszbj3qwtkvajo3dkwfwgeuqgz35bnfrtvcpx6idfq/mem/QmVgNWuYTtmSWQcFMfKXRg7xYRZ1yuuuRrBh9Q7RaXxj59"},
+				"peer/upstream":          {Path: "peer/upstream@/mem/QmZpxEqPvAbLCpTzNH3dCqCRgW8KzuDrmTXNQL8XPYygHs"},
+			},
+		},
+	}
+	got := upstreamRefs(ds)
+	if len(got) != 1 || got[0].Path != "/mem/QmZpxEqPvAbLCpTzNH3dCqCRgW8KzuDrmTXNQL8XPYygHs" {
+		t.Errorf("expected only the upstream dataset, got: %v", got)
+	}
+}
@@ -275,6 +275,7 @@ func (inst *Instance) AllMethods() []MethodSet {
 		inst.Remote(),
 		inst.Search(),
 		inst.Automation(),
+		inst.Transform(),
 	}
 }
 
@@ -294,6 +295,7 @@ func (inst *Instance) RegisterMethods() {
 	inst.registerOne("follow", inst.Follow(), followImpl{}, reg)
 	inst.registerOne("remote", inst.Remote(), remoteImpl{}, reg)
 	inst.registerOne("search", inst.Search(), searchImpl{}, reg)
+	inst.registerOne("transform", inst.Transform(), transformImpl{}, reg)
 	inst.regMethods = &regMethodSet{reg: reg}
 }
 
@@ -1,7 +1,9 @@
 package lib
 
 import (
+	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io/ioutil"
 	"net/http"
@@ -22,9 +24,15 @@ func PathJoinPosix(left, right string) string {
 	return path.Join(left, right)
 }
 
+// ErrConflictingBody indicates more than one file provided to ReadDatasetFiles
+// defines a dataset body
+var ErrConflictingBody = errors.New("conflict, multiple files define a dataset body")
+
 // ReadDatasetFiles reads zero or more files, each representing a dataset or component of a
-// dataset, and deserializes them, merging the results into a single dataset object. It is an
-// error to provide any combination of files whose contents overlap (modify the same component).
+// dataset, and deserializes them, merging the results into a single dataset object. Files are
+// merged in order, each file contributing its component, with later files overriding earlier
+// ones. It is an error to provide a full dataset alongside other files, or more than one file
+// that defines the dataset body.
 func ReadDatasetFiles(pathList ...string) (*dataset.Dataset, error) {
 	// If there's only a single file provided, read it and return the dataset.
 	if len(pathList) == 1 {
@@ -33,8 +41,9 @@ func ReadDatasetFiles(pathList ...string) (*dataset.Dataset, error) {
 	}
 
 	// If there's multiple files provided, read each one and merge them. Any exclusive
-	// component is an error, any component showing up multiple times is an error.
-	foundKinds := make(map[string]bool)
+	// component is an error, as is more than one body. Components showing up
+	// multiple times are assigned in order, later files taking precedence
+	bodyPath := ""
 	ds := dataset.Dataset{}
 	for _, p := range pathList {
 		component, kind, err := readSingleFile(p)
@@ -45,10 +54,12 @@ func ReadDatasetFiles(pathList ...string) (*dataset.Dataset, error) {
 		if kind == "zip" || kind == "ds" {
 			return nil, fmt.Errorf("conflict, cannot save a full dataset with other components")
 		}
-		if _, ok := foundKinds[kind]; ok {
-			return nil, fmt.Errorf("conflict, multiple components of kind \"%s\"", kind)
+		if kind == "bd" {
+			if bodyPath != "" {
+				return nil, fmt.Errorf("%w: %q and %q", ErrConflictingBody, bodyPath, p)
+			}
+			bodyPath = p
 		}
-		foundKinds[kind] = true
 
 		ds.Assign(component)
 	}
@@ -101,11 +112,18 @@ func readSingleFile(path string) (*dataset.Dataset, string, error) {
 			return &ds, kind, err
 
 		case ".json":
+			data, err := ioutil.ReadAll(f)
+			if err != nil {
+				return nil, "", err
+			}
+			// json files with a top-level array are dataset bodies
+			if trimmed := bytes.TrimSpace(data); len(trimmed) > 0 && trimmed[0] == '[' {
+				ds.BodyPath = path
+				return &ds, "bd", nil
+			}
+
 			fields := make(map[string]interface{})
-			if err = json.NewDecoder(f).Decode(&fields); err != nil {
-				if strings.HasPrefix(err.Error(), "json: cannot unmarshal array") {
-					err = fmt.Errorf("json has top-level type \"array\", cannot be a dataset file")
-				}
+			if err = json.Unmarshal(data, &fields); err != nil {
 				return nil, "", err
 			}
 			kind, err := fillDatasetOrComponent(fields, path, &ds)
@@ -119,6 +137,12 @@ func readSingleFile(path string) (*dataset.Dataset, string, error) {
 			err = archive.UnzipDatasetBytes(data, &ds)
 			return &ds, "zip", err
 
+		case ".csv", ".ndjson", ".jsonl", ".xlsx", ".cbor":
+			// data files are assumed to be the dataset body
+			f.Close()
+			ds.BodyPath = path
+			return &ds, "bd", nil
+
 		case ".star":
 			// starlark files are assumed to be a transform script with no additional
 			// tranform component details:
@@ -129,6 +129,19 @@ func TestReadDatasetFiles(t *testing.T) {
 				},
 			},
 		},
+
+		{"later component files override earlier",
+			[]string{
+				"testdata/component_files/meta.json",
+				"testdata/detect/meta.json",
+			},
+			&dataset.Dataset{
+				Meta: &dataset.Meta{
+					Qri:   "md",
+					Title: "This is dataset title",
+				},
+			},
+		},
 	}
 
 	for i, c := range cases {
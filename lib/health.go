@@ -0,0 +1,185 @@
+package lib
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/qri-io/qfs"
+)
+
+// HealthStatus describes the state of an instance subsystem
+type HealthStatus string
+
+const (
+	// HealthOK indicates a subsystem is working as expected
+	HealthOK HealthStatus = "ok"
+	// HealthDisabled indicates a subsystem isn't configured for this instance
+	HealthDisabled HealthStatus = "disabled"
+	// HealthOffline indicates a subsystem is enabled but not connected. Offline
+	// subsystems don't make an instance unhealthy
+	HealthOffline HealthStatus = "offline"
+	// HealthError indicates a subsystem failed its health probe
+	HealthError HealthStatus = "error"
+)
+
+// SubsystemHealth is the result of probing a single subsystem
+type SubsystemHealth struct {
+	Name   string       `json:"name"`
+	Status HealthStatus `json:"status"`
+	Error  string       `json:"error,omitempty"`
+}
+
+// HealthReport aggregates the status of an instance's subsystems. An instance
+// is healthy when no subsystem reports an error
+type HealthReport struct {
+	Healthy    bool              `json:"healthy"`
+	Subsystems []SubsystemHealth `json:"subsystems"`
+}
+
+// Subsystem returns the health of a named subsystem, nil if the report
+// doesn't include it
+func (r *HealthReport) Subsystem(name string) *SubsystemHealth {
+	for i, s := range r.Subsystems {
+		if s.Name == name {
+			return &r.Subsystems[i]
+		}
+	}
+	return nil
+}
+
+// HealthParams configures an instance health check
+type HealthParams struct {
+	// WriteProbe opts in to writing & deleting a file on the default write
+	// filesystem. Write probes run at most once per HealthWriteProbeInterval,
+	// more frequent checks reuse the last result. Without a write probe the
+	// filesystem is only checked for presence
+	WriteProbe bool
+}
+
+// HealthWriteProbeInterval is the minimum time between filesystem write probes
+var HealthWriteProbeInterval = time.Minute
+
+// healthProbe checks a single subsystem
+type healthProbe struct {
+	name  string
+	probe func(ctx context.Context, inst *Instance, p *HealthParams) (HealthStatus, error)
+}
+
+// writeProbeCache holds the result of the most recent filesystem write probe
+type writeProbeCache struct {
+	sync.Mutex
+	at  time.Time
+	err error
+}
+
+// healthProbes is the list of subsystems checked by Instance.Health, in the
+// order they're reported
+var healthProbes = []healthProbe{
+	{"logbook", probeLogbook},
+	{"dscache", probeDscache},
+	{"filesystem", probeFilesystem},
+	{"p2p", probeP2P},
+}
+
+// Health probes each of the instance's subsystems, returning a structured
+// report. Health only returns an error if the instance itself is unusable,
+// subsystem failures are recorded in the report. A nil params value runs the
+// default, read-only checks
+func (inst *Instance) Health(ctx context.Context, params *HealthParams) (*HealthReport, error) {
+	if inst == nil {
+		return nil, ErrDispatchNilInstance
+	}
+	if params == nil {
+		params = &HealthParams{}
+	}
+
+	report := &HealthReport{Healthy: true}
+	for _, p := range healthProbes {
+		status, err := p.probe(ctx, inst, params)
+		sh := SubsystemHealth{Name: p.name, Status: status}
+		if err != nil {
+			sh.Status = HealthError
+			sh.Error = err.Error()
+		}
+		if sh.Status == HealthError {
+			report.Healthy = false
+		}
+		report.Subsystems = append(report.Subsystems, sh)
+	}
+	return report, nil
+}
+
+// probeLogbook confirms the logbook can be read
+func probeLogbook(ctx context.Context, inst *Instance, _ *HealthParams) (HealthStatus, error) {
+	if inst.logbook == nil {
+		return HealthError, fmt.Errorf("no logbook")
+	}
+	if _, err := inst.logbook.ListAllLogs(ctx); err != nil {
+		return HealthError, fmt.Errorf("reading logbook: %w", err)
+	}
+	return HealthOK, nil
+}
+
+// probeDscache validates the dscache, if one exists
+func probeDscache(ctx context.Context, inst *Instance, _ *HealthParams) (HealthStatus, error) {
+	if inst.dscache == nil || inst.dscache.IsEmpty() {
+		return HealthDisabled, nil
+	}
+	if err := inst.dscache.Validate(); err != nil {
+		return HealthError, err
+	}
+	return HealthOK, nil
+}
+
+// probeFilesystem confirms the instance has a default write filesystem. When
+// write probes are requested it also writes & removes a small file, reusing
+// the last result if a write probe ran within HealthWriteProbeInterval
+func probeFilesystem(ctx context.Context, inst *Instance, p *HealthParams) (HealthStatus, error) {
+	r := inst.Repo()
+	if r == nil || r.Filesystem() == nil {
+		return HealthError, fmt.Errorf("no filesystem")
+	}
+	fs := r.Filesystem().DefaultWriteFS()
+	if fs == nil {
+		return HealthError, fmt.Errorf("no default write filesystem")
+	}
+	if !p.WriteProbe {
+		return HealthOK, nil
+	}
+
+	inst.writeProbe.Lock()
+	defer inst.writeProbe.Unlock()
+	if inst.writeProbe.at.IsZero() || time.Since(inst.writeProbe.at) >= HealthWriteProbeInterval {
+		inst.writeProbe.err = writeProbe(ctx, fs)
+		inst.writeProbe.at = time.Now()
+	}
+	if inst.writeProbe.err != nil {
+		return HealthError, inst.writeProbe.err
+	}
+	return HealthOK, nil
+}
+
+// writeProbe writes & removes a small file on a filesystem
+func writeProbe(ctx context.Context, fs qfs.Filesystem) error {
+	path, err := fs.Put(ctx, qfs.NewMemfileBytes("health_probe", []byte("qri health probe")))
+	if err != nil {
+		return fmt.Errorf("writing to %s filesystem: %w", fs.Type(), err)
+	}
+	if err := fs.Delete(ctx, path); err != nil {
+		return fmt.Errorf("deleting from %s filesystem: %w", fs.Type(), err)
+	}
+	return nil
+}
+
+// probeP2P reports whether the peer-2-peer node is online
+func probeP2P(ctx context.Context, inst *Instance, _ *HealthParams) (HealthStatus, error) {
+	if inst.cfg == nil || inst.cfg.P2P == nil || !inst.cfg.P2P.Enabled || inst.node == nil {
+		return HealthDisabled, nil
+	}
+	if !inst.node.Online {
+		return HealthOffline, nil
+	}
+	return HealthOK, nil
+}
@@ -0,0 +1,92 @@
+package lib
+
+import (
+	"context"
+	"testing"
+
+	testcfg "github.com/qri-io/qri/config/test"
+	"github.com/qri-io/qri/dscache"
+	"github.com/qri-io/qri/dsref"
+)
+
+func TestHealth(t *testing.T) {
+	ctx, done := context.WithCancel(context.Background())
+	defer done()
+
+	node := newTestQriNode(t)
+	inst := NewInstanceFromConfigAndNode(ctx, testcfg.DefaultConfigForTesting(), node)
+
+	report, err := inst.Health(ctx, &HealthParams{WriteProbe: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !report.Healthy {
+		t.Errorf("expected test instance to be healthy, got: %#v", report)
+	}
+	for _, name := range []string{"logbook", "dscache", "filesystem", "p2p"} {
+		if report.Subsystem(name) == nil {
+			t.Errorf("expected report to include subsystem %q", name)
+		}
+	}
+	if s := report.Subsystem("filesystem"); s.Status != HealthOK {
+		t.Errorf("expected filesystem probe to succeed, got: %#v", s)
+	}
+
+	// break the dscache with an entry that has no user association
+	pro := node.Repo.Profiles().Owner(ctx)
+	builder := dscache.NewBuilder()
+	builder.AddUser(pro.Peername, pro.ID.Encode())
+	builder.AddDsVersionInfo(dsref.VersionInfo{InitID: "abcd1", ProfileID: "QmUnknownProfileIDUnknownProfileIDUnknownProf", Name: "broken"})
+	inst.dscache = builder.Build()
+
+	report, err = inst.Health(ctx, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Healthy {
+		t.Errorf("expected instance with a broken dscache to be unhealthy")
+	}
+	s := report.Subsystem("dscache")
+	if s.Status != HealthError {
+		t.Errorf("expected dscache status %q, got: %q", HealthError, s.Status)
+	}
+	if s.Error == "" {
+		t.Errorf("expected dscache error to be reported")
+	}
+	if s := report.Subsystem("logbook"); s.Status != HealthOK {
+		t.Errorf("expected logbook to remain healthy, got: %#v", s)
+	}
+}
+
+func TestHealthWriteProbeInterval(t *testing.T) {
+	ctx, done := context.WithCancel(context.Background())
+	defer done()
+
+	node := newTestQriNode(t)
+	inst := NewInstanceFromConfigAndNode(ctx, testcfg.DefaultConfigForTesting(), node)
+
+	if _, err := inst.Health(ctx, nil); err != nil {
+		t.Fatal(err)
+	}
+	if !inst.writeProbe.at.IsZero() {
+		t.Errorf("expected health check without a write probe not to write")
+	}
+
+	if _, err := inst.Health(ctx, &HealthParams{WriteProbe: true}); err != nil {
+		t.Fatal(err)
+	}
+	first := inst.writeProbe.at
+	if first.IsZero() {
+		t.Fatal("expected write probe to run")
+	}
+	report, err := inst.Health(ctx, &HealthParams{WriteProbe: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !inst.writeProbe.at.Equal(first) {
+		t.Errorf("expected write probes within the interval to reuse the last result")
+	}
+	if s := report.Subsystem("filesystem"); s.Status != HealthOK {
+		t.Errorf("expected cached filesystem probe to succeed, got: %#v", s)
+	}
+}
@@ -65,6 +65,13 @@ const (
 	// AEAnalyzeTransform performs static analysis on a starlark transform script
 	AEAnalyzeTransform APIEndpoint = "/auto/analyze-transform"
 
+	// transform endpoints
+
+	// AETransformBuiltins lists the modules & builtins available to transforms
+	AETransformBuiltins APIEndpoint = "/transform/builtins"
+	// AETransformDatasets lists the datasets transforms can load
+	AETransformDatasets APIEndpoint = "/transform/datasets"
+
 	// dataset endpoints
 
 	// AEGet is an endpoint for fetch individual dataset components
@@ -77,6 +84,8 @@ const (
 	AESave APIEndpoint = "/ds/save"
 	// AEPull facilittates dataset pull requests from a remote
 	AEPull APIEndpoint = "/ds/pull"
+	// AEResolvePull reports where a reference passed to pull resolves
+	AEResolvePull APIEndpoint = "/ds/pull/resolve"
 	// AEPush facilitates dataset push requests to a remote
 	AEPush APIEndpoint = "/ds/push"
 	// AERender renders the current dataset ref
@@ -91,8 +100,19 @@ const (
 	AEManifestMissing APIEndpoint = "/ds/manifest/missing"
 	// AEDAGInfo generates a dag.Info for a dataset path
 	AEDAGInfo APIEndpoint = "/ds/daginfo"
+	// AEComputeCID computes the CID of a dataset version without saving it
+	AEComputeCID APIEndpoint = "/ds/computecid"
+	// AEResolveInfo resolves a reference to the VersionInfo for a dataset version
+	AEResolveInfo APIEndpoint = "/ds/resolveinfo"
+	// AEVerify checks the blocks of a dataset version are present & intact
+	AEVerify APIEndpoint = "/ds/verify"
 	// AEWhatChanged gets what changed at a specific version in history
 	AEWhatChanged APIEndpoint = "/ds/whatchanged"
+	// AEStats gets the stats component of a dataset version
+	AEStats APIEndpoint = "/ds/stats"
+	// AEProvenance lists the upstream datasets a dataset version was
+	// derived from
+	AEProvenance APIEndpoint = "/ds/provenance"
 
 	// peer endpoints
 
@@ -120,6 +140,8 @@ const (
 
 	// AEFeeds fetches and index of named feeds
 	AEFeeds APIEndpoint = "/remote/feeds"
+	// AERemoteListByPeer lists the datasets a remote holds for a peer
+	AERemoteListByPeer APIEndpoint = "/remote/listbypeer"
 	// AEPreview fetches a dataset preview from the registry
 	AEPreview APIEndpoint = "/remote/preview"
 	// AERemoteRemove removes a dataset from a given remote
@@ -4,18 +4,23 @@ import (
 	"context"
 	"fmt"
 	"net/http/httptest"
+	"strings"
 	"testing"
 
+	"github.com/google/go-cmp/cmp"
 	"github.com/qri-io/dataset"
 	"github.com/qri-io/ioes"
 	"github.com/qri-io/qri/auth/key"
+	"github.com/qri-io/qri/base/dsfs"
 	"github.com/qri-io/qri/base/params"
 	"github.com/qri-io/qri/config"
 	"github.com/qri-io/qri/dsref"
 	dsrefspec "github.com/qri-io/qri/dsref/spec"
+	"github.com/qri-io/qri/event"
 	"github.com/qri-io/qri/registry"
 	"github.com/qri-io/qri/registry/regserver"
 	"github.com/qri-io/qri/remote"
+	"github.com/qri-io/qri/remote/access"
 	repotest "github.com/qri-io/qri/repo/test"
 )
 
@@ -59,6 +64,9 @@ func TestTwoActorRegistryIntegration(t *testing.T) {
 	if err := AssertLogsEqual(nasim, hinshun, ref); err != nil {
 		t.Error(err)
 	}
+	if err := AssertDatasetBlocksEqual(nasim, hinshun, ref); err != nil {
+		t.Error(err)
+	}
 
 	// 5. nasim commits a new version
 	ref = Commit2WorldBank(tr.Ctx, t, nasim)
@@ -77,11 +85,15 @@ func TestTwoActorRegistryIntegration(t *testing.T) {
 	}
 
 	// TODO (b5) - assert hinshun DOES NOT have blocks for the latest commit to world bank dataset
+	// using AssertDatasetBlocksEqual. Pull currently ignores LogsOnly and fetches
+	// blocks as well as logs, so this can't be checked until that's fixed
 
 	// 8. hinshun pulls latest version
 	Pull(tr.Ctx, t, hinshun, ref.Alias())
 
-	// TODO (b5) - assert hinshun has world bank dataset blocks
+	if err := AssertDatasetBlocksEqual(nasim, hinshun, ref); err != nil {
+		t.Error(err)
+	}
 
 	// all three should now have the same HEAD reference & InitID
 	dsrefspec.ConsistentResolvers(t, dsref.Ref{
@@ -94,6 +106,72 @@ func TestTwoActorRegistryIntegration(t *testing.T) {
 	)
 }
 
+func TestPullMarksDatasetForeign(t *testing.T) {
+	tr := NewNetworkIntegrationTestRunner(t, "integration_pull_foreign")
+	defer tr.Cleanup()
+
+	nasim := tr.InitNasim(t)
+	nasim.Dscache().CreateNewEnabled = true
+	ref := InitWorldBankDataset(tr.Ctx, t, nasim)
+	PushToRegistry(tr.Ctx, t, nasim, ref.Alias())
+
+	hinshun := tr.InitHinshun(t)
+	hinshun.Dscache().CreateNewEnabled = true
+	Pull(tr.Ctx, t, hinshun, ref.Alias())
+
+	assertForeign := func(inst *Instance, expect bool) {
+		t.Helper()
+		refs, err := inst.Dscache().ListRefs()
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, r := range refs {
+			if r.Name == ref.Name {
+				if r.Foreign != expect {
+					t.Errorf("%s dscache: expected %s foreign to be %t", inst.cfg.Profile.Peername, ref.Alias(), expect)
+				}
+				return
+			}
+		}
+		t.Errorf("%s dscache: %s not found in refs: %v", inst.cfg.Profile.Peername, ref.Alias(), refs)
+	}
+
+	assertForeign(nasim, false)
+	assertForeign(hinshun, true)
+}
+
+func TestResolvePull(t *testing.T) {
+	tr := NewNetworkIntegrationTestRunner(t, "integration_resolve_pull")
+	defer tr.Cleanup()
+
+	nasim := tr.InitNasim(t)
+	ref := InitWorldBankDataset(tr.Ctx, t, nasim)
+	PushToRegistry(tr.Ctx, t, nasim, ref.Alias())
+
+	hinshun := tr.InitHinshun(t)
+	res, err := hinshun.WithSource("network").Dataset().ResolvePull(tr.Ctx, &ResolvePullParams{Ref: ref.Alias()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Source != ResolvedFromRegistry {
+		t.Errorf("expected source %q, got: %q", ResolvedFromRegistry, res.Source)
+	}
+	if res.Ref.Path != ref.Path {
+		t.Errorf("expected resolved path %q, got: %q", ref.Path, res.Ref.Path)
+	}
+	if _, err := hinshun.Repo().Filesystem().Get(tr.Ctx, ref.Path); err == nil {
+		t.Errorf("expected resolving a pull not to fetch dataset blocks")
+	}
+
+	res, err = nasim.WithSource("local").Dataset().ResolvePull(tr.Ctx, &ResolvePullParams{Ref: ref.Alias()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Source != ResolvedFromLocal {
+		t.Errorf("expected source %q, got: %q", ResolvedFromLocal, res.Source)
+	}
+}
+
 func TestReferencePulling(t *testing.T) {
 	tr := NewNetworkIntegrationTestRunner(t, "integration_reference_pulling")
 	defer tr.Cleanup()
@@ -168,6 +246,97 @@ dataset.commit(ds)
 	}
 }
 
+func TestUnpublishFromRegistry(t *testing.T) {
+	tr := NewNetworkIntegrationTestRunner(t, "integration_unpublish")
+	defer tr.Cleanup()
+
+	nasim := tr.InitNasim(t)
+	ref := InitWorldBankDataset(tr.Ctx, t, nasim)
+	PushToRegistry(tr.Ctx, t, nasim, ref.Alias())
+
+	hinshun := tr.InitHinshun(t)
+	if results := SearchFor(tr.Ctx, t, hinshun, "bank"); len(results) != 1 {
+		t.Fatalf("expected published dataset to be searchable, got %d results", len(results))
+	}
+
+	// - hinshun pulls nasim's dataset, and can't unpublish it
+	Pull(tr.Ctx, t, hinshun, ref.Alias())
+	if _, err := hinshun.Remote().Remove(tr.Ctx, &PushParams{Ref: ref.Alias()}); err == nil {
+		t.Errorf("expected removing another user's dataset to fail")
+	}
+	// skipping the client-side namespace check must be rejected by the registry
+	err := hinshun.RemoteClient().RemoveDataset(tr.Ctx, ref, tr.RegistryHTTPServer.URL)
+	if err == nil || !strings.Contains(err.Error(), access.ErrAccessDenied.Error()) {
+		t.Errorf("expected registry to deny remove by non-owner, got: %v", err)
+	}
+	if results := SearchFor(tr.Ctx, t, hinshun, "bank"); len(results) != 1 {
+		t.Fatalf("expected dataset to remain searchable after denied remove, got %d results", len(results))
+	}
+
+	removed := false
+	nasim.Bus().SubscribeTypes(func(ctx context.Context, e event.Event) error {
+		removed = true
+		return nil
+	}, event.ETRemoteClientRemoveDatasetCompleted)
+
+	// - nasim unpublishes
+	if _, err := nasim.Remote().Remove(tr.Ctx, &PushParams{Ref: ref.Alias()}); err != nil {
+		t.Fatal(err)
+	}
+	if !removed {
+		t.Errorf("expected %q event to fire", event.ETRemoteClientRemoveDatasetCompleted)
+	}
+
+	if results := SearchFor(tr.Ctx, t, hinshun, "bank"); len(results) != 0 {
+		t.Errorf("expected unpublished dataset not to be searchable, got %d results", len(results))
+	}
+
+	logRes, err := nasim.Dataset().Activity(tr.Ctx, &ActivityParams{Ref: ref.Alias(), List: params.List{Limit: 1}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if logRes[0].Published {
+		t.Errorf("expected nasim's HEAD to be marked unpublished")
+	}
+}
+
+func TestListByPeer(t *testing.T) {
+	tr := NewNetworkIntegrationTestRunner(t, "integration_list_by_peer")
+	defer tr.Cleanup()
+
+	nasim := tr.InitNasim(t)
+	ref := InitWorldBankDataset(tr.Ctx, t, nasim)
+	PushToRegistry(tr.Ctx, t, nasim, ref.Alias())
+
+	hinshun := tr.InitHinshun(t)
+	res, err := hinshun.Remote().ListByPeer(tr.Ctx, &ListByPeerParams{Peername: "nasim"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res) != 1 {
+		t.Fatalf("expected 1 dataset for nasim, got %d: %v", len(res), res)
+	}
+	if res[0].SimpleRef().Alias() != ref.Alias() {
+		t.Errorf("expected %q, got %q", ref.Alias(), res[0].SimpleRef().Alias())
+	}
+
+	res, err = hinshun.Remote().ListByPeer(tr.Ctx, &ListByPeerParams{Peername: "nasim", List: params.List{Offset: 1}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res) != 0 {
+		t.Errorf("expected offset past the end to return no results, got %d", len(res))
+	}
+
+	res, err = hinshun.Remote().ListByPeer(tr.Ctx, &ListByPeerParams{Peername: "hinshun"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res) != 0 {
+		t.Errorf("expected no datasets for hinshun, got %d", len(res))
+	}
+}
+
 type NetworkIntegrationTestRunner struct {
 	Ctx        context.Context
 	prefix     string
@@ -218,6 +387,7 @@ func (tr *NetworkIntegrationTestRunner) InitNasim(t *testing.T) *Instance {
 	if tr.RegistryHTTPServer != nil {
 		cfg := r.GetConfig()
 		cfg.Registry.Location = tr.RegistryHTTPServer.URL
+		cfg.Registry.ProfileID = tr.registryRepo.GetConfig().Profile.ID
 		r.WriteConfigFile()
 	}
 	tr.nasimRepo = &r
@@ -238,6 +408,7 @@ func (tr *NetworkIntegrationTestRunner) InitHinshun(t *testing.T) *Instance {
 	if tr.RegistryHTTPServer != nil {
 		cfg := r.GetConfig()
 		cfg.Registry.Location = tr.RegistryHTTPServer.URL
+		cfg.Registry.ProfileID = tr.registryRepo.GetConfig().Profile.ID
 		r.WriteConfigFile()
 	}
 	tr.hinshunRepo = &r
@@ -258,6 +429,7 @@ func (tr *NetworkIntegrationTestRunner) InitAdnan(t *testing.T) *Instance {
 	if tr.RegistryHTTPServer != nil {
 		cfg := r.GetConfig()
 		cfg.Registry.Location = tr.RegistryHTTPServer.URL
+		cfg.Registry.ProfileID = tr.registryRepo.GetConfig().Profile.ID
 		r.WriteConfigFile()
 	}
 	tr.adnanRepo = &r
@@ -337,6 +509,89 @@ func AssertLogsEqual(a, b *Instance, ref dsref.Ref) error {
 	return nil
 }
 
+// AssertDatasetBlocksEqual checks that both instances hold the blocks for the
+// dataset version ref refers to in their local stores, and that the content
+// paths of each dataset component match. If ref has no path, each instance
+// resolves ref to its own local head
+func AssertDatasetBlocksEqual(a, b *Instance, ref dsref.Ref) error {
+	ctx := context.Background()
+
+	aComps, err := localComponentPaths(ctx, a, ref)
+	if err != nil {
+		return fmt.Errorf("a instance: %w", err)
+	}
+	bComps, err := localComponentPaths(ctx, b, ref)
+	if err != nil {
+		return fmt.Errorf("b instance: %w", err)
+	}
+
+	if diff := cmp.Diff(aComps, bComps); diff != "" {
+		return fmt.Errorf("dataset component paths mismatch (-a +b):\n%s", diff)
+	}
+	return nil
+}
+
+// localComponentPaths loads a dataset from an instance's local filesystem
+// without touching the network, returning a map of component name to content
+// path. It errors if any component block is missing from the local store
+func localComponentPaths(ctx context.Context, inst *Instance, ref dsref.Ref) (map[string]string, error) {
+	if ref.Path == "" {
+		if _, err := inst.ResolveReference(ctx, &ref, "local"); err != nil {
+			return nil, fmt.Errorf("resolving ref %q: %w", ref, err)
+		}
+	}
+
+	fs := inst.Repo().Filesystem()
+	if has, err := fs.Has(ctx, ref.Path); err != nil {
+		return nil, err
+	} else if !has {
+		return nil, fmt.Errorf("missing dataset block %s", ref.Path)
+	}
+
+	ds, err := dsfs.LoadDataset(ctx, fs, ref.Path)
+	if err != nil {
+		return nil, fmt.Errorf("loading dataset %s: %w", ref.Path, err)
+	}
+
+	comps := map[string]string{"dataset": ds.Path}
+	if ds.Commit != nil {
+		comps["commit"] = ds.Commit.Path
+	}
+	if ds.Meta != nil {
+		comps["meta"] = ds.Meta.Path
+	}
+	if ds.Structure != nil {
+		comps["structure"] = ds.Structure.Path
+	}
+	if ds.Readme != nil {
+		comps["readme"] = ds.Readme.Path
+	}
+	if ds.Transform != nil {
+		comps["transform"] = ds.Transform.Path
+	}
+	if ds.Viz != nil {
+		comps["viz"] = ds.Viz.Path
+	}
+	if ds.Stats != nil {
+		comps["stats"] = ds.Stats.Path
+	}
+	if ds.BodyPath != "" {
+		comps["body"] = ds.BodyPath
+	}
+
+	for name, path := range comps {
+		if path == "" {
+			continue
+		}
+		if has, err := fs.Has(ctx, path); err != nil {
+			return nil, err
+		} else if !has {
+			return nil, fmt.Errorf("missing %s block %s", name, path)
+		}
+	}
+	return comps, nil
+}
+
 func InitWorldBankDataset(ctx context.Context, t *testing.T, inst *Instance) dsref.Ref {
 	res, err := inst.Dataset().Save(ctx, &SaveParams{
 		Ref: "me/world_bank_population",
@@ -91,6 +91,7 @@ type InstanceOptions struct {
 	tokenProvider           token.Provider
 	logAll                  bool
 	automationOptions       *automation.OrchestratorOptions
+	commitMessage           dsfs.CommitMessageFunc
 
 	remoteMockClient bool
 	// use OptRemoteOptions to set this
@@ -291,6 +292,16 @@ func OptTokenProvider(t token.Provider) Option {
 	}
 }
 
+// OptCommitMessageFunc sets a function for generating commit titles &
+// messages when saving. Titles & messages provided in save params take
+// precedence
+func OptCommitMessageFunc(f dsfs.CommitMessageFunc) Option {
+	return func(o *InstanceOptions) error {
+		o.commitMessage = f
+		return nil
+	}
+}
+
 // OptOrchestratorOptions provides orchestrator options for the creation of an Orchestrator
 func OptOrchestratorOptions(a *automation.OrchestratorOptions) Option {
 	return func(o *InstanceOptions) error {
@@ -445,6 +456,7 @@ func NewInstance(ctx context.Context, repoPath string, opts ...Option) (qri *Ins
 		logbook:       o.logbook,
 		keystore:      o.keyStore,
 		tokenProvider: o.tokenProvider,
+		commitMessage: o.commitMessage,
 		dscache:       o.dscache,
 		profiles:      o.profiles,
 		bus:           o.bus,
@@ -503,8 +515,8 @@ func NewInstance(ctx context.Context, repoPath string, opts ...Option) (qri *Ins
 			return nil, err
 		}
 
+		inst.releasers.Add(1)
 		go func() {
-			inst.releasers.Add(1)
 			<-inst.qfs.Done()
 			inst.doneErr = inst.qfs.DoneErr()
 			inst.releasers.Done()
@@ -545,13 +557,23 @@ func NewInstance(ctx context.Context, repoPath string, opts ...Option) (qri *Ins
 	}
 
 	if inst.dscache == nil {
-		inst.dscache, err = newDscache(ctx, inst.qfs, inst.bus, pro.Peername, inst.repoPath)
+		inst.dscache, err = newDscache(ctx, inst.qfs, cfg, inst.bus, pro.Peername, inst.repoPath)
 		if err != nil {
 			log.Error("initalizing dscache:", err.Error())
 			return nil, fmt.Errorf("newDsache: %w", err)
 		}
 	}
 
+	inst.releasers.Add(1)
+	go func() {
+		<-ctx.Done()
+		// write any dscache changes that are waiting on a deferred save
+		if err := inst.dscache.Flush(); err != nil {
+			log.Errorw("flushing dscache", "err", err)
+		}
+		inst.releasers.Done()
+	}()
+
 	if inst.repo == nil {
 		if inst.repo, err = buildrepo.New(ctx, inst.repoPath, cfg, func(o *buildrepo.Options) {
 			o.Bus = inst.bus
@@ -604,9 +626,10 @@ func NewInstance(ctx context.Context, repoPath string, opts ...Option) (qri *Ins
 		if inst.remoteClient, err = newClient(ctx, inst.node, inst.bus); err != nil {
 			return nil, err
 		}
+		trustRegistrySigner(inst.remoteClient, inst.cfg)
 
+		inst.releasers.Add(1)
 		go func() {
-			inst.releasers.Add(1)
 			<-inst.remoteClient.Done()
 			inst.releasers.Done()
 		}()
@@ -657,6 +680,10 @@ func NewInstance(ctx context.Context, repoPath string, opts ...Option) (qri *Ins
 		if err != nil {
 			return nil, err
 		}
+		if cfg.Automation != nil {
+			orchestratorOpts.MaxConcurrentRuns = cfg.Automation.MaxConcurrentRuns
+			orchestratorOpts.MaxQueuedRuns = cfg.Automation.MaxQueuedRuns
+		}
 		o.automationOptions = &orchestratorOpts
 	}
 	inst.automation, err = automation.NewOrchestrator(ctx, inst.bus, &runner{owner: inst}, *o.automationOptions)
@@ -707,9 +734,12 @@ func newLogbook(fs qfs.Filesystem, cfg *config.Config, bus event.Bus, pro *profi
 	return logbook.NewJournal(*pro, bus, fs, logbookPath)
 }
 
-func newDscache(ctx context.Context, fs qfs.Filesystem, bus event.Bus, username, repoPath string) (*dscache.Dscache, error) {
+func newDscache(ctx context.Context, fs qfs.Filesystem, cfg *config.Config, bus event.Bus, username, repoPath string) (*dscache.Dscache, error) {
 	dscachePath := filepath.Join(repoPath, "dscache.qfb")
-	return dscache.NewDscache(ctx, fs, bus, username, dscachePath), nil
+	cache := dscache.NewDscache(ctx, fs, bus, username, dscachePath)
+	cache.CreateNewEnabled = cfg.Repo.DscacheCreateNew
+	cache.SaveDelay = time.Duration(cfg.Repo.DscacheSaveDelayMs) * time.Millisecond
+	return cache, nil
 }
 
 func newEventBus(ctx context.Context) event.Bus {
@@ -818,6 +848,7 @@ func NewInstanceFromConfigAndNodeAndBusAndOrchestratorOpts(ctx context.Context,
 		cancel()
 		panic(err)
 	}
+	trustRegistrySigner(inst.remoteClient, inst.cfg)
 
 	set, err := collection.NewLocalSet(ctx, "", func(o *collection.LocalSetOptions) {
 		o.MigrateRepo = inst.repo
@@ -868,6 +899,7 @@ type Instance struct {
 	automation    *automation.Orchestrator
 	compStat      *base.ComponentStatus
 	tokenProvider token.Provider
+	commitMessage dsfs.CommitMessageFunc
 	bus           event.Bus
 	appCtx        context.Context
 
@@ -878,6 +910,8 @@ type Instance struct {
 
 	http *qhttp.Client
 
+	writeProbe writeProbeCache
+
 	cancel    context.CancelFunc
 	doneCh    chan struct{}
 	doneErr   error
@@ -915,8 +949,9 @@ func (inst *Instance) ConnectP2P(ctx context.Context) (err error) {
 		log.Debugf("remote.NewClient error=%q", err)
 		return
 	}
+	trustRegistrySigner(inst.remoteClient, inst.cfg)
+	inst.releasers.Add(1)
 	go func() {
-		inst.releasers.Add(1)
 		<-inst.remoteClient.Done()
 		inst.releasers.Done()
 	}()
@@ -953,8 +988,8 @@ func (inst *Instance) AutomationListen(ctx context.Context) (err error) {
 	if err != nil {
 		return
 	}
+	inst.releasers.Add(1)
 	go func() {
-		inst.releasers.Add(1)
 		<-inst.automation.Done()
 		inst.releasers.Done()
 	}()
@@ -1027,6 +1062,11 @@ func (inst *Instance) Search() SearchMethods {
 	return SearchMethods{d: inst}
 }
 
+// Transform returns the TransformMethods that Instance has registered
+func (inst *Instance) Transform() TransformMethods {
+	return TransformMethods{d: inst}
+}
+
 // WithSource returns a wrapped instance that will resolve refs from the given source
 func (inst *Instance) WithSource(source string) *InstanceSourceWrap {
 	return &InstanceSourceWrap{
@@ -1273,3 +1313,20 @@ func (inst *Instance) waitForAllDone() {
 	log.Debug("closing instance")
 	close(inst.doneCh)
 }
+
+// trustRegistrySigner configures a remote client to accept previews signed by
+// the configured registry
+func trustRegistrySigner(cli remote.Client, cfg *config.Config) {
+	if cli == nil || cfg == nil || cfg.Registry == nil || cfg.Registry.ProfileID == "" {
+		return
+	}
+	pid, err := profile.IDB58Decode(cfg.Registry.ProfileID)
+	if err != nil {
+		log.Errorf("invalid registry profile ID %q: %s", cfg.Registry.ProfileID, err)
+		return
+	}
+	cli.TrustSigner(cfg.Registry.Location, pid)
+	for _, addr := range cfg.Registry.Fallbacks {
+		cli.TrustSigner(addr, pid)
+	}
+}
@@ -1,6 +1,7 @@
 package lib
 
 import (
+	"bytes"
 	"context"
 	"fmt"
 	"io/ioutil"
@@ -23,6 +24,7 @@ import (
 	"github.com/qri-io/qri/collection"
 	"github.com/qri-io/qri/config"
 	testcfg "github.com/qri-io/qri/config/test"
+	"github.com/qri-io/qri/dscache"
 	"github.com/qri-io/qri/dsref"
 	"github.com/qri-io/qri/event"
 	"github.com/qri-io/qri/logbook"
@@ -113,6 +115,55 @@ func TestNewInstance(t *testing.T) {
 	<-finished
 }
 
+func TestInstanceShutdownFlushesDscache(t *testing.T) {
+	tr, err := repotest.NewTempRepo("foo", "dscache_flush_test", repotest.NewTestCrypto())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tr.Delete()
+
+	cfg := testcfg.DefaultConfigForTesting()
+	cfg.Filesystems = []qfs.Config{
+		{Type: "mem"},
+		{Type: "local"},
+	}
+	cfg.Repo.Type = "mem"
+	cfg.Repo.DscacheSaveDelayMs = int(time.Hour / time.Millisecond)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	inst, err := NewInstance(ctx, tr.QriPath, OptConfig(cfg))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if inst.dscache.SaveDelay != time.Hour {
+		t.Errorf("expected dscache save delay to be configured to %s, got: %s", time.Hour, inst.dscache.SaveDelay)
+	}
+
+	builder := dscache.NewBuilder()
+	builder.AddUser("foo", "QmeL2mdVka1eahKENjehK6tBxkkpk5dNQ1qMcgWi7Hrb4B")
+	if err := inst.dscache.Assign(builder.Build()); err != nil {
+		t.Fatal(err)
+	}
+	filename := filepath.Join(tr.QriPath, "dscache.qfb")
+	if _, err := os.Stat(filename); !os.IsNotExist(err) {
+		t.Fatalf("expected dscache write to be deferred, got: %v", err)
+	}
+
+	select {
+	case <-inst.Shutdown():
+	case <-time.After(time.Second):
+		t.Fatal("timed out waiting for instance to shut down")
+	}
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		t.Fatalf("expected shutdown to write pending dscache changes: %s", err)
+	}
+	if !bytes.Equal(data, inst.dscache.Buffer) {
+		t.Error("expected written dscache to match pending changes")
+	}
+}
+
 func TestNewDefaultInstance(t *testing.T) {
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
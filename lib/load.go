@@ -2,14 +2,18 @@ package lib
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"net"
+	"time"
 
 	"github.com/qri-io/dataset"
 	"github.com/qri-io/qri/base"
 	"github.com/qri-io/qri/base/dsfs"
 	"github.com/qri-io/qri/dsref"
 	qerr "github.com/qri-io/qri/errors"
+	"github.com/qri-io/qri/p2p"
 )
 
 type datasetLoader struct {
@@ -115,3 +119,130 @@ func (d *datasetLoader) loadLocalDataset(ctx context.Context, ref dsref.Ref) (*d
 
 	return ds, nil
 }
+
+// localOnlyLoader is a dsref.Loader for transforms that may not pull
+// datasets. It wraps a loader that only resolves local datasets, explaining
+// why datasets that aren't stored locally can't be found. Errors still wrap
+// dsref.ErrRefNotFound, so loading a dataset's own missing history isn't a
+// failure
+type localOnlyLoader struct {
+	loader dsref.Loader
+}
+
+// LoadDataset implements the dsref.Loader interface
+func (l *localOnlyLoader) LoadDataset(ctx context.Context, refstr string) (*dataset.Dataset, error) {
+	ds, err := l.loader.LoadDataset(ctx, refstr)
+	if errors.Is(err, dsref.ErrRefNotFound) {
+		err = fmt.Errorf("%w: %q isn't stored locally and transform dataset pulling is disabled", err, refstr)
+		return nil, qerr.New(err, err.Error())
+	}
+	return ds, err
+}
+
+// defaultLoadRetryBackoff is the wait before the first retry of a load when
+// a retry policy doesn't specify one
+const defaultLoadRetryBackoff = 500 * time.Millisecond
+
+// LoadRetryPolicy configures retrying dataset loads that fail for transient
+// reasons, like a source peer being briefly unreachable. Loads that fail
+// permanently, like a reference that doesn't exist, are never retried
+type LoadRetryPolicy struct {
+	// Attempts is the total number of tries, including the first. values
+	// below two disable retrying
+	Attempts int `json:"attempts"`
+	// Backoff is the wait before the first retry, doubling for each retry
+	// that follows. zero uses a default of half a second. JSON encodes backoff
+	// as a duration string like "500ms"
+	Backoff time.Duration `json:"backoff"`
+}
+
+// loadRetryPolicyJSON is the JSON encoding of a LoadRetryPolicy
+type loadRetryPolicyJSON struct {
+	Attempts int             `json:"attempts"`
+	Backoff  json.RawMessage `json:"backoff,omitempty"`
+}
+
+// MarshalJSON encodes the policy with backoff as a duration string
+func (p LoadRetryPolicy) MarshalJSON() ([]byte, error) {
+	backoff, err := json.Marshal(p.Backoff.String())
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(loadRetryPolicyJSON{Attempts: p.Attempts, Backoff: backoff})
+}
+
+// UnmarshalJSON decodes a policy, reading backoff as either a duration string
+// or a number of nanoseconds
+func (p *LoadRetryPolicy) UnmarshalJSON(data []byte) error {
+	v := loadRetryPolicyJSON{}
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	p.Attempts = v.Attempts
+	p.Backoff = 0
+	if len(v.Backoff) == 0 || string(v.Backoff) == "null" {
+		return nil
+	}
+
+	var str string
+	if err := json.Unmarshal(v.Backoff, &str); err == nil {
+		d, err := time.ParseDuration(str)
+		if err != nil {
+			return fmt.Errorf("invalid load retry backoff %q: %w", str, err)
+		}
+		p.Backoff = d
+		return nil
+	}
+	var ns int64
+	if err := json.Unmarshal(v.Backoff, &ns); err != nil {
+		return fmt.Errorf("invalid load retry backoff %s: must be a duration string", v.Backoff)
+	}
+	p.Backoff = time.Duration(ns)
+	return nil
+}
+
+// withLoadRetries wraps a loader to retry transient failures according to a
+// retry policy, returning the loader unchanged if the policy doesn't retry
+func withLoadRetries(loader dsref.Loader, attempts int, backoff time.Duration) dsref.Loader {
+	if attempts < 2 {
+		return loader
+	}
+	if backoff <= 0 {
+		backoff = defaultLoadRetryBackoff
+	}
+	return &retryLoader{loader: loader, attempts: attempts, backoff: backoff}
+}
+
+// retryLoader is a dsref.Loader that retries transient load failures with
+// exponential backoff
+type retryLoader struct {
+	loader   dsref.Loader
+	attempts int
+	backoff  time.Duration
+}
+
+// LoadDataset implements the dsref.Loader interface
+func (l *retryLoader) LoadDataset(ctx context.Context, refstr string) (*dataset.Dataset, error) {
+	wait := l.backoff
+	for attempt := 1; ; attempt++ {
+		ds, err := l.loader.LoadDataset(ctx, refstr)
+		if err == nil || attempt >= l.attempts || !isTransientLoadError(err) {
+			return ds, err
+		}
+		log.Debugw("retrying transient load failure", "ref", refstr, "attempt", attempt, "wait", wait, "err", err)
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(wait):
+		}
+		wait *= 2
+	}
+}
+
+// isTransientLoadError reports whether a load failure might succeed if tried
+// again. Network failures are transient, everything else is permanent
+func isTransientLoadError(err error) bool {
+	var netErr net.Error
+	return errors.As(err, &netErr) || errors.Is(err, p2p.ErrNodeOffline)
+}
@@ -1,8 +1,14 @@
 package lib
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net"
 	"testing"
+	"time"
 
+	"github.com/google/go-cmp/cmp"
 	"github.com/qri-io/dataset"
 	"github.com/qri-io/qri/base/dsfs"
 	"github.com/qri-io/qri/dsref"
@@ -62,3 +68,104 @@ func TestLoadDataset(t *testing.T) {
 		return ref, nil
 	})
 }
+
+// flakyLoader fails with a queue of errors before loading successfully
+type flakyLoader struct {
+	errs  []error
+	calls int
+}
+
+func (l *flakyLoader) LoadDataset(ctx context.Context, refstr string) (*dataset.Dataset, error) {
+	l.calls++
+	if len(l.errs) > 0 {
+		err := l.errs[0]
+		l.errs = l.errs[1:]
+		return nil, err
+	}
+	return &dataset.Dataset{Name: "loaded"}, nil
+}
+
+func TestLoadRetries(t *testing.T) {
+	ctx := context.Background()
+	unreachable := &net.OpError{Op: "dial", Net: "tcp", Err: errors.New("connection refused")}
+
+	flaky := &flakyLoader{errs: []error{unreachable}}
+	loader := withLoadRetries(flaky, 3, time.Millisecond)
+	ds, err := loader.LoadDataset(ctx, "peer/cities")
+	if err != nil {
+		t.Fatalf("expected retry to succeed, got: %s", err)
+	}
+	if ds.Name != "loaded" {
+		t.Errorf("expected loaded dataset, got: %#v", ds)
+	}
+	if flaky.calls != 2 {
+		t.Errorf("expected flaky loader to succeed on the second attempt, got %d calls", flaky.calls)
+	}
+
+	// permanent errors aren't retried
+	flaky = &flakyLoader{errs: []error{dsref.ErrRefNotFound, unreachable}}
+	loader = withLoadRetries(flaky, 3, time.Millisecond)
+	if _, err := loader.LoadDataset(ctx, "peer/cities"); !errors.Is(err, dsref.ErrRefNotFound) {
+		t.Errorf("expected error %q, got: %v", dsref.ErrRefNotFound, err)
+	}
+	if flaky.calls != 1 {
+		t.Errorf("expected not found error to fail without retrying, got %d calls", flaky.calls)
+	}
+
+	// retrying stops after the configured number of attempts
+	flaky = &flakyLoader{errs: []error{unreachable, unreachable, unreachable}}
+	loader = withLoadRetries(flaky, 2, time.Millisecond)
+	if _, err := loader.LoadDataset(ctx, "peer/cities"); !errors.Is(err, unreachable) {
+		t.Errorf("expected error %q, got: %v", unreachable, err)
+	}
+	if flaky.calls != 2 {
+		t.Errorf("expected 2 attempts, got %d calls", flaky.calls)
+	}
+
+	// cancelling the context while waiting to retry returns the context error
+	cctx, cancel := context.WithCancel(ctx)
+	cancel()
+	flaky = &flakyLoader{errs: []error{unreachable, unreachable}}
+	loader = withLoadRetries(flaky, 3, time.Minute)
+	if _, err := loader.LoadDataset(cctx, "peer/cities"); !errors.Is(err, context.Canceled) {
+		t.Errorf("expected error %q, got: %v", context.Canceled, err)
+	}
+
+	flaky = &flakyLoader{}
+	if withLoadRetries(flaky, 1, time.Second) != dsref.Loader(flaky) {
+		t.Errorf("expected a single attempt policy to return the loader unchanged")
+	}
+}
+
+func TestLoadRetryPolicyJSON(t *testing.T) {
+	data, err := json.Marshal(LoadRetryPolicy{Attempts: 3, Backoff: 250 * time.Millisecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(`{"attempts":3,"backoff":"250ms"}`, string(data)); diff != "" {
+		t.Errorf("encoding mismatch (-want +got):\n%s", diff)
+	}
+
+	cases := []struct {
+		data   string
+		expect LoadRetryPolicy
+	}{
+		{`{"attempts":3,"backoff":"250ms"}`, LoadRetryPolicy{Attempts: 3, Backoff: 250 * time.Millisecond}},
+		{`{"attempts":2,"backoff":"1s"}`, LoadRetryPolicy{Attempts: 2, Backoff: time.Second}},
+		{`{"attempts":2,"backoff":1000000}`, LoadRetryPolicy{Attempts: 2, Backoff: time.Millisecond}},
+		{`{"attempts":2}`, LoadRetryPolicy{Attempts: 2}},
+	}
+	for _, c := range cases {
+		got := LoadRetryPolicy{}
+		if err := json.Unmarshal([]byte(c.data), &got); err != nil {
+			t.Fatalf("decoding %s: %s", c.data, err)
+		}
+		if diff := cmp.Diff(c.expect, got); diff != "" {
+			t.Errorf("decoding %s mismatch (-want +got):\n%s", c.data, diff)
+		}
+	}
+
+	if err := json.Unmarshal([]byte(`{"backoff":"soon"}`), &LoadRetryPolicy{}); err == nil {
+		t.Error("expected an invalid duration string to fail decoding")
+	}
+}
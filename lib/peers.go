@@ -311,8 +311,8 @@ func (peerImpl) Disconnect(scope scope, p *ConnectParamsPod) error {
 // Connections lists PeerID's we're currently connected to. If running
 // IPFS this will also return connected IPFS nodes
 func (peerImpl) Connections(scope scope, p *ConnectionsParams) ([]string, error) {
-	// TODO (ramfox): limit and offset not currently used
-	return scope.Node().ConnectedPeers(), nil
+	peers, _ := scope.Node().ConnectedPeersPage(p.Offset, p.Limit)
+	return peers, nil
 }
 
 // ConnectedQriProfiles lists profiles we're currently connected to
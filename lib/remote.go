@@ -6,6 +6,7 @@ import (
 
 	"github.com/qri-io/dataset"
 	"github.com/qri-io/qri/base"
+	"github.com/qri-io/qri/base/params"
 	"github.com/qri-io/qri/dsref"
 	qhttp "github.com/qri-io/qri/lib/http"
 	"github.com/qri-io/qri/remote"
@@ -27,9 +28,10 @@ func (m RemoteMethods) Name() string {
 // Attributes defines attributes for each method
 func (m RemoteMethods) Attributes() map[string]AttributeSet {
 	return map[string]AttributeSet{
-		"feeds":   {Endpoint: qhttp.AEFeeds, HTTPVerb: "POST"},
-		"preview": {Endpoint: qhttp.AEPreview, HTTPVerb: "POST"},
-		"remove":  {Endpoint: qhttp.AERemoteRemove, HTTPVerb: "POST", DefaultSource: "network"},
+		"feeds":      {Endpoint: qhttp.AEFeeds, HTTPVerb: "POST"},
+		"listbypeer": {Endpoint: qhttp.AERemoteListByPeer, HTTPVerb: "POST"},
+		"preview":    {Endpoint: qhttp.AEPreview, HTTPVerb: "POST"},
+		"remove":     {Endpoint: qhttp.AERemoteRemove, HTTPVerb: "POST", DefaultSource: "network"},
 	}
 }
 
@@ -43,6 +45,31 @@ func (m RemoteMethods) Feeds(ctx context.Context, p *EmptyParams) (map[string][]
 	return nil, dispatchReturnError(got, err)
 }
 
+// ListByPeerParams provides arguments to the listbypeer method
+type ListByPeerParams struct {
+	params.List
+	Peername string `json:"peername"`
+}
+
+// SetNonZeroDefaults sets a default limit and offset
+func (p *ListByPeerParams) SetNonZeroDefaults() {
+	if p.Offset < 0 {
+		p.Offset = 0
+	}
+	if p.Limit <= 0 {
+		p.Limit = params.DefaultListLimit
+	}
+}
+
+// ListByPeer lists the datasets a remote holds for a peer
+func (m RemoteMethods) ListByPeer(ctx context.Context, p *ListByPeerParams) ([]dsref.VersionInfo, error) {
+	got, _, err := m.d.Dispatch(ctx, dispatchMethodName(m, "listbypeer"), p)
+	if res, ok := got.([]dsref.VersionInfo); ok {
+		return res, err
+	}
+	return nil, dispatchReturnError(got, err)
+}
+
 // PreviewParams provides arguments to the preview method
 type PreviewParams struct {
 	Ref string `json:"ref"`
@@ -57,7 +84,8 @@ func (m RemoteMethods) Preview(ctx context.Context, p *PreviewParams) (*dataset.
 	return nil, dispatchReturnError(got, err)
 }
 
-// Remove asks a remote to remove a dataset
+// Remove asks a remote to remove a dataset, unpublishing it. Only the dataset
+// owner can remove a dataset from a remote
 func (m RemoteMethods) Remove(ctx context.Context, p *PushParams) (*dsref.Ref, error) {
 	got, _, err := m.d.Dispatch(ctx, dispatchMethodName(m, "remove"), p)
 	if res, ok := got.(*dsref.Ref); ok {
@@ -84,6 +112,20 @@ func (remoteImpl) Feeds(scope scope, p *EmptyParams) (map[string][]dsref.Version
 	return feed, nil
 }
 
+// ListByPeer lists the datasets a remote holds for a peer
+func (remoteImpl) ListByPeer(scope scope, p *ListByPeerParams) ([]dsref.VersionInfo, error) {
+	if p.Peername == "" {
+		return nil, fmt.Errorf("peername is required")
+	}
+
+	addr, err := remote.Address(scope.Config(), scope.SourceName())
+	if err != nil {
+		return nil, err
+	}
+
+	return scope.RemoteClient().ListPeerDatasets(scope.Context(), addr, p.Peername, p.List)
+}
+
 // Preview requests a dataset preview from a remote
 func (remoteImpl) Preview(scope scope, p *PreviewParams) (*dataset.Dataset, error) {
 	ref, err := dsref.Parse(p.Ref)
@@ -119,6 +161,9 @@ func (remoteImpl) Remove(scope scope, p *PushParams) (*dsref.Ref, error) {
 	if _, err := scope.ResolveReference(scope.Context(), &ref); err != nil {
 		return nil, err
 	}
+	if !base.InAuthorNamespace(scope.Context(), author, ref) {
+		return nil, fmt.Errorf("can't remove datasets that are not in your namespace")
+	}
 
 	addr, err := remote.Address(scope.Config(), p.Remote)
 	if err != nil {
@@ -3,6 +3,7 @@ package lib
 import (
 	"context"
 	"fmt"
+	"strings"
 
 	"github.com/qri-io/qri/dsref"
 	"github.com/qri-io/qri/remote"
@@ -46,24 +47,53 @@ func (inst *Instance) ResolveReference(ctx context.Context, ref *dsref.Ref, sour
 	return resolver.ResolveRef(ctx, ref)
 }
 
+// ResolveSource resolves a reference like ResolveReference, also reporting
+// which subsystem the reference resolved from
+func (inst *Instance) ResolveSource(ctx context.Context, ref *dsref.Ref, source string) (resolvedFrom, location string, err error) {
+	log.Debugf("inst.ResolveSource ref=%q source=%q", ref, source)
+	if inst == nil {
+		return "", "", dsref.ErrRefNotFound
+	}
+	if ref.Username == "me" {
+		ref.Username = inst.cfg.Profile.Peername
+	}
+
+	resolver, err := inst.taggedResolverForSource(source, tagResolver)
+	if err != nil {
+		return "", "", err
+	}
+	tagged, err := resolver.ResolveRef(ctx, ref)
+	if err != nil {
+		return "", "", err
+	}
+	resolvedFrom, location = untagLocation(tagged)
+	return resolvedFrom, location, nil
+}
+
 func (inst *Instance) resolverForSource(source string) (dsref.Resolver, error) {
+	return inst.taggedResolverForSource(source, func(_ string, r dsref.Resolver) dsref.Resolver { return r })
+}
+
+// taggedResolverForSource composes the resolvers for a source, passing each
+// one through tag along with the name of the subsystem it resolves from
+func (inst *Instance) taggedResolverForSource(source string, tag func(resolvedFrom string, r dsref.Resolver) dsref.Resolver) (dsref.Resolver, error) {
 	switch source {
 	case "":
-		return inst.defaultResolver(), nil
+		return inst.defaultResolver(tag), nil
 	case "local":
 		return dsref.SequentialResolver(
-			inst.dscache,
-			inst.repo,
+			tag(ResolvedFromDscache, inst.dscache),
+			tag(ResolvedFromLocal, inst.repo),
 		), nil
 	case "network":
 		return dsref.ParallelResolver(
-			inst.registryResolver(),
-			inst.p2pResolver(),
+			tag(ResolvedFromRegistry, inst.registryResolver()),
+			tag(ResolvedFromPeer, inst.p2pResolver()),
 		), nil
 	case "registry":
-		return inst.registryResolver(), nil
+		return tag(ResolvedFromRegistry, inst.registryResolver()), nil
 	case "p2p":
-		return inst.p2pResolver(), nil
+		return tag(ResolvedFromPeer, inst.p2pResolver()), nil
 	}
 
 	// TODO (b5) - source could be one of:
@@ -75,15 +105,15 @@ func (inst *Instance) resolverForSource(source string) (dsref.Resolver, error) {
 	if err != nil {
 		return nil, err
 	}
-	return inst.remoteClient.NewRemoteRefResolver(addr), nil
+	return tag(ResolvedFromPeer, inst.remoteClient.NewRemoteRefResolver(addr)), nil
 }
 
-func (inst *Instance) defaultResolver() dsref.Resolver {
+func (inst *Instance) defaultResolver(tag func(resolvedFrom string, r dsref.Resolver) dsref.Resolver) dsref.Resolver {
 	return dsref.SequentialResolver(
-		inst.dscache,
-		inst.repo,
+		tag(ResolvedFromDscache, inst.dscache),
+		tag(ResolvedFromLocal, inst.repo),
 		dsref.ParallelResolver(
-			inst.registryResolver(),
+			tag(ResolvedFromRegistry, inst.registryResolver()),
 			// inst.node,
 		),
 	)
@@ -100,3 +130,50 @@ func (inst *Instance) registryResolver() dsref.Resolver {
 func (inst *Instance) p2pResolver() dsref.Resolver {
 	return inst.node.NewP2PRefResolver()
 }
+
+const (
+	// ResolvedFromLocal indicates a reference resolved from the local repo
+	ResolvedFromLocal = "local"
+	// ResolvedFromDscache indicates a reference resolved from the dscache
+	ResolvedFromDscache = "dscache"
+	// ResolvedFromRegistry indicates a reference resolved from the configured
+	// registry
+	ResolvedFromRegistry = "registry"
+	// ResolvedFromPeer indicates a reference resolved from a peer or remote
+	// other than the registry
+	ResolvedFromPeer = "peer"
+)
+
+// tagSeparator splits a tagged location into the resolver name & location
+const tagSeparator = "\x00"
+
+// taggedResolver prefixes the locations a resolver returns with the name of
+// the subsystem it resolves from. Composed resolvers pass the location of the
+// resolver that answered through, so the tag reports the resolver actually used
+type taggedResolver struct {
+	resolvedFrom string
+	r            dsref.Resolver
+}
+
+func tagResolver(resolvedFrom string, r dsref.Resolver) dsref.Resolver {
+	if r == nil {
+		return nil
+	}
+	return taggedResolver{resolvedFrom: resolvedFrom, r: r}
+}
+
+// ResolveRef implements the dsref.Resolver interface
+func (t taggedResolver) ResolveRef(ctx context.Context, ref *dsref.Ref) (string, error) {
+	location, err := t.r.ResolveRef(ctx, ref)
+	if err != nil {
+		return "", err
+	}
+	return t.resolvedFrom + tagSeparator + location, nil
+}
+
+func untagLocation(tagged string) (resolvedFrom, location string) {
+	if i := strings.Index(tagged, tagSeparator); i >= 0 {
+		return tagged[:i], tagged[i+len(tagSeparator):]
+	}
+	return "", tagged
+}
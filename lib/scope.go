@@ -7,6 +7,7 @@ import (
 	"github.com/qri-io/qri/automation"
 	"github.com/qri-io/qri/automation/workflow"
 	"github.com/qri-io/qri/base"
+	"github.com/qri-io/qri/base/dsfs"
 	"github.com/qri-io/qri/collection"
 	"github.com/qri-io/qri/config"
 	"github.com/qri-io/qri/dscache"
@@ -103,6 +104,64 @@ func (s *scope) Config() *config.Config {
 	return s.inst.cfg
 }
 
+// AllowedHTTPDomains returns the domains transform scripts may make http
+// requests to. nil means all domains are allowed
+func (s *scope) AllowedHTTPDomains() []string {
+	cfg := s.inst.cfg
+	if cfg == nil || cfg.Automation == nil || len(cfg.Automation.AllowedHTTPDomains) == 0 {
+		return nil
+	}
+	return cfg.Automation.AllowedHTTPDomains
+}
+
+// BodyDiffThreshold returns the largest body size saves & transforms will diff
+// to describe body changes. 0 means the default threshold
+func (s *scope) BodyDiffThreshold() int {
+	cfg := s.inst.cfg
+	if cfg == nil || cfg.Automation == nil {
+		return 0
+	}
+	return cfg.Automation.BodyDiffThreshold
+}
+
+// VerifyBodyChecksum returns whether transforms confirm bodies reported as
+// unchanged by comparing checksums with the previous version
+func (s *scope) VerifyBodyChecksum() bool {
+	cfg := s.inst.cfg
+	if cfg == nil || cfg.Automation == nil {
+		return false
+	}
+	return cfg.Automation.VerifyBodyChecksum
+}
+
+// PrettyJSON returns whether saved datasets write indented JSON metadata files
+func (s *scope) PrettyJSON() bool {
+	cfg := s.inst.cfg
+	if cfg == nil || cfg.Repo == nil {
+		return false
+	}
+	return cfg.Repo.PrettyJSON
+}
+
+// SignTransforms returns whether saved datasets sign their transform scripts
+func (s *scope) SignTransforms() bool {
+	cfg := s.inst.cfg
+	if cfg == nil || cfg.Repo == nil {
+		return false
+	}
+	return cfg.Repo.SignTransforms
+}
+
+// VerifyTransformSignatures returns whether loading a dataset requires its
+// transform script to have a verifiable signature
+func (s *scope) VerifyTransformSignatures() bool {
+	cfg := s.inst.cfg
+	if cfg == nil || cfg.Repo == nil {
+		return false
+	}
+	return cfg.Repo.VerifyTransformSignatures
+}
+
 // Context returns the context for this scope. Though this pattern is usually
 // discouraged, we're following http.Request's lead, as scope plays the same
 // role. The lifetime of a single scope matches the lifetime of the Context;
@@ -167,6 +226,17 @@ func (s *scope) Loader() dsref.Loader {
 	return newDatasetLoader(s.inst, username, s.source)
 }
 
+// TransformLoader returns the loader transforms use to load datasets. When
+// transform pulling is disabled in the automation config, the loader only
+// loads datasets that are stored locally
+func (s *scope) TransformLoader() dsref.Loader {
+	cfg := s.inst.cfg
+	if cfg == nil || cfg.Automation == nil || !cfg.Automation.DisableTransformPulling {
+		return s.Loader()
+	}
+	return &localOnlyLoader{loader: newDatasetLoader(s.inst, cfg.Profile.Peername, "local")}
+}
+
 // Logbook returns the repo logbook
 func (s *scope) Logbook() *logbook.Book {
 	return s.inst.logbook
@@ -221,6 +291,12 @@ func (s *scope) ResolveReference(ctx context.Context, ref *dsref.Ref) (string, e
 	return s.inst.ResolveReference(ctx, ref, s.source)
 }
 
+// ResolveSource finds the identifier & HEAD path for a dataset reference,
+// also reporting the subsystem that resolved it
+func (s *scope) ResolveSource(ctx context.Context, ref *dsref.Ref) (resolvedFrom, location string, err error) {
+	return s.inst.ResolveSource(ctx, ref, s.source)
+}
+
 // LocalResolver returns a resolver for local refs
 func (s *scope) LocalResolver() (dsref.Resolver, error) {
 	return s.inst.resolverForSource("local")
@@ -241,6 +317,11 @@ func (s *scope) Stats() *stats.Service {
 	return s.inst.stats
 }
 
+// CommitMessageFunc returns the instance's commit message generator, if any
+func (s *scope) CommitMessageFunc() dsfs.CommitMessageFunc {
+	return s.inst.commitMessage
+}
+
 // UseDscache returns whether dscache should be generated
 // TODO(dustmop): Add a config option or environment variable to experimentally
 // enable the dscache
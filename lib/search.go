@@ -70,8 +70,33 @@ func (searchImpl) Search(scope scope, p *SearchParams) ([]registry.SearchResult,
 	}
 
 	regResults, err := client.Search(scope.Context(), params)
-	if err != nil {
-		return nil, err
+	if err == nil {
+		return withSnippets(regResults, p.Query), nil
 	}
-	return regResults, nil
+
+	// try any configured fallback registries in order, returning the first
+	// successful response
+	if cfg := scope.Config(); cfg != nil && cfg.Registry != nil {
+		for _, loc := range cfg.Registry.Fallbacks {
+			log.Debugw("search failed, trying fallback registry", "error", err, "location", loc)
+			fallback := regclient.NewClient(&regclient.Config{Location: loc})
+			regResults, fbErr := fallback.Search(scope.Context(), params)
+			if fbErr == nil {
+				return withSnippets(regResults, p.Query), nil
+			}
+			err = fbErr
+		}
+	}
+	return nil, err
+}
+
+// withSnippets computes matched-field snippets for any results the registry
+// didn't provide snippets for
+func withSnippets(results []registry.SearchResult, query string) []registry.SearchResult {
+	for i, r := range results {
+		if len(r.Snippets) == 0 {
+			results[i].Snippets = registry.Snippets(r.Value, query)
+		}
+	}
+	return results
 }
@@ -6,11 +6,13 @@ import (
 	"net/http/httptest"
 	"testing"
 
+	"github.com/google/go-cmp/cmp"
 	"github.com/qri-io/qri/base/params"
 	"github.com/qri-io/qri/config"
 	testcfg "github.com/qri-io/qri/config/test"
 	"github.com/qri-io/qri/event"
 	"github.com/qri-io/qri/p2p"
+	"github.com/qri-io/qri/registry"
 	"github.com/qri-io/qri/registry/regclient"
 	testrepo "github.com/qri-io/qri/repo/test"
 )
@@ -46,6 +48,91 @@ func TestSearch(t *testing.T) {
 	}
 }
 
+func TestSearchRegistryFallback(t *testing.T) {
+	ctx, done := context.WithCancel(context.Background())
+	defer done()
+
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte(`{"meta":{"code":500,"error":"registry unavailable"}}`))
+	}))
+	defer failing.Close()
+	answering := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write(mockResponse)
+	}))
+	defer answering.Close()
+
+	mr, err := testrepo.NewTestRepo()
+	if err != nil {
+		t.Fatalf("error allocating test repo: %s", err.Error())
+	}
+	node, err := p2p.NewQriNode(mr, testcfg.DefaultP2PForTesting(), event.NilBus, nil)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	cfg := config.DefaultConfig()
+	cfg.Registry.Location = failing.URL
+	cfg.Registry.Fallbacks = []string{answering.URL}
+	inst := NewInstanceFromConfigAndNode(ctx, cfg, node)
+	inst.registry = regclient.NewClient(&regclient.Config{Location: failing.URL})
+
+	p := &SearchParams{Query: "nuun", List: params.List{Offset: 0, Limit: 100}}
+	got, err := inst.Search().Search(ctx, p)
+	if err != nil {
+		t.Fatalf("expected fallback registry to answer, got error: %s", err)
+	}
+	if 1 != len(got) {
+		t.Errorf("expected: %d results, got: %d", 1, len(got))
+	}
+
+	cfg.Registry.Fallbacks = []string{failing.URL}
+	if _, err := inst.Search().Search(ctx, p); err == nil {
+		t.Errorf("expected error when all registries fail")
+	}
+}
+
+func TestSearchSnippets(t *testing.T) {
+	ctx, done := context.WithCancel(context.Background())
+	defer done()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"data":[{
+			"type": "dataset",
+			"id": "/ipfs/QmZEnjt3Y5RxXsoZyufJfFzcogicBEwfaimJSyDuC7nySA",
+			"value": {
+				"peername": "nuun",
+				"name": "weather",
+				"meta": {
+					"title": "daily readings",
+					"description": "Temperature and rainfall readings collected from stations across the Great Lakes region, updated every morning"
+				}
+			}
+		}],"meta":{"code":200}}`))
+	}))
+	defer server.Close()
+
+	node := newTestQriNode(t)
+	inst := NewInstanceFromConfigAndNode(ctx, config.DefaultConfig(), node)
+	inst.registry = regclient.NewClient(&regclient.Config{Location: server.URL})
+
+	p := &SearchParams{Query: "great lakes", List: params.List{Offset: 0, Limit: 100}}
+	got, err := inst.Search().Search(ctx, p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 1 {
+		t.Fatalf("expected: %d results, got: %d", 1, len(got))
+	}
+
+	expect := []registry.SearchSnippet{
+		{Field: "meta.description", Snippet: "...collected from stations across the <mark>Great Lakes</mark> region, updated every morning"},
+	}
+	if diff := cmp.Diff(expect, got[0].Snippets); diff != "" {
+		t.Errorf("snippets mismatch (-want +got):\n%s", diff)
+	}
+}
+
 var mockResponse = []byte(`{"data":[
   {
     "type": "dataset",
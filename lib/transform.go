@@ -0,0 +1,141 @@
+package lib
+
+import (
+	"context"
+	"sort"
+
+	"github.com/qri-io/qri/base/params"
+	"github.com/qri-io/qri/dsref"
+	qhttp "github.com/qri-io/qri/lib/http"
+	"github.com/qri-io/qri/registry/regclient"
+	reporef "github.com/qri-io/qri/repo/ref"
+	"github.com/qri-io/qri/transform/startf"
+)
+
+// TransformMethods groups together methods for working with transform scripts
+type TransformMethods struct {
+	d dispatcher
+}
+
+// Name returns the name of this method group
+func (m TransformMethods) Name() string {
+	return "transform"
+}
+
+// Attributes defines attributes for each method
+func (m TransformMethods) Attributes() map[string]AttributeSet {
+	return map[string]AttributeSet{
+		"builtins":          {Endpoint: qhttp.DenyHTTP}, // builtins is served over GET by the api package's `TransformBuiltinsHandler`
+		"availabledatasets": {Endpoint: qhttp.AETransformDatasets, HTTPVerb: "POST"},
+	}
+}
+
+// BuiltinsParams are parameters for listing transform builtins
+type BuiltinsParams struct{}
+
+// Builtins lists the starlark modules transform scripts can load, along with
+// the values each module exports
+func (m TransformMethods) Builtins(ctx context.Context, p *BuiltinsParams) ([]startf.ModuleDoc, error) {
+	got, _, err := m.d.Dispatch(ctx, dispatchMethodName(m, "builtins"), p)
+	if res, ok := got.([]startf.ModuleDoc); ok {
+		return res, err
+	}
+	return nil, dispatchReturnError(got, err)
+}
+
+// AvailableDatasetsParams are parameters for listing the datasets transforms
+// can load
+type AvailableDatasetsParams struct {
+	// Query, when set, adds datasets from the registry that match the query
+	Query string `json:"q"`
+}
+
+// LoadableDataset is a dataset a transform can load with load_dataset
+type LoadableDataset struct {
+	dsref.VersionInfo
+	// Ref is the reference to pass to load_dataset
+	Ref string `json:"ref"`
+	// Local is true when the dataset is stored locally. Datasets that aren't
+	// local are pulled when loaded
+	Local bool `json:"local"`
+}
+
+// AvailableDatasets lists the datasets transform scripts can load, starting
+// with local datasets
+func (m TransformMethods) AvailableDatasets(ctx context.Context, p *AvailableDatasetsParams) ([]LoadableDataset, error) {
+	got, _, err := m.d.Dispatch(ctx, dispatchMethodName(m, "availabledatasets"), p)
+	if res, ok := got.([]LoadableDataset); ok {
+		return res, err
+	}
+	return nil, dispatchReturnError(got, err)
+}
+
+// Implementations for transform methods follow
+
+// transformImpl holds the method implementations for transforms
+type transformImpl struct{}
+
+// Builtins lists the starlark modules transform scripts can load
+func (transformImpl) Builtins(scope scope, p *BuiltinsParams) ([]startf.ModuleDoc, error) {
+	return startf.ModuleDocs()
+}
+
+// AvailableDatasets lists the datasets transform scripts can load. Local
+// datasets come from the dscache when one exists, the collection otherwise
+func (transformImpl) AvailableDatasets(scope scope, p *AvailableDatasetsParams) ([]LoadableDataset, error) {
+	var local []dsref.VersionInfo
+	if dc := scope.Dscache(); !dc.IsEmpty() {
+		refs, err := dc.ListRefs()
+		if err != nil {
+			return nil, err
+		}
+		for i := range refs {
+			local = append(local, reporef.ConvertToVersionInfo(&refs[i]))
+		}
+	} else {
+		vis, err := scope.CollectionSet().List(scope.Context(), scope.ActiveProfile().ID, params.ListAll)
+		if err != nil {
+			return nil, err
+		}
+		local = vis
+	}
+
+	res := make([]LoadableDataset, 0, len(local))
+	seen := map[string]bool{}
+	for _, vi := range local {
+		if vi.Path == "" {
+			// datasets with no history can't be loaded
+			continue
+		}
+		ref := vi.Alias()
+		seen[ref] = true
+		res = append(res, LoadableDataset{VersionInfo: vi, Ref: ref, Local: true})
+	}
+	sort.Slice(res, func(i, j int) bool { return res[i].Ref < res[j].Ref })
+
+	if p.Query == "" {
+		return res, nil
+	}
+	client := scope.RegistryClient()
+	if client == nil {
+		return res, nil
+	}
+	results, err := client.Search(scope.Context(), &regclient.SearchParams{Query: p.Query, Limit: params.DefaultListLimit})
+	if err != nil {
+		log.Debugw("searching registry for loadable datasets", "err", err)
+		return res, nil
+	}
+	for _, r := range results {
+		if r.Value == nil {
+			continue
+		}
+		vi := dsref.ConvertDatasetToVersionInfo(r.Value)
+		ref := vi.Alias()
+		if seen[ref] {
+			continue
+		}
+		seen[ref] = true
+		res = append(res, LoadableDataset{VersionInfo: vi, Ref: ref})
+	}
+	return res, nil
+}
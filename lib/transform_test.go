@@ -0,0 +1,31 @@
+package lib
+
+import (
+	"testing"
+)
+
+func TestTransformAvailableDatasets(t *testing.T) {
+	tr := newTestRunner(t)
+	defer tr.Delete()
+
+	ds := tr.MustSaveFromBody(t, "loadable_ds", "testdata/cities_2/body.csv")
+
+	res, err := tr.Instance.Transform().AvailableDatasets(tr.Ctx, &AvailableDatasetsParams{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ref := ds.Peername + "/" + ds.Name
+	for _, d := range res {
+		if d.Ref == ref {
+			if !d.Local {
+				t.Errorf("expected %s to be local", ref)
+			}
+			if d.Path != ds.Path {
+				t.Errorf("expected path %q, got: %q", ds.Path, d.Path)
+			}
+			return
+		}
+	}
+	t.Errorf("expected %s in available datasets, got: %v", ref, res)
+}
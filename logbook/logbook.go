@@ -1256,6 +1256,22 @@ func (book Book) Items(ctx context.Context, ref dsref.Ref, offset, limit int, te
 	return filteredBranchToVersionInfos(branchLog, ref, offset, limit, term, true), nil
 }
 
+// VersionHistory lists every committed version of a dataset, newest first.
+// Unlike Items, the list isn't paginated and excludes transform runs that
+// didn't produce a version
+func (book Book) VersionHistory(ctx context.Context, ref dsref.Ref) ([]dsref.VersionInfo, error) {
+	initID, err := book.RefToInitID(dsref.Ref{Username: ref.Username, Name: ref.Name})
+	if err != nil {
+		return nil, err
+	}
+	branchLog, err := book.branchLog(ctx, initID)
+	if err != nil {
+		return nil, err
+	}
+
+	return filteredBranchToVersionInfos(branchLog, ref, 0, -1, "history", true), nil
+}
+
 // ConvertLogsToVersionInfos collapses the history of a dataset branch into linear log items
 func ConvertLogsToVersionInfos(l *oplog.Log, ref dsref.Ref) []dsref.VersionInfo {
 	return branchToVersionInfos(newBranchLog(l), ref, true)
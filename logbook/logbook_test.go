@@ -928,6 +928,62 @@ func TestItems(t *testing.T) {
 	}
 }
 
+func TestVersionHistory(t *testing.T) {
+	tr, cleanup := newTestRunner(t)
+	defer cleanup()
+	book := tr.Book
+
+	initID, err := book.WriteDatasetInit(tr.Ctx, tr.Owner, "history")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 1; i <= 3; i++ {
+		ds := &dataset.Dataset{
+			ID:       initID,
+			Peername: tr.Owner.Peername,
+			Name:     "history",
+			Commit: &dataset.Commit{
+				Timestamp: time.Date(2000, time.January, i, 0, 0, 0, 0, time.UTC),
+				Title:     fmt.Sprintf("v%d", i),
+			},
+			Path: fmt.Sprintf("QmHashOfVersion%d", i),
+		}
+		if err := book.WriteVersionSave(tr.Ctx, tr.Owner, ds, nil); err != nil {
+			t.Fatal(err)
+		}
+		// runs that don't produce a version aren't part of history
+		rs := &run.State{ID: fmt.Sprintf("run_%d", i), Status: run.RSUnchanged}
+		if err := book.WriteTransformRun(tr.Ctx, tr.Owner, initID, rs); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	ref := dsref.Ref{Username: tr.Owner.Peername, Name: "history"}
+	got, err := book.VersionHistory(tr.Ctx, ref)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expect := []dsref.VersionInfo{}
+	for i := 3; i >= 1; i-- {
+		expect = append(expect, dsref.VersionInfo{
+			Username:    tr.Owner.Peername,
+			Name:        "history",
+			Path:        fmt.Sprintf("QmHashOfVersion%d", i),
+			CommitTime:  time.Date(2000, time.January, i, 0, 0, 0, 0, time.UTC),
+			CommitTitle: fmt.Sprintf("v%d", i),
+		})
+	}
+	if diff := cmp.Diff(expect, got); diff != "" {
+		t.Errorf("result mismatch (-want +got):\n%s", diff)
+	}
+
+	if _, err := book.VersionHistory(tr.Ctx, dsref.Ref{Username: tr.Owner.Peername, Name: "unknown"}); err == nil {
+		t.Error("expected history of an unknown dataset to error")
+	}
+}
+
 func TestFilteredItems(t *testing.T) {
 	tr, cleanup := newTestRunner(t)
 	defer cleanup()
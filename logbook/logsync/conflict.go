@@ -0,0 +1,125 @@
+package logsync
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/qri-io/qri/logbook"
+	"github.com/qri-io/qri/logbook/oplog"
+)
+
+// ErrLogConflict indicates a log received from a remote has diverged from the
+// local copy of the same log. Errors that wrap ErrLogConflict are always a
+// *ConflictError
+var ErrLogConflict = fmt.Errorf("logsync: logs have diverged")
+
+// ConflictError describes a log that's diverged between two logbooks. Both
+// logs share operations up to Ancestor, then each has operations the other
+// lacks. Merging diverged logs would silently discard one side's operations
+type ConflictError struct {
+	// ID of the diverged log
+	LogID string `json:"logID"`
+	// hash of the last operation both logs share
+	Ancestor string `json:"ancestor"`
+	// hashes of operations only the local log has
+	Local []string `json:"local"`
+	// hashes of operations only the remote log has
+	Remote []string `json:"remote"`
+}
+
+// Error implements the error interface
+func (e *ConflictError) Error() string {
+	return fmt.Sprintf("%s: log %s diverges after operation %s. local operations: %v, remote operations: %v", ErrLogConflict, e.LogID, e.Ancestor, e.Local, e.Remote)
+}
+
+// Unwrap allows ConflictError to be checked with errors.Is(err, ErrLogConflict)
+func (e *ConflictError) Unwrap() error {
+	return ErrLogConflict
+}
+
+// decodeConflict reads a conflict sent by a remote. The remote reports the
+// conflict from its side, so local & remote operations are swapped. A remote
+// that doesn't send conflict details still produces a *ConflictError
+func decodeConflict(data []byte) *ConflictError {
+	sent := &ConflictError{}
+	if err := json.Unmarshal(data, sent); err != nil {
+		log.Debugf("decoding log conflict: %s", err)
+	}
+	return &ConflictError{
+		LogID:    sent.LogID,
+		Ancestor: sent.Ancestor,
+		Local:    sent.Remote,
+		Remote:   sent.Local,
+	}
+}
+
+// checkConflict compares each dataset in an incoming user log with the local
+// copy of that dataset, returning a *ConflictError if any of them has
+// diverged. Datasets with no local copy can't conflict
+func checkConflict(ctx context.Context, book *logbook.Book, incoming *oplog.Log) error {
+	for _, dsLog := range incoming.Logs {
+		local, err := book.UserDatasetBranchesLog(ctx, dsLog.ID())
+		if errors.Is(err, oplog.ErrNotFound) || errors.Is(err, logbook.ErrNotFound) {
+			continue
+		} else if err != nil {
+			return err
+		}
+		if local == nil {
+			continue
+		}
+		for _, localDsLog := range local.Logs {
+			if localDsLog.ID() != dsLog.ID() {
+				continue
+			}
+			if conflict := divergence(localDsLog, dsLog); conflict != nil {
+				return conflict
+			}
+		}
+	}
+	return nil
+}
+
+// divergence compares two copies of a log & their descendants, returning a
+// conflict describing the first log where the copies disagree. Copies where
+// one has only appended operations to the other don't conflict
+func divergence(local, remote *oplog.Log) *ConflictError {
+	n := len(local.Ops)
+	if len(remote.Ops) < n {
+		n = len(remote.Ops)
+	}
+	for i := 0; i < n; i++ {
+		if local.Ops[i].Equal(remote.Ops[i]) {
+			continue
+		}
+		conflict := &ConflictError{
+			LogID:  local.ID(),
+			Local:  opHashes(local.Ops[i:]),
+			Remote: opHashes(remote.Ops[i:]),
+		}
+		if i > 0 {
+			conflict.Ancestor = local.Ops[i-1].Hash()
+		}
+		return conflict
+	}
+
+	for _, r := range remote.Logs {
+		for _, l := range local.Logs {
+			if len(l.Ops) > 0 && len(r.Ops) > 0 && l.Ops[0].Equal(r.Ops[0]) {
+				if conflict := divergence(l, r); conflict != nil {
+					return conflict
+				}
+			}
+		}
+	}
+	return nil
+}
+
+func opHashes(ops []oplog.Op) []string {
+	hashes := make([]string, len(ops))
+	for i, op := range ops {
+		hashes[i] = op.Hash()
+	}
+	return hashes
+}
@@ -0,0 +1,172 @@
+package logsync
+
+import (
+	"errors"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	cmp "github.com/google/go-cmp/cmp"
+	"github.com/qri-io/dataset"
+)
+
+func TestPullConflict(t *testing.T) {
+	tr, cleanup := newTestRunner(t)
+	defer cleanup()
+
+	a := New(tr.A)
+	b := New(tr.B, func(o *Options) {
+		o.MemTransports = []*MemTransport{NewMemTransport("mem:a", a)}
+	})
+
+	ref, err := writeNasdaqLogs(tr.Ctx, tr.A)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pull, err := b.NewPull(ref, "mem:a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	pull.Merge = true
+	if _, err := pull.Do(tr.Ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	// A adds a version
+	ds := &dataset.Dataset{
+		ID:       ref.InitID,
+		Peername: ref.Username,
+		Name:     ref.Name,
+		Commit: &dataset.Commit{
+			Timestamp: time.Date(2000, time.January, 4, 0, 0, 0, 0, time.UTC),
+			Title:     "more data",
+		},
+		Path:         "v2",
+		PreviousPath: "v1",
+	}
+	if err := tr.A.WriteVersionSave(tr.Ctx, tr.A.Owner(), ds, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	// B's copy of the branch forks with a different operation
+	userLog, err := tr.A.UserDatasetBranchesLog(tr.Ctx, ref.InitID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	branchID := userLog.Logs[0].Logs[0].ID()
+	aBranch, err := tr.A.Log(tr.Ctx, branchID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	bBranch, err := tr.B.Log(tr.Ctx, branchID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ancestor := bBranch.Head()
+	forked := ancestor
+	forked.Note = "forked"
+	forked.Timestamp++
+	bBranch.Append(forked)
+
+	_, err = pull.Do(tr.Ctx)
+	conflict := &ConflictError{}
+	if !errors.As(err, &conflict) {
+		t.Fatalf("expected pulling a diverged log to return a conflict, got: %v", err)
+	}
+	if !errors.Is(err, ErrLogConflict) {
+		t.Errorf("expected conflict to be ErrLogConflict")
+	}
+
+	expect := &ConflictError{
+		LogID:    branchID,
+		Ancestor: ancestor.Hash(),
+		Local:    []string{forked.Hash()},
+		Remote:   opHashes(aBranch.Ops[len(aBranch.Ops)-1:]),
+	}
+	if diff := cmp.Diff(expect, conflict); diff != "" {
+		t.Errorf("conflict mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestPushConflictHTTP(t *testing.T) {
+	tr, cleanup := newTestRunner(t)
+	defer cleanup()
+
+	a, b := tr.DefaultLogsyncs()
+	server := httptest.NewServer(HTTPHandler(a))
+	defer server.Close()
+
+	ref, err := writeWorldBankLogs(tr.Ctx, tr.B)
+	if err != nil {
+		t.Fatal(err)
+	}
+	push, err := b.NewPush(ref, server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := push.Do(tr.Ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	// B adds a version
+	ds := &dataset.Dataset{
+		ID:       ref.InitID,
+		Peername: ref.Username,
+		Name:     ref.Name,
+		Commit: &dataset.Commit{
+			Timestamp: time.Date(2000, time.January, 4, 0, 0, 0, 0, time.UTC),
+			Title:     "more data",
+		},
+		Path:         "/ipfs/QmVersion3",
+		PreviousPath: "/ipfs/QmVersion2",
+	}
+	if err := tr.B.WriteVersionSave(tr.Ctx, tr.B.Owner(), ds, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	// A's copy of the branch forks with a different operation
+	userLog, err := tr.B.UserDatasetBranchesLog(tr.Ctx, ref.InitID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	branchID := userLog.Logs[0].Logs[0].ID()
+	aBranch, err := tr.A.Log(tr.Ctx, branchID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ancestor := aBranch.Head()
+	forked := ancestor
+	forked.Note = "forked"
+	forked.Timestamp++
+	aBranch.Append(forked)
+
+	err = push.Do(tr.Ctx)
+	conflict := &ConflictError{}
+	if !errors.As(err, &conflict) {
+		t.Fatalf("expected pushing a diverged log to return a conflict, got: %v", err)
+	}
+	if !errors.Is(err, ErrLogConflict) {
+		t.Errorf("expected conflict to be ErrLogConflict")
+	}
+
+	// the pushed log ends with B's new version, followed by the push operation
+	// that's rolled back when the push fails
+	bBranch, err := tr.B.Log(tr.Ctx, branchID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	newVersion := opHashes(bBranch.Ops[len(aBranch.Ops)-1:])
+	if len(conflict.Local) != 2 {
+		t.Fatalf("expected 2 local operations, got: %v", conflict.Local)
+	}
+	expect := &ConflictError{
+		LogID:    branchID,
+		Ancestor: ancestor.Hash(),
+		Local:    append(newVersion, conflict.Local[1]),
+		Remote:   []string{forked.Hash()},
+	}
+	if diff := cmp.Diff(expect, conflict); diff != "" {
+		t.Errorf("conflict mismatch (-want +got):\n%s", diff)
+	}
+}
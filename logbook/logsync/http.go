@@ -1,13 +1,16 @@
 package logsync
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
 	"io/ioutil"
 	"net/http"
 	"net/url"
+	"time"
 
 	"github.com/qri-io/qri/auth/key"
 	"github.com/qri-io/qri/dsref"
@@ -57,18 +60,28 @@ func (c *httpClient) put(ctx context.Context, author profile.Author, ref dsref.R
 	if err != nil {
 		return err
 	}
+	defer res.Body.Close()
+
 	if res.StatusCode != http.StatusOK {
-		if errmsg, err := ioutil.ReadAll(res.Body); err == nil {
-			return fmt.Errorf(string(errmsg))
+		errmsg, err := ioutil.ReadAll(res.Body)
+		if err != nil {
+			return err
 		}
-		return err
+		if res.StatusCode == http.StatusConflict {
+			return decodeConflict(errmsg)
+		}
+		return errors.New(string(errmsg))
 	}
 
 	return nil
 }
 
 func (c *httpClient) get(ctx context.Context, author profile.Author, ref dsref.Ref) (profile.Author, io.Reader, error) {
-	log.Debugw("httpClient.get", "ref", ref)
+	return c.getSince(ctx, author, ref, "")
+}
+
+func (c *httpClient) getSince(ctx context.Context, author profile.Author, ref dsref.Ref, since string) (profile.Author, io.Reader, error) {
+	log.Debugw("httpClient.get", "ref", ref, "since", since)
 	u, err := url.Parse(c.URL)
 	if err != nil {
 		return nil, nil, fmt.Errorf("invalid logsync client url: %w", err)
@@ -79,6 +92,9 @@ func (c *httpClient) get(ctx context.Context, author profile.Author, ref dsref.R
 	// field
 	ref.InitID = ""
 	q.Set("ref", ref.String())
+	if since != "" {
+		q.Set("since", since)
+	}
 	u.RawQuery = q.Encode()
 
 	req, err := http.NewRequest("GET", u.String(), nil)
@@ -100,10 +116,14 @@ func (c *httpClient) get(ctx context.Context, author profile.Author, ref dsref.R
 
 	if res.StatusCode != http.StatusOK {
 		log.Debugf("httpClient.get statusCode=%d", res.StatusCode)
-		if errmsg, err := ioutil.ReadAll(res.Body); err == nil {
-			return nil, nil, fmt.Errorf(string(errmsg))
+		errmsg, err := ioutil.ReadAll(res.Body)
+		if err != nil {
+			return nil, nil, err
 		}
-		return nil, nil, err
+		if res.StatusCode == http.StatusRequestedRangeNotSatisfiable {
+			return nil, nil, wrapRemoteError(ErrLogGap, string(errmsg))
+		}
+		return nil, nil, errors.New(string(errmsg))
 	}
 
 	sender, err := senderFromHTTPHeaders(res.Header)
@@ -131,12 +151,56 @@ func (c *httpClient) del(ctx context.Context, author profile.Author, ref dsref.R
 	}
 	if res.StatusCode != http.StatusOK {
 		if errmsg, err := ioutil.ReadAll(res.Body); err == nil {
-			return fmt.Errorf(string(errmsg))
+			return errors.New(string(errmsg))
 		}
 	}
 	return err
 }
 
+func (c *httpClient) list(ctx context.Context, author profile.Author) ([]dsref.Ref, error) {
+	log.Debugw("httpClient.list")
+	u, err := url.Parse(c.URL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid logsync client url: %w", err)
+	}
+	q := u.Query()
+	q.Set("list", "true")
+	u.RawQuery = q.Encode()
+
+	req, err := http.NewRequest("GET", u.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	req = req.WithContext(ctx)
+
+	if err := addAuthorHTTPHeaders(req.Header, author); err != nil {
+		return nil, err
+	}
+
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		errmsg, err := ioutil.ReadAll(res.Body)
+		if err != nil {
+			return nil, err
+		}
+		if res.StatusCode == http.StatusForbidden {
+			return nil, wrapRemoteError(ErrAccessDenied, string(errmsg))
+		}
+		return nil, errors.New(string(errmsg))
+	}
+
+	refs := []dsref.Ref{}
+	if err := json.NewDecoder(res.Body).Decode(&refs); err != nil {
+		return nil, err
+	}
+	return refs, nil
+}
+
 func addAuthorHTTPHeaders(h http.Header, author profile.Author) error {
 	h.Set("ID", author.AuthorID())
 	h.Set("username", author.Username())
@@ -157,6 +221,58 @@ func senderFromHTTPHeaders(h http.Header) (profile.Author, error) {
 	return profile.NewAuthor(h.Get("ID"), pub, h.Get("username")), nil
 }
 
+// readPushBody reads pushed log data, erroring if the body is larger than
+// maxSize bytes or isn't fully received within timeout
+func readPushBody(ctx context.Context, body io.ReadCloser, maxSize int64, timeout time.Duration) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	type result struct {
+		data []byte
+		err  error
+	}
+	res := make(chan result, 1)
+	go func() {
+		data, err := ioutil.ReadAll(io.LimitReader(body, maxSize+1))
+		res <- result{data: data, err: err}
+	}()
+
+	select {
+	case r := <-res:
+		if r.err != nil {
+			return nil, r.err
+		}
+		if int64(len(r.data)) > maxSize {
+			return nil, fmt.Errorf("%w: exceeds %d bytes", ErrPushTooLarge, maxSize)
+		}
+		return r.data, nil
+	case <-ctx.Done():
+		// closing the body unblocks the pending read
+		body.Close()
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			return nil, ErrPushTimeout
+		}
+		return nil, ctx.Err()
+	}
+}
+
+// badRefMessage describes a repo.ParseDatasetRef error for a HTTP response
+func badRefMessage(ref string, err error) string {
+	switch {
+	case errors.Is(err, repo.ErrEmptyRef):
+		return "ref parameter is required"
+	case errors.Is(err, repo.ErrBadRefUsername):
+		return fmt.Sprintf("invalid ref %q: username must start with a letter, and only contain letters, numbers, dashes, and underscores", ref)
+	case errors.Is(err, repo.ErrBadRefName):
+		return fmt.Sprintf("invalid ref %q: dataset name must start with a letter, and only contain letters, numbers, dashes, and underscores", ref)
+	case errors.Is(err, repo.ErrBadRefProfileID):
+		return fmt.Sprintf("invalid ref %q: profileID must be a base58-encoded multihash", ref)
+	case errors.Is(err, repo.ErrBadRefPath):
+		return fmt.Sprintf("invalid ref %q: path must have the form /network/hash", ref)
+	}
+	return fmt.Sprintf("invalid ref %q: %s", ref, err)
+}
+
 // HTTPHandler exposes a Dsync remote over HTTP by exposing a HTTP handler
 // that interlocks with methods exposed by httpClient
 func HTTPHandler(lsync *Logsync) http.HandlerFunc {
@@ -178,16 +294,57 @@ func HTTPHandler(lsync *Logsync) http.HandlerFunc {
 				w.Write([]byte(err.Error()))
 				return
 			}
-			if err := lsync.put(r.Context(), sender, ref, r.Body); err != nil {
-				w.WriteHeader(http.StatusBadRequest)
+			maxSize, timeout := lsync.pushLimits()
+			data, err := readPushBody(r.Context(), r.Body, maxSize, timeout)
+			if err != nil {
+				log.Debugf("PUT readPushBody error=%q", err)
+				switch {
+				case errors.Is(err, ErrPushTooLarge):
+					w.WriteHeader(http.StatusRequestEntityTooLarge)
+				case errors.Is(err, ErrPushTimeout):
+					w.WriteHeader(http.StatusRequestTimeout)
+				default:
+					w.WriteHeader(http.StatusBadRequest)
+				}
 				w.Write([]byte(err.Error()))
 				return
 			}
 			r.Body.Close()
 
+			if err := lsync.put(r.Context(), sender, ref, bytes.NewReader(data)); err != nil {
+				conflict := &ConflictError{}
+				if errors.As(err, &conflict) {
+					w.Header().Set("Content-Type", "application/json")
+					w.WriteHeader(http.StatusConflict)
+					json.NewEncoder(w).Encode(conflict)
+					return
+				}
+				w.WriteHeader(http.StatusBadRequest)
+				w.Write([]byte(err.Error()))
+				return
+			}
+
 			addAuthorHTTPHeaders(w.Header(), lsync.Author())
 			return
 		case "GET":
+			if r.FormValue("list") == "true" {
+				refs, err := lsync.list(r.Context(), sender)
+				if err != nil {
+					log.Debugf("GET list error=%q", err)
+					if errors.Is(err, ErrAccessDenied) {
+						w.WriteHeader(http.StatusForbidden)
+						w.Write([]byte(err.Error()))
+						return
+					}
+					w.WriteHeader(http.StatusBadRequest)
+					w.Write([]byte(err.Error()))
+					return
+				}
+				addAuthorHTTPHeaders(w.Header(), lsync.Author())
+				json.NewEncoder(w).Encode(refs)
+				return
+			}
+
 			ref, err := dsref.Parse(r.FormValue("ref"))
 			if err != nil {
 				log.Debugf("GET dsref.Parse error=%q", err)
@@ -196,7 +353,7 @@ func HTTPHandler(lsync *Logsync) http.HandlerFunc {
 				return
 			}
 
-			receiver, r, err := lsync.get(r.Context(), sender, ref)
+			receiver, r, err := lsync.getSince(r.Context(), sender, ref, r.FormValue("since"))
 			if err != nil {
 				log.Debugf("GET error=%q ref=%q", err, ref)
 				// TODO (ramfox): implement a robust error response strategy
@@ -205,6 +362,11 @@ func HTTPHandler(lsync *Logsync) http.HandlerFunc {
 					w.Write([]byte(err.Error()))
 					return
 				}
+				if errors.Is(err, ErrLogGap) {
+					w.WriteHeader(http.StatusRequestedRangeNotSatisfiable)
+					w.Write([]byte(err.Error()))
+					return
+				}
 				w.WriteHeader(http.StatusBadRequest)
 				w.Write([]byte(err.Error()))
 				return
@@ -215,8 +377,9 @@ func HTTPHandler(lsync *Logsync) http.HandlerFunc {
 		case "DELETE":
 			ref, err := repo.ParseDatasetRef(r.FormValue("ref"))
 			if err != nil {
+				log.Debugf("DELETE repo.ParseDatasetRef error=%q", err)
 				w.WriteHeader(http.StatusBadRequest)
-				w.Write([]byte(err.Error()))
+				w.Write([]byte(badRefMessage(r.FormValue("ref"), err)))
 				return
 			}
 
@@ -1,13 +1,22 @@
 package logsync
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"io"
+	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
+	"strings"
 	"testing"
+	"time"
 
 	cmp "github.com/google/go-cmp/cmp"
+	"github.com/qri-io/dataset"
 	"github.com/qri-io/qri/dsref"
+	"github.com/qri-io/qri/logbook/oplog"
 	"github.com/qri-io/qri/profile"
 )
 
@@ -89,6 +98,183 @@ func TestSyncHTTP(t *testing.T) {
 	}
 }
 
+func TestSyncHTTPSince(t *testing.T) {
+	tr, cleanup := newTestRunner(t)
+	defer cleanup()
+
+	a, b := tr.DefaultLogsyncs()
+
+	server := httptest.NewServer(HTTPHandler(a))
+	defer server.Close()
+
+	ref, err := writeNasdaqLogs(tr.Ctx, tr.A)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pull, err := b.NewPull(ref, server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pull.Merge = true
+	if _, err := pull.Do(tr.Ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	// add two versions to A, bringing the branch log to 5 operations
+	ds := &dataset.Dataset{
+		ID:       ref.InitID,
+		Peername: ref.Username,
+		Name:     ref.Name,
+		Commit: &dataset.Commit{
+			Timestamp: time.Date(2000, time.January, 4, 0, 0, 0, 0, time.UTC),
+			Title:     "more data",
+		},
+	}
+	for _, path := range []string{"v2", "v3"} {
+		ds.PreviousPath = ds.Path
+		ds.Path = path
+		if err := tr.A.WriteVersionSave(tr.Ctx, tr.A.Owner(), ds, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	pull, err = b.NewPull(ref, server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pull.Merge = true
+	pull.Since = 3
+	got, err := pull.Do(tr.Ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ops := len(got.Logs[0].Logs[0].Ops); ops != 2 {
+		t.Errorf("expected incremental fetch to return 2 operations, got %d", ops)
+	}
+
+	var expectItems, gotItems []dsref.VersionInfo
+	if expectItems, err = tr.A.Items(tr.Ctx, ref, 0, 100, ""); err != nil {
+		t.Fatal(err)
+	}
+	if gotItems, err = tr.B.Items(tr.Ctx, ref, 0, 100, ""); err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(expectItems, gotItems); diff != "" {
+		t.Errorf("result mismatch. (-want +got):\n%s", diff)
+	}
+
+	authorB := profile.NewAuthorFromProfile(tr.B.Owner())
+	if _, _, err := a.getSince(tr.Ctx, authorB, ref, "10"); !errors.Is(err, ErrLogGap) {
+		t.Errorf("expected since past the end of the log to return ErrLogGap, got: %v", err)
+	}
+	cli := &httpClient{URL: server.URL}
+	if _, _, err := cli.getSince(tr.Ctx, authorB, ref, "not_an_op_hash"); !errors.Is(err, ErrLogGap) {
+		t.Errorf("expected http client to return ErrLogGap for an unknown op, got: %v", err)
+	}
+
+	// fetched ops must follow the op the local log ends the shared prefix with
+	_, r, err := a.getSince(tr.Ctx, authorB, ref, "3")
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := pull.spliceLocalOps(tr.Ctx, data, "not_the_shared_op"); !errors.Is(err, ErrLogGap) {
+		t.Errorf("expected splicing onto a diverged local log to return ErrLogGap, got: %v", err)
+	}
+
+	// pulls that don't line up with the local log fall back to the full log
+	pull.Since = 10
+	got, err = pull.Do(tr.Ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ops := len(got.Logs[0].Logs[0].Ops); ops != 5 {
+		t.Errorf("expected fallback to fetch all 5 operations, got %d", ops)
+	}
+}
+
+func TestListHTTP(t *testing.T) {
+	tr, cleanup := newTestRunner(t)
+	defer cleanup()
+
+	nasdaqRef, err := writeNasdaqLogs(tr.Ctx, tr.A)
+	if err != nil {
+		t.Fatal(err)
+	}
+	nasdaqRef.ProfileID = tr.A.Owner().ID.Encode()
+	nasdaqRef.Path = "v1"
+
+	// the world bank dataset is private, and shouldn't be listed
+	if _, err = writeWorldBankLogs(tr.Ctx, tr.A); err != nil {
+		t.Fatal(err)
+	}
+
+	listAllowed := true
+	a := New(tr.A, func(o *Options) {
+		o.ListPreCheck = func(ctx context.Context, author profile.Author, ref dsref.Ref, l *oplog.Log) error {
+			if !listAllowed {
+				return fmt.Errorf("listing not allowed")
+			}
+			return nil
+		}
+		o.PullPreCheck = func(ctx context.Context, author profile.Author, ref dsref.Ref, l *oplog.Log) error {
+			if ref.Name == "world_bank_population" {
+				return fmt.Errorf("private dataset")
+			}
+			return nil
+		}
+	})
+	b := New(tr.B)
+
+	server := httptest.NewServer(HTTPHandler(a))
+	defer server.Close()
+
+	got, err := b.List(tr.Ctx, server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expect := []dsref.Ref{nasdaqRef}
+	if diff := cmp.Diff(expect, got); diff != "" {
+		t.Errorf("result mismatch. (-want +got):\n%s", diff)
+	}
+
+	// every listed ref should be pullable
+	for _, ref := range got {
+		pull, err := b.NewPull(dsref.Ref{Username: ref.Username, Name: ref.Name}, server.URL)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := pull.Do(tr.Ctx); err != nil {
+			t.Errorf("pulling listed ref %s: %s", ref, err)
+		}
+	}
+
+	listAllowed = false
+	if _, err := b.List(tr.Ctx, server.URL); !errors.Is(err, ErrAccessDenied) {
+		t.Errorf("expected list to return ErrAccessDenied when the list precheck fails, got: %v", err)
+	}
+
+	req, err := http.NewRequest("GET", server.URL+"?list=true", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := addAuthorHTTPHeaders(req.Header, profile.NewAuthorFromProfile(tr.B.Owner())); err != nil {
+		t.Fatal(err)
+	}
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+	if res.StatusCode != http.StatusForbidden {
+		t.Errorf("expected denied list to respond with status %d, got: %d", http.StatusForbidden, res.StatusCode)
+	}
+}
+
 func TestHTTPClientErrors(t *testing.T) {
 	tr, cleanup := newTestRunner(t)
 	defer cleanup()
@@ -171,3 +357,72 @@ func TestHTTPHandlerErrors(t *testing.T) {
 		}
 	}
 }
+
+func TestHTTPHandlerBadRefs(t *testing.T) {
+	tr, cleanup := newTestRunner(t)
+	defer cleanup()
+	handler := HTTPHandler(nil)
+	authorA := profile.NewAuthorFromProfile(tr.A.Owner())
+
+	cases := []struct {
+		description string
+		ref         string
+		expect      string
+	}{
+		{"empty ref", "", "ref parameter is required"},
+		{"bad username", "_peername/datasetname", `invalid ref "_peername/datasetname": username must start with a letter, and only contain letters, numbers, dashes, and underscores`},
+		{"bad name", "peername/data$et", `invalid ref "peername/data$et": dataset name must start with a letter, and only contain letters, numbers, dashes, and underscores`},
+		{"bad profileID", "peername/datasetname@not_an_id", `invalid ref "peername/datasetname@not_an_id": profileID must be a base58-encoded multihash`},
+		{"bad path", "peername/datasetname/junk", `invalid ref "peername/datasetname/junk": path must have the form /network/hash`},
+	}
+
+	for _, c := range cases {
+		t.Run(c.description, func(t *testing.T) {
+			u := url.URL{Scheme: "http", Host: "remote.qri.io", RawQuery: url.Values{"ref": {c.ref}}.Encode()}
+			r := httptest.NewRequest("DELETE", u.String(), nil)
+			addAuthorHTTPHeaders(r.Header, authorA)
+
+			w := httptest.NewRecorder()
+			handler(w, r)
+
+			resp := w.Result()
+			if http.StatusBadRequest != resp.StatusCode {
+				t.Errorf("response code mismatch. expected: %d, got: %d", http.StatusBadRequest, resp.StatusCode)
+			}
+			if diff := cmp.Diff(c.expect, w.Body.String()); diff != "" {
+				t.Errorf("response body mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestHTTPHandlerPushLimits(t *testing.T) {
+	tr, cleanup := newTestRunner(t)
+	defer cleanup()
+	authorB := profile.NewAuthorFromProfile(tr.B.Owner())
+
+	a := New(tr.A, func(o *Options) {
+		o.MaxPushSize = 16
+		o.PushReadTimeout = time.Millisecond * 50
+	})
+	handler := HTTPHandler(a)
+
+	r := httptest.NewRequest("PUT", "http://remote.qri.io?ref=peer/dataset", strings.NewReader(strings.Repeat("a", 1024)))
+	addAuthorHTTPHeaders(r.Header, authorB)
+	w := httptest.NewRecorder()
+	handler(w, r)
+	if resp := w.Result(); resp.StatusCode != http.StatusRequestEntityTooLarge {
+		t.Errorf("oversized body response code mismatch. expected: %d, got: %d", http.StatusRequestEntityTooLarge, resp.StatusCode)
+	}
+
+	// a body that never finishes sending should hit the read deadline
+	pr, pw := io.Pipe()
+	defer pw.Close()
+	r = httptest.NewRequest("PUT", "http://remote.qri.io?ref=peer/dataset", pr)
+	addAuthorHTTPHeaders(r.Header, authorB)
+	w = httptest.NewRecorder()
+	handler(w, r)
+	if resp := w.Result(); resp.StatusCode != http.StatusRequestTimeout {
+		t.Errorf("stalled body response code mismatch. expected: %d, got: %d", http.StatusRequestTimeout, resp.StatusCode)
+	}
+}
@@ -4,10 +4,13 @@ package logsync
 import (
 	"bytes"
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"io/ioutil"
+	"strconv"
 	"strings"
+	"time"
 
 	golog "github.com/ipfs/go-log"
 	host "github.com/libp2p/go-libp2p-core/host"
@@ -21,6 +24,25 @@ import (
 var (
 	// ErrNoLogsync indicates no logsync pointer has been allocated where one is expected
 	ErrNoLogsync = fmt.Errorf("logsync: does not exist")
+	// ErrLogGap indicates a request for operations that don't follow on from the
+	// requester's copy of a log, either because they begin past the end of the
+	// log, or because the logs have diverged. Fetching them would leave a gap or
+	// a mismatch in the requester's copy of the log
+	ErrLogGap = fmt.Errorf("logsync: requested operations begin past the end of the log")
+	// ErrPushTooLarge indicates pushed log data exceeds the maximum accepted size
+	ErrPushTooLarge = fmt.Errorf("logsync: pushed log data is too large")
+	// ErrPushTimeout indicates pushed log data wasn't received before the read
+	// deadline
+	ErrPushTimeout = fmt.Errorf("logsync: timed out reading pushed log data")
+	// ErrAccessDenied indicates a check hook refused a request
+	ErrAccessDenied = fmt.Errorf("logsync: access denied")
+
+	// DefaultMaxPushSize is the largest pushed log, in bytes, accepted when
+	// Options.MaxPushSize is unset
+	DefaultMaxPushSize int64 = 10 << 20
+	// DefaultPushReadTimeout bounds reading pushed log data when
+	// Options.PushReadTimeout is unset
+	DefaultPushReadTimeout = time.Second * 30
 
 	log = golog.Logger("logsync")
 )
@@ -38,6 +60,11 @@ type Logsync struct {
 	pulled         Hook
 	removePreCheck Hook
 	removed        Hook
+	listPreCheck   Hook
+
+	maxPushSize     int64
+	pushReadTimeout time.Duration
+	memTransports   []*MemTransport
 }
 
 // Options encapsulates runtime configuration for a remote
@@ -59,6 +86,16 @@ type Options struct {
 	RemovePreCheck Hook
 	// called after removing
 	Removed Hook
+	// called with an empty ref before listing refs. Listed refs are further
+	// filtered by PullPreCheck, omitting refs the requester can't pull
+	ListPreCheck Hook
+
+	// maximum size of pushed log data in bytes, defaults to DefaultMaxPushSize
+	MaxPushSize int64
+	// time allowed to read pushed log data, defaults to DefaultPushReadTimeout
+	PushReadTimeout time.Duration
+	// in-process transports to other Logsync instances, reachable by address
+	MemTransports []*MemTransport
 }
 
 // New creates a remote from a logbook and optional configuration functions
@@ -78,6 +115,11 @@ func New(book *logbook.Book, opts ...func(*Options)) *Logsync {
 		pulled:         o.Pulled,
 		removePreCheck: o.RemovePreCheck,
 		removed:        o.Removed,
+		listPreCheck:   o.ListPreCheck,
+
+		maxPushSize:     o.MaxPushSize,
+		pushReadTimeout: o.PushReadTimeout,
+		memTransports:   o.MemTransports,
 	}
 
 	if o.Libp2pHost != nil {
@@ -87,6 +129,21 @@ func New(book *logbook.Book, opts ...func(*Options)) *Logsync {
 	return logsync
 }
 
+// pushLimits returns the maximum size & read timeout for pushed log data
+func (lsync *Logsync) pushLimits() (maxSize int64, timeout time.Duration) {
+	maxSize, timeout = DefaultMaxPushSize, DefaultPushReadTimeout
+	if lsync == nil {
+		return maxSize, timeout
+	}
+	if lsync.maxPushSize > 0 {
+		maxSize = lsync.maxPushSize
+	}
+	if lsync.pushReadTimeout > 0 {
+		timeout = lsync.pushReadTimeout
+	}
+	return maxSize, timeout
+}
+
 // Hook is a function called at specified points in the sync lifecycle
 type Hook func(ctx context.Context, author profile.Author, ref dsref.Ref, l *oplog.Log) error
 
@@ -162,7 +219,27 @@ func (lsync *Logsync) DoRemove(ctx context.Context, ref dsref.Ref, remoteAddr st
 	return err
 }
 
+// List fetches the references of all datasets a remote is willing to sync
+func (lsync *Logsync) List(ctx context.Context, remoteAddr string) ([]dsref.Ref, error) {
+	if lsync == nil {
+		return nil, ErrNoLogsync
+	}
+
+	rem, err := lsync.remoteClient(ctx, remoteAddr)
+	if err != nil {
+		return nil, err
+	}
+
+	return rem.list(ctx, lsync.Author())
+}
+
 func (lsync *Logsync) remoteClient(ctx context.Context, remoteAddr string) (rem remote, err error) {
+	for _, t := range lsync.memTransports {
+		if t.Addr == remoteAddr {
+			return t, nil
+		}
+	}
+
 	if strings.HasPrefix(remoteAddr, "http") {
 		return &httpClient{URL: remoteAddr}, nil
 	}
@@ -191,7 +268,12 @@ type remote interface {
 	addr() string
 	put(ctx context.Context, author profile.Author, ref dsref.Ref, r io.Reader) error
 	get(ctx context.Context, author profile.Author, ref dsref.Ref) (sender profile.Author, data io.Reader, err error)
+	// getSince fetches a log with branch operations trimmed to only those that
+	// follow a point in the branch. since is either an operation count or the
+	// hash of the last operation the requester already has
+	getSince(ctx context.Context, author profile.Author, ref dsref.Ref, since string) (sender profile.Author, data io.Reader, err error)
 	del(ctx context.Context, author profile.Author, ref dsref.Ref) error
+	list(ctx context.Context, author profile.Author) ([]dsref.Ref, error)
 }
 
 // assert at compile-time that Logsync is a remote
@@ -237,6 +319,10 @@ func (lsync *Logsync) put(ctx context.Context, author profile.Author, ref dsref.
 		return fmt.Errorf("ref contained in log data does not match")
 	}
 
+	if err := checkConflict(ctx, lsync.book, lg); err != nil {
+		return err
+	}
+
 	if lsync.pushFinalCheck != nil {
 		if err := lsync.pushFinalCheck(ctx, author, logRef, lg); err != nil {
 			return err
@@ -256,7 +342,11 @@ func (lsync *Logsync) put(ctx context.Context, author profile.Author, ref dsref.
 }
 
 func (lsync *Logsync) get(ctx context.Context, author profile.Author, ref dsref.Ref) (profile.Author, io.Reader, error) {
-	log.Debugf("logsync.get author.AuthorID=%q ref=%q", author.AuthorID, ref)
+	return lsync.getSince(ctx, author, ref, "")
+}
+
+func (lsync *Logsync) getSince(ctx context.Context, author profile.Author, ref dsref.Ref, since string) (profile.Author, io.Reader, error) {
+	log.Debugf("logsync.get author.AuthorID=%q ref=%q since=%q", author.AuthorID, ref, since)
 	if lsync == nil {
 		return nil, nil, ErrNoLogsync
 	}
@@ -279,6 +369,13 @@ func (lsync *Logsync) get(ctx context.Context, author profile.Author, ref dsref.
 		return lsync.Author(), nil, err
 	}
 
+	if since != "" {
+		if l, err = branchOpsSince(l.DeepCopy(), since); err != nil {
+			log.Debugf("branchOpsSince error=%q initID=%q since=%q", err, ref.InitID, since)
+			return lsync.Author(), nil, err
+		}
+	}
+
 	data, err := lsync.book.LogBytes(l, lsync.book.Owner().PrivKey)
 	if err != nil {
 		log.Debugf("LogBytes error=%q initID=%q", err, ref.InitID)
@@ -294,6 +391,41 @@ func (lsync *Logsync) get(ctx context.Context, author profile.Author, ref dsref.
 	return lsync.Author(), bytes.NewReader(data), nil
 }
 
+// branchOpsSince trims each branch of a user-dataset-branches log to the
+// operations that follow since, modifying the passed-in log
+func branchOpsSince(l *oplog.Log, since string) (*oplog.Log, error) {
+	for _, dsLog := range l.Logs {
+		for _, branch := range dsLog.Logs {
+			i, err := opIndexSince(branch.Ops, since)
+			if err != nil {
+				return nil, err
+			}
+			branch.Ops = branch.Ops[i:]
+		}
+	}
+	return l, nil
+}
+
+// opIndexSince resolves since to the index of the first operation that follows
+// it. since may be an operation count or an operation hash
+func opIndexSince(ops []oplog.Op, since string) (int, error) {
+	if n, err := strconv.Atoi(since); err == nil {
+		if n < 0 {
+			return 0, fmt.Errorf("invalid since value %d: must be a positive number", n)
+		}
+		if n > len(ops) {
+			return 0, fmt.Errorf("%w: since %d, log has %d operations", ErrLogGap, n, len(ops))
+		}
+		return n, nil
+	}
+	for i, op := range ops {
+		if op.Hash() == since {
+			return i + 1, nil
+		}
+	}
+	return 0, fmt.Errorf("%w: no operation with hash %q", ErrLogGap, since)
+}
+
 func (lsync *Logsync) del(ctx context.Context, sender profile.Author, ref dsref.Ref) error {
 	if lsync == nil {
 		return ErrNoLogsync
@@ -340,6 +472,47 @@ func (lsync *Logsync) del(ctx context.Context, sender profile.Author, ref dsref.
 	return nil
 }
 
+func (lsync *Logsync) list(ctx context.Context, author profile.Author) ([]dsref.Ref, error) {
+	if lsync == nil {
+		return nil, ErrNoLogsync
+	}
+
+	if lsync.listPreCheck != nil {
+		if err := lsync.listPreCheck(ctx, author, dsref.Ref{}, nil); err != nil {
+			return nil, fmt.Errorf("%w: %s", ErrAccessDenied, err)
+		}
+	}
+
+	logs, err := lsync.book.ListAllLogs(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	refs := []dsref.Ref{}
+	for _, userLog := range logs {
+		for _, dsLog := range userLog.Logs {
+			if dsLog.Removed() {
+				continue
+			}
+			ref, err := lsync.book.Ref(ctx, dsLog.ID())
+			if err != nil {
+				log.Debugf("book.Ref error=%q initID=%q", err, dsLog.ID())
+				continue
+			}
+			// only list refs the requester is allowed to pull. the pull check
+			// carries the pull access policy
+			if lsync.pullPreCheck != nil {
+				if err := lsync.pullPreCheck(ctx, author, ref, nil); err != nil {
+					log.Debugf("list pullPreCheck error=%q ref=%q", err, ref)
+					continue
+				}
+			}
+			refs = append(refs, ref)
+		}
+	}
+	return refs, nil
+}
+
 // Push is a request to place a log on a remote
 type Push struct {
 	ref    dsref.Ref
@@ -376,6 +549,12 @@ func (p *Push) Do(ctx context.Context) error {
 	return nil
 }
 
+// wrapRemoteError makes an error message received from a remote match a known
+// error, without repeating the known error's message
+func wrapRemoteError(known error, errmsg string) error {
+	return fmt.Errorf("%w%s", known, strings.TrimPrefix(errmsg, known.Error()))
+}
+
 // Pull is a request to fetch a log
 type Pull struct {
 	book   *logbook.Book
@@ -384,12 +563,25 @@ type Pull struct {
 
 	// set to true to merge these logs into the local store on successful pull
 	Merge bool
+	// Since is the number of branch operations the puller already has. When
+	// set, only operations that follow are fetched, and merging splices them
+	// onto the local log. Incremental pulls that don't line up with the local
+	// log fall back to pulling the full log
+	Since int
 }
 
 // Do executes the pull
 func (p *Pull) Do(ctx context.Context) (*oplog.Log, error) {
 	log.Debugw("pull.Do", "ref", p.ref)
 	author := profile.NewAuthorFromProfile(p.book.Owner())
+	if p.Since > 0 {
+		l, err := p.pullSince(ctx, author)
+		if !errors.Is(err, ErrLogGap) {
+			return l, err
+		}
+		log.Debugw("incremental pull doesn't line up with the local log, pulling full log", "ref", p.ref, "since", p.Since, "err", err)
+	}
+
 	sender, r, err := p.remote.get(ctx, author, p.ref)
 	if err != nil {
 		return nil, err
@@ -405,6 +597,9 @@ func (p *Pull) Do(ctx context.Context) (*oplog.Log, error) {
 	}
 
 	if p.Merge {
+		if err := checkConflict(ctx, p.book, l); err != nil {
+			return nil, err
+		}
 		if err := p.book.MergeLog(ctx, sender.AuthorPubKey(), l); err != nil {
 			return nil, err
 		}
@@ -412,3 +607,104 @@ func (p *Pull) Do(ctx context.Context) (*oplog.Log, error) {
 
 	return l, nil
 }
+
+// pullSince fetches the operations that follow the first p.Since operations
+// of the local log. The remote is asked for operations after the hash of the
+// last operation we share, so a remote log that doesn't contain that
+// operation fails with ErrLogGap instead of returning unrelated operations
+func (p *Pull) pullSince(ctx context.Context, author profile.Author) (*oplog.Log, error) {
+	local, err := p.localBranch(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if len(local.Ops) < p.Since {
+		return nil, fmt.Errorf("%w: local log has %d operations, since %d", ErrLogGap, len(local.Ops), p.Since)
+	}
+	since := local.Ops[p.Since-1].Hash()
+
+	sender, r, err := p.remote.getSince(ctx, author, p.ref, since)
+	if err != nil {
+		return nil, err
+	}
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	l := &oplog.Log{}
+	if err := l.UnmarshalFlatbufferBytes(data); err != nil {
+		return nil, err
+	}
+
+	if p.Merge {
+		merged, err := p.spliceLocalOps(ctx, data, since)
+		if err != nil {
+			return nil, err
+		}
+		if err := checkConflict(ctx, p.book, merged); err != nil {
+			return nil, err
+		}
+		if err := p.book.MergeLog(ctx, sender.AuthorPubKey(), merged); err != nil {
+			return nil, err
+		}
+	}
+
+	return l, nil
+}
+
+// localBranch returns the local branch log of the pulled dataset. incremental
+// pulls are anchored to a single operation hash, so datasets with more than
+// one branch can't be pulled incrementally
+func (p *Pull) localBranch(ctx context.Context) (*oplog.Log, error) {
+	ref := p.ref
+	if _, err := p.book.ResolveRef(ctx, &ref); err != nil {
+		return nil, fmt.Errorf("%w: resolving local log: %s", ErrLogGap, err)
+	}
+	local, err := p.book.UserDatasetBranchesLog(ctx, ref.InitID)
+	if err != nil {
+		return nil, fmt.Errorf("%w: reading local log: %s", ErrLogGap, err)
+	}
+	if len(local.Logs) != 1 || len(local.Logs[0].Logs) != 1 {
+		return nil, fmt.Errorf("%w: incremental pulls require a single branch", ErrLogGap)
+	}
+	return local.Logs[0].Logs[0], nil
+}
+
+// spliceLocalOps decodes fetched log data, prepending the first p.Since
+// operations of the local branch to the fetched branch. Trimmed branches lose
+// their first operation & with it their ID, so the local operation at p.Since
+// must be the one the fetch was anchored to, and the spliced branch must have
+// the ID of the local branch, otherwise the logs have diverged
+func (p *Pull) spliceLocalOps(ctx context.Context, data []byte, since string) (*oplog.Log, error) {
+	l, err := oplog.FromFlatbufferBytes(data)
+	if err != nil {
+		return nil, err
+	}
+	if len(l.Logs) != 1 || len(l.Logs[0].Logs) != 1 {
+		return nil, fmt.Errorf("%w: incremental pulls require a single branch", ErrLogGap)
+	}
+
+	local, err := p.book.UserDatasetBranchesLog(ctx, l.Logs[0].ID())
+	if err != nil {
+		return nil, fmt.Errorf("%w: reading local log: %s", ErrLogGap, err)
+	}
+	if len(local.Logs) != 1 || len(local.Logs[0].Logs) != 1 {
+		return nil, fmt.Errorf("%w: incremental pulls require a single branch", ErrLogGap)
+	}
+
+	branch := l.Logs[0].Logs[0]
+	localBranch := local.Logs[0].Logs[0]
+	if len(localBranch.Ops) < p.Since {
+		return nil, fmt.Errorf("%w: local log is missing operations before %d", ErrLogGap, p.Since)
+	}
+	if localBranch.Ops[p.Since-1].Hash() != since {
+		return nil, fmt.Errorf("%w: local branch %q has diverged", ErrLogGap, localBranch.ID())
+	}
+	ops := make([]oplog.Op, 0, p.Since+len(branch.Ops))
+	ops = append(ops, localBranch.Ops[:p.Since]...)
+	branch.Ops = append(ops, branch.Ops...)
+	if branch.ID() != localBranch.ID() {
+		return nil, fmt.Errorf("%w: fetched branch doesn't match local branch %q", ErrLogGap, localBranch.ID())
+	}
+	return l, nil
+}
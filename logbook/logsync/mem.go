@@ -0,0 +1,92 @@
+package logsync
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"io/ioutil"
+
+	"github.com/qri-io/qri/dsref"
+	"github.com/qri-io/qri/profile"
+)
+
+// MemTransport connects logsync clients directly to a Logsync instance in the
+// same process, without a network. Clients reach a MemTransport by passing its
+// address as the remote address to NewPush, NewPull, DoRemove & List, after
+// adding the transport with Options.MemTransports. MemTransport is intended for
+// tests
+type MemTransport struct {
+	// Addr is the remote address clients use to reach this transport
+	Addr string
+	// Fail, when set, is called before each request is handed to the remote.
+	// returning an error fails the request with that error, for injecting
+	// failures. method is one of "put", "get", "del", or "list"
+	Fail func(method string, ref dsref.Ref) error
+
+	remote *Logsync
+}
+
+// assert at compile time that MemTransport is a remote
+var _ remote = (*MemTransport)(nil)
+
+// NewMemTransport creates an in-process transport to a Logsync instance
+func NewMemTransport(addr string, remote *Logsync) *MemTransport {
+	return &MemTransport{Addr: addr, remote: remote}
+}
+
+func (t *MemTransport) fail(method string, ref dsref.Ref) error {
+	if t.Fail == nil {
+		return nil
+	}
+	return t.Fail(method, ref)
+}
+
+func (t *MemTransport) addr() string {
+	return t.Addr
+}
+
+func (t *MemTransport) put(ctx context.Context, author profile.Author, ref dsref.Ref, r io.Reader) error {
+	if err := t.fail("put", ref); err != nil {
+		return err
+	}
+	// copy data so the remote never shares a reader with the caller, the same
+	// way it wouldn't across a network
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	return t.remote.put(ctx, author, ref, bytes.NewReader(data))
+}
+
+func (t *MemTransport) get(ctx context.Context, author profile.Author, ref dsref.Ref) (profile.Author, io.Reader, error) {
+	return t.getSince(ctx, author, ref, "")
+}
+
+func (t *MemTransport) getSince(ctx context.Context, author profile.Author, ref dsref.Ref, since string) (profile.Author, io.Reader, error) {
+	if err := t.fail("get", ref); err != nil {
+		return nil, nil, err
+	}
+	sender, r, err := t.remote.getSince(ctx, author, ref, since)
+	if err != nil {
+		return nil, nil, err
+	}
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, nil, err
+	}
+	return sender, bytes.NewReader(data), nil
+}
+
+func (t *MemTransport) del(ctx context.Context, author profile.Author, ref dsref.Ref) error {
+	if err := t.fail("del", ref); err != nil {
+		return err
+	}
+	return t.remote.del(ctx, author, ref)
+}
+
+func (t *MemTransport) list(ctx context.Context, author profile.Author) ([]dsref.Ref, error) {
+	if err := t.fail("list", dsref.Ref{}); err != nil {
+		return nil, err
+	}
+	return t.remote.list(ctx, author)
+}
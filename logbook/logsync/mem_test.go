@@ -0,0 +1,80 @@
+package logsync
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	cmp "github.com/google/go-cmp/cmp"
+	"github.com/qri-io/qri/dsref"
+)
+
+func TestSyncMemTransport(t *testing.T) {
+	tr, cleanup := newTestRunner(t)
+	defer cleanup()
+
+	a := New(tr.A)
+	transport := NewMemTransport("mem:a", a)
+	b := New(tr.B, func(o *Options) {
+		o.MemTransports = []*MemTransport{transport}
+	})
+
+	ref, err := writeNasdaqLogs(tr.Ctx, tr.A)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pull, err := b.NewPull(ref, "mem:a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	pull.Merge = true
+	if _, err := pull.Do(tr.Ctx); err != nil {
+		t.Fatalf("pulling nasdaq logs: %s", err)
+	}
+
+	var expect, got []dsref.VersionInfo
+	if expect, err = tr.A.Items(tr.Ctx, ref, 0, 100, ""); err != nil {
+		t.Fatal(err)
+	}
+	if got, err = tr.B.Items(tr.Ctx, ref, 0, 100, ""); err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(expect, got); diff != "" {
+		t.Errorf("result mismatch. (-want +got):\n%s", diff)
+	}
+
+	worldBankRef, err := writeWorldBankLogs(tr.Ctx, tr.B)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	errInjected := fmt.Errorf("injected failure")
+	transport.Fail = func(method string, ref dsref.Ref) error {
+		if method == "put" {
+			return errInjected
+		}
+		return nil
+	}
+	push, err := b.NewPush(worldBankRef, "mem:a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := push.Do(tr.Ctx); !errors.Is(err, errInjected) {
+		t.Errorf("expected injected failure, got: %v", err)
+	}
+
+	transport.Fail = nil
+	if err := push.Do(tr.Ctx); err != nil {
+		t.Fatal(err)
+	}
+	if expect, err = tr.B.Items(tr.Ctx, worldBankRef, 0, 100, ""); err != nil {
+		t.Fatal(err)
+	}
+	if got, err = tr.A.Items(tr.Ctx, worldBankRef, 0, 100, ""); err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(expect, got); diff != "" {
+		t.Errorf("result mismatch. (-want +got):\n%s", diff)
+	}
+}
@@ -3,6 +3,8 @@ package logsync
 import (
 	"bytes"
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"io/ioutil"
@@ -35,6 +37,8 @@ var (
 	mtGet = p2putil.MsgType("get")
 	// mtDel identifies the "del" message type, a request to remove a log
 	mtDel = p2putil.MsgType("del")
+	// mtList identifies the "list" message type, a request for all syncable refs
+	mtList = p2putil.MsgType("list")
 )
 
 type p2pClient struct {
@@ -64,14 +68,22 @@ func (c *p2pClient) put(ctx context.Context, author profile.Author, ref dsref.Re
 		return err
 	}
 	msg := p2putil.NewMessage(c.host.ID(), mtPut, data).WithHeaders(headers...)
-	_, err = c.sendMessage(ctx, msg, c.remotePeerID)
-	return err
+	res, err := c.sendMessage(ctx, msg, c.remotePeerID)
+	if err != nil {
+		return err
+	}
+	return errorFromP2PHeaders(res)
 }
 
 func (c *p2pClient) get(ctx context.Context, author profile.Author, ref dsref.Ref) (sender profile.Author, data io.Reader, err error) {
+	return c.getSince(ctx, author, ref, "")
+}
+
+func (c *p2pClient) getSince(ctx context.Context, author profile.Author, ref dsref.Ref, since string) (sender profile.Author, data io.Reader, err error) {
 	headers := []string{
 		"phase", "request",
 		"ref", ref.String(),
+		"since", since,
 	}
 	headers, err = addAuthorP2PHeaders(headers, author)
 	if err != nil {
@@ -84,6 +96,9 @@ func (c *p2pClient) get(ctx context.Context, author profile.Author, ref dsref.Re
 	if err != nil {
 		return nil, nil, err
 	}
+	if err := errorFromP2PHeaders(res); err != nil {
+		return nil, nil, err
+	}
 
 	sender, err = authorFromP2PHeaders(res)
 	return sender, bytes.NewReader(res.Body), err
@@ -104,6 +119,31 @@ func (c *p2pClient) del(ctx context.Context, author profile.Author, ref dsref.Re
 	return err
 }
 
+func (c *p2pClient) list(ctx context.Context, author profile.Author) ([]dsref.Ref, error) {
+	headers, err := addAuthorP2PHeaders([]string{"phase", "request"}, author)
+	if err != nil {
+		return nil, err
+	}
+
+	msg := p2putil.NewMessage(c.host.ID(), mtList, nil).WithHeaders(headers...)
+	res, err := c.sendMessage(ctx, msg, c.remotePeerID)
+	if err != nil {
+		return nil, err
+	}
+	if res.Header("phase") != "response" {
+		return nil, fmt.Errorf("listing refs: remote did not respond")
+	}
+	if err := errorFromP2PHeaders(res); err != nil {
+		return nil, err
+	}
+
+	refs := []dsref.Ref{}
+	if err := json.Unmarshal(res.Body, &refs); err != nil {
+		return nil, err
+	}
+	return refs, nil
+}
+
 func addAuthorP2PHeaders(h []string, author profile.Author) ([]string, error) {
 	pubKey, err := key.EncodePubKeyB64(author.AuthorPubKey())
 	if err != nil {
@@ -121,6 +161,50 @@ func authorFromP2PHeaders(msg p2putil.Message) (profile.Author, error) {
 	return profile.NewAuthor(msg.Header("author_id"), pub, msg.Header("author_username")), nil
 }
 
+// p2pErrorHeaders describes an error in response headers. Errors the
+// requester can act on are tagged with a kind so they survive the trip
+func p2pErrorHeaders(err error) []string {
+	headers := []string{"phase", "response", "error", err.Error()}
+	if errors.Is(err, ErrLogGap) {
+		headers = append(headers, "error_kind", "log_gap")
+	} else if errors.Is(err, ErrLogConflict) {
+		headers = append(headers, "error_kind", "log_conflict")
+	} else if errors.Is(err, ErrAccessDenied) {
+		headers = append(headers, "error_kind", "access_denied")
+	}
+	return headers
+}
+
+// p2pErrorResponse responds to msg with an error. Conflicts carry their
+// details in the response body
+func p2pErrorResponse(msg p2putil.Message, err error) p2putil.Message {
+	res := msg.WithHeaders(p2pErrorHeaders(err)...).Update(nil)
+	conflict := &ConflictError{}
+	if errors.As(err, &conflict) {
+		if data, err := json.Marshal(conflict); err == nil {
+			res = res.Update(data)
+		}
+	}
+	return res
+}
+
+// errorFromP2PHeaders returns the error described by response headers, if any
+func errorFromP2PHeaders(msg p2putil.Message) error {
+	errmsg := msg.Header("error")
+	if errmsg == "" {
+		return nil
+	}
+	switch msg.Header("error_kind") {
+	case "log_gap":
+		return wrapRemoteError(ErrLogGap, errmsg)
+	case "log_conflict":
+		return decodeConflict(msg.Body)
+	case "access_denied":
+		return wrapRemoteError(ErrAccessDenied, errmsg)
+	}
+	return errors.New(errmsg)
+}
+
 // p2pHandler implements logsync as a libp2p protocol handler
 type p2pHandler struct {
 	logsync  *Logsync
@@ -132,9 +216,10 @@ type p2pHandler struct {
 func newp2pHandler(logsync *Logsync, host host.Host) *p2pHandler {
 	c := &p2pHandler{logsync: logsync, host: host}
 	c.handlers = map[p2putil.MsgType]p2putil.HandlerFunc{
-		mtPut: c.HandlePut,
-		mtGet: c.HandleGet,
-		mtDel: c.HandleDel,
+		mtPut:  c.HandlePut,
+		mtGet:  c.HandleGet,
+		mtDel:  c.HandleDel,
+		mtList: c.HandleList,
 	}
 
 	go host.SetStreamHandler(LogsyncProtocolID, c.LibP2PStreamHandler)
@@ -163,6 +248,7 @@ func (c *p2pHandler) HandlePut(ws *p2putil.WrappedStream, msg p2putil.Message) (
 		}
 
 		if err = c.logsync.put(ctx, author, ref, bytes.NewReader(msg.Body)); err != nil {
+			ws.SendMessage(p2pErrorResponse(msg, err))
 			return true
 		}
 
@@ -196,8 +282,11 @@ func (c *p2pHandler) HandleGet(ws *p2putil.WrappedStream, msg p2putil.Message) (
 			return true
 		}
 
-		sender, r, err := c.logsync.get(ctx, author, reporef.ConvertToDsref(ref))
+		sender, r, err := c.logsync.getSince(ctx, author, reporef.ConvertToDsref(ref), msg.Header("since"))
 		if err != nil {
+			if errors.Is(err, ErrLogGap) {
+				ws.SendMessage(p2pErrorResponse(msg, err))
+			}
 			return true
 		}
 
@@ -249,6 +338,34 @@ func (c *p2pHandler) HandleDel(ws *p2putil.WrappedStream, msg p2putil.Message) (
 	return true
 }
 
+// HandleList responds with the refs a requester is allowed to sync
+func (c *p2pHandler) HandleList(ws *p2putil.WrappedStream, msg p2putil.Message) (hangup bool) {
+	if msg.Header("phase") == "request" {
+		ctx := context.Background()
+		author, err := authorFromP2PHeaders(msg)
+		if err != nil {
+			return true
+		}
+
+		refs, err := c.logsync.list(ctx, author)
+		if err != nil {
+			ws.SendMessage(p2pErrorResponse(msg, err))
+			return true
+		}
+
+		data, err := json.Marshal(refs)
+		if err != nil {
+			return true
+		}
+
+		res := msg.WithHeaders("phase", "response").Update(data)
+		if err := ws.SendMessage(res); err != nil {
+			return true
+		}
+	}
+	return true
+}
+
 // sendMessage opens a stream & sends a message to a peer id
 func (c *p2pHandler) sendMessage(ctx context.Context, msg p2putil.Message, pid peer.ID) (p2putil.Message, error) {
 	s, err := c.host.NewStream(ctx, pid, LogsyncProtocolID)
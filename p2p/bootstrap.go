@@ -14,6 +14,10 @@ import (
 // This is a naive version of IPFS bootstrapping, which we'll add in once
 // qri's settled on a shared-state implementation
 func (n *QriNode) Bootstrap(boostrapAddrs []string) {
+	if n.host == nil {
+		log.Debug(ErrNodeOffline.Error())
+		return
+	}
 	peers, err := ParseMultiaddrs(boostrapAddrs)
 	if err != nil {
 		log.Info("error parsing bootstrap addresses:", err.Error())
@@ -36,8 +36,8 @@ func (n *QriNode) RequestDatasetsList(ctx context.Context, pid peer.ID, p Datase
 		return n.Repo.References(p.Offset, p.Limit)
 	}
 
-	if !n.Online {
-		return nil, fmt.Errorf("not connected to p2p network")
+	if !n.Online || n.host == nil {
+		return nil, ErrNodeOffline
 	}
 
 	req, err := p2putil.NewJSONBodyMessage(n.ID, MtDatasets, p)
@@ -231,9 +231,14 @@ func (n *QriNode) startOnlineServices(ctx context.Context) error {
 // GoOffline takes the peer offline and shuts it down
 func (n *QriNode) GoOffline() error {
 	if n != nil && n.Online {
-		err := n.Host().Close()
+		var err error
+		if n.host != nil {
+			err = n.host.Close()
+		}
 		// clean up the "GoOnline" context
-		n.shutdown()
+		if n.shutdown != nil {
+			n.shutdown()
+		}
 		ctx, cancel := context.WithCancel(context.Background())
 		defer cancel()
 		n.pub.Publish(ctx, event.ETP2PGoneOffline, nil)
@@ -272,6 +277,9 @@ func (n *QriNode) IPFSCoreAPI() (coreiface.CoreAPI, error) {
 // ListenAddresses gives the listening addresses of this node on the p2p network as
 // a slice of strings
 func (n *QriNode) ListenAddresses() ([]string, error) {
+	if n.host == nil {
+		return nil, ErrNodeOffline
+	}
 	maddrs := n.EncapsulatedAddresses()
 	addrs := make([]string, len(maddrs))
 	for i, maddr := range maddrs {
@@ -282,6 +290,9 @@ func (n *QriNode) ListenAddresses() ([]string, error) {
 
 // EncapsulatedAddresses returns a slice of full multaddrs for this node
 func (n *QriNode) EncapsulatedAddresses() []ma.Multiaddr {
+	if n.host == nil {
+		return nil
+	}
 	// Build host multiaddress
 	hostAddr, err := ma.NewMultiaddr(fmt.Sprintf("/p2p/%s", n.host.ID().Pretty()))
 	if err != nil {
@@ -372,18 +383,27 @@ func (n *QriNode) libp2pSubscribe(ctx context.Context) error {
 	return nil
 }
 
-// Keys returns the KeyBook for the node.
+// Keys returns the KeyBook for the node, nil if the node has no host
 func (n *QriNode) Keys() peerstore.KeyBook {
+	if n.host == nil {
+		return nil
+	}
 	return n.host.Peerstore()
 }
 
-// Addrs returns the AddrBook for the node.
+// Addrs returns the AddrBook for the node, nil if the node has no host
 func (n *QriNode) Addrs() peerstore.AddrBook {
+	if n.host == nil {
+		return nil
+	}
 	return n.host.Peerstore()
 }
 
 // SimpleAddrInfo returns a PeerInfo with just the ID and Addresses.
 func (n *QriNode) SimpleAddrInfo() peer.AddrInfo {
+	if n.host == nil {
+		return peer.AddrInfo{}
+	}
 	return peer.AddrInfo{
 		ID:    n.host.ID(),
 		Addrs: n.host.Addrs(),
@@ -2,6 +2,7 @@ package p2p
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"sync"
 	"testing"
@@ -167,3 +168,74 @@ func TestNodeEvents(t *testing.T) {
 		t.Error(err)
 	}
 }
+
+func TestOfflineNodeMethods(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	keyData := testkeys.GetKeyData(0)
+	r, err := test.NewTestRepoFromProfileID(profile.IDFromPeerID(keyData.PeerID), 0, -1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	node, err := NewQriNode(r, testcfg.DefaultP2PForTesting(), event.NilBus, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	other := testkeys.GetKeyData(1).PeerID
+
+	// run each method on a node that never went online, and on a node that
+	// claims to be online without a host
+	for _, online := range []bool{false, true} {
+		node.Online = online
+		name := fmt.Sprintf("online_%t", online)
+
+		if _, err := node.ConnectToPeer(ctx, PeerConnectionParams{PeerID: other}); !errors.Is(err, ErrNodeOffline) {
+			t.Errorf("%s ConnectToPeer: expected ErrNodeOffline, got: %v", name, err)
+		}
+		if _, err := node.ConnectToPeerWithInfo(ctx, PeerConnectionParams{PeerID: other}); !errors.Is(err, ErrNodeOffline) {
+			t.Errorf("%s ConnectToPeerWithInfo: expected ErrNodeOffline, got: %v", name, err)
+		}
+		if err := node.DisconnectFromPeer(ctx, PeerConnectionParams{PeerID: other}); !errors.Is(err, ErrNodeOffline) {
+			t.Errorf("%s DisconnectFromPeer: expected ErrNodeOffline, got: %v", name, err)
+		}
+		if _, err := node.RequestDatasetsList(ctx, other, DatasetsListParams{Limit: 10}); !errors.Is(err, ErrNodeOffline) {
+			t.Errorf("%s RequestDatasetsList: expected ErrNodeOffline, got: %v", name, err)
+		}
+		if _, err := node.ListenAddresses(); !errors.Is(err, ErrNodeOffline) {
+			t.Errorf("%s ListenAddresses: expected ErrNodeOffline, got: %v", name, err)
+		}
+		if info := node.PeerInfo(other); info.ID != "" {
+			t.Errorf("%s PeerInfo: expected empty peer info, got: %v", name, info)
+		}
+		if info := node.SimpleAddrInfo(); info.ID != "" {
+			t.Errorf("%s SimpleAddrInfo: expected empty peer info, got: %v", name, info)
+		}
+		if addrs := node.EncapsulatedAddresses(); len(addrs) != 0 {
+			t.Errorf("%s EncapsulatedAddresses: expected no addresses, got: %v", name, addrs)
+		}
+		if peers := node.ClosestConnectedQriPeers(ctx, profile.IDFromPeerID(other), 5); len(peers) != 0 {
+			t.Errorf("%s ClosestConnectedQriPeers: expected no peers, got: %v", name, peers)
+		}
+		if peers := node.Peers(); len(peers) != 0 {
+			t.Errorf("%s Peers: expected no peers, got: %v", name, peers)
+		}
+		if peers := node.ConnectedPeers(); len(peers) != 0 {
+			t.Errorf("%s ConnectedPeers: expected no peers, got: %v", name, peers)
+		}
+		if peers, total := node.ConnectedPeersPage(0, 10); len(peers) != 0 || total != 0 {
+			t.Errorf("%s ConnectedPeersPage: expected no peers, got: %v", name, peers)
+		}
+		if pros := node.ConnectedQriProfiles(ctx); len(pros) != 0 {
+			t.Errorf("%s ConnectedQriProfiles: expected no profiles, got: %v", name, pros)
+		}
+		if node.Keys() != nil || node.Addrs() != nil {
+			t.Errorf("%s expected nil key & address books", name)
+		}
+		node.Bootstrap([]string{"/ip4/127.0.0.1/tcp/4001/ipfs/" + other.Pretty()})
+	}
+
+	if err := node.GoOffline(); err != nil {
+		t.Errorf("GoOffline: unexpected error: %s", err)
+	}
+}
@@ -20,6 +20,9 @@ var (
 	ErrQriProtocolNotSupported = fmt.Errorf("peer doesn't support the qri protocol")
 	// ErrNoQriNode indicates a qri node doesn't exist
 	ErrNoQriNode = fmt.Errorf("p2p: no qri node")
+	// ErrNodeOffline is returned when a method that requires a p2p host is
+	// called on a node that isn't online
+	ErrNodeOffline = fmt.Errorf("p2p: node offline")
 )
 
 const (
@@ -3,6 +3,7 @@ package p2p
 import (
 	"context"
 	"fmt"
+	"sort"
 
 	"github.com/qri-io/qri/config"
 	"github.com/qri-io/qri/profile"
@@ -42,18 +43,37 @@ func (n *QriNode) ConnectedQriProfiles(ctx context.Context) map[profile.ID]*conf
 	return peers
 }
 
+// ReconcileProfilePod merges a profile sent by a peer into the local profile
+// store, see QriProfileService.ReconcileProfilePod
+func (n *QriNode) ReconcileProfilePod(ctx context.Context, sender peer.ID, pod *config.ProfilePod) (*profile.Profile, bool, error) {
+	return n.qis.ReconcileProfilePod(ctx, sender, pod)
+}
+
 // ConnectedQriPeerIDs returns a slice of peer.IDs this peer is currently connected to
 func (n *QriNode) ConnectedQriPeerIDs() []peer.ID {
+	if n.qis == nil {
+		return []peer.ID{}
+	}
 	return n.qis.ConnectedQriPeers()
 }
 
+// ConnectedQriPeerIDsPage returns a page of the peer.IDs of connected qri
+// peers ordered by peer ID, along with the total number of connected qri
+// peers. A limit less than zero returns all peers after offset
+func (n *QriNode) ConnectedQriPeerIDsPage(offset, limit int) (ids []peer.ID, total int) {
+	ids = n.ConnectedQriPeerIDs()
+	sortPeerIDs(ids)
+	start, end := pageBounds(offset, limit, len(ids))
+	return ids[start:end], len(ids)
+}
+
 // ClosestConnectedQriPeers checks if a peer is connected, and if so adds it to the top
 // of a slice cap(max) of peers to try to connect to
 // TODO - In the future we'll use a few tricks to improve on just iterating the list
 // at a bare minimum we should grab a randomized set of peers
 func (n *QriNode) ClosestConnectedQriPeers(ctx context.Context, profileID profile.ID, max int) (pid []peer.ID) {
 	added := 0
-	if !n.Online {
+	if !n.Online || n.host == nil {
 		return []peer.ID{}
 	}
 
@@ -86,24 +106,72 @@ func (n *QriNode) ClosestConnectedQriPeers(ctx context.Context, profileID profil
 	return
 }
 
-// peerDifference returns a slice of peer IDs that are present in a but not b
-func peerDifference(a, b []peer.ID) (diff []peer.ID) {
-	m := make(map[peer.ID]bool)
-	for _, bid := range b {
-		m[bid] = true
+// PeerSetDifference returns the peer IDs present in a but not b, in the order
+// they appear in a. Duplicate IDs are only included once
+func PeerSetDifference(a, b []peer.ID) []peer.ID {
+	exclude := peerSet(b)
+	diff := make([]peer.ID, 0, len(a))
+	seen := make(map[peer.ID]struct{}, len(a))
+	for _, id := range a {
+		if _, ok := exclude[id]; ok {
+			continue
+		}
+		if _, ok := seen[id]; ok {
+			continue
+		}
+		seen[id] = struct{}{}
+		diff = append(diff, id)
 	}
+	return diff
+}
 
-	for _, aid := range a {
-		if _, ok := m[aid]; !ok {
-			diff = append(diff, aid)
+// PeerSetIntersection returns the peer IDs present in both a and b, in the
+// order they appear in a. Duplicate IDs are only included once
+func PeerSetIntersection(a, b []peer.ID) []peer.ID {
+	include := peerSet(b)
+	both := make([]peer.ID, 0)
+	seen := make(map[peer.ID]struct{})
+	for _, id := range a {
+		if _, ok := include[id]; !ok {
+			continue
 		}
+		if _, ok := seen[id]; ok {
+			continue
+		}
+		seen[id] = struct{}{}
+		both = append(both, id)
 	}
-	return
+	return both
+}
+
+// PeerSetUnion returns the peer IDs present in either a or b, listing the IDs
+// of a followed by any IDs only in b. Duplicate IDs are only included once
+func PeerSetUnion(a, b []peer.ID) []peer.ID {
+	union := make([]peer.ID, 0, len(a)+len(b))
+	seen := make(map[peer.ID]struct{}, len(a)+len(b))
+	for _, ids := range [][]peer.ID{a, b} {
+		for _, id := range ids {
+			if _, ok := seen[id]; ok {
+				continue
+			}
+			seen[id] = struct{}{}
+			union = append(union, id)
+		}
+	}
+	return union
+}
+
+func peerSet(ids []peer.ID) map[peer.ID]struct{} {
+	set := make(map[peer.ID]struct{}, len(ids))
+	for _, id := range ids {
+		set[id] = struct{}{}
+	}
+	return set
 }
 
 // PeerInfo returns peer peer ID & network multiaddrs from the Host Peerstore
 func (n *QriNode) PeerInfo(pid peer.ID) peer.AddrInfo {
-	if !n.Online {
+	if !n.Online || n.host == nil {
 		return peer.AddrInfo{}
 	}
 
@@ -149,6 +217,54 @@ func (n *QriNode) ConnectedPeers() []string {
 	return peers
 }
 
+// ConnectedPeersPage returns a page of connected IPFS peers ordered by peer
+// ID, along with the total number of connections. Peers are described the same
+// way as ConnectedPeers. A limit less than zero returns all peers after offset
+func (n *QriNode) ConnectedPeersPage(offset, limit int) (peers []string, total int) {
+	if n.host == nil {
+		return []string{}, 0
+	}
+	conns := n.host.Network().Conns()
+	ids := make([]peer.ID, len(conns))
+	for i, c := range conns {
+		ids[i] = c.RemotePeer()
+	}
+	sortPeerIDs(ids)
+
+	start, end := pageBounds(offset, limit, len(ids))
+	peers = make([]string, 0, end-start)
+	for _, id := range ids[start:end] {
+		desc := id.Pretty()
+		if ti := n.host.ConnManager().GetTagInfo(id); ti != nil {
+			desc = fmt.Sprintf("%s, %d, %v", id.Pretty(), ti.Value, ti.Tags)
+		}
+		peers = append(peers, desc)
+	}
+	return peers, len(ids)
+}
+
+// sortPeerIDs orders a slice of peer IDs in place
+func sortPeerIDs(ids []peer.ID) {
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+}
+
+// pageBounds returns the slice bounds of a page within a list of total items,
+// clamping offset & limit to the list. A negative limit extends to the end of
+// the list
+func pageBounds(offset, limit, total int) (start, end int) {
+	if offset < 0 {
+		offset = 0
+	}
+	if offset > total {
+		offset = total
+	}
+	end = total
+	if limit >= 0 && offset+limit < total {
+		end = offset + limit
+	}
+	return offset, end
+}
+
 // PeerConnectionParams defines parameters for the ConnectToPeer command
 type PeerConnectionParams struct {
 	Peername  string
@@ -160,7 +276,33 @@ type PeerConnectionParams struct {
 // ConnectToPeer takes a raw peer ID & tries to work out a route to that
 // peer, explicitly connecting to them.
 func (n *QriNode) ConnectToPeer(ctx context.Context, p PeerConnectionParams) (*profile.Profile, error) {
+	info, err := n.ConnectToPeerWithInfo(ctx, p)
+	if err != nil {
+		return nil, err
+	}
+	return info.Profile, nil
+}
+
+// PeerConnectionInfo describes an established connection to a qri peer
+type PeerConnectionInfo struct {
+	// Profile of the connected peer
+	Profile *profile.Profile
+	// PeerID of the connected peer
+	PeerID peer.ID
+	// Protocols the peer supports, as recorded in the peerstore
+	Protocols []string
+	// Multiaddr is the remote address of the connection
+	Multiaddr ma.Multiaddr
+}
+
+// ConnectToPeerWithInfo connects to a peer like ConnectToPeer, returning
+// details of the connection, including the protocols the peer speaks,
+// which can be used to confirm the qri protocol upgrade succeeded
+func (n *QriNode) ConnectToPeerWithInfo(ctx context.Context, p PeerConnectionParams) (*PeerConnectionInfo, error) {
 	log.Debugf("connect to peer: %v", p)
+	if n.host == nil {
+		return nil, ErrNodeOffline
+	}
 	pinfo, err := n.peerConnectionParamsToPeerInfo(ctx, p)
 	if err != nil {
 		return nil, err
@@ -183,15 +325,43 @@ func (n *QriNode) ConnectToPeer(ctx context.Context, p PeerConnectionParams) (*p
 
 	// ConnectedPeerProfile will return nil if the profile is not found
 	pro := n.qis.ConnectedPeerProfile(pinfo.ID)
-	if err == nil {
+	if pro == nil {
 		return nil, fmt.Errorf("unable to get profile from peer %q", pinfo.ID)
 	}
 
-	return pro, nil
+	// prefer keeping qri peers when the connection manager prunes connections
+	n.host.ConnManager().TagPeer(pinfo.ID, qriSupportKey, n.qriPeerTagValue())
+
+	info := &PeerConnectionInfo{
+		Profile: pro,
+		PeerID:  pinfo.ID,
+	}
+	if protocols, err := n.host.Peerstore().GetProtocols(pinfo.ID); err == nil {
+		info.Protocols = protocols
+	} else {
+		log.Debugw("getting peer protocols", "peerID", pinfo.ID, "err", err)
+	}
+	if conns := n.host.Network().ConnsToPeer(pinfo.ID); len(conns) > 0 {
+		info.Multiaddr = conns[0].RemoteMultiaddr()
+	}
+
+	return info, nil
+}
+
+// qriPeerTagValue returns the configured connection manager tag value for
+// qri peers, falling back to the default
+func (n *QriNode) qriPeerTagValue() int {
+	if n.cfg != nil && n.cfg.QriPeerTagValue > 0 {
+		return n.cfg.QriPeerTagValue
+	}
+	return qriSupportValue
 }
 
 // DisconnectFromPeer explicitly closes a connection to a peer
 func (n *QriNode) DisconnectFromPeer(ctx context.Context, p PeerConnectionParams) error {
+	if n.host == nil {
+		return ErrNodeOffline
+	}
 	pinfo, err := n.peerConnectionParamsToPeerInfo(ctx, p)
 	if err != nil {
 		return err
@@ -234,14 +404,35 @@ func (n *QriNode) peerConnectionParamsToPeerInfo(ctx context.Context, p PeerConn
 		return peer.AddrInfo{}, fmt.Errorf("no network info for %s", proID)
 	}
 
-	// TODO - there's ambiguity here that we should address, for now
-	// we'll just by default connect to the first peer
-	return n.getPeerInfo(ids[0])
+	// a profile may have many peers, prefer one we know speaks qri
+	return n.getPeerInfo(n.preferQriPeer(ids))
+}
+
+// preferQriPeer picks the first peer ID the peerstore knows supports the qri
+// protocol, falling back to the first peer ID if none are known to speak qri
+func (n *QriNode) preferQriPeer(ids []peer.ID) peer.ID {
+	if n.host == nil {
+		return ids[0]
+	}
+	for _, id := range ids {
+		protocols, err := n.host.Peerstore().SupportsProtocols(id, string(ProfileProtocolID), string(QriProtocolID), string(depQriProtocolID))
+		if err != nil {
+			log.Debugw("checking peer protocols", "peerID", id, "err", err)
+			continue
+		}
+		if len(protocols) > 0 {
+			return id
+		}
+	}
+	return ids[0]
 }
 
 // getPeerInfo first looks for local peer info, then tries to fall back to using IPFS
 // to do routing lookups
 func (n *QriNode) getPeerInfo(pid peer.ID) (peer.AddrInfo, error) {
+	if n.host == nil {
+		return peer.AddrInfo{}, ErrNodeOffline
+	}
 	// first check for local peer info
 	if pinfo := n.host.Peerstore().PeerInfo(pid); len(pinfo.ID) > 0 {
 		// _, err := n.RequestProfile(pinfo.ID)
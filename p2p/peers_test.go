@@ -2,9 +2,18 @@ package p2p
 
 import (
 	"context"
+	"sort"
 	"testing"
 
+	"github.com/google/go-cmp/cmp"
+	peer "github.com/libp2p/go-libp2p-core/peer"
+	peerstore "github.com/libp2p/go-libp2p-core/peerstore"
+	mocknet "github.com/libp2p/go-libp2p/p2p/net/mock"
+	ma "github.com/multiformats/go-multiaddr"
+	testkeys "github.com/qri-io/qri/auth/key/test"
+	"github.com/qri-io/qri/event"
 	p2ptest "github.com/qri-io/qri/p2p/test"
+	"github.com/qri-io/qri/profile"
 )
 
 // Convert from test nodes to non-test nodes.
@@ -94,3 +103,229 @@ func TestConnectedQriProfiles(t *testing.T) {
 		}
 	}
 }
+
+func TestConnectionParamsPreferQriPeer(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	factory := p2ptest.NewTestNodeFactory(NewTestableQriNode)
+	testPeers, err := p2ptest.NewTestNetwork(ctx, factory, 1)
+	if err != nil {
+		t.Fatalf("error creating network: %s", err.Error())
+	}
+	node := testPeers[0].(*QriNode)
+
+	addr, err := ma.NewMultiaddr("/ip4/127.0.0.1/tcp/4001")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// a profile with two peers, the first of which doesn't speak qri
+	otherPeer := testkeys.GetKeyData(10).PeerID
+	qriPeer := testkeys.GetKeyData(11).PeerID
+	pstore := node.host.Peerstore()
+	pstore.AddAddr(otherPeer, addr, peerstore.PermanentAddrTTL)
+	pstore.AddAddr(qriPeer, addr, peerstore.PermanentAddrTTL)
+	if err := pstore.AddProtocols(otherPeer, "/ipfs/bitswap/1.2.0"); err != nil {
+		t.Fatal(err)
+	}
+	if err := pstore.AddProtocols(qriPeer, "/ipfs/bitswap/1.2.0", string(ProfileProtocolID)); err != nil {
+		t.Fatal(err)
+	}
+
+	pro := &profile.Profile{
+		ID:       profile.IDFromPeerID(otherPeer),
+		Peername: "multi_peer",
+		PeerIDs:  []peer.ID{otherPeer, qriPeer},
+	}
+	if err := node.Repo.Profiles().PutProfile(ctx, pro); err != nil {
+		t.Fatal(err)
+	}
+
+	pinfo, err := node.peerConnectionParamsToPeerInfo(ctx, PeerConnectionParams{Peername: "multi_peer"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pinfo.ID != qriPeer {
+		t.Errorf("expected qri peer %s to be chosen, got %s", qriPeer, pinfo.ID)
+	}
+}
+
+func TestPeerSetOperations(t *testing.T) {
+	a := testkeys.GetKeyData(0).PeerID
+	b := testkeys.GetKeyData(1).PeerID
+	c := testkeys.GetKeyData(2).PeerID
+	d := testkeys.GetKeyData(3).PeerID
+
+	cases := []struct {
+		description  string
+		left, right  []peer.ID
+		difference   []peer.ID
+		intersection []peer.ID
+		union        []peer.ID
+	}{
+		{"both empty", nil, nil, []peer.ID{}, []peer.ID{}, []peer.ID{}},
+		{"left empty", nil, []peer.ID{a, b}, []peer.ID{}, []peer.ID{}, []peer.ID{a, b}},
+		{"right empty", []peer.ID{a, b}, nil, []peer.ID{a, b}, []peer.ID{}, []peer.ID{a, b}},
+		{"disjoint", []peer.ID{a, b}, []peer.ID{c, d}, []peer.ID{a, b}, []peer.ID{}, []peer.ID{a, b, c, d}},
+		{"overlapping", []peer.ID{a, b, c}, []peer.ID{c, d, b}, []peer.ID{a}, []peer.ID{b, c}, []peer.ID{a, b, c, d}},
+		{"identical", []peer.ID{a, b}, []peer.ID{b, a}, []peer.ID{}, []peer.ID{a, b}, []peer.ID{a, b}},
+		{"duplicates", []peer.ID{a, a, b, c}, []peer.ID{c, c}, []peer.ID{a, b}, []peer.ID{c}, []peer.ID{a, b, c}},
+	}
+
+	for _, c := range cases {
+		t.Run(c.description, func(t *testing.T) {
+			if diff := cmp.Diff(c.difference, PeerSetDifference(c.left, c.right)); diff != "" {
+				t.Errorf("difference mismatch (-want +got):\n%s", diff)
+			}
+			if diff := cmp.Diff(c.intersection, PeerSetIntersection(c.left, c.right)); diff != "" {
+				t.Errorf("intersection mismatch (-want +got):\n%s", diff)
+			}
+			if diff := cmp.Diff(c.union, PeerSetUnion(c.left, c.right)); diff != "" {
+				t.Errorf("union mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestConnectToPeerWithInfo(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	factory := p2ptest.NewTestNodeFactory(NewTestableQriNode)
+	testPeers, err := p2ptest.NewTestNetwork(ctx, factory, 2)
+	if err != nil {
+		t.Fatalf("error creating network: %s", err.Error())
+	}
+	nodes := asQriNodes(testPeers)
+
+	remote := testPeers[1].SimpleAddrInfo()
+	nodes[0].host.Peerstore().AddAddrs(remote.ID, remote.Addrs, peerstore.PermanentAddrTTL)
+
+	info, err := nodes[0].ConnectToPeerWithInfo(ctx, PeerConnectionParams{PeerID: remote.ID})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if info.Profile == nil {
+		t.Fatal("expected connection info to include the peer's profile")
+	}
+	if info.PeerID != remote.ID {
+		t.Errorf("peer ID mismatch. want: %s, got: %s", remote.ID, info.PeerID)
+	}
+	if info.Multiaddr == nil {
+		t.Error("expected connection info to include the connection's multiaddr")
+	}
+
+	found := false
+	for _, proto := range info.Protocols {
+		if proto == string(ProfileProtocolID) {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("expected protocols to include %q, got: %v", ProfileProtocolID, info.Protocols)
+	}
+
+	ti := nodes[0].host.ConnManager().GetTagInfo(remote.ID)
+	if ti == nil || ti.Tags[qriSupportKey] != qriSupportValue {
+		t.Errorf("expected connected qri peer to be tagged %q with value %d, got: %v", qriSupportKey, qriSupportValue, ti)
+	}
+}
+
+func TestConnectToPeerConfiguredTagValue(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	factory := p2ptest.NewTestNodeFactory(NewTestableQriNode)
+	testPeers, err := p2ptest.NewTestNetwork(ctx, factory, 2)
+	if err != nil {
+		t.Fatalf("error creating network: %s", err.Error())
+	}
+	nodes := asQriNodes(testPeers)
+	nodes[0].cfg.QriPeerTagValue = 250
+
+	remote := testPeers[1].SimpleAddrInfo()
+	nodes[0].host.Peerstore().AddAddrs(remote.ID, remote.Addrs, peerstore.PermanentAddrTTL)
+
+	if _, err := nodes[0].ConnectToPeer(ctx, PeerConnectionParams{PeerID: remote.ID}); err != nil {
+		t.Fatal(err)
+	}
+
+	ti := nodes[0].host.ConnManager().GetTagInfo(remote.ID)
+	if ti == nil || ti.Tags[qriSupportKey] != 250 {
+		t.Errorf("expected connected qri peer to be tagged %q with configured value 250, got: %v", qriSupportKey, ti)
+	}
+}
+
+func TestConnectedPeersPagination(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	const numPeers = 25
+	mn := mocknet.New(ctx)
+	for i := 0; i <= numPeers; i++ {
+		if _, err := mn.GenPeer(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := mn.LinkAll(); err != nil {
+		t.Fatal(err)
+	}
+	hosts := mn.Hosts()
+	local := hosts[0]
+	qis := NewQriProfileService(nil, event.NilBus)
+	for _, h := range hosts[1:] {
+		if _, err := mn.ConnectPeers(local.ID(), h.ID()); err != nil {
+			t.Fatal(err)
+		}
+		qis.peers[h.ID()] = make(chan struct{})
+	}
+	node := &QriNode{host: local, qis: qis}
+
+	cases := []struct {
+		offset, limit int
+		expectLen     int
+	}{
+		{0, 10, 10},
+		{10, 10, 10},
+		{20, 10, 5},
+		{25, 10, 0},
+		{100, 10, 0},
+		{5, -1, 20},
+	}
+
+	var allPeers []string
+	for _, c := range cases {
+		peers, total := node.ConnectedPeersPage(c.offset, c.limit)
+		if total != numPeers {
+			t.Errorf("ConnectedPeersPage(%d, %d) total mismatch. expected: %d, got: %d", c.offset, c.limit, numPeers, total)
+		}
+		if len(peers) != c.expectLen {
+			t.Errorf("ConnectedPeersPage(%d, %d) length mismatch. expected: %d, got: %d", c.offset, c.limit, c.expectLen, len(peers))
+		}
+		if c.limit == 10 {
+			allPeers = append(allPeers, peers...)
+		}
+
+		ids, total := node.ConnectedQriPeerIDsPage(c.offset, c.limit)
+		if total != numPeers {
+			t.Errorf("ConnectedQriPeerIDsPage(%d, %d) total mismatch. expected: %d, got: %d", c.offset, c.limit, numPeers, total)
+		}
+		if len(ids) != c.expectLen {
+			t.Errorf("ConnectedQriPeerIDsPage(%d, %d) length mismatch. expected: %d, got: %d", c.offset, c.limit, c.expectLen, len(ids))
+		}
+	}
+
+	// pages are stable, ordered by peer ID & don't overlap
+	if !sort.StringsAreSorted(allPeers) {
+		t.Errorf("expected pages to be ordered by peer ID")
+	}
+	if len(allPeers) != numPeers {
+		t.Errorf("expected pages to cover all %d peers, got %d", numPeers, len(allPeers))
+	}
+	first, _ := node.ConnectedPeersPage(0, 10)
+	if diff := cmp.Diff(allPeers[:10], first); diff != "" {
+		t.Errorf("expected repeated page request to be stable (-want +got):\n%s", diff)
+	}
+}
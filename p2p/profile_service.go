@@ -2,6 +2,8 @@ package p2p
 
 import (
 	"context"
+	"encoding/base64"
+	"errors"
 	"fmt"
 	"sync"
 	"time"
@@ -10,6 +12,7 @@ import (
 	"github.com/libp2p/go-libp2p-core/network"
 	"github.com/libp2p/go-libp2p-core/peer"
 	protocol "github.com/libp2p/go-libp2p-core/protocol"
+	"github.com/qri-io/qri/auth/key"
 	"github.com/qri-io/qri/config"
 	"github.com/qri-io/qri/event"
 	p2putil "github.com/qri-io/qri/p2p/p2putil"
@@ -34,6 +37,9 @@ var (
 	// ErrPeerNotFound is returned when the profile service cannot find the
 	// peer in question
 	ErrPeerNotFound = fmt.Errorf("peer not found")
+	// ErrProfileSenderMismatch is returned when a peer sends a profile that
+	// doesn't belong to the peer
+	ErrProfileSenderMismatch = fmt.Errorf("profile does not belong to sending peer")
 )
 
 // QriProfileService manages the profile exchange. This exchange should happen
@@ -237,7 +243,7 @@ func (q *QriProfileService) receiveAndStoreProfile(ctx context.Context, s networ
 	// This closes the stream not the underlying connection
 	defer s.Close()
 
-	pro, err := receiveProfile(s)
+	pro, proof, err := receiveProfile(s)
 	if err != nil {
 		log.Errorf("%s error reading profile message from %q: %s", s.Protocol(), s.Conn().RemotePeer(), err)
 		return
@@ -245,12 +251,135 @@ func (q *QriProfileService) receiveAndStoreProfile(ctx context.Context, s networ
 
 	log.Debugf("%s received profile message from %q %s", s.Protocol(), s.Conn().RemotePeer(), s.Conn().RemoteMultiaddr())
 
-	if err := q.profiles.PutProfile(ctx, pro); err != nil {
+	if _, _, err := q.reconcileProfile(ctx, s.Conn().RemotePeer(), pro, proof); err != nil {
 		log.Debugw("putting received profile in store", "err", err)
 	}
 	return
 }
 
+// ReconcileProfilePod merges a profile sent by peer "sender" into the profile
+// store. Profiles we haven't seen before are added, known profiles have their
+// mutable fields updated while preserving local-only data. Peers can only
+// speak for their own profile: pods with an ID that doesn't match the sender
+// are rejected with ErrProfileSenderMismatch, and the sender is the only peer
+// ID recorded for the profile. The owner's profile is never modified.
+// Returns the stored profile & true if the store changed, publishing an
+// ETP2PProfileUpdated event on change
+func (q *QriProfileService) ReconcileProfilePod(ctx context.Context, sender peer.ID, pod *config.ProfilePod) (*profile.Profile, bool, error) {
+	// never accept private keys from the network
+	remotePod := *pod
+	remotePod.PrivKey = ""
+	remote := &profile.Profile{}
+	if err := remote.Decode(&remotePod); err != nil {
+		return nil, false, fmt.Errorf("error decoding Profile from config.ProfilePod: %w", err)
+	}
+	return q.reconcileProfile(ctx, sender, remote, nil)
+}
+
+func (q *QriProfileService) reconcileProfile(ctx context.Context, sender peer.ID, remote *profile.Profile, proof *profileProof) (*profile.Profile, bool, error) {
+	if err := proof.verify(sender, remote.ID); err != nil {
+		return nil, false, err
+	}
+	// keys & online state are local to this node, and the only peer we can
+	// vouch for is the one we're talking to
+	remote.PrivKey = nil
+	remote.Online = false
+	remote.PeerIDs = []peer.ID{sender}
+	if own := q.profiles.Owner(ctx); own != nil && own.ID == remote.ID {
+		return own, false, nil
+	}
+
+	stored, err := q.profiles.GetProfile(ctx, remote.ID)
+	if errors.Is(err, profile.ErrNotFound) {
+		stored = remote
+	} else if err != nil {
+		return nil, false, err
+	} else {
+		changed := stored.MergeRemote(remote)
+		if !containsPeer(stored.PeerIDs, sender) {
+			stored.PeerIDs = append(stored.PeerIDs, sender)
+			changed = true
+		}
+		if !changed {
+			return stored, false, nil
+		}
+	}
+
+	if err := q.profiles.PutProfile(ctx, stored); err != nil {
+		return nil, false, err
+	}
+	if err := q.pub.Publish(ctx, event.ETP2PProfileUpdated, stored); err != nil {
+		log.Debugw("publishing profile updated event", "err", err)
+	}
+	return stored, true, nil
+}
+
+func containsPeer(ids []peer.ID, id peer.ID) bool {
+	for _, pid := range ids {
+		if pid == id {
+			return true
+		}
+	}
+	return false
+}
+
+// profileProof binds a profile to the peer that sends it. A profile's ID is
+// derived from the profile key, which doesn't have to be the key of the node
+// serving the profile, so nodes sign their peer ID with the profile key
+type profileProof struct {
+	PubKey    string `json:"pubkey"`
+	Signature string `json:"signature"`
+}
+
+func newProfileProof(pro *profile.Profile, sender peer.ID) (*profileProof, error) {
+	pubKey, err := key.EncodePubKeyB64(pro.PrivKey.GetPublic())
+	if err != nil {
+		return nil, err
+	}
+	sig, err := pro.PrivKey.Sign([]byte(sender))
+	if err != nil {
+		return nil, err
+	}
+	return &profileProof{
+		PubKey:    pubKey,
+		Signature: base64.StdEncoding.EncodeToString(sig),
+	}, nil
+}
+
+// verify checks that sender is allowed to speak for profile id, either
+// because the profile and peer share a key, or because the proof carries a
+// signature of the sender's peer ID made with the profile's key
+func (p *profileProof) verify(sender peer.ID, id profile.ID) error {
+	if id == profile.IDFromPeerID(sender) {
+		return nil
+	}
+	if p == nil {
+		return fmt.Errorf("%w: peer %s sent profile %s", ErrProfileSenderMismatch, sender, id)
+	}
+	pubKey, err := key.DecodeB64PubKey(p.PubKey)
+	if err != nil {
+		return fmt.Errorf("%w: %s", ErrProfileSenderMismatch, err)
+	}
+	if keyID, err := key.IDFromPubKey(pubKey); err != nil || keyID != id.Encode() {
+		return fmt.Errorf("%w: key does not match profile %s", ErrProfileSenderMismatch, id)
+	}
+	sig, err := base64.StdEncoding.DecodeString(p.Signature)
+	if err != nil {
+		return fmt.Errorf("%w: %s", ErrProfileSenderMismatch, err)
+	}
+	if ok, err := pubKey.Verify([]byte(sender), sig); err != nil || !ok {
+		return fmt.Errorf("%w: invalid signature from peer %s for profile %s", ErrProfileSenderMismatch, sender, id)
+	}
+	return nil
+}
+
+// profileMessage is the profile exchange wire format. Embedding the pod keeps
+// the message readable by peers that decode a plain config.ProfilePod
+type profileMessage struct {
+	*config.ProfilePod
+	Proof *profileProof `json:"proof,omitempty"`
+}
+
 func sendProfile(s network.Stream, pro *profile.Profile) error {
 	ws := p2putil.WrapStream(s)
 
@@ -258,8 +387,16 @@ func sendProfile(s network.Stream, pro *profile.Profile) error {
 	if err != nil {
 		return fmt.Errorf("error encoding profile.Profile to config.ProfilePod: %s", err)
 	}
+	// never send private keys over the wire
+	pod.PrivKey = ""
+	msg := profileMessage{ProfilePod: pod}
+	if pro.PrivKey != nil {
+		if msg.Proof, err = newProfileProof(pro, s.Conn().LocalPeer()); err != nil {
+			return fmt.Errorf("error signing profile: %s", err)
+		}
+	}
 
-	if err := ws.Enc.Encode(&pod); err != nil {
+	if err := ws.Enc.Encode(&msg); err != nil {
 		return fmt.Errorf("error encoding profile to wrapped stream: %s", err)
 	}
 
@@ -270,15 +407,16 @@ func sendProfile(s network.Stream, pro *profile.Profile) error {
 	return nil
 }
 
-func receiveProfile(s network.Stream) (*profile.Profile, error) {
+func receiveProfile(s network.Stream) (*profile.Profile, *profileProof, error) {
 	ws := p2putil.WrapStream(s)
-	pod := &config.ProfilePod{}
-	if err := ws.Dec.Decode(&pod); err != nil {
-		return nil, fmt.Errorf("error decoding config.ProfilePod from wrapped stream: %s", err)
+	msg := &profileMessage{ProfilePod: &config.ProfilePod{}}
+	if err := ws.Dec.Decode(msg); err != nil {
+		return nil, nil, fmt.Errorf("error decoding config.ProfilePod from wrapped stream: %s", err)
 	}
+	msg.PrivKey = ""
 	pro := &profile.Profile{}
-	if err := pro.Decode(pod); err != nil {
-		return nil, fmt.Errorf("error decoding Profile from config.ProfilePod: %s", err)
+	if err := pro.Decode(msg.ProfilePod); err != nil {
+		return nil, nil, fmt.Errorf("error decoding Profile from config.ProfilePod: %s", err)
 	}
-	return pro, nil
+	return pro, msg.Proof, nil
 }
@@ -2,13 +2,18 @@ package p2p
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"sort"
 	"sync"
 	"testing"
 	"time"
 
+	"github.com/google/go-cmp/cmp"
 	peer "github.com/libp2p/go-libp2p-core/peer"
+	"github.com/qri-io/qri/auth/key"
+	testkeys "github.com/qri-io/qri/auth/key/test"
+	"github.com/qri-io/qri/config"
 	"github.com/qri-io/qri/event"
 	p2ptest "github.com/qri-io/qri/p2p/test"
 	"github.com/qri-io/qri/profile"
@@ -283,3 +288,152 @@ func TestDiscoveryConnection(t *testing.T) {
 	nodeA.GoOffline()
 	nodeB.GoOffline()
 }
+
+func TestReconcileProfilePod(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	owner, err := profile.NewSparsePKProfile("owner", testkeys.GetKeyData(0).PrivKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ks, err := key.NewMemStore()
+	if err != nil {
+		t.Fatal(err)
+	}
+	store, err := profile.NewMemStore(ctx, owner, ks)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	created := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	updated := created.Add(time.Hour)
+	remoteKeys := testkeys.GetKeyData(1)
+	known := &profile.Profile{
+		ID:          profile.IDFromPeerID(remoteKeys.PeerID),
+		Peername:    "remote",
+		Created:     created,
+		Updated:     updated,
+		Description: "old bio",
+		Photo:       "/ipfs/QmOldAvatar",
+		PeerIDs:     []peer.ID{remoteKeys.PeerID},
+	}
+	if err := store.PutProfile(ctx, known); err != nil {
+		t.Fatal(err)
+	}
+
+	bus := event.NewBus(ctx)
+	var (
+		lk      sync.Mutex
+		updates []*profile.Profile
+	)
+	bus.SubscribeTypes(func(_ context.Context, e event.Event) error {
+		lk.Lock()
+		defer lk.Unlock()
+		updates = append(updates, e.Payload.(*profile.Profile))
+		return nil
+	}, event.ETP2PProfileUpdated)
+
+	qis := NewQriProfileService(store, bus)
+	otherPeer := testkeys.GetKeyData(2).PeerID
+	pod := &config.ProfilePod{
+		ID:          known.ID.Encode(),
+		Type:        "peer",
+		Peername:    "remote",
+		Updated:     updated.Add(time.Hour),
+		Description: "new bio",
+		Photo:       "/ipfs/QmNewAvatar",
+		Online:      true,
+		PeerIDs:     []string{"/ipfs/" + otherPeer.Pretty()},
+	}
+
+	got, changed, err := qis.ReconcileProfilePod(ctx, remoteKeys.PeerID, pod)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !changed {
+		t.Fatal("expected updated profile to change the store")
+	}
+
+	stored, err := store.GetProfile(ctx, known.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stored.Description != "new bio" || stored.Photo != "/ipfs/QmNewAvatar" {
+		t.Errorf("expected mutable fields to update, got description %q photo %q", stored.Description, stored.Photo)
+	}
+	if !stored.Created.Equal(created) {
+		t.Errorf("expected local created timestamp to be preserved, got: %s", stored.Created)
+	}
+	if !stored.Updated.Equal(pod.Updated) {
+		t.Errorf("expected updated timestamp %s, got: %s", pod.Updated, stored.Updated)
+	}
+	if stored.Online {
+		t.Errorf("expected online state not to be taken from the network")
+	}
+	if diff := cmp.Diff([]peer.ID{remoteKeys.PeerID}, stored.PeerIDs); diff != "" {
+		t.Errorf("expected peerIDs claimed by the profile to be ignored (-want +got):\n%s", diff)
+	}
+	if got.Description != stored.Description {
+		t.Errorf("expected returned profile to match the store")
+	}
+	if len(updates) != 1 || updates[0].ID != known.ID {
+		t.Errorf("expected one %q event for the updated profile, got: %v", event.ETP2PProfileUpdated, updates)
+	}
+
+	// stale pods don't overwrite newer data
+	stale := *pod
+	stale.Description = "stale bio"
+	stale.Updated = created
+	if _, changed, err := qis.ReconcileProfilePod(ctx, remoteKeys.PeerID, &stale); err != nil || changed {
+		t.Errorf("expected stale profile to be ignored, got changed: %t err: %v", changed, err)
+	}
+
+	// receiving the same pod again is a no-op
+	if _, changed, err := qis.ReconcileProfilePod(ctx, remoteKeys.PeerID, pod); err != nil || changed {
+		t.Errorf("expected repeated profile to be a no-op, got changed: %t err: %v", changed, err)
+	}
+
+	// the owner's profile is never modified from the network
+	ownerPod := &config.ProfilePod{ID: owner.ID.Encode(), Type: "peer", Peername: "impostor", Updated: time.Now()}
+	if _, changed, err := qis.ReconcileProfilePod(ctx, testkeys.GetKeyData(0).PeerID, ownerPod); err != nil || changed {
+		t.Errorf("expected owner profile to be left alone, got changed: %t err: %v", changed, err)
+	}
+	if own := store.Owner(ctx); own.Peername != "owner" {
+		t.Errorf("expected owner peername to be preserved, got: %q", own.Peername)
+	}
+
+	// peers can't speak for other profiles
+	forged := *pod
+	forged.Description = "forged bio"
+	forged.Updated = time.Now()
+	if _, _, err := qis.ReconcileProfilePod(ctx, otherPeer, &forged); !errors.Is(err, ErrProfileSenderMismatch) {
+		t.Errorf("expected profile from a different peer to fail with %q, got: %v", ErrProfileSenderMismatch, err)
+	}
+	if stored, _ := store.GetProfile(ctx, known.ID); stored.Description != "new bio" {
+		t.Errorf("expected forged profile not to change the store, got description: %q", stored.Description)
+	}
+
+	if len(updates) != 1 {
+		t.Errorf("expected no further update events, got %d", len(updates))
+	}
+
+	// peers can serve a profile with a different key if they prove the
+	// profile key signed off on the peer
+	signed, err := profile.NewSparsePKProfile("signed", testkeys.GetKeyData(3).PrivKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	proof, err := newProfileProof(signed, remoteKeys.PeerID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	replayed := &profile.Profile{ID: signed.ID, Peername: "signed"}
+	if _, _, err := qis.reconcileProfile(ctx, otherPeer, replayed, proof); !errors.Is(err, ErrProfileSenderMismatch) {
+		t.Errorf("expected proof for a different peer to fail with %q, got: %v", ErrProfileSenderMismatch, err)
+	}
+	received := &profile.Profile{ID: signed.ID, Peername: "signed"}
+	if _, changed, err := qis.reconcileProfile(ctx, remoteKeys.PeerID, received, proof); err != nil || !changed {
+		t.Errorf("expected signed profile to be stored, got changed: %t err: %v", changed, err)
+	}
+}
@@ -198,6 +198,64 @@ func (p Profile) Encode() (*config.ProfilePod, error) {
 	return pp, nil
 }
 
+// MergeRemote updates p with the publicly-visible fields of a profile
+// received from the network, returning true if any field changed. Local-only
+// data like keys, creation time, & online state is preserved, and network
+// addresses are combined. PeerIDs are never taken from remote, a profile can
+// claim any peer, so callers must add peer IDs they've authenticated
+// themselves. Remote profiles for a different ID, or that were updated before
+// p are ignored
+func (p *Profile) MergeRemote(remote *Profile) (changed bool) {
+	if remote == nil || remote.ID != p.ID {
+		return false
+	}
+	if !remote.Updated.IsZero() && remote.Updated.Before(p.Updated) {
+		return false
+	}
+
+	set := func(dst *string, src string) {
+		if *dst != src {
+			*dst = src
+			changed = true
+		}
+	}
+	set(&p.Peername, remote.Peername)
+	set(&p.Email, remote.Email)
+	set(&p.Name, remote.Name)
+	set(&p.Description, remote.Description)
+	set(&p.HomeURL, remote.HomeURL)
+	set(&p.Color, remote.Color)
+	set(&p.Thumb, remote.Thumb)
+	set(&p.Photo, remote.Photo)
+	set(&p.Poster, remote.Poster)
+	set(&p.Twitter, remote.Twitter)
+	if p.Type != remote.Type {
+		p.Type = remote.Type
+		changed = true
+	}
+
+	for _, addr := range remote.NetworkAddrs {
+		if !containsMultiaddr(p.NetworkAddrs, addr) {
+			p.NetworkAddrs = append(p.NetworkAddrs, addr)
+			changed = true
+		}
+	}
+
+	if changed && remote.Updated.After(p.Updated) {
+		p.Updated = remote.Updated
+	}
+	return changed
+}
+
+func containsMultiaddr(addrs []ma.Multiaddr, addr ma.Multiaddr) bool {
+	for _, a := range addrs {
+		if a.Equal(addr) {
+			return true
+		}
+	}
+	return false
+}
+
 // ValidOwnerProfile checks if a profile can be used as an owner profile
 func (p *Profile) ValidOwnerProfile() error {
 	if p == nil {
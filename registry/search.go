@@ -2,7 +2,10 @@ package registry
 
 import (
 	"fmt"
+	"html"
+	"regexp"
 	"strings"
+	"unicode/utf8"
 
 	"github.com/qri-io/dataset"
 )
@@ -33,6 +36,114 @@ type SearchResult struct {
 	ID    string           `json:"id"`
 	URL   string           `json:"url"`
 	Value *dataset.Dataset `json:"value"`
+	// Snippets describe the fields of Value that matched the search query
+	Snippets []SearchSnippet `json:"snippets,omitempty"`
+}
+
+const (
+	// HighlightStart marks the beginning of a query match within a snippet
+	HighlightStart = "<mark>"
+	// HighlightEnd marks the end of a query match within a snippet
+	HighlightEnd = "</mark>"
+	// snippetContext is the number of bytes of surrounding text to include on
+	// either side of a match
+	snippetContext = 40
+)
+
+// SearchSnippet is an excerpt of a dataset field that matched a search query,
+// with the match wrapped in HighlightStart and HighlightEnd. Snippet text is
+// HTML-escaped
+type SearchSnippet struct {
+	Field   string `json:"field"`
+	Snippet string `json:"snippet"`
+}
+
+// Snippets computes matched-field snippets for a dataset & search query,
+// checking the dataset name, meta title, description, and keywords. Matching
+// is case-insensitive. Snippets returns nil if the query is empty or nothing
+// matches
+func Snippets(ds *dataset.Dataset, query string) []SearchSnippet {
+	query = strings.TrimSpace(query)
+	if ds == nil || query == "" {
+		return nil
+	}
+	re, err := regexp.Compile("(?i)" + regexp.QuoteMeta(query))
+	if err != nil {
+		return nil
+	}
+
+	fields := []struct{ name, text string }{
+		{"name", ds.Name},
+	}
+	if ds.Meta != nil {
+		fields = append(fields,
+			struct{ name, text string }{"meta.title", ds.Meta.Title},
+			struct{ name, text string }{"meta.description", ds.Meta.Description},
+		)
+		for i, kw := range ds.Meta.Keywords {
+			fields = append(fields, struct{ name, text string }{fmt.Sprintf("meta.keywords.%d", i), kw})
+		}
+	}
+
+	var snippets []SearchSnippet
+	for _, f := range fields {
+		loc := re.FindStringIndex(f.text)
+		if loc == nil {
+			continue
+		}
+		snippets = append(snippets, SearchSnippet{
+			Field:   f.name,
+			Snippet: highlight(f.text, loc[0], loc[1]),
+		})
+	}
+	return snippets
+}
+
+// highlight excerpts text around the match in the range [start, end),
+// wrapping the match in highlight markers & eliding text outside the window.
+// Excerpted text is HTML-escaped so the markers are the only markup in the
+// snippet
+func highlight(text string, start, end int) string {
+	from := start - snippetContext
+	if from < 0 {
+		from = 0
+	}
+	for from > 0 && !utf8.RuneStart(text[from]) {
+		from--
+	}
+	// avoid starting mid-word
+	if from > 0 {
+		if i := strings.IndexByte(text[from:start], ' '); i >= 0 {
+			from += i + 1
+		}
+	}
+	to := end + snippetContext
+	if to > len(text) {
+		to = len(text)
+	}
+	for to < len(text) && !utf8.RuneStart(text[to]) {
+		to++
+	}
+	// avoid ending mid-word
+	if to < len(text) {
+		if i := strings.LastIndexByte(text[end:to], ' '); i >= 0 {
+			to = end + i
+		}
+	}
+
+	b := strings.Builder{}
+	if from > 0 {
+		b.WriteString("...")
+	}
+	b.WriteString(html.EscapeString(text[from:start]))
+	b.WriteString(HighlightStart)
+	b.WriteString(html.EscapeString(text[start:end]))
+	b.WriteString(HighlightEnd)
+	b.WriteString(html.EscapeString(text[end:to]))
+	if to < len(text) {
+		b.WriteString("...")
+	}
+	return b.String()
 }
 
 // ErrSearchNotSupported is the canonical error to indicate search
@@ -71,9 +182,10 @@ func (ms MockSearch) Search(p SearchParams) (results []SearchResult, err error)
 
 	for _, v := range res {
 		results = append(results, SearchResult{
-			Type:  "dataset",
-			ID:    v.Path,
-			Value: v,
+			Type:     "dataset",
+			ID:       v.Path,
+			Value:    v,
+			Snippets: Snippets(v, p.Q),
 		})
 	}
 
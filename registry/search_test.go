@@ -0,0 +1,24 @@
+package registry
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/qri-io/dataset"
+)
+
+func TestSnippetsEscapeHTML(t *testing.T) {
+	ds := &dataset.Dataset{
+		Name: "weather",
+		Meta: &dataset.Meta{
+			Description: `<script>alert("hi")</script> rain & <b>snow</b> totals`,
+		},
+	}
+	got := Snippets(ds, "<b>snow")
+	expect := []SearchSnippet{
+		{Field: "meta.description", Snippet: `&lt;script&gt;alert(&#34;hi&#34;)&lt;/script&gt; rain &amp; <mark>&lt;b&gt;snow</mark>&lt;/b&gt; totals`},
+	}
+	if diff := cmp.Diff(expect, got); diff != "" {
+		t.Errorf("snippet mismatch (-want +got):\n%s", diff)
+	}
+}
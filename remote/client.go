@@ -3,14 +3,19 @@ package remote
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io/ioutil"
 	"net/http"
 	"net/url"
 	"strings"
+	"sync"
 	"time"
 
+	cid "github.com/ipfs/go-cid"
+	ipld "github.com/ipfs/go-ipld-format"
 	coreiface "github.com/ipfs/interface-go-ipfs-core"
+	ipfspath "github.com/ipfs/interface-go-ipfs-core/path"
 	crypto "github.com/libp2p/go-libp2p-core/crypto"
 	peer "github.com/libp2p/go-libp2p-core/peer"
 	"github.com/qri-io/dag/dsync"
@@ -19,6 +24,7 @@ import (
 	"github.com/qri-io/qri/auth/key"
 	"github.com/qri-io/qri/base"
 	"github.com/qri-io/qri/base/dsfs"
+	"github.com/qri-io/qri/base/params"
 	"github.com/qri-io/qri/dsref"
 	"github.com/qri-io/qri/event"
 	"github.com/qri-io/qri/logbook/logsync"
@@ -35,6 +41,9 @@ var (
 	ErrNoRemoteClient = fmt.Errorf("remote: no client to make remote requests")
 	// ErrRemoteNotFound indicates a specified remote couldn't be located
 	ErrRemoteNotFound = fmt.Errorf("remote not found")
+	// ErrIntegrityCheck indicates pulled data doesn't match the content address
+	// it was requested by
+	ErrIntegrityCheck = fmt.Errorf("remote: pulled data failed integrity check")
 )
 
 // ClientConstructor is a factory function that creates client implementations
@@ -48,6 +57,12 @@ type Client interface {
 	Feeds(ctx context.Context, remoteAddr string) (map[string][]dsref.VersionInfo, error)
 	// Feed fetches a named feed of datasets
 	Feed(ctx context.Context, remoteAddr, feedName string, page, pageSize int) ([]dsref.VersionInfo, error)
+	// ListPeerDatasets fetches a page of the datasets a remote holds for a peer
+	ListPeerDatasets(ctx context.Context, remoteAddr, peername string, lp params.List) ([]dsref.VersionInfo, error)
+	// TrustSigner accepts previews from remoteAddr signed by the profile pid.
+	// Previews signed by the dataset author are always accepted. Once a signer
+	// is trusted for a remote, unsigned & untrusted previews are rejected
+	TrustSigner(remoteAddr string, pid profile.ID)
 	// Preview fetches a size-bounded subset of a single dataset version,
 	// summarizing the contents of the dataset version
 	PreviewDatasetVersion(ctx context.Context, ref dsref.Ref, remoteAddr string) (*dataset.Dataset, error)
@@ -85,11 +100,15 @@ type client struct {
 	profile *profile.Profile
 	pk      crypto.PrivKey
 	ds      *dsync.Dsync
+	lng     ipld.NodeGetter
 	logsync *logsync.Logsync
 	capi    coreiface.CoreAPI
 	node    *p2p.QriNode
 	events  event.Publisher
 
+	trustLk sync.Mutex
+	trusted map[string]profile.ID
+
 	doneCh   chan struct{}
 	doneErr  error
 	shutdown context.CancelFunc
@@ -98,10 +117,13 @@ type client struct {
 // NewClient creates a remote client suitable for syncing peers
 func NewClient(ctx context.Context, node *p2p.QriNode, pub event.Publisher) (c Client, err error) {
 	ctx, cancel := context.WithCancel(ctx)
-	var ds *dsync.Dsync
+	var (
+		ds  *dsync.Dsync
+		lng ipld.NodeGetter
+	)
 	capi, capiErr := node.IPFSCoreAPI()
 	if capiErr == nil {
-		lng, err := dsync.NewLocalNodeGetter(capi)
+		lng, err = dsync.NewLocalNodeGetter(capi)
 		if err != nil {
 			cancel()
 			return nil, err
@@ -140,6 +162,7 @@ func NewClient(ctx context.Context, node *p2p.QriNode, pub event.Publisher) (c C
 		pk:      node.Repo.Profiles().Owner(ctx).PrivKey,
 		profile: pro,
 		ds:      ds,
+		lng:     lng,
 		logsync: ls,
 		capi:    capi,
 		node:    node,
@@ -254,6 +277,80 @@ func (c *client) Feed(ctx context.Context, remoteAddr, feedName string, page, pa
 	return env.Data, nil
 }
 
+// ListPeerDatasets fetches a page of the datasets a remote holds for a peer
+func (c *client) ListPeerDatasets(ctx context.Context, remoteAddr, peername string, lp params.List) ([]dsref.VersionInfo, error) {
+	log.Debugf("client.ListPeerDatasets remoteAddr=%q peername=%q offset=%d limit=%d", remoteAddr, peername, lp.Offset, lp.Limit)
+	if at := addressType(remoteAddr); at != "http" {
+		return nil, fmt.Errorf("listing peer datasets is only supported over HTTP")
+	}
+
+	u := fmt.Sprintf("%s/remote/datasets/%s?offset=%d&limit=%d", remoteAddr, url.PathEscape(peername), lp.Offset, lp.Limit)
+	req, err := http.NewRequest("GET", u, nil)
+	if err != nil {
+		return nil, err
+	}
+	req = req.WithContext(ctx)
+
+	if err := c.signHTTPRequest(ctx, req); err != nil {
+		return nil, err
+	}
+
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		if strings.Contains(err.Error(), "no such host") {
+			return nil, ErrNoRemoteClient
+		}
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	// add response to an envelope
+	env := struct {
+		Data []dsref.VersionInfo
+		Meta struct {
+			Error  string
+			Status string
+			Code   int
+		}
+	}{}
+
+	if err := json.NewDecoder(res.Body).Decode(&env); err != nil {
+		return nil, err
+	}
+
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("error %d: %s", res.StatusCode, env.Meta.Error)
+	}
+
+	return env.Data, nil
+}
+
+// TrustSigner accepts previews from remoteAddr signed by the profile pid
+func (c *client) TrustSigner(remoteAddr string, pid profile.ID) {
+	c.trustLk.Lock()
+	defer c.trustLk.Unlock()
+	if c.trusted == nil {
+		c.trusted = map[string]profile.ID{}
+	}
+	c.trusted[remoteAddr] = pid
+}
+
+// trustedSigners lists the profile IDs a preview of ref from remoteAddr may be
+// signed by. pinned reports whether a signer has been trusted for remoteAddr,
+// which requires previews from the remote to carry a trusted signature
+func (c *client) trustedSigners(ref dsref.Ref, remoteAddr string) (ids []string, pinned bool) {
+	if ref.ProfileID != "" {
+		ids = append(ids, ref.ProfileID)
+	}
+	c.trustLk.Lock()
+	defer c.trustLk.Unlock()
+	if pid, ok := c.trusted[remoteAddr]; ok {
+		ids = append(ids, pid.Encode())
+		pinned = true
+	}
+	return ids, pinned
+}
+
 // PreviewDatasetVersion fetches a dataset preview from the registry
 func (c *client) PreviewDatasetVersion(ctx context.Context, ref dsref.Ref, remoteAddr string) (*dataset.Dataset, error) {
 	log.Debugf("client.PreviewDatasetVersion ref=%q remoteAddr=%q", ref, remoteAddr)
@@ -286,9 +383,10 @@ func (c *client) previewDatasetVersionHTTP(ctx context.Context, ref dsref.Ref, r
 		return nil, err
 	}
 
-	// add response to an envelope
+	// add response to an envelope, keeping the raw preview bytes for signature
+	// verification
 	env := struct {
-		Data *dataset.Dataset
+		Data json.RawMessage
 		Meta struct {
 			Error  string
 			Status string
@@ -304,7 +402,17 @@ func (c *client) previewDatasetVersionHTTP(ctx context.Context, ref dsref.Ref, r
 		return nil, fmt.Errorf("error %d: %s", res.StatusCode, env.Meta.Error)
 	}
 
-	return env.Data, nil
+	trusted, pinned := c.trustedSigners(ref, remoteAddr)
+	if err := verifyPreviewSignature(res.Header, env.Data, trusted, pinned); err != nil {
+		log.Debugw("verifying preview signature", "remoteAddr", remoteAddr, "err", err)
+		return nil, err
+	}
+
+	ds := &dataset.Dataset{}
+	if err := json.Unmarshal(env.Data, ds); err != nil {
+		return nil, err
+	}
+	return ds, nil
 }
 
 // NewRemoteRefResolver creates a resolver backed by a remote
@@ -500,6 +608,16 @@ func (c *client) PullDataset(ctx context.Context, ref *dsref.Ref, remoteAddr str
 
 	node := c.node
 
+	// logs for datasets we haven't seen before are removed if the pull fails
+	// part way through, so a cancelled pull doesn't leave a dataset with no
+	// data behind
+	book := node.Repo.Logbook()
+	newLog := false
+	if book != nil && ref.InitID != "" {
+		_, err := book.Log(ctx, ref.InitID)
+		newLog = errors.Is(err, oplog.ErrNotFound)
+	}
+
 	if err := c.pullLogs(ctx, *ref, remoteAddr); err != nil {
 		log.Debugf("client.pullLogs error=%q", err)
 		return nil, err
@@ -507,6 +625,9 @@ func (c *client) PullDataset(ctx context.Context, ref *dsref.Ref, remoteAddr str
 
 	if err := c.pullDatasetVersion(ctx, ref, remoteAddr); err != nil {
 		log.Debugf("client.pullDatasetVersion error=%q", err)
+		if newLog {
+			c.removePulledLog(*ref)
+		}
 		return nil, err
 	}
 	node.LocalStreams.PrintErr(fmt.Sprintf("🗼 fetched from remote %q\n", remoteAddr))
@@ -536,8 +657,7 @@ func (c *client) PullDataset(ctx context.Context, ref *dsref.Ref, remoteAddr str
 			return nil, fmt.Errorf("error loading added dataset: %s", ref.Path)
 		}
 
-		vi := dsref.ConvertDatasetToVersionInfo(ds)
-		if err := c.events.Publish(ctx, event.ETDatasetPulled, vi); err != nil {
+		if err := c.events.Publish(ctx, event.ETDatasetPulled, c.pulledVersionInfo(*ref, ds)); err != nil {
 			return nil, err
 		}
 
@@ -564,14 +684,36 @@ func (c *client) PullDataset(ctx context.Context, ref *dsref.Ref, remoteAddr str
 		return nil, err
 	}
 
-	vi := dsref.ConvertDatasetToVersionInfo(ds)
-	if err := c.events.Publish(ctx, event.ETDatasetPulled, vi); err != nil {
+	if err := c.events.Publish(ctx, event.ETDatasetPulled, c.pulledVersionInfo(*ref, ds)); err != nil {
 		return nil, err
 	}
 
 	return ds, nil
 }
 
+// removePulledLog drops a log that was fetched for a pull that didn't
+// complete. ctx may already be cancelled, so cleanup gets a context of its own
+func (c *client) removePulledLog(ref dsref.Ref) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*2)
+	defer cancel()
+	if err := c.node.Repo.Logbook().RemoveLog(ctx, ref); err != nil {
+		log.Debugw("removing log for incomplete pull", "ref", ref, "err", err)
+	}
+}
+
+// pulledVersionInfo describes a pulled dataset version. Datasets authored by
+// anyone other than the client's profile are marked as foreign
+func (c *client) pulledVersionInfo(ref dsref.Ref, ds *dataset.Dataset) dsref.VersionInfo {
+	vi := dsref.ConvertDatasetToVersionInfo(ds)
+	vi.InitID = ref.InitID
+	vi.Username = ref.Username
+	vi.ProfileID = ref.ProfileID
+	vi.Name = ref.Name
+	vi.Path = ref.Path
+	vi.Foreign = c.profile == nil || ref.ProfileID != c.profile.ID.Encode()
+	return vi
+}
+
 // pullLogs fetches logbook data from a remote & stores it locally
 func (c *client) pullLogs(ctx context.Context, ref dsref.Ref, remoteAddr string) error {
 	log.Debugf("client.pullLogs ref=%q remoteAddr=%q", ref, remoteAddr)
@@ -638,7 +780,34 @@ func (c *client) pullDatasetVersion(ctx context.Context, ref *dsref.Ref, remoteA
 		}
 	}()
 
-	if err := pull.Do(ctx); err != nil {
+	// the transfer itself doesn't stop when ctx is cancelled, so wait on
+	// either one to finish. A cancelled transfer keeps writing blocks until it
+	// exits, so cleanup of partially pulled blocks waits on it in the background
+	path := ref.Path
+	pullErr := make(chan error, 1)
+	go func() { pullErr <- pull.Do(ctx) }()
+	select {
+	case err := <-pullErr:
+		if err != nil {
+			c.removeUnpinnedBlocks(path)
+			return err
+		}
+	case <-ctx.Done():
+		go func() {
+			<-pullErr
+			c.removeUnpinnedBlocks(path)
+		}()
+		return ctx.Err()
+	}
+	// per-block transfers end without error when cancelled
+	if err := ctx.Err(); err != nil {
+		c.removeUnpinnedBlocks(path)
+		return err
+	}
+
+	// don't trust the remote to have sent what we asked for
+	if err := verifyDAG(ctx, c.lng, path); err != nil {
+		c.removeUnpinnedBlocks(path)
 		return err
 	}
 
@@ -659,6 +828,80 @@ func (c *client) pullDatasetVersion(ctx context.Context, ref *dsref.Ref, remoteA
 	return c.events.Publish(ctx, event.ETRemoteClientPullVersionCompleted, progEvt)
 }
 
+// verifyDAG walks the DAG rooted at path in the local block store, confirming
+// every block is present and its content hashes to its CID
+func verifyDAG(ctx context.Context, ng ipld.NodeGetter, path string) error {
+	root, err := cid.Parse(path)
+	if err != nil {
+		return err
+	}
+
+	seen := map[cid.Cid]struct{}{}
+	queue := []cid.Cid{root}
+	for len(queue) > 0 {
+		id := queue[0]
+		queue = queue[1:]
+		if _, ok := seen[id]; ok {
+			continue
+		}
+		seen[id] = struct{}{}
+
+		nd, err := ng.Get(ctx, id)
+		if err != nil {
+			return fmt.Errorf("%w: block %s: %s", ErrIntegrityCheck, id, err)
+		}
+		sum, err := id.Prefix().Sum(nd.RawData())
+		if err != nil {
+			return err
+		}
+		if !sum.Equals(id) {
+			return fmt.Errorf("%w: block %s hashes to %s", ErrIntegrityCheck, id, sum)
+		}
+		for _, l := range nd.Links() {
+			queue = append(queue, l.Cid)
+		}
+	}
+	return nil
+}
+
+// removeUnpinnedBlocks drops the locally stored blocks of the DAG rooted at
+// path, cleaning up after a pull that didn't finish. Blocks that are pinned by
+// another dataset version are kept
+func (c *client) removeUnpinnedBlocks(path string) {
+	if c.capi == nil {
+		return
+	}
+	root, err := cid.Parse(path)
+	if err != nil {
+		log.Debugf("removing pulled blocks: %s", err)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	seen := map[cid.Cid]struct{}{}
+	queue := []cid.Cid{root}
+	for len(queue) > 0 {
+		id := queue[0]
+		queue = queue[1:]
+		if _, ok := seen[id]; ok {
+			continue
+		}
+		seen[id] = struct{}{}
+
+		// partial DAGs are missing blocks, keep walking what's present
+		if nd, err := c.lng.Get(ctx, id); err == nil {
+			for _, l := range nd.Links() {
+				queue = append(queue, l.Cid)
+			}
+		}
+		if err := c.capi.Block().Rm(ctx, ipfspath.IpfsPath(id)); err != nil {
+			log.Debugf("removing pulled block %s: %s", id, err)
+		}
+	}
+}
+
 // RemoveDataset requests a remote remove logbook data from an address
 func (c *client) RemoveDataset(ctx context.Context, ref dsref.Ref, remoteAddr string) error {
 	log.Debugf("client.RemoveDataset ref=%q remoteAddr=%q", ref, remoteAddr)
@@ -766,6 +1009,11 @@ func (c *client) signHTTPRequest(ctx context.Context, req *http.Request) error {
 		return err
 	}
 
+	pubKey, err := key.EncodePubKeyB64(pk.GetPublic())
+	if err != nil {
+		return err
+	}
+
 	b64Sig, err := signString(pk, requestSigningString(now, peerID, req.URL.Path))
 	if err != nil {
 		return err
@@ -773,6 +1021,7 @@ func (c *client) signHTTPRequest(ctx context.Context, req *http.Request) error {
 
 	req.Header.Add("timestamp", now)
 	req.Header.Add("pid", peerID)
+	req.Header.Add("pubkey", pubKey)
 	req.Header.Add("signature", b64Sig)
 	req.Header.Add("qri-version", version.Version)
 	return nil
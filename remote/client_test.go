@@ -1,14 +1,26 @@
 package remote
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/google/go-cmp/cmp"
+	"github.com/gorilla/mux"
+	cid "github.com/ipfs/go-cid"
+	ipld "github.com/ipfs/go-ipld-format"
+	merkledag "github.com/ipfs/go-merkledag"
 	"github.com/qri-io/dataset/dstest"
 	"github.com/qri-io/qfs"
 	"github.com/qri-io/qfs/muxfs"
+	apiutil "github.com/qri-io/qri/api/util"
+	"github.com/qri-io/qri/auth/key"
 	testkeys "github.com/qri-io/qri/auth/key/test"
 	testcfg "github.com/qri-io/qri/config/test"
 	"github.com/qri-io/qri/dsref"
@@ -138,6 +150,135 @@ func TestClientFeedsAndPreviews(t *testing.T) {
 	dstest.CompareGoldenDatasetAndUpdateIfEnvVarSet(t, "testdata/expect/TestClientFeedsAndPreviews.json", ds)
 }
 
+func TestClientRejectsTamperedPreview(t *testing.T) {
+	tr, cleanup := newTestRunner(t)
+	defer cleanup()
+
+	worldBankRef := writeWorldBankPopulation(tr.Ctx, t, tr.NodeA.Repo)
+	wbp := reporef.RefFromDsref(worldBankRef)
+	setRefPublished(tr.Ctx, t, tr.NodeA.Repo, tr.NodeA.Repo.Profiles().Owner(tr.Ctx), &wbp)
+
+	rem := tr.NodeARemote(t)
+	m := mux.NewRouter()
+	rem.AddDefaultRoutes(m)
+
+	// tampering server rewrites the preview title in transit, preserving the
+	// signature headers of the original response
+	tamper := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rec := httptest.NewRecorder()
+		m.ServeHTTP(rec, r)
+		for k, v := range rec.Header() {
+			w.Header()[k] = v
+		}
+		w.WriteHeader(rec.Code)
+		w.Write(bytes.Replace(rec.Body.Bytes(), []byte("World Bank Population"), []byte("World Bank Populace"), 1))
+	}))
+	defer tamper.Close()
+
+	cli := tr.NodeBClient(t)
+	_, err := cli.PreviewDatasetVersion(tr.Ctx, worldBankRef, tamper.URL)
+	if !errors.Is(err, ErrInvalidPreviewSignature) {
+		t.Errorf("expected tampered preview to fail with %q, got: %v", ErrInvalidPreviewSignature, err)
+	}
+}
+
+func TestClientRejectsResignedPreview(t *testing.T) {
+	tr, cleanup := newTestRunner(t)
+	defer cleanup()
+
+	worldBankRef := writeWorldBankPopulation(tr.Ctx, t, tr.NodeA.Repo)
+	wbp := reporef.RefFromDsref(worldBankRef)
+	setRefPublished(tr.Ctx, t, tr.NodeA.Repo, tr.NodeA.Repo.Profiles().Owner(tr.Ctx), &wbp)
+
+	rem := tr.NodeARemote(t)
+	m := mux.NewRouter()
+	rem.AddDefaultRoutes(m)
+
+	// an intermediary with its own key rewrites the preview and signs the
+	// result, producing a self-consistent signature the client doesn't trust
+	intermediary := testkeys.GetKeyData(9).PrivKey
+	resign := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rec := httptest.NewRecorder()
+		m.ServeHTTP(rec, r)
+		env := struct {
+			Data json.RawMessage
+			Meta json.RawMessage
+		}{}
+		if err := json.Unmarshal(rec.Body.Bytes(), &env); err != nil {
+			t.Error(err)
+			return
+		}
+		data := bytes.Replace(env.Data, []byte("World Bank Population"), []byte("World Bank Populace"), 1)
+		if err := addPreviewSignatureHTTPHeaders(w.Header(), intermediary, "intermediary", data); err != nil {
+			t.Error(err)
+			return
+		}
+		apiutil.WriteResponse(w, json.RawMessage(data))
+	}))
+	defer resign.Close()
+
+	cli := tr.NodeBClient(t)
+	// without a signer on record for the remote, the preview is accepted
+	if _, err := cli.PreviewDatasetVersion(tr.Ctx, worldBankRef, resign.URL); err != nil {
+		t.Errorf("expected preview from a remote without a trusted signer to succeed, got: %s", err)
+	}
+
+	// trusting the remote's key rejects previews signed by anyone else
+	cli.TrustSigner(resign.URL, tr.NodeA.Repo.Profiles().Owner(tr.Ctx).ID)
+	_, err := cli.PreviewDatasetVersion(tr.Ctx, worldBankRef, resign.URL)
+	if !errors.Is(err, ErrInvalidPreviewSignature) {
+		t.Errorf("expected re-signed preview to fail with %q, got: %v", ErrInvalidPreviewSignature, err)
+	}
+
+	// explicitly trusting the intermediary's key accepts its previews
+	id, err := key.IDFromPrivKey(intermediary)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cli.TrustSigner(resign.URL, profile.IDB58DecodeOrEmpty(id))
+	if _, err := cli.PreviewDatasetVersion(tr.Ctx, worldBankRef, resign.URL); err != nil {
+		t.Errorf("expected preview from trusted signer to succeed, got: %s", err)
+	}
+}
+
+func TestClientAcceptsUnsignedPreview(t *testing.T) {
+	tr, cleanup := newTestRunner(t)
+	defer cleanup()
+
+	worldBankRef := writeWorldBankPopulation(tr.Ctx, t, tr.NodeA.Repo)
+	wbp := reporef.RefFromDsref(worldBankRef)
+	setRefPublished(tr.Ctx, t, tr.NodeA.Repo, tr.NodeA.Repo.Profiles().Owner(tr.Ctx), &wbp)
+
+	rem := tr.NodeARemote(t)
+	m := mux.NewRouter()
+	rem.AddDefaultRoutes(m)
+
+	// older servers don't sign previews
+	unsigned := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rec := httptest.NewRecorder()
+		m.ServeHTTP(rec, r)
+		w.WriteHeader(rec.Code)
+		w.Write(rec.Body.Bytes())
+	}))
+	defer unsigned.Close()
+
+	cli := tr.NodeBClient(t)
+	ds, err := cli.PreviewDatasetVersion(tr.Ctx, worldBankRef, unsigned.URL)
+	if err != nil {
+		t.Fatalf("expected unsigned preview to succeed, got: %s", err)
+	}
+	if ds.Meta == nil || ds.Meta.Title != "World Bank Population" {
+		t.Errorf("unexpected preview meta: %v", ds.Meta)
+	}
+
+	// a trusted signer on record for the remote requires a signature
+	cli.TrustSigner(unsigned.URL, tr.NodeA.Repo.Profiles().Owner(tr.Ctx).ID)
+	_, err = cli.PreviewDatasetVersion(tr.Ctx, worldBankRef, unsigned.URL)
+	if !errors.Is(err, ErrInvalidPreviewSignature) {
+		t.Errorf("expected unsigned preview from a remote with a trusted signer to fail with %q, got: %v", ErrInvalidPreviewSignature, err)
+	}
+}
+
 func newMemRepoTestNode(t *testing.T) *p2p.QriNode {
 	ctx := context.Background()
 	fs := qfs.NewMemFS()
@@ -190,3 +331,57 @@ func connectMapStores(peers []*p2p.QriNode) {
 		}
 	}
 }
+
+func TestVerifyDAGCorruptedBlock(t *testing.T) {
+	ctx := context.Background()
+
+	leaf := merkledag.NewRawNode([]byte("leaf block"))
+	root := merkledag.NodeWithData([]byte("root block"))
+	if err := root.AddNodeLink("leaf", leaf); err != nil {
+		t.Fatal(err)
+	}
+
+	ng := mapNodeGetter{root.Cid(): root, leaf.Cid(): leaf}
+	if err := verifyDAG(ctx, ng, root.Cid().String()); err != nil {
+		t.Fatalf("expected intact DAG to verify, got: %s", err)
+	}
+
+	// a block stored under its CID with different bytes
+	ng[leaf.Cid()] = corruptNode{Node: leaf, data: []byte("corrupted leaf block")}
+	err := verifyDAG(ctx, ng, root.Cid().String())
+	if !errors.Is(err, ErrIntegrityCheck) {
+		t.Fatalf("expected corrupted block to fail with ErrIntegrityCheck, got: %v", err)
+	}
+	if !strings.Contains(err.Error(), "hashes to") {
+		t.Errorf("expected a hash mismatch error, got: %s", err)
+	}
+}
+
+// mapNodeGetter is an in-memory ipld.NodeGetter that returns nodes as stored,
+// without checking their contents
+type mapNodeGetter map[cid.Cid]ipld.Node
+
+func (m mapNodeGetter) Get(_ context.Context, id cid.Cid) (ipld.Node, error) {
+	if nd, ok := m[id]; ok {
+		return nd, nil
+	}
+	return nil, ipld.ErrNotFound
+}
+
+func (m mapNodeGetter) GetMany(ctx context.Context, ids []cid.Cid) <-chan *ipld.NodeOption {
+	ch := make(chan *ipld.NodeOption, len(ids))
+	for _, id := range ids {
+		nd, err := m.Get(ctx, id)
+		ch <- &ipld.NodeOption{Node: nd, Err: err}
+	}
+	close(ch)
+	return ch
+}
+
+// corruptNode reports raw data that doesn't match its CID
+type corruptNode struct {
+	ipld.Node
+	data []byte
+}
+
+func (n corruptNode) RawData() []byte { return n.data }
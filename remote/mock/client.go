@@ -8,6 +8,7 @@ import (
 	"github.com/qri-io/qfs"
 	testkeys "github.com/qri-io/qri/auth/key/test"
 	"github.com/qri-io/qri/base/dsfs"
+	"github.com/qri-io/qri/base/params"
 	"github.com/qri-io/qri/dsref"
 	"github.com/qri-io/qri/event"
 	"github.com/qri-io/qri/logbook"
@@ -78,6 +79,14 @@ func (c *Client) Feed(ctx context.Context, remoteAddr, feedName string, page, pa
 	return nil, ErrNotImplemented
 }
 
+// ListPeerDatasets is not implemented
+func (c *Client) ListPeerDatasets(ctx context.Context, remoteAddr, peername string, lp params.List) ([]dsref.VersionInfo, error) {
+	return nil, ErrNotImplemented
+}
+
+// TrustSigner is a no-op
+func (c *Client) TrustSigner(remoteAddr string, pid profile.ID) {}
+
 // PreviewDatasetVersion is not implemented
 func (c *Client) PreviewDatasetVersion(ctx context.Context, ref dsref.Ref, remoteAddr string) (*dataset.Dataset, error) {
 	return nil, ErrNotImplemented
@@ -147,6 +156,7 @@ func (c *Client) PullDataset(ctx context.Context, ref *dsref.Ref, remoteAddr str
 	info.Username = ref.Username
 	info.Name = ref.Name
 	info.ProfileID = ref.ProfileID
+	info.Foreign = true
 	if err := c.pub.Publish(ctx, event.ETDatasetPulled, info); err != nil {
 		return nil, err
 	}
@@ -4,22 +4,35 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
 	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 
 	"github.com/google/go-cmp/cmp"
 	"github.com/gorilla/mux"
+	cid "github.com/ipfs/go-cid"
 	core "github.com/ipfs/go-ipfs/core"
+	car "github.com/ipld/go-car"
+	carutil "github.com/ipld/go-car/util"
+	"github.com/qri-io/dag/dsync"
 	"github.com/qri-io/dataset"
 	"github.com/qri-io/qfs"
 	"github.com/qri-io/qri/base"
 	"github.com/qri-io/qri/base/dsfs"
+	"github.com/qri-io/qri/base/params"
 	"github.com/qri-io/qri/config"
 	testcfg "github.com/qri-io/qri/config/test"
 	"github.com/qri-io/qri/dsref"
 	"github.com/qri-io/qri/event"
 	"github.com/qri-io/qri/logbook"
+	"github.com/qri-io/qri/logbook/oplog"
 	"github.com/qri-io/qri/p2p"
 	p2ptest "github.com/qri-io/qri/p2p/test"
 	"github.com/qri-io/qri/profile"
@@ -544,3 +557,496 @@ func mustJSON(data string, v interface{}) {
 		panic(err)
 	}
 }
+
+func TestPreviewAccessControl(t *testing.T) {
+	tr, cleanup := newTestRunner(t)
+	defer cleanup()
+
+	rem := tr.NodeARemote(t)
+	server := tr.RemoteTestServer(rem)
+	defer server.Close()
+
+	ds := &dataset.Dataset{
+		Name:   "restricted",
+		Commit: &dataset.Commit{Title: "initial commit"},
+		Structure: &dataset.Structure{
+			Format: "json",
+			Schema: dataset.BaseSchemaArray,
+		},
+	}
+	owner := tr.NodeA.Repo.Profiles().Owner(tr.Ctx)
+	if err := dsfs.SetAccessControl(ds, &dsfs.AccessControl{Owner: owner.ID.Encode()}); err != nil {
+		t.Fatal(err)
+	}
+	ds.SetBodyFile(qfs.NewMemfileBytes("body.json", []byte("[1]")))
+	ref := saveDataset(tr.Ctx, tr.NodeA.Repo, owner, ds)
+
+	ownerCli, err := NewClient(tr.Ctx, tr.NodeA, tr.NodeA.Repo.Bus())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ownerCli.PreviewDatasetVersion(tr.Ctx, ref, server.URL); err != nil {
+		t.Errorf("expected owner preview to succeed, got: %s", err)
+	}
+
+	cli := tr.NodeBClient(t)
+	if _, err := cli.PreviewDatasetVersion(tr.Ctx, ref, server.URL); err == nil {
+		t.Error("expected unauthorized preview to fail")
+	} else if !strings.Contains(err.Error(), access.ErrAccessDenied.Error()) {
+		t.Errorf("expected unauthorized preview to be denied access, got: %s", err)
+	}
+
+	if _, err := cli.PullDataset(tr.Ctx, &ref, server.URL); err == nil {
+		t.Error("expected unauthorized pull to fail")
+	}
+
+	// claiming the owner's ID without the owner's key must not grant access
+	u := fmt.Sprintf("%s/remote/dataset/preview/%s", server.URL, ref.String())
+	forged, err := http.NewRequest("GET", u, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := cli.(*client).signHTTPRequest(tr.Ctx, forged); err != nil {
+		t.Fatal(err)
+	}
+	forged.Header.Set("pid", owner.ID.Encode())
+	res, err := http.DefaultClient.Do(forged)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+	if res.StatusCode != http.StatusUnauthorized {
+		t.Errorf("expected preview with forged pid header to respond %d, got: %d", http.StatusUnauthorized, res.StatusCode)
+	}
+
+	unsigned, err := http.NewRequest("GET", u, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	unsigned.Header.Set("pid", owner.ID.Encode())
+	if res, err = http.DefaultClient.Do(unsigned); err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+	if res.StatusCode != http.StatusForbidden {
+		t.Errorf("expected preview with unsigned pid header to respond %d, got: %d", http.StatusForbidden, res.StatusCode)
+	}
+
+	meta, err := sigParams(tr.NodeB.Repo.Profiles().Owner(tr.Ctx).PrivKey, "B", ref)
+	if err != nil {
+		t.Fatal(err)
+	}
+	meta["pid"] = owner.ID.Encode()
+	dsCli := &dsync.HTTPClient{URL: server.URL + "/remote/dsync"}
+	if _, err := dsCli.GetDagInfo(tr.Ctx, ref.Path, meta); err == nil {
+		t.Error("expected dag info request with forged pid to fail")
+	}
+
+	// requests from older clients don't send a public key & are anonymous
+	delete(meta, "pubkey")
+	if _, err := dsCli.GetDagInfo(tr.Ctx, ref.Path, meta); err == nil {
+		t.Error("expected anonymous dag info request for an access controlled dataset to fail")
+	}
+}
+
+func TestLegacyUnsignedRequests(t *testing.T) {
+	tr, cleanup := newTestRunner(t)
+	defer cleanup()
+
+	rem := tr.NodeARemote(t)
+	server := tr.RemoteTestServer(rem)
+	defer server.Close()
+
+	ref := writeWorldBankPopulation(tr.Ctx, t, tr.NodeA.Repo)
+
+	// older clients sign requests without sending a public key
+	meta, err := sigParams(tr.NodeB.Repo.Profiles().Owner(tr.Ctx).PrivKey, "B", ref)
+	if err != nil {
+		t.Fatal(err)
+	}
+	delete(meta, "pubkey")
+	dsCli := &dsync.HTTPClient{URL: server.URL + "/remote/dsync"}
+	if _, err := dsCli.GetDagInfo(tr.Ctx, ref.Path, meta); err != nil {
+		t.Errorf("expected dag info request without a public key to succeed, got: %s", err)
+	}
+
+	u := fmt.Sprintf("%s/remote/dataset/preview/%s", server.URL, ref.String())
+	req, err := http.NewRequest("GET", u, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("pid", tr.NodeB.Repo.Profiles().Owner(tr.Ctx).ID.Encode())
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		t.Errorf("expected preview without a public key to respond %d, got: %d", http.StatusOK, res.StatusCode)
+	}
+}
+
+func TestListPeerDatasetsAccessControl(t *testing.T) {
+	tr, cleanup := newTestRunner(t)
+	defer cleanup()
+
+	rem := tr.NodeARemote(t)
+	server := tr.RemoteTestServer(rem)
+	defer server.Close()
+
+	owner := tr.NodeA.Repo.Profiles().Owner(tr.Ctx)
+	writeWorldBankPopulation(tr.Ctx, t, tr.NodeA.Repo)
+	writeVideoViewStats(tr.Ctx, t, tr.NodeA.Repo)
+	ds := &dataset.Dataset{
+		Name:   "restricted",
+		Commit: &dataset.Commit{Title: "initial commit"},
+		Structure: &dataset.Structure{
+			Format: "json",
+			Schema: dataset.BaseSchemaArray,
+		},
+	}
+	if err := dsfs.SetAccessControl(ds, &dsfs.AccessControl{Owner: owner.ID.Encode()}); err != nil {
+		t.Fatal(err)
+	}
+	ds.SetBodyFile(qfs.NewMemfileBytes("body.json", []byte("[1]")))
+	saveDataset(tr.Ctx, tr.NodeA.Repo, owner, ds)
+
+	names := func(infos []dsref.VersionInfo) []string {
+		res := make([]string, len(infos))
+		for i, vi := range infos {
+			res[i] = vi.Name
+		}
+		return res
+	}
+
+	ownerCli, err := NewClient(tr.Ctx, tr.NodeA, tr.NodeA.Repo.Bus())
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := ownerCli.ListPeerDatasets(tr.Ctx, server.URL, owner.Peername, params.List{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expect := []string{"restricted", "video_view_stats", "world_bank_population"}
+	if diff := cmp.Diff(expect, names(got)); diff != "" {
+		t.Errorf("owner listing mismatch (-want +got):\n%s", diff)
+	}
+
+	// datasets the requester can't read are left out, pages are counted over
+	// the datasets the requester can read
+	cli := tr.NodeBClient(t)
+	got, err = cli.ListPeerDatasets(tr.Ctx, server.URL, owner.Peername, params.List{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expect = []string{"video_view_stats", "world_bank_population"}
+	if diff := cmp.Diff(expect, names(got)); diff != "" {
+		t.Errorf("listing mismatch (-want +got):\n%s", diff)
+	}
+	got, err = cli.ListPeerDatasets(tr.Ctx, server.URL, owner.Peername, params.List{Offset: 1, Limit: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff([]string{"world_bank_population"}, names(got)); diff != "" {
+		t.Errorf("paged listing mismatch (-want +got):\n%s", diff)
+	}
+
+	got, err = cli.ListPeerDatasets(tr.Ctx, server.URL, "unknown_peer", params.List{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 0 {
+		t.Errorf("expected no datasets for an unknown peer, got: %v", names(got))
+	}
+}
+
+func TestRemoveDatasetAuthentication(t *testing.T) {
+	tr, cleanup := newTestRunner(t)
+	defer cleanup()
+
+	rem := tr.NodeARemote(t)
+	server := tr.RemoteTestServer(rem)
+	defer server.Close()
+
+	wbp := writeWorldBankPopulation(tr.Ctx, t, tr.NodeA.Repo)
+	owner := tr.NodeA.Repo.Profiles().Owner(tr.Ctx)
+	bKey := tr.NodeB.Repo.Profiles().Owner(tr.Ctx).PrivKey
+
+	removeRefs := func(params map[string]string) int {
+		q := url.Values{}
+		for key, val := range params {
+			q.Set(key, val)
+		}
+		req, err := http.NewRequest("DELETE", server.URL+"/remote/refs?"+q.Encode(), nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		res, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		res.Body.Close()
+		return res.StatusCode
+	}
+
+	// claiming the owner's ID without the owner's key must not remove
+	forged, err := sigParams(bKey, "B", wbp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	forged["pid"] = owner.ID.Encode()
+	if code := removeRefs(forged); code != http.StatusUnauthorized {
+		t.Errorf("expected remove with forged pid to respond %d, got: %d", http.StatusUnauthorized, code)
+	}
+
+	unsigned := map[string]string{
+		"username": wbp.Username,
+		"name":     wbp.Name,
+		"pid":      owner.ID.Encode(),
+	}
+	// unsigned requests are anonymous, which can't remove another user's data
+	if code := removeRefs(unsigned); code != http.StatusForbidden {
+		t.Errorf("expected unsigned remove to respond %d, got: %d", http.StatusForbidden, code)
+	}
+
+	signed, err := sigParams(bKey, "B", wbp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if code := removeRefs(signed); code != http.StatusForbidden {
+		t.Errorf("expected remove by a non-owner to respond %d, got: %d", http.StatusForbidden, code)
+	}
+
+	dsCli := &dsync.HTTPClient{URL: server.URL + "/remote/dsync"}
+	if err := dsCli.RemoveCID(tr.Ctx, wbp.Path, forged); err == nil {
+		t.Error("expected dsync remove with forged pid to fail")
+	}
+	if err := dsCli.RemoveCID(tr.Ctx, wbp.Path, signed); err == nil {
+		t.Error("expected dsync remove by a non-owner to fail")
+	} else if !strings.Contains(err.Error(), access.ErrAccessDenied.Error()) {
+		t.Errorf("expected dsync remove by a non-owner to be denied access, got: %s", err)
+	}
+
+	if _, err := tr.NodeA.Repo.GetRef(reporef.DatasetRef{Peername: wbp.Username, Name: wbp.Name}); err != nil {
+		t.Errorf("expected rejected removes to keep the dataset, got: %s", err)
+	}
+}
+
+func TestPullDatasetCancel(t *testing.T) {
+	tr, cleanup := newTestRunner(t)
+	defer cleanup()
+
+	rem := tr.NodeARemote(t)
+	m := mux.NewRouter()
+	rem.AddDefaultRoutes(m)
+
+	// send the first block, then stall the transfer until released, simulating
+	// a slow remote
+	var firstBlock cid.Cid
+	transferStarted := make(chan struct{})
+	release := make(chan struct{})
+	releaseOnce := sync.Once{}
+	releaseTransfer := func() { releaseOnce.Do(func() { close(release) }) }
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPatch || !strings.HasSuffix(r.URL.Path, "/remote/dsync") {
+			m.ServeHTTP(w, r)
+			return
+		}
+		rec := httptest.NewRecorder()
+		m.ServeHTTP(rec, r)
+		cr, err := car.NewCarReader(rec.Body)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		blk, err := cr.Next()
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		firstBlock = blk.Cid()
+
+		for k, v := range rec.Header() {
+			w.Header()[k] = v
+		}
+		w.WriteHeader(rec.Code)
+		if err := car.WriteHeader(cr.Header, w); err != nil {
+			t.Error(err)
+			return
+		}
+		if err := carutil.LdWrite(w, blk.Cid().Bytes(), blk.RawData()); err != nil {
+			t.Error(err)
+			return
+		}
+		w.(http.Flusher).Flush()
+		close(transferStarted)
+		<-release
+	}))
+	defer server.Close()
+	defer releaseTransfer()
+
+	wbp := writeWorldBankPopulation(tr.Ctx, t, tr.NodeA.Repo)
+	cli := tr.NodeBClient(t)
+
+	capi, err := tr.NodeB.IPFSCoreAPI()
+	if err != nil {
+		t.Fatal(err)
+	}
+	lng, err := dsync.NewLocalNodeGetter(capi)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// cancel once the first block has been stored
+	ctx, cancel := context.WithCancel(tr.Ctx)
+	go func() {
+		<-transferStarted
+		for i := 0; i < 250; i++ {
+			if _, err := lng.Get(tr.Ctx, firstBlock); err == nil {
+				break
+			}
+			time.Sleep(time.Millisecond * 20)
+		}
+		cancel()
+	}()
+
+	errCh := make(chan error)
+	go func() {
+		ref := wbp
+		_, err := cli.PullDataset(ctx, &ref, server.URL)
+		errCh <- err
+	}()
+
+	select {
+	case err := <-errCh:
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("expected pull to fail with context.Canceled, got: %v", err)
+		}
+	case <-time.After(time.Second * 5):
+		t.Fatal("timed out waiting for cancelled pull to stop")
+	}
+
+	if _, err := tr.NodeB.Repo.GetRef(reporef.DatasetRef{Peername: wbp.Username, Name: wbp.Name}); err != repo.ErrNotFound {
+		t.Errorf("expected cancelled pull not to add a ref, got: %v", err)
+	}
+	if _, err := tr.NodeB.Repo.Logbook().Log(tr.Ctx, wbp.InitID); !errors.Is(err, oplog.ErrNotFound) {
+		t.Errorf("expected cancelled pull to remove the pulled log, got: %v", err)
+	}
+
+	// once the stalled transfer exits, the blocks it pulled are removed
+	releaseTransfer()
+	deadline := time.Now().Add(time.Second * 5)
+	for {
+		if _, err := lng.Get(tr.Ctx, firstBlock); err != nil {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("expected cancelled pull to remove partially pulled block %s", firstBlock)
+		}
+		time.Sleep(time.Millisecond * 20)
+	}
+}
+
+func TestPullDatasetTamperedBlock(t *testing.T) {
+	tr, cleanup := newTestRunner(t)
+	defer cleanup()
+
+	rem := tr.NodeARemote(t)
+	m := mux.NewRouter()
+	rem.AddDefaultRoutes(m)
+
+	// rewrite the block stream, swapping the last block for a different one.
+	// the substitute block is self-consistent, so only a check against the
+	// requested DAG can catch it
+	var (
+		sentLk sync.Mutex
+		sent   []cid.Cid
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPatch || !strings.HasSuffix(r.URL.Path, "/remote/dsync") {
+			m.ServeHTTP(w, r)
+			return
+		}
+
+		rec := httptest.NewRecorder()
+		m.ServeHTTP(rec, r)
+		cr, err := car.NewCarReader(rec.Body)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		var (
+			ids   []cid.Cid
+			datas [][]byte
+		)
+		for {
+			blk, err := cr.Next()
+			if err == io.EOF {
+				break
+			} else if err != nil {
+				t.Error(err)
+				return
+			}
+			ids = append(ids, blk.Cid())
+			datas = append(datas, blk.RawData())
+		}
+
+		for k, v := range rec.Header() {
+			w.Header()[k] = v
+		}
+		w.WriteHeader(rec.Code)
+		if err := car.WriteHeader(cr.Header, w); err != nil {
+			t.Error(err)
+			return
+		}
+		for i, id := range ids {
+			data := datas[i]
+			if i == len(ids)-1 {
+				data = append([]byte("tampered"), data...)
+				if id, err = id.Prefix().Sum(data); err != nil {
+					t.Error(err)
+					return
+				}
+			}
+			if err := carutil.LdWrite(w, id.Bytes(), data); err != nil {
+				t.Error(err)
+				return
+			}
+			sentLk.Lock()
+			sent = append(sent, id)
+			sentLk.Unlock()
+		}
+	}))
+	defer server.Close()
+
+	wbp := writeWorldBankPopulation(tr.Ctx, t, tr.NodeA.Repo)
+	cli := tr.NodeBClient(t)
+
+	ref := wbp
+	if _, err := cli.PullDataset(tr.Ctx, &ref, server.URL); !errors.Is(err, ErrIntegrityCheck) {
+		t.Errorf("expected pulling a tampered block to fail with ErrIntegrityCheck, got: %v", err)
+	}
+	if _, err := tr.NodeB.Repo.GetRef(reporef.DatasetRef{Peername: wbp.Username, Name: wbp.Name}); err != repo.ErrNotFound {
+		t.Errorf("expected failed pull not to add a ref, got: %v", err)
+	}
+
+	// blocks stored before the DAG failed verification are removed
+	capi, err := tr.NodeB.IPFSCoreAPI()
+	if err != nil {
+		t.Fatal(err)
+	}
+	lng, err := dsync.NewLocalNodeGetter(capi)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sentLk.Lock()
+	defer sentLk.Unlock()
+	if len(sent) < 2 {
+		t.Fatalf("expected more than one block to be sent, got %d", len(sent))
+	}
+	for _, id := range sent[:len(sent)-1] {
+		if _, err := lng.Get(tr.Ctx, id); err == nil {
+			t.Errorf("expected block %s from the failed pull to be removed", id)
+		}
+	}
+}
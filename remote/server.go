@@ -9,16 +9,21 @@ import (
 	"io/ioutil"
 	"net/http"
 	"os"
+	"sort"
 	"strings"
 	"time"
 
 	"github.com/gorilla/mux"
+	ipld "github.com/ipfs/go-ipld-format"
 	golog "github.com/ipfs/go-log"
 	"github.com/qri-io/dag"
 	"github.com/qri-io/dag/dsync"
+	"github.com/qri-io/dataset"
 	apiutil "github.com/qri-io/qri/api/util"
 	"github.com/qri-io/qri/auth/key"
 	"github.com/qri-io/qri/base"
+	"github.com/qri-io/qri/base/dsfs"
+	"github.com/qri-io/qri/base/params"
 	"github.com/qri-io/qri/config"
 	"github.com/qri-io/qri/dsref"
 	"github.com/qri-io/qri/event"
@@ -99,6 +104,7 @@ type Server struct {
 	localResolver dsref.Resolver
 
 	dsync   *dsync.Dsync
+	lng     ipld.NodeGetter
 	logsync *logsync.Logsync
 
 	Feeds    Feeds
@@ -209,6 +215,7 @@ func NewServer(node *p2p.QriNode, cfg *config.RemoteServer, localResolver dsref.
 	if err != nil {
 		return nil, err
 	}
+	r.lng = lng
 
 	r.dsync, err = dsync.New(lng, capi.Block(), func(dsyncConfig *dsync.Config) {
 		if host := r.node.Host(); host != nil {
@@ -224,6 +231,7 @@ func NewServer(node *p2p.QriNode, cfg *config.RemoteServer, localResolver dsref.
 		dsyncConfig.PushComplete = r.dsPushComplete
 		dsyncConfig.RemoveCheck = r.dsRemovePreCheck
 		dsyncConfig.GetDagInfoCheck = r.dsGetDagInfo
+		dsyncConfig.OpenBlockStreamCheck = r.dsOpenBlockStreamCheck
 	})
 	if err != nil {
 		return nil, err
@@ -236,7 +244,7 @@ func NewServer(node *p2p.QriNode, cfg *config.RemoteServer, localResolver dsref.
 			lso.Pushed = r.logHook("Pushed", o.LogPushed)
 			lso.PullPreCheck = r.logPreCheckHook("PullPreCheck", "remote:pull", o.LogPullPreCheck)
 			lso.Pulled = r.logHook("Pulled", o.LogPulled)
-			lso.RemovePreCheck = r.logPreCheckHook("RemovePreCheck", "remote:remove", o.LogRemovePreCheck)
+			lso.RemovePreCheck = r.logRemovePreCheckHook(o.LogRemovePreCheck)
 			lso.Removed = r.logHook("Removed", o.LogRemoved)
 		})
 	}
@@ -303,6 +311,10 @@ func (r *Server) RemoveDataset(ctx context.Context, params map[string]string) er
 		}
 	}
 
+	if err := r.checkRemoveOwner(ctx, pid, ref); err != nil {
+		return err
+	}
+
 	// run pre check hook
 	if r.datasetRemovePreCheck != nil {
 		if err = r.datasetRemovePreCheck(ctx, pid, ref); err != nil {
@@ -450,6 +462,10 @@ func (r *Server) dsRemovePreCheck(ctx context.Context, info dag.Info, meta map[s
 		}
 	}
 
+	if err := r.checkRemoveOwner(ctx, pid, ref); err != nil {
+		return err
+	}
+
 	if r.datasetRemovePreCheck != nil {
 		if err = r.datasetRemovePreCheck(ctx, pid, ref); err != nil {
 			return err
@@ -467,6 +483,11 @@ func (r *Server) dsGetDagInfo(ctx context.Context, into dag.Info, meta map[strin
 	pid := subj.ID
 	log.Debugf("pid %s pulling ref %s", pid.Encode(), ref.String())
 
+	if err := r.enforceDatasetAccess(ctx, pid, ref); err != nil {
+		log.Debugw("dataset pull denied", "pid", pid.Encode(), "ref", ref.String(), "err", err)
+		return err
+	}
+
 	if r.datasetPulled != nil {
 		if err = r.datasetPulled(ctx, pid, ref); err != nil {
 			log.Errorf("dataset pulled hook: %s", err.Error())
@@ -476,6 +497,72 @@ func (r *Server) dsGetDagInfo(ctx context.Context, into dag.Info, meta map[strin
 	return nil
 }
 
+// dsOpenBlockStreamCheck applies pull access control to block stream
+// requests, which can be made without first requesting dag info
+func (r *Server) dsOpenBlockStreamCheck(ctx context.Context, info dag.Info, meta map[string]string) error {
+	subj, ref, err := r.subjAndRefFromMeta(meta)
+	if err != nil {
+		return err
+	}
+	if info.Manifest == nil || len(info.Manifest.Nodes) == 0 {
+		return fmt.Errorf("block stream requires a manifest")
+	}
+	// check the dataset that's actually being streamed, not the one named
+	root := info.RootCID()
+	ref.Path = "/ipfs/" + root.String()
+	if err := r.enforceDatasetAccess(ctx, subj.ID, ref); err != nil {
+		return err
+	}
+
+	// a manifest can list any blocks, only stream blocks that belong to the
+	// root we've checked access for
+	full, err := dag.NewInfo(ctx, r.lng, root)
+	if err != nil {
+		return err
+	}
+	inDAG := make(map[string]bool, len(full.Manifest.Nodes))
+	for _, id := range full.Manifest.Nodes {
+		inDAG[id] = true
+	}
+	for _, id := range info.Manifest.Nodes {
+		if !inDAG[id] {
+			return fmt.Errorf("%w: block %s is not part of %s", access.ErrAccessDenied, id, root)
+		}
+	}
+	return nil
+}
+
+// enforceDatasetAccess returns access.ErrAccessDenied if the dataset version
+// ref points to has access control that doesn't allow pid to read it
+func (r *Server) enforceDatasetAccess(ctx context.Context, pid profile.ID, ref dsref.Ref) error {
+	if ref.Path == "" {
+		if _, err := r.localResolver.ResolveRef(ctx, &ref); err != nil {
+			return err
+		}
+	}
+	ds, err := dsfs.LoadDataset(ctx, r.node.Repo.Filesystem(), ref.Path)
+	if err != nil {
+		return err
+	}
+	return checkDatasetReadAccess(ds, pid)
+}
+
+// checkDatasetReadAccess returns access.ErrAccessDenied if ds has access
+// control that doesn't allow pid to read it
+func checkDatasetReadAccess(ds *dataset.Dataset, pid profile.ID) error {
+	ac, err := dsfs.DatasetAccessControl(ds)
+	if err != nil {
+		return err
+	}
+	if ac == nil {
+		return nil
+	}
+	if !ac.CanRead(pid.Encode()) {
+		return access.ErrAccessDenied
+	}
+	return nil
+}
+
 func (r *Server) subjAndRefFromMeta(meta map[string]string) (*profile.Profile, dsref.Ref, error) {
 	ref := dsref.Ref{
 		Username:  meta["username"],
@@ -489,8 +576,17 @@ func (r *Server) subjAndRefFromMeta(meta map[string]string) (*profile.Profile, d
 		ref.Username = meta["peername"]
 	}
 
-	pid, err := profile.IDB58Decode(meta["pid"])
-	if err == nil && ref.ProfileID == "" {
+	// only trust the subject's identity if the request proves it. requests
+	// that can't are anonymous, and can only read datasets without access
+	// control
+	pid, err := authenticateSubject(meta)
+	if err != nil {
+		return &profile.Profile{}, ref, err
+	}
+	if pid == "" {
+		return &profile.Profile{}, ref, nil
+	}
+	if ref.ProfileID == "" {
 		ref.ProfileID = pid.Encode()
 	}
 
@@ -499,7 +595,7 @@ func (r *Server) subjAndRefFromMeta(meta map[string]string) (*profile.Profile, d
 		Peername: meta["subject_username"],
 	}
 
-	return pro, ref, err
+	return pro, ref, nil
 }
 
 func (r *Server) logHook(name string, h Hook) logsync.Hook {
@@ -564,11 +660,51 @@ func (r *Server) logPreCheckHook(name string, action string, h Hook) logsync.Hoo
 	}
 }
 
+// logRemovePreCheckHook wraps the standard log pre check, only allowing the
+// owner of a dataset to remove its log
+func (r *Server) logRemovePreCheckHook(h Hook) logsync.Hook {
+	check := r.logPreCheckHook("RemovePreCheck", "remote:remove", h)
+	return func(ctx context.Context, author profile.Author, ref dsref.Ref, l *oplog.Log) error {
+		kid, err := key.IDFromPubKey(author.AuthorPubKey())
+		if err != nil {
+			return err
+		}
+		pid, err := profile.IDB58Decode(kid)
+		if err != nil {
+			return err
+		}
+		if err := r.checkRemoveOwner(ctx, pid, ref); err != nil {
+			return err
+		}
+		return check(ctx, author, ref, l)
+	}
+}
+
+// checkRemoveOwner returns access.ErrAccessDenied if the dataset ref resolves
+// to a profile other than pid. Refs this remote can't resolve pass the check,
+// there's nothing of another user's to protect
+func (r *Server) checkRemoveOwner(ctx context.Context, pid profile.ID, ref dsref.Ref) error {
+	owned := dsref.Ref{Username: ref.Username, Name: ref.Name}
+	if _, err := r.localResolver.ResolveRef(ctx, &owned); err != nil {
+		if errors.Is(err, dsref.ErrRefNotFound) || errors.Is(err, logbook.ErrNotFound) {
+			return nil
+		}
+		return err
+	}
+
+	if owned.ProfileID != "" && owned.ProfileID != pid.Encode() {
+		log.Debugf("denying remove of %q by profile %q", owned.Alias(), pid.Encode())
+		return fmt.Errorf("%w: only the owner of %s can remove it", access.ErrAccessDenied, owned.Alias())
+	}
+	return nil
+}
+
 // AddDefaultRoutes attaches routes a remote client will expect to an HTTP muxer
 func (r *Server) AddDefaultRoutes(m *mux.Router) {
 	m.Handle("/remote/dsync", r.DsyncHTTPHandler())
 	m.Handle("/remote/logsync", r.LogsyncHTTPHandler())
 	m.Handle("/remote/refs", r.RefsHTTPHandler())
+	m.Handle("/remote/datasets/{peername}", r.PeerDatasetsHTTPHandler("/remote/datasets/"))
 
 	if fs := r.Feeds; fs != nil {
 		m.Handle("/remote/feeds", r.FeedsHTTPHandler())
@@ -595,8 +731,8 @@ func (r *Server) FeedsHTTPHandler() http.HandlerFunc {
 	return func(w http.ResponseWriter, req *http.Request) {
 		ctx := req.Context()
 		if r.FeedPreCheck != nil {
-			id, err := profile.IDB58Decode(req.Header.Get("pid"))
-			if err != nil {
+			id, err := authenticateHTTPRequest(req)
+			if err != nil || id == "" {
 				apiutil.WriteErrResponse(w, http.StatusBadRequest, fmt.Errorf("missing signature details"))
 				return
 			}
@@ -624,8 +760,8 @@ func (r *Server) FeedHTTPHandler(prefix string) http.HandlerFunc {
 	return func(w http.ResponseWriter, req *http.Request) {
 		ctx := req.Context()
 		if r.FeedPreCheck != nil {
-			id, err := profile.IDB58Decode(req.Header.Get("pid"))
-			if err != nil {
+			id, err := authenticateHTTPRequest(req)
+			if err != nil || id == "" {
 				apiutil.WriteErrResponse(w, http.StatusBadRequest, fmt.Errorf("missing signature details"))
 				return
 			}
@@ -645,13 +781,117 @@ func (r *Server) FeedHTTPHandler(prefix string) http.HandlerFunc {
 	}
 }
 
+// ListPeerDatasets returns a page of datasets this remote holds for a peer,
+// ordered by dataset name. Datasets with access control that doesn't allow pid
+// to read them are left out. An empty pid lists datasets without access control
+func (r *Server) ListPeerDatasets(ctx context.Context, pid profile.ID, peername string, offset, limit int) ([]dsref.VersionInfo, error) {
+	infos, err := r.peerDatasets(peername)
+	if err != nil {
+		return nil, err
+	}
+
+	res := []dsref.VersionInfo{}
+	for _, vi := range infos {
+		if limit >= 0 && len(res) == limit {
+			break
+		}
+		ds, err := dsfs.LoadDataset(ctx, r.node.Repo.Filesystem(), vi.Path)
+		if err != nil {
+			return nil, err
+		}
+		if err := checkDatasetReadAccess(ds, pid); err != nil {
+			if errors.Is(err, access.ErrAccessDenied) {
+				continue
+			}
+			return nil, err
+		}
+		if offset > 0 {
+			offset--
+			continue
+		}
+		res = append(res, vi)
+	}
+	return res, nil
+}
+
+// peerDatasets lists the datasets a peer has in this remote's repo, sorted
+// by name. The dscache is used when present, repos without one fall back to
+// scanning the refstore
+func (r *Server) peerDatasets(peername string) ([]dsref.VersionInfo, error) {
+	if dsc := r.node.Repo.Dscache(); !dsc.IsEmpty() {
+		return dsc.ListUserDatasets(peername)
+	}
+
+	num, err := r.node.Repo.RefCount()
+	if err != nil {
+		return nil, err
+	}
+	infos, err := repo.ListVersionInfoShim(r.node.Repo, 0, num)
+	if err != nil {
+		return nil, err
+	}
+	res := make([]dsref.VersionInfo, 0, len(infos))
+	for _, vi := range infos {
+		if vi.Username == peername {
+			res = append(res, vi)
+		}
+	}
+	sort.Slice(res, func(i, j int) bool { return res[i].Name < res[j].Name })
+	return res, nil
+}
+
+// PeerDatasetsHTTPHandler lists the datasets a remote holds for a peer.
+// Requests that don't set a limit, or ask for more than feedPageSize datasets,
+// get a page of feedPageSize datasets
+func (r *Server) PeerDatasetsHTTPHandler(prefix string) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		ctx := req.Context()
+		if r.FeedPreCheck != nil {
+			id, err := authenticateHTTPRequest(req)
+			if err != nil || id == "" {
+				apiutil.WriteErrResponse(w, http.StatusBadRequest, fmt.Errorf("missing signature details"))
+				return
+			}
+			if err := r.FeedPreCheck(ctx, id, dsref.Ref{}); err != nil {
+				apiutil.WriteErrResponse(w, http.StatusBadRequest, fmt.Errorf("missing signature details"))
+				return
+			}
+		}
+
+		// listings are subject to the same access control as pulls. unsigned
+		// requests only list datasets without access control
+		pid, err := authenticateHTTPRequest(req)
+		if err != nil {
+			apiutil.WriteErrResponse(w, http.StatusUnauthorized, err)
+			return
+		}
+
+		lp := params.List{}
+		if err := lp.ListParamsFromRequest(req); err != nil {
+			apiutil.WriteErrResponse(w, http.StatusBadRequest, err)
+			return
+		}
+		if lp.Limit <= 0 || lp.Limit > feedPageSize {
+			lp.Limit = feedPageSize
+		}
+
+		refs, err := r.ListPeerDatasets(ctx, pid, strings.TrimPrefix(req.URL.Path, prefix), lp.Offset, lp.Limit)
+		if err != nil {
+			apiutil.WriteErrResponse(w, http.StatusInternalServerError, err)
+			return
+		}
+
+		apiutil.WriteResponse(w, refs)
+	}
+}
+
 // PreviewHTTPHandler handles dataset preview requests over HTTP
 func (r *Server) PreviewHTTPHandler(prefix string) http.HandlerFunc {
 	return func(w http.ResponseWriter, req *http.Request) {
 		ctx := req.Context()
 		if r.PreviewPreCheck != nil {
-			id, err := profile.IDB58Decode(req.Header.Get("pid"))
-			if err != nil {
+			id, err := authenticateHTTPRequest(req)
+			if err != nil || id == "" {
 				apiutil.WriteErrResponse(w, http.StatusBadRequest, fmt.Errorf("missing signature details"))
 				return
 			}
@@ -667,7 +907,36 @@ func (r *Server) PreviewHTTPHandler(prefix string) http.HandlerFunc {
 			return
 		}
 
-		apiutil.WriteResponse(w, preview)
+		// previews are subject to the same access control as pulls. unsigned
+		// requests can only preview datasets without access control
+		pid, err := authenticateHTTPRequest(req)
+		if err != nil {
+			apiutil.WriteErrResponse(w, http.StatusUnauthorized, err)
+			return
+		}
+		if err := checkDatasetReadAccess(preview, pid); err != nil {
+			if errors.Is(err, access.ErrAccessDenied) {
+				apiutil.WriteErrResponse(w, http.StatusForbidden, err)
+				return
+			}
+			apiutil.WriteErrResponse(w, http.StatusInternalServerError, err)
+			return
+		}
+
+		// sign the serialized preview so clients can verify it wasn't tampered
+		// with in transit
+		data, err := json.Marshal(preview)
+		if err != nil {
+			apiutil.WriteErrResponse(w, http.StatusInternalServerError, err)
+			return
+		}
+		owner := r.node.Repo.Profiles().Owner(ctx)
+		if err := addPreviewSignatureHTTPHeaders(w.Header(), owner.PrivKey, owner.Peername, data); err != nil {
+			apiutil.WriteErrResponse(w, http.StatusInternalServerError, err)
+			return
+		}
+
+		apiutil.WriteResponse(w, json.RawMessage(data))
 	}
 }
 
@@ -713,7 +982,14 @@ func (r *Server) RefsHTTPHandler() http.HandlerFunc {
 				params[key] = req.FormValue(key)
 			}
 			if err := r.RemoveDataset(req.Context(), params); err != nil {
-				w.WriteHeader(http.StatusInternalServerError)
+				switch {
+				case errors.Is(err, ErrInvalidRequestSignature):
+					w.WriteHeader(http.StatusUnauthorized)
+				case errors.Is(err, access.ErrAccessDenied):
+					w.WriteHeader(http.StatusForbidden)
+				default:
+					w.WriteHeader(http.StatusInternalServerError)
+				}
 				w.Write([]byte(err.Error()))
 				return
 			}
@@ -3,16 +3,24 @@ package remote
 import (
 	"encoding/base64"
 	"fmt"
+	"net/http"
 	"time"
 
 	crypto "github.com/libp2p/go-libp2p-core/crypto"
 	"github.com/qri-io/qri/auth/key"
 	"github.com/qri-io/qri/dsref"
+	"github.com/qri-io/qri/profile"
 )
 
 var (
 	// nowFunc is an ps function for getting timestamps
 	nowFunc = time.Now
+	// ErrInvalidPreviewSignature indicates a preview response could not be
+	// verified against the public key of the peer that sent it
+	ErrInvalidPreviewSignature = fmt.Errorf("remote: invalid preview signature")
+	// ErrInvalidRequestSignature indicates a request claimed an identity it
+	// couldn't prove with a valid signature
+	ErrInvalidRequestSignature = fmt.Errorf("remote: invalid request signature")
 )
 
 func sigParams(pk crypto.PrivKey, subjectUsername string, ref dsref.Ref) (map[string]string, error) {
@@ -21,6 +29,11 @@ func sigParams(pk crypto.PrivKey, subjectUsername string, ref dsref.Ref) (map[st
 		return nil, err
 	}
 
+	pubKey, err := key.EncodePubKeyB64(pk.GetPublic())
+	if err != nil {
+		return nil, err
+	}
+
 	now := fmt.Sprintf("%d", nowFunc().In(time.UTC).Unix())
 	rss := requestSigningString(now, pid, ref.Path)
 	b64Sig, err := signString(pk, rss)
@@ -35,7 +48,8 @@ func sigParams(pk crypto.PrivKey, subjectUsername string, ref dsref.Ref) (map[st
 		"profileID": ref.ProfileID,
 		"path":      ref.Path,
 
-		"pid": pid,
+		"pid":    pid,
+		"pubkey": pubKey,
 		// subject_username is the client node's username, will be used
 		// on the remote side to determine access control
 		"subject_username": subjectUsername,
@@ -83,6 +97,61 @@ func VerifySigParams(pubkey crypto.PubKey, params map[string]string) (bool, erro
 	return pubkey.Verify([]byte(rss), sigBytes)
 }
 
+// authenticatePID returns the profile ID that signed a set of request params.
+// the public key sent with the params must hash to the claimed "pid", and the
+// signature must verify against that key. Params without a valid signature
+// return ErrInvalidRequestSignature
+func authenticatePID(params map[string]string) (profile.ID, error) {
+	if params["signature"] == "" || params["pubkey"] == "" {
+		return "", fmt.Errorf("%w: missing signature details", ErrInvalidRequestSignature)
+	}
+	pub, err := key.DecodeB64PubKey(params["pubkey"])
+	if err != nil {
+		return "", fmt.Errorf("%w: decoding public key: %s", ErrInvalidRequestSignature, err)
+	}
+	id, err := key.IDFromPubKey(pub)
+	if err != nil {
+		return "", fmt.Errorf("%w: %s", ErrInvalidRequestSignature, err)
+	}
+	if id != params["pid"] {
+		return "", fmt.Errorf("%w: public key does not match claimed ID %q", ErrInvalidRequestSignature, params["pid"])
+	}
+	if ok, err := VerifySigParams(pub, params); err != nil || !ok {
+		return "", ErrInvalidRequestSignature
+	}
+	return profile.IDB58Decode(id)
+}
+
+// authenticateSubject returns the profile ID that signed a set of request
+// params. Older clients don't send a public key, which leaves them unable to
+// prove an identity. Their requests are treated as anonymous, returning an
+// empty ID
+func authenticateSubject(params map[string]string) (profile.ID, error) {
+	if params["pubkey"] == "" {
+		log.Debugw("treating request without a public key as anonymous", "pid", params["pid"])
+		return "", nil
+	}
+	return authenticatePID(params)
+}
+
+// authenticateHTTPRequest returns the profile ID that signed an HTTP request
+// with signHTTPRequest. Requests that don't claim an identity, or come from
+// older clients that don't send a public key, return an empty ID. Requests
+// that claim an identity without proving it return ErrInvalidRequestSignature
+func authenticateHTTPRequest(req *http.Request) (profile.ID, error) {
+	if req.Header.Get("pid") == "" || req.Header.Get("pubkey") == "" {
+		return "", nil
+	}
+	return authenticatePID(map[string]string{
+		"pid":              req.Header.Get("pid"),
+		"pubkey":           req.Header.Get("pubkey"),
+		"timestamp":        req.Header.Get("timestamp"),
+		"signature":        req.Header.Get("signature"),
+		"path":             req.URL.Path,
+		"subject_username": "",
+	})
+}
+
 func requestSigningString(timestamp, peerID, cidStr string) string {
 	return fmt.Sprintf("%s.%s.%s", timestamp, peerID, cidStr)
 }
@@ -94,3 +163,77 @@ func signString(privKey crypto.PrivKey, str string) (b64Sig string, err error) {
 	}
 	return base64.StdEncoding.EncodeToString(sigbytes), nil
 }
+
+// addPreviewSignatureHTTPHeaders signs preview data with the given private key
+// and writes the signature along with the signer's identity & public key to
+// a set of headers, mirroring the author headers used by logsync
+func addPreviewSignatureHTTPHeaders(h http.Header, pk crypto.PrivKey, username string, data []byte) error {
+	id, err := key.IDFromPrivKey(pk)
+	if err != nil {
+		return err
+	}
+	pubKey, err := key.EncodePubKeyB64(pk.GetPublic())
+	if err != nil {
+		return err
+	}
+	sigbytes, err := pk.Sign(data)
+	if err != nil {
+		return fmt.Errorf("error signing %s", err.Error())
+	}
+
+	h.Set("ID", id)
+	h.Set("username", username)
+	h.Set("PubKey", pubKey)
+	h.Set("signature", base64.StdEncoding.EncodeToString(sigbytes))
+	return nil
+}
+
+// verifyPreviewSignature checks preview data was signed by one of the trusted
+// profile IDs. The public key sent in headers must hash to the signer's ID, so
+// a key taken from the response is only used once it's tied to an ID the
+// client already trusts. When pinned is false the client has no signer on
+// record for the remote, so unsigned previews & previews from unknown signers
+// are accepted with a warning. Signed previews must always match their
+// signature
+func verifyPreviewSignature(h http.Header, data []byte, trusted []string, pinned bool) error {
+	if h.Get("signature") == "" || h.Get("PubKey") == "" {
+		if pinned {
+			return fmt.Errorf("%w: missing signature details", ErrInvalidPreviewSignature)
+		}
+		log.Warnf("accepting unsigned preview, the remote may be running an older version of qri")
+		return nil
+	}
+	pub, err := key.DecodeB64PubKey(h.Get("PubKey"))
+	if err != nil {
+		return fmt.Errorf("%w: decoding public key: %s", ErrInvalidPreviewSignature, err)
+	}
+	id, err := key.IDFromPubKey(pub)
+	if err != nil {
+		return fmt.Errorf("%w: %s", ErrInvalidPreviewSignature, err)
+	}
+	if id != h.Get("ID") {
+		return fmt.Errorf("%w: public key does not match claimed ID %q", ErrInvalidPreviewSignature, h.Get("ID"))
+	}
+	sigBytes, err := base64.StdEncoding.DecodeString(h.Get("signature"))
+	if err != nil {
+		return fmt.Errorf("%w: decoding signature: %s", ErrInvalidPreviewSignature, err)
+	}
+	ok, err := pub.Verify(data, sigBytes)
+	if err != nil {
+		return fmt.Errorf("%w: %s", ErrInvalidPreviewSignature, err)
+	}
+	if !ok {
+		return ErrInvalidPreviewSignature
+	}
+
+	for _, tid := range trusted {
+		if tid != "" && tid == id {
+			return nil
+		}
+	}
+	if pinned {
+		return fmt.Errorf("%w: signer %q is not trusted", ErrInvalidPreviewSignature, id)
+	}
+	log.Warnf("accepting preview signed by unknown profile %q. set the remote's profile ID to require trusted signatures", id)
+	return nil
+}
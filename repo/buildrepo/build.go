@@ -79,7 +79,7 @@ func New(ctx context.Context, path string, cfg *config.Config, opts ...func(o *O
 			}
 		}
 		if o.Dscache == nil {
-			if o.Dscache, err = newDscache(ctx, o.Filesystem, o.Bus, o.Logbook, pro.Peername, path); err != nil {
+			if o.Dscache, err = newDscache(ctx, o.Filesystem, o.Bus, o.Logbook, pro.Peername, path, cfg.Repo.DscacheCreateNew); err != nil {
 				return nil, err
 			}
 		}
@@ -127,11 +127,13 @@ func newLogbook(fs qfs.Filesystem, bus event.Bus, pro *profile.Profile, repoPath
 	return logbook.NewJournal(*pro, bus, fs, logbookPath)
 }
 
-func newDscache(ctx context.Context, fs qfs.Filesystem, bus event.Bus, book *logbook.Book, username, repoPath string) (*dscache.Dscache, error) {
+func newDscache(ctx context.Context, fs qfs.Filesystem, bus event.Bus, book *logbook.Book, username, repoPath string, createNew bool) (*dscache.Dscache, error) {
 	// This seems to be a bug, the repoPath does not end in "qri" in some tests.
 	if !strings.HasSuffix(repoPath, "qri") {
 		return nil, fmt.Errorf("invalid repo path: %q", repoPath)
 	}
 	dscachePath := filepath.Join(repoPath, "dscache.qfb")
-	return dscache.NewDscache(ctx, fs, bus, username, dscachePath), nil
+	cache := dscache.NewDscache(ctx, fs, bus, username, dscachePath)
+	cache.CreateNewEnabled = createNew
+	return cache, nil
 }
@@ -0,0 +1,77 @@
+package buildrepo
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/qri-io/qfs"
+	testcfg "github.com/qri-io/qri/config/test"
+	"github.com/qri-io/qri/dsref"
+	"github.com/qri-io/qri/event"
+)
+
+func TestNewDscacheCreateNew(t *testing.T) {
+	cases := []struct {
+		description string
+		createNew   bool
+	}{
+		{"create new disabled", false},
+		{"create new enabled", true},
+	}
+
+	for _, c := range cases {
+		t.Run(c.description, func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			tmpdir, err := ioutil.TempDir("", "buildrepo_dscache")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer os.RemoveAll(tmpdir)
+
+			repoPath := filepath.Join(tmpdir, "qri")
+			if err := os.MkdirAll(repoPath, 0755); err != nil {
+				t.Fatal(err)
+			}
+
+			cfg := testcfg.DefaultConfigForTesting()
+			cfg.SetPath(filepath.Join(repoPath, "config.yaml"))
+			cfg.Filesystems = []qfs.Config{{Type: "mem"}, {Type: "local"}}
+			cfg.Repo.DscacheCreateNew = c.createNew
+
+			r, err := New(ctx, repoPath, cfg, func(o *Options) {
+				o.Bus = event.NewBus(ctx)
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			owner := r.Profiles().Owner(ctx)
+			initID, err := r.Logbook().WriteDatasetInit(ctx, owner, "created_dataset")
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			_, err = os.Stat(filepath.Join(repoPath, "dscache.qfb"))
+			if c.createNew && err != nil {
+				t.Fatalf("expected dscache file to exist: %s", err)
+			} else if !c.createNew && !os.IsNotExist(err) {
+				t.Fatalf("expected no dscache file to be created, got err: %v", err)
+			}
+
+			if c.createNew {
+				ref := dsref.Ref{Username: owner.Peername, Name: "created_dataset"}
+				if _, err := r.Dscache().ResolveRef(ctx, &ref); err != nil {
+					t.Fatal(err)
+				}
+				if ref.InitID != initID {
+					t.Errorf("expected cached initID %q, got %q", initID, ref.InitID)
+				}
+			}
+		})
+	}
+}
@@ -2,6 +2,7 @@ package repo
 
 import (
 	"fmt"
+	"regexp"
 	"strings"
 
 	"github.com/mr-tron/base58/base58"
@@ -10,6 +11,9 @@ import (
 	reporef "github.com/qri-io/qri/repo/ref"
 )
 
+// validRefName matches usernames & dataset names ParseDatasetRef accepts
+var validRefName = regexp.MustCompile(`^[a-zA-Z][\w-]*$`)
+
 // ParseDatasetRef decodes a dataset reference from a string value
 // It’s possible to refer to a dataset in a number of ways.
 // The full definition of a dataset reference is as follows:
@@ -36,6 +40,10 @@ import (
 //
 // see tests for more exmples
 //
+// parse failures wrap one of ErrEmptyRef, ErrMalformedRef, ErrBadRefUsername,
+// ErrBadRefName, ErrBadRefProfileID or ErrBadRefPath, and can be checked with
+// errors.Is
+//
 // TODO - add validation that prevents peernames from being
 // valid base58 multihashes and makes sure hashes are actually valid base58 multihashes
 // TODO - figure out how IPFS CID's play into this
@@ -58,6 +66,9 @@ func ParseDatasetRef(ref string) (reporef.DatasetRef, error) {
 
 		dsr.Peername, dsr.Name = parseAlias(ref[:atIndex])
 		dsr.ProfileID, dsr.Path, err = parseIdentifiers(ref[atIndex+1:])
+		if err != nil {
+			return reporef.DatasetRef{}, err
+		}
 
 	} else {
 
@@ -99,8 +110,16 @@ func ParseDatasetRef(ref string) (reporef.DatasetRef, error) {
 	}
 
 	if dsr.ProfileID == "" && dsr.Peername == "" && dsr.Name == "" && dsr.Path == "" {
-		err = fmt.Errorf("malformed reporef.DatasetRef string: %s", ref)
-		return dsr, err
+		return reporef.DatasetRef{}, fmt.Errorf("%w: %q", ErrMalformedRef, ref)
+	}
+	if dsr.Peername != "" && !validRefName.MatchString(dsr.Peername) {
+		return reporef.DatasetRef{}, fmt.Errorf("%w: %q", ErrBadRefUsername, dsr.Peername)
+	}
+	if dsr.Name != "" && !validRefName.MatchString(dsr.Name) {
+		return reporef.DatasetRef{}, fmt.Errorf("%w: %q", ErrBadRefName, dsr.Name)
+	}
+	if dsr.Path != "" && !isRefPath(dsr.Path) {
+		return reporef.DatasetRef{}, fmt.Errorf("%w: %q", ErrBadRefPath, dsr.Path)
 	}
 
 	// if dsr.ProfileID != "" {
@@ -130,10 +149,12 @@ func parseIdentifiers(ids string) (profileID profile.ID, path string, err error)
 	toks := strings.Split(ids, "/")
 	switch len(toks) {
 	case 0:
-		err = fmt.Errorf("malformed reporef.DatasetRef identifier: %s", ids)
+		err = fmt.Errorf("%w: %q", ErrMalformedRef, ids)
 	case 1:
 		if toks[0] != "" {
-			profileID, err = profile.IDB58Decode(toks[0])
+			if profileID, err = profile.IDB58Decode(toks[0]); err != nil {
+				err = fmt.Errorf("%w: %q", ErrBadRefProfileID, toks[0])
+			}
 			// if !isBase58Multihash(toks[0]) {
 			// 	err = fmt.Errorf("'%s' is not a base58 multihash", ids)
 			// }
@@ -162,6 +183,12 @@ func parseIdentifiers(ids string) (profileID profile.ID, path string, err error)
 	return
 }
 
+// isRefPath checks a path has the form /network/hash
+func isRefPath(path string) bool {
+	toks := strings.Split(path, "/")
+	return len(toks) >= 3 && toks[0] == "" && toks[1] != "" && toks[2] != ""
+}
+
 // TODO - this could be more robust?
 func stripProtocol(ref string) string {
 	if strings.HasPrefix(ref, "/ipfs/") {
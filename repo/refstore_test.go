@@ -186,6 +186,14 @@ func TestParseDatasetRef(t *testing.T) {
 
 		{"peername/datasetname/@/network/QmYCvbfNbCwFR45HiNP45rwJgvatpiW38D961L5qAhUM5Y/junk/junk/...", fullDatasetRef, ""},
 		{"peername/datasetname/@/ipfs/QmYCvbfNbCwFR45HiNP45rwJgvatpiW38D961L5qAhUM5Y/junk/junk/...", fullIPFSDatasetRef, ""},
+
+		{"@", reporef.DatasetRef{}, `repo: malformed dataset reference: "@"`},
+		{"_peername/datasetname", reporef.DatasetRef{}, `repo: invalid dataset reference username: "_peername"`},
+		{"peer name/datasetname", reporef.DatasetRef{}, `repo: invalid dataset reference username: "peer name"`},
+		{"peername/data$et", reporef.DatasetRef{}, `repo: invalid dataset reference name: "data$et"`},
+		{"peername/datasetname@not_an_id", reporef.DatasetRef{}, `repo: invalid dataset reference profileID: "not_an_id"`},
+		{"peername/datasetname/junk", reporef.DatasetRef{}, `repo: invalid dataset reference path: "junk"`},
+		{"peername/datasetname@foo/bar", reporef.DatasetRef{}, `repo: invalid dataset reference path: "bar"`},
 	}
 
 	for i, c := range cases {
@@ -47,6 +47,16 @@ var (
 	ErrNoRegistry = fmt.Errorf("no configured registry")
 	// ErrEmptyRef indicates that the given reference is empty
 	ErrEmptyRef = fmt.Errorf("repo: empty dataset reference")
+	// ErrMalformedRef indicates a reference has no recognizable components
+	ErrMalformedRef = fmt.Errorf("repo: malformed dataset reference")
+	// ErrBadRefUsername indicates a reference has an invalid username
+	ErrBadRefUsername = fmt.Errorf("repo: invalid dataset reference username")
+	// ErrBadRefName indicates a reference has an invalid dataset name
+	ErrBadRefName = fmt.Errorf("repo: invalid dataset reference name")
+	// ErrBadRefProfileID indicates a reference has an invalid profileID
+	ErrBadRefProfileID = fmt.Errorf("repo: invalid dataset reference profileID")
+	// ErrBadRefPath indicates a reference has an invalid path
+	ErrBadRefPath = fmt.Errorf("repo: invalid dataset reference path")
 )
 
 // Repo is the interface for working with a qri repository qri repos are stored
@@ -2,9 +2,11 @@ package test
 
 import (
 	"archive/zip"
+	"fmt"
 	"io"
 	"os"
 	"path/filepath"
+	"strings"
 
 	"github.com/qri-io/qri/auth/key"
 	testkeys "github.com/qri-io/qri/auth/key/test"
@@ -29,37 +31,86 @@ func (g *testCryptoGenerator) GeneratePrivateKeyAndPeerID() (string, string) {
 
 // InitIPFSRepo creates an IPFS repo by un-zipping a preconstructed IPFS repo
 func InitIPFSRepo(repoPath, configPath string) error {
-	unzipFile(TestdataPath("empty_ipfs_repo.zip"), repoPath)
-	return nil
+	return unzipFile(TestdataPath("empty_ipfs_repo.zip"), repoPath)
+}
+
+const (
+	// maxUnzipFileSize is the largest uncompressed size of a single file
+	// unzipFile will extract
+	maxUnzipFileSize = 64 << 20
+	// maxUnzipTotalSize is the largest total uncompressed size of all files
+	// unzipFile will extract
+	maxUnzipTotalSize = 256 << 20
+)
+
+// unzipFile extracts the contents of a zip archive into destDir. Entries with
+// names that would be written outside of destDir are rejected
+func unzipFile(sourceZip, destDir string) error {
+	return unzipFileWithLimits(sourceZip, destDir, maxUnzipFileSize, maxUnzipTotalSize)
 }
 
-func unzipFile(sourceZip, destDir string) {
+// unzipFileWithLimits extracts a zip archive, erroring if any file or the sum
+// of all files decompresses to more than the given number of bytes
+func unzipFileWithLimits(sourceZip, destDir string, fileLimit, totalLimit int64) error {
 	r, err := zip.OpenReader(sourceZip)
 	if err != nil {
-		panic(err)
+		return err
 	}
 	defer r.Close()
 
+	var total int64
 	for _, f := range r.File {
-		rc, err := f.Open()
+		n, err := unzipEntry(f, destDir, fileLimit)
 		if err != nil {
-			panic(err)
+			return err
 		}
-		defer rc.Close()
-
-		fpath := filepath.Join(destDir, f.Name)
-		if f.FileInfo().IsDir() {
-			os.MkdirAll(fpath, os.ModePerm)
-		} else {
-			if err = os.MkdirAll(filepath.Dir(fpath), os.ModePerm); err != nil {
-				panic(err)
-			}
-			outFile, err := os.OpenFile(fpath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, f.Mode())
-			if err != nil {
-				panic(err)
-			}
-			_, err = io.Copy(outFile, rc)
-			outFile.Close()
+		total += n
+		if total > totalLimit {
+			return fmt.Errorf("zip archive exceeds total size limit of %d bytes", totalLimit)
 		}
 	}
+	return nil
+}
+
+// unzipEntry writes a single zip entry into destDir, returning the number of
+// bytes written
+func unzipEntry(f *zip.File, destDir string, limit int64) (int64, error) {
+	fpath := filepath.Join(destDir, f.Name)
+	// guard against "zip slip", entries like "../../evil" that escape destDir
+	if !strings.HasPrefix(fpath, filepath.Clean(destDir)+string(os.PathSeparator)) {
+		return 0, fmt.Errorf("illegal file path in zip archive: %q", f.Name)
+	}
+
+	if f.FileInfo().IsDir() {
+		return 0, os.MkdirAll(fpath, os.ModePerm)
+	}
+	if f.UncompressedSize64 > uint64(limit) {
+		return 0, fmt.Errorf("zip entry %q exceeds size limit of %d bytes", f.Name, limit)
+	}
+	if err := os.MkdirAll(filepath.Dir(fpath), os.ModePerm); err != nil {
+		return 0, err
+	}
+
+	rc, err := f.Open()
+	if err != nil {
+		return 0, err
+	}
+	defer rc.Close()
+
+	outFile, err := os.OpenFile(fpath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, f.Mode())
+	if err != nil {
+		return 0, err
+	}
+	// the size recorded in the archive header can't be trusted, read at most
+	// one byte past the limit to detect entries that lie about their size
+	n, err := io.CopyN(outFile, rc, limit+1)
+	if err != nil && err != io.EOF {
+		outFile.Close()
+		return n, err
+	}
+	if n > limit {
+		outFile.Close()
+		return n, fmt.Errorf("zip entry %q exceeds size limit of %d bytes", f.Name, limit)
+	}
+	return n, outFile.Close()
 }
@@ -0,0 +1,127 @@
+package test
+
+import (
+	"archive/zip"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeTestZip(t *testing.T, path string, files map[string]string) {
+	t.Helper()
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	w := zip.NewWriter(f)
+	for name, content := range files {
+		fw, err := w.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := fw.Write([]byte(content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestUnzipFileRejectsPathTraversal(t *testing.T) {
+	dir, err := ioutil.TempDir("", "unzip_traversal")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	zipPath := filepath.Join(dir, "evil.zip")
+	writeTestZip(t, zipPath, map[string]string{
+		"../escaped.txt": "should never be written",
+	})
+
+	dest := filepath.Join(dir, "dest")
+	err = unzipFile(zipPath, dest)
+	if err == nil {
+		t.Fatal("expected error unzipping an entry outside the destination, got nil")
+	}
+	expect := `illegal file path in zip archive: "../escaped.txt"`
+	if err.Error() != expect {
+		t.Errorf("error mismatch. want: %q, got: %q", expect, err.Error())
+	}
+	if _, err := os.Stat(filepath.Join(dir, "escaped.txt")); !os.IsNotExist(err) {
+		t.Error("expected escaping entry to not be written")
+	}
+
+	// a well-behaved archive is extracted
+	zipPath = filepath.Join(dir, "good.zip")
+	writeTestZip(t, zipPath, map[string]string{
+		"sub/file.txt": "hello",
+	})
+	if err := unzipFile(zipPath, dest); err != nil {
+		t.Fatal(err)
+	}
+	data, err := ioutil.ReadFile(filepath.Join(dest, "sub", "file.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "hello" {
+		t.Errorf("content mismatch. want: %q, got: %q", "hello", string(data))
+	}
+}
+
+func TestUnzipFileSizeLimits(t *testing.T) {
+	dir, err := ioutil.TempDir("", "unzip_limits")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	zipPath := filepath.Join(dir, "big.zip")
+	writeTestZip(t, zipPath, map[string]string{
+		"small.txt": "tiny",
+		"big.txt":   strings.Repeat("a", 2048),
+	})
+
+	err = unzipFileWithLimits(zipPath, filepath.Join(dir, "per_file"), 1024, 1<<20)
+	if err == nil {
+		t.Fatal("expected error extracting an oversized entry, got nil")
+	}
+	expect := `zip entry "big.txt" exceeds size limit of 1024 bytes`
+	if err.Error() != expect {
+		t.Errorf("error mismatch. want: %q, got: %q", expect, err.Error())
+	}
+
+	err = unzipFileWithLimits(zipPath, filepath.Join(dir, "total"), 4096, 2050)
+	if err == nil {
+		t.Fatal("expected error exceeding total size limit, got nil")
+	}
+	expect = `zip archive exceeds total size limit of 2050 bytes`
+	if err.Error() != expect {
+		t.Errorf("error mismatch. want: %q, got: %q", expect, err.Error())
+	}
+
+	if err := unzipFileWithLimits(zipPath, filepath.Join(dir, "ok"), 4096, 4096); err != nil {
+		t.Errorf("expected archive within limits to extract, got: %s", err)
+	}
+}
+
+func TestInitIPFSRepoReturnsErrors(t *testing.T) {
+	dir, err := ioutil.TempDir("", "init_ipfs_repo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	// a file in place of the repo directory makes extraction fail
+	repoPath := filepath.Join(dir, "ipfs")
+	if err := ioutil.WriteFile(repoPath, []byte("not a directory"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := InitIPFSRepo(repoPath, ""); err == nil {
+		t.Error("expected InitIPFSRepo to return an extraction error, got nil")
+	}
+}
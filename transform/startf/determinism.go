@@ -0,0 +1,110 @@
+package startf
+
+import (
+	"fmt"
+	"math/rand"
+	"time"
+
+	starlarktime "go.starlark.net/lib/time"
+	"go.starlark.net/starlark"
+	"go.starlark.net/starlarkstruct"
+)
+
+const (
+	// RandomModuleName is the name scripts use to load the random module,
+	// eg: load('random.star', 'random')
+	RandomModuleName = "random.star"
+	// randKey is the thread-local key for a transform's random source
+	randKey = "qri.rand"
+)
+
+// DeterministicTime is the time returned by time.now() when a transform
+// runs in deterministic mode
+var DeterministicTime = time.Date(2000, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+// randomModule exposes pseudo-random number generation to starlark. Numbers
+// are drawn from the thread's random source, which is seeded from the
+// transform's seed in deterministic mode
+var randomModule = &starlarkstruct.Module{
+	Name: "random",
+	Members: starlark.StringDict{
+		"random":  starlark.NewBuiltin("random", randomRandom),
+		"randint": starlark.NewBuiltin("randint", randomRandint),
+		"choice":  starlark.NewBuiltin("choice", randomChoice),
+	},
+}
+
+// threadRand returns the random source for a thread, creating a
+// time-seeded source if the thread doesn't have one
+func threadRand(thread *starlark.Thread) *rand.Rand {
+	if r, ok := thread.Local(randKey).(*rand.Rand); ok {
+		return r
+	}
+	r := rand.New(rand.NewSource(time.Now().UnixNano()))
+	thread.SetLocal(randKey, r)
+	return r
+}
+
+// random() returns a float in the range [0.0, 1.0)
+func randomRandom(thread *starlark.Thread, _ *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	if err := starlark.UnpackPositionalArgs("random", args, kwargs, 0); err != nil {
+		return nil, err
+	}
+	return starlark.Float(threadRand(thread).Float64()), nil
+}
+
+// randint(a, b) returns an integer N such that a <= N <= b
+func randomRandint(thread *starlark.Thread, _ *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	var a, b int
+	if err := starlark.UnpackPositionalArgs("randint", args, kwargs, 2, &a, &b); err != nil {
+		return nil, err
+	}
+	if b < a {
+		return nil, fmt.Errorf("randint: empty range (%d, %d)", a, b)
+	}
+	return starlark.MakeInt(a + threadRand(thread).Intn(b-a+1)), nil
+}
+
+// choice(seq) returns a random element from a non-empty sequence
+func randomChoice(thread *starlark.Thread, _ *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	var seq starlark.Indexable
+	if err := starlark.UnpackPositionalArgs("choice", args, kwargs, 1, &seq); err != nil {
+		return nil, err
+	}
+	if seq.Len() == 0 {
+		return nil, fmt.Errorf("choice: cannot choose from an empty sequence")
+	}
+	return seq.Index(threadRand(thread).Intn(seq.Len())), nil
+}
+
+// deterministicTimeModule is the time module with now() replaced by a fixed
+// clock
+var deterministicTimeModule = func() *starlarkstruct.Module {
+	members := starlark.StringDict{}
+	for k, v := range starlarktime.Module.Members {
+		members[k] = v
+	}
+	members["now"] = starlark.NewBuiltin("now", func(thread *starlark.Thread, _ *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+		return starlarktime.Time(DeterministicTime), nil
+	})
+	return &starlarkstruct.Module{Name: "time", Members: members}
+}()
+
+// runnerModuleLoader wraps a module loader, adding the random module and
+// substituting a fixed clock in deterministic mode
+func runnerModuleLoader(load ModuleLoader, deterministic bool) ModuleLoader {
+	return func(thread *starlark.Thread, module string) (starlark.StringDict, error) {
+		switch module {
+		case RandomModuleName:
+			return starlark.StringDict{"random": randomModule}, nil
+		case "time.star":
+			if deterministic {
+				return starlark.StringDict{"time": deterministicTimeModule}, nil
+			}
+		}
+		if load == nil {
+			return nil, fmt.Errorf("invalid module %q", module)
+		}
+		return load(thread, module)
+	}
+}
@@ -0,0 +1,74 @@
+package startf
+
+import (
+	"sort"
+
+	stards "github.com/qri-io/qri/transform/startf/ds"
+	"go.starlark.net/starlark"
+)
+
+// ModuleDoc describes a starlark module that can be loaded by transform
+// scripts
+type ModuleDoc struct {
+	// Name is the name passed to load(), eg: "dataset.star"
+	Name    string      `json:"name"`
+	Members []MemberDoc `json:"members"`
+}
+
+// MemberDoc describes a value exported by a module. Structs list their
+// fields as members
+type MemberDoc struct {
+	Name    string      `json:"name"`
+	Type    string      `json:"type"`
+	Members []MemberDoc `json:"members,omitempty"`
+}
+
+// LoadableModules maps module names to functions that load them. Only
+// modules that can be loaded without any runtime state are listed
+var LoadableModules = map[string]func() (starlark.StringDict, error){
+	stards.ModuleName: stards.LoadModule,
+	RandomModuleName: func() (starlark.StringDict, error) {
+		return starlark.StringDict{"random": randomModule}, nil
+	},
+}
+
+// ModuleDocs describes all LoadableModules, sorted by name
+func ModuleDocs() ([]ModuleDoc, error) {
+	docs := make([]ModuleDoc, 0, len(LoadableModules))
+	for name, load := range LoadableModules {
+		dict, err := load()
+		if err != nil {
+			return nil, err
+		}
+		docs = append(docs, ModuleDoc{
+			Name:    name,
+			Members: describeStringDict(dict),
+		})
+	}
+	sort.Slice(docs, func(i, j int) bool { return docs[i].Name < docs[j].Name })
+	return docs, nil
+}
+
+func describeStringDict(dict starlark.StringDict) []MemberDoc {
+	members := make([]MemberDoc, 0, len(dict))
+	for _, name := range dict.Keys() {
+		members = append(members, describeValue(name, dict[name]))
+	}
+	return members
+}
+
+func describeValue(name string, v starlark.Value) MemberDoc {
+	doc := MemberDoc{Name: name, Type: v.Type()}
+	if attrs, ok := v.(starlark.HasAttrs); ok {
+		names := attrs.AttrNames()
+		sort.Strings(names)
+		for _, attr := range names {
+			av, err := attrs.Attr(attr)
+			if err != nil || av == nil {
+				continue
+			}
+			doc.Members = append(doc.Members, describeValue(attr, av))
+		}
+	}
+	return doc
+}
@@ -4,18 +4,29 @@ import (
 	"fmt"
 
 	"github.com/qri-io/dataset"
+	"github.com/qri-io/qri/dsref"
 	"github.com/qri-io/starlib/dataframe"
 	"go.starlark.net/starlark"
 )
 
+// LatestResourceKey is the transform resource key that records the version
+// dataset.latest(pin=True) resolved to
+const LatestResourceKey = "latest"
+
 // BoundDataset represents the datset a transform script is bound to
 type BoundDataset struct {
 	frozen       bool
 	commitCalled bool
 	latest       *dataset.Dataset
-	outconf      *dataframe.OutputConfig
-	onCommit     func(ds *Dataset) error
-	load         func(refstr string) (*Dataset, error)
+	// reference to the version latest was loaded from, in
+	// peername/name@path form. empty if the dataset has no history
+	latestRef string
+	// dataset the script committed to, if different from the bound dataset.
+	// empty when the script writes back to the bound dataset
+	outputRef string
+	outconf   *dataframe.OutputConfig
+	onCommit  func(ds *Dataset) error
+	load      func(refstr string) (*dataset.Dataset, error)
 }
 
 // compile-time interface assertions
@@ -29,6 +40,20 @@ func NewBoundDataset(latest *dataset.Dataset, outconf *dataframe.OutputConfig, o
 	return &BoundDataset{latest: latest, onCommit: onCommit, outconf: outconf}
 }
 
+// SetLatestRef records the version the bound dataset was loaded from, as a
+// peername/name@path reference string
+func (b *BoundDataset) SetLatestRef(ref string) { b.latestRef = ref }
+
+// SetLoader sets the function used to load pinned versions
+func (b *BoundDataset) SetLoader(load func(refstr string) (*dataset.Dataset, error)) {
+	b.load = load
+}
+
+// OutputRef returns the reference a script passed to dataset.commit(ref=...),
+// in username/name form. OutputRef is empty if the script committed to the
+// bound dataset
+func (b *BoundDataset) OutputRef() string { return b.outputRef }
+
 // String returns the Dataset as a string
 func (b *BoundDataset) String() string { return b.stringify() }
 
@@ -66,18 +91,83 @@ var boundDatasetMethods = map[string]*starlark.Builtin{
 
 func head(thread *starlark.Thread, builtin *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
 	self := builtin.Receiver().(*BoundDataset)
+	var pin bool
+	if err := starlark.UnpackArgs("latest", args, kwargs, "pin?", &pin); err != nil {
+		return starlark.None, err
+	}
+	if pin {
+		if err := self.pinLatest(); err != nil {
+			return starlark.None, err
+		}
+	}
 	return NewDataset(self.latest, self.outconf), nil
 }
 
+// pinLatest uses the version recorded in the transform's resources as the
+// latest version, loading it if it isn't the current head. If no version is
+// recorded the current head is recorded
+func (b *BoundDataset) pinLatest() error {
+	if b.latest.Transform == nil {
+		b.latest.Transform = &dataset.Transform{}
+	}
+	tf := b.latest.Transform
+	if r, ok := tf.Resources[LatestResourceKey]; ok && r.Path != "" {
+		if r.Path == b.latestRef {
+			return nil
+		}
+		if b.load == nil {
+			return fmt.Errorf("latest: loading pinned version %q is not enabled", r.Path)
+		}
+		pinned, err := b.load(r.Path)
+		if err != nil {
+			return fmt.Errorf("latest: loading pinned version %q: %w", r.Path, err)
+		}
+		pinned.DropTransientValues()
+		pinned.DropDerivedValues()
+		pinned.Commit = nil
+		pinned.ID = b.latest.ID
+		pinned.Peername = b.latest.Peername
+		pinned.Name = b.latest.Name
+		pinned.Transform = tf
+		*b.latest = *pinned
+		return nil
+	}
+
+	if b.latestRef == "" {
+		// no previous version to pin
+		return nil
+	}
+	if tf.Resources == nil {
+		tf.Resources = map[string]*dataset.TransformResource{}
+	}
+	tf.Resources[LatestResourceKey] = &dataset.TransformResource{Path: b.latestRef}
+	return nil
+}
+
 func commit(thread *starlark.Thread, builtin *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
 	self := builtin.Receiver().(*BoundDataset)
 	if self.commitCalled {
 		return nil, fmt.Errorf("commit can only be called once in a transform script")
 	}
-	starDs := &Dataset{}
-	if err := starlark.UnpackArgs("commit", args, kwargs, "ds", starDs); err != nil {
+	var (
+		starDs = &Dataset{}
+		refstr starlark.String
+	)
+	if err := starlark.UnpackArgs("commit", args, kwargs, "ds", starDs, "ref?", &refstr); err != nil {
 		return starlark.None, err
 	}
+	if refstr != "" {
+		// commit to a dataset other than the one the script is bound to
+		ref, err := dsref.ParseHumanFriendly(refstr.GoString())
+		if err != nil {
+			return starlark.None, fmt.Errorf("commit: invalid ref %q: %w", refstr.GoString(), err)
+		}
+		self.outputRef = ref.Alias()
+		starDs.lk.Lock()
+		starDs.ds.Peername = ref.Username
+		starDs.ds.Name = ref.Name
+		starDs.lk.Unlock()
+	}
 	if self.onCommit != nil {
 		if err := self.onCommit(starDs); err != nil {
 			return starlark.None, err
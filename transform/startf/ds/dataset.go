@@ -2,14 +2,17 @@
 package ds
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io/ioutil"
+	"math"
 	"sort"
 	"strings"
 	"sync"
+	"unicode/utf8"
 
 	golog "github.com/ipfs/go-log"
 	"github.com/qri-io/dataset"
@@ -50,15 +53,32 @@ func LoadModule() (starlark.StringDict, error) {
 	return datasetModule, nil
 }
 
-// Dataset is a qri dataset starlark type
+// Dataset is a qri dataset starlark type. Methods are safe for concurrent
+// use. Once frozen a Dataset can only be read
 type Dataset struct {
+	// lk guards all fields below
+	lk        sync.RWMutex
 	frozen    bool
 	ds        *dataset.Dataset
 	bodyFrame starlark.Value
 	changes   map[string]struct{}
+	// every component change in order, never cleared by ResetChanges, so
+	// snapshots remain valid across resets
+	changeLog []string
 	outconf   *dataframe.OutputConfig
+	// confirm unchanged bodies by comparing checksums
+	verifyBody bool
+	// largest body size to diff for commit messages, 0 uses the default
+	bodyDiffThreshold int
+	// customizes generated commit titles & messages, nil uses the default
+	commitMessage dsfs.CommitMessageFunc
+	// layout of a partitioned body, in body order. nil for single-file bodies
+	partitions []dsfs.BodyPartition
 }
 
+// ChangeSnapshot marks a point in a dataset's history of changes
+type ChangeSnapshot int
+
 // compile-time interface assertions
 var (
 	_ starlark.Value       = (*Dataset)(nil)
@@ -73,6 +93,9 @@ var dsMethods = map[string]*starlark.Builtin{
 	"get_meta":      starlark.NewBuiltin("get_meta", dsGetMeta),
 	"get_structure": starlark.NewBuiltin("get_structure", dsGetStructure),
 	"set_structure": starlark.NewBuiltin("set_structure", dsSetStructure),
+
+	"rename_columns":  starlark.NewBuiltin("rename_columns", dsRenameColumns),
+	"reorder_columns": starlark.NewBuiltin("reorder_columns", dsReorderColumns),
 }
 
 // NewDataset creates a dataset object, intended to be called from go-land to prepare datasets
@@ -98,17 +121,113 @@ func (d *Dataset) Unpack(v starlark.Value) error {
 	if !ok {
 		return fmt.Errorf("expected dataset, got: %s", v.Type())
 	}
-	*d = *ds
+	ds.lk.RLock()
+	defer ds.lk.RUnlock()
+	d.frozen = ds.frozen
+	d.ds = ds.ds
+	d.bodyFrame = ds.bodyFrame
+	d.changes = ds.changes
+	d.changeLog = ds.changeLog
+	d.outconf = ds.outconf
+	d.verifyBody = ds.verifyBody
+	d.bodyDiffThreshold = ds.bodyDiffThreshold
+	d.commitMessage = ds.commitMessage
+	d.partitions = ds.partitions
 	return nil
 }
 
-// Changes returns a map of which components have been changed
+// Changes returns a map of which components have been changed. The map is a
+// copy, modifying it doesn't affect the dataset
 func (d *Dataset) Changes() map[string]struct{} {
-	return d.changes
+	d.lk.RLock()
+	defer d.lk.RUnlock()
+	changes := make(map[string]struct{}, len(d.changes))
+	for comp := range d.changes {
+		changes[comp] = struct{}{}
+	}
+	return changes
+}
+
+// SetVerifyBodyChecksum configures whether a body reported as unchanged is
+// confirmed by comparing its checksum to the previous version's structure
+// checksum when assigning components
+func (d *Dataset) SetVerifyBodyChecksum(verify bool) {
+	d.lk.Lock()
+	defer d.lk.Unlock()
+	d.verifyBody = verify
+}
+
+// SetBodyDiffThreshold sets the largest body size in bytes that is diffed
+// against the previous version to describe body changes in the commit
+// message. Larger bodies get a summary message. 0 uses
+// dsfs.BodySizeSmallEnoughToDiff
+func (d *Dataset) SetBodyDiffThreshold(size int) {
+	d.lk.Lock()
+	defer d.lk.Unlock()
+	d.bodyDiffThreshold = size
+}
+
+// SetCommitMessageFunc sets a function that customizes the commit title &
+// message generated when assigning components. Titles & messages set by the
+// script are never replaced
+func (d *Dataset) SetCommitMessageFunc(f dsfs.CommitMessageFunc) {
+	d.lk.Lock()
+	defer d.lk.Unlock()
+	d.commitMessage = f
+}
+
+// diffThreshold returns the body size limit for diffing
+func (d *Dataset) diffThreshold() int {
+	if d.bodyDiffThreshold > 0 {
+		return d.bodyDiffThreshold
+	}
+	return dsfs.BodySizeSmallEnoughToDiff
+}
+
+// ResetChanges clears the set of changed components
+func (d *Dataset) ResetChanges() {
+	d.lk.Lock()
+	defer d.lk.Unlock()
+	d.changes = make(map[string]struct{})
+}
+
+// SnapshotChanges returns a snapshot of the current point in the dataset's
+// history of changes, for use with ChangedSince
+func (d *Dataset) SnapshotChanges() ChangeSnapshot {
+	d.lk.RLock()
+	defer d.lk.RUnlock()
+	return ChangeSnapshot(len(d.changeLog))
+}
+
+// ChangedSince returns the set of components changed after the snapshot was
+// taken. Components changed both before and after the snapshot are included
+func (d *Dataset) ChangedSince(snapshot ChangeSnapshot) map[string]struct{} {
+	d.lk.RLock()
+	defer d.lk.RUnlock()
+	changed := make(map[string]struct{})
+	if int(snapshot) < 0 || int(snapshot) > len(d.changeLog) {
+		return changed
+	}
+	for _, comp := range d.changeLog[snapshot:] {
+		changed[comp] = struct{}{}
+	}
+	return changed
+}
+
+// markChanged records a component change. callers must hold the write lock
+func (d *Dataset) markChanged(comp string) {
+	d.changes[comp] = struct{}{}
+	d.changeLog = append(d.changeLog, comp)
 }
 
-// Dataset exposes the internal dataset pointer
-func (d *Dataset) Dataset() *dataset.Dataset { return d.ds }
+// Dataset exposes the internal dataset pointer. The pointer is shared, not
+// copied: it's only safe to read or modify once no starlark code is running
+// against the dataset, and changes made through it aren't tracked by Changes
+func (d *Dataset) Dataset() *dataset.Dataset {
+	d.lk.RLock()
+	defer d.lk.RUnlock()
+	return d.ds
+}
 
 // String returns the Dataset as a string
 func (d *Dataset) String() string {
@@ -116,10 +235,14 @@ func (d *Dataset) String() string {
 }
 
 // Type returns a short string describing the value's type.
-func (Dataset) Type() string { return fmt.Sprintf("%s.Dataset", "dataset") }
+func (*Dataset) Type() string { return fmt.Sprintf("%s.Dataset", "dataset") }
 
 // Freeze renders Dataset immutable.
-func (d *Dataset) Freeze() { d.frozen = true }
+func (d *Dataset) Freeze() {
+	d.lk.Lock()
+	defer d.lk.Unlock()
+	d.frozen = true
+}
 
 // Hash cannot be used with Dataset
 func (d *Dataset) Hash() (uint32, error) {
@@ -146,6 +269,8 @@ func (d *Dataset) AttrNames() []string {
 
 // SetField assigns to a field of the Dataset
 func (d *Dataset) SetField(name string, val starlark.Value) error {
+	d.lk.Lock()
+	defer d.lk.Unlock()
 	if d.frozen {
 		return fmt.Errorf("cannot set, Dataset is frozen")
 	}
@@ -180,6 +305,8 @@ func builtinAttrNames(methods map[string]*starlark.Builtin) []string {
 // dsGetMeta gets a dataset meta component
 func dsGetMeta(_ *starlark.Thread, b *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
 	self := b.Receiver().(*Dataset)
+	self.lk.RLock()
+	defer self.lk.RUnlock()
 
 	if self.ds.Meta == nil {
 		return starlark.None, nil
@@ -209,10 +336,12 @@ func dsSetMeta(_ *starlark.Thread, b *starlark.Builtin, args starlark.Tuple, kwa
 	}
 	self := b.Receiver().(*Dataset)
 
+	self.lk.Lock()
+	defer self.lk.Unlock()
 	if self.frozen {
 		return starlark.None, fmt.Errorf("cannot call set_meta on frozen dataset")
 	}
-	self.changes["meta"] = struct{}{}
+	self.markChanged("meta")
 
 	key := keyx.GoString()
 
@@ -231,6 +360,8 @@ func dsSetMeta(_ *starlark.Thread, b *starlark.Builtin, args starlark.Tuple, kwa
 // dsGetStructure gets a dataset structure component
 func dsGetStructure(_ *starlark.Thread, b *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
 	self := b.Receiver().(*Dataset)
+	self.lk.RLock()
+	defer self.lk.RUnlock()
 
 	if self.ds.Structure == nil {
 		return starlark.None, nil
@@ -258,18 +389,20 @@ func dsSetStructure(_ *starlark.Thread, b *starlark.Builtin, args starlark.Tuple
 		return nil, err
 	}
 
+	self.lk.Lock()
+	defer self.lk.Unlock()
 	if self.frozen {
 		return starlark.None, fmt.Errorf("cannot call set_structure on frozen dataset")
 	}
-	self.changes["structure"] = struct{}{}
 
 	val, err := util.Unmarshal(valx)
 	if err != nil {
 		return starlark.None, err
 	}
 
-	if self.ds.Structure == nil {
-		self.ds.Structure = &dataset.Structure{}
+	st := &dataset.Structure{}
+	if self.ds.Structure != nil {
+		st.Assign(self.ds.Structure)
 	}
 
 	data, err := json.Marshal(val)
@@ -277,11 +410,341 @@ func dsSetStructure(_ *starlark.Thread, b *starlark.Builtin, args starlark.Tuple
 		return starlark.None, err
 	}
 
-	err = json.Unmarshal(data, self.ds.Structure)
-	return starlark.None, err
+	if err = json.Unmarshal(data, st); err != nil {
+		return starlark.None, err
+	}
+	if err = validateStructure(st); err != nil {
+		return starlark.None, fmt.Errorf("set_structure: %w", err)
+	}
+
+	self.ds.Structure = st
+	self.markChanged("structure")
+	return starlark.None, nil
+}
+
+// columnSchemaTypes are the types a tabular schema column may declare
+var columnSchemaTypes = map[string]bool{
+	"string":  true,
+	"integer": true,
+	"number":  true,
+	"boolean": true,
+	"object":  true,
+	"array":   true,
+	"null":    true,
+}
+
+// validateStructure checks that a structure is well-formed enough to describe
+// a body, returning an error that points at the first problem found
+func validateStructure(st *dataset.Structure) error {
+	if st.Format != "" {
+		if _, err := dataset.ParseDataFormatString(st.Format); err != nil {
+			return fmt.Errorf("structure.format: %w", err)
+		}
+	}
+	if st.Schema == nil {
+		return nil
+	}
+
+	topLevelType, ok := st.Schema["type"].(string)
+	if !ok {
+		return fmt.Errorf(`structure.schema.type: top-level type is required and must be a string`)
+	}
+	if topLevelType != "array" && topLevelType != "object" {
+		return fmt.Errorf(`structure.schema.type: %q is not a valid top-level type, must be "array" or "object"`, topLevelType)
+	}
+
+	items, ok := st.Schema["items"]
+	if !ok || topLevelType != "array" {
+		return nil
+	}
+	itemsObj, ok := items.(map[string]interface{})
+	if !ok {
+		return fmt.Errorf("structure.schema.items: must be an object describing a row")
+	}
+	cols, ok := itemsObj["items"]
+	if !ok {
+		return nil
+	}
+	colList, ok := cols.([]interface{})
+	if !ok {
+		return fmt.Errorf("structure.schema.items.items: must be a list of column schemas")
+	}
+
+	for i, c := range colList {
+		col, ok := c.(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("structure.schema.items.items[%d]: column schema must be an object", i)
+		}
+		if title, ok := col["title"]; ok {
+			if _, ok := title.(string); !ok {
+				return fmt.Errorf("structure.schema.items.items[%d].title: must be a string", i)
+			}
+		}
+		if t, ok := col["type"]; ok {
+			if err := validateColumnSchemaType(t); err != nil {
+				return fmt.Errorf("structure.schema.items.items[%d].type: %w", i, err)
+			}
+		}
+	}
+	return nil
+}
+
+func validateColumnSchemaType(t interface{}) error {
+	switch x := t.(type) {
+	case string:
+		if !columnSchemaTypes[x] {
+			return fmt.Errorf("%q is not a valid type", x)
+		}
+		return nil
+	case []interface{}:
+		if len(x) == 0 {
+			return fmt.Errorf("list of types cannot be empty")
+		}
+		for _, v := range x {
+			str, ok := v.(string)
+			if !ok {
+				return fmt.Errorf("type list must only contain strings")
+			}
+			if !columnSchemaTypes[str] {
+				return fmt.Errorf("%q is not a valid type", str)
+			}
+		}
+		return nil
+	default:
+		return fmt.Errorf("must be a string or list of strings")
+	}
+}
+
+// dsRenameColumns renames body columns using a dict of old names to new
+// names, updating the structure's schema to match
+func dsRenameColumns(_ *starlark.Thread, b *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	self := b.Receiver().(*Dataset)
+
+	var renamesx *starlark.Dict
+	if err := starlark.UnpackPositionalArgs("rename_columns", args, kwargs, 1, &renamesx); err != nil {
+		return nil, err
+	}
+
+	self.lk.Lock()
+	defer self.lk.Unlock()
+	if self.frozen {
+		return starlark.None, fmt.Errorf("cannot call rename_columns on frozen dataset")
+	}
+
+	renames := map[string]string{}
+	for _, item := range renamesx.Items() {
+		from, ok := starlark.AsString(item[0])
+		if !ok {
+			return starlark.None, fmt.Errorf("rename_columns: column names must be strings, got %s", item[0].Type())
+		}
+		to, ok := starlark.AsString(item[1])
+		if !ok {
+			return starlark.None, fmt.Errorf("rename_columns: column names must be strings, got %s", item[1].Type())
+		}
+		renames[from] = to
+	}
+
+	df, names, err := self.bodyFrameColumns()
+	if err != nil {
+		return starlark.None, err
+	}
+
+	found := map[string]bool{}
+	renamed := make([]string, len(names))
+	for i, name := range names {
+		renamed[i] = name
+		if to, ok := renames[name]; ok {
+			renamed[i] = to
+			found[name] = true
+		}
+	}
+	for from := range renames {
+		if !found[from] {
+			return starlark.None, fmt.Errorf("rename_columns: column %q not found", from)
+		}
+	}
+
+	frame, err := dataframe.NewDataFrame(df, renamed, nil, self.outconf)
+	if err != nil {
+		return starlark.None, err
+	}
+
+	err = self.updateSchemaColumns(func(cols []interface{}) ([]interface{}, error) {
+		for i, col := range cols {
+			if to, ok := renames[col.(map[string]interface{})["title"].(string)]; ok {
+				cols[i].(map[string]interface{})["title"] = to
+			}
+		}
+		return cols, nil
+	})
+	if err != nil {
+		return starlark.None, err
+	}
+
+	self.bodyFrame = frame
+	self.markChanged("body")
+	self.markChanged("structure")
+	return starlark.None, nil
+}
+
+// dsReorderColumns reorders body columns to match a list of column names,
+// updating the structure's schema to match. Every column must be listed
+func dsReorderColumns(_ *starlark.Thread, b *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	self := b.Receiver().(*Dataset)
+
+	var orderx *starlark.List
+	if err := starlark.UnpackPositionalArgs("reorder_columns", args, kwargs, 1, &orderx); err != nil {
+		return nil, err
+	}
+
+	self.lk.Lock()
+	defer self.lk.Unlock()
+	if self.frozen {
+		return starlark.None, fmt.Errorf("cannot call reorder_columns on frozen dataset")
+	}
+
+	df, names, err := self.bodyFrameColumns()
+	if err != nil {
+		return starlark.None, err
+	}
+
+	positions := make(map[string]int, len(names))
+	for i, name := range names {
+		positions[name] = i
+	}
+
+	if orderx.Len() != len(names) {
+		return starlark.None, fmt.Errorf("reorder_columns: expected %d column names, got %d", len(names), orderx.Len())
+	}
+	order := make([]string, orderx.Len())
+	for i := 0; i < orderx.Len(); i++ {
+		name, ok := starlark.AsString(orderx.Index(i))
+		if !ok {
+			return starlark.None, fmt.Errorf("reorder_columns: column names must be strings, got %s", orderx.Index(i).Type())
+		}
+		if _, ok := positions[name]; !ok {
+			return starlark.None, fmt.Errorf("reorder_columns: column %q not found", name)
+		}
+		order[i] = name
+	}
+
+	rows := make([][]interface{}, df.NumRows())
+	for i := range rows {
+		row := df.Row(i)
+		rows[i] = make([]interface{}, len(order))
+		for j, name := range order {
+			rows[i][j] = row[positions[name]]
+		}
+	}
+
+	var data interface{}
+	if len(rows) > 0 {
+		data = rows
+	}
+	frame, err := dataframe.NewDataFrame(data, order, nil, self.outconf)
+	if err != nil {
+		return starlark.None, err
+	}
+
+	err = self.updateSchemaColumns(func(cols []interface{}) ([]interface{}, error) {
+		byTitle := make(map[string]interface{}, len(cols))
+		for _, col := range cols {
+			byTitle[col.(map[string]interface{})["title"].(string)] = col
+		}
+		reordered := make([]interface{}, len(order))
+		for i, name := range order {
+			col, ok := byTitle[name]
+			if !ok {
+				return nil, fmt.Errorf("reorder_columns: column %q not found in structure", name)
+			}
+			reordered[i] = col
+		}
+		return reordered, nil
+	})
+	if err != nil {
+		return starlark.None, err
+	}
+
+	self.bodyFrame = frame
+	self.markChanged("body")
+	self.markChanged("structure")
+	return starlark.None, nil
+}
+
+// bodyFrameColumns loads the body as a dataframe, returning it along with its
+// column names
+func (d *Dataset) bodyFrameColumns() (*dataframe.DataFrame, []string, error) {
+	body, err := d.loadBody()
+	if err != nil {
+		return nil, nil, err
+	}
+	df, ok := body.(*dataframe.DataFrame)
+	if !ok {
+		return nil, nil, fmt.Errorf("bodyFrame has invalid type %T", body)
+	}
+	names, _ := df.ColumnNamesTypes()
+	if names == nil {
+		return nil, nil, fmt.Errorf("dataset body does not have named columns")
+	}
+	return df, names, nil
+}
+
+// updateSchemaColumns replaces the column definitions of a tabular schema with
+// the result of calling update on a copy of the existing columns. Structures
+// without column definitions are left alone, their schema is derived from the
+// body's columns when components are assigned from the dataframe
+func (d *Dataset) updateSchemaColumns(update func(cols []interface{}) ([]interface{}, error)) error {
+	var cols []interface{}
+	if d.ds.Structure != nil && d.createColumnsFromStructure() != nil {
+		items := d.ds.Structure.Schema["items"].(map[string]interface{})
+		for _, col := range items["items"].([]interface{}) {
+			colCopy := map[string]interface{}{}
+			for k, v := range col.(map[string]interface{}) {
+				colCopy[k] = v
+			}
+			cols = append(cols, colCopy)
+		}
+	}
+
+	if cols == nil {
+		return nil
+	}
+
+	cols, err := update(cols)
+	if err != nil {
+		return err
+	}
+
+	schema := map[string]interface{}{}
+	for k, v := range d.ds.Structure.Schema {
+		schema[k] = v
+	}
+	items := map[string]interface{}{}
+	for k, v := range schema["items"].(map[string]interface{}) {
+		items[k] = v
+	}
+	items["items"] = cols
+	schema["items"] = items
+	d.ds.Structure.Schema = schema
+	return nil
 }
 
 func (d *Dataset) getBody() (starlark.Value, error) {
+	d.lk.RLock()
+	df := d.bodyFrame
+	d.lk.RUnlock()
+	if df != nil {
+		return df, nil
+	}
+
+	d.lk.Lock()
+	defer d.lk.Unlock()
+	return d.loadBody()
+}
+
+// loadBody returns the body dataframe, reading it from the body file if it
+// hasn't been loaded. callers must hold the write lock
+func (d *Dataset) loadBody() (starlark.Value, error) {
 	if d.bodyFrame != nil {
 		return d.bodyFrame, nil
 	}
@@ -301,22 +764,56 @@ func (d *Dataset) getBody() (starlark.Value, error) {
 	// Create columns from the structure, if one exists
 	columns := d.createColumnsFromStructure()
 
+	// a directory of parts reads as a single body, streaming one part after
+	// another
+	var partitioned *dsfs.PartitionedBodyFile
+	if bodyfile.IsDirectory() {
+		partitioned = dsfs.NewPartitionedBodyFile(d.ds.Structure, bodyfile)
+		bodyfile = partitioned
+	}
+
 	// TODO(dustmop): DataFrame should be able to work with an
 	// efficient, streaming body file.
-	data, err := ioutil.ReadAll(d.ds.BodyFile())
+	data, err := ioutil.ReadAll(bodyfile)
 	if err != nil {
 		return starlark.None, err
 	}
 	d.ds.SetBodyFile(qfs.NewMemfileBytes("body.json", data))
 
+	if partitioned != nil {
+		d.partitions = partitioned.Partitions()
+		dsfs.SetBodyPartitions(d.ds.Structure, d.partitions)
+	} else if d.partitions, err = dsfs.BodyPartitions(d.ds.Structure); err != nil {
+		return starlark.None, err
+	}
+
+	rows, err := d.readBodyRows(data)
+	if err != nil {
+		return starlark.None, err
+	}
+
+	df, err := dataframe.NewDataFrame(rows, columns, nil, d.outconf)
+	if err != nil {
+		return nil, err
+	}
+	d.bodyFrame = df
+	return df, nil
+}
+
+// readBodyRows decodes body data in the structure's format into rows
+func (d *Dataset) readBodyRows(data []byte) ([][]interface{}, error) {
+	if err := checkBodyFormat(d.ds.Structure.Format, data); err != nil {
+		return nil, err
+	}
+
 	rr, err := dsio.NewEntryReader(d.ds.Structure, qfs.NewMemfileBytes("body.json", data))
 	if err != nil {
-		return starlark.None, fmt.Errorf("error allocating data reader: %s", err)
+		return nil, fmt.Errorf("error allocating data reader: %s", err)
 	}
 
 	entries, err := base.ReadEntries(rr)
 	if err != nil {
-		return starlark.None, err
+		return nil, err
 	}
 	rows := [][]interface{}{}
 	eachEntry := entries.([]interface{})
@@ -324,13 +821,58 @@ func (d *Dataset) getBody() (starlark.Value, error) {
 		r := ent.([]interface{})
 		rows = append(rows, r)
 	}
+	return rows, nil
+}
 
-	df, err := dataframe.NewDataFrame(rows, columns, nil, d.outconf)
+// ErrBodyFormatMismatch indicates body data is not encoded in the format
+// the dataset structure declares
+var ErrBodyFormatMismatch = errors.New("body format mismatch")
+
+// checkBodyFormat compares the apparent encoding of body data with the format
+// declared by a structure, returning a descriptive error if they disagree.
+// Unrecognized formats aren't checked
+func checkBodyFormat(declared string, data []byte) error {
+	df, err := dataset.ParseDataFormatString(declared)
 	if err != nil {
-		return nil, err
+		return nil
 	}
-	d.bodyFrame = df
-	return df, nil
+	apparent := sniffBodyFormat(data)
+
+	mismatch := false
+	switch df {
+	case dataset.JSONDataFormat, dataset.NDJSONDataFormat:
+		mismatch = apparent != "json" && apparent != ""
+	case dataset.CSVDataFormat:
+		mismatch = apparent == "json" || apparent == "xlsx" || apparent == "binary"
+	case dataset.XLSXDataFormat:
+		mismatch = apparent != "xlsx" && apparent != ""
+	case dataset.CBORDataFormat:
+		mismatch = apparent == "xlsx"
+	}
+	if mismatch {
+		return fmt.Errorf("%w: structure format is %q, but body data looks like %s", ErrBodyFormatMismatch, declared, apparent)
+	}
+	return nil
+}
+
+// sniffBodyFormat guesses the encoding of body data from its leading bytes,
+// returning one of "json", "xlsx", "text", "binary", or the empty string for
+// empty data
+func sniffBodyFormat(data []byte) string {
+	if bytes.HasPrefix(data, []byte("PK\x03\x04")) {
+		return "xlsx"
+	}
+	trimmed := bytes.TrimLeft(bytes.TrimPrefix(data, []byte("\xef\xbb\xbf")), " \t\r\n")
+	if len(trimmed) == 0 {
+		return ""
+	}
+	if trimmed[0] == '[' || trimmed[0] == '{' {
+		return "json"
+	}
+	if utf8.Valid(trimmed) {
+		return "text"
+	}
+	return "binary"
 }
 
 func (d *Dataset) setBody(val starlark.Value) error {
@@ -339,7 +881,7 @@ func (d *Dataset) setBody(val starlark.Value) error {
 		return err
 	}
 	d.bodyFrame = df
-	d.changes["body"] = struct{}{}
+	d.markChanged("body")
 	return nil
 }
 
@@ -367,10 +909,22 @@ func (d *Dataset) writeStructure(data starlark.Value) *dataset.Structure {
 // AssignComponentsFromDataframe looks for changes to the Dataframe body
 // and columns, and assigns them to the Dataset's body and structure
 func (d *Dataset) AssignComponentsFromDataframe(ctx context.Context, changeSet map[string]struct{}, fs qfs.Filesystem, loader dsref.Loader) error {
+	d.lk.Lock()
+	defer d.lk.Unlock()
 	if d.ds == nil {
 		return nil
 	}
 
+	// coerce a changed body to the column types declared by the structure.
+	// the structure is replaced with one derived from the dataframe below, so
+	// coercion only applies to bodies that keep the declared columns
+	_, hasBodyChange := changeSet["body"]
+	if hasBodyChange {
+		if err := d.coerceBodyToDeclaredTypes(); err != nil {
+			return err
+		}
+	}
+
 	// assign the structure first. This is necessary because the
 	// body writer will use this structure to serialize the new body
 	if err := d.assignStructureFromDataframeColumns(); err != nil {
@@ -384,13 +938,142 @@ func (d *Dataset) AssignComponentsFromDataframe(ctx context.Context, changeSet m
 
 	// assign details to structure and commit based upon how and
 	// whether the body has changed
-	_, hasBodyChange := changeSet["body"]
-	if err := d.assignStructureAndCommitDetails(ctx, fs, loader, hasBodyChange); err != nil {
+	if err := d.assignStructureAndCommitDetails(ctx, fs, loader, changeSet); err != nil {
 		return err
 	}
 	return nil
 }
 
+// coerceBodyToDeclaredTypes converts the cells of the dataframe body to the
+// column types declared in the structure's schema, returning an error for any
+// cell that cannot be represented as its column's type. Coercion only happens
+// when the dataframe has the same columns as the schema, in the same order
+// (or the same number of columns if the dataframe has no column names). A
+// body with reshaped columns gets a new structure derived from the dataframe,
+// so the previously declared types no longer apply
+func (d *Dataset) coerceBodyToDeclaredTypes() error {
+	if d.bodyFrame == nil || d.ds.Structure == nil {
+		return nil
+	}
+	df, ok := d.bodyFrame.(*dataframe.DataFrame)
+	if !ok {
+		return fmt.Errorf("bodyFrame has invalid type %T", d.bodyFrame)
+	}
+
+	titles := d.createColumnsFromStructure()
+	if titles == nil || df.NumRows() == 0 {
+		return nil
+	}
+	names, _ := df.ColumnNamesTypes()
+	if len(titles) != df.NumCols() {
+		return nil
+	}
+	for i, name := range names {
+		if name != titles[i] {
+			return nil
+		}
+	}
+
+	declared := d.columnTypesFromStructure()
+	colTypes := make([]string, len(titles))
+	for i, title := range titles {
+		colTypes[i] = declared[title]
+	}
+
+	rows := make([][]interface{}, df.NumRows())
+	for i := range rows {
+		row := df.Row(i)
+		for j, val := range row {
+			if colTypes[j] == "" {
+				continue
+			}
+			coerced, err := coerceToQriType(val, colTypes[j])
+			if err != nil {
+				return fmt.Errorf("body row %d, column %q: %w", i, titles[j], err)
+			}
+			row[j] = coerced
+		}
+		rows[i] = row
+	}
+
+	frame, err := dataframe.NewDataFrame(rows, names, nil, d.outconf)
+	if err != nil {
+		return err
+	}
+	d.bodyFrame = frame
+	return nil
+}
+
+// columnTypesFromStructure returns a map of column titles to the qri type
+// declared for each column. Columns that declare multiple types are omitted
+func (d *Dataset) columnTypesFromStructure() map[string]string {
+	types := map[string]string{}
+	items, ok := d.ds.Structure.Schema["items"].(map[string]interface{})
+	if !ok {
+		return types
+	}
+	cols, ok := items["items"].([]interface{})
+	if !ok {
+		return types
+	}
+	for _, col := range cols {
+		colMap, ok := col.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		title, _ := colMap["title"].(string)
+		if colType, ok := colMap["type"].(string); ok && title != "" {
+			types[title] = colType
+		}
+	}
+	return types
+}
+
+// coerceToQriType converts a go native value to the given qri type, erroring
+// if the value can't be represented as that type. Null values are allowed in
+// any column
+func coerceToQriType(val interface{}, qriType string) (interface{}, error) {
+	if val == nil {
+		return nil, nil
+	}
+	switch qriType {
+	case "integer":
+		switch v := val.(type) {
+		case int, int64:
+			return v, nil
+		case float64:
+			// whole numbers outside the int64 range can't be converted without
+			// wrapping, leave them to error
+			if v == math.Trunc(v) && v >= -(1<<63) && v < 1<<63 {
+				return int(v), nil
+			}
+		}
+	case "number":
+		switch v := val.(type) {
+		case int:
+			return float64(v), nil
+		case int64:
+			return float64(v), nil
+		case float64:
+			return v, nil
+		}
+	case "boolean":
+		if v, ok := val.(bool); ok {
+			return v, nil
+		}
+	case "string":
+		switch v := val.(type) {
+		case string:
+			return v, nil
+		case int, int64, float64, bool:
+			return fmt.Sprint(v), nil
+		}
+	default:
+		return val, nil
+	}
+	return nil, fmt.Errorf("cannot write %T value %v as type %q", val, val, qriType)
+}
+
 // AssignBodyFromDataframe converts the DataFrame on the object into
 // a proper dataset.bodyfile
 func (d *Dataset) assignBodyFromDataframe() error {
@@ -410,6 +1093,10 @@ func (d *Dataset) assignBodyFromDataframe() error {
 		}
 	}
 
+	if len(d.partitions) > 0 {
+		return d.assignPartitionedBody(df, st)
+	}
+
 	w, err := dsio.NewEntryBuffer(st)
 	if err != nil {
 		return err
@@ -438,12 +1125,90 @@ func (d *Dataset) assignBodyFromDataframe() error {
 	return nil
 }
 
+// assignPartitionedBody writes the dataframe back as a single body, keeping
+// the partition layout it was read with. Rows are split into contiguous runs
+// in partition order, each part keeping the number of rows it was read with.
+// The last part takes any added rows, and parts left without rows after
+// removals are dropped
+func (d *Dataset) assignPartitionedBody(df *dataframe.DataFrame, st *dataset.Structure) error {
+	var (
+		parts []dsfs.BodyPartition
+		start int
+		total = df.NumRows()
+	)
+	for i, p := range d.partitions {
+		if start >= total && len(parts) > 0 {
+			break
+		}
+		end := start + p.Entries
+		if i == len(d.partitions)-1 || end > total {
+			end = total
+		}
+		parts = append(parts, dsfs.BodyPartition{Name: p.Name, Entries: end - start})
+		start = end
+	}
+
+	w, err := dsio.NewEntryBuffer(st)
+	if err != nil {
+		return err
+	}
+	for i := 0; i < total; i++ {
+		w.WriteEntry(dsio.Entry{Index: i, Value: df.Row(i)})
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+
+	if d.ds.Structure == nil {
+		d.ds.Structure = st
+	}
+	dsfs.SetBodyPartitions(d.ds.Structure, parts)
+	d.partitions = parts
+	d.ds.SetBodyFile(qfs.NewMemfileBytes(fmt.Sprintf("body.%s", st.Format), w.Bytes()))
+	d.ds.Structure.Entries = total
+	d.ds.Structure.Length = len(w.Bytes())
+	return nil
+}
+
+// inlineBodies reads the body of this version into memory so it can be diffed
+// against the previous version for the commit message, returning a func that
+// drops the inlined body. dsfs inlines the previous body. Bodies that can't be
+// read only produce a less detailed message
+func (d *Dataset) inlineBodies(prev *dataset.Dataset) (drop func()) {
+	drop = func() {}
+	bf := d.ds.BodyFile()
+	if bf == nil || bf.IsDirectory() || d.ds.Body != nil || prev == nil || prev.Structure == nil {
+		return drop
+	}
+
+	data, err := ioutil.ReadAll(bf)
+	if err != nil {
+		log.Debugw("inlining body", "err", err)
+		return drop
+	}
+	// reading consumes the body file, replace it
+	d.ds.SetBodyFile(qfs.NewMemfileBytes(bf.FileName(), data))
+	r, err := dsio.NewEntryReader(d.ds.Structure, qfs.NewMemfileBytes(bf.FileName(), data))
+	if err != nil {
+		log.Debugw("inlining body", "err", err)
+		return drop
+	}
+	body, err := dsio.ReadAll(r)
+	if err != nil {
+		log.Debugw("inlining body", "err", err)
+		return drop
+	}
+
+	d.ds.Body = body
+	return func() { d.ds.Body = nil }
+}
+
 // load the previous dataset version to get the number of entries
 // and assign them to this version's structure
-func (d *Dataset) assignStructureAndCommitDetails(ctx context.Context, fs qfs.Filesystem, loader dsref.Loader, hasBodyChange bool) error {
+func (d *Dataset) assignStructureAndCommitDetails(ctx context.Context, fs qfs.Filesystem, loader dsref.Loader, changeSet map[string]struct{}) error {
 	// get the previous dataset version, if one exists
 	var prev *dataset.Dataset
-	ref := dsref.ConvertDatasetToVersionInfo(d.Dataset()).SimpleRef()
+	ref := dsref.ConvertDatasetToVersionInfo(d.ds).SimpleRef()
 	if !ref.IsEmpty() {
 		var err error
 		prev, err = loader.LoadDataset(ctx, ref.Alias())
@@ -456,18 +1221,36 @@ func (d *Dataset) assignStructureAndCommitDetails(ctx context.Context, fs qfs.Fi
 		}
 	}
 
+	_, hasBodyChange := changeSet["body"]
+	if !hasBodyChange && d.verifyBody {
+		changed, err := d.bodyChangedFromChecksum(ctx, fs, prev)
+		if err != nil {
+			return err
+		}
+		if changed {
+			log.Debugw("body checksum differs from previous version, body has changed")
+			hasBodyChange = true
+			if changeSet != nil {
+				changeSet["body"] = struct{}{}
+			}
+		}
+	}
+
 	// calculate the commit title and message
 	bodyAct := dsfs.BodyDefault
 	if !hasBodyChange {
 		bodyAct = dsfs.BodySame
-	} else if d.ds.Structure.Length > dsfs.BodySizeSmallEnoughToDiff {
+	} else if d.ds.Structure.Length > d.diffThreshold() {
 		bodyAct = dsfs.BodyTooBig
+	} else {
+		drop := d.inlineBodies(prev)
+		defer drop()
 	}
 	fileHint := d.ds.Transform.ScriptPath
 	if strings.HasPrefix(fileHint, "/ipfs/") {
 		fileHint = ""
 	}
-	err := dsfs.EnsureCommitTitleAndMessage(ctx, fs, d.ds, prev, bodyAct, fileHint, false)
+	err := dsfs.DescribeCommit(ctx, fs, d.ds, prev, bodyAct, fileHint, false, d.diffThreshold(), d.commitMessage)
 	if err != nil && !errors.Is(err, dsfs.ErrNoChanges) {
 		return err
 	}
@@ -502,6 +1285,53 @@ func (d *Dataset) assignStructureAndCommitDetails(ctx context.Context, fs qfs.Fi
 	return nil
 }
 
+// bodyChangedFromChecksum reports whether the checksum of the dataset body
+// differs from the checksum recorded in the previous version's structure.
+// Bodies that can't be compared are reported as unchanged
+func (d *Dataset) bodyChangedFromChecksum(ctx context.Context, fs qfs.Filesystem, prev *dataset.Dataset) (bool, error) {
+	bf := d.ds.BodyFile()
+	if bf == nil || bf.IsDirectory() || prev == nil || prev.Structure == nil || prev.Structure.Checksum == "" {
+		return false, nil
+	}
+	store := checksumStore(fs, prev.Structure.Checksum)
+	if store == nil {
+		log.Debugw("no store available to verify body checksum", "checksum", prev.Structure.Checksum)
+		return false, nil
+	}
+
+	body, err := ioutil.ReadAll(bf)
+	if err != nil {
+		return false, err
+	}
+	// reading consumes the body file, replace it
+	d.ds.SetBodyFile(qfs.NewMemfileBytes(bf.FileName(), body))
+
+	checksum, err := dsfs.BodyChecksum(ctx, store, body)
+	if err != nil {
+		return false, err
+	}
+	return checksum != prev.Structure.Checksum, nil
+}
+
+// checksumStore returns the filesystem that generated a checksum, if it's
+// available and able to compute checksums
+func checksumStore(fs qfs.Filesystem, checksum string) qfs.MerkleDagStore {
+	if fs == nil {
+		return nil
+	}
+	fsType := strings.SplitN(strings.TrimPrefix(checksum, "/"), "/", 2)[0]
+	if mux, ok := fs.(interface {
+		Filesystem(fsType string) qfs.Filesystem
+	}); ok {
+		fs = mux.Filesystem(fsType)
+	}
+	if fs == nil || fs.Type() != fsType {
+		return nil
+	}
+	store, _ := fs.(qfs.MerkleDagStore)
+	return store
+}
+
 func (d *Dataset) assignStructureFromDataframeColumns() error {
 	if d.bodyFrame == nil {
 		return nil
@@ -518,9 +1348,13 @@ func (d *Dataset) assignStructureFromDataframeColumns() error {
 
 	cols := make([]interface{}, len(names))
 	for i := range names {
+		qriType := dataframeTypeToQriType(types[i])
+		if types[i] == "object" {
+			qriType = objectColumnQriType(df, i)
+		}
 		cols[i] = map[string]string{
 			"title": names[i],
-			"type":  dataframeTypeToQriType(types[i]),
+			"type":  qriType,
 		}
 	}
 
@@ -593,6 +1427,34 @@ func (d *Dataset) createColumnsFromStructure() []string {
 	return result
 }
 
+// objectColumnQriType inspects the cells of a dataframe column with the
+// "object" dtype. Columns where every cell is a dict are objects, columns where
+// every cell is a list are arrays, and all other columns are strings
+func objectColumnQriType(df *dataframe.DataFrame, col int) string {
+	qriType := ""
+	for i := 0; i < df.NumRows(); i++ {
+		cellType := ""
+		switch df.Row(i)[col].(type) {
+		case nil:
+			continue
+		case map[string]interface{}, *starlark.Dict:
+			cellType = "object"
+		case []interface{}, *starlark.List, starlark.Tuple:
+			cellType = "array"
+		default:
+			return "string"
+		}
+		if qriType != "" && qriType != cellType {
+			return "string"
+		}
+		qriType = cellType
+	}
+	if qriType == "" {
+		return "string"
+	}
+	return qriType
+}
+
 // TODO(dustmop): Probably move this to some more common location
 func dataframeTypeToQriType(dfType string) string {
 	if dfType == "int64" {
@@ -1,12 +1,18 @@
 package ds
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"io/ioutil"
+	"sync"
 	"testing"
 
 	"github.com/google/go-cmp/cmp"
 	"github.com/qri-io/dataset"
+	"github.com/qri-io/dataset/tabular"
 	"github.com/qri-io/qfs"
+	"github.com/qri-io/qri/base/dsfs"
 	"github.com/qri-io/starlib/dataframe"
 	"github.com/qri-io/starlib/testdata"
 	"go.starlark.net/resolve"
@@ -166,3 +172,552 @@ func TestCreateColumnsFromStructure(t *testing.T) {
 		}
 	}
 }
+
+func TestRenameAndReorderColumns(t *testing.T) {
+	thread := &starlark.Thread{}
+	ds := csvDataset()
+
+	renames := starlark.NewDict(1)
+	renames.SetKey(starlark.String("count"), starlark.String("total"))
+	if _, err := callMethod(thread, ds, "rename_columns", starlark.Tuple{renames}); err != nil {
+		t.Fatal(err)
+	}
+
+	order := starlark.NewList([]starlark.Value{starlark.String("total"), starlark.String("title"), starlark.String("is great")})
+	if _, err := callMethod(thread, ds, "reorder_columns", starlark.Tuple{order}); err != nil {
+		t.Fatal(err)
+	}
+
+	expectChanges := map[string]struct{}{"body": {}, "structure": {}}
+	if diff := cmp.Diff(expectChanges, ds.Changes()); diff != "" {
+		t.Errorf("changes mismatch (-want +got):\n%s", diff)
+	}
+
+	body, err := ds.Attr("body")
+	if err != nil {
+		t.Fatal(err)
+	}
+	names, _ := body.(*dataframe.DataFrame).ColumnNamesTypes()
+	expectNames := []string{"total", "title", "is great"}
+	if diff := cmp.Diff(expectNames, names); diff != "" {
+		t.Errorf("body columns mismatch (-want +got):\n%s", diff)
+	}
+	expectRow := []interface{}{2, "bar", "false"}
+	if diff := cmp.Diff(expectRow, body.(*dataframe.DataFrame).Row(1)); diff != "" {
+		t.Errorf("body row mismatch (-want +got):\n%s", diff)
+	}
+
+	if diff := cmp.Diff(expectNames, ds.createColumnsFromStructure()); diff != "" {
+		t.Errorf("structure columns mismatch (-want +got):\n%s", diff)
+	}
+
+	// renaming a column that doesn't exist is an error
+	renames = starlark.NewDict(1)
+	renames.SetKey(starlark.String("missing"), starlark.String("found"))
+	if _, err := callMethod(thread, ds, "rename_columns", starlark.Tuple{renames}); err == nil {
+		t.Error("expected renaming a missing column to error")
+	}
+}
+
+func TestCoerceBodyToDeclaredTypes(t *testing.T) {
+	ctx := context.Background()
+	newDataset := func() *Dataset {
+		return NewDataset(&dataset.Dataset{
+			Structure: &dataset.Structure{
+				Format: "json",
+				Schema: map[string]interface{}{
+					"type": "array",
+					"items": map[string]interface{}{
+						"type": "array",
+						"items": []interface{}{
+							map[string]interface{}{"title": "name", "type": "string"},
+							map[string]interface{}{"title": "count", "type": "integer"},
+						},
+					},
+				},
+			},
+		}, &dataframe.OutputConfig{})
+	}
+	row := func(vals ...starlark.Value) starlark.Value {
+		return starlark.NewList(vals)
+	}
+	changes := map[string]struct{}{"body": {}}
+
+	// a whole number float is coerced to the declared integer type
+	ds := newDataset()
+	body := starlark.NewList([]starlark.Value{
+		row(starlark.String("a"), starlark.MakeInt(1)),
+		row(starlark.String("b"), starlark.Float(2)),
+	})
+	if err := ds.SetField("body", body); err != nil {
+		t.Fatal(err)
+	}
+	if err := ds.coerceBodyToDeclaredTypes(); err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff([]interface{}{"b", 2}, ds.bodyFrame.(*dataframe.DataFrame).Row(1)); diff != "" {
+		t.Errorf("coerced row mismatch (-want +got):\n%s", diff)
+	}
+
+	// a string cannot be written to a declared integer column
+	ds = newDataset()
+	body = starlark.NewList([]starlark.Value{
+		row(starlark.String("a"), starlark.MakeInt(1)),
+		row(starlark.String("b"), starlark.String("two")),
+	})
+	if err := ds.SetField("body", body); err != nil {
+		t.Fatal(err)
+	}
+	err := ds.AssignComponentsFromDataframe(ctx, changes, nil, nil)
+	if err == nil {
+		t.Fatal("expected error writing a string to an integer column, got nil")
+	}
+	expect := `body row 1, column "count": cannot write string value two as type "integer"`
+	if diff := cmp.Diff(expect, err.Error()); diff != "" {
+		t.Errorf("error mismatch (-want +got):\n%s", diff)
+	}
+
+	// a whole number float beyond the int64 range isn't wrapped into an integer
+	ds = newDataset()
+	body = starlark.NewList([]starlark.Value{
+		row(starlark.String("a"), starlark.MakeInt(1)),
+		row(starlark.String("b"), starlark.Float(1e19)),
+	})
+	if err := ds.SetField("body", body); err != nil {
+		t.Fatal(err)
+	}
+	err = ds.AssignComponentsFromDataframe(ctx, changes, nil, nil)
+	if err == nil {
+		t.Fatal("expected error writing an out of range float to an integer column, got nil")
+	}
+	expect = `body row 1, column "count": cannot write float64 value 1e+19 as type "integer"`
+	if diff := cmp.Diff(expect, err.Error()); diff != "" {
+		t.Errorf("error mismatch (-want +got):\n%s", diff)
+	}
+
+	// reshaped bodies aren't held to the previously declared column types
+	ds = newDataset()
+	body = starlark.NewList([]starlark.Value{
+		row(starlark.String("a"), starlark.String("one"), starlark.MakeInt(1)),
+		row(starlark.String("b"), starlark.String("two"), starlark.MakeInt(2)),
+	})
+	if err := ds.SetField("body", body); err != nil {
+		t.Fatal(err)
+	}
+	if err := ds.coerceBodyToDeclaredTypes(); err != nil {
+		t.Errorf("expected reshaped body not to be coerced, got: %s", err)
+	}
+}
+
+func TestAssignStructureNestedColumnTypes(t *testing.T) {
+	rows := [][]interface{}{
+		{"a", "", "", "x"},
+		{"b", "", "", "y"},
+	}
+	df, err := dataframe.NewDataFrame(rows, []string{"name", "props", "tags", "mixed"}, nil, &dataframe.OutputConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	cells := map[[2]int]interface{}{
+		{0, 1}: map[string]interface{}{"color": "red"},
+		{1, 1}: map[string]interface{}{"color": "blue"},
+		{0, 2}: []interface{}{"one"},
+		{1, 2}: []interface{}{"two", "three"},
+		{1, 3}: map[string]interface{}{"not": "uniform"},
+	}
+	for pos, val := range cells {
+		if err := df.SetAt2d(pos[0], pos[1], val); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	ds := NewDataset(&dataset.Dataset{}, nil)
+	ds.bodyFrame = df
+	if err := ds.assignStructureFromDataframeColumns(); err != nil {
+		t.Fatal(err)
+	}
+
+	expect := []interface{}{
+		map[string]interface{}{"title": "name", "type": "string"},
+		map[string]interface{}{"title": "props", "type": "object"},
+		map[string]interface{}{"title": "tags", "type": "array"},
+		map[string]interface{}{"title": "mixed", "type": "string"},
+	}
+	items := ds.ds.Structure.Schema["items"].(map[string]interface{})
+	if diff := cmp.Diff(expect, items["items"]); diff != "" {
+		t.Errorf("schema columns mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestChangedSince(t *testing.T) {
+	thread := &starlark.Thread{}
+	ds := NewDataset(&dataset.Dataset{}, &dataframe.OutputConfig{})
+	start := ds.SnapshotChanges()
+
+	// first phase sets meta
+	if _, err := callMethod(thread, ds, "set_meta", starlark.Tuple{starlark.String("title"), starlark.String("first")}); err != nil {
+		t.Fatal(err)
+	}
+	afterMeta := ds.SnapshotChanges()
+
+	// second phase sets body
+	body := starlark.NewList([]starlark.Value{starlark.NewList([]starlark.Value{starlark.String("a")})})
+	if err := ds.SetField("body", body); err != nil {
+		t.Fatal(err)
+	}
+
+	if diff := cmp.Diff(map[string]struct{}{"meta": {}, "body": {}}, ds.ChangedSince(start)); diff != "" {
+		t.Errorf("changes since start mismatch (-want +got):\n%s", diff)
+	}
+	if diff := cmp.Diff(map[string]struct{}{"body": {}}, ds.ChangedSince(afterMeta)); diff != "" {
+		t.Errorf("changes since meta mismatch (-want +got):\n%s", diff)
+	}
+
+	ds.ResetChanges()
+	if diff := cmp.Diff(map[string]struct{}{}, ds.Changes()); diff != "" {
+		t.Errorf("changes after reset mismatch (-want +got):\n%s", diff)
+	}
+
+	// snapshots remain valid after a reset, and include components changed
+	// more than once
+	if _, err := callMethod(thread, ds, "set_meta", starlark.Tuple{starlark.String("title"), starlark.String("second")}); err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(map[string]struct{}{"meta": {}}, ds.Changes()); diff != "" {
+		t.Errorf("changes after reset mismatch (-want +got):\n%s", diff)
+	}
+
+	// changes are returned as a copy
+	delete(ds.Changes(), "meta")
+	if _, ok := ds.Changes()["meta"]; !ok {
+		t.Errorf("expected modifying returned changes not to affect the dataset")
+	}
+	if diff := cmp.Diff(map[string]struct{}{"meta": {}, "body": {}}, ds.ChangedSince(afterMeta)); diff != "" {
+		t.Errorf("changes since meta mismatch (-want +got):\n%s", diff)
+	}
+}
+
+type prevLoader struct {
+	prev *dataset.Dataset
+}
+
+func (l prevLoader) LoadDataset(ctx context.Context, refstr string) (*dataset.Dataset, error) {
+	return l.prev, nil
+}
+
+func TestVerifyBodyChecksum(t *testing.T) {
+	ctx := context.Background()
+	fs := qfs.NewMemFS()
+
+	newDataset := func(rows ...starlark.Value) *Dataset {
+		ds := NewDataset(&dataset.Dataset{
+			Peername: "peer",
+			Name:     "dataset",
+			Structure: &dataset.Structure{
+				Format: "json",
+				Schema: dataset.BaseSchemaArray,
+			},
+			Transform: &dataset.Transform{},
+		}, &dataframe.OutputConfig{})
+		if err := ds.SetField("body", starlark.NewList(rows)); err != nil {
+			t.Fatal(err)
+		}
+		return ds
+	}
+	row := func(name string, count int) starlark.Value {
+		return starlark.NewList([]starlark.Value{starlark.String(name), starlark.MakeInt(count)})
+	}
+
+	// write the previous version body to the store to get a checksum
+	prevDs := newDataset(row("a", 1), row("b", 2))
+	prevDs.ds.Peername, prevDs.ds.Name = "", ""
+	if err := prevDs.AssignComponentsFromDataframe(ctx, map[string]struct{}{"body": {}}, fs, nil); err != nil {
+		t.Fatal(err)
+	}
+	prevBody, err := ioutil.ReadAll(prevDs.ds.BodyFile())
+	if err != nil {
+		t.Fatal(err)
+	}
+	checksum, err := dsfs.BodyChecksum(ctx, fs, prevBody)
+	if err != nil {
+		t.Fatal(err)
+	}
+	prev := &dataset.Dataset{
+		Peername:  "peer",
+		Name:      "dataset",
+		Structure: &dataset.Structure{Format: "json", Checksum: checksum, Entries: 2},
+	}
+	loader := prevLoader{prev: prev}
+
+	// identical body stays unchanged
+	ds := newDataset(row("a", 1), row("b", 2))
+	ds.SetVerifyBodyChecksum(true)
+	changes := map[string]struct{}{}
+	if err := ds.AssignComponentsFromDataframe(ctx, changes, fs, loader); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := changes["body"]; ok {
+		t.Error("expected identical body to remain unchanged")
+	}
+
+	// the change set says the body is unchanged, but content differs
+	ds = newDataset(row("a", 1), row("b", 3))
+	ds.SetVerifyBodyChecksum(true)
+	changes = map[string]struct{}{}
+	if err := ds.AssignComponentsFromDataframe(ctx, changes, fs, loader); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := changes["body"]; !ok {
+		t.Error("expected differing body checksum to mark body as changed")
+	}
+	if _, err := ioutil.ReadAll(ds.ds.BodyFile()); err != nil {
+		t.Errorf("expected body file to remain readable after verification: %s", err)
+	}
+
+	// without verification the change set is trusted
+	ds = newDataset(row("a", 1), row("b", 3))
+	changes = map[string]struct{}{}
+	if err := ds.AssignComponentsFromDataframe(ctx, changes, fs, loader); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := changes["body"]; ok {
+		t.Error("expected unverified change set to be trusted")
+	}
+}
+
+func TestBodyDiffThreshold(t *testing.T) {
+	ctx := context.Background()
+	fs := qfs.NewMemFS()
+
+	// stand in a small default so the test body is "too big" to diff
+	prevBodySizeLimit := dsfs.BodySizeSmallEnoughToDiff
+	defer func() { dsfs.BodySizeSmallEnoughToDiff = prevBodySizeLimit }()
+	dsfs.BodySizeSmallEnoughToDiff = 10
+
+	row := func(name string, count int) starlark.Value {
+		return starlark.NewList([]starlark.Value{starlark.String(name), starlark.MakeInt(count)})
+	}
+	commit := func(threshold int) *dataset.Commit {
+		ds := NewDataset(&dataset.Dataset{
+			Peername: "peer",
+			Name:     "dataset",
+			Structure: &dataset.Structure{
+				Format: "json",
+				Schema: dataset.BaseSchemaArray,
+			},
+			Transform: &dataset.Transform{},
+		}, &dataframe.OutputConfig{})
+		if err := ds.SetField("body", starlark.NewList([]starlark.Value{row("a", 1), row("b", 3), row("c", 4)})); err != nil {
+			t.Fatal(err)
+		}
+		ds.SetBodyDiffThreshold(threshold)
+		loader := prevLoader{prev: &dataset.Dataset{
+			Peername:  "peer",
+			Name:      "dataset",
+			Structure: &dataset.Structure{Format: "json", Schema: dataset.BaseSchemaArray, Entries: 2, Checksum: "/mem/QmPrevChecksum"},
+			Transform: &dataset.Transform{},
+			Body:      []interface{}{[]interface{}{"a", 1}, []interface{}{"b", 2}},
+		}}
+		if err := ds.AssignComponentsFromDataframe(ctx, map[string]struct{}{"body": {}}, fs, loader); err != nil {
+			t.Fatal(err)
+		}
+		return ds.ds.Commit
+	}
+
+	cm := commit(0)
+	if cm.Message != "body changed" {
+		t.Errorf("body above the default threshold: expected summary message, got: %q", cm.Message)
+	}
+
+	cm = commit(1000)
+	if expect := "body:\n\tupdated row 1.1\n\tadded row 2"; cm.Message != expect {
+		t.Errorf("body below a raised threshold: expected detailed message %q, got: %q", expect, cm.Message)
+	}
+}
+
+func TestFrozenDatasetConcurrentReads(t *testing.T) {
+	ds := csvDataset()
+	ds.ds.Meta = &dataset.Meta{Title: "concurrent"}
+	ds.Freeze()
+
+	errs := make(chan error, 100)
+	wg := sync.WaitGroup{}
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			thread := &starlark.Thread{}
+			body, err := ds.Attr("body")
+			if err != nil {
+				errs <- err
+				return
+			}
+			if body.(*dataframe.DataFrame).NumRows() != 3 {
+				errs <- fmt.Errorf("expected 3 body rows, got %d", body.(*dataframe.DataFrame).NumRows())
+			}
+			if _, err := callMethod(thread, ds, "get_meta", starlark.Tuple{}); err != nil {
+				errs <- err
+			}
+			if _, err := callMethod(thread, ds, "get_structure", starlark.Tuple{}); err != nil {
+				errs <- err
+			}
+			ds.Changes()
+			ds.ChangedSince(ds.SnapshotChanges())
+			if err := ds.SetField("body", starlark.NewList(nil)); err == nil {
+				errs <- fmt.Errorf("expected setting body on a frozen dataset to fail")
+			}
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Error(err)
+	}
+}
+
+func TestDatasetConcurrentWrites(t *testing.T) {
+	ds := csvDataset()
+
+	wg := sync.WaitGroup{}
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			thread := &starlark.Thread{}
+			args := starlark.Tuple{starlark.String("title"), starlark.String(fmt.Sprintf("title %d", i))}
+			if _, err := callMethod(thread, ds, "set_meta", args); err != nil {
+				t.Error(err)
+			}
+			if _, err := ds.Attr("body"); err != nil {
+				t.Error(err)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	if _, ok := ds.Changes()["meta"]; !ok {
+		t.Error("expected meta to be marked as changed")
+	}
+}
+
+func TestBodyFormatMismatch(t *testing.T) {
+	ds := &dataset.Dataset{
+		Structure: &dataset.Structure{
+			Format: "csv",
+			Schema: tabular.BaseTabularSchema,
+		},
+	}
+	ds.SetBodyFile(qfs.NewMemfileBytes("body.csv", []byte(`[["a",1],["b",2]]`)))
+
+	_, err := NewDataset(ds, nil).Attr("body")
+	if !errors.Is(err, ErrBodyFormatMismatch) {
+		t.Fatalf("expected error %q, got: %v", ErrBodyFormatMismatch, err)
+	}
+	expect := `body format mismatch: structure format is "csv", but body data looks like json`
+	if diff := cmp.Diff(expect, err.Error()); diff != "" {
+		t.Errorf("error mismatch (-want +got):\n%s", diff)
+	}
+
+	cases := []struct {
+		format string
+		data   string
+		err    bool
+	}{
+		{"csv", "a,b\n1,2\n", false},
+		{"json", "  \n[1,2]", false},
+		{"json", "\xef\xbb\xbf{\"a\":1}", false},
+		{"ndjson", "{\"a\":1}\n{\"a\":2}\n", false},
+		{"json", "a,b\n1,2\n", true},
+		{"xlsx", "a,b\n1,2\n", true},
+		{"xlsx", "PK\x03\x04", false},
+		{"csv", "PK\x03\x04", true},
+		{"cbor", "\x82\x01\x02", false},
+		{"json", "", false},
+	}
+	for _, c := range cases {
+		err := checkBodyFormat(c.format, []byte(c.data))
+		if c.err != (err != nil) {
+			t.Errorf("format %q, data %q: expected error: %t, got: %v", c.format, c.data, c.err, err)
+		}
+	}
+}
+
+func TestPartitionedBody(t *testing.T) {
+	st := &dataset.Structure{
+		Format:       "csv",
+		FormatConfig: map[string]interface{}{"headerRow": true},
+		Schema: map[string]interface{}{
+			"type": "array",
+			"items": map[string]interface{}{
+				"type": "array",
+				"items": []interface{}{
+					map[string]interface{}{"title": "city", "type": "string"},
+					map[string]interface{}{"title": "temp", "type": "integer"},
+				},
+			},
+		},
+	}
+	ds := &dataset.Dataset{Structure: st}
+	// parts are read in filename order, regardless of directory order
+	ds.SetBodyFile(qfs.NewMemdir("body",
+		qfs.NewMemfileBytes("2021-08-02.csv", []byte("city,temp\ntoronto,22\nchicago,27\n")),
+		qfs.NewMemfileBytes("2021-08-01.csv", []byte("city,temp\nnew york,30\n")),
+	))
+
+	d := NewDataset(ds, &dataframe.OutputConfig{})
+	body, err := d.Attr("body")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expect := `         city  temp
+0    new york    30
+1     toronto    22
+2     chicago    27`
+	if diff := cmp.Diff(expect, body.String()); diff != "" {
+		t.Errorf("body mismatch (-want +got):\n%s", diff)
+	}
+
+	if err := d.assignBodyFromDataframe(); err != nil {
+		t.Fatal(err)
+	}
+	if ds.Structure.Entries != 3 {
+		t.Errorf("expected 3 entries, got: %d", ds.Structure.Entries)
+	}
+	expectLayout := []dsfs.BodyPartition{
+		{Name: "2021-08-01.csv", Entries: 1},
+		{Name: "2021-08-02.csv", Entries: 2},
+	}
+	layout, err := dsfs.BodyPartitions(ds.Structure)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(expectLayout, layout); diff != "" {
+		t.Errorf("partition layout mismatch (-want +got):\n%s", diff)
+	}
+	if ds.BodyFile().IsDirectory() {
+		t.Fatalf("expected partitioned body to be written as a single file")
+	}
+	dir, err := dsfs.OpenPartitionedBody(ds.Structure, ds.BodyFile())
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := map[string]string{}
+	for {
+		f, err := dir.NextFile()
+		if err != nil {
+			break
+		}
+		data, err := ioutil.ReadAll(f)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got[f.FileName()] = string(data)
+	}
+	expectParts := map[string]string{
+		"2021-08-01.csv": "city,temp\nnew york,30\n",
+		"2021-08-02.csv": "city,temp\ntoronto,22\nchicago,27\n",
+	}
+	if diff := cmp.Diff(expectParts, got); diff != "" {
+		t.Errorf("partitions mismatch (-want +got):\n%s", diff)
+	}
+}
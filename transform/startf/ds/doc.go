@@ -15,7 +15,11 @@
           get_structure() dict|None
             get dataset structure component if one is defined
           set_structure(structure) structure
-            set dataset structure component
+            set dataset structure component. errors immediately if the format or schema is malformed
+          rename_columns(renames dict)
+            rename body columns using a dict of old column names to new names. the structure schema is updated to match
+          reorder_columns(names list)
+            reorder body columns to match a list of every column name. the structure schema is updated to match
           get_body() dict|list|None
             get dataset body component if one is defined
           set_body(data dict|list, parse_as? string) body
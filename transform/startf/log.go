@@ -0,0 +1,67 @@
+package startf
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/qri-io/qri/event"
+	"github.com/qri-io/starlib/util"
+	"go.starlark.net/starlark"
+)
+
+// logFunc returns an implementation of the starlark log function, which emits
+// a structured log line: log(level, msg, fields=None). level is one of
+// "debug", "info", "warn" or "error", fields is an optional dict of values
+// to record with the message
+func (r *StepRunner) logFunc(thread *starlark.Thread, _ *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	var (
+		lvl, msg starlark.String
+		fields   *starlark.Dict
+	)
+	if err := starlark.UnpackArgs("log", args, kwargs, "level", &lvl, "msg", &msg, "fields?", &fields); err != nil {
+		return starlark.None, err
+	}
+
+	entry := event.TransformLog{Msg: msg.GoString()}
+	switch l := event.TransformMsgLvl(strings.ToLower(lvl.GoString())); l {
+	case event.TransformMsgLvlDebug, event.TransformMsgLvlInfo, event.TransformMsgLvlWarn, event.TransformMsgLvlError:
+		entry.Lvl = l
+	default:
+		return starlark.None, fmt.Errorf("log: invalid level %q, must be one of debug, info, warn, error", lvl.GoString())
+	}
+
+	if fields != nil {
+		v, err := util.Unmarshal(fields)
+		if err != nil {
+			return starlark.None, fmt.Errorf("log: fields: %w", err)
+		}
+		m, ok := v.(map[string]interface{})
+		if !ok {
+			return starlark.None, fmt.Errorf("log: fields must be a dict with string keys")
+		}
+		entry.Fields = m
+	}
+
+	if r.eventsCh != nil {
+		r.eventsCh <- event.Event{Type: event.ETTransformLog, Payload: entry}
+	}
+	r.writer.Write([]byte(formatLogLine(entry) + "\n"))
+	return starlark.None, nil
+}
+
+// formatLogLine renders a log entry as a single line of text, with fields
+// sorted by key
+func formatLogLine(entry event.TransformLog) string {
+	b := &strings.Builder{}
+	fmt.Fprintf(b, "%s: %s", entry.Lvl, entry.Msg)
+	keys := make([]string, 0, len(entry.Fields))
+	for key := range entry.Fields {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		fmt.Fprintf(b, " %s=%v", key, entry.Fields[key])
+	}
+	return b.String()
+}
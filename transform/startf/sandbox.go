@@ -1,6 +1,7 @@
 package startf
 
 import (
+	"context"
 	"fmt"
 	"net/http"
 	"strings"
@@ -14,21 +15,74 @@ var (
 	httpGuard = &HTTPGuard{NetworkEnabled: true}
 	// ErrNtwkDisabled is returned whenever a network call is attempted but h.NetworkEnabled is false
 	ErrNtwkDisabled = fmt.Errorf("network use is disabled. http can only be used during download step")
+	// ErrDomainNotAllowed is returned when a network call is made to a host
+	// that isn't in the transform's domain allowlist
+	ErrDomainNotAllowed = fmt.Errorf("http requests to this domain are not allowed")
 )
 
+// allowedDomainsKey is the thread-local key for a transform's http domain
+// allowlist
+const allowedDomainsKey = "qri.allowedDomains"
+
 // HTTPGuard protects network requests, only allowing when network is enabled
 type HTTPGuard struct {
 	NetworkEnabled bool
 }
 
 // Allowed implements starlib/http RequestGuard
-func (h *HTTPGuard) Allowed(_ *starlark.Thread, req *http.Request) (*http.Request, error) {
+func (h *HTTPGuard) Allowed(thread *starlark.Thread, req *http.Request) (*http.Request, error) {
 	if !h.NetworkEnabled {
 		return nil, ErrNtwkDisabled
 	}
+	if thread == nil {
+		return req, nil
+	}
+	if domains, ok := thread.Local(allowedDomainsKey).([]string); ok {
+		host := req.URL.Hostname()
+		if !domainAllowed(domains, host) {
+			return nil, fmt.Errorf("%w: %q", ErrDomainNotAllowed, host)
+		}
+		// carry the allowlist with the request so redirects are checked too
+		req = req.WithContext(context.WithValue(req.Context(), allowedDomainsCtxKey, domains))
+	}
 	return req, nil
 }
 
+// allowedDomainsCtxKey is the request context key for a transform's http
+// domain allowlist
+type allowedDomainsCtxKeyType struct{}
+
+var allowedDomainsCtxKey = allowedDomainsCtxKeyType{}
+
+// maxRedirects matches the net/http default redirect limit
+const maxRedirects = 10
+
+// checkRedirect applies a request's domain allowlist to every redirect hop
+func checkRedirect(req *http.Request, via []*http.Request) error {
+	if len(via) >= maxRedirects {
+		return fmt.Errorf("stopped after %d redirects", maxRedirects)
+	}
+	if domains, ok := req.Context().Value(allowedDomainsCtxKey).([]string); ok {
+		if host := req.URL.Hostname(); !domainAllowed(domains, host) {
+			return fmt.Errorf("%w: redirect to %q", ErrDomainNotAllowed, host)
+		}
+	}
+	return nil
+}
+
+// domainAllowed checks a host against a list of allowed domains. Allowing
+// a domain also allows all of its subdomains
+func domainAllowed(domains []string, host string) bool {
+	host = strings.ToLower(host)
+	for _, d := range domains {
+		d = strings.ToLower(strings.TrimPrefix(d, "."))
+		if host == d || strings.HasSuffix(host, "."+d) {
+			return true
+		}
+	}
+	return false
+}
+
 // EnableNtwk allows network calls
 func (h *HTTPGuard) EnableNtwk() {
 	h.NetworkEnabled = true
@@ -42,6 +96,7 @@ func (h *HTTPGuard) DisableNtwk() {
 func init() {
 	// connect httpGuard instance to starlib http guard
 	starhttp.Guard = httpGuard
+	starhttp.Client = &http.Client{CheckRedirect: checkRedirect}
 }
 
 type config map[string]interface{}
@@ -1,8 +1,17 @@
 package startf
 
 import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
 	"testing"
 
+	"github.com/qri-io/dataset"
+	"github.com/qri-io/qfs"
+
 	"github.com/qri-io/starlib/testdata"
 	"go.starlark.net/starlark"
 	"go.starlark.net/starlarktest"
@@ -53,3 +62,70 @@ assert.eq(secrets.get("c"), "string")
 		t.Fatal(err)
 	}
 }
+
+func TestHTTPAllowedDomains(t *testing.T) {
+	ctx := context.Background()
+	var disallowedURL string
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/redirect" {
+			http.Redirect(w, r, disallowedURL, http.StatusFound)
+			return
+		}
+		w.Write([]byte(`["ok"]`))
+	}))
+	defer s.Close()
+
+	// httptest servers listen on 127.0.0.1, "localhost" resolves to the same
+	// server under a different host name
+	allowedURL := s.URL
+	_, port, err := net.SplitHostPort(s.Listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	disallowedURL = "http://localhost:" + port
+
+	run := func(url string) error {
+		script := fmt.Sprintf(`
+load("http.star", "http")
+
+ds = dataset.latest()
+ds.body = [http.get(%q).json()]
+dataset.commit(ds)
+`, url)
+		ds := &dataset.Dataset{Transform: &dataset.Transform{}}
+		ds.Transform.SetScriptFile(qfs.NewMemfileBytes("tf.star", []byte(script)))
+		return ExecScript(ctx, ds, func(o *ExecOpts) {
+			o.ModuleLoader = testModuleLoader(t)
+		}, AllowHTTPDomains([]string{"127.0.0.1"}))
+	}
+
+	if err := run(allowedURL); err != nil {
+		t.Errorf("expected request to allowed domain to succeed, got: %s", err)
+	}
+	if err := run(disallowedURL); err == nil || !strings.Contains(err.Error(), ErrDomainNotAllowed.Error()) {
+		t.Errorf("expected request to disallowed domain to fail with %q, got: %v", ErrDomainNotAllowed, err)
+	}
+	if err := run(allowedURL + "/redirect"); err == nil || !strings.Contains(err.Error(), ErrDomainNotAllowed.Error()) {
+		t.Errorf("expected redirect to disallowed domain to fail with %q, got: %v", ErrDomainNotAllowed, err)
+	}
+}
+
+func TestDomainAllowed(t *testing.T) {
+	domains := []string{"example.com", ".data.gov"}
+	cases := []struct {
+		host   string
+		expect bool
+	}{
+		{"example.com", true},
+		{"api.EXAMPLE.com", true},
+		{"badexample.com", false},
+		{"census.data.gov", true},
+		{"data.gov.evil.com", false},
+		{"", false},
+	}
+	for _, c := range cases {
+		if got := domainAllowed(domains, c.host); got != c.expect {
+			t.Errorf("host %q: expected %t, got %t", c.host, c.expect, got)
+		}
+	}
+}
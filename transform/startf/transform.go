@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"io"
 	"io/ioutil"
+	"math/rand"
 	"runtime/debug"
 	"strings"
 
@@ -12,6 +13,7 @@ import (
 	"github.com/qri-io/dataset"
 	"github.com/qri-io/dataset/preview"
 	"github.com/qri-io/qfs"
+	"github.com/qri-io/qri/base/dsfs"
 	"github.com/qri-io/qri/dsref"
 	"github.com/qri-io/qri/event"
 	"github.com/qri-io/qri/repo"
@@ -21,6 +23,7 @@ import (
 	"github.com/qri-io/starlib/dataframe"
 	"go.starlark.net/resolve"
 	"go.starlark.net/starlark"
+	"go.starlark.net/starlarkstruct"
 	"go.starlark.net/syntax"
 )
 
@@ -29,6 +32,9 @@ var (
 	Version = version.Version
 	// ErrNotDefined is for when a starlark value is not defined or does not exist
 	ErrNotDefined = fmt.Errorf("not defined")
+	// ErrSetupFailed wraps errors returned by a script's setup function,
+	// indicating the transform was aborted before running any further steps
+	ErrSetupFailed = fmt.Errorf("transform setup failed")
 	// log for this package
 	log = golog.Logger("startf")
 )
@@ -64,6 +70,24 @@ type ExecOpts struct {
 	// the size of the output area, for stringifying large objects
 	OutputWidth  int
 	OutputHeight int
+	// confirm bodies marked unchanged by comparing checksums with the
+	// previous version
+	VerifyBodyChecksum bool
+	// largest body size in bytes to diff when writing commit messages.
+	// 0 uses dsfs.BodySizeSmallEnoughToDiff
+	BodyDiffThreshold int
+	// customizes generated commit titles & messages, nil uses the default
+	CommitMessage dsfs.CommitMessageFunc
+	// domains the http module may make requests to. nil allows all domains
+	AllowedHTTPDomains []string
+	// run with a fixed clock & seeded random source, so repeated runs
+	// produce identical output
+	Deterministic bool
+	// seed for the random source in deterministic mode
+	Seed int64
+	// reference to the version the target dataset was loaded from, in
+	// peername/name@path form. dataset.latest(pin=True) records this version
+	LatestRef string
 }
 
 // AddDatasetLoader is required to enable the load_dataset starlark builtin
@@ -133,6 +157,53 @@ func SizeInfo(outWidth, outHeight int) func(o *ExecOpts) {
 	}
 }
 
+// VerifyBodyChecksum confirms bodies the change set reports as unchanged by
+// comparing body checksums with the previous version, instead of trusting
+// the change set
+func VerifyBodyChecksum(o *ExecOpts) {
+	o.VerifyBodyChecksum = true
+}
+
+// SetBodyDiffThreshold sets the largest body size in bytes that's diffed to
+// describe body changes in the commit message
+func SetBodyDiffThreshold(size int) func(o *ExecOpts) {
+	return func(o *ExecOpts) {
+		o.BodyDiffThreshold = size
+	}
+}
+
+// SetCommitMessageFunc sets a function that customizes the commit title &
+// message generated when the transform commits
+func SetCommitMessageFunc(f dsfs.CommitMessageFunc) func(o *ExecOpts) {
+	return func(o *ExecOpts) {
+		o.CommitMessage = f
+	}
+}
+
+// AllowHTTPDomains restricts http requests made by the transform to the given
+// domains & their subdomains
+func AllowHTTPDomains(domains []string) func(o *ExecOpts) {
+	return func(o *ExecOpts) {
+		o.AllowedHTTPDomains = domains
+	}
+}
+
+// Deterministic runs the transform with a fixed clock and a random source
+// seeded with seed
+func Deterministic(seed int64) func(o *ExecOpts) {
+	return func(o *ExecOpts) {
+		o.Deterministic = true
+		o.Seed = seed
+	}
+}
+
+// SetLatestRef records the version the target dataset was loaded from
+func SetLatestRef(ref string) func(o *ExecOpts) {
+	return func(o *ExecOpts) {
+		o.LatestRef = ref
+	}
+}
+
 // DefaultExecOpts applies default options to an ExecOpts pointer
 func DefaultExecOpts(o *ExecOpts) {
 	o.AllowFloat = true
@@ -155,7 +226,11 @@ type StepRunner struct {
 	writer       io.Writer
 	thread       *starlark.Thread
 	changeSet    map[string]struct{}
+	verifyBody   bool
+	diffLimit    int
+	commitMsg    dsfs.CommitMessageFunc
 	commitCalled bool
+	setupCalled  bool
 }
 
 // NewStepRunner returns a new StepRunner for the given dataset
@@ -181,7 +256,7 @@ func NewStepRunner(target *dataset.Dataset, opts ...func(o *ExecOpts)) *StepRunn
 	}
 
 	thread := &starlark.Thread{
-		Load: o.ModuleLoader,
+		Load: runnerModuleLoader(o.ModuleLoader, o.Deterministic),
 		Print: func(thread *starlark.Thread, msg string) {
 			if o.EventsCh != nil {
 				o.EventsCh <- event.Event{
@@ -195,22 +270,38 @@ func NewStepRunner(target *dataset.Dataset, opts ...func(o *ExecOpts)) *StepRunn
 		},
 	}
 
+	if o.AllowedHTTPDomains != nil {
+		thread.SetLocal(allowedDomainsKey, o.AllowedHTTPDomains)
+	}
+	if o.Deterministic {
+		thread.SetLocal(randKey, rand.New(rand.NewSource(o.Seed)))
+	}
+
 	// Store the OutputConfig on the starlark thread. This allows functions
 	// such as the DataFrame constructor to get this configuration
 	outconf := dataframe.SetOutputSize(thread, o.OutputWidth, o.OutputHeight)
 
 	r := &StepRunner{
-		config:    target.Transform.Config,
-		secrets:   o.Secrets,
-		fs:        o.Filesystem,
-		dsLoader:  o.DatasetLoader,
-		eventsCh:  o.EventsCh,
-		writer:    o.ErrWriter,
-		thread:    thread,
-		globals:   starlark.StringDict{},
-		changeSet: o.ChangeSet,
+		config:     target.Transform.Config,
+		secrets:    o.Secrets,
+		fs:         o.Filesystem,
+		dsLoader:   o.DatasetLoader,
+		eventsCh:   o.EventsCh,
+		writer:     o.ErrWriter,
+		thread:     thread,
+		globals:    starlark.StringDict{},
+		changeSet:  o.ChangeSet,
+		verifyBody: o.VerifyBodyChecksum,
+		diffLimit:  o.BodyDiffThreshold,
+		commitMsg:  o.CommitMessage,
 	}
 	r.stards = stards.NewBoundDataset(target, outconf, r.onCommit)
+	r.stards.SetLatestRef(o.LatestRef)
+	if r.dsLoader != nil {
+		r.stards.SetLoader(func(refstr string) (*dataset.Dataset, error) {
+			return r.dsLoader.LoadDataset(context.TODO(), refstr)
+		})
+	}
 
 	return r
 }
@@ -218,6 +309,7 @@ func NewStepRunner(target *dataset.Dataset, opts ...func(o *ExecOpts)) *StepRunn
 // RunStep runs the single transform step using the dataset
 func (r *StepRunner) RunStep(ctx context.Context, ds *dataset.Dataset, st *dataset.TransformStep) (err error) {
 	r.globals["load_dataset"] = starlark.NewBuiltin("load_dataset", r.loadDatasetFunc(ctx, ds))
+	r.globals["log"] = starlark.NewBuiltin("log", r.logFunc)
 	r.globals["dataset"] = r.stards
 	r.globals["config"] = config(r.config)
 	r.globals["secrets"] = secrets(r.secrets)
@@ -245,6 +337,17 @@ func (r *StepRunner) RunStep(ctx context.Context, ds *dataset.Dataset, st *datas
 
 	r.printFinalStatement(file)
 
+	// stop script execution if the context is cancelled
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-ctx.Done():
+			r.thread.Cancel(ctx.Err().Error())
+		case <-done:
+		}
+	}()
+
 	globals, err := mod.Init(r.thread, r.globals)
 	if err != nil {
 		if evalErr, ok := err.(*starlark.EvalError); ok {
@@ -256,9 +359,40 @@ func (r *StepRunner) RunStep(ctx context.Context, ds *dataset.Dataset, st *datas
 		r.globals[key] = val
 	}
 
+	// run the setup function as soon as the step that defines it completes, so
+	// a script can abort before any subsequent (expensive) steps execute
+	if !r.setupCalled {
+		if fn, fnErr := r.globalFunc("setup"); fnErr == nil {
+			r.setupCalled = true
+			return r.callSetup(fn)
+		}
+	}
+
 	return
 }
 
+// callSetup calls a script-defined setup function, passing a context value
+// that exposes config, secrets & log when the function accepts an argument. Any
+// error returned wraps ErrSetupFailed
+func (r *StepRunner) callSetup(fn *starlark.Function) error {
+	args := starlark.Tuple{}
+	if fn.NumParams() > 0 {
+		args = append(args, starlarkstruct.FromStringDict(starlarkstruct.Default, starlark.StringDict{
+			"config":  config(r.config),
+			"secrets": secrets(r.secrets),
+			"log":     starlark.NewBuiltin("log", r.logFunc),
+		}))
+	}
+
+	if _, err := starlark.Call(r.thread, fn, args, nil); err != nil {
+		if evalErr, ok := err.(*starlark.EvalError); ok && evalErr.Unwrap() != nil {
+			return fmt.Errorf("%w: %s", ErrSetupFailed, evalErr.Unwrap())
+		}
+		return fmt.Errorf("%w: %s", ErrSetupFailed, err)
+	}
+	return nil
+}
+
 // TODO(b5): this needs to be finished
 func (r *StepRunner) printFinalStatement(f *syntax.File) {
 	if len(f.Stmts) == 0 {
@@ -285,6 +419,12 @@ func (r *StepRunner) CommitCalled() bool {
 	return r.commitCalled
 }
 
+// OutputRef returns the reference the script committed to, if it named a
+// dataset other than the target
+func (r *StepRunner) OutputRef() string {
+	return r.stards.OutputRef()
+}
+
 // globalFunc checks if a global function is defined
 func (r *StepRunner) globalFunc(name string) (fn *starlark.Function, err error) {
 	x, ok := r.globals[name]
@@ -357,15 +497,21 @@ func (r *StepRunner) loadDatasetFunc(ctx context.Context, target *dataset.Datase
 
 func (r *StepRunner) onCommit(ds *stards.Dataset) error {
 	// Which components were changed
+	changes := ds.Changes()
 	if r.changeSet != nil {
-		changes := ds.Changes()
 		for comp := range changes {
 			r.changeSet[comp] = changes[comp]
 		}
 	}
 
 	ctx := context.TODO()
-	if err := ds.AssignComponentsFromDataframe(ctx, r.changeSet, r.fs, r.dsLoader); err != nil {
+	ds.SetVerifyBodyChecksum(r.verifyBody)
+	ds.SetBodyDiffThreshold(r.diffLimit)
+	ds.SetCommitMessageFunc(r.commitMsg)
+	// assign from a copy of the script's changes. Body changes found by
+	// checksum verification weren't made by the script, and shouldn't be
+	// reported as such
+	if err := ds.AssignComponentsFromDataframe(ctx, changes, r.fs, r.dsLoader); err != nil {
 		return err
 	}
 
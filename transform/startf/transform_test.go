@@ -5,6 +5,7 @@ import (
 	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 
 	"github.com/google/go-cmp/cmp"
@@ -13,8 +14,10 @@ import (
 	"github.com/qri-io/dataset/stepfile"
 	"github.com/qri-io/qfs"
 	"github.com/qri-io/qri/base"
+	"github.com/qri-io/qri/dsref"
 	"github.com/qri-io/qri/repo"
 	repoTest "github.com/qri-io/qri/repo/test"
+	stards "github.com/qri-io/qri/transform/startf/ds"
 	"github.com/qri-io/starlib"
 	"github.com/qri-io/starlib/testdata"
 	"go.starlark.net/starlark"
@@ -292,3 +295,126 @@ func ExecScript(ctx context.Context, ds *dataset.Dataset, opts ...func(o *ExecOp
 	}
 	return nil
 }
+
+func TestDeterministic(t *testing.T) {
+	ctx := context.Background()
+	script := `
+load("random.star", "random")
+load("time.star", "time")
+
+ds = dataset.latest()
+ds.body = [[random.randint(0, 1000000), random.random(), random.choice(["a", "b", "c"]), str(time.now())] for i in range(10)]
+dataset.commit(ds)
+`
+	run := func(opts ...func(o *ExecOpts)) string {
+		ds := &dataset.Dataset{Transform: &dataset.Transform{}}
+		ds.Transform.SetScriptFile(qfs.NewMemfileBytes("tf.star", []byte(script)))
+		if err := ExecScript(ctx, ds, opts...); err != nil {
+			t.Fatal(err)
+		}
+		data, err := ioutil.ReadAll(ds.BodyFile())
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(data)
+	}
+
+	a := run(Deterministic(42))
+	b := run(Deterministic(42))
+	if diff := cmp.Diff(a, b); diff != "" {
+		t.Errorf("expected deterministic runs to produce identical bodies (-a +b):\n%s", diff)
+	}
+	if !strings.Contains(a, DeterministicTime.Format("2006-01-02")) {
+		t.Errorf("expected body to use the deterministic clock, got:\n%s", a)
+	}
+
+	if c := run(Deterministic(7)); a == c {
+		t.Errorf("expected runs with different seeds to produce different bodies")
+	}
+	if d := run(); a == d {
+		t.Errorf("expected non-deterministic run to produce a different body")
+	}
+}
+
+type mapLoader map[string]*dataset.Dataset
+
+func (l mapLoader) LoadDataset(ctx context.Context, refstr string) (*dataset.Dataset, error) {
+	ds, ok := l[refstr]
+	if !ok {
+		return nil, dsref.ErrRefNotFound
+	}
+	return ds, nil
+}
+
+func TestLatestPin(t *testing.T) {
+	ctx := context.Background()
+	script := `
+ds = dataset.latest(pin=True)
+ds.body = [["base", ds.get_meta()["title"]]]
+dataset.commit(ds)
+`
+	newTarget := func(title string, resources map[string]*dataset.TransformResource) *dataset.Dataset {
+		ds := &dataset.Dataset{
+			Peername:  "peer",
+			Name:      "pinned",
+			Meta:      &dataset.Meta{Title: title},
+			Transform: &dataset.Transform{Resources: resources},
+		}
+		ds.Transform.SetScriptFile(qfs.NewMemfileBytes("tf.star", []byte(script)))
+		return ds
+	}
+	body := func(ds *dataset.Dataset) string {
+		data, err := ioutil.ReadAll(ds.BodyFile())
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(data)
+	}
+
+	loader := mapLoader{
+		"peer/pinned@/mem/QmOld": &dataset.Dataset{
+			Peername: "peer",
+			Name:     "pinned",
+			Path:     "/mem/QmOld",
+			Meta:     &dataset.Meta{Title: "old title"},
+		},
+	}
+
+	// pinning records the version latest resolved to
+	ds := newTarget("head title", nil)
+	if err := ExecScript(ctx, ds, SetLatestRef("peer/pinned@/mem/QmHead"), AddDatasetLoader(loader)); err != nil {
+		t.Fatal(err)
+	}
+	expect := map[string]*dataset.TransformResource{
+		stards.LatestResourceKey: {Path: "peer/pinned@/mem/QmHead"},
+	}
+	if diff := cmp.Diff(expect, ds.Transform.Resources); diff != "" {
+		t.Errorf("transform resources mismatch (-want +got):\n%s", diff)
+	}
+
+	// re-applying a pinned transform uses the pinned version, not the head
+	pinned := map[string]*dataset.TransformResource{
+		stards.LatestResourceKey: {Path: "peer/pinned@/mem/QmOld"},
+	}
+	ds = newTarget("head title", pinned)
+	err := ExecScript(ctx, ds, SetLatestRef("peer/pinned@/mem/QmHead"), AddDatasetLoader(loader))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := body(ds); !strings.Contains(got, "old title") {
+		t.Errorf("expected body to be derived from the pinned version, got: %q", got)
+	}
+	if got := ds.Transform.Resources[stards.LatestResourceKey].Path; got != "peer/pinned@/mem/QmOld" {
+		t.Errorf("expected pinned resource to be retained, got %q", got)
+	}
+
+	// without pinning latest resolves to the head & nothing is recorded
+	ds = newTarget("head title", nil)
+	ds.Transform.SetScriptFile(qfs.NewMemfileBytes("tf.star", []byte(strings.Replace(script, "pin=True", "", 1))))
+	if err := ExecScript(ctx, ds, SetLatestRef("peer/pinned@/mem/QmHead"), AddDatasetLoader(loader)); err != nil {
+		t.Fatal(err)
+	}
+	if len(ds.Transform.Resources) != 0 {
+		t.Errorf("expected no resources to be recorded, got: %v", ds.Transform.Resources)
+	}
+}
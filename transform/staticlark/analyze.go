@@ -1,6 +1,8 @@
 package staticlark
 
 import (
+	"encoding/json"
+
 	golog "github.com/ipfs/go-log"
 	"go.starlark.net/starlark"
 	"go.starlark.net/syntax"
@@ -22,6 +24,8 @@ func AnalyzeFile(filename string) ([]Diagnostic, error) {
 	}
 	// Constuct pre-defined global symbols
 	globals := newSymtable(starlark.Universe)
+	// Symbols imported by load statements are also globals
+	addLoadedSymbols(f.Stmts, globals)
 	// Build a graph of all calls, using top level calls and pre-defined globals
 	callGraph := buildCallGraph(funcs, topLevel, globals)
 
@@ -35,7 +39,41 @@ func AnalyzeFile(filename string) ([]Diagnostic, error) {
 	// TODO(dustmop): As more analysis steps are introduced, refactor this
 	// into a generic interface that creates Diagnostics
 	unusedDiags := callGraph.findUnusedFuncs()
-	return append(dataflowDiags, unusedDiags...), nil
+	recursionDiags := callGraph.findRecursiveFuncs()
+	diags := append(dataflowDiags, unusedDiags...)
+	return append(diags, recursionDiags...), nil
+}
+
+// CallGraph parses a script and returns its call graph as text, listing each
+// function followed by the functions it calls, indented by call depth
+func CallGraph(filename string) (string, error) {
+	f, err := syntax.Parse(filename, nil, 0)
+	if err != nil {
+		return "", err
+	}
+	funcs, topLevel, err := collectFuncDefsTopLevelCalls(f.Stmts)
+	if err != nil {
+		return "", err
+	}
+	globals := newSymtable(starlark.Universe)
+	addLoadedSymbols(f.Stmts, globals)
+	return buildCallGraph(funcs, topLevel, globals).String(), nil
+}
+
+// FunctionsJSON parses a script and serializes its top level function
+// definitions as a JSON array. Each function lists its name, parameters, the
+// names of functions it calls, and the position of its body, allowing
+// external tools like editors to build a call graph view
+func FunctionsJSON(filename string) ([]byte, error) {
+	f, err := syntax.Parse(filename, nil, 0)
+	if err != nil {
+		return nil, err
+	}
+	funcs, _, err := collectFuncDefsTopLevelCalls(f.Stmts)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(funcs)
 }
 
 // Diagnostic represents a diagnostic message describing an issue with the code
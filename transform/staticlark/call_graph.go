@@ -10,6 +10,8 @@ import (
 type callGraph struct {
 	nodes  []*funcNode
 	lookup map[string]*funcNode
+	// names of called functions that could not be found in the symbol table
+	notFound []string
 }
 
 // buildCallGraph iterates the function nodes provided, and adds
@@ -57,45 +59,120 @@ func addToCallGraph(f *funcNode, graph *callGraph, symtable map[string]*funcNode
 		name:   f.name,
 		params: f.params,
 		body:   f.body,
+		module: f.module,
 		calls:  make([]*funcNode, 0),
 	}
+	// add to the lookup before visiting calls, so recursive functions resolve
+	// to this node instead of recursing forever
+	graph.lookup[f.name] = me
 	for _, name := range f.callNames {
 		child, ok := symtable[name]
 		if !ok {
 			log.Debugw("addToCallGraph func not found", "name", name)
+			graph.notFound = append(graph.notFound, name)
 			continue
 		}
 		n := addToCallGraph(child, graph, symtable)
 		me.calls = append(me.calls, n)
 	}
-	graph.lookup[f.name] = me
 	graph.nodes = append(graph.nodes, me)
 	return me
 }
 
+// setCallHeight assigns the height of each function in the call graph, the
+// length of the longest chain of calls below it. Calls that recurse back to a
+// function already being measured count as leaves, keeping height bounded
 func (n *funcNode) setCallHeight() {
+	n.setCallHeightVisiting(map[*funcNode]bool{})
+}
+
+func (n *funcNode) setCallHeightVisiting(visiting map[*funcNode]bool) {
+	visiting[n] = true
+	defer delete(visiting, n)
+
 	maxChild := -1
 	for _, call := range n.calls {
-		call.setCallHeight()
-		if call.height > maxChild {
-			maxChild = call.height
+		height := 0
+		if !visiting[call] {
+			call.setCallHeightVisiting(visiting)
+			height = call.height
+		}
+		if height > maxChild {
+			maxChild = height
 		}
 	}
 	n.height = maxChild + 1
 }
 
 func (n *funcNode) markReachable() {
+	if n.reach {
+		return
+	}
 	n.reach = true
 	for _, call := range n.calls {
 		call.markReachable()
 	}
 }
 
+// findRecursiveFuncs returns a diagnostic for each cycle of calls in the
+// graph, describing the chain of calls that leads back to the first function
+func (cg *callGraph) findRecursiveFuncs() []Diagnostic {
+	cycles := map[string]struct{}{}
+	done := map[*funcNode]bool{}
+	for _, n := range cg.nodes {
+		findCycles(n, []*funcNode{}, done, cycles)
+	}
+
+	results := make([]Diagnostic, 0, len(cycles))
+	for cycle := range cycles {
+		results = append(results, Diagnostic{
+			Category: "recursion",
+			Message:  cycle,
+		})
+	}
+	sort.Slice(results, func(i, j int) bool {
+		return results[i].Message < results[j].Message
+	})
+	return results
+}
+
+func findCycles(n *funcNode, stack []*funcNode, done map[*funcNode]bool, cycles map[string]struct{}) {
+	for i, ancestor := range stack {
+		if ancestor == n {
+			// rotate the cycle to start at the lowest name, so the same
+			// cycle is always reported the same way
+			loop := stack[i:]
+			start := 0
+			for j, f := range loop {
+				if f.name < loop[start].name {
+					start = j
+				}
+			}
+			names := make([]string, 0, len(loop)+1)
+			for j := range loop {
+				names = append(names, loop[(start+j)%len(loop)].name)
+			}
+			names = append(names, names[0])
+			cycles[strings.Join(names, " -> ")] = struct{}{}
+			return
+		}
+	}
+	if done[n] {
+		return
+	}
+	stack = append(stack, n)
+	for _, call := range n.calls {
+		findCycles(call, stack, done, cycles)
+	}
+	done[n] = true
+}
+
 func (cg *callGraph) findUnusedFuncs() []Diagnostic {
 	// Recursively walk the tree to find unreachable nodes
 	unusedNames := map[string]struct{}{}
+	visited := map[*funcNode]bool{}
 	for _, f := range cg.nodes {
-		checkfuncNodeUnused(f, unusedNames)
+		checkfuncNodeUnused(f, unusedNames, visited)
 	}
 	// Sort the function names
 	results := make([]Diagnostic, 0, len(unusedNames))
@@ -111,13 +188,17 @@ func (cg *callGraph) findUnusedFuncs() []Diagnostic {
 	return results
 }
 
-func checkfuncNodeUnused(node *funcNode, unusedNames map[string]struct{}) {
+func checkfuncNodeUnused(node *funcNode, unusedNames map[string]struct{}, visited map[*funcNode]bool) {
+	if visited[node] {
+		return
+	}
+	visited[node] = true
 	if !node.reach {
 		// TODO(dustmop): Copy the position of the function definition
 		unusedNames[node.name] = struct{}{}
 	}
 	for _, call := range node.calls {
-		checkfuncNodeUnused(call, unusedNames)
+		checkfuncNodeUnused(call, unusedNames, visited)
 	}
 }
 
@@ -125,13 +206,19 @@ func checkfuncNodeUnused(node *funcNode, unusedNames map[string]struct{}) {
 func (cg *callGraph) String() string {
 	text := ""
 	for _, n := range cg.nodes {
-		text += stringifyNode(n, 0)
+		text += stringifyNode(n, 0, map[*funcNode]bool{})
 	}
 	return text
 }
 
-func stringifyNode(n *funcNode, depth int) string {
+func stringifyNode(n *funcNode, depth int, ancestors map[*funcNode]bool) string {
 	padding := strings.Repeat(" ", depth)
+	if ancestors[n] {
+		return fmt.Sprintf("%s%s (recursive)\n", padding, n.name)
+	}
+	ancestors[n] = true
+	defer delete(ancestors, n)
+
 	seen := map[string]struct{}{}
 	text := fmt.Sprintf("%s%s\n", padding, n.name)
 	for _, call := range n.calls {
@@ -139,7 +226,7 @@ func stringifyNode(n *funcNode, depth int) string {
 			continue
 		}
 		seen[call.name] = struct{}{}
-		text += stringifyNode(call, depth+1)
+		text += stringifyNode(call, depth+1, ancestors)
 	}
 	return text
 }
@@ -88,3 +88,78 @@ func TestUnusedFunctions(t *testing.T) {
 		t.Errorf("mismatch (-want +got):\n%s", diff)
 	}
 }
+
+func TestRecursiveFunctions(t *testing.T) {
+	filename := "testdata/recursive_funcs.star"
+
+	f, err := syntax.Parse(filename, nil, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	funcs, topLevel, err := collectFuncDefsTopLevelCalls(f.Stmts)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Building the graph must not recurse forever on recursive functions
+	callGraph := buildCallGraph(funcs, topLevel, newSymtable(starlark.Universe))
+
+	recursive := callGraph.findRecursiveFuncs()
+	expectRecursive := []Diagnostic{
+		{Category: "recursion", Message: "fact -> fact"},
+		{Category: "recursion", Message: "is_even -> is_odd -> is_even"},
+	}
+	if diff := cmp.Diff(expectRecursive, recursive, cmpopts.IgnoreFields(Diagnostic{}, "Pos")); diff != "" {
+		t.Errorf("mismatch (-want +got):\n%s", diff)
+	}
+
+	if h := callGraph.lookup["check"].height; h != 3 {
+		t.Errorf("expected height of check to be 3, got %d", h)
+	}
+
+	if _, err := AnalyzeFile(filename); err != nil {
+		t.Errorf("analyzing recursive functions: %s", err)
+	}
+}
+
+func TestLoadedFunctions(t *testing.T) {
+	filename := "testdata/load_funcs.star"
+
+	f, err := syntax.Parse(filename, nil, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	funcs, topLevel, err := collectFuncDefsTopLevelCalls(f.Stmts)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	globals := newSymtable(starlark.Universe)
+	addLoadedSymbols(f.Stmts, globals)
+	callGraph := buildCallGraph(funcs, topLevel, globals)
+
+	if len(callGraph.notFound) != 0 {
+		t.Errorf("expected all calls to be found, not found: %v", callGraph.notFound)
+	}
+
+	actual := callGraph.String()
+	expect := `bar
+qux
+use_loaded
+ bar
+ qux
+`
+	if diff := cmp.Diff(expect, actual); diff != "" {
+		t.Errorf("mismatch (-want +got):\n%s", diff)
+	}
+
+	expectModules := map[string]string{
+		"bar": "foo.star",
+		"qux": "baz.star",
+	}
+	for name, module := range expectModules {
+		if got := callGraph.lookup[name].module; got != module {
+			t.Errorf("expected %q to be loaded from %q, got %q", name, module, got)
+		}
+	}
+}
@@ -286,7 +286,22 @@ func (builder *cfBuilder) buildSingleNode(stmt syntax.Stmt) {
 		builder.dangling = []int{startPos}
 
 	case *syntax.WhileStmt:
-		// NOTE: analyzer does not support while loops
+		startPos := builder.refNext()
+		builder.makeBlock()
+
+		// condition for loop
+		checkUnit := &unit{atom: "while"}
+		checkUnit.tail = []*unit{exprToUnit(item.Cond)}
+		builder.put(checkUnit)
+		loopEntry := builder.finish()
+
+		bodyPos := builder.refNext()
+		loopLeave := builder.buildSubGraph(item.Body)
+
+		// add edges to create the loop flow
+		builder.addEdges(loopEntry, []int{bodyPos})
+		builder.addEdges(loopLeave, []int{startPos})
+		builder.dangling = []int{startPos}
 
 	case *syntax.IfStmt:
 		builder.makeBlock()
@@ -227,6 +227,39 @@ func TestControlFlowLoopWithBreak(t *testing.T) {
 	}
 }
 
+func TestControlFlowWhileLoop(t *testing.T) {
+	funcmap := mustReadScriptFunctionMap(t, "testdata/loop_funcs.star")
+
+	cf, err := newControlFlowFromFunc(funcmap["collatz"])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expect := `0: [set! steps 0]
+  out: 1
+1: [while [> n 1]]
+  out: 2,6
+2: [if [== [% n 2] 0]]
+  out: 3,4, join: 5
+3: [set! n [/ n 2]]
+  out: 5
+4: [set! n [+ [* 3 n] 1]]
+  out: 5
+5: [set! steps [+= steps 1]]
+  out: 1
+6: [for i [range limit]]
+  out: 7,8
+7: [print i]
+  out: 6
+8: [return steps]
+  out: return
+`
+	actual := cf.stringify()
+	if diff := cmp.Diff(expect, actual); diff != "" {
+		t.Errorf("mismatch (-want +got):\n%s", diff)
+	}
+}
+
 func mustReadScriptFunctionMap(t *testing.T, filename string) map[string]*funcNode {
 	f, err := syntax.Parse(filename, nil, 0)
 	if err != nil {
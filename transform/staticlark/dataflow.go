@@ -14,9 +14,10 @@ import (
 // rule is that sensitive data may not be passed to a dangerous function
 func analyzeSensitiveDataflow(graph *callGraph, axioms map[string]*funcNode) ([]Diagnostic, error) {
 	dataflowAnalyzer := &dataflowAnalyzer{
-		graph:  graph,
-		axioms: axioms,
-		seen:   make(map[string]struct{}),
+		graph:    graph,
+		axioms:   axioms,
+		seen:     make(map[string]struct{}),
+		visiting: make(map[string]struct{}),
 	}
 	for _, fn := range graph.nodes {
 		if err := dataflowAnalyzer.traverseNode(fn); err != nil {
@@ -30,7 +31,9 @@ type dataflowAnalyzer struct {
 	graph  *callGraph
 	axioms map[string]*funcNode
 	seen   map[string]struct{}
-	diags  []Diagnostic
+	// functions currently being traversed, used to break recursive calls
+	visiting map[string]struct{}
+	diags    []Diagnostic
 	// a stack of sources: values that influence any assignments created
 	// within the current control structure
 	controlSrcStack [][]string
@@ -42,6 +45,12 @@ func (da *dataflowAnalyzer) traverseNode(fn *funcNode) error {
 	if _, ok := da.seen[fn.name]; ok {
 		return nil
 	}
+	// A recursive call back to a function that is already being traversed
+	if _, ok := da.visiting[fn.name]; ok {
+		return nil
+	}
+	da.visiting[fn.name] = struct{}{}
+	defer delete(da.visiting, fn.name)
 	// Have to check the invoked functions first
 	for _, call := range fn.calls {
 		if err := da.traverseNode(call); err != nil {
@@ -1,6 +1,7 @@
 package staticlark
 
 import (
+	"encoding/json"
 	"fmt"
 	"reflect"
 
@@ -27,6 +28,21 @@ func collectFuncDefsTopLevelCalls(stmts []syntax.Stmt) ([]*funcNode, []string, e
 	return functions, topLevel, nil
 }
 
+// addLoadedSymbols registers each symbol imported by a top level load
+// statement in the symbol table, tracking the module it is loaded from
+func addLoadedSymbols(stmts []syntax.Stmt, symtable map[string]*funcNode) {
+	for _, stmt := range stmts {
+		load, ok := stmt.(*syntax.LoadStmt)
+		if !ok {
+			continue
+		}
+		module := load.ModuleName()
+		for _, local := range load.To {
+			symtable[local.Name] = &funcNode{name: local.Name, module: module}
+		}
+	}
+}
+
 // build a function object, contains calls to other functions
 func analyzeFunction(def *syntax.DefStmt) (*funcNode, error) {
 	params := make([]string, len(def.Params))
@@ -60,6 +76,9 @@ type funcNode struct {
 	name   string
 	params []string
 	body   []syntax.Stmt
+	// module the function is imported from by a load statement, empty for
+	// functions defined in the script or builtins
+	module string
 	calls  []*funcNode
 	reach  bool
 	height int
@@ -73,6 +92,46 @@ type funcNode struct {
 	reasonParams    []reason
 }
 
+// funcNodeJSON is the serialized form of a funcNode
+type funcNodeJSON struct {
+	Name   string   `json:"name"`
+	Params []string `json:"params"`
+	Calls  []string `json:"calls"`
+	// Body is the position of the function body in the source script
+	Body *funcBodySpan `json:"body,omitempty"`
+}
+
+// funcBodySpan is the start & end position of a function body
+type funcBodySpan struct {
+	Start syntax.Position `json:"start"`
+	End   syntax.Position `json:"end"`
+}
+
+// MarshalJSON serializes a function's name, parameters, and the names of the
+// functions it calls. The raw syntax tree of the body is never included, only
+// the position of the body within the script
+func (n *funcNode) MarshalJSON() ([]byte, error) {
+	calls := n.callNames
+	if calls == nil {
+		// nodes in the call graph keep resolved calls instead of names
+		calls = make([]string, 0, len(n.calls))
+		for _, c := range n.calls {
+			calls = append(calls, c.name)
+		}
+	}
+	v := funcNodeJSON{
+		Name:   n.name,
+		Params: n.params,
+		Calls:  calls,
+	}
+	if len(n.body) > 0 {
+		start, _ := n.body[0].Span()
+		_, end := n.body[len(n.body)-1].Span()
+		v.Body = &funcBodySpan{Start: start, End: end}
+	}
+	return json.Marshal(v)
+}
+
 // newFuncNode constructs a new funcNode
 func newFuncNode() *funcNode {
 	return &funcNode{calls: []*funcNode{}}
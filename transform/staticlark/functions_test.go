@@ -1,6 +1,7 @@
 package staticlark
 
 import (
+	"encoding/json"
 	"fmt"
 	"testing"
 
@@ -70,3 +71,41 @@ func TestCollectFunctionsAllSyntax(t *testing.T) {
 		t.Errorf("mismatch (-want +got):\n%s", diff)
 	}
 }
+
+func TestFunctionsJSON(t *testing.T) {
+	data, err := FunctionsJSON("testdata/some_funcs.star")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	funcs := []struct {
+		Name   string
+		Params []string
+		Calls  []string
+		Body   *struct {
+			Start struct{ Line int }
+			End   struct{ Line int }
+		}
+	}{}
+	if err := json.Unmarshal(data, &funcs); err != nil {
+		t.Fatal(err)
+	}
+	if len(funcs) != 8 {
+		t.Fatalf("expected 8 functions, got %d", len(funcs))
+	}
+
+	got := funcs[4]
+	if got.Name != "top_level_func" {
+		t.Fatalf("expected function 4 to be %q, got %q", "top_level_func", got.Name)
+	}
+	expectCalls := []string{"use_branch", "len", "branch_multiple", "branch_no_else", "another_function"}
+	if diff := cmp.Diff(expectCalls, got.Calls); diff != "" {
+		t.Errorf("calls mismatch (-want +got):\n%s", diff)
+	}
+	if diff := cmp.Diff([]string{"container"}, funcs[0].Params); diff != "" {
+		t.Errorf("params mismatch (-want +got):\n%s", diff)
+	}
+	if funcs[0].Body == nil || funcs[0].Body.Start.Line != 3 || funcs[0].Body.End.Line != 9 {
+		t.Errorf("expected body of %q to span lines 3-9, got: %v", funcs[0].Name, funcs[0].Body)
+	}
+}
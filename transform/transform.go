@@ -9,6 +9,7 @@ import (
 	"github.com/qri-io/dataset"
 	"github.com/qri-io/dataset/stepfile"
 	"github.com/qri-io/qfs"
+	"github.com/qri-io/qri/base/dsfs"
 	"github.com/qri-io/qri/dsref"
 	"github.com/qri-io/qri/event"
 	"github.com/qri-io/qri/profile"
@@ -57,6 +58,20 @@ type Transformer struct {
 	pub      event.Publisher
 	sizeInfo SizeInfo
 	changes  map[string]struct{}
+	// dataset the most recent application committed to, if the script named
+	// one other than the target
+	outputRef string
+	// domains scripts may make http requests to, nil allows all domains
+	allowedDomains []string
+	// run scripts with a fixed clock & seeded random source
+	deterministic bool
+	seed          int64
+	// largest body size to diff for commit messages, 0 uses the default
+	bodyDiffThreshold int
+	// customizes generated commit titles & messages, nil uses the default
+	commitMessage dsfs.CommitMessageFunc
+	// confirm bodies reported as unchanged by comparing checksums
+	verifyBody bool
 }
 
 // SizeInfo is info about the size of the area that output is displayed on
@@ -76,6 +91,37 @@ func NewTransformer(appCtx context.Context, fs qfs.Filesystem, loader dsref.Load
 	}
 }
 
+// AllowHTTPDomains restricts http requests made by transform scripts to the
+// given domains & their subdomains. A nil list allows all domains
+func (t *Transformer) AllowHTTPDomains(domains []string) {
+	t.allowedDomains = domains
+}
+
+// Deterministic runs transform scripts with a fixed clock and a random source
+// seeded with seed, so applying the same transform produces identical output
+func (t *Transformer) Deterministic(seed int64) {
+	t.deterministic = true
+	t.seed = seed
+}
+
+// SetBodyDiffThreshold sets the largest body size in bytes that will be
+// diffed to describe body changes in commit messages. 0 uses the default
+func (t *Transformer) SetBodyDiffThreshold(size int) {
+	t.bodyDiffThreshold = size
+}
+
+// SetCommitMessageFunc sets a function that customizes the commit title &
+// message generated when a transform commits. nil uses the default
+func (t *Transformer) SetCommitMessageFunc(f dsfs.CommitMessageFunc) {
+	t.commitMessage = f
+}
+
+// SetVerifyBodyChecksum configures whether bodies a transform reports as
+// unchanged are confirmed by comparing checksums with the previous version
+func (t *Transformer) SetVerifyBodyChecksum(verify bool) {
+	t.verifyBody = verify
+}
+
 // Apply applies the transform script to a target dataset
 func (t *Transformer) Apply(
 	ctx context.Context,
@@ -123,6 +169,7 @@ func (t *Transformer) apply(
 
 	ownerID := profile.IDFromCtx(ctx)
 
+	latestRef := ""
 	if target.Name != "" {
 		head, err := t.loader.LoadDataset(ctx, fmt.Sprintf("%s/%s", target.Peername, target.Name))
 		if errors.Is(err, dsref.ErrRefNotFound) || errors.Is(err, dsref.ErrNoHistory) {
@@ -132,6 +179,9 @@ func (t *Transformer) apply(
 		} else if err != nil {
 			return err
 		}
+		if head.Path != "" {
+			latestRef = fmt.Sprintf("%s/%s@%s", target.Peername, target.Name, head.Path)
+		}
 
 		head.DropTransientValues()
 		head.DropDerivedValues()
@@ -148,6 +198,7 @@ func (t *Transformer) apply(
 	}
 
 	t.changes = make(map[string]struct{})
+	t.outputRef = ""
 	eventsCh := make(chan event.Event)
 
 	opts := []func(*startf.ExecOpts){
@@ -157,6 +208,16 @@ func (t *Transformer) apply(
 		startf.AddEventsChannel(eventsCh),
 		startf.TrackChanges(t.changes),
 		startf.SizeInfo(t.sizeInfo.OutputWidth, t.sizeInfo.OutputHeight),
+		startf.AllowHTTPDomains(t.allowedDomains),
+		startf.SetLatestRef(latestRef),
+		startf.SetBodyDiffThreshold(t.bodyDiffThreshold),
+		startf.SetCommitMessageFunc(t.commitMessage),
+	}
+	if t.deterministic {
+		opts = append(opts, startf.Deterministic(t.seed))
+	}
+	if t.verifyBody {
+		opts = append(opts, startf.VerifyBodyChecksum)
 	}
 
 	doneCh := make(chan error)
@@ -174,14 +235,24 @@ func (t *Transformer) apply(
 		ctx, cancel := context.WithCancel(ctx)
 		defer cancel()
 
-		// Forward events from the events channel to the eventBus
+		// Forward events from the events channel to the eventBus. Once the
+		// context is cancelled remaining events are drained & dropped until the
+		// channel is closed, so the transform never blocks on sending an event
 		go func() {
 			receivedTransformStopEvt := false
 			inTransformStep := false
 			transformStepPayload := event.TransformStepLifecycle{}
+			ctxDone := ctx.Done()
 			for {
 				select {
-				case e := <-eventsCh:
+				case e, ok := <-eventsCh:
+					if !ok {
+						return
+					}
+					if ctxDone == nil {
+						// context has been cancelled, drop the event
+						continue
+					}
 					t.pub.PublishID(ctx, e.Type, runID, e.Payload)
 					if e.Type == event.ETTransformStop {
 						receivedTransformStopEvt = true
@@ -198,7 +269,8 @@ func (t *Transformer) apply(
 						inTransformStep = false
 						transformStepPayload = event.TransformStepLifecycle{}
 					}
-				case <-ctx.Done():
+				case <-ctxDone:
+					ctxDone = nil
 					if !receivedTransformStopEvt {
 						log.Warnw("context closed before transform stop event was sent", "runID", runID)
 
@@ -232,7 +304,6 @@ func (t *Transformer) apply(
 							log.Debugw("error publishing ETTransformCanceled", "err", err)
 						}
 					}
-					return
 				}
 			}
 		}()
@@ -250,6 +321,7 @@ func (t *Transformer) apply(
 		if len(target.Transform.Steps) == 0 && target.Transform.ScriptFile() != nil {
 			steps, err := stepfile.Read(target.Transform.ScriptFile())
 			if err != nil {
+				close(eventsCh)
 				doneCh <- err
 				return
 			}
@@ -330,6 +402,8 @@ func (t *Transformer) apply(
 			}
 		}
 
+		t.outputRef = stepRunner.OutputRef()
+
 		// warn user if commit wasn't called
 		if status != StatusFailed && !stepRunner.CommitCalled() {
 			eventsCh <- event.Event{
@@ -352,6 +426,7 @@ func (t *Transformer) apply(
 				Status: status,
 			},
 		}
+		close(eventsCh)
 		doneCh <- runErr
 	}()
 
@@ -363,6 +438,14 @@ func (t *Transformer) Changes() map[string]struct{} {
 	return t.changes
 }
 
+// OutputRef returns the dataset reference the most recent application
+// committed to when a script calls dataset.commit with a ref other than the
+// target, eg: dataset.commit(ds, ref="me/output"). OutputRef is empty when the
+// script committed to the target dataset
+func (t *Transformer) OutputRef() string {
+	return t.outputRef
+}
+
 // scriptLen returns the length of the script string, -1 if the script is not
 // a string type
 func scriptLen(step *dataset.TransformStep) int {
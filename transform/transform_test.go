@@ -3,6 +3,7 @@ package transform
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"io/ioutil"
 	"testing"
 
@@ -10,8 +11,10 @@ import (
 	"github.com/google/go-cmp/cmp/cmpopts"
 	"github.com/qri-io/dataset"
 	"github.com/qri-io/qfs"
+	"github.com/qri-io/qri/automation/run"
 	"github.com/qri-io/qri/dsref"
 	"github.com/qri-io/qri/event"
+	"github.com/qri-io/qri/transform/startf"
 )
 
 func TestApply(t *testing.T) {
@@ -166,6 +169,65 @@ func TestCommit(t *testing.T) {
 	}
 }
 
+func TestApplySetupAbort(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	runID := "setup_abort"
+	tf := &dataset.Transform{
+		Steps: []*dataset.TransformStep{
+			{Syntax: "starlark", Category: "setup", Script: "def setup(ctx):\n  if not ctx.secrets.get('api_key'):\n    error('missing api_key secret')"},
+			{Syntax: "starlark", Category: "download", Script: `print("downloading")`},
+			{Syntax: "starlark", Category: "transform", Script: `print("transforming")`},
+		},
+	}
+
+	bus := event.NewBus(ctx)
+	log := []event.Event{}
+	stopped := make(chan struct{}, 1)
+	bus.SubscribeID(func(ctx context.Context, e event.Event) error {
+		log = append(log, e)
+		if e.Type == event.ETTransformStop {
+			stopped <- struct{}{}
+		}
+		return nil
+	}, runID)
+
+	transformer := NewTransformer(ctx, qfs.NewMemFS(), &noHistoryLoader{}, bus, SizeInfo{})
+	err := transformer.Apply(ctx, &dataset.Dataset{Transform: tf}, runID, true, nil)
+	if !errors.Is(err, startf.ErrSetupFailed) {
+		t.Fatalf("expected error to wrap %q, got: %v", startf.ErrSetupFailed, err)
+	}
+	<-stopped
+
+	expect := []event.Event{
+		{Type: event.ETTransformStart, Payload: event.TransformLifecycle{RunID: runID, StepCount: 3, Mode: "apply"}},
+		{Type: event.ETTransformStepStart, Payload: event.TransformStepLifecycle{Category: "setup", Mode: "apply"}},
+		{Type: event.ETTransformError, Payload: event.TransformMessage{Lvl: event.TransformMsgLvlError, Msg: `transform setup failed: transform error: "missing api_key secret"`, Mode: "apply"}},
+		{Type: event.ETTransformStepStop, Payload: event.TransformStepLifecycle{Category: "setup", Status: StatusFailed, Mode: "apply"}},
+		{Type: event.ETTransformStepSkip, Payload: event.TransformStepLifecycle{Category: "download", Mode: "apply"}},
+		{Type: event.ETTransformStepSkip, Payload: event.TransformStepLifecycle{Category: "transform", Mode: "apply"}},
+		{Type: event.ETTransformStop, Payload: event.TransformLifecycle{RunID: runID, Status: StatusFailed, Mode: "apply"}},
+	}
+	compareEventLogs(t, expect, log)
+
+	// with the secret present setup passes & all steps run
+	log = []event.Event{}
+	if err := transformer.Apply(ctx, &dataset.Dataset{Transform: tf}, runID, true, map[string]string{"api_key": "secret"}); err != nil {
+		t.Fatal(err)
+	}
+	<-stopped
+	prints := 0
+	for _, e := range log {
+		if e.Type == event.ETTransformPrint {
+			prints++
+		}
+	}
+	if prints < 2 {
+		t.Errorf("expected download & transform steps to print after setup succeeds, got %d print events", prints)
+	}
+}
+
 // run a transform script & capture the event log. transform runs against an
 // empty dataset history
 func applyNoHistoryTransform(t *testing.T, initID string, tf *dataset.Transform, runID, runMode string) []event.Event {
@@ -311,3 +373,48 @@ func TestApplyAssignsColumnsAndBody(t *testing.T) {
 	}
 
 }
+
+func TestApplyStructuredLog(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	runID := "structured_log"
+	tf := &dataset.Transform{
+		Steps: []*dataset.TransformStep{
+			{Syntax: "starlark", Name: "setup", Category: "setup", Script: "def setup(ctx):\n  ctx.log('debug', 'starting')"},
+			{Syntax: "starlark", Name: "transform", Category: "transform", Script: `log("warn", "missing rows", {"count": 2})`},
+		},
+	}
+
+	bus := event.NewBus(ctx)
+	rs := run.NewState(runID)
+	stopped := make(chan struct{}, 1)
+	bus.SubscribeID(func(ctx context.Context, e event.Event) error {
+		if err := rs.AddTransformEvent(e); err != nil {
+			t.Error(err)
+		}
+		if e.Type == event.ETTransformStop {
+			stopped <- struct{}{}
+		}
+		return nil
+	}, runID)
+
+	transformer := NewTransformer(ctx, qfs.NewMemFS(), &noHistoryLoader{}, bus, SizeInfo{})
+	if err := transformer.Apply(ctx, &dataset.Dataset{Transform: tf}, runID, true, nil); err != nil {
+		t.Fatal(err)
+	}
+	<-stopped
+
+	expect := []run.LogEntry{
+		{Step: "setup", TransformLog: event.TransformLog{Lvl: event.TransformMsgLvlDebug, Msg: "starting"}},
+		{Step: "transform", TransformLog: event.TransformLog{Lvl: event.TransformMsgLvlWarn, Msg: "missing rows", Fields: map[string]interface{}{"count": 2}}},
+	}
+	got := rs.Logs(run.LogQuery{})
+	if diff := cmp.Diff(expect, got, cmpopts.IgnoreFields(run.LogEntry{}, "Timestamp")); diff != "" {
+		t.Errorf("result mismatch (-want +got):\n%s", diff)
+	}
+
+	if warnings := rs.Logs(run.LogQuery{Lvl: event.TransformMsgLvlWarn}); len(warnings) != 1 {
+		t.Errorf("expected 1 warning, got %d", len(warnings))
+	}
+}